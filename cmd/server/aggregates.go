@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/soaringjerry/Synap/internal/api"
+)
+
+// rebuildAggregates implements `server rebuild-aggregates [scale_id...]`: it recomputes the
+// analytics aggregates of the given scales (all stored ones by default), prints one JSON check
+// per scale and exits with 1 when any stored aggregate had drifted from the responses.
+func rebuildAggregates(store api.Store, scaleIDs []string) int {
+	checks, err := api.RebuildAnalyticsAggregates(store, scaleIDs)
+	if err != nil {
+		log.Printf("rebuild aggregates: %v", err)
+		return 2
+	}
+	enc := json.NewEncoder(os.Stdout)
+	drifted := 0
+	for _, c := range checks {
+		if c.Stored && !c.Consistent {
+			drifted++
+		}
+		_ = enc.Encode(c)
+	}
+	log.Printf("rebuilt %d analytics aggregates, %d had drifted", len(checks), drifted)
+	if drifted > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -23,6 +24,12 @@ import (
 )
 
 func main() {
+	if err := middleware.LoadKeys(); err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	if err := api.CheckMailConfig(); err != nil {
+		log.Fatalf("mail: %v", err)
+	}
 	sqlitePath := os.Getenv("SYNAP_SQLITE_PATH")
 	if sqlitePath == "" {
 		sqlitePath = "./data/synap.sqlite"
@@ -53,6 +60,15 @@ func main() {
 	if err != nil {
 		log.Fatalf("init sqlite store: %v", err)
 	}
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-aggregates" {
+		os.Exit(rebuildAggregates(store, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(verifyAudit(store))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export-audit" {
+		os.Exit(exportAudit(store))
+	}
 
 	addr := os.Getenv("SYNAP_ADDR")
 	if addr == "" {
@@ -63,7 +79,12 @@ func main() {
 
 	mux := http.NewServeMux()
 	// API routes
-	api.NewRouterWithStore(store).Register(mux)
+	router := api.NewRouterWithStore(store)
+	router.Register(mux)
+	// Outgoing webhook delivery queue
+	go router.RunWebhookDeliveries(context.Background(), 5*time.Second)
+	// Signed checkpoints of the audit hash chain
+	go router.RunAuditCheckpoints(context.Background(), time.Hour)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		locale := middleware.LocaleFromContext(r.Context())
@@ -89,6 +89,16 @@ func copySnapshotToStore(snap *api.LegacySnapshot, dst api.Store) error {
 			dst.AddUser(u)
 		}
 	}
+	// the migrations ran before the users existed, so older snapshots get their memberships here
+	members := snap.Memberships
+	if members == nil {
+		members = api.LegacyMemberships(snap.Users)
+	}
+	for _, m := range members {
+		if m != nil {
+			dst.SaveTenantMember(m)
+		}
+	}
 	for _, cfg := range snap.AIConfigs {
 		if cfg != nil {
 			dst.UpsertAIConfig(cfg)
@@ -1,8 +1,15 @@
-# Placeholder Python example for calling the API
-# Replace with actual endpoints once implemented.
+# Minimal Python example for calling the API with a personal API token.
+# Create one under /api/auth/tokens (e.g. scopes ["scales:read", "responses:export"]) and export it
+# as SYNAP_TOKEN.
+
+import os
 
 import requests
 
-resp = requests.get("http://localhost:8080/health", timeout=5)
-print(resp.status_code, resp.json())
+BASE = os.environ.get("SYNAP_URL", "http://localhost:8080")
+headers = {"Authorization": f"Bearer {os.environ['SYNAP_TOKEN']}"}
 
+resp = requests.get(f"{BASE}/api/admin/scales", headers=headers, timeout=10)
+resp.raise_for_status()
+for scale in resp.json()["scales"]:
+    print(scale["id"], scale.get("name_i18n", {}).get("en", ""))
@@ -14,6 +14,10 @@ import { ScaleEditor } from './pages/ScaleEditor'
 import { AdminKeys } from './pages/AdminKeys'
 import { AdminAI } from './pages/AdminAI'
 import { SelfManage } from './pages/SelfManage'
+import { ResetPassword } from './pages/ResetPassword'
+import { VerifyEmail } from './pages/VerifyEmail'
+import { Invite } from './pages/Invite'
+import { refreshSession, sendVerificationEmail } from './api/client'
 
 import React from 'react'
 import { ToastProvider } from './components/Toast'
@@ -22,14 +26,18 @@ import { useTranslation } from 'react-i18next'
 function useServerAuth() {
   const [authed, setAuthed] = React.useState<boolean>(false)
   const [loading, setLoading] = React.useState(true)
-  const [user, setUser] = React.useState<{user_id:string;tenant_id:string;email:string}|null>(null)
+  const [user, setUser] = React.useState<{user_id:string;tenant_id:string;email:string;email_verified?:boolean}|null>(null)
   const loc = useLocation()
   React.useEffect(() => {
     let cancelled = false
     ;(async () => {
       setLoading(true)
       try {
-        const res = await fetch('/api/auth/me', { headers: { 'Accept': 'application/json' } })
+        let res = await fetch('/api/auth/me', { headers: { 'Accept': 'application/json' } })
+        // the access cookie is short-lived; renew it once from the refresh cookie
+        if (res.status === 401 && await refreshSession().then(() => true, () => false)) {
+          res = await fetch('/api/auth/me', { headers: { 'Accept': 'application/json' } })
+        }
         if (!res.ok) { setAuthed(false); setUser(null); return }
         const data = await res.json()
         if (!cancelled) { setUser(data); setAuthed(true) }
@@ -41,6 +49,11 @@ function useServerAuth() {
     })()
     return () => { cancelled = true }
   }, [loc.pathname])
+  React.useEffect(() => {
+    if (!authed) return
+    const timer = window.setInterval(() => { refreshSession().catch(() => setAuthed(false)) }, 10 * 60 * 1000)
+    return () => window.clearInterval(timer)
+  }, [authed])
   return { authed, loading, user, setAuthed }
 }
 
@@ -52,11 +65,21 @@ async function logout(setAuthed: (b:boolean)=>void, navigate: (path:string)=>voi
 }
 
 function RootLayout() {
-  const { authed, setAuthed } = useServerAuth() as any
+  const { authed, setAuthed, user } = useServerAuth() as any
   const { t } = useTranslation()
   const navigate = useNavigate()
   const location = useLocation()
   const [menuOpen, setMenuOpen] = React.useState(false)
+  const [verifyMsg, setVerifyMsg] = React.useState('')
+
+  async function resendVerification() {
+    try {
+      await sendVerificationEmail()
+      setVerifyMsg(t('auth:verify_sent'))
+    } catch (e: any) {
+      setVerifyMsg(e.message || String(e))
+    }
+  }
 
   React.useEffect(() => {
     setMenuOpen(false)
@@ -99,6 +122,13 @@ function RootLayout() {
       </header>
       <main className="page">
         <div className="container">
+          {authed && user && user.email_verified === false && (
+            <div className="card" role="status" style={{ marginBottom: 12, display:'flex', gap: 8, alignItems:'center', flexWrap:'wrap' }}>
+              <span className="muted">{t('auth:verify_banner')}</span>
+              <button className="btn btn-ghost" onClick={resendVerification}>{t('auth:verify_resend')}</button>
+              {verifyMsg && <span className="muted">{verifyMsg}</span>}
+            </div>
+          )}
           <Outlet />
         </div>
       </main>
@@ -135,6 +165,9 @@ const router = createBrowserRouter([
     { path: '/admin/scale/:id', element: <Protected><ScaleEditor/></Protected> },
     { path: '/admin/scale/:id/legacy', element: <Protected><AdminScale/></Protected> },
     { path: '/auth', element: <Auth/> },
+    { path: '/auth/reset', element: <ResetPassword/> },
+    { path: '/auth/verify', element: <VerifyEmail/> },
+    { path: '/invite', element: <Invite/> },
     { path: '/survey/:scaleId', element: <Survey/> },
     { path: '/legal/privacy', element: <Privacy/> },
     { path: '/legal/terms', element: <Terms/> },
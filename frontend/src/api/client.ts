@@ -1,4 +1,6 @@
-export type Scale = { id: string; points: number; randomize?: boolean; name_i18n?: Record<string, string>; consent_i18n?: Record<string,string>; collect_email?: 'off'|'optional'|'required'; e2ee_enabled?: boolean; region?: 'auto'|'gdpr'|'pipl'|'pdpa'|'ccpa'; turnstile_enabled?: boolean; turnstile_sitekey?: string; items_per_page?: number; likert_labels_i18n?: Record<string,string[]>; likert_show_numbers?: boolean; likert_preset?: string }
+import type { FeedbackReport, FeedbackSpec } from '../utils/feedback'
+
+export type Scale = { id: string; points: number; randomize?: boolean; name_i18n?: Record<string, string>; consent_i18n?: Record<string,string>; collect_email?: 'off'|'optional'|'required'; e2ee_enabled?: boolean; region?: 'auto'|'gdpr'|'pipl'|'pdpa'|'ccpa'; turnstile_enabled?: boolean; turnstile_sitekey?: string; items_per_page?: number; likert_labels_i18n?: Record<string,string[]>; likert_show_numbers?: boolean; likert_preset?: string; tenant_id?: string; shared_role?: 'editor'|'viewer' }
 export type ItemOut = {
   id: string
   stem: string
@@ -41,13 +43,19 @@ export async function listItems(scaleId: string, lang: string) {
   return j<{ scale_id: string; items: ItemOut[] }>(res)
 }
 
-export async function submitBulk(scaleId: string, email: string, answers: { item_id: string; raw: any }[], opts?: { consent_id?: string, turnstile_token?: string }) {
+export async function submitBulk(scaleId: string, email: string, answers: { item_id: string; raw: any }[], opts?: { consent_id?: string, turnstile_token?: string, condition?: string, lang?: string }) {
   const res = await fetch(`${base}/api/responses/bulk`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ participant: { email }, scale_id: scaleId, answers, consent_id: opts?.consent_id, turnstile_token: opts?.turnstile_token })
+    body: JSON.stringify({ participant: { email }, scale_id: scaleId, answers, consent_id: opts?.consent_id, turnstile_token: opts?.turnstile_token, condition: opts?.condition, lang: opts?.lang })
   })
-  return j<{ ok: boolean; participant_id: string; count: number; self_token?: string; self_export?: string; self_delete?: string }>(res)
+  return j<{ ok: boolean; participant_id: string; count: number; self_token?: string; self_export?: string; self_delete?: string; self_feedback?: string; feedback?: FeedbackReport }>(res)
+}
+
+// Feedback report spec for computing reports locally (E2EE projects); see utils/feedback.ts
+export async function getFeedbackSpec(scaleId: string) {
+  const res = await fetch(`${base}/api/scales/${encodeURIComponent(scaleId)}/feedback/spec`)
+  return j<FeedbackSpec>(res)
 }
 
 export async function getAlpha(scaleId: string) {
@@ -61,6 +69,26 @@ function authHeaders(): Record<string,string> {
   return token ? { Authorization: `Bearer ${token}` } : {} as Record<string,string>
 }
 
+// Step-up: sensitive operations answer 403 "step-up required" until a TOTP or recovery code is
+// verified; the step-up token then replaces the auth cookie for 10 minutes.
+export async function stepUp(code: string) {
+  const res = await fetch(`${base}/api/auth/step-up`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ code }) })
+  const out = await j<{ token: string; expires_at: string; method: 'totp'|'recovery' }>(res)
+  if (typeof window !== 'undefined' && localStorage.getItem('token')) localStorage.setItem('token', out.token)
+  return out
+}
+async function withStepUp<T>(call: () => Promise<T>): Promise<T> {
+  try {
+    return await call()
+  } catch (e: any) {
+    if (e?.status !== 403 || !String(e.message).includes('step-up required') || typeof window === 'undefined') throw e
+    const code = window.prompt('Enter the code from your authenticator app (or a recovery code)')
+    if (!code) throw e
+    await stepUp(code.trim())
+    return call()
+  }
+}
+
 export async function adminListScales() {
   const res = await fetch(`${base}/api/admin/scales`, { headers: authHeaders() })
   return j<{ scales: Scale[] }>(res)
@@ -90,12 +118,16 @@ export async function adminUpdateScale(id: string, input: Partial<Scale>) {
   return j<{ok:true}>(res)
 }
 export async function adminDeleteScale(id: string) {
-  const res = await fetch(`${base}/api/admin/scales/${encodeURIComponent(id)}`, { method: 'DELETE', headers: authHeaders() })
-  return j<{ok:true}>(res)
+  return withStepUp(async () => {
+    const res = await fetch(`${base}/api/admin/scales/${encodeURIComponent(id)}`, { method: 'DELETE', headers: authHeaders() })
+    return j<{ok:true}>(res)
+  })
 }
 export async function adminPurgeResponses(scaleId: string) {
-  const res = await fetch(`${base}/api/admin/scales/${encodeURIComponent(scaleId)}/responses`, { method: 'DELETE', headers: authHeaders() })
-  return j<{ok:true; removed:number}>(res)
+  return withStepUp(async () => {
+    const res = await fetch(`${base}/api/admin/scales/${encodeURIComponent(scaleId)}/responses`, { method: 'DELETE', headers: authHeaders() })
+    return j<{ok:true; removed:number}>(res)
+  })
 }
 export async function adminCreateItem(input: { scale_id: string, reverse_scored?: boolean, stem_i18n: Record<string,string>, type?: ItemOut['type'], options_i18n?: Record<string,string[]>, min?: number, max?: number, step?: number, required?: boolean, placeholder_i18n?: Record<string,string>, likert_labels_i18n?: Record<string,string[]>, likert_show_numbers?: boolean }) {
   const res = await fetch(`${base}/api/items`, { method: 'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
@@ -123,6 +155,34 @@ export type AnalyticsSummary = {
   timeseries: { date: string; count: number }[]
   alpha: number
   n: number
+  descriptives?: ItemDescriptive[]
+  reliability?: Reliability
+}
+
+export type Reliability = {
+  n: number
+  alpha: number
+  omega: number
+  lambda6: number
+  split_half_odd_even: number
+  split_half_random: number
+  bootstrap?: number
+  seed?: number
+  confidence?: number
+  ci?: Record<string, { lower: number; upper: number }>
+}
+
+export type NumericStats = { mean: number; sd: number; median: number; min: number; max: number; skewness: number; kurtosis: number }
+export type ItemDescriptive = {
+  id: string
+  type: string
+  stem_i18n?: Record<string,string>
+  reverse_scored?: boolean
+  n: number
+  missing: number
+  stats?: NumericStats
+  frequencies?: { value: string; count: number; percent: number }[]
+  text_length?: NumericStats
 }
 
 export async function adminAnalyticsSummary(scaleId: string) {
@@ -130,6 +190,224 @@ export async function adminAnalyticsSummary(scaleId: string) {
   return j<AnalyticsSummary>(res)
 }
 
+// Live response feed (Server-Sent Events). Relies on the session cookie, since EventSource cannot send headers.
+export type ScaleEvent = { id: number; type: string; scale_id: string; participant_id?: string; response_id?: string; consent_id?: string; format?: string; count?: number; time: string }
+export function subscribeScaleEvents(scaleId: string, handlers: { ready?: (s: { scale_id: string; e2ee: boolean; responses: number }) => void; event: (ev: ScaleEvent) => void; error?: (e: Event) => void }) {
+  const es = new EventSource(`${base}/api/admin/scales/${encodeURIComponent(scaleId)}/events`, { withCredentials: true })
+  es.addEventListener('ready', e => handlers.ready?.(JSON.parse((e as MessageEvent).data)))
+  for (const type of ['response.submitted', 'e2ee_response.submitted', 'consent.signed', 'participant.deleted', 'e2ee_response.deleted', 'export.downloaded']) {
+    es.addEventListener(type, e => handlers.event(JSON.parse((e as MessageEvent).data)))
+  }
+  if (handlers.error) es.onerror = handlers.error
+  return () => es.close()
+}
+
+// Outgoing webhooks
+export type Webhook = { id: string; tenant_id: string; scale_id?: string; url: string; events: string[]; active: boolean; created_at: string; updated_at: string }
+export type WebhookDelivery = { id: string; webhook_id: string; scale_id?: string; event: string; payload: any; status: 'pending'|'delivered'|'dead'; attempts: number; next_attempt_at?: string; last_attempt_at?: string; last_status?: number; last_error?: string; created_at: string; delivered_at?: string }
+export type WebhookInput = { url: string; events: string[]; scale_id?: string; active?: boolean }
+export async function adminListWebhooks() {
+  const res = await fetch(`${base}/api/admin/webhooks`, { headers: authHeaders() })
+  return j<{ webhooks: Webhook[]; events: string[] }>(res)
+}
+export async function adminCreateWebhook(input: WebhookInput) {
+  const res = await fetch(`${base}/api/admin/webhooks`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
+  return j<{ webhook: Webhook; secret: string }>(res)
+}
+export async function adminUpdateWebhook(id: string, input: WebhookInput) {
+  const res = await fetch(`${base}/api/admin/webhooks/${encodeURIComponent(id)}`, { method:'PUT', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
+  return j<Webhook>(res)
+}
+export async function adminDeleteWebhook(id: string) {
+  const res = await fetch(`${base}/api/admin/webhooks/${encodeURIComponent(id)}`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+export async function adminTestWebhook(id: string) {
+  const res = await fetch(`${base}/api/admin/webhooks/${encodeURIComponent(id)}/test`, { method:'POST', headers: authHeaders() })
+  return j<WebhookDelivery>(res)
+}
+export async function adminListWebhookDeliveries(id: string, status?: WebhookDelivery['status']) {
+  const q = status ? `?status=${status}` : ''
+  const res = await fetch(`${base}/api/admin/webhooks/${encodeURIComponent(id)}/deliveries${q}`, { headers: authHeaders() })
+  return j<{ deliveries: WebhookDelivery[] }>(res)
+}
+export async function adminRedeliverWebhook(id: string, deliveryId: string) {
+  const res = await fetch(`${base}/api/admin/webhooks/${encodeURIComponent(id)}/deliveries/${encodeURIComponent(deliveryId)}/redeliver`, { method:'POST', headers: authHeaders() })
+  return j<WebhookDelivery>(res)
+}
+
+// Sessions: access tokens live 15 minutes; the refresh cookie (path /api/auth) renews them
+export type Session = { id: string; user_agent: string; ip: string; created_at: string; last_seen_at: string; expires_at: string; current: boolean }
+export async function refreshSession() {
+  const res = await fetch(`${base}/api/auth/refresh`, { method:'POST', credentials:'include' })
+  return j<{ token: string; tenant_id: string; user_id: string; expires_in: number }>(res)
+}
+export async function listSessions() {
+  const res = await fetch(`${base}/api/auth/sessions`, { headers: authHeaders() })
+  return j<{ sessions: Session[] }>(res)
+}
+export async function revokeSession(id: string) {
+  const res = await fetch(`${base}/api/auth/sessions/${encodeURIComponent(id)}`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+export async function revokeAllSessions(keepCurrent = false) {
+  const res = await fetch(`${base}/api/auth/sessions${keepCurrent ? '?keep_current=true' : ''}`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true; revoked: number }>(res)
+}
+
+// Single sign-on (OpenID Connect) per tenant
+export type OIDCConfig = { tenant_id: string; enabled: boolean; issuer: string; client_id: string; client_secret?: string; has_client_secret: boolean; scopes?: string; allowed_domains: string[]; role_claim?: string; role_map?: Record<string, 'admin'|'member'>; default_role: ''|'admin'|'member'; updated_at?: string }
+export async function discoverSSO(email: string) {
+  const res = await fetch(`${base}/api/auth/oidc/discover?email=${encodeURIComponent(email)}`)
+  return j<{ tenant_id: string; login_url: string }>(res)
+}
+export async function adminGetOIDCConfig() {
+  const res = await fetch(`${base}/api/admin/oidc`, { headers: authHeaders() })
+  return j<OIDCConfig>(res)
+}
+export async function adminUpdateOIDCConfig(input: Partial<OIDCConfig>) {
+  const res = await fetch(`${base}/api/admin/oidc`, { method:'PUT', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
+  return j<OIDCConfig>(res)
+}
+export async function adminDeleteOIDCConfig() {
+  const res = await fetch(`${base}/api/admin/oidc`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+
+// Password reset and email verification
+export async function requestPasswordReset(email: string) {
+  const res = await fetch(`${base}/api/auth/password/forgot`, { method:'POST', headers: { 'Content-Type':'application/json' }, body: JSON.stringify({ email }) })
+  return j<{ ok: true }>(res)
+}
+export async function resetPassword(token: string, password: string) {
+  const res = await fetch(`${base}/api/auth/password/reset`, { method:'POST', headers: { 'Content-Type':'application/json' }, body: JSON.stringify({ token, password }) })
+  return j<{ ok: true }>(res)
+}
+export async function verifyEmail(token: string) {
+  const res = await fetch(`${base}/api/auth/verify-email`, { method:'POST', headers: { 'Content-Type':'application/json' }, body: JSON.stringify({ token }) })
+  return j<{ ok: true }>(res)
+}
+export async function sendVerificationEmail() {
+  const res = await fetch(`${base}/api/auth/verify-email/send`, { method:'POST', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+
+// Failed sign-in lockouts of the tenant's accounts
+export type Lockout = { email: string; failures: number; last_failure_at: string; locked_until?: string; locked: boolean }
+export async function adminListLockouts() {
+  const res = await fetch(`${base}/api/admin/lockouts`, { headers: authHeaders() })
+  return j<{ lockouts: Lockout[] }>(res)
+}
+export async function adminUnlockAccount(email: string) {
+  const res = await fetch(`${base}/api/admin/lockouts?email=${encodeURIComponent(email)}`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+
+// Tenant members and the signed-in user's tenants
+export type TenantRole = 'owner' | 'admin' | 'member'
+export type Membership = { tenant_id: string; tenant_name?: string; user_id: string; email: string; role: TenantRole; created_at: string }
+export type InviteStatus = 'pending' | 'accepted' | 'revoked' | 'expired'
+export type MemberInvite = { id: string; tenant_id: string; scale_id?: string; email: string; role: string; invited_by: string; created_at: string; expires_at: string; accepted_at?: string; revoked_at?: string; status: InviteStatus; token?: string }
+export async function listMembers() {
+  const res = await fetch(`${base}/api/admin/members`, { headers: authHeaders() })
+  return j<{ members: Membership[] }>(res)
+}
+export async function inviteMember(email: string, role: 'admin' | 'member') {
+  const res = await fetch(`${base}/api/admin/members`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ email, role }) })
+  return j<{ invite: MemberInvite; invite_url: string }>(res)
+}
+export async function changeMemberRole(userId: string, role: 'admin' | 'member') {
+  const res = await fetch(`${base}/api/admin/members/${encodeURIComponent(userId)}`, { method:'PUT', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ role }) })
+  return j<Membership>(res)
+}
+export async function removeMember(userId: string) {
+  const res = await fetch(`${base}/api/admin/members/${encodeURIComponent(userId)}`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+export async function transferOwnership(userId: string) {
+  return withStepUp(async () => {
+    const res = await fetch(`${base}/api/admin/members/transfer`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ user_id: userId }) })
+    return j<{ ok: true }>(res)
+  })
+}
+// Invitations into the tenant (admins) or onto a scale (scaleId, any member)
+export async function listInvites(scaleId?: string) {
+  const q = scaleId ? `?scale_id=${encodeURIComponent(scaleId)}` : ''
+  const res = await fetch(`${base}/api/admin/invites${q}`, { headers: authHeaders() })
+  return j<{ invites: MemberInvite[] }>(res)
+}
+export async function revokeInvite(id: string) {
+  const res = await fetch(`${base}/api/admin/invites/${encodeURIComponent(id)}`, { method:'DELETE', headers: authHeaders() })
+  return j<{ ok: true }>(res)
+}
+export async function resendInvite(id: string) {
+  const res = await fetch(`${base}/api/admin/invites/${encodeURIComponent(id)}/resend`, { method:'POST', headers: authHeaders() })
+  return j<{ invite: MemberInvite; invite_url: string }>(res)
+}
+export type InviteInfo = { email: string; role: string; tenant_id: string; tenant_name: string; scale_id?: string; expires_at: string; account_exists: boolean }
+export async function lookupInvite(token: string) {
+  const res = await fetch(`${base}/api/invites/accept?token=${encodeURIComponent(token)}`)
+  return j<InviteInfo>(res)
+}
+// Signed in as the invited address the invite is applied; otherwise password creates the account.
+export async function acceptInvite(token: string, password?: string) {
+  const res = await fetch(`${base}/api/invites/accept`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ token, password }) })
+  return j<{ invite?: MemberInvite; token?: string; tenant_id?: string; user_id?: string }>(res)
+}
+export async function listMyTenants() {
+  const res = await fetch(`${base}/api/auth/tenants`, { headers: authHeaders() })
+  return j<{ tenants: Membership[]; current: string }>(res)
+}
+export async function createTenant(name: string) {
+  const res = await fetch(`${base}/api/auth/tenants`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ name }) })
+  return j<Membership>(res)
+}
+export async function switchTenant(tenantId: string) {
+  const res = await fetch(`${base}/api/auth/tenant/switch`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ tenant_id: tenantId }) })
+  const out = await j<{ token: string; tenant_id: string; user_id: string; expires_in: number }>(res)
+  if (typeof window !== 'undefined' && localStorage.getItem('token')) localStorage.setItem('token', out.token)
+  return out
+}
+
+// Two-factor authentication (TOTP) used for step-up
+export type MFAStatus = { enabled: boolean; pending: boolean; recovery_codes_left: number; enabled_at?: string }
+export async function getMFAStatus() {
+  const res = await fetch(`${base}/api/auth/mfa`, { headers: authHeaders() })
+  return j<MFAStatus>(res)
+}
+export async function enrollMFA() {
+  const res = await fetch(`${base}/api/auth/mfa/enroll`, { method:'POST', headers: authHeaders() })
+  return j<{ secret: string; otpauth_url: string }>(res)
+}
+async function mfaAction(action: 'confirm'|'recovery-codes'|'disable', code: string) {
+  return fetch(`${base}/api/auth/mfa/${action}`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ code }) })
+}
+export async function confirmMFA(code: string) {
+  return j<{ recovery_codes: string[] }>(await mfaAction('confirm', code))
+}
+export async function regenerateRecoveryCodes(code: string) {
+  return j<{ recovery_codes: string[] }>(await mfaAction('recovery-codes', code))
+}
+export async function disableMFA(code: string) {
+  return j<{ ok: true }>(await mfaAction('disable', code))
+}
+
+// Personal API tokens
+export type APIToken = { id: string; name: string; prefix: string; scopes: string[]; scale_id?: string; expires_at?: string; last_used_at?: string; created_at: string; revoked_at?: string }
+export type APITokenInput = { name: string; scopes: string[]; scale_id?: string; expires_in_days?: number }
+export async function listAPITokens() {
+  const res = await fetch(`${base}/api/auth/tokens`, { headers: authHeaders() })
+  return j<{ tokens: APIToken[]; scopes: string[] }>(res)
+}
+export async function createAPIToken(input: APITokenInput) {
+  const res = await fetch(`${base}/api/auth/tokens`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
+  return j<{ api_token: APIToken; token: string }>(res)
+}
+export async function revokeAPIToken(id: string) {
+  const res = await fetch(`${base}/api/auth/tokens/${encodeURIComponent(id)}`, { method:'DELETE', headers: authHeaders() })
+  return j<APIToken>(res)
+}
+
 // E2EE keys management
 export async function adminListProjectKeys(projectId: string) {
   const res = await fetch(`${base}/api/projects/${encodeURIComponent(projectId)}/keys`, { headers: authHeaders() })
@@ -155,6 +433,10 @@ export async function participantSelfExport(pid: string, token: string) {
   const res = await fetch(`${base}/api/self/participant/export?pid=${encodeURIComponent(pid)}&token=${encodeURIComponent(token)}`)
   return j<{ participant: any; responses: any[] }>(res)
 }
+export async function participantSelfFeedback(pid: string, token: string, lang?: string) {
+  const res = await fetch(`${base}/api/self/participant/feedback?pid=${encodeURIComponent(pid)}&token=${encodeURIComponent(token)}${lang?`&lang=${encodeURIComponent(lang)}`:''}`)
+  return j<FeedbackReport>(res)
+}
 export async function participantSelfDelete(pid: string, token: string, hard?: boolean) {
   const res = await fetch(`${base}/api/self/participant/delete?pid=${encodeURIComponent(pid)}&token=${encodeURIComponent(token)}${hard?'&hard=true':''}`, { method: 'POST' })
   return j<{ ok: boolean }>(res)
@@ -170,8 +452,10 @@ export async function e2eeSelfDelete(response_id: string, token: string) {
 
 // E2EE export (step-up + short URL)
 export async function adminCreateE2EEExport(scale_id: string) {
-  const res = await fetch(`${base}/api/exports/e2ee`, { method:'POST', headers: { 'Content-Type':'application/json', 'X-Step-Up':'true', ...authHeaders() }, body: JSON.stringify({ scale_id }) })
-  return j<{ url: string; expires_at: string }>(res)
+  return withStepUp(async () => {
+    const res = await fetch(`${base}/api/exports/e2ee`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ scale_id }) })
+    return j<{ url: string; expires_at: string }>(res)
+  })
 }
 
 // --- Admin AI config & translation ---
@@ -181,8 +465,10 @@ export async function adminGetAIConfig() {
   return j<AIConfig>(res)
 }
 export async function adminUpdateAIConfig(input: Partial<AIConfig>) {
-  const res = await fetch(`${base}/api/admin/ai/config`, { method:'PUT', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
-  return j<{ok:true}>(res)
+  return withStepUp(async () => {
+    const res = await fetch(`${base}/api/admin/ai/config`, { method:'PUT', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify(input) })
+    return j<{ok:true}>(res)
+  })
 }
 export async function adminAITranslatePreview(scale_id: string, target_langs: string[], model?: string) {
   const res = await fetch(`${base}/api/admin/ai/translate/preview`, { method:'POST', headers: { 'Content-Type':'application/json', ...authHeaders() }, body: JSON.stringify({ scale_id, target_langs, model }) })
@@ -202,7 +488,7 @@ export async function getScalePublicMeta(id: string) {
 }
 
 // --- Team collaborators ---
-export type Collaborator = { user_id: string; email: string; role: 'editor'|'viewer' }
+export type Collaborator = { user_id: string; email: string; role: 'editor'|'viewer'; guest?: boolean }
 export async function adminListCollaborators(scaleId: string) {
   const res = await fetch(`${base}/api/admin/scales/${encodeURIComponent(scaleId)}/collaborators`, { headers: authHeaders() })
   return j<{ collaborators: Collaborator[] }>(res)
@@ -229,7 +229,7 @@ export function Admin() {
           )}
           {scales.map((s:any)=>(
             <div key={s.id} className="item" style={{display:'flex',justifyContent:'space-between', alignItems:'center'}}>
-              <div><b>{s.id}</b> · {(s.name_i18n?.en||'')}{s.name_i18n?.zh?` / ${s.name_i18n.zh}`:''}</div>
+              <div><b>{s.id}</b> · {(s.name_i18n?.en||'')}{s.name_i18n?.zh?` / ${s.name_i18n.zh}`:''}{s.shared_role && <span className="muted"> · {t('team.shared_as', { role: t(`team.role_${s.shared_role}`) })}</span>}</div>
               <div style={{display:'flex',gap:8}}>
                 <button className="btn" onClick={()=>copyLink(s.id)}>{t('share')}</button>
                 <a className="btn btn-ghost" href={shareLink(s.id)} target="_blank" rel="noreferrer">{t('open')}</a>
@@ -2,15 +2,32 @@ import React, { useEffect, useMemo, useState } from 'react'
 import { useTranslation } from 'react-i18next'
 import { adminListScales } from '../api/client'
 
-type AuditEntry = { time: string; actor: string; action: string; target: string; note?: string }
+type AuditEntry = { seq?: number; time: string; actor: string; action: string; target: string; note?: string; ip?: string; result?: string }
+type Source = 'all'|'admin'|'participant'
+type Filters = { scaleId: string; source: Source; action: string; actor: string; from: string; to: string }
+
+const emptyFilters: Filters = { scaleId: '', source: 'all', action: '', actor: '', from: '', to: '' }
+
+function auditParams(f: Filters) {
+  const params = new URLSearchParams()
+  if (f.scaleId) params.set('scale_id', f.scaleId)
+  if (f.source !== 'all') params.set('source', f.source)
+  if (f.action.trim()) params.set('action', f.action.trim())
+  if (f.actor.trim()) params.set('actor', f.actor.trim())
+  if (f.from) params.set('from', f.from)
+  if (f.to) params.set('to', f.to)
+  return params
+}
 
 export function AdminAudit() {
   const { t } = useTranslation()
   const [entries, setEntries] = useState<AuditEntry[]>([])
-  const [scaleId, setScaleId] = useState<string>('')
+  const [cursor, setCursor] = useState('')
+  const [filters, setFilters] = useState<Filters>(emptyFilters)
   const [scales, setScales] = useState<{ id: string; name_i18n?: Record<string,string> }[]>([])
   const [loading, setLoading] = useState(false)
   const [err, setErr] = useState('')
+  const [chain, setChain] = useState('')
 
   async function loadScales() {
     try {
@@ -19,76 +36,101 @@ export function AdminAudit() {
     } catch (e:any) { setErr(e.message||String(e)) }
   }
 
-  const [source, setSource] = useState<'all'|'admin'|'participant'>('all')
-
-  async function loadAudit(sel?: string, src?: 'all'|'admin'|'participant') {
+  // loadAudit fetches the first page for f, or the next one when after is set
+  async function loadAudit(f: Filters, after?: string) {
     setLoading(true)
     setErr('')
     try {
-      const params = new URLSearchParams()
-      if (sel) params.set('scale_id', sel)
-      if (src && src !== 'all') params.set('source', src)
+      const params = auditParams(f)
+      if (after) params.set('cursor', after)
       const qs = params.toString()
-      const url = qs ? `/api/admin/audit?${qs}` : '/api/admin/audit'
-      const res = await fetch(url)
-      const data = await res.json()
+      const res = await fetch(qs ? `/api/admin/audit?${qs}` : '/api/admin/audit')
+      const data = await res.json().catch(()=>({}))
       if (!res.ok) throw new Error(data?.error || res.statusText)
-      setEntries((data||[]).map((e:any)=>({
-        time: e.time || e.ts || '',
-        actor: e.actor,
-        action: e.action,
-        target: e.target,
-        note: e.note,
-      })))
+      const page: AuditEntry[] = data.entries || []
+      setEntries(prev => after ? [...prev, ...page] : page)
+      setCursor(data.next_cursor || '')
     } catch (e:any) {
       setErr(e.message||String(e))
     } finally { setLoading(false) }
   }
 
-  useEffect(()=>{ loadScales(); loadAudit('', source) },[])
+  async function verifyChain() {
+    setChain('')
+    try {
+      const res = await fetch('/api/admin/audit/verify')
+      const data = await res.json().catch(()=>({}))
+      if (!res.ok) throw new Error(data?.error || res.statusText)
+      setChain(data.ok
+        ? t('admin.audit_chain_ok', { entries: data.entries, checkpoints: data.checkpoints })
+        : t('admin.audit_chain_broken', { seq: data.broken?.seq, reason: data.broken?.reason }))
+    } catch (e:any) { setChain(e.message||String(e)) }
+  }
 
-  const onFilter = (e: React.ChangeEvent<HTMLSelectElement>) => {
-    const sel = e.target.value
-    setScaleId(sel)
-    loadAudit(sel, source)
+  const exportURL = (format: 'proof'|'csv'|'jsonl') => {
+    const params = auditParams(filters)
+    params.set('format', format)
+    return `/api/admin/audit/export?${params.toString()}`
   }
-  const onSource = (e: React.ChangeEvent<HTMLSelectElement>) => {
-    const val = (e.target.value as any) as 'all'|'admin'|'participant'
-    setSource(val)
-    loadAudit(scaleId, val)
+
+  useEffect(()=>{ loadScales(); loadAudit(emptyFilters) },[])
+
+  // selects apply at once; text and date filters wait for the search button
+  const update = (patch: Partial<Filters>, reload = false) => {
+    const next = { ...filters, ...patch }
+    setFilters(next)
+    if (reload) loadAudit(next)
   }
 
   const title = useMemo(()=>{
-    const s = scales.find(s=> s.id===scaleId)
+    const s = scales.find(s=> s.id===filters.scaleId)
     const base = t('admin.audit_log') || 'Audit Log'
     if (!s) return base
     const name = s.name_i18n?.en || s.name_i18n?.zh || s.id
     return `${base} · ${name}`
-  }, [scales, scaleId])
+  }, [scales, filters.scaleId])
 
   return (
     <div className="container">
       <div className="hero"><div className="glitch" data-text={title}>{title}</div></div>
       <div className="row">
         <section className="card span-12">
-          <div style={{display:'flex', gap:12, alignItems:'center'}}>
+          <div style={{display:'flex', gap:12, alignItems:'center', flexWrap:'wrap'}}>
             <label>
               <span style={{marginRight:8}}>{t('admin.audit_filter')||'Filter by Scale'}</span>
-              <select className="input" value={scaleId} onChange={onFilter}>
+              <select className="input" value={filters.scaleId} onChange={e=> update({ scaleId: e.target.value }, true)}>
                 <option value="">{t('admin.audit_all_scales')||'All scales'}</option>
                 {scales.map(s=> <option key={s.id} value={s.id}>{s.id}</option>)}
               </select>
             </label>
             <label>
               <span style={{marginRight:8}}>{t('admin.audit_source')||'Source'}</span>
-              <select className="input" value={source} onChange={onSource}>
+              <select className="input" value={filters.source} onChange={e=> update({ source: e.target.value as Source }, true)}>
                 <option value="all">{t('admin.audit_src_all')||'All'}</option>
                 <option value="admin">{t('admin.audit_src_admin')||'Admin'}</option>
                 <option value="participant">{t('admin.audit_src_participant')||'Participant'}</option>
               </select>
             </label>
+            <input className="input" placeholder={t('admin.audit_action')||'Action'} value={filters.action} onChange={e=> update({ action: e.target.value })} />
+            <input className="input" placeholder={t('admin.audit_actor')||'Actor'} value={filters.actor} onChange={e=> update({ actor: e.target.value })} />
+            <label>
+              <span style={{marginRight:8}}>{t('admin.audit_from')}</span>
+              <input className="input" type="date" value={filters.from} onChange={e=> update({ from: e.target.value })} />
+            </label>
+            <label>
+              <span style={{marginRight:8}}>{t('admin.audit_to')}</span>
+              <input className="input" type="date" value={filters.to} onChange={e=> update({ to: e.target.value })} />
+            </label>
+            <button className="btn" onClick={()=> loadAudit(filters)}>{t('admin.audit_search')}</button>
             {loading && <div className="muted">Loading…</div>}
           </div>
+          <div style={{display:'flex', gap:12, alignItems:'center', marginTop:8}}>
+            <a className="btn" href={exportURL('csv')}>{t('admin.audit_export_csv')}</a>
+            <a className="btn" href={exportURL('jsonl')}>{t('admin.audit_export_jsonl')}</a>
+            <a className="btn" href={exportURL('proof')}>{t('admin.audit_export')}</a>
+            <button className="btn" onClick={verifyChain}>{t('admin.audit_verify')}</button>
+          </div>
+          {chain && <div className="muted" style={{marginTop:8}}>{chain}</div>}
           <div style={{overflowX:'auto', marginTop:12}}>
             <table className="table" style={{width:'100%'}}>
               <thead>
@@ -98,24 +140,31 @@ export function AdminAudit() {
                   <th style={{textAlign:'left'}}>{t('admin.audit_action')||'Action'}</th>
                   <th style={{textAlign:'left'}}>{t('admin.audit_target')||'Target'}</th>
                   <th style={{textAlign:'left'}}>{t('admin.audit_note')||'Note'}</th>
+                  <th style={{textAlign:'left'}}>{t('admin.audit_ip')}</th>
+                  <th style={{textAlign:'left'}}>{t('admin.audit_result')}</th>
                 </tr>
               </thead>
               <tbody>
                 {entries.map((e, i)=> (
-                  <tr key={i}>
+                  <tr key={e.seq ?? i}>
                     <td>{(e.time||'').replace('T',' ').replace('Z','')}</td>
                     <td>{e.actor}</td>
                     <td>{e.action}</td>
                     <td>{e.target}</td>
                     <td>{e.note||''}</td>
+                    <td>{e.ip||''}</td>
+                    <td>{e.result||''}</td>
                   </tr>
                 ))}
                 {entries.length===0 && !loading && (
-                  <tr><td colSpan={5} className="muted">{t('admin.audit_empty')||'No audit entries'}</td></tr>
+                  <tr><td colSpan={7} className="muted">{t('admin.audit_empty')||'No audit entries'}</td></tr>
                 )}
               </tbody>
             </table>
           </div>
+          {cursor && (
+            <button className="btn" style={{marginTop:8}} disabled={loading} onClick={()=> loadAudit(filters, cursor)}>{t('admin.audit_more')}</button>
+          )}
           {err && <div className="muted" style={{marginTop:8}}>{err}</div>}
         </section>
       </div>
@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { useTranslation } from 'react-i18next'
+import { discoverSSO, requestPasswordReset } from '../api/client'
 
 export function Auth() {
   const { t } = useTranslation()
@@ -39,12 +40,40 @@ export function Auth() {
     }
   }
 
+  async function sso() {
+    setMsg('')
+    try {
+      setLoading(true)
+      const { login_url } = await discoverSSO(email)
+      window.location.href = `${login_url}&return_to=${encodeURIComponent('/admin')}`
+    } catch (e: any) {
+      setMsg(e.status === 404 ? t('auth:sso_unavailable') : (e.message || String(e)))
+      setLoading(false)
+    }
+  }
+
+  async function forgot() {
+    setMsg('')
+    try {
+      setLoading(true)
+      await requestPasswordReset(email)
+      setMsg(t('auth:reset_requested'))
+    } catch (e: any) {
+      setMsg(e.message || String(e))
+    } finally {
+      setLoading(false)
+    }
+  }
+
   useEffect(() => {
     const usp = new URLSearchParams(location.search)
     const inv = usp.get('invite')
     const em = usp.get('email')
     if (inv) setInvite(inv)
     if (em && !email) setEmail(em)
+    const ssoError = usp.get('sso_error')
+    if (ssoError) setMsg(ssoError)
+    if (usp.get('reset')) { setMode('login'); setMsg(t('auth:reset_done')) }
   // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [])
 
@@ -77,6 +106,12 @@ export function Auth() {
           )}
           <div style={{ height: 12 }} />
           <button className="neon-btn" onClick={submit} disabled={loading}>{mode==='register'?t('create_account'):t('login')}</button>
+          {mode==='login' && (
+            <>
+              <button className="btn btn-ghost" style={{ marginLeft: 8 }} onClick={sso} disabled={loading || !email}>{t('auth:sso')}</button>
+              <button className="btn btn-ghost" style={{ marginLeft: 8 }} onClick={forgot} disabled={loading || !email}>{t('auth:forgot')}</button>
+            </>
+          )}
           {msg && <div className="muted" role="alert" style={{ marginTop:8 }}>{msg}</div>}
         </section>
       </div>
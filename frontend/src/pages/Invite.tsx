@@ -0,0 +1,76 @@
+import React, { useEffect, useState } from 'react'
+import { Link, useNavigate } from 'react-router-dom'
+import { useTranslation } from 'react-i18next'
+import { acceptInvite, lookupInvite, type InviteInfo } from '../api/client'
+
+export function Invite() {
+  const { t } = useTranslation()
+  const nav = useNavigate()
+  const token = new URLSearchParams(location.search).get('token') || ''
+  const [info, setInfo] = useState<InviteInfo | null>(null)
+  const [password, setPassword] = useState('')
+  const [confirm, setConfirm] = useState('')
+  const [msg, setMsg] = useState('')
+  const [loading, setLoading] = useState(true)
+
+  useEffect(() => {
+    if (!token) { setMsg(t('auth:link_invalid')); setLoading(false); return }
+    lookupInvite(token)
+      .then(setInfo)
+      .catch((e: any) => setMsg(e.message || String(e)))
+      .finally(() => setLoading(false))
+  // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [])
+
+  async function accept() {
+    setMsg('')
+    if (info && !info.account_exists && password !== confirm) { setMsg(t('auth:password_mismatch')); return }
+    try {
+      setLoading(true)
+      await acceptInvite(token, info?.account_exists ? undefined : password)
+      nav('/admin')
+    } catch (e: any) {
+      setMsg(e.message || String(e))
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const target = info ? (info.tenant_name || info.tenant_id) : ''
+  return (
+    <div className="container">
+      <div className="row">
+        <section className="card span-6">
+          <h3 style={{marginTop:0}}>{t('auth:title_invite')}</h3>
+          {info && (
+            <>
+              <div className="muted">
+                {info.scale_id
+                  ? t('auth:invite_scale', { email: info.email, tenant: target, scale: info.scale_id, role: info.role })
+                  : t('auth:invite_tenant', { email: info.email, tenant: target, role: info.role })}
+              </div>
+              <div style={{ height: 12 }} />
+              {info.account_exists ? (
+                <div className="muted">{t('auth:invite_sign_in', { email: info.email })} <Link to="/auth">{t('auth:title_login')}</Link></div>
+              ) : (
+                <>
+                  <div className="item"><div className="label">{t('auth:new_password')}</div>
+                    <input className="input" type="password" autoComplete="new-password" value={password} onChange={e=>setPassword(e.target.value)} />
+                  </div>
+                  <div className="item"><div className="label">{t('auth:confirm_password')}</div>
+                    <input className="input" type="password" autoComplete="new-password" value={confirm} onChange={e=>setConfirm(e.target.value)} />
+                  </div>
+                  <div className="muted">{t('auth:password_rules')}</div>
+                </>
+              )}
+              <div style={{ height: 12 }} />
+              <button className="neon-btn" onClick={accept} disabled={loading || (!info.account_exists && !password)}>{t('auth:invite_accept')}</button>
+            </>
+          )}
+          {loading && !info && <div className="muted">{t('auth:verifying')}</div>}
+          {msg && <div className="muted" role="alert" style={{ marginTop:8 }}>{msg} {!info && <Link to="/auth">{t('auth:back_to_login')}</Link>}</div>}
+        </section>
+      </div>
+    </div>
+  )
+}
@@ -0,0 +1,54 @@
+import React, { useState } from 'react'
+import { Link, useNavigate } from 'react-router-dom'
+import { useTranslation } from 'react-i18next'
+import { resetPassword } from '../api/client'
+
+export function ResetPassword() {
+  const { t } = useTranslation()
+  const nav = useNavigate()
+  const token = new URLSearchParams(location.search).get('token') || ''
+  const [password, setPassword] = useState('')
+  const [confirm, setConfirm] = useState('')
+  const [msg, setMsg] = useState('')
+  const [loading, setLoading] = useState(false)
+
+  async function submit() {
+    setMsg('')
+    if (password !== confirm) { setMsg(t('auth:password_mismatch')); return }
+    try {
+      setLoading(true)
+      await resetPassword(token, password)
+      nav('/auth?reset=1')
+    } catch (e: any) {
+      setMsg(e.message || String(e))
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="container">
+      <div className="row">
+        <section className="card span-6">
+          <h3 style={{marginTop:0}}>{t('auth:title_reset')}</h3>
+          {!token ? (
+            <div className="muted" role="alert">{t('auth:link_invalid')} <Link to="/auth">{t('auth:back_to_login')}</Link></div>
+          ) : (
+            <>
+              <div className="item"><div className="label">{t('auth:new_password')}</div>
+                <input className="input" type="password" autoComplete="new-password" value={password} onChange={e=>setPassword(e.target.value)} />
+              </div>
+              <div className="item"><div className="label">{t('auth:confirm_password')}</div>
+                <input className="input" type="password" autoComplete="new-password" value={confirm} onChange={e=>setConfirm(e.target.value)} />
+              </div>
+              <div className="muted">{t('auth:password_rules')}</div>
+              <div style={{ height: 12 }} />
+              <button className="neon-btn" onClick={submit} disabled={loading || !password}>{t('auth:set_password')}</button>
+            </>
+          )}
+          {msg && <div className="muted" role="alert" style={{ marginTop:8 }}>{msg}</div>}
+        </section>
+      </div>
+    </div>
+  )
+}
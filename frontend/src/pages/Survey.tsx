@@ -908,7 +908,7 @@ export function Survey() {
               return
             } else {
               const arr = Object.entries(answers).map(([item_id, raw])=>({item_id, raw}))
-              const res = await submitBulk(scaleId, email.trim(), arr as any, { consent_id: consentId || undefined, turnstile_token: turnstileToken || undefined })
+              const res = await submitBulk(scaleId, email.trim(), arr as any, { consent_id: consentId || undefined, turnstile_token: turnstileToken || undefined, condition: new URLSearchParams(window.location.search).get('condition') || undefined })
               const participantId = (res as any).participant_id
               const manage = `${window.location.origin}/self?pid=${encodeURIComponent(participantId)}&token=${encodeURIComponent((res as any).self_token||'')}`
               const stems = items.reduce((m:any,it:any)=> (m[it.id]=it.stem, m), {} as Record<string,string>)
@@ -0,0 +1,32 @@
+import React, { useEffect, useState } from 'react'
+import { Link } from 'react-router-dom'
+import { useTranslation } from 'react-i18next'
+import { verifyEmail } from '../api/client'
+
+export function VerifyEmail() {
+  const { t } = useTranslation()
+  const [state, setState] = useState<'pending'|'ok'|'error'>('pending')
+  const [msg, setMsg] = useState('')
+
+  useEffect(() => {
+    const token = new URLSearchParams(location.search).get('token') || ''
+    if (!token) { setState('error'); setMsg(t('auth:link_invalid')); return }
+    verifyEmail(token)
+      .then(() => setState('ok'))
+      .catch((e: any) => { setState('error'); setMsg(e.message || String(e)) })
+  // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [])
+
+  return (
+    <div className="container">
+      <div className="row">
+        <section className="card span-6">
+          <h3 style={{marginTop:0}}>{t('auth:title_verify')}</h3>
+          {state === 'pending' && <div className="muted">{t('auth:verifying')}</div>}
+          {state === 'ok' && <div className="muted" role="status">{t('auth:verified')} <Link to="/admin">{t('nav.admin')}</Link></div>}
+          {state === 'error' && <div className="muted" role="alert">{msg} <Link to="/auth">{t('auth:back_to_login')}</Link></div>}
+        </section>
+      </div>
+    </div>
+  )
+}
@@ -1,7 +1,7 @@
 import React, { useEffect, useMemo, useState } from 'react'
 import { useTranslation } from 'react-i18next'
 import { useToast } from '../../../components/Toast'
-import { adminListCollaborators, adminAddCollaborator, adminRemoveCollaborator, Collaborator } from '../../../api/client'
+import { adminListCollaborators, adminAddCollaborator, adminRemoveCollaborator, Collaborator, listInvites, revokeInvite, resendInvite, MemberInvite } from '../../../api/client'
 import { useScaleEditor } from '../ScaleEditorContext'
 
 const roles: Array<'editor'|'viewer'> = ['editor','viewer']
@@ -11,6 +11,7 @@ export const CollaboratorsPanel: React.FC = () => {
   const toast = useToast()
   const { scaleId } = useScaleEditor()
   const [list, setList] = useState<Collaborator[]>([])
+  const [invites, setInvites] = useState<MemberInvite[]>([])
   const [email, setEmail] = useState('')
   const [role, setRole] = useState<'editor'|'viewer'>('editor')
   const [loading, setLoading] = useState(false)
@@ -21,6 +22,8 @@ export const CollaboratorsPanel: React.FC = () => {
     try {
       const res = await adminListCollaborators(scaleId)
       setList(res.collaborators || [])
+      const inv = await listInvites(scaleId)
+      setInvites((inv.invites || []).filter(i => i.status === 'pending' || i.status === 'expired'))
     } catch (e: any) {
       toast.error(e?.message || String(e))
     }
@@ -57,6 +60,26 @@ export const CollaboratorsPanel: React.FC = () => {
     }
   }
 
+  const revoke = async (id: string) => {
+    try {
+      await revokeInvite(id)
+      await load()
+    } catch (e: any) {
+      toast.error(e?.message || String(e))
+    }
+  }
+
+  const resend = async (id: string) => {
+    try {
+      const res = await resendInvite(id)
+      setInviteLink(res.invite_url)
+      await load()
+      toast.success(t('team.invite_resent'))
+    } catch (e: any) {
+      toast.error(e?.message || String(e))
+    }
+  }
+
   const updateRole = async (c: Collaborator, nextRole: 'editor'|'viewer') => {
     try {
       await adminAddCollaborator(scaleId, { email: c.email, role: nextRole })
@@ -79,6 +102,7 @@ export const CollaboratorsPanel: React.FC = () => {
         <select className="input" value={role} onChange={e=> setRole(e.target.value as any)}>
           {roles.map(r => <option key={r} value={r}>{t(`team.role_${r}`)}</option>)}
         </select>
+        <div className="muted">{t('team.guest_hint')}</div>
       </div>
       <div className="cta-row" style={{ gap: 8, flexWrap:'wrap' }}>
         <button className="btn" onClick={add} disabled={loading || !email.trim()}>{t('team.add')}</button>
@@ -99,6 +123,7 @@ export const CollaboratorsPanel: React.FC = () => {
                   const data = await res.json()
                   if (!res.ok) throw new Error(data?.error || res.statusText)
                   setInviteLink(data.invite_url as string)
+                  await load()
                 } catch (e: any) {
                   toast.error(e?.message || String(e))
                 }
@@ -120,7 +145,7 @@ export const CollaboratorsPanel: React.FC = () => {
             <div />
             {list.map(c => (
               <React.Fragment key={c.user_id}>
-                <div>{c.email}</div>
+                <div>{c.email}{c.guest && <span className="muted"> · {t('team.guest')}</span>}</div>
                 <div>
                   <select className="input" value={c.role} onChange={e => updateRole(c, e.target.value as any)}>
                     {roles.map(r => <option key={r} value={r}>{t(`team.role_${r}`)}</option>)}
@@ -134,6 +159,25 @@ export const CollaboratorsPanel: React.FC = () => {
           </div>
         )}
       </div>
+
+      {invites.length > 0 && (
+        <div className="tile" style={{ padding: 8, marginTop: 12 }}>
+          <div className="muted" style={{ marginBottom: 8 }}>{t('team.invites')}</div>
+          <div style={{ display:'grid', gridTemplateColumns:'2fr 1fr 1fr 200px', gap:8, alignItems:'center' }}>
+            {invites.map(i => (
+              <React.Fragment key={i.id}>
+                <div>{i.email}</div>
+                <div>{t(`team.role_${i.role}`)}</div>
+                <div className="muted">{t(`team.invite_${i.status}`)}</div>
+                <div className="cta-row" style={{ justifyContent:'flex-end' }}>
+                  <button className="btn btn-ghost" onClick={() => resend(i.id)}>{t('team.invite_resend')}</button>
+                  {i.status === 'pending' && <button className="btn btn-ghost" onClick={() => revoke(i.id)}>{t('team.invite_revoke')}</button>}
+                </div>
+              </React.Fragment>
+            ))}
+          </div>
+        </div>
+      )}
     </div>
   )
 }
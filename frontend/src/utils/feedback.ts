@@ -0,0 +1,173 @@
+// Client-side evaluation of participant feedback specs (GET /api/scales/{id}/feedback/spec).
+// Mirrors services.EvaluateFeedback on the server so E2EE participants get the same report
+// without their answers ever leaving the browser in plaintext.
+
+export const FEEDBACK_SPEC_VERSION = 1
+
+export type I18n = Record<string, string>
+export type NormCondition = { item_id: string; equals?: string[]; min?: number; max?: number }
+export type NormStratum = { label: string; conditions?: NormCondition[]; n?: number; mean: number; sd: number; percentiles?: { raw: number; percentile: number }[] }
+export type NormTable = { key: string; name?: string; item_ids?: string[]; strata: NormStratum[] }
+export type FeedbackBand = { min?: number; max?: number; label_i18n: I18n; text_i18n?: I18n }
+export type FeedbackScore = { key: string; label_i18n?: I18n; item_ids?: string[]; norm?: string; basis?: 'raw'|'z'|'t'|'percentile'; bands?: FeedbackBand[] }
+export type FeedbackSample = { n: number; mean: number; sd: number; difference?: number }
+export type FeedbackSpecItem = { id: string; type: string; reverse_scored?: boolean; min?: number; max?: number; options_i18n?: Record<string, string[]> }
+export type FeedbackSpec = {
+  version: number
+  scale_id: string
+  points: number
+  items: FeedbackSpecItem[]
+  intro_i18n?: I18n
+  scores: FeedbackScore[]
+  norms?: NormTable[]
+  sample?: Record<string, FeedbackSample>
+  fingerprint: string
+}
+export type NormScore = { key: string; name?: string; stratum: string; raw: number; z: number; t: number; percentile: number }
+export type FeedbackScoreResult = { key: string; label: string; raw: number; answered: number; items: number; norm?: NormScore; band_value?: number; band?: string; band_text?: string; sample?: FeedbackSample }
+export type FeedbackReport = { scale_id: string; participant_id?: string; lang: string; intro?: string; scores: FeedbackScoreResult[]; spec_fingerprint: string }
+
+type Answer = { score: number; numeric?: number; values: string[] }
+
+// roundTo matches Go's math.Round (half away from zero).
+function roundTo(x: number, digits: number): number {
+  const f = Math.pow(10, digits)
+  return Math.sign(x) * Math.round(Math.abs(x) * f) / f
+}
+
+function pickI18n(m: I18n | undefined, lang: string): string {
+  if (!m) return ''
+  if (m[lang]) return m[lang]
+  if (m.en) return m.en
+  for (const k of Object.keys(m).sort()) if (m[k]) return m[k]
+  return ''
+}
+
+function toInt(raw: any): number | undefined {
+  if (typeof raw === 'number' && isFinite(raw)) return Math.trunc(raw)
+  if (typeof raw === 'string' && /^\s*[-+]?\d+\s*$/.test(raw)) return parseInt(raw, 10)
+  return undefined
+}
+
+function toEnglish(item: FeedbackSpecItem, v: string): string {
+  const opts = item.options_i18n
+  if (!opts) return v
+  for (const list of Object.values(opts)) {
+    const i = list.indexOf(v)
+    if (i >= 0 && opts.en && i < opts.en.length) return opts.en[i]
+  }
+  return v
+}
+
+// scoreAnswer reproduces how the server stores an answer's score value.
+function scoreAnswer(item: FeedbackSpecItem, raw: any, points: number): Answer {
+  const n = toInt(raw)
+  if (item.type === 'likert') {
+    if (n !== undefined && n >= 1 && n <= points) {
+      const score = item.reverse_scored ? points + 1 - n : n
+      return { score, numeric: score, values: [] }
+    }
+    return { score: 0, values: [] }
+  }
+  if (item.type === 'rating' || item.type === 'slider' || item.type === 'numeric') {
+    const min = item.min || 0, max = item.max || 0
+    if (n !== undefined && ((min === 0 && max === 0) || (min <= n && (max === 0 || n <= max)))) {
+      return { score: n, numeric: n, values: [] }
+    }
+    return { score: 0, values: [] }
+  }
+  const list = Array.isArray(raw) ? raw : [raw]
+  const values = list.filter(v => typeof v === 'string' && v.trim() !== '').map(v => toEnglish(item, String(v).trim()))
+  return { score: 0, values }
+}
+
+function erfc(x: number): number {
+  // Numerical Recipes erfcc, fractional error < 1.2e-7
+  const z = Math.abs(x)
+  const t = 1 / (1 + 0.5 * z)
+  const r = t * Math.exp(-z * z - 1.26551223 + t * (1.00002368 + t * (0.37409196 + t * (0.09678418 + t * (-0.18628806 + t * (0.27886807 + t * (-1.13520398 + t * (1.48851587 + t * (-0.82215223 + t * 0.17087277)))))))))
+  return x >= 0 ? r : 2 - r
+}
+
+function normPercentile(st: NormStratum, raw: number, z: number): number {
+  const pts = [...(st.percentiles || [])].sort((a, b) => a.raw - b.raw)
+  if (pts.length === 0) return 50 * erfc(-z / Math.SQRT2)
+  if (raw <= pts[0].raw) return pts[0].percentile
+  for (let i = 1; i < pts.length; i++) {
+    if (raw <= pts[i].raw) {
+      const lo = pts[i - 1], hi = pts[i]
+      return lo.percentile + (raw - lo.raw) / (hi.raw - lo.raw) * (hi.percentile - lo.percentile)
+    }
+  }
+  return pts[pts.length - 1].percentile
+}
+
+function conditionHolds(c: NormCondition, a: Answer | undefined): boolean {
+  if (!a) return false
+  if (c.equals && c.equals.length > 0) {
+    const want = c.equals.map(v => v.trim().toLowerCase())
+    if (!a.values.some(v => want.includes(v.toLowerCase()))) return false
+  }
+  if (c.min !== undefined || c.max !== undefined) {
+    if (a.numeric === undefined) return false
+    if (c.min !== undefined && a.numeric < c.min) return false
+    if (c.max !== undefined && a.numeric > c.max) return false
+  }
+  return true
+}
+
+function normScore(t: NormTable, answers: Map<string, Answer>): NormScore | undefined {
+  let raw = 0, answered = 0
+  const ids = t.item_ids && t.item_ids.length > 0 ? t.item_ids : [...answers.keys()]
+  for (const id of ids) {
+    const a = answers.get(id)
+    if (a) { raw += a.score; answered++ }
+  }
+  if (answered === 0) return undefined
+  const st = t.strata.find(s => (s.conditions || []).every(c => conditionHolds(c, answers.get(c.item_id))))
+  if (!st) return undefined
+  const z = (raw - st.mean) / st.sd
+  return { key: t.key, name: t.name, stratum: st.label, raw, z: roundTo(z, 4), t: roundTo(50 + 10 * z, 2), percentile: roundTo(normPercentile(st, raw, z), 2) }
+}
+
+// evaluateFeedback computes the report for raw answers keyed by item ID, as they were submitted.
+export function evaluateFeedback(spec: FeedbackSpec, rawAnswers: Record<string, any>, lang = 'en'): FeedbackReport {
+  if (spec.version !== FEEDBACK_SPEC_VERSION) throw new Error(`unsupported feedback spec version ${spec.version}`)
+  lang = lang || 'en'
+  const answers = new Map<string, Answer>()
+  for (const item of spec.items) {
+    if (item.id in rawAnswers && rawAnswers[item.id] !== undefined && rawAnswers[item.id] !== null) {
+      answers.set(item.id, scoreAnswer(item, rawAnswers[item.id], spec.points))
+    }
+  }
+  const norms = new Map((spec.norms || []).map(t => [t.key, t] as const))
+  const report: FeedbackReport = { scale_id: spec.scale_id, lang, scores: [], spec_fingerprint: spec.fingerprint }
+  const intro = pickI18n(spec.intro_i18n, lang)
+  if (intro) report.intro = intro
+  for (const score of spec.scores) {
+    const ids = score.item_ids || []
+    let raw = 0, answered = 0
+    for (const id of ids) {
+      const a = answers.get(id)
+      if (a) { raw += a.score; answered++ }
+    }
+    if (answered === 0) continue
+    const res: FeedbackScoreResult = { key: score.key, label: pickI18n(score.label_i18n, lang) || score.key, raw, answered, items: ids.length }
+    const table = score.norm ? norms.get(score.norm) : undefined
+    if (table) res.norm = normScore(table, answers)
+    const basis = score.basis || 'raw'
+    const value = basis === 'raw' ? raw : res.norm ? (basis === 'z' ? res.norm.z : basis === 't' ? res.norm.t : res.norm.percentile) : undefined
+    if (value !== undefined) {
+      res.band_value = value
+      const band = (score.bands || []).find(b => (b.min === undefined || value >= b.min) && (b.max === undefined || value < b.max))
+      if (band) {
+        res.band = pickI18n(band.label_i18n, lang) || undefined
+        res.band_text = pickI18n(band.text_i18n, lang) || undefined
+      }
+    }
+    const smp = spec.sample?.[score.key]
+    if (smp && smp.n > 0) res.sample = { ...smp, difference: roundTo(raw - smp.mean, 4) }
+    report.scores.push(res)
+  }
+  return report
+}
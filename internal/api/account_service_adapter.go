@@ -0,0 +1,73 @@
+package api
+
+import (
+	"time"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type accountStoreAdapter struct {
+	auth  services.AuthStore
+	store Store
+}
+
+func newAccountStoreAdapter(store Store) services.AccountStore {
+	return &accountStoreAdapter{auth: newAuthStoreAdapter(store), store: store}
+}
+
+func (a *accountStoreAdapter) FindUserByEmail(email string) (*services.User, error) {
+	return a.auth.FindUserByEmail(email)
+}
+
+func (a *accountStoreAdapter) SetUserPassword(userID string, hash []byte) error {
+	if !a.store.SetUserPassword(userID, hash) {
+		return services.NewNotFoundError("user not found")
+	}
+	return nil
+}
+
+func (a *accountStoreAdapter) SetEmailVerified(userID string, at time.Time) error {
+	if !a.store.SetEmailVerified(userID, at) {
+		return services.NewNotFoundError("user not found")
+	}
+	return nil
+}
+
+func (a *accountStoreAdapter) SaveEmailToken(t *services.EmailToken) error {
+	if t == nil {
+		return services.NewInvalidError("token required")
+	}
+	if !a.store.SaveEmailToken(&EmailToken{ID: t.ID, UserID: t.UserID, Email: t.Email, Purpose: t.Purpose, Hash: t.Hash,
+		CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, UsedAt: t.UsedAt}) {
+		return services.NewConflictError("unable to save token")
+	}
+	return nil
+}
+
+func toServiceEmailToken(t *EmailToken) *services.EmailToken {
+	return &services.EmailToken{ID: t.ID, UserID: t.UserID, Email: t.Email, Purpose: t.Purpose, Hash: t.Hash,
+		CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, UsedAt: t.UsedAt}
+}
+
+func (a *accountStoreAdapter) GetEmailTokenByHash(hash string) (*services.EmailToken, error) {
+	t := a.store.GetEmailTokenByHash(hash)
+	if t == nil {
+		return nil, nil
+	}
+	return toServiceEmailToken(t), nil
+}
+
+func (a *accountStoreAdapter) ListEmailTokens(userID, purpose string) ([]*services.EmailToken, error) {
+	list := a.store.ListEmailTokens(userID, purpose)
+	out := make([]*services.EmailToken, 0, len(list))
+	for _, t := range list {
+		out = append(out, toServiceEmailToken(t))
+	}
+	return out, nil
+}
+
+func (a *accountStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+var _ services.AccountStore = (*accountStoreAdapter)(nil)
@@ -21,4 +21,8 @@ func (a *aiConfigStoreAdapter) UpsertAIConfig(cfg *services.TenantAIConfig) erro
 	return nil
 }
 
+func (a *aiConfigStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
 var _ services.AIConfigStore = (*aiConfigStoreAdapter)(nil)
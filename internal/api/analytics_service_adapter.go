@@ -1,6 +1,10 @@
 package api
 
-import "github.com/soaringjerry/Synap/internal/services"
+import (
+	"encoding/json"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
 
 type analyticsStoreAdapter struct {
 	store Store
@@ -32,4 +36,88 @@ func (a *analyticsStoreAdapter) ListResponsesByScale(scaleID string) ([]*service
 	return out, nil
 }
 
+func (a *analyticsStoreAdapter) ListParticipantConditions(scaleID string) (map[string]string, error) {
+	return a.store.ListParticipantConditions(scaleID), nil
+}
+
 var _ services.AnalyticsStore = (*analyticsStoreAdapter)(nil)
+
+type analyticsAggregateStoreAdapter struct {
+	*analyticsStoreAdapter
+	participants *participantStoreAdapter
+}
+
+func newAnalyticsAggregateStoreAdapter(store Store) services.AnalyticsAggregateStore {
+	return &analyticsAggregateStoreAdapter{
+		analyticsStoreAdapter: &analyticsStoreAdapter{store: store},
+		participants:          &participantStoreAdapter{store: store},
+	}
+}
+
+func (a *analyticsAggregateStoreAdapter) GetItem(id string) (*services.Item, error) {
+	it := a.store.GetItem(id)
+	if it == nil {
+		return nil, nil
+	}
+	return convertAPIItem(it), nil
+}
+
+func (a *analyticsAggregateStoreAdapter) ListResponsesByParticipant(id string) ([]*services.Response, error) {
+	return a.participants.ListResponsesByParticipant(id)
+}
+
+func (a *analyticsAggregateStoreAdapter) GetAnalyticsAggregate(scaleID string) (*services.AnalyticsAggregate, error) {
+	stored := a.store.GetAnalyticsAggregate(scaleID)
+	if stored == nil {
+		return nil, nil
+	}
+	var agg services.AnalyticsAggregate
+	if err := json.Unmarshal(stored.Data, &agg); err != nil {
+		return nil, err
+	}
+	agg.ScaleID, agg.UpdatedAt = stored.ScaleID, stored.UpdatedAt
+	return &agg, nil
+}
+
+func (a *analyticsAggregateStoreAdapter) SaveAnalyticsAggregate(agg *services.AnalyticsAggregate) error {
+	if agg == nil {
+		return services.NewInvalidError("analytics aggregate required")
+	}
+	b, err := json.Marshal(agg)
+	if err != nil {
+		return err
+	}
+	if !a.store.SaveAnalyticsAggregate(&AnalyticsAggregate{ScaleID: agg.ScaleID, Data: b, UpdatedAt: agg.UpdatedAt}) {
+		return services.NewConflictError("unable to save analytics aggregate")
+	}
+	return nil
+}
+
+func (a *analyticsAggregateStoreAdapter) DeleteAnalyticsAggregate(scaleID string) error {
+	a.store.DeleteAnalyticsAggregate(scaleID)
+	return nil
+}
+
+func (a *analyticsAggregateStoreAdapter) ListAnalyticsAggregateScaleIDs() ([]string, error) {
+	return a.store.ListAnalyticsAggregateScaleIDs(), nil
+}
+
+// RebuildAnalyticsAggregates recomputes the analytics aggregates of the given scales (every scale
+// with a stored aggregate when none are given) and reports which ones had drifted.
+func RebuildAnalyticsAggregates(store Store, scaleIDs []string) ([]*services.AggregateCheck, error) {
+	aggregates := services.NewAnalyticsAggregates(newAnalyticsAggregateStoreAdapter(store))
+	if len(scaleIDs) == 0 {
+		return aggregates.RebuildAll()
+	}
+	out := make([]*services.AggregateCheck, 0, len(scaleIDs))
+	for _, id := range scaleIDs {
+		check, err := aggregates.Rebuild(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, check)
+	}
+	return out, nil
+}
+
+var _ services.AnalyticsAggregateStore = (*analyticsAggregateStoreAdapter)(nil)
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"time"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type apiTokenStoreAdapter struct {
+	store Store
+}
+
+func newAPITokenStoreAdapter(store Store) services.APITokenStore {
+	return &apiTokenStoreAdapter{store: store}
+}
+
+func (a *apiTokenStoreAdapter) GetScale(id string) (*services.Scale, error) {
+	sc := a.store.GetScale(id)
+	if sc == nil {
+		return nil, nil
+	}
+	return convertAPIScale(sc), nil
+}
+
+func (a *apiTokenStoreAdapter) SaveAPIToken(t *services.APIToken) error {
+	if t == nil {
+		return services.NewInvalidError("token required")
+	}
+	if !a.store.SaveAPIToken(&APIToken{ID: t.ID, TenantID: t.TenantID, UserID: t.UserID, Email: t.Email, Name: t.Name,
+		Prefix: t.Prefix, TokenHash: t.TokenHash, Scopes: t.Scopes, ScaleID: t.ScaleID, ExpiresAt: t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt, CreatedAt: t.CreatedAt, RevokedAt: t.RevokedAt}) {
+		return services.NewConflictError("unable to save api token")
+	}
+	return nil
+}
+
+func (a *apiTokenStoreAdapter) GetAPIToken(id string) (*services.APIToken, error) {
+	t := a.store.GetAPIToken(id)
+	if t == nil {
+		return nil, nil
+	}
+	return convertAPIToken(t), nil
+}
+
+func (a *apiTokenStoreAdapter) GetAPITokenByHash(hash string) (*services.APIToken, error) {
+	t := a.store.GetAPITokenByHash(hash)
+	if t == nil {
+		return nil, nil
+	}
+	return convertAPIToken(t), nil
+}
+
+func (a *apiTokenStoreAdapter) ListAPITokens(tenantID string) ([]*services.APIToken, error) {
+	list := a.store.ListAPITokens(tenantID)
+	out := make([]*services.APIToken, 0, len(list))
+	for _, t := range list {
+		out = append(out, convertAPIToken(t))
+	}
+	return out, nil
+}
+
+func (a *apiTokenStoreAdapter) TouchAPIToken(id string, at time.Time) error {
+	if !a.store.TouchAPIToken(id, at) {
+		return services.NewUnauthorizedError("invalid api token")
+	}
+	return nil
+}
+
+func (a *apiTokenStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+func convertAPIToken(t *APIToken) *services.APIToken {
+	return &services.APIToken{ID: t.ID, TenantID: t.TenantID, UserID: t.UserID, Email: t.Email, Name: t.Name,
+		Prefix: t.Prefix, TokenHash: t.TokenHash, Scopes: t.Scopes, ScaleID: t.ScaleID, ExpiresAt: t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt, CreatedAt: t.CreatedAt, RevokedAt: t.RevokedAt}
+}
+
+var _ services.APITokenStore = (*apiTokenStoreAdapter)(nil)
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type auditStoreAdapter struct {
+	store Store
+}
+
+func newAuditStoreAdapter(store Store) services.AuditStore {
+	return &auditStoreAdapter{store: store}
+}
+
+func toServiceAudit(e AuditEntry) services.AuditEntry {
+	return services.AuditEntry{Time: e.Time, Actor: e.Actor, Action: e.Action, Target: e.Target, Note: e.Note, TenantID: e.TenantID, ActorID: e.ActorID,
+		IP: e.IP, UserAgent: e.UserAgent, Result: e.Result, Seq: e.Seq, PrevHash: e.PrevHash, Hash: e.Hash}
+}
+
+func toServiceAuditQuery(q AuditQuery) services.AuditQuery {
+	return services.AuditQuery{TenantID: q.TenantID, LegacyTargets: q.LegacyTargets, Action: q.Action, Actor: q.Actor, Targets: q.Targets,
+		Source: q.Source, From: q.From, To: q.To, Before: q.Before, Limit: q.Limit}
+}
+
+// Matches reports whether e satisfies q, ignoring the cursor and limit.
+func (q AuditQuery) Matches(e AuditEntry) bool {
+	sq := toServiceAuditQuery(q)
+	return sq.Matches(toServiceAudit(e))
+}
+
+// recordAudit is how the service adapters write audit entries. The service sets the tenant of the
+// request it served; recordAudit only adds the actor's user ID when the service knew just the
+// email, and a success result.
+func recordAudit(store Store, e services.AuditEntry) {
+	if e.ActorID == "" && strings.Contains(e.Actor, "@") {
+		if actor := store.FindUserByEmail(e.Actor); actor != nil {
+			e.ActorID = actor.ID
+		}
+	}
+	if e.Result == "" {
+		e.Result = services.AuditResultSuccess
+	}
+	store.AddAudit(AuditEntry{Time: e.Time, Actor: e.Actor, Action: e.Action, Target: e.Target, Note: e.Note, TenantID: e.TenantID,
+		ActorID: e.ActorID, IP: e.IP, UserAgent: e.UserAgent, Result: e.Result})
+}
+
+// ChainAuditEntry links e to the entry before it: it stamps the time if missing, then sets Seq,
+// PrevHash and Hash. Stores call it while holding the lock that orders appends.
+func ChainAuditEntry(e *AuditEntry, seq int64, prevHash string) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.Seq, e.PrevHash = seq, prevHash
+	e.Hash = services.AuditHash(toServiceAudit(*e))
+}
+
+// SealAuditLog chains entries written before the audit log was hash-chained. It only acts while
+// no entry carries a hash, so entries slipped in later still show up as broken links.
+func SealAuditLog(entries []AuditEntry) []AuditEntry {
+	for _, e := range entries {
+		if e.Hash != "" {
+			return entries
+		}
+	}
+	prev := ""
+	for i := range entries {
+		ChainAuditEntry(&entries[i], int64(i+1), prev)
+		prev = entries[i].Hash
+	}
+	return entries
+}
+
+func (a *auditStoreAdapter) ListAuditChain(afterSeq int64, limit int) ([]services.AuditEntry, error) {
+	list := a.store.ListAuditChain(afterSeq, limit)
+	out := make([]services.AuditEntry, 0, len(list))
+	for _, e := range list {
+		out = append(out, toServiceAudit(e))
+	}
+	return out, nil
+}
+
+func (a *auditStoreAdapter) QueryAudit(q services.AuditQuery) ([]services.AuditEntry, error) {
+	list := a.store.QueryAudit(AuditQuery{TenantID: q.TenantID, LegacyTargets: q.LegacyTargets, Action: q.Action, Actor: q.Actor, Targets: q.Targets,
+		Source: q.Source, From: q.From, To: q.To, Before: q.Before, Limit: q.Limit})
+	out := make([]services.AuditEntry, 0, len(list))
+	for _, e := range list {
+		out = append(out, toServiceAudit(e))
+	}
+	return out, nil
+}
+
+func (a *auditStoreAdapter) AuditHead() (*services.AuditEntry, error) {
+	e := a.store.AuditHead()
+	if e == nil {
+		return nil, nil
+	}
+	se := toServiceAudit(*e)
+	return &se, nil
+}
+
+func (a *auditStoreAdapter) SaveAuditCheckpoint(cp *services.AuditCheckpoint) error {
+	if !a.store.AddAuditCheckpoint(AuditCheckpoint{Seq: cp.Seq, Hash: cp.Hash, CreatedAt: cp.CreatedAt, PublicKey: cp.PublicKey, Signature: cp.Signature}) {
+		return services.NewConflictError("unable to save audit checkpoint")
+	}
+	return nil
+}
+
+func (a *auditStoreAdapter) ListAuditCheckpoints() ([]*services.AuditCheckpoint, error) {
+	list := a.store.ListAuditCheckpoints()
+	out := make([]*services.AuditCheckpoint, 0, len(list))
+	for _, cp := range list {
+		out = append(out, &services.AuditCheckpoint{Seq: cp.Seq, Hash: cp.Hash, CreatedAt: cp.CreatedAt, PublicKey: cp.PublicKey, Signature: cp.Signature})
+	}
+	return out, nil
+}
+
+// newAuditService signs checkpoints only with the key from SYNAP_SIGN_SEED, since a random key is
+// gone after a restart, and trusts it plus the public keys in SYNAP_AUDIT_TRUSTED_KEYS.
+func newAuditService(store Store) *services.AuditService {
+	return services.NewAuditService(newAuditStoreAdapter(store), seededSignKey()).WithTrustedKeys(auditTrustedKeys()...)
+}
+
+// auditTrustedKeys parses SYNAP_AUDIT_TRUSTED_KEYS, a comma-separated list of base64 ed25519
+// public keys of earlier seeds.
+func auditTrustedKeys() []ed25519.PublicKey {
+	var keys []ed25519.PublicKey
+	for _, v := range strings.Split(os.Getenv("SYNAP_AUDIT_TRUSTED_KEYS"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		pub, err := base64.StdEncoding.DecodeString(v)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			log.Printf("SYNAP_AUDIT_TRUSTED_KEYS: ignoring %q, not a base64 ed25519 public key", v)
+			continue
+		}
+		keys = append(keys, ed25519.PublicKey(pub))
+	}
+	return keys
+}
+
+// VerifyAuditLog checks the audit hash chain and its checkpoints against the trusted keys.
+func VerifyAuditLog(store Store) (*services.AuditVerification, error) {
+	return newAuditService(store).Verify()
+}
+
+// ExportAuditLog writes the whole chain as a proof export, every entry in full.
+func ExportAuditLog(store Store, w io.Writer) error {
+	return newAuditService(store).Export(w, nil)
+}
+
+var _ services.AuditStore = (*auditStoreAdapter)(nil)
@@ -19,14 +19,14 @@ func (a *authStoreAdapter) FindUserByEmail(email string) (*services.User, error)
 	if u == nil {
 		return nil, nil
 	}
-	return &services.User{ID: u.ID, Email: u.Email, PassHash: u.PassHash, TenantID: u.TenantID, CreatedAt: u.CreatedAt}, nil
+	return &services.User{ID: u.ID, Email: u.Email, PassHash: u.PassHash, TenantID: u.TenantID, CreatedAt: u.CreatedAt, EmailVerifiedAt: u.EmailVerifiedAt}, nil
 }
 
 func (a *authStoreAdapter) AddUser(u *services.User) error {
 	if u == nil {
 		return services.NewInvalidError("user required")
 	}
-	a.store.AddUser(&User{ID: u.ID, Email: u.Email, PassHash: u.PassHash, TenantID: u.TenantID, CreatedAt: u.CreatedAt})
+	a.store.AddUser(&User{ID: u.ID, Email: u.Email, PassHash: u.PassHash, TenantID: u.TenantID, CreatedAt: u.CreatedAt, EmailVerifiedAt: u.EmailVerifiedAt})
 	return nil
 }
 
@@ -0,0 +1,116 @@
+package api
+
+import "github.com/soaringjerry/Synap/internal/services"
+
+type catStoreAdapter struct {
+	*irtStoreAdapter
+}
+
+func newCATStoreAdapter(store Store) services.CATStore {
+	return &catStoreAdapter{irtStoreAdapter: &irtStoreAdapter{analyticsStoreAdapter: &analyticsStoreAdapter{store: store}}}
+}
+
+func (a *catStoreAdapter) GetIRTCalibration(scaleID string, version int) (*services.IRTCalibration, error) {
+	c := a.store.GetIRTCalibration(scaleID, version)
+	if c == nil {
+		return nil, nil
+	}
+	out, err := convertAPIIRTCalibrations([]*IRTCalibration{c})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+func (a *catStoreAdapter) GetCATSettings(scaleID string) (*services.CATSettings, error) {
+	c := a.store.GetCATSettings(scaleID)
+	if c == nil {
+		return nil, nil
+	}
+	return &services.CATSettings{ScaleID: c.ScaleID, Enabled: c.Enabled, CalibrationVersion: c.CalibrationVersion, MinItems: c.MinItems, MaxItems: c.MaxItems, SETarget: c.SETarget, UpdatedAt: c.UpdatedAt}, nil
+}
+
+func (a *catStoreAdapter) SaveCATSettings(c *services.CATSettings) error {
+	if c == nil {
+		return services.NewInvalidError("settings required")
+	}
+	if !a.store.SaveCATSettings(&CATSettings{ScaleID: c.ScaleID, Enabled: c.Enabled, CalibrationVersion: c.CalibrationVersion, MinItems: c.MinItems, MaxItems: c.MaxItems, SETarget: c.SETarget, UpdatedAt: c.UpdatedAt}) {
+		return services.NewInvalidError("unable to save cat settings")
+	}
+	return nil
+}
+
+func (a *catStoreAdapter) GetCATSession(id string) (*services.CATSession, error) {
+	return convertAPICATSession(a.store.GetCATSession(id)), nil
+}
+
+func (a *catStoreAdapter) SaveCATSession(sess *services.CATSession, prevStatus string, prevSteps int) (bool, error) {
+	if sess == nil {
+		return false, services.NewInvalidError("session required")
+	}
+	steps := make([]CATStep, 0, len(sess.Steps))
+	for _, st := range sess.Steps {
+		steps = append(steps, CATStep{ItemID: st.ItemID, Raw: st.Raw, Score: st.Score, Information: st.Information, Theta: st.Theta, SE: st.SE, AnsweredAt: st.AnsweredAt})
+	}
+	return a.store.SaveCATSession(&CATSession{
+		ID:                 sess.ID,
+		ScaleID:            sess.ScaleID,
+		TokenHash:          sess.TokenHash,
+		CalibrationVersion: sess.CalibrationVersion,
+		Status:             sess.Status,
+		StopReason:         sess.StopReason,
+		NextItemID:         sess.NextItemID,
+		NextInformation:    sess.NextInformation,
+		Steps:              steps,
+		Theta:              sess.Theta,
+		SE:                 sess.SE,
+		ParticipantID:      sess.ParticipantID,
+		ParticipantEmail:   sess.ParticipantEmail,
+		ConsentID:          sess.ConsentID,
+		Condition:          sess.Condition,
+		CreatedAt:          sess.CreatedAt,
+		UpdatedAt:          sess.UpdatedAt,
+		FinishedAt:         sess.FinishedAt,
+	}, prevStatus, prevSteps), nil
+}
+
+func (a *catStoreAdapter) ListCATSessions(scaleID string) ([]*services.CATSession, error) {
+	sessions := a.store.ListCATSessions(scaleID)
+	out := make([]*services.CATSession, 0, len(sessions))
+	for _, sess := range sessions {
+		out = append(out, convertAPICATSession(sess))
+	}
+	return out, nil
+}
+
+func convertAPICATSession(sess *CATSession) *services.CATSession {
+	if sess == nil {
+		return nil
+	}
+	steps := make([]services.CATStep, 0, len(sess.Steps))
+	for _, st := range sess.Steps {
+		steps = append(steps, services.CATStep{ItemID: st.ItemID, Raw: st.Raw, Score: st.Score, Information: st.Information, Theta: st.Theta, SE: st.SE, AnsweredAt: st.AnsweredAt})
+	}
+	return &services.CATSession{
+		ID:                 sess.ID,
+		ScaleID:            sess.ScaleID,
+		TokenHash:          sess.TokenHash,
+		CalibrationVersion: sess.CalibrationVersion,
+		Status:             sess.Status,
+		StopReason:         sess.StopReason,
+		NextItemID:         sess.NextItemID,
+		NextInformation:    sess.NextInformation,
+		Steps:              steps,
+		Theta:              sess.Theta,
+		SE:                 sess.SE,
+		ParticipantID:      sess.ParticipantID,
+		ParticipantEmail:   sess.ParticipantEmail,
+		ConsentID:          sess.ConsentID,
+		Condition:          sess.Condition,
+		CreatedAt:          sess.CreatedAt,
+		UpdatedAt:          sess.UpdatedAt,
+		FinishedAt:         sess.FinishedAt,
+	}
+}
+
+var _ services.CATStore = (*catStoreAdapter)(nil)
@@ -29,7 +29,7 @@ func (a *consentStoreAdapter) AddConsentRecord(cr *services.ConsentRecord) error
 }
 
 func (a *consentStoreAdapter) AddAudit(entry services.AuditEntry) {
-	a.store.AddAudit(AuditEntry{Time: entry.Time, Actor: entry.Actor, Action: entry.Action, Target: entry.Target, Note: entry.Note})
+	recordAudit(a.store, entry)
 }
 
 var _ services.ConsentStore = (*consentStoreAdapter)(nil)
@@ -0,0 +1,106 @@
+package api
+
+import "github.com/soaringjerry/Synap/internal/services"
+
+type doubleEntryStoreAdapter struct {
+	store Store
+}
+
+func newDoubleEntryStoreAdapter(store Store) services.DoubleEntryStore {
+	return &doubleEntryStoreAdapter{store: store}
+}
+
+func (a *doubleEntryStoreAdapter) GetScale(id string) (*services.Scale, error) {
+	return convertAPIScale(a.store.GetScale(id)), nil
+}
+
+func (a *doubleEntryStoreAdapter) ListItems(scaleID string) ([]*services.Item, error) {
+	items := a.store.ListItems(scaleID)
+	out := make([]*services.Item, 0, len(items))
+	for _, it := range items {
+		out = append(out, convertAPIItem(it))
+	}
+	return out, nil
+}
+
+func (a *doubleEntryStoreAdapter) GetDataEntryForm(scaleID, formID string) (*services.DataEntryForm, error) {
+	return convertAPIDataEntryForm(a.store.GetDataEntryForm(scaleID, formID)), nil
+}
+
+func (a *doubleEntryStoreAdapter) SaveDataEntryForm(f *services.DataEntryForm, prevStatus string) (bool, error) {
+	if f == nil {
+		return false, services.NewInvalidError("form required")
+	}
+	return a.store.SaveDataEntryForm(convertServiceDataEntryForm(f), prevStatus), nil
+}
+
+func (a *doubleEntryStoreAdapter) CommitDataEntryForm(f *services.DataEntryForm, prevStatus string, p *services.Participant, rs []*services.Response) (bool, error) {
+	if f == nil || p == nil {
+		return false, services.NewInvalidError("form required")
+	}
+	out := make([]*Response, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, &Response{ParticipantID: r.ParticipantID, ItemID: r.ItemID, RawValue: r.RawValue, ScoreValue: r.ScoreValue, SubmittedAt: r.SubmittedAt, RawJSON: r.RawJSON})
+	}
+	return a.store.CommitDataEntryForm(convertServiceDataEntryForm(f), prevStatus, &Participant{ID: p.ID, Email: p.Email, ConsentID: p.ConsentID}, out), nil
+}
+
+func convertServiceDataEntryForm(f *services.DataEntryForm) *DataEntryForm {
+	mismatches := make([]DataEntryMismatch, 0, len(f.Mismatches))
+	for _, m := range f.Mismatches {
+		mismatches = append(mismatches, DataEntryMismatch{ItemID: m.ItemID, First: m.First, Second: m.Second, Resolved: m.Resolved})
+	}
+	return &DataEntryForm{
+		ScaleID:       f.ScaleID,
+		FormID:        f.FormID,
+		FirstEntry:    f.FirstEntry,
+		FirstBy:       f.FirstBy,
+		SecondEntry:   f.SecondEntry,
+		SecondBy:      f.SecondBy,
+		Status:        f.Status,
+		Mismatches:    mismatches,
+		ResolvedBy:    f.ResolvedBy,
+		ParticipantID: f.ParticipantID,
+		CreatedAt:     f.CreatedAt,
+		UpdatedAt:     f.UpdatedAt,
+	}
+}
+
+func (a *doubleEntryStoreAdapter) ListDataEntryForms(scaleID string) ([]*services.DataEntryForm, error) {
+	forms := a.store.ListDataEntryForms(scaleID)
+	out := make([]*services.DataEntryForm, 0, len(forms))
+	for _, f := range forms {
+		out = append(out, convertAPIDataEntryForm(f))
+	}
+	return out, nil
+}
+
+func (a *doubleEntryStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+func convertAPIDataEntryForm(f *DataEntryForm) *services.DataEntryForm {
+	if f == nil {
+		return nil
+	}
+	mismatches := make([]services.DataEntryMismatch, 0, len(f.Mismatches))
+	for _, m := range f.Mismatches {
+		mismatches = append(mismatches, services.DataEntryMismatch{ItemID: m.ItemID, First: m.First, Second: m.Second, Resolved: m.Resolved})
+	}
+	return &services.DataEntryForm{
+		ScaleID:       f.ScaleID,
+		FormID:        f.FormID,
+		FirstEntry:    f.FirstEntry,
+		FirstBy:       f.FirstBy,
+		SecondEntry:   f.SecondEntry,
+		SecondBy:      f.SecondBy,
+		Status:        f.Status,
+		Mismatches:    mismatches,
+		ResolvedBy:    f.ResolvedBy,
+		ParticipantID: f.ParticipantID,
+		CreatedAt:     f.CreatedAt,
+		UpdatedAt:     f.UpdatedAt,
+	}
+}
+
+var _ services.DoubleEntryStore = (*doubleEntryStoreAdapter)(nil)
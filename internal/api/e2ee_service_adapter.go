@@ -79,7 +79,7 @@ func (a *e2eeStoreAdapter) FindRecentExportJob(tid, scaleID, ip string, within t
 }
 
 func (a *e2eeStoreAdapter) AddAudit(entry services.AuditEntry) {
-	a.store.AddAudit(AuditEntry{Time: entry.Time, Actor: entry.Actor, Action: entry.Action, Target: entry.Target, Note: entry.Note})
+	recordAudit(a.store, entry)
 }
 
 var _ services.E2EEStore = (*e2eeStoreAdapter)(nil)
@@ -48,4 +48,12 @@ func (a *exportStoreAdapter) GetConsentByID(id string) (*services.ConsentRecord,
 	return &services.ConsentRecord{ID: c.ID, ScaleID: c.ScaleID, Choices: c.Choices, SignedAt: c.SignedAt}, nil
 }
 
+func (a *exportStoreAdapter) ListIRTCalibrations(scaleID string) ([]*services.IRTCalibration, error) {
+	return convertAPIIRTCalibrations(a.store.ListIRTCalibrations(scaleID))
+}
+
+func (a *exportStoreAdapter) ListNormTables(scaleID string) ([]*services.NormTable, error) {
+	return convertAPINormTables(a.store.ListNormTables(scaleID))
+}
+
 var _ services.ExportStore = (*exportStoreAdapter)(nil)
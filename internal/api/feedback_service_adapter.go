@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type feedbackStoreAdapter struct {
+	*normStoreAdapter
+	participants *participantStoreAdapter
+}
+
+func newFeedbackStoreAdapter(store Store) services.FeedbackStore {
+	return &feedbackStoreAdapter{
+		normStoreAdapter: &normStoreAdapter{analyticsStoreAdapter: &analyticsStoreAdapter{store: store}},
+		participants:     &participantStoreAdapter{store: store},
+	}
+}
+
+func (a *feedbackStoreAdapter) GetItem(id string) (*services.Item, error) {
+	it := a.store.GetItem(id)
+	if it == nil {
+		return nil, nil
+	}
+	return &services.Item{ID: it.ID, ScaleID: it.ScaleID, Type: it.Type, ReverseScored: it.ReverseScored}, nil
+}
+
+func (a *feedbackStoreAdapter) GetParticipant(id string) (*services.Participant, error) {
+	return a.participants.GetParticipant(id)
+}
+
+func (a *feedbackStoreAdapter) ListResponsesByParticipant(id string) ([]*services.Response, error) {
+	return a.participants.ListResponsesByParticipant(id)
+}
+
+func (a *feedbackStoreAdapter) GetFeedbackConfig(scaleID string) (*services.FeedbackConfig, error) {
+	c := a.store.GetFeedbackConfig(scaleID)
+	if c == nil {
+		return nil, nil
+	}
+	var cfg services.FeedbackConfig
+	if err := json.Unmarshal(c.Config, &cfg); err != nil {
+		return nil, err
+	}
+	cfg.ScaleID, cfg.Enabled, cfg.UpdatedAt = c.ScaleID, c.Enabled, c.UpdatedAt
+	return &cfg, nil
+}
+
+func (a *feedbackStoreAdapter) SaveFeedbackConfig(c *services.FeedbackConfig) error {
+	if c == nil {
+		return services.NewInvalidError("feedback config required")
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if !a.store.SaveFeedbackConfig(&FeedbackConfig{ScaleID: c.ScaleID, Enabled: c.Enabled, Config: b, UpdatedAt: c.UpdatedAt}) {
+		return services.NewConflictError("unable to save feedback config")
+	}
+	return nil
+}
+
+var _ services.FeedbackStore = (*feedbackStoreAdapter)(nil)
@@ -0,0 +1,78 @@
+package api
+
+import "github.com/soaringjerry/Synap/internal/services"
+
+type inviteStoreAdapter struct {
+	members services.MembershipStore
+	store   Store
+}
+
+func newInviteStoreAdapter(store Store) services.InviteStore {
+	return &inviteStoreAdapter{members: newMembershipStoreAdapter(store), store: store}
+}
+
+func toServiceInvite(inv *Invite) *services.Invite {
+	return &services.Invite{ID: inv.ID, TenantID: inv.TenantID, ScaleID: inv.ScaleID, Email: inv.Email, Role: inv.Role, TokenHash: inv.TokenHash,
+		InvitedBy: inv.InvitedBy, CreatedAt: inv.CreatedAt, ExpiresAt: inv.ExpiresAt, AcceptedAt: inv.AcceptedAt, AcceptedBy: inv.AcceptedBy, RevokedAt: inv.RevokedAt}
+}
+
+func (a *inviteStoreAdapter) SaveInvite(inv *services.Invite) error {
+	if inv == nil {
+		return services.NewInvalidError("invite required")
+	}
+	if !a.store.SaveInvite(&Invite{ID: inv.ID, TenantID: inv.TenantID, ScaleID: inv.ScaleID, Email: inv.Email, Role: inv.Role, TokenHash: inv.TokenHash,
+		InvitedBy: inv.InvitedBy, CreatedAt: inv.CreatedAt, ExpiresAt: inv.ExpiresAt, AcceptedAt: inv.AcceptedAt, AcceptedBy: inv.AcceptedBy, RevokedAt: inv.RevokedAt}) {
+		return services.NewConflictError("unable to save invite")
+	}
+	return nil
+}
+
+func (a *inviteStoreAdapter) GetInvite(id string) (*services.Invite, error) {
+	inv := a.store.GetInvite(id)
+	if inv == nil {
+		return nil, nil
+	}
+	return toServiceInvite(inv), nil
+}
+
+func (a *inviteStoreAdapter) GetInviteByHash(hash string) (*services.Invite, error) {
+	inv := a.store.GetInviteByHash(hash)
+	if inv == nil {
+		return nil, nil
+	}
+	return toServiceInvite(inv), nil
+}
+
+func (a *inviteStoreAdapter) ListInvites(tenantID string) ([]*services.Invite, error) {
+	list := a.store.ListInvites(tenantID)
+	out := make([]*services.Invite, 0, len(list))
+	for _, inv := range list {
+		out = append(out, toServiceInvite(inv))
+	}
+	return out, nil
+}
+
+func (a *inviteStoreAdapter) GetScale(id string) (*services.Scale, error) {
+	return convertAPIScale(a.store.GetScale(id)), nil
+}
+
+func (a *inviteStoreAdapter) GetTenant(id string) (*services.Tenant, error) {
+	return a.members.GetTenant(id)
+}
+
+func (a *inviteStoreAdapter) FindUserByEmail(email string) (*services.User, error) {
+	return a.members.FindUserByEmail(email)
+}
+
+func (a *inviteStoreAdapter) AddScaleCollaborator(scaleID, userID, role string) error {
+	if !a.store.AddScaleCollaborator(scaleID, userID, role) {
+		return services.NewInvalidError("unable to add collaborator")
+	}
+	return nil
+}
+
+func (a *inviteStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+var _ services.InviteStore = (*inviteStoreAdapter)(nil)
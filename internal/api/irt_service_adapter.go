@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type irtStoreAdapter struct {
+	*analyticsStoreAdapter
+}
+
+func newIRTStoreAdapter(store Store) services.IRTStore {
+	return &irtStoreAdapter{analyticsStoreAdapter: &analyticsStoreAdapter{store: store}}
+}
+
+func (a *irtStoreAdapter) SaveIRTCalibration(c *services.IRTCalibration) error {
+	if c == nil {
+		return services.NewInvalidError("calibration required")
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	ok := a.store.SaveIRTCalibration(&IRTCalibration{
+		ScaleID:          c.ScaleID,
+		Version:          c.Version,
+		ScaleFingerprint: c.ScaleFingerprint,
+		Model:            c.Model,
+		Result:           b,
+		CreatedAt:        c.CreatedAt,
+	})
+	if !ok {
+		return services.NewConflictError("unable to save calibration")
+	}
+	return nil
+}
+
+func (a *irtStoreAdapter) ListIRTCalibrations(scaleID string) ([]*services.IRTCalibration, error) {
+	return convertAPIIRTCalibrations(a.store.ListIRTCalibrations(scaleID))
+}
+
+func convertAPIIRTCalibrations(cals []*IRTCalibration) ([]*services.IRTCalibration, error) {
+	out := make([]*services.IRTCalibration, 0, len(cals))
+	for _, c := range cals {
+		var cal services.IRTCalibration
+		if err := json.Unmarshal(c.Result, &cal); err != nil {
+			return nil, err
+		}
+		cal.ScaleID, cal.Version, cal.ScaleFingerprint, cal.Model, cal.CreatedAt = c.ScaleID, c.Version, c.ScaleFingerprint, c.Model, c.CreatedAt
+		out = append(out, &cal)
+	}
+	return out, nil
+}
+
+var _ services.IRTStore = (*irtStoreAdapter)(nil)
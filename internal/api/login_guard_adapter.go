@@ -0,0 +1,52 @@
+package api
+
+import "github.com/soaringjerry/Synap/internal/services"
+
+type loginGuardStoreAdapter struct {
+	auth  services.AuthStore
+	store Store
+}
+
+func newLoginGuardStoreAdapter(store Store) services.LoginGuardStore {
+	return &loginGuardStoreAdapter{auth: newAuthStoreAdapter(store), store: store}
+}
+
+func toServiceLoginThrottle(t *LoginThrottle) *services.LoginThrottle {
+	return &services.LoginThrottle{Key: t.Key, TenantID: t.TenantID, Failures: t.Failures, LastFailureAt: t.LastFailureAt, LockedUntil: t.LockedUntil}
+}
+
+func (a *loginGuardStoreAdapter) GetLoginThrottle(key string) (*services.LoginThrottle, error) {
+	t := a.store.GetLoginThrottle(key)
+	if t == nil {
+		return nil, nil
+	}
+	return toServiceLoginThrottle(t), nil
+}
+
+func (a *loginGuardStoreAdapter) SaveLoginThrottle(t *services.LoginThrottle) error {
+	if t == nil {
+		return services.NewInvalidError("throttle required")
+	}
+	if !a.store.SaveLoginThrottle(&LoginThrottle{Key: t.Key, TenantID: t.TenantID, Failures: t.Failures, LastFailureAt: t.LastFailureAt, LockedUntil: t.LockedUntil}) {
+		return services.NewConflictError("unable to save login throttle")
+	}
+	return nil
+}
+
+func (a *loginGuardStoreAdapter) DeleteLoginThrottle(key string) (bool, error) {
+	return a.store.DeleteLoginThrottle(key), nil
+}
+
+func (a *loginGuardStoreAdapter) ListTenantMembers(tenantID string) ([]*services.Membership, error) {
+	return toServiceMemberships(a.store.ListTenantMembers(tenantID)), nil
+}
+
+func (a *loginGuardStoreAdapter) FindUserByEmail(email string) (*services.User, error) {
+	return a.auth.FindUserByEmail(email)
+}
+
+func (a *loginGuardStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+var _ services.LoginGuardStore = (*loginGuardStoreAdapter)(nil)
@@ -0,0 +1,90 @@
+package api
+
+import "github.com/soaringjerry/Synap/internal/services"
+
+type membershipStoreAdapter struct {
+	auth  services.AuthStore
+	store Store
+}
+
+func newMembershipStoreAdapter(store Store) services.MembershipStore {
+	return &membershipStoreAdapter{auth: newAuthStoreAdapter(store), store: store}
+}
+
+func toServiceMembership(m *TenantMember) *services.Membership {
+	return &services.Membership{TenantID: m.TenantID, TenantName: m.TenantName, UserID: m.UserID, Email: m.Email, Role: m.Role, CreatedAt: m.CreatedAt}
+}
+
+func toServiceMemberships(list []*TenantMember) []*services.Membership {
+	out := make([]*services.Membership, 0, len(list))
+	for _, m := range list {
+		out = append(out, toServiceMembership(m))
+	}
+	return out
+}
+
+func (a *membershipStoreAdapter) GetMembership(tenantID, userID string) (*services.Membership, error) {
+	m := a.store.GetTenantMember(tenantID, userID)
+	if m == nil {
+		return nil, nil
+	}
+	return toServiceMembership(m), nil
+}
+
+func (a *membershipStoreAdapter) ListTenantMembers(tenantID string) ([]*services.Membership, error) {
+	return toServiceMemberships(a.store.ListTenantMembers(tenantID)), nil
+}
+
+func (a *membershipStoreAdapter) ListUserMemberships(userID string) ([]*services.Membership, error) {
+	return toServiceMemberships(a.store.ListUserTenants(userID)), nil
+}
+
+func (a *membershipStoreAdapter) SaveMembership(m *services.Membership) error {
+	if m == nil {
+		return services.NewInvalidError("membership required")
+	}
+	if !a.store.SaveTenantMember(&TenantMember{TenantID: m.TenantID, UserID: m.UserID, Role: m.Role, CreatedAt: m.CreatedAt}) {
+		return services.NewConflictError("unable to save membership")
+	}
+	return nil
+}
+
+func (a *membershipStoreAdapter) DeleteMembership(tenantID, userID string) (bool, error) {
+	return a.store.DeleteTenantMember(tenantID, userID), nil
+}
+
+func (a *membershipStoreAdapter) GetTenant(id string) (*services.Tenant, error) {
+	t := a.store.GetTenant(id)
+	if t == nil {
+		return nil, nil
+	}
+	return &services.Tenant{ID: t.ID, Name: t.Name}, nil
+}
+
+func (a *membershipStoreAdapter) AddTenant(t *services.Tenant) error {
+	return a.auth.AddTenant(t)
+}
+
+func (a *membershipStoreAdapter) FindUserByEmail(email string) (*services.User, error) {
+	return a.auth.FindUserByEmail(email)
+}
+
+func (a *membershipStoreAdapter) SetUserTenant(userID, tenantID string) error {
+	if !a.store.SetUserTenant(userID, tenantID) {
+		return services.NewNotFoundError("user not found")
+	}
+	return nil
+}
+
+func (a *membershipStoreAdapter) RemoveTenantCollaborations(tenantID, userID string) error {
+	if !a.store.RemoveTenantCollaborations(tenantID, userID) {
+		return services.NewConflictError("unable to remove collaborations")
+	}
+	return nil
+}
+
+func (a *membershipStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+var _ services.MembershipStore = (*membershipStoreAdapter)(nil)
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type mfaStoreAdapter struct {
+	store Store
+}
+
+func newMFAStoreAdapter(store Store) services.MFAStore {
+	return &mfaStoreAdapter{store: store}
+}
+
+func (a *mfaStoreAdapter) GetMFA(userID string) (*services.MFAConfig, error) {
+	m := a.store.GetMFA(userID)
+	if m == nil {
+		return nil, nil
+	}
+	return &services.MFAConfig{UserID: m.UserID, Secret: m.Secret, Enabled: m.Enabled, RecoveryHashes: m.RecoveryHashes,
+		LastStep: m.LastStep, CreatedAt: m.CreatedAt, EnabledAt: m.EnabledAt}, nil
+}
+
+func (a *mfaStoreAdapter) SaveMFA(m *services.MFAConfig) error {
+	if m == nil {
+		return services.NewInvalidError("enrollment required")
+	}
+	if !a.store.SaveMFA(&MFAConfig{UserID: m.UserID, Secret: m.Secret, Enabled: m.Enabled, RecoveryHashes: m.RecoveryHashes,
+		LastStep: m.LastStep, CreatedAt: m.CreatedAt, EnabledAt: m.EnabledAt}) {
+		return services.NewConflictError("unable to save enrollment")
+	}
+	return nil
+}
+
+func (a *mfaStoreAdapter) DeleteMFA(userID string) error {
+	a.store.DeleteMFA(userID)
+	return nil
+}
+
+func (a *mfaStoreAdapter) GetLoginThrottle(key string) (*services.LoginThrottle, error) {
+	return newLoginGuardStoreAdapter(a.store).GetLoginThrottle(key)
+}
+
+func (a *mfaStoreAdapter) SaveLoginThrottle(t *services.LoginThrottle) error {
+	return newLoginGuardStoreAdapter(a.store).SaveLoginThrottle(t)
+}
+
+func (a *mfaStoreAdapter) DeleteLoginThrottle(key string) (bool, error) {
+	return newLoginGuardStoreAdapter(a.store).DeleteLoginThrottle(key)
+}
+
+func (a *mfaStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+var _ services.MFAStore = (*mfaStoreAdapter)(nil)
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type normStoreAdapter struct {
+	*analyticsStoreAdapter
+}
+
+func newNormStoreAdapter(store Store) services.NormStore {
+	return &normStoreAdapter{analyticsStoreAdapter: &analyticsStoreAdapter{store: store}}
+}
+
+func (a *normStoreAdapter) ListNormTables(scaleID string) ([]*services.NormTable, error) {
+	return convertAPINormTables(a.store.ListNormTables(scaleID))
+}
+
+func (a *normStoreAdapter) SaveNormTable(t *services.NormTable) error {
+	if t == nil {
+		return services.NewInvalidError("norm table required")
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if !a.store.SaveNormTable(&NormTable{ScaleID: t.ScaleID, Key: t.Key, Definition: b, UpdatedAt: t.UpdatedAt}) {
+		return services.NewConflictError("unable to save norm table")
+	}
+	return nil
+}
+
+func (a *normStoreAdapter) DeleteNormTable(scaleID, key string) (bool, error) {
+	return a.store.DeleteNormTable(scaleID, key), nil
+}
+
+func convertAPINormTables(tables []*NormTable) ([]*services.NormTable, error) {
+	out := make([]*services.NormTable, 0, len(tables))
+	for _, t := range tables {
+		var nt services.NormTable
+		if err := json.Unmarshal(t.Definition, &nt); err != nil {
+			return nil, err
+		}
+		nt.ScaleID, nt.Key, nt.UpdatedAt = t.ScaleID, t.Key, t.UpdatedAt
+		out = append(out, &nt)
+	}
+	return out, nil
+}
+
+var _ services.NormStore = (*normStoreAdapter)(nil)
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"time"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type oidcStoreAdapter struct {
+	auth  services.AuthStore
+	store Store
+}
+
+func newOIDCStoreAdapter(store Store) services.OIDCStore {
+	return &oidcStoreAdapter{auth: newAuthStoreAdapter(store), store: store}
+}
+
+func toServiceOIDCConfig(c *OIDCConfig) *services.OIDCConfig {
+	return &services.OIDCConfig{TenantID: c.TenantID, Enabled: c.Enabled, Issuer: c.Issuer, ClientID: c.ClientID, ClientSecret: c.ClientSecret,
+		Scopes: c.Scopes, AllowedDomains: c.AllowedDomains, RoleClaim: c.RoleClaim, RoleMap: c.RoleMap, DefaultRole: c.DefaultRole, UpdatedAt: c.UpdatedAt}
+}
+
+func (a *oidcStoreAdapter) GetOIDCConfig(tenantID string) (*services.OIDCConfig, error) {
+	c := a.store.GetOIDCConfig(tenantID)
+	if c == nil {
+		return nil, nil
+	}
+	return toServiceOIDCConfig(c), nil
+}
+
+func (a *oidcStoreAdapter) ListOIDCConfigs() ([]*services.OIDCConfig, error) {
+	list := a.store.ListOIDCConfigs()
+	out := make([]*services.OIDCConfig, 0, len(list))
+	for _, c := range list {
+		out = append(out, toServiceOIDCConfig(c))
+	}
+	return out, nil
+}
+
+func (a *oidcStoreAdapter) SaveOIDCConfig(c *services.OIDCConfig) error {
+	if c == nil {
+		return services.NewInvalidError("config required")
+	}
+	if !a.store.SaveOIDCConfig(&OIDCConfig{TenantID: c.TenantID, Enabled: c.Enabled, Issuer: c.Issuer, ClientID: c.ClientID, ClientSecret: c.ClientSecret,
+		Scopes: c.Scopes, AllowedDomains: c.AllowedDomains, RoleClaim: c.RoleClaim, RoleMap: c.RoleMap, DefaultRole: c.DefaultRole, UpdatedAt: c.UpdatedAt}) {
+		return services.NewConflictError("unable to save oidc config")
+	}
+	return nil
+}
+
+func (a *oidcStoreAdapter) DeleteOIDCConfig(tenantID string) (bool, error) {
+	return a.store.DeleteOIDCConfig(tenantID), nil
+}
+
+func (a *oidcStoreAdapter) GetOIDCIdentity(issuer, subject string) (*services.OIDCIdentity, error) {
+	id := a.store.GetOIDCIdentity(issuer, subject)
+	if id == nil {
+		return nil, nil
+	}
+	return &services.OIDCIdentity{Issuer: id.Issuer, Subject: id.Subject, UserID: id.UserID, TenantID: id.TenantID, Email: id.Email,
+		Role: id.Role, CreatedAt: id.CreatedAt, LastLoginAt: id.LastLoginAt}, nil
+}
+
+func (a *oidcStoreAdapter) SaveOIDCIdentity(id *services.OIDCIdentity) error {
+	if id == nil {
+		return services.NewInvalidError("identity required")
+	}
+	if !a.store.SaveOIDCIdentity(&OIDCIdentity{Issuer: id.Issuer, Subject: id.Subject, UserID: id.UserID, TenantID: id.TenantID, Email: id.Email,
+		Role: id.Role, CreatedAt: id.CreatedAt, LastLoginAt: id.LastLoginAt}) {
+		return services.NewConflictError("unable to link identity")
+	}
+	return nil
+}
+
+func (a *oidcStoreAdapter) FindUserByEmail(email string) (*services.User, error) {
+	return a.auth.FindUserByEmail(email)
+}
+
+func (a *oidcStoreAdapter) AddUser(u *services.User) error {
+	return a.auth.AddUser(u)
+}
+
+func (a *oidcStoreAdapter) SetEmailVerified(userID string, at time.Time) error {
+	if !a.store.SetEmailVerified(userID, at) {
+		return services.NewNotFoundError("user not found")
+	}
+	return nil
+}
+
+func (a *oidcStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+var _ services.OIDCStore = (*oidcStoreAdapter)(nil)
@@ -35,6 +35,22 @@ func (a *participantStoreAdapter) ListResponsesByParticipant(id string) ([]*serv
 	return out, nil
 }
 
+func (a *participantStoreAdapter) GetItem(id string) (*services.Item, error) {
+	it := a.store.GetItem(id)
+	if it == nil {
+		return nil, nil
+	}
+	return convertAPIItem(it), nil
+}
+
+func (a *participantStoreAdapter) GetScale(id string) (*services.Scale, error) {
+	sc := a.store.GetScale(id)
+	if sc == nil {
+		return nil, nil
+	}
+	return convertAPIScale(sc), nil
+}
+
 func (a *participantStoreAdapter) DeleteParticipantByID(id string, hard bool) (bool, error) {
 	return a.store.DeleteParticipantByID(id, hard), nil
 }
@@ -52,7 +68,7 @@ func (a *participantStoreAdapter) DeleteE2EEResponse(id string) (bool, error) {
 }
 
 func (a *participantStoreAdapter) AddAudit(entry services.AuditEntry) {
-	a.store.AddAudit(AuditEntry{Time: entry.Time, Actor: entry.Actor, Action: entry.Action, Target: entry.Target, Note: entry.Note})
+	recordAudit(a.store, entry)
 }
 
 var _ services.ParticipantStore = (*participantStoreAdapter)(nil)
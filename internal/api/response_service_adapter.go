@@ -44,9 +44,9 @@ func (a *responseStoreAdapter) GetConsentByID(id string) *services.ConsentRecord
 }
 
 func (a *responseStoreAdapter) AddParticipant(p *services.Participant) (*services.Participant, error) {
-	ap := &Participant{ID: p.ID, Email: p.Email, ConsentID: p.ConsentID}
+	ap := &Participant{ID: p.ID, Email: p.Email, ConsentID: p.ConsentID, Condition: p.Condition}
 	a.store.AddParticipant(ap)
-	return &services.Participant{ID: ap.ID, Email: ap.Email, ConsentID: ap.ConsentID, SelfToken: ap.SelfToken}, nil
+	return &services.Participant{ID: ap.ID, Email: ap.Email, ConsentID: ap.ConsentID, SelfToken: ap.SelfToken, Condition: ap.Condition}, nil
 }
 
 func (a *responseStoreAdapter) AddResponses(rs []*services.Response) error {
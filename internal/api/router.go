@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/base64"
@@ -10,7 +11,9 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +35,44 @@ type Router struct {
 	analyticsSvc   *services.AnalyticsService
 	consentSvc     *services.ConsentService
 	teamSvc        *services.TeamService
+	doubleEntrySvc *services.DoubleEntryService
+	efaSvc         *services.EFAService
+	irtSvc         *services.IRTService
+	catSvc         *services.CATService
+	normSvc        *services.NormService
+	feedbackSvc    *services.FeedbackService
+	webhookSvc     *services.WebhookService
+	apiTokenSvc    *services.APITokenService
+	sessionSvc     *services.SessionService
+	mfaSvc         *services.MFAService
+	oidcSvc        *services.OIDCService
+	accountSvc     *services.AccountService
+	loginGuard     *services.LoginGuard
+	memberSvc      *services.MembershipService
+	inviteSvc      *services.InviteService
+	auditSvc       *services.AuditService
+	limits         rateLimits
+	events         *services.EventBus
+}
+
+// rateLimits are per-IP request limits for public endpoints that are expensive (bcrypt, mail) or
+// can be scripted to flood the database.
+type rateLimits struct {
+	login    *middleware.RateLimiter // password sign-in, on top of the per-account LoginGuard
+	register *middleware.RateLimiter // every registration creates a tenant
+	mail     *middleware.RateLimiter // password reset mail
+	token    *middleware.RateLimiter // reset / verification tokens and step-up codes
+	submit   *middleware.RateLimiter // participant submissions and consent signatures
+}
+
+func newRateLimits() rateLimits {
+	return rateLimits{
+		login:    middleware.NewRateLimiter(30, time.Minute),
+		register: middleware.NewRateLimiter(10, time.Hour),
+		mail:     middleware.NewRateLimiter(5, 15*time.Minute),
+		token:    middleware.NewRateLimiter(20, time.Minute),
+		submit:   middleware.NewRateLimiter(60, time.Minute),
+	}
 }
 
 func NewRouterWithStore(store Store) *Router {
@@ -56,12 +97,74 @@ func NewRouterWithStore(store Store) *Router {
 			return base64.StdEncoding.EncodeToString(ed25519.Sign(ert.signPriv, data)), nil
 		})
 	}
+	ert.auditSvc = newAuditService(store)
 	ert.analyticsSvc = services.NewAnalyticsService(newAnalyticsStoreAdapter(store))
+	aggregates := services.NewAnalyticsAggregates(newAnalyticsAggregateStoreAdapter(store))
+	ert.analyticsSvc.WithAggregates(aggregates)
+	ert.responseSvc.WithAggregates(aggregates)
+	ert.scaleSvc.WithAggregates(aggregates)
+	ert.participantSvc.WithAggregates(aggregates)
 	ert.consentSvc = services.NewConsentService(newConsentStoreAdapter(store))
 	ert.teamSvc = services.NewTeamService(newTeamStoreAdapter(store))
+	ert.doubleEntrySvc = services.NewDoubleEntryService(newDoubleEntryStoreAdapter(store))
+	ert.doubleEntrySvc.WithAggregates(aggregates)
+	ert.efaSvc = services.NewEFAService(newAnalyticsStoreAdapter(store))
+	ert.irtSvc = services.NewIRTService(newIRTStoreAdapter(store))
+	ert.catSvc = services.NewCATService(newCATStoreAdapter(store), ert.responseSvc)
+	ert.normSvc = services.NewNormService(newNormStoreAdapter(store))
+	ert.feedbackSvc = services.NewFeedbackService(newFeedbackStoreAdapter(store))
+	ert.events = services.NewEventBus()
+	ert.responseSvc.WithEvents(ert.events)
+	ert.e2eeSvc.WithEvents(ert.events)
+	ert.consentSvc.WithEvents(ert.events)
+	ert.participantSvc.WithEvents(ert.events)
+	ert.exportSvc.WithEvents(ert.events)
+	// SYNAP_WEBHOOK_ALLOW_PRIVATE lets webhooks reach loopback and private receivers (development only)
+	allowPrivate, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv("SYNAP_WEBHOOK_ALLOW_PRIVATE")))
+	ert.webhookSvc = services.NewWebhookService(newWebhookStoreAdapter(store), services.NewWebhookClient(allowPrivate))
+	ert.events.Observe(func(ev services.Event) {
+		if err := ert.webhookSvc.Handle(ev); err != nil {
+			log.Printf("queue webhooks for %s on %s: %v", ev.Type, ev.ScaleID, err)
+		}
+	})
+	ert.apiTokenSvc = services.NewAPITokenService(newAPITokenStoreAdapter(store))
+	ert.sessionSvc = services.NewSessionService(newSessionStoreAdapter(store), middleware.SignSessionToken)
+	ert.authSvc.WithSessions(ert.sessionSvc)
+	ert.mfaSvc = services.NewMFAService(newMFAStoreAdapter(store), middleware.SignStepUpToken)
+	// SYNAP_OIDC_ALLOW_PRIVATE lets identity providers live on loopback and private networks (development only)
+	allowPrivateIdP, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv("SYNAP_OIDC_ALLOW_PRIVATE")))
+	ert.oidcSvc = services.NewOIDCService(newOIDCStoreAdapter(store), ert.authSvc, services.NewOIDCClient(allowPrivateIdP))
+	if allowPrivateIdP {
+		ert.oidcSvc.WithLocalProviders()
+	}
+	mailer := mailerFromEnv()
+	ert.accountSvc = services.NewAccountService(newAccountStoreAdapter(store), mailer)
+	ert.accountSvc.WithSessions(ert.sessionSvc)
+	ert.loginGuard = services.NewLoginGuard(newLoginGuardStoreAdapter(store))
+	ert.authSvc.WithLoginGuard(ert.loginGuard)
+	ert.memberSvc = services.NewMembershipService(newMembershipStoreAdapter(store))
+	ert.memberSvc.WithSessions(ert.sessionSvc)
+	ert.authSvc.WithMemberships(ert.memberSvc)
+	ert.oidcSvc.WithMemberships(ert.memberSvc)
+	ert.teamSvc.WithMemberships(ert.memberSvc)
+	ert.loginGuard.WithMemberships(ert.memberSvc)
+	ert.inviteSvc = services.NewInviteService(newInviteStoreAdapter(store), ert.memberSvc, ert.authSvc, mailer)
+	ert.limits = newRateLimits()
+	// access tokens stop working as soon as their session is revoked
+	middleware.SetRevocationCheck(ert.sessionSvc.IsRevoked)
 	return ert
 }
 
+// RunWebhookDeliveries sends queued webhook deliveries until ctx is done.
+func (rt *Router) RunWebhookDeliveries(ctx context.Context, interval time.Duration) {
+	rt.webhookSvc.Run(ctx, interval, func(err error) { log.Printf("webhook deliveries: %v", err) })
+}
+
+// RunAuditCheckpoints signs the head of the audit hash chain every interval until ctx is done.
+func (rt *Router) RunAuditCheckpoints(ctx context.Context, interval time.Duration) {
+	rt.auditSvc.Run(ctx, interval, func(err error) { log.Printf("audit checkpoint: %v", err) })
+}
+
 func NewRouter() *Router {
 	// Optionally load snapshot from disk via SYNAP_DB_PATH (MVP persistence)
 	// If empty or unavailable, fall back to pure in-memory.
@@ -72,12 +175,24 @@ func NewRouter() *Router {
 	return NewRouterWithStore(store)
 }
 
-func deriveSignKey() ed25519.PrivateKey {
+// seededSignKey returns the key from SYNAP_SIGN_SEED, or nil when it is unset or invalid.
+func seededSignKey() ed25519.PrivateKey {
 	seedB64 := strings.TrimSpace(os.Getenv("SYNAP_SIGN_SEED"))
-	if seedB64 != "" {
-		if seed, err := base64.StdEncoding.DecodeString(seedB64); err == nil && len(seed) == ed25519.SeedSize {
-			return ed25519.NewKeyFromSeed(seed)
-		}
+	if seedB64 == "" {
+		return nil
+	}
+	seed, err := base64.StdEncoding.DecodeString(seedB64)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		log.Printf("SYNAP_SIGN_SEED is not a base64 32-byte seed; ignoring it")
+		return nil
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// deriveSignKey returns the seeded key or, for E2EE manifests only, a random one per start.
+func deriveSignKey() ed25519.PrivateKey {
+	if key := seededSignKey(); key != nil {
+		return key
 	}
 	seed := make([]byte, ed25519.SeedSize)
 	if _, err := rand.Read(seed); err == nil {
@@ -86,36 +201,165 @@ func deriveSignKey() ed25519.PrivateKey {
 	return nil
 }
 
+// withScope registers an authenticated route that personal API tokens may also call, limited by rule.
+func (rt *Router) withScope(rule middleware.ScopeRule, h http.HandlerFunc) http.Handler {
+	return middleware.WithScope(rt.resolveAPIToken, rule, rt.withScaleAccess(rule, h))
+}
+
+// withScaleAccess lets collaborators from other tenants reach the scale the rule names: the
+// request runs in the scale's tenant, limited by the role the scale was shared with. Everybody
+// else keeps their own tenant, so requireScaleTenant (or the service's tenant check) refuses them.
+func (rt *Router) withScaleAccess(rule middleware.ScopeRule, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := middleware.ClaimsFromContext(r.Context())
+		_, scaleID := rule(r)
+		if !ok || scaleID == "" {
+			h(w, r)
+			return
+		}
+		if sc := rt.store.GetScale(scaleID); sc == nil || sc.TenantID == c.TID {
+			h(w, r)
+			return
+		}
+		access, err := rt.teamSvc.Access(c.UID, scaleID)
+		if err != nil || !access.Guest {
+			h(w, r)
+			return
+		}
+		if !guestAllowed(access.Role, r) {
+			http.Error(w, "not allowed for the "+access.Role+" role on this shared scale", http.StatusForbidden)
+			return
+		}
+		guest := *c
+		guest.TID, guest.GuestRole = access.TenantID, access.Role
+		h(w, r.WithContext(middleware.ContextWithClaims(r.Context(), &guest)))
+	}
+}
+
+// guestAllowed reports whether a guest with role may make r. Viewers only read; deleting the
+// scale or its responses and managing collaborators stay with the scale's tenant.
+func guestAllowed(role string, r *http.Request) bool {
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if strings.HasPrefix(r.URL.Path, "/api/admin/scales/") {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/scales/"), "/"), "/")
+		if len(parts) >= 2 && parts[1] == "collaborators" && !read {
+			return false
+		}
+		if r.Method == http.MethodDelete && (len(parts) == 1 || parts[1] == "responses") {
+			return false
+		}
+	}
+	return read || role == services.ScaleRoleEditor
+}
+
+// resolveAPIToken authenticates a personal API token and records the request it was used for.
+func (rt *Router) resolveAPIToken(token string, r *http.Request) (*middleware.Claims, error) {
+	t, err := rt.apiTokenSvc.Authenticate(token, r.Method+" "+r.URL.Path, sessionClient(r))
+	if err != nil {
+		return nil, err
+	}
+	// a token stops working when its owner leaves the tenant
+	if role, err := rt.memberSvc.Role(t.TenantID, t.UserID); err != nil || role == "" {
+		return nil, services.NewUnauthorizedError("token owner is not a member of the tenant")
+	}
+	return &middleware.Claims{UID: t.UserID, TID: t.TenantID, Email: t.Email, TokenID: t.ID, Scopes: t.Scopes, ScaleID: t.ScaleID}, nil
+}
+
+var scalesReadWrite = middleware.ReadWrite(services.ScopeScalesRead, services.ScopeScalesWrite)
+
+// scopeScales covers routes that list or create scales and items without naming one scale.
+func scopeScales(r *http.Request) (string, string) {
+	return scalesReadWrite(r), ""
+}
+
+// scopeQueryScale covers read-only routes that take the scale from ?scale_id=.
+func scopeQueryScale(scope string) middleware.ScopeRule {
+	return func(r *http.Request) (string, string) {
+		return scope, strings.TrimSpace(r.URL.Query().Get("scale_id"))
+	}
+}
+
+// scopeAdminScale covers /api/admin/scales/{id}/...; collaborator management stays session-only.
+func scopeAdminScale(r *http.Request) (string, string) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/admin/scales/"), "/")
+	if len(parts) >= 2 && parts[1] == "collaborators" {
+		return "", parts[0]
+	}
+	return scalesReadWrite(r), parts[0]
+}
+
+func (rt *Router) scopeAdminItem(r *http.Request) (string, string) {
+	scaleID := ""
+	if it := rt.store.GetItem(strings.TrimPrefix(r.URL.Path, "/api/admin/items/")); it != nil {
+		scaleID = it.ScaleID
+	}
+	return scalesReadWrite(r), scaleID
+}
+
 func (rt *Router) Register(mux *http.ServeMux) {
 	mux.HandleFunc("/api/seed", rt.handleSeed) // POST
-	mux.Handle("/api/scales", middleware.WithAuth(http.HandlerFunc(rt.handleScales)))
-	mux.Handle("/api/items", middleware.WithAuth(http.HandlerFunc(rt.handleItems)))
+	mux.HandleFunc("/.well-known/jwks.json", middleware.JWKS)
+	mux.Handle("/api/scales", rt.withScope(scopeScales, rt.handleScales))
+	mux.Handle("/api/items", rt.withScope(scopeScales, rt.handleItems))
 	mux.HandleFunc("/api/scales/", rt.handleScaleScoped)
 	mux.HandleFunc("/api/scale/", rt.handleScaleMeta) // public metadata
-	mux.HandleFunc("/api/responses/bulk", rt.handleBulkResponses)
-	mux.Handle("/api/export", middleware.WithAuth(http.HandlerFunc(rt.handleExport)))       // GET (auth)
-	mux.Handle("/api/metrics/alpha", middleware.WithAuth(http.HandlerFunc(rt.handleAlpha))) // GET (auth)
-	mux.HandleFunc("/api/auth/register", rt.handleRegister)
-	mux.HandleFunc("/api/auth/login", rt.handleLogin)
-	mux.HandleFunc("/api/auth/logout", rt.handleLogout)
+	mux.Handle("/api/responses/bulk", rt.limits.submit.Limit(http.HandlerFunc(rt.handleBulkResponses)))
+	mux.Handle("/api/export", rt.withScope(scopeQueryScale(services.ScopeResponsesExport), rt.handleExport))  // GET (auth)
+	mux.Handle("/api/metrics/alpha", rt.withScope(scopeQueryScale(services.ScopeScalesRead), rt.handleAlpha)) // GET (auth)
+	mux.Handle("/api/auth/register", rt.limits.register.Limit(http.HandlerFunc(rt.handleRegister)))
+	mux.Handle("/api/auth/login", rt.limits.login.Limit(http.HandlerFunc(rt.handleLogin)))
+	mux.Handle("/api/auth/logout", middleware.WithAuth(http.HandlerFunc(rt.handleLogout)))
+	mux.HandleFunc("/api/auth/refresh", rt.handleRefresh)
+	mux.Handle("/api/auth/password/forgot", rt.limits.mail.Limit(http.HandlerFunc(rt.handlePasswordForgot)))
+	mux.Handle("/api/auth/password/reset", rt.limits.token.Limit(http.HandlerFunc(rt.handlePasswordReset)))
+	mux.Handle("/api/auth/verify-email", rt.limits.token.Limit(http.HandlerFunc(rt.handleVerifyEmail)))
+	mux.Handle("/api/auth/verify-email/send", middleware.WithAuth(http.HandlerFunc(rt.handleSendVerification)))
+	mux.HandleFunc("/api/auth/oidc/discover", rt.handleOIDCDiscover)
+	mux.HandleFunc("/api/auth/oidc/login", rt.handleOIDCLogin)
+	mux.HandleFunc("/api/auth/oidc/callback", rt.handleOIDCCallback)
+	mux.Handle("/api/auth/oidc/link", middleware.WithAuth(http.HandlerFunc(rt.handleOIDCLink)))
+	mux.Handle("/api/auth/sessions", middleware.WithAuth(http.HandlerFunc(rt.handleSessions)))
+	mux.Handle("/api/auth/sessions/", middleware.WithAuth(http.HandlerFunc(rt.handleSessions)))
+	mux.Handle("/api/auth/mfa", rt.limits.token.Limit(middleware.WithAuth(http.HandlerFunc(rt.handleMFA))))
+	mux.Handle("/api/auth/mfa/", rt.limits.token.Limit(middleware.WithAuth(http.HandlerFunc(rt.handleMFA))))
+	mux.Handle("/api/auth/step-up", rt.limits.token.Limit(middleware.WithAuth(http.HandlerFunc(rt.handleStepUp))))
 	mux.Handle("/api/auth/me", middleware.WithAuth(http.HandlerFunc(rt.handleAuthMe)))
-	mux.Handle("/api/admin/scales", middleware.WithAuth(http.HandlerFunc(rt.handleAdminScales)))
-	mux.Handle("/api/admin/stats", middleware.WithAuth(http.HandlerFunc(rt.handleAdminStats)))
-	mux.Handle("/api/admin/analytics/summary", middleware.WithAuth(http.HandlerFunc(rt.handleAdminAnalyticsSummary)))
+	mux.Handle("/api/auth/tokens", middleware.WithAuth(http.HandlerFunc(rt.handleAPITokens)))
+	mux.Handle("/api/auth/tokens/", middleware.WithAuth(http.HandlerFunc(rt.handleAPITokens)))
+	mux.Handle("/api/auth/tenants", middleware.WithAuth(http.HandlerFunc(rt.handleMyTenants)))
+	mux.Handle("/api/auth/tenant/switch", middleware.WithAuth(http.HandlerFunc(rt.handleSwitchTenant)))
+	mux.Handle("/api/invites/accept", rt.limits.token.Limit(middleware.WithAuth(http.HandlerFunc(rt.handleAcceptInvite))))
+	mux.Handle("/api/admin/scales", rt.withScope(scopeScales, rt.handleAdminScales))
+	mux.Handle("/api/admin/stats", rt.withScope(scopeQueryScale(services.ScopeScalesRead), rt.handleAdminStats))
+	mux.Handle("/api/admin/analytics/summary", rt.withScope(scopeQueryScale(services.ScopeScalesRead), rt.handleAdminAnalyticsSummary))
+	mux.Handle("/api/admin/analytics/items", rt.withScope(scopeQueryScale(services.ScopeScalesRead), rt.handleAdminItemAnalysis))
+	mux.Handle("/api/admin/analytics/efa", rt.withScope(scopeQueryScale(services.ScopeScalesRead), rt.handleAdminEFA))
+	mux.Handle("/api/admin/analytics/compare", rt.withScope(scopeQueryScale(services.ScopeScalesRead), rt.handleAdminCompare))
 	// Admin: scale & item management
-	mux.Handle("/api/admin/scales/", middleware.WithAuth(http.HandlerFunc(rt.handleAdminScaleOps)))
-	mux.Handle("/api/admin/items/", middleware.WithAuth(http.HandlerFunc(rt.handleAdminItemOps)))
+	mux.Handle("/api/admin/scales/", rt.withScope(scopeAdminScale, rt.handleAdminScaleOps))
+	mux.Handle("/api/admin/items/", rt.withScope(rt.scopeAdminItem, rt.handleAdminItemOps))
 	// Participant data rights (admin-triggered for now)
 	mux.Handle("/api/admin/participant/export", middleware.WithAuth(http.HandlerFunc(rt.handleExportParticipant)))
 	mux.Handle("/api/admin/participant/delete", middleware.WithAuth(http.HandlerFunc(rt.handleDeleteParticipant)))
-	mux.Handle("/api/admin/audit", middleware.WithAuth(http.HandlerFunc(rt.handleAudit)))
+	mux.Handle("/api/admin/audit", rt.withScope(scopeQueryScale(services.ScopeAuditRead), rt.handleAudit))
+	mux.Handle("/api/admin/audit/export", rt.withScope(scopeQueryScale(services.ScopeAuditRead), rt.handleAuditExport))
+	mux.Handle("/api/admin/audit/verify", rt.withScope(scopeQueryScale(services.ScopeAuditRead), rt.handleAuditVerify))
+	// Outgoing webhooks and their delivery queue
+	mux.Handle("/api/admin/webhooks", middleware.WithAuth(http.HandlerFunc(rt.handleAdminWebhooks)))
+	mux.Handle("/api/admin/webhooks/", middleware.WithAuth(http.HandlerFunc(rt.handleAdminWebhooks)))
 	// AI config + translation preview
 	mux.Handle("/api/admin/ai/config", middleware.WithAuth(http.HandlerFunc(rt.handleAdminAIConfig)))
+	mux.Handle("/api/admin/oidc", middleware.WithAuth(http.HandlerFunc(rt.handleAdminOIDC)))
+	mux.Handle("/api/admin/lockouts", middleware.WithAuth(http.HandlerFunc(rt.handleAdminLockouts)))
+	mux.Handle("/api/admin/members", middleware.WithAuth(http.HandlerFunc(rt.handleAdminMembers)))
+	mux.Handle("/api/admin/members/", middleware.WithAuth(http.HandlerFunc(rt.handleAdminMembers)))
+	mux.Handle("/api/admin/invites", middleware.WithAuth(http.HandlerFunc(rt.handleAdminInvites)))
+	mux.Handle("/api/admin/invites/", middleware.WithAuth(http.HandlerFunc(rt.handleAdminInvites)))
 	mux.Handle("/api/admin/ai/translate/preview", middleware.WithAuth(http.HandlerFunc(rt.handleAdminAITranslatePreview)))
 	// E2EE project keys: GET (public), POST (auth) — WithAuth attaches claims when present (non-blocking for GET)
 	mux.Handle("/api/projects/", middleware.WithAuth(http.HandlerFunc(rt.handleProjectKeys)))
 	// E2EE encrypted responses (public submission)
-	mux.HandleFunc("/api/responses/e2ee", rt.handleE2EEResponse)
+	mux.Handle("/api/responses/e2ee", rt.limits.submit.Limit(http.HandlerFunc(rt.handleE2EEResponse)))
 	// Export encrypted bundle (auth + step-up header)
 	mux.Handle("/api/exports/e2ee", middleware.WithAuth(http.HandlerFunc(rt.handleExportE2EE)))
 	// Rewrap (auth)
@@ -124,10 +368,11 @@ func (rt *Router) Register(mux *http.ServeMux) {
 	// GDPR self-service for participants
 	mux.HandleFunc("/api/self/participant/export", rt.handleSelfExportParticipant) // GET
 	mux.HandleFunc("/api/self/participant/delete", rt.handleSelfDeleteParticipant) // POST
+	mux.HandleFunc("/api/self/participant/feedback", rt.handleSelfFeedback)        // GET
 	mux.HandleFunc("/api/self/e2ee/export", rt.handleSelfExportE2EE)               // GET (single response)
 	mux.HandleFunc("/api/self/e2ee/delete", rt.handleSelfDeleteE2EE)               // POST
 	// Consent signature evidence
-	mux.HandleFunc("/api/consent/sign", rt.handleConsentSign) // POST
+	mux.Handle("/api/consent/sign", rt.limits.submit.Limit(http.HandlerFunc(rt.handleConsentSign))) // POST
 }
 
 // POST /api/seed — create a sample scale+items
@@ -219,6 +464,14 @@ func (rt *Router) handleScaleScoped(w http.ResponseWriter, r *http.Request) {
 	}
 	rest := strings.TrimPrefix(r.URL.Path, "/api/scales/")
 	parts := strings.Split(rest, "/")
+	if len(parts) >= 3 && parts[1] == "cat" && parts[2] == "sessions" {
+		rt.handleCATSessions(w, r, parts[0], parts[3:])
+		return
+	}
+	if len(parts) == 3 && parts[1] == "feedback" && parts[2] == "spec" {
+		rt.handleFeedbackSpec(w, r, parts[0])
+		return
+	}
 	if len(parts) < 2 || parts[1] != "items" {
 		http.NotFound(w, r)
 		return
@@ -250,6 +503,10 @@ func (rt *Router) handleScaleMeta(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	cfg := rt.store.GetCATSettings(sc.ID)
+	catEnabled := cfg != nil && cfg.Enabled
+	fb := rt.store.GetFeedbackConfig(sc.ID)
+	feedbackEnabled := fb != nil && fb.Enabled
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"id":                sc.ID,
@@ -268,9 +525,74 @@ func (rt *Router) handleScaleMeta(w http.ResponseWriter, r *http.Request) {
 		"likert_labels_i18n":  sc.LikertLabelsI18n,
 		"likert_show_numbers": sc.LikertShowNumbers,
 		"likert_preset":       sc.LikertPreset,
+		"cat_enabled":         catEnabled,
+		"feedback_enabled":    feedbackEnabled,
 	})
 }
 
+// handleCATSessions serves adaptive (CAT) administration for participants.
+// POST /api/scales/{id}/cat/sessions                 -> {lang?, participant:{email}?, consent_id?, condition?, turnstile_token?} start; returns token + first item
+// GET  /api/scales/{id}/cat/sessions/{sid}?token=... -> current state (resume)
+// POST /api/scales/{id}/cat/sessions/{sid}/answer    -> {token, item_id, raw|raw_value, lang?}; next item or final θ
+func (rt *Router) handleCATSessions(w http.ResponseWriter, r *http.Request, scaleID string, rest []string) {
+	var (
+		res *services.CATState
+		err error
+	)
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var in struct {
+			Lang        string `json:"lang"`
+			Participant struct {
+				Email string `json:"email"`
+			} `json:"participant"`
+			ConsentID      string `json:"consent_id,omitempty"`
+			Condition      string `json:"condition,omitempty"`
+			TurnstileToken string `json:"turnstile_token,omitempty"`
+		}
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil && !errors.Is(derr, io.EOF) {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.catSvc.StartSession(services.CATStartRequest{
+			ScaleID:          scaleID,
+			Lang:             in.Lang,
+			ParticipantEmail: in.Participant.Email,
+			ConsentID:        in.ConsentID,
+			Condition:        in.Condition,
+			TurnstileToken:   in.TurnstileToken,
+			VerifyTurnstile: func(token string) (bool, error) {
+				return rt.verifyTurnstile(r, token), nil
+			},
+		})
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		q := r.URL.Query()
+		res, err = rt.catSvc.GetSession(rest[0], q.Get("token"), q.Get("lang"))
+	case len(rest) == 2 && rest[1] == "answer" && r.Method == http.MethodPost:
+		var in struct {
+			Token  string          `json:"token"`
+			Lang   string          `json:"lang"`
+			ItemID string          `json:"item_id"`
+			Raw    json.RawMessage `json:"raw"`
+			RawInt *int            `json:"raw_value,omitempty"`
+		}
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.catSvc.Answer(rest[0], in.Token, in.Lang, services.BulkAnswer{ItemID: in.ItemID, Raw: in.Raw, RawInt: in.RawInt})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
 // verifyTurnstile validates a Turnstile token with Cloudflare when server secret is set.
 // Returns true if verification succeeds. If secret is missing, returns true (skip enforcement).
 func (rt *Router) verifyTurnstile(r *http.Request, token string) bool {
@@ -373,7 +695,9 @@ func (rt *Router) handleBulkResponses(w http.ResponseWriter, r *http.Request) {
 			RawInt *int            `json:"raw_value,omitempty"`
 		} `json:"answers"`
 		ConsentID      string `json:"consent_id,omitempty"`
+		Condition      string `json:"condition,omitempty"`
 		TurnstileToken string `json:"turnstile_token,omitempty"`
+		Lang           string `json:"lang,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -387,6 +711,7 @@ func (rt *Router) handleBulkResponses(w http.ResponseWriter, r *http.Request) {
 		ScaleID:          req.ScaleID,
 		ParticipantEmail: req.Participant.Email,
 		ConsentID:        req.ConsentID,
+		Condition:        req.Condition,
 		TurnstileToken:   req.TurnstileToken,
 		Answers:          answers,
 		VerifyTurnstile: func(token string) (bool, error) {
@@ -406,21 +731,41 @@ func (rt *Router) handleBulkResponses(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
 	selfBase := "/api/self/participant"
-	_ = json.NewEncoder(w).Encode(map[string]any{
+	out := map[string]any{
 		"ok":             true,
 		"participant_id": result.ParticipantID,
 		"count":          result.ResponsesCount,
 		"self_token":     result.SelfToken,
 		"self_export":    selfBase + "/export?pid=" + result.ParticipantID + "&token=" + result.SelfToken,
 		"self_delete":    selfBase + "/delete?pid=" + result.ParticipantID + "&token=" + result.SelfToken,
-	})
+	}
+	// the submission is stored at this point; a failing report must not turn it into an error
+	report, ferr := rt.feedbackSvc.ReportFor(req.ScaleID, result.ParticipantID, req.Lang)
+	if ferr != nil {
+		log.Printf("feedback report for %s: %v", result.ParticipantID, ferr)
+	} else if report != nil {
+		out["feedback"] = report
+		out["self_feedback"] = selfBase + "/feedback?pid=" + result.ParticipantID + "&token=" + result.SelfToken
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
 }
 
 // GET /api/export?scale_id=...&format=long|wide|score|items
 func (rt *Router) handleExport(w http.ResponseWriter, r *http.Request) {
+	if !rt.requireVerified(w, r) {
+		return
+	}
 	scaleID := r.URL.Query().Get("scale_id")
+	if scaleID == "" {
+		http.Error(w, "scale_id required", http.StatusBadRequest)
+		return
+	}
+	if !rt.requireScaleTenant(w, r, scaleID) {
+		return
+	}
+	tid, _ := middleware.TenantIDFromContext(r.Context())
 	format := r.URL.Query().Get("format")
 	consentHeader := r.URL.Query().Get("consent_header")
 	headerLang := r.URL.Query().Get("header_lang") // en|zh
@@ -430,7 +775,7 @@ func (rt *Router) handleExport(w http.ResponseWriter, r *http.Request) {
 		// Default to English labels for consent columns for analysis friendliness
 		consentHeader = "label_en"
 	}
-	res, err := rt.exportSvc.ExportCSV(services.ExportParams{ScaleID: scaleID, Format: format, ConsentHeader: consentHeader, HeaderLang: headerLang, ValuesMode: valuesMode, ValueLang: valueLang})
+	res, err := rt.exportSvc.ExportCSV(services.ExportParams{TenantID: tid, ScaleID: scaleID, Format: format, ConsentHeader: consentHeader, HeaderLang: headerLang, ValuesMode: valuesMode, ValueLang: valueLang})
 	if err != nil {
 		rt.writeServiceError(w, err)
 		return
@@ -443,16 +788,19 @@ func (rt *Router) handleExport(w http.ResponseWriter, r *http.Request) {
 
 // GET /api/admin/participant/export?email=...
 func (rt *Router) handleExportParticipant(w http.ResponseWriter, r *http.Request) {
+	if !rt.requireVerified(w, r) {
+		return
+	}
 	email := strings.TrimSpace(r.URL.Query().Get("email"))
 	if email == "" {
 		http.Error(w, "email required", http.StatusBadRequest)
 		return
 	}
-	actor := "admin"
+	actor, tenantID := "admin", ""
 	if c, ok := middleware.ClaimsFromContext(r.Context()); ok {
-		actor = c.Email
+		actor, tenantID = c.Email, c.TID
 	}
-	res, err := rt.participantSvc.AdminExportByEmail(email, actor)
+	res, err := rt.participantSvc.AdminExportByEmail(tenantID, email, actor)
 	if err != nil {
 		rt.writeServiceError(w, err)
 		return
@@ -474,12 +822,15 @@ func (rt *Router) handleDeleteParticipant(w http.ResponseWriter, r *http.Request
 		http.Error(w, "email required", http.StatusBadRequest)
 		return
 	}
+	if !rt.requireStepUp(w, r) {
+		return
+	}
 	hard := r.URL.Query().Get("hard") == "true"
-	actor := "admin"
+	actor, tenantID := "admin", ""
 	if c, ok := middleware.ClaimsFromContext(r.Context()); ok {
-		actor = c.Email
+		actor, tenantID = c.Email, c.TID
 	}
-	if err := rt.participantSvc.AdminDeleteByEmail(email, hard, actor); err != nil {
+	if err := rt.participantSvc.AdminDeleteByEmail(tenantID, email, hard, actor); err != nil {
 		rt.writeServiceError(w, err)
 		return
 	}
@@ -586,19 +937,21 @@ func (rt *Router) handleE2EEResponse(w http.ResponseWriter, r *http.Request) {
 }
 
 // --- Export encrypted bundle (JSONL.enc-like JSON for MVP) ---
-// GET /api/exports/e2ee?scale_id=...
-// Requires X-Step-Up: true header for step-up confirmation (MVP stub)
+// POST /api/exports/e2ee {scale_id} -> short-lived download link; GET /api/exports/e2ee?job=...&token=...
+// Creating a link (and the legacy GET ?scale_id=...) requires a step-up token.
 func (rt *Router) handleExportE2EE(w http.ResponseWriter, r *http.Request) {
 	tid, ok := middleware.TenantIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if !rt.requireVerified(w, r) {
+		return
+	}
 
 	switch r.Method {
 	case http.MethodPost:
-		if r.Header.Get("X-Step-Up") != "true" {
-			http.Error(w, "step-up required", http.StatusForbidden)
+		if !rt.requireStepUp(w, r) {
 			return
 		}
 		var in struct {
@@ -608,11 +961,7 @@ func (rt *Router) handleExportE2EE(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "scale_id required", http.StatusBadRequest)
 			return
 		}
-		ip := r.Header.Get("X-Forwarded-For")
-		if ip == "" {
-			ip = r.RemoteAddr
-		}
-		res, err := rt.e2eeSvc.RequestExport(services.E2EEExportRequest{TenantID: tid, ScaleID: in.ScaleID, RemoteIP: ip, Actor: actorEmail(r)})
+		res, err := rt.e2eeSvc.RequestExport(services.E2EEExportRequest{TenantID: tid, ScaleID: in.ScaleID, RemoteIP: middleware.ClientIP(r), Actor: actorEmail(r)})
 		if err != nil {
 			rt.writeServiceError(w, err)
 			return
@@ -628,7 +977,7 @@ func (rt *Router) handleExportE2EE(w http.ResponseWriter, r *http.Request) {
 			JobID:    r.URL.Query().Get("job"),
 			JobToken: r.URL.Query().Get("token"),
 			Actor:    actorEmail(r),
-			StepUp:   r.Header.Get("X-Step-Up") == "true",
+			StepUp:   middleware.HasStepUp(r.Context()),
 		})
 		if err != nil {
 			rt.writeServiceError(w, err)
@@ -723,13 +1072,26 @@ func (rt *Router) handleAdminAIConfig(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(cfg)
 		return
 	case http.MethodPut:
+		c, ok := rt.requireTenantRole(w, r, services.TenantRoleAdmin)
+		if !ok {
+			return
+		}
 		var in services.TenantAIConfig
 		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		in.TenantID = tid
-		if err := rt.aiCfgSvc.Update(&in); err != nil {
+		cur, err := rt.aiCfgSvc.Get(tid)
+		if err != nil {
+			rt.writeServiceError(w, err)
+			return
+		}
+		// changing the key, or where it is sent, needs a step-up token
+		if (in.OpenAIKey != cur.OpenAIKey || in.OpenAIBase != cur.OpenAIBase) && !rt.requireStepUp(w, r) {
+			return
+		}
+		if err := rt.aiCfgSvc.Update(&in, c.Email, c.UID, sessionClient(r)); err != nil {
 			rt.writeServiceError(w, err)
 			return
 		}
@@ -779,75 +1141,238 @@ func (rt *Router) handleAdminAITranslatePreview(w http.ResponseWriter, r *http.R
 	_ = json.NewEncoder(w).Encode(res)
 }
 
-// GET /api/admin/audit
+// GET /api/admin/audit?action=&actor=&target=&scale_id=&source=&from=&to=&cursor=&limit=
+// → {entries, next_cursor}, newest first
 func (rt *Router) handleAudit(w http.ResponseWriter, r *http.Request) {
-	// Require tenant auth and filter to tenant-owned resources
-	tid, ok := middleware.TenantIDFromContext(r.Context())
+	q, ok := rt.auditQuery(w, r)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	// Optional: filter by scale_id
-	filterScaleID := strings.TrimSpace(r.URL.Query().Get("scale_id"))
-	source := strings.TrimSpace(r.URL.Query().Get("source")) // "admin" | "participant" | ""
-	var allowedScales map[string]bool
-	if filterScaleID != "" {
-		sc := rt.store.GetScale(filterScaleID)
-		if sc == nil || sc.TenantID != tid {
+	page, err := rt.auditSvc.Search(q)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+// auditQuery reads the audit filters of r. Tenant admins see the whole tenant log; other members
+// and shared-scale guests only entries about the tenant's scales and API tokens, narrowed to one
+// scale by scale_id. It writes the error and returns false when the request is refused.
+func (rt *Router) auditQuery(w http.ResponseWriter, r *http.Request) (services.AuditQuery, bool) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || c.TID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return services.AuditQuery{}, false
+	}
+	v := r.URL.Query()
+	q := services.AuditQuery{TenantID: c.TID, Action: strings.TrimSpace(v.Get("action")), Actor: strings.TrimSpace(v.Get("actor")),
+		Source: strings.TrimSpace(v.Get("source"))}
+	var err error
+	if q.From, err = parseAuditTime(v.Get("from"), false); err != nil {
+		http.Error(w, "from: "+err.Error(), http.StatusBadRequest)
+		return q, false
+	}
+	if q.To, err = parseAuditTime(v.Get("to"), true); err != nil {
+		http.Error(w, "to: "+err.Error(), http.StatusBadRequest)
+		return q, false
+	}
+	if cur := strings.TrimSpace(v.Get("cursor")); cur != "" {
+		if q.Before, err = strconv.ParseInt(cur, 10, 64); err != nil || q.Before <= 0 {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return q, false
+		}
+	}
+	if l := strings.TrimSpace(v.Get("limit")); l != "" {
+		if q.Limit, err = strconv.Atoi(l); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return q, false
+		}
+	}
+	// entries logged before the tenant was recorded are found by their target
+	scaleID := strings.TrimSpace(v.Get("scale_id"))
+	if scaleID != "" {
+		if sc := rt.store.GetScale(scaleID); sc == nil || sc.TenantID != c.TID {
 			http.Error(w, "forbidden", http.StatusForbidden)
-			return
+			return q, false
 		}
-		allowedScales = map[string]bool{filterScaleID: true}
-	} else {
-		// Build allow-list from tenant scales
-		allowedScales = map[string]bool{}
-		for _, sc := range rt.store.ListScalesByTenant(tid) {
-			allowedScales[sc.ID] = true
-		}
-	}
-
-	raw := rt.store.ListAudit()
-	out := make([]AuditEntry, 0, len(raw))
-	for _, e := range raw {
-		// Filter by source type when requested
-		if source == "admin" && strings.EqualFold(e.Actor, "participant") {
-			continue
-		}
-		if source == "participant" && !strings.EqualFold(e.Actor, "participant") {
-			continue
-		}
-		// Tenant scoping: prefer matching target scale; for participant scope also try E2EE response -> scale mapping
-		if allowedScales[e.Target] {
-			out = append(out, e)
-		} else if source == "participant" && e.Target != "" {
-			if er := rt.store.GetE2EEResponse(e.Target); er != nil {
-				if allowedScales[er.ScaleID] {
-					out = append(out, e)
-				}
-			}
+	}
+	var scoped []string
+	for _, sc := range rt.store.ListScalesByTenant(c.TID) {
+		q.LegacyTargets = append(q.LegacyTargets, sc.ID)
+		if scaleID == "" || sc.ID == scaleID {
+			scoped = append(scoped, sc.ID)
+		}
+	}
+	for _, t := range rt.store.ListAPITokens(c.TID) {
+		q.LegacyTargets = append(q.LegacyTargets, t.ID)
+		if scaleID == "" || t.ScaleID == scaleID {
+			scoped = append(scoped, t.ID)
+		}
+	}
+	if scaleID != "" || c.GuestRole != "" || rt.memberSvc.Require(c.TID, c.UID, services.TenantRoleAdmin) != nil {
+		q.Targets = scoped
+		if len(q.Targets) == 0 {
+			q.Targets = []string{""}
+		}
+	}
+	if target := strings.TrimSpace(v.Get("target")); target != "" {
+		allowed := len(q.Targets) == 0
+		for _, t := range q.Targets {
+			allowed = allowed || t == target
 		}
-		if len(out) >= 500 {
-			break
+		if !allowed {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return q, false
 		}
+		q.Targets = []string{target}
+	}
+	if err := q.Validate(); err != nil {
+		rt.writeServiceError(w, err)
+		return q, false
+	}
+	return q, true
+}
+
+// parseAuditTime accepts an RFC 3339 time or a date. A date as the end of a range includes that day.
+func parseAuditTime(v string, end bool) (time.Time, error) {
+	if v = strings.TrimSpace(v); v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return t, errors.New("expected an RFC 3339 time or a YYYY-MM-DD date")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// GET /api/admin/audit/export?format=proof|jsonl|csv plus the filters of /api/admin/audit.
+// proof (the default) holds the matching entries with their hashes, the verification of the
+// caller's segment and the signed checkpoints; jsonl and csv hold just the matching entries. The
+// whole chain is only exported by the operator (server export-audit).
+func (rt *Router) handleAuditExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := strings.TrimSpace(r.URL.Query().Get("format"))
+	switch format {
+	case "", "proof":
+		format = "proof"
+	case "jsonl", "csv":
+	default:
+		http.Error(w, "format must be proof, jsonl or csv", http.StatusBadRequest)
+		return
+	}
+	q, ok := rt.auditQuery(w, r)
+	if !ok {
+		return
+	}
+	var err error
+	switch format {
+	case "proof":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename=audit_trail.jsonl")
+		err = rt.auditSvc.Export(w, q.Matches)
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename=audit_log.jsonl")
+		err = rt.auditSvc.ExportFiltered(w, format, q)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename=audit_log.csv")
+		err = rt.auditSvc.ExportFiltered(w, format, q)
+	}
+	if err != nil {
+		log.Printf("audit export: %v", err)
+	}
+}
+
+// GET /api/admin/audit/verify plus the filters of /api/admin/audit → verifies the chain and reports
+// on the tenant's entries only; tenant admins only. The whole chain is verified by the operator
+// (server verify-audit).
+func (rt *Router) handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := rt.requireTenantRole(w, r, services.TenantRoleAdmin); !ok {
+		return
+	}
+	q, ok := rt.auditQuery(w, r)
+	if !ok {
+		return
+	}
+	res, err := rt.auditSvc.VerifyTenant(q.Matches)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(out)
+	_ = json.NewEncoder(w).Encode(res)
 }
 
-// GET /api/metrics/alpha?scale_id=...
+// GET /api/metrics/alpha?scale_id=...&bootstrap=1000&seed=42&confidence=0.95
 func (rt *Router) handleAlpha(w http.ResponseWriter, r *http.Request) {
 	scaleID := r.URL.Query().Get("scale_id")
 	if scaleID == "" {
 		http.Error(w, "scale_id required", http.StatusBadRequest)
 		return
 	}
-	alpha, n, err := rt.analyticsSvc.Alpha(scaleID)
+	if !rt.requireScaleTenant(w, r, scaleID) {
+		return
+	}
+	tid, _ := middleware.TenantIDFromContext(r.Context())
+	opts, err := reliabilityOptionsFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rel, err := rt.analyticsSvc.Reliability(tid, scaleID, opts)
 	if err != nil {
 		rt.writeServiceError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"scale_id": scaleID, "alpha": alpha, "n": n})
+	_ = json.NewEncoder(w).Encode(map[string]any{"scale_id": scaleID, "alpha": rel.Alpha, "n": rel.N, "reliability": rel})
+}
+
+// reliabilityOptionsFromQuery reads the optional bootstrap/seed/confidence parameters.
+func reliabilityOptionsFromQuery(r *http.Request) (services.ReliabilityOptions, error) {
+	var opts services.ReliabilityOptions
+	q := r.URL.Query()
+	if v := q.Get("bootstrap"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, errors.New("invalid bootstrap")
+		}
+		if n > services.MaxBootstrapSamples {
+			return opts, fmt.Errorf("bootstrap must be at most %d", services.MaxBootstrapSamples)
+		}
+		opts.Bootstrap = n
+	}
+	if v := q.Get("seed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return opts, errors.New("invalid seed")
+		}
+		opts.Seed = n
+	}
+	if v := q.Get("confidence"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f >= 1 {
+			return opts, errors.New("invalid confidence")
+		}
+		opts.Confidence = f
+	}
+	return opts, nil
 }
 
 // --- Auth & Admin ---
@@ -869,47 +1394,24 @@ func (rt *Router) handleRegister(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
 		return
 	}
-	// If invite token is provided and valid, register into inviter's tenant and auto-add collaborator
+	// An invite token registers into the inviter's tenant and applies the invite
 	if strings.TrimSpace(req.InviteToken) != "" {
-		inv := rt.store.GetInvite(strings.TrimSpace(req.InviteToken))
-		if inv == nil || inv.ExpiresAt.Before(time.Now().UTC()) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid or expired invite"})
-			return
-		}
-		// Email must match
-		if !strings.EqualFold(strings.TrimSpace(req.Email), strings.TrimSpace(inv.Email)) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invite email mismatch"})
-			return
-		}
-		res, err := rt.authSvc.RegisterWithTenant(req.Email, req.Password, inv.TenantID)
+		res, err := rt.inviteSvc.Register(req.InviteToken, req.Email, req.Password, sessionClient(r))
 		if err != nil {
 			rt.writeAuthJSONError(w, err)
 			return
 		}
-		_ = rt.store.MarkInviteAccepted(inv.Token)
-		// Auto-add collaborator
-		if _, addErr := rt.teamSvc.Add(inv.TenantID, inv.ScaleID, inv.Email, inv.Role, "system:invite"); addErr != nil {
-			log.Printf("invite: add collaborator error: %v", addErr)
-		}
-		maxAge := int(rt.authSvc.TokenTTL().Seconds())
-		http.SetCookie(w, &http.Cookie{Name: "synap_token", Value: res.Token, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/", MaxAge: maxAge})
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"token": res.Token, "tenant_id": res.TenantID, "user_id": res.UserID})
+		rt.sendVerification(req.Email)
+		rt.writeAuthResult(w, res)
 		return
 	}
-	res, err := rt.authSvc.Register(req.Email, req.Password, req.TenantName)
+	res, err := rt.authSvc.Register(req.Email, req.Password, req.TenantName, sessionClient(r))
 	if err != nil {
 		rt.writeAuthJSONError(w, err)
 		return
 	}
-	maxAge := int(rt.authSvc.TokenTTL().Seconds())
-	http.SetCookie(w, &http.Cookie{Name: "synap_token", Value: res.Token, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/", MaxAge: maxAge})
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"token": res.Token, "tenant_id": res.TenantID, "user_id": res.UserID})
+	rt.sendVerification(req.Email)
+	rt.writeAuthResult(w, res)
 }
 
 // POST /api/auth/login {email,password}
@@ -927,68 +1429,933 @@ func (rt *Router) handleLogin(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
 		return
 	}
-	res, err := rt.authSvc.Login(req.Email, req.Password)
+	res, err := rt.authSvc.Login(req.Email, req.Password, sessionClient(r))
 	if err != nil {
 		rt.writeAuthJSONError(w, err)
 		return
 	}
-	maxAge := int(rt.authSvc.TokenTTL().Seconds())
-	http.SetCookie(w, &http.Cookie{Name: "synap_token", Value: res.Token, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/", MaxAge: maxAge})
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"token": res.Token, "tenant_id": res.TenantID, "user_id": res.UserID})
+	rt.writeAuthResult(w, res)
 }
 
-// POST /api/auth/logout — expire auth cookie; frontend should also clear token
+// POST /api/auth/logout — revoke the current session and expire the auth cookies; frontend should also clear token
 func (rt *Router) handleLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	// Expire the cookie
+	var err error
+	if c, ok := middleware.ClaimsFromContext(r.Context()); ok && c.ID != "" {
+		err = rt.sessionSvc.Revoke(c.UID, c.ID)
+	} else if ck, cerr := r.Cookie(refreshCookie); cerr == nil {
+		err = rt.sessionSvc.RevokeRefresh(ck.Value)
+	}
+	if err != nil {
+		log.Printf("logout: revoke session: %v", err)
+	}
+	// Expire the cookies
 	http.SetCookie(w, &http.Cookie{Name: "synap_token", Value: "", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/", MaxAge: -1, Expires: time.Unix(0, 0)})
+	http.SetCookie(w, &http.Cookie{Name: refreshCookie, Value: "", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/api/auth", MaxAge: -1, Expires: time.Unix(0, 0)})
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
 }
 
-// GET /api/admin/scales -> list scales for tenant
-func (rt *Router) handleAdminScales(w http.ResponseWriter, r *http.Request) {
-	tid, ok := middleware.TenantIDFromContext(r.Context())
+// mailerFromEnv selects the mail backend: SYNAP_MAILER=smtp (SYNAP_SMTP_ADDR, SYNAP_SMTP_USER,
+// SYNAP_SMTP_PASSWORD), file (SYNAP_MAIL_DIR) or log (default, development only).
+func mailerFromEnv() services.Mailer {
+	from := strings.TrimSpace(os.Getenv("SYNAP_MAIL_FROM"))
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("SYNAP_MAILER"))) {
+	case "smtp":
+		return &services.SMTPMailer{Addr: os.Getenv("SYNAP_SMTP_ADDR"), Username: os.Getenv("SYNAP_SMTP_USER"), Password: os.Getenv("SYNAP_SMTP_PASSWORD"), From: from}
+	case "file":
+		return &services.LogMailer{Dir: os.Getenv("SYNAP_MAIL_DIR"), From: from}
+	}
+	return &services.LogMailer{From: from}
+}
+
+// requireVerified answers 403 unless the caller has confirmed their email address.
+func (rt *Router) requireVerified(w http.ResponseWriter, r *http.Request) bool {
+	c, ok := middleware.ClaimsFromContext(r.Context())
 	if !ok {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	verified, err := rt.accountSvc.IsVerified(c.Email)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return false
+	}
+	if !verified {
+		rt.writeJSONError(w, http.StatusForbidden, "email not verified")
+		return false
+	}
+	return true
+}
+
+// sendVerification mails the verification link after registration without delaying the response.
+func (rt *Router) sendVerification(email string) {
+	base, err := mailBaseURL()
+	if err != nil {
+		log.Printf("verification mail: %v", err)
 		return
 	}
-	list := rt.store.ListScalesByTenant(tid)
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"scales": list})
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := rt.accountSvc.SendVerification(ctx, email, base); err != nil {
+			log.Printf("verification mail: %v", err)
+		}
+	}()
 }
 
-// GET /api/admin/stats?scale_id=...
-func (rt *Router) handleAdminStats(w http.ResponseWriter, r *http.Request) {
-	tid, ok := middleware.TenantIDFromContext(r.Context())
-	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+// POST /api/auth/password/forgot {email} -> {ok} whether or not the address has an account
+func (rt *Router) handlePasswordForgot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	scaleID := r.URL.Query().Get("scale_id")
-	if scaleID == "" {
-		http.Error(w, "scale_id required", http.StatusBadRequest)
+	var in struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	sc := rt.store.GetScale(scaleID)
-	if sc == nil || sc.TenantID != tid {
-		http.Error(w, "forbidden", http.StatusForbidden)
+	base, ok := rt.mailBase(w)
+	if !ok {
+		return
+	}
+	if err := rt.accountSvc.RequestPasswordReset(r.Context(), in.Email, base); err != nil {
+		rt.writeServiceError(w, err)
 		return
 	}
-	rs := rt.store.ListResponsesByScale(scaleID)
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"count": len(rs)})
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
 }
 
-// GET /api/admin/analytics/summary?scale_id=...
-// Returns per-item histograms, daily timeseries counts, and Cronbach's alpha.
-func (rt *Router) handleAdminAnalyticsSummary(w http.ResponseWriter, r *http.Request) {
-	// Enforce tenant scope
-	tid, ok := middleware.TenantIDFromContext(r.Context())
+// POST /api/auth/password/reset {token, password} -> {ok}; signs the user out everywhere
+func (rt *Router) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := rt.accountSvc.ResetPassword(in.Token, in.Password); err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+}
+
+// POST /api/auth/verify-email {token} -> {ok}
+func (rt *Router) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := rt.accountSvc.VerifyEmail(in.Token); err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+}
+
+// POST /api/auth/verify-email/send -> {ok}: mail a new verification link to the signed-in user
+func (rt *Router) handleSendVerification(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	base, ok := rt.mailBase(w)
+	if !ok {
+		return
+	}
+	if err := rt.accountSvc.SendVerification(r.Context(), c.Email, base); err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+}
+
+// oidcStateCookie binds a single sign-on to the browser that started it.
+const oidcStateCookie = "synap_oidc"
+
+// mailBaseURL is the origin of the links sent by mail. It only ever comes from SYNAP_PUBLIC_URL:
+// the Host header is chosen by the client, and a forged one would mail a reset or invite token
+// pointing at the attacker's host.
+func mailBaseURL() (string, error) {
+	raw := strings.TrimRight(strings.TrimSpace(os.Getenv("SYNAP_PUBLIC_URL")), "/")
+	if raw == "" {
+		return "", errors.New("SYNAP_PUBLIC_URL is not set; mail links are disabled")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("SYNAP_PUBLIC_URL %q is not an http(s) origin", raw)
+	}
+	return raw, nil
+}
+
+// CheckMailConfig fails when mail is delivered (SYNAP_MAILER smtp or file) but SYNAP_PUBLIC_URL
+// cannot be used to build its links.
+func CheckMailConfig() error {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("SYNAP_MAILER"))) {
+	case "smtp", "file":
+		_, err := mailBaseURL()
+		return err
+	}
+	return nil
+}
+
+// mailBase returns the origin for mailed links, or answers 503 when none is configured.
+func (rt *Router) mailBase(w http.ResponseWriter) (string, bool) {
+	base, err := mailBaseURL()
+	if err != nil {
+		log.Printf("mail links: %v", err)
+		rt.writeJSONError(w, http.StatusServiceUnavailable, "mail is not configured")
+		return "", false
+	}
+	return base, true
+}
+
+// publicURL is the externally visible origin for the single sign-on redirect URI: SYNAP_PUBLIC_URL,
+// or the request's scheme and host. Mailed links use mailBaseURL instead.
+func publicURL(r *http.Request) string {
+	if u := strings.TrimRight(strings.TrimSpace(os.Getenv("SYNAP_PUBLIC_URL")), "/"); u != "" {
+		return u
+	}
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// GET /api/auth/oidc/discover?email=... -> {tenant_id, login_url} of the provider for the email's domain
+func (rt *Router) handleOIDCDiscover(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tid, err := rt.oidcSvc.Discover(r.URL.Query().Get("email"))
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"tenant_id": tid, "login_url": "/api/auth/oidc/login?tenant_id=" + url.QueryEscape(tid)})
+}
+
+// GET /api/auth/oidc/login?tenant_id=...&return_to=/admin -> redirect to the tenant's identity provider
+func (rt *Router) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	login, err := rt.oidcSvc.Begin(r.Context(), q.Get("tenant_id"), publicURL(r)+"/api/auth/oidc/callback", q.Get("return_to"))
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: login.State, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode,
+		Path: "/api/auth/oidc", MaxAge: 600})
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// POST /api/auth/oidc/link {return_to?} -> {login_url}: links the current tenant's provider to the signed-in user
+func (rt *Router) handleOIDCLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || c.TID == "" || c.GuestRole != "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var in struct {
+		ReturnTo string `json:"return_to"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&in)
+	login, err := rt.oidcSvc.BeginLink(r.Context(), c.TID, c.UID, publicURL(r)+"/api/auth/oidc/callback", in.ReturnTo)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: login.State, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode,
+		Path: "/api/auth/oidc", MaxAge: 600})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"login_url": login.URL})
+}
+
+// GET /api/auth/oidc/callback?code=...&state=... -> sign in and redirect to return_to; failures go to /auth?sso_error=...
+func (rt *Router) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode,
+		Path: "/api/auth/oidc", MaxAge: -1, Expires: time.Unix(0, 0)})
+	fail := func(msg string) {
+		http.Redirect(w, r, "/auth?sso_error="+url.QueryEscape(msg), http.StatusFound)
+	}
+	if e := q.Get("error"); e != "" {
+		fail(strings.TrimSpace(e + " " + q.Get("error_description")))
+		return
+	}
+	state := q.Get("state")
+	if ck, err := r.Cookie(oidcStateCookie); err != nil || state == "" || ck.Value != state {
+		fail("sign-in was started in another browser or has expired")
+		return
+	}
+	res, err := rt.oidcSvc.Complete(r.Context(), state, q.Get("code"), sessionClient(r))
+	if err != nil {
+		var se *services.ServiceError
+		if errors.As(err, &se) {
+			fail(se.Message)
+		} else {
+			log.Printf("oidc callback: %v", err)
+			fail("sign-in failed")
+		}
+		return
+	}
+	rt.setAuthCookies(w, res.AuthResult)
+	http.Redirect(w, r, res.ReturnTo, http.StatusFound)
+}
+
+// GET/PUT/DELETE /api/admin/oidc — the tenant's single sign-on provider (the client secret is write-only)
+func (rt *Router) handleAdminOIDC(w http.ResponseWriter, r *http.Request) {
+	c, ok := rt.requireTenantRole(w, r, services.TenantRoleAdmin)
+	if !ok {
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		res, err = rt.oidcSvc.GetConfig(c.TID)
+	case http.MethodPut:
+		var in services.OIDCConfig
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.oidcSvc.UpdateConfig(c.TID, c.Email, &in)
+	case http.MethodDelete:
+		err = rt.oidcSvc.DeleteConfig(c.TID, c.Email)
+		res = map[string]any{"ok": true}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// refreshCookie holds the refresh token; it is only sent to /api/auth/*.
+const refreshCookie = "synap_refresh"
+
+// GET /api/admin/lockouts -> { lockouts:[{ email, failures, last_failure_at, locked_until?, locked }] }
+// DELETE /api/admin/lockouts?email=... -> { ok } clears the failed sign-ins of an account in the tenant
+func (rt *Router) handleAdminLockouts(w http.ResponseWriter, r *http.Request) {
+	c, ok := rt.requireTenantRole(w, r, services.TenantRoleAdmin)
+	if !ok {
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		var list []services.Lockout
+		list, err = rt.loginGuard.ListLockouts(c.TID)
+		res = map[string]any{"lockouts": list}
+	case http.MethodDelete:
+		err = rt.loginGuard.Unlock(c.TID, c.Email, r.URL.Query().Get("email"))
+		res = map[string]any{"ok": true}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// requireTenantRole rejects the request unless the caller holds at least role in the tenant of
+// their token.
+func (rt *Router) requireTenantRole(w http.ResponseWriter, r *http.Request, role string) (*middleware.Claims, bool) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err := rt.memberSvc.Require(c.TID, c.UID, role); err != nil {
+		rt.writeServiceError(w, err)
+		return nil, false
+	}
+	return c, true
+}
+
+// claimsUser loads the signed-in user.
+func (rt *Router) claimsUser(c *middleware.Claims) (*services.User, error) {
+	u, err := newAuthStoreAdapter(rt.store).FindUserByEmail(c.Email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.ID != c.UID {
+		return nil, services.NewUnauthorizedError("user not found")
+	}
+	return u, nil
+}
+
+// GET /api/admin/members -> { members:[{ user_id, email, role, created_at }] }
+// POST /api/admin/members {email, role} -> { invite, invite_url }; the invitee joins on accepting
+// PUT /api/admin/members/{userID} {role}; DELETE /api/admin/members/{userID}
+// POST /api/admin/members/transfer {user_id} — make another member the owner (needs step-up)
+func (rt *Router) handleAdminMembers(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/members"), "/")
+	var (
+		res any
+		err error
+	)
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var list []*services.Membership
+		list, err = rt.memberSvc.ListMembers(c.TID, c.UID)
+		res = map[string]any{"members": list}
+	case id == "" && r.Method == http.MethodPost:
+		if !rt.requireVerified(w, r) {
+			return
+		}
+		var in struct{ Email, Role string }
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		base, ok := rt.mailBase(w)
+		if !ok {
+			return
+		}
+		var inv *services.Invite
+		inv, err = rt.inviteSvc.Create(r.Context(), c.TID, "", c.UID, c.Email, in.Email, in.Role, base)
+		if inv != nil {
+			res = map[string]any{"invite": inv, "invite_url": services.InviteURL(base, inv.Token)}
+		}
+	case id == "transfer" && r.Method == http.MethodPost:
+		if !rt.requireStepUp(w, r) {
+			return
+		}
+		var in struct {
+			UserID string `json:"user_id"`
+		}
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		err = rt.memberSvc.TransferOwnership(c.TID, c.UID, c.Email, in.UserID)
+		res = map[string]any{"ok": true}
+	case id != "" && r.Method == http.MethodPut:
+		var in struct{ Role string }
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.memberSvc.ChangeRole(c.TID, c.UID, c.Email, id, in.Role)
+	case id != "" && r.Method == http.MethodDelete:
+		err = rt.memberSvc.Remove(c.TID, c.UID, c.Email, id)
+		res = map[string]any{"ok": true}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/admin/invites[?scale_id=] -> { invites:[...] } — tenant invites need an admin, a scale's any member
+// DELETE /api/admin/invites/{id} — revoke a pending invite
+// POST /api/admin/invites/{id}/resend -> { invite, invite_url } — issue a new link, the old one stops working
+func (rt *Router) handleAdminInvites(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/invites"), "/")
+	id, action, _ := strings.Cut(rest, "/")
+	var (
+		res any
+		err error
+	)
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var list []*services.Invite
+		list, err = rt.inviteSvc.List(c.TID, r.URL.Query().Get("scale_id"), c.UID)
+		res = map[string]any{"invites": list}
+	case id != "" && action == "" && r.Method == http.MethodDelete:
+		err = rt.inviteSvc.Revoke(c.TID, c.UID, c.Email, id)
+		res = map[string]any{"ok": true}
+	case id != "" && action == "resend" && r.Method == http.MethodPost:
+		if !rt.requireVerified(w, r) {
+			return
+		}
+		base, ok := rt.mailBase(w)
+		if !ok {
+			return
+		}
+		var inv *services.Invite
+		inv, err = rt.inviteSvc.Resend(r.Context(), c.TID, c.UID, c.Email, id, base)
+		if inv != nil {
+			res = map[string]any{"invite": inv, "invite_url": services.InviteURL(base, inv.Token)}
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/invites/accept?token= -> { email, role, tenant_name, scale_id, expires_at, account_exists }
+// POST /api/invites/accept {token, password}
+//
+//	signed in as the invited address: applies the invite -> { invite }
+//	otherwise: creates the invited account -> tokens like register
+func (rt *Router) handleAcceptInvite(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		info, err := rt.inviteSvc.Lookup(r.URL.Query().Get("token"))
+		if err != nil {
+			rt.writeAuthJSONError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(info)
+	case http.MethodPost:
+		var in struct{ Token, Password string }
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			rt.writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if c, ok := middleware.ClaimsFromContext(r.Context()); ok && c.TokenID == "" {
+			u, err := rt.claimsUser(c)
+			if err != nil {
+				rt.writeAuthJSONError(w, err)
+				return
+			}
+			inv, err := rt.inviteSvc.Accept(in.Token, u)
+			if err != nil {
+				rt.writeAuthJSONError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"invite": inv})
+			return
+		}
+		info, err := rt.inviteSvc.Lookup(in.Token)
+		if err != nil {
+			rt.writeAuthJSONError(w, err)
+			return
+		}
+		res, err := rt.inviteSvc.Register(in.Token, "", in.Password, sessionClient(r))
+		if err != nil {
+			rt.writeAuthJSONError(w, err)
+			return
+		}
+		rt.sendVerification(info.Email)
+		rt.writeAuthResult(w, res)
+	default:
+		rt.writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// GET /api/auth/tenants -> { tenants:[{ tenant_id, tenant_name, role, created_at }], current }
+// POST /api/auth/tenants {name} -> membership of a new tenant owned by the caller
+func (rt *Router) handleMyTenants(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		var list []*services.Membership
+		list, err = rt.memberSvc.ListTenants(c.UID)
+		res = map[string]any{"tenants": list, "current": c.TID}
+	case http.MethodPost:
+		var in struct{ Name string }
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		var u *services.User
+		if u, err = rt.claimsUser(c); err == nil {
+			res, err = rt.memberSvc.CreateTenant(u, in.Name)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// POST /api/auth/tenant/switch {tenant_id} — move the current session to another of the user's
+// tenants; returns new tokens like login and makes the tenant the default for the next sign-in
+func (rt *Router) handleSwitchTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rt.writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok || c.TokenID != "" {
+		rt.writeJSONError(w, http.StatusUnauthorized, "session required")
+		return
+	}
+	var in struct {
+		TenantID string `json:"tenant_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		rt.writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := rt.claimsUser(c)
+	if err != nil {
+		rt.writeAuthJSONError(w, err)
+		return
+	}
+	if _, err := rt.memberSvc.Switch(u, strings.TrimSpace(in.TenantID)); err != nil {
+		rt.writeAuthJSONError(w, err)
+		return
+	}
+	st, err := rt.sessionSvc.SwitchTenant(c.UID, c.ID, strings.TrimSpace(in.TenantID), sessionClient(r))
+	if err != nil {
+		rt.writeAuthJSONError(w, err)
+		return
+	}
+	rt.writeAuthResult(w, &services.AuthResult{Token: st.AccessToken, RefreshToken: st.RefreshToken, SessionID: st.SessionID, TenantID: st.TenantID, UserID: st.UserID})
+}
+
+func sessionClient(r *http.Request) services.SessionClient {
+	return services.SessionClient{UserAgent: r.UserAgent(), IP: middleware.ClientIP(r)}
+}
+
+// writeAuthResult sets the access and refresh cookies and returns the tokens to API clients.
+func (rt *Router) writeAuthResult(w http.ResponseWriter, res *services.AuthResult) {
+	maxAge := rt.setAuthCookies(w, res)
+	out := map[string]any{"token": res.Token, "tenant_id": res.TenantID, "user_id": res.UserID, "expires_in": maxAge}
+	if res.RefreshToken != "" {
+		out["refresh_token"] = res.RefreshToken
+		out["session_id"] = res.SessionID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// setAuthCookies sets the access (and refresh) cookies and returns the access token lifetime in seconds.
+func (rt *Router) setAuthCookies(w http.ResponseWriter, res *services.AuthResult) int {
+	maxAge := int(rt.authSvc.TokenTTL().Seconds())
+	http.SetCookie(w, &http.Cookie{Name: "synap_token", Value: res.Token, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/", MaxAge: maxAge})
+	if res.RefreshToken != "" {
+		http.SetCookie(w, &http.Cookie{Name: refreshCookie, Value: res.RefreshToken, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode,
+			Path: "/api/auth", MaxAge: int(rt.sessionSvc.RefreshTTL().Seconds())})
+	}
+	return maxAge
+}
+
+// POST /api/auth/refresh {refresh_token?} — rotate the refresh token (body or cookie) and issue a new access token
+func (rt *Router) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rt.writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			rt.writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		if ck, err := r.Cookie(refreshCookie); err == nil {
+			req.RefreshToken = ck.Value
+		}
+	}
+	st, err := rt.sessionSvc.Refresh(req.RefreshToken, sessionClient(r))
+	if err != nil {
+		rt.writeAuthJSONError(w, err)
+		return
+	}
+	rt.writeAuthResult(w, &services.AuthResult{Token: st.AccessToken, RefreshToken: st.RefreshToken, SessionID: st.SessionID, TenantID: st.TenantID, UserID: st.UserID})
+}
+
+// requireStepUp rejects the request unless it carries a step-up token (see handleStepUp).
+func (rt *Router) requireStepUp(w http.ResponseWriter, r *http.Request) bool {
+	if middleware.HasStepUp(r.Context()) {
+		return true
+	}
+	http.Error(w, "step-up required", http.StatusForbidden)
+	return false
+}
+
+// POST /api/auth/step-up {code} — verify a TOTP or recovery code and issue a step-up access token
+// for the current session (also set as the auth cookie until it expires)
+func (rt *Router) handleStepUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var in struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := rt.mfaSvc.StepUp(c.UID, c.TID, c.Email, c.ID, in.Code)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: "synap_token", Value: res.Token, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode, Path: "/",
+		MaxAge: int(rt.mfaSvc.StepUpWindow().Seconds())})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/auth/mfa -> status; POST /api/auth/mfa/enroll -> {secret, otpauth_url};
+// POST /api/auth/mfa/confirm {code} -> {recovery_codes}; POST /api/auth/mfa/recovery-codes {code} -> {recovery_codes};
+// POST /api/auth/mfa/disable {code}
+func (rt *Router) handleMFA(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth/mfa"), "/")
+	if action == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		st, err := rt.mfaSvc.Status(c.UID)
+		if err != nil {
+			rt.writeServiceError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(st)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var in struct {
+		Code string `json:"code"`
+	}
+	if action != "enroll" {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var (
+		res any
+		err error
+	)
+	switch action {
+	case "enroll":
+		res, err = rt.mfaSvc.Enroll(c.UID, c.Email)
+	case "confirm":
+		var codes []string
+		codes, err = rt.mfaSvc.Confirm(c.UID, c.TID, c.Email, in.Code)
+		res = map[string]any{"recovery_codes": codes}
+	case "recovery-codes":
+		var codes []string
+		codes, err = rt.mfaSvc.RegenerateRecoveryCodes(c.UID, c.TID, c.Email, in.Code)
+		res = map[string]any{"recovery_codes": codes}
+	case "disable":
+		err = rt.mfaSvc.Disable(c.UID, c.TID, c.Email, in.Code)
+		res = map[string]any{"ok": true}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/auth/sessions -> the user's active sessions; DELETE /api/auth/sessions[?keep_current=true] -> sign out
+// everywhere; DELETE /api/auth/sessions/{id} -> revoke one session. Session tokens only.
+func (rt *Router) handleSessions(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth/sessions"), "/")
+	var (
+		res any
+		err error
+	)
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var sessions []*services.Session
+		sessions, err = rt.sessionSvc.List(c.UID, c.ID)
+		res = map[string]any{"sessions": sessions}
+	case id == "" && r.Method == http.MethodDelete:
+		except := ""
+		if r.URL.Query().Get("keep_current") == "true" {
+			except = c.ID
+		}
+		var n int
+		n, err = rt.sessionSvc.RevokeAll(c.UID, except)
+		res = map[string]any{"ok": true, "revoked": n}
+	case id != "" && r.Method == http.MethodDelete:
+		err = rt.sessionSvc.Revoke(c.UID, id)
+		res = map[string]any{"ok": true}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/admin/scales -> scales of the tenant, then scales of other tenants shared with the caller
+func (rt *Router) handleAdminScales(w http.ResponseWriter, r *http.Request) {
+	tid, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	list := rt.store.ListScalesByTenant(tid)
+	out := make([]any, 0, len(list))
+	for _, sc := range list {
+		out = append(out, sc)
+	}
+	// scales of other tenants shared with the caller follow, marked with the caller's role
+	if c, ok := middleware.ClaimsFromContext(r.Context()); ok {
+		shared, err := rt.teamSvc.Shared(tid, c.UID)
+		if err != nil {
+			rt.writeServiceError(w, err)
+			return
+		}
+		for _, s := range shared {
+			if sc := rt.store.GetScale(s.ScaleID); sc != nil {
+				out = append(out, sharedScale{Scale: sc, SharedRole: s.Role})
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"scales": out})
+}
+
+// sharedScale is a scale of another tenant in the listing of a guest collaborator.
+type sharedScale struct {
+	*Scale
+	SharedRole string `json:"shared_role"`
+}
+
+// GET /api/admin/stats?scale_id=...
+func (rt *Router) handleAdminStats(w http.ResponseWriter, r *http.Request) {
+	tid, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	scaleID := r.URL.Query().Get("scale_id")
+	if scaleID == "" {
+		http.Error(w, "scale_id required", http.StatusBadRequest)
+		return
+	}
+	if !rt.requireScaleTenant(w, r, scaleID) {
+		return
+	}
+	count, err := rt.analyticsSvc.ResponseCount(tid, scaleID)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"count": count})
+}
+
+// GET /api/admin/analytics/summary?scale_id=...
+// Returns per-item histograms, daily timeseries counts, and Cronbach's alpha.
+func (rt *Router) handleAdminAnalyticsSummary(w http.ResponseWriter, r *http.Request) {
+	// Enforce tenant scope
+	tid, ok := middleware.TenantIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
@@ -998,7 +2365,12 @@ func (rt *Router) handleAdminAnalyticsSummary(w http.ResponseWriter, r *http.Req
 		http.Error(w, "scale_id required", http.StatusBadRequest)
 		return
 	}
-	summary, err := rt.analyticsSvc.Summary(tid, scaleID)
+	opts, err := reliabilityOptionsFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, err := rt.analyticsSvc.SummaryWithOptions(tid, scaleID, opts)
 	if err != nil {
 		rt.writeServiceError(w, err)
 		return
@@ -1007,6 +2379,120 @@ func (rt *Router) handleAdminAnalyticsSummary(w http.ResponseWriter, r *http.Req
 	_ = json.NewEncoder(w).Encode(summary)
 }
 
+// GET /api/admin/analytics/items?scale_id=...&missing=listwise|pairwise
+func (rt *Router) handleAdminItemAnalysis(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tid, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	scaleID := r.URL.Query().Get("scale_id")
+	if scaleID == "" {
+		http.Error(w, "scale_id required", http.StatusBadRequest)
+		return
+	}
+	missing := r.URL.Query().Get("missing")
+	if missing != "" && missing != "listwise" && missing != "pairwise" {
+		http.Error(w, "missing must be listwise or pairwise", http.StatusBadRequest)
+		return
+	}
+	analysis, err := rt.analyticsSvc.ItemAnalysis(tid, scaleID, missing == "pairwise")
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(analysis)
+}
+
+// GET /api/admin/analytics/efa?scale_id=...&item_ids=a,b,c&factors=2&extraction=paf|minres&rotation=varimax|oblimin|none&iterations=100&seed=1
+func (rt *Router) handleAdminEFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tid, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	scaleID := q.Get("scale_id")
+	if scaleID == "" {
+		http.Error(w, "scale_id required", http.StatusBadRequest)
+		return
+	}
+	opts := services.EFAOptions{Extraction: q.Get("extraction"), Rotation: q.Get("rotation")}
+	for _, id := range strings.Split(q.Get("item_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			opts.ItemIDs = append(opts.ItemIDs, id)
+		}
+	}
+	for key, dst := range map[string]*int{"factors": &opts.Factors, "iterations": &opts.Iterations} {
+		if v := q.Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+key, http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	if v := q.Get("seed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid seed", http.StatusBadRequest)
+			return
+		}
+		opts.Seed = n
+	}
+	res, err := rt.efaSvc.Run(tid, scaleID, opts)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/admin/analytics/compare?scale_id=...&group_by=condition|item&group_item=...&outcome_item=...
+func (rt *Router) handleAdminCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tid, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	scaleID := q.Get("scale_id")
+	if scaleID == "" {
+		http.Error(w, "scale_id required", http.StatusBadRequest)
+		return
+	}
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = services.GroupByCondition
+	}
+	res, err := rt.analyticsSvc.Compare(tid, scaleID, services.GroupCompareOptions{
+		GroupBy:       groupBy,
+		GroupItemID:   q.Get("group_item"),
+		OutcomeItemID: q.Get("outcome_item"),
+	})
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
 // --- Admin scale/item ops ---
 // GET /api/admin/scales/{id}    -> scale detail
 // GET /api/admin/scales/{id}/items -> full items
@@ -1029,6 +2515,41 @@ func (rt *Router) handleAdminScaleOps(w http.ResponseWriter, r *http.Request) {
 		rt.handleAdminScaleCollaborators(w, r, id)
 		return
 	}
+	// double data entry subresource (paper forms)
+	if len(parts) >= 2 && parts[1] == "entries" {
+		rt.handleAdminScaleEntries(w, r, id, parts[2:])
+		return
+	}
+	// adaptive testing settings and sessions
+	if len(parts) >= 2 && parts[1] == "cat" {
+		rt.handleAdminScaleCAT(w, r, id, parts[2:])
+		return
+	}
+	// participant feedback report configuration
+	if len(parts) == 2 && parts[1] == "feedback" {
+		rt.handleAdminScaleFeedback(w, r, id)
+		return
+	}
+	// analytics aggregate consistency check
+	if len(parts) == 3 && parts[1] == "analytics" && parts[2] == "rebuild" {
+		rt.handleAdminScaleAnalyticsRebuild(w, r, id)
+		return
+	}
+	// live response feed (Server-Sent Events)
+	if len(parts) == 2 && parts[1] == "events" {
+		rt.handleAdminScaleEvents(w, r, id)
+		return
+	}
+	// norm tables and standardized scores
+	if len(parts) >= 2 && parts[1] == "norms" {
+		rt.handleAdminScaleNorms(w, r, id, parts[2:])
+		return
+	}
+	// IRT calibrations subresource
+	if len(parts) >= 2 && parts[1] == "irt" {
+		rt.handleAdminScaleIRT(w, r, id, parts[2:])
+		return
+	}
 	if len(parts) == 3 && parts[1] == "items" && parts[2] == "reorder" && r.Method == http.MethodPut {
 		rt.handleAdminScaleReorderItems(w, r, id)
 		return
@@ -1037,6 +2558,9 @@ func (rt *Router) handleAdminScaleOps(w http.ResponseWriter, r *http.Request) {
 		rt.handleAdminScaleImportItems(w, r, id)
 		return
 	}
+	if !rt.requireScaleTenant(w, r, id) {
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
 		rt.handleAdminScaleGet(w, r, id, parts)
@@ -1049,6 +2573,26 @@ func (rt *Router) handleAdminScaleOps(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// requireScaleTenant answers 403 unless the scale belongs to the caller's tenant, which for guest
+// collaborators is the tenant of the shared scale (see withScaleAccess).
+func (rt *Router) requireScaleTenant(w http.ResponseWriter, r *http.Request, scaleID string) bool {
+	tid, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	sc := rt.store.GetScale(scaleID)
+	if sc == nil {
+		http.NotFound(w, r)
+		return false
+	}
+	if sc.TenantID != tid {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 // handleAdminScaleCollaborators reduces cyclomatic complexity in handleAdminScaleOps by
 // factoring the collaborators subresource logic into a dedicated helper.
 func (rt *Router) handleAdminScaleCollaborators(w http.ResponseWriter, r *http.Request, scaleID string) {
@@ -1099,6 +2643,397 @@ func (rt *Router) handleAdminScaleCollaborators(w http.ResponseWriter, r *http.R
 	}
 }
 
+// handleAdminScaleEntries serves the double data entry workflow for paper questionnaires.
+// GET  /api/admin/scales/{id}/entries?status=...          -> list forms
+// POST /api/admin/scales/{id}/entries                      -> {form_id, answers:[{item_id, raw|raw_value}]}
+// GET  /api/admin/scales/{id}/entries/{form_id}            -> form with mismatches
+// POST /api/admin/scales/{id}/entries/{form_id}/resolve    -> {values:{item_id: raw}}
+func (rt *Router) handleAdminScaleEntries(w http.ResponseWriter, r *http.Request, scaleID string, rest []string) {
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		var forms []*services.DataEntryForm
+		forms, err = rt.doubleEntrySvc.ListForms(tenantID, scaleID, strings.TrimSpace(r.URL.Query().Get("status")))
+		res = map[string]any{"forms": forms}
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var in struct {
+			FormID  string `json:"form_id"`
+			Answers []struct {
+				ItemID string          `json:"item_id"`
+				Raw    json.RawMessage `json:"raw"`
+				RawInt *int            `json:"raw_value,omitempty"`
+			} `json:"answers"`
+		}
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		answers := make([]services.BulkAnswer, 0, len(in.Answers))
+		for _, a := range in.Answers {
+			answers = append(answers, services.BulkAnswer{ItemID: a.ItemID, Raw: a.Raw, RawInt: a.RawInt})
+		}
+		res, err = rt.doubleEntrySvc.SubmitEntry(tenantID, scaleID, in.FormID, actorEmail(r), answers)
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		res, err = rt.doubleEntrySvc.GetForm(tenantID, scaleID, rest[0])
+	case len(rest) == 2 && rest[1] == "resolve" && r.Method == http.MethodPost:
+		var in struct {
+			Values map[string]json.RawMessage `json:"values"`
+		}
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.doubleEntrySvc.Resolve(tenantID, scaleID, rest[0], actorEmail(r), in.Values)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// handleAdminScaleIRT calibrates and serves IRT models of a scale.
+// GET  /api/admin/scales/{id}/irt              -> list calibrations (version, model, scale_fingerprint, current)
+// POST /api/admin/scales/{id}/irt              -> {model: rasch|grm, max_iterations} calibrate a new version
+// GET  /api/admin/scales/{id}/irt/latest       -> latest calibration of the current scale version
+// GET  /api/admin/scales/{id}/irt/{version}    -> stored calibration
+func (rt *Router) handleAdminScaleIRT(w http.ResponseWriter, r *http.Request, scaleID string, rest []string) {
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		res, err = rt.irtSvc.ListCalibrations(tenantID, scaleID)
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var in struct {
+			Model         string `json:"model"`
+			MaxIterations int    `json:"max_iterations"`
+		}
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil && !errors.Is(derr, io.EOF) {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.irtSvc.Calibrate(tenantID, scaleID, services.IRTOptions{Model: in.Model, MaxIterations: in.MaxIterations})
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		version := 0
+		if rest[0] != "latest" {
+			version, err = strconv.Atoi(rest[0])
+			if err != nil || version <= 0 {
+				http.Error(w, "invalid version", http.StatusBadRequest)
+				return
+			}
+		}
+		res, err = rt.irtSvc.GetCalibration(tenantID, scaleID, version)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// handleAdminScaleFeedback reads and updates the participant feedback configuration.
+// GET /api/admin/scales/{id}/feedback -> config
+// PUT /api/admin/scales/{id}/feedback -> {enabled, intro_i18n, show_sample_mean, scores[]}
+func (rt *Router) handleAdminScaleFeedback(w http.ResponseWriter, r *http.Request, scaleID string) {
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		res, err = rt.feedbackSvc.GetConfig(tenantID, scaleID)
+	case http.MethodPut:
+		var in services.FeedbackConfig
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.feedbackSvc.UpdateConfig(tenantID, scaleID, in)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// POST /api/admin/scales/{id}/analytics/rebuild
+// Recomputes the scale's analytics aggregate from all responses and reports whether the stored one had drifted.
+func (rt *Router) handleAdminScaleAnalyticsRebuild(w http.ResponseWriter, r *http.Request, scaleID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	check, err := rt.analyticsSvc.RebuildAggregates(tenantID, scaleID)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(check)
+}
+
+// handleAdminScaleEvents streams a scale's live response feed as Server-Sent Events.
+// GET /api/admin/scales/{id}/events -> "ready" with the current response count, then one event per
+// submission, consent or deletion. Events carry IDs and counts only, never answers.
+func (rt *Router) handleAdminScaleEvents(w http.ResponseWriter, r *http.Request, scaleID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sc := rt.store.GetScale(scaleID)
+	if sc == nil || sc.TenantID != tenantID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	var count int
+	if sc.E2EEEnabled {
+		count = len(rt.store.ListE2EEResponses(scaleID))
+	} else {
+		var err error
+		if count, err = rt.analyticsSvc.ResponseCount(tenantID, scaleID); err != nil {
+			rt.writeServiceError(w, err)
+			return
+		}
+	}
+	events, cancel := rt.events.Subscribe(scaleID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	ready, _ := json.Marshal(map[string]any{"scale_id": scaleID, "e2ee": sc.E2EEEnabled, "responses": count})
+	fmt.Fprintf(w, "event: ready\ndata: %s\n\n", ready)
+	flusher.Flush()
+
+	ping := time.NewTicker(sseKeepAlive)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, open := <-events:
+			if !open {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// sseKeepAlive is how often an idle event stream sends a comment so proxies keep it open.
+const sseKeepAlive = 25 * time.Second
+
+// handleAdminScaleNorms manages norm tables and reports standardized scores.
+// GET    /api/admin/scales/{id}/norms        -> norm tables
+// POST   /api/admin/scales/{id}/norms        -> {key, name, source, item_ids, strata[]} create or replace by key
+// GET    /api/admin/scales/{id}/norms/scores -> z/T/percentile per participant and table
+// DELETE /api/admin/scales/{id}/norms/{key}  -> remove a table
+func (rt *Router) handleAdminScaleNorms(w http.ResponseWriter, r *http.Request, scaleID string, rest []string) {
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		var tables []*services.NormTable
+		tables, err = rt.normSvc.ListTables(tenantID, scaleID)
+		res = map[string]any{"tables": tables}
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var in services.NormTable
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.normSvc.SaveTable(tenantID, scaleID, &in)
+	case len(rest) == 1 && rest[0] == "scores" && r.Method == http.MethodGet:
+		var scores map[string][]services.NormScore
+		scores, err = rt.normSvc.ScoreParticipants(tenantID, scaleID)
+		res = map[string]any{"scores": scores}
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		err = rt.normSvc.DeleteTable(tenantID, scaleID, rest[0])
+		res = map[string]any{"ok": true}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// handleAdminScaleCAT configures adaptive delivery and lists administered sessions.
+// GET /api/admin/scales/{id}/cat          -> settings
+// PUT /api/admin/scales/{id}/cat          -> {enabled, calibration_version, min_items, max_items, se_target}
+// GET /api/admin/scales/{id}/cat/sessions -> sessions with administered sequence and final θ
+func (rt *Router) handleAdminScaleCAT(w http.ResponseWriter, r *http.Request, scaleID string, rest []string) {
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var (
+		res any
+		err error
+	)
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		res, err = rt.catSvc.GetSettings(tenantID, scaleID)
+	case len(rest) == 0 && r.Method == http.MethodPut:
+		var in services.CATSettings
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.catSvc.UpdateSettings(tenantID, scaleID, in)
+	case len(rest) == 1 && rest[0] == "sessions" && r.Method == http.MethodGet:
+		var sessions []*services.CATSession
+		sessions, err = rt.catSvc.ListSessions(tenantID, scaleID)
+		res = map[string]any{"sessions": sessions}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// handleAdminWebhooks manages the tenant's outgoing webhooks.
+// GET    /api/admin/webhooks                                  -> webhooks
+// POST   /api/admin/webhooks                                  -> {url, events[], scale_id?, active?} => {webhook, secret}
+// PUT    /api/admin/webhooks/{id}                             -> replace url/events/scale_id/active
+// DELETE /api/admin/webhooks/{id}                             -> remove with its deliveries
+// POST   /api/admin/webhooks/{id}/test                        -> send a ping now
+// GET    /api/admin/webhooks/{id}/deliveries?status=          -> deliveries, newest first
+// POST   /api/admin/webhooks/{id}/deliveries/{did}/redeliver  -> queue again with fresh attempts
+func (rt *Router) handleAdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := middleware.TenantIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if _, ok := rt.requireTenantRole(w, r, services.TenantRoleAdmin); !ok {
+		return
+	}
+	var rest []string
+	if p := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/webhooks"), "/"); p != "" {
+		rest = strings.Split(p, "/")
+	}
+	var (
+		res any
+		err error
+	)
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		var hooks []*services.Webhook
+		hooks, err = rt.webhookSvc.List(tenantID)
+		res = map[string]any{"webhooks": hooks, "events": services.WebhookEvents}
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		var in services.WebhookInput
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.webhookSvc.Create(tenantID, in)
+	case len(rest) == 1 && r.Method == http.MethodPut:
+		var in services.WebhookInput
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.webhookSvc.Update(tenantID, rest[0], in)
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		err = rt.webhookSvc.Delete(tenantID, rest[0])
+		res = map[string]any{"ok": true}
+	case len(rest) == 2 && rest[1] == "test" && r.Method == http.MethodPost:
+		res, err = rt.webhookSvc.Test(tenantID, rest[0])
+	case len(rest) == 2 && rest[1] == "deliveries" && r.Method == http.MethodGet:
+		var deliveries []*services.WebhookDelivery
+		deliveries, err = rt.webhookSvc.Deliveries(tenantID, rest[0], r.URL.Query().Get("status"))
+		res = map[string]any{"deliveries": deliveries}
+	case len(rest) == 4 && rest[1] == "deliveries" && rest[3] == "redeliver" && r.Method == http.MethodPost:
+		res, err = rt.webhookSvc.Redeliver(tenantID, rest[0], rest[2])
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
 // Helper: reorder items under a scale
 func (rt *Router) handleAdminScaleReorderItems(w http.ResponseWriter, r *http.Request, scaleID string) {
 	tid, ok := middleware.TenantIDFromContext(r.Context())
@@ -1149,6 +3084,10 @@ func (rt *Router) handleAdminScaleGet(w http.ResponseWriter, r *http.Request, sc
 
 // Helper: DELETE scale or responses
 func (rt *Router) handleAdminScaleDelete(w http.ResponseWriter, r *http.Request, scaleID string, parts []string) {
+	// deleting a scale and purging its responses both need a step-up token
+	if !rt.requireStepUp(w, r) {
+		return
+	}
 	if len(parts) == 2 && parts[1] == "responses" {
 		tid, ok := middleware.TenantIDFromContext(r.Context())
 		if !ok {
@@ -1214,6 +3153,9 @@ func (rt *Router) writeServiceError(w http.ResponseWriter, err error) {
 		case services.ErrorTooManyRequests:
 			status = http.StatusTooManyRequests
 		}
+		if se.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((se.RetryAfter+time.Second-1)/time.Second)))
+		}
 		http.Error(w, se.Message, status)
 		return
 	}
@@ -1241,6 +3183,9 @@ func (rt *Router) writeAuthJSONError(w http.ResponseWriter, err error) {
 			status = http.StatusUnauthorized
 		case services.ErrorConflict:
 			status = http.StatusConflict
+		case services.ErrorTooManyRequests:
+			middleware.WriteTooManyRequests(w, se.RetryAfter, se.Message)
+			return
 		}
 		rt.writeJSONError(w, status, se.Message)
 		return
@@ -1252,10 +3197,14 @@ func (rt *Router) writeAuthJSONError(w http.ResponseWriter, err error) {
 // DELETE /api/admin/items/{id}
 func (rt *Router) handleAdminItemOps(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/admin/items/")
-	if id == "" {
+	it := rt.store.GetItem(id)
+	if id == "" || it == nil {
 		http.NotFound(w, r)
 		return
 	}
+	if !rt.requireScaleTenant(w, r, it.ScaleID) {
+		return
+	}
 	switch r.Method {
 	case http.MethodPut:
 		var in services.Item
@@ -1303,6 +3252,39 @@ func (rt *Router) handleSelfExportParticipant(w http.ResponseWriter, r *http.Req
 	_ = json.NewEncoder(w).Encode(res)
 }
 
+// --- Participant feedback report (self token) ---
+// GET /api/self/participant/feedback?pid=...&token=...&lang=...
+func (rt *Router) handleSelfFeedback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	pid := strings.TrimSpace(r.URL.Query().Get("pid"))
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	res, err := rt.feedbackSvc.SelfReport(pid, token, r.URL.Query().Get("lang"))
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// GET /api/scales/{id}/feedback/spec -> deterministic report spec for client-side evaluation (E2EE)
+func (rt *Router) handleFeedbackSpec(w http.ResponseWriter, r *http.Request, scaleID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	spec, err := rt.feedbackSvc.Spec(scaleID)
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(spec)
+}
+
 // --- GDPR self-service: participant non-E2EE delete ---
 // POST /api/self/participant/delete?pid=...&token=...&hard=true
 func (rt *Router) handleSelfDeleteParticipant(w http.ResponseWriter, r *http.Request) {
@@ -1365,18 +3347,70 @@ func (rt *Router) handleAuthMe(w http.ResponseWriter, r *http.Request) {
 	// Require claims
 	if c, ok := middleware.ClaimsFromContext(r.Context()); ok {
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"user_id": c.UID, "tenant_id": c.TID, "email": c.Email})
+		verified, err := rt.accountSvc.IsVerified(c.Email)
+		if err != nil {
+			rt.writeServiceError(w, err)
+			return
+		}
+		role, err := rt.memberSvc.Role(c.TID, c.UID)
+		if err != nil {
+			rt.writeServiceError(w, err)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"user_id": c.UID, "tenant_id": c.TID, "email": c.Email, "email_verified": verified, "role": role})
 		return
 	}
 	http.Error(w, "unauthorized", http.StatusUnauthorized)
 }
 
+// GET/POST /api/auth/tokens, DELETE /api/auth/tokens/{id}: the signed-in user's personal API tokens.
+// Managing tokens needs a session; a token cannot mint or revoke tokens.
+func (rt *Router) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	c, ok := middleware.ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth/tokens"), "/")
+	var (
+		res any
+		err error
+	)
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		var tokens []*services.APIToken
+		tokens, err = rt.apiTokenSvc.List(c.TID, c.UID)
+		res = map[string]any{"tokens": tokens, "scopes": services.APITokenScopes}
+	case id == "" && r.Method == http.MethodPost:
+		var in services.APITokenInput
+		if derr := json.NewDecoder(r.Body).Decode(&in); derr != nil {
+			http.Error(w, derr.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err = rt.apiTokenSvc.Create(c.TID, c.UID, c.Email, in)
+	case id != "" && r.Method == http.MethodDelete:
+		res, err = rt.apiTokenSvc.Revoke(c.TID, c.UID, c.Email, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		rt.writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
 // Helper: invite collaborator
 func (rt *Router) handleAdminScaleInvite(w http.ResponseWriter, r *http.Request, id string) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if !rt.requireVerified(w, r) {
+		return
+	}
 	tenantID, ok := middleware.TenantIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
@@ -1392,17 +3426,18 @@ func (rt *Router) handleAdminScaleInvite(w http.ResponseWriter, r *http.Request,
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	token := strings.ReplaceAll(base64.StdEncoding.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)+":"+id+":"+in.Email)), "=", "")
-	if len(token) > 32 {
-		token = token[:32]
+	base, ok := rt.mailBase(w)
+	if !ok {
+		return
 	}
-	inv := &ScaleInvite{Token: token, TenantID: sc.TenantID, ScaleID: id, Email: strings.TrimSpace(in.Email), Role: strings.TrimSpace(in.Role), CreatedAt: time.Now().UTC(), ExpiresAt: time.Now().UTC().Add(7 * 24 * time.Hour)}
-	if _, err := rt.store.CreateInvite(inv); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	c, _ := middleware.ClaimsFromContext(r.Context())
+	inv, err := rt.inviteSvc.Create(r.Context(), tenantID, id, c.UID, c.Email, in.Email, in.Role, base)
+	if err != nil {
+		rt.writeServiceError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"token": token, "expires_at": inv.ExpiresAt.Format(time.RFC3339), "invite_url": "/auth?invite=" + token + "&email=" + inv.Email})
+	_ = json.NewEncoder(w).Encode(map[string]any{"id": inv.ID, "token": inv.Token, "expires_at": inv.ExpiresAt.Format(time.RFC3339), "invite_url": services.InviteURL(base, inv.Token)})
 }
 
 // Helper: import items CSV
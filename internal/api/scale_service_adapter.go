@@ -80,7 +80,7 @@ func (a *scaleStoreAdapter) DeleteResponsesByScale(scaleID string) (int, error)
 }
 
 func (a *scaleStoreAdapter) AddAudit(entry services.AuditEntry) {
-	a.store.AddAudit(AuditEntry{Time: entry.Time, Actor: entry.Actor, Action: entry.Action, Target: entry.Target, Note: entry.Note})
+	recordAudit(a.store, entry)
 }
 
 func convertServiceScale(sc *services.Scale) *Scale {
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type sessionStoreAdapter struct {
+	store Store
+}
+
+func newSessionStoreAdapter(store Store) services.SessionStore {
+	return &sessionStoreAdapter{store: store}
+}
+
+func (a *sessionStoreAdapter) SaveSession(s *services.Session) error {
+	if s == nil {
+		return services.NewInvalidError("session required")
+	}
+	if !a.store.SaveSession(&Session{ID: s.ID, UserID: s.UserID, TenantID: s.TenantID, Email: s.Email, UserAgent: s.UserAgent,
+		IP: s.IP, RefreshHash: s.RefreshHash, PrevRefreshHash: s.PrevRefreshHash, CreatedAt: s.CreatedAt, LastSeenAt: s.LastSeenAt,
+		ExpiresAt: s.ExpiresAt, RevokedAt: s.RevokedAt}) {
+		return services.NewConflictError("unable to save session")
+	}
+	return nil
+}
+
+func (a *sessionStoreAdapter) GetSession(id string) (*services.Session, error) {
+	s := a.store.GetSession(id)
+	if s == nil {
+		return nil, nil
+	}
+	return convertAPISession(s), nil
+}
+
+func (a *sessionStoreAdapter) GetSessionByRefreshHash(hash string) (*services.Session, error) {
+	s := a.store.GetSessionByRefreshHash(hash)
+	if s == nil {
+		return nil, nil
+	}
+	return convertAPISession(s), nil
+}
+
+func (a *sessionStoreAdapter) RotateSession(s *services.Session, oldHash string) (bool, error) {
+	return a.store.RotateSession(&Session{ID: s.ID, UserID: s.UserID, TenantID: s.TenantID, Email: s.Email, UserAgent: s.UserAgent,
+		IP: s.IP, RefreshHash: s.RefreshHash, PrevRefreshHash: s.PrevRefreshHash, CreatedAt: s.CreatedAt, LastSeenAt: s.LastSeenAt,
+		ExpiresAt: s.ExpiresAt, RevokedAt: s.RevokedAt}, oldHash), nil
+}
+
+func (a *sessionStoreAdapter) ListSessionsByUser(userID string) ([]*services.Session, error) {
+	list := a.store.ListSessionsByUser(userID)
+	out := make([]*services.Session, 0, len(list))
+	for _, s := range list {
+		out = append(out, convertAPISession(s))
+	}
+	return out, nil
+}
+
+func (a *sessionStoreAdapter) AddAudit(entry services.AuditEntry) {
+	recordAudit(a.store, entry)
+}
+
+func convertAPISession(s *Session) *services.Session {
+	return &services.Session{ID: s.ID, UserID: s.UserID, TenantID: s.TenantID, Email: s.Email, UserAgent: s.UserAgent,
+		IP: s.IP, RefreshHash: s.RefreshHash, PrevRefreshHash: s.PrevRefreshHash, CreatedAt: s.CreatedAt, LastSeenAt: s.LastSeenAt,
+		ExpiresAt: s.ExpiresAt, RevokedAt: s.RevokedAt}
+}
+
+var _ services.SessionStore = (*sessionStoreAdapter)(nil)
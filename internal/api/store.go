@@ -72,6 +72,8 @@ type Participant struct {
 	SelfToken string `json:"self_token,omitempty"`
 	// ConsentID links to a ConsentRecord.ID if provided at submission time
 	ConsentID string `json:"consent_id,omitempty"`
+	// Condition is the experimental condition (arm) label supplied at submission time
+	Condition string `json:"condition,omitempty"`
 }
 
 type Response struct {
@@ -143,16 +145,289 @@ type ScaleCollaborator struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// ScaleInvite represents a pending invitation for a collaborator to join a tenant/scale.
-type ScaleInvite struct {
-	Token      string    `json:"token"`
+// Invite is an invitation into a tenant, or with ScaleID onto a scale (see services.Invite).
+// Only the SHA-256 hash of its token is kept.
+type Invite struct {
+	ID         string    `json:"id"`
 	TenantID   string    `json:"tenant_id"`
-	ScaleID    string    `json:"scale_id"`
+	ScaleID    string    `json:"scale_id,omitempty"`
 	Email      string    `json:"email"`
 	Role       string    `json:"role"`
+	TokenHash  string    `json:"token_hash"`
+	InvitedBy  string    `json:"invited_by"`
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
 	AcceptedAt time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy string    `json:"accepted_by,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+}
+
+// DataEntryMismatch is one disagreeing item between two independent entries of a paper form.
+type DataEntryMismatch struct {
+	ItemID   string          `json:"item_id"`
+	First    json.RawMessage `json:"first,omitempty"`
+	Second   json.RawMessage `json:"second,omitempty"`
+	Resolved json.RawMessage `json:"resolved,omitempty"`
+}
+
+// DataEntryForm stores the double data entry state of one paper form (keyed by scale + form ID).
+type DataEntryForm struct {
+	ScaleID       string                     `json:"scale_id"`
+	FormID        string                     `json:"form_id"`
+	FirstEntry    map[string]json.RawMessage `json:"first_entry,omitempty"`
+	FirstBy       string                     `json:"first_by,omitempty"`
+	SecondEntry   map[string]json.RawMessage `json:"second_entry,omitempty"`
+	SecondBy      string                     `json:"second_by,omitempty"`
+	Status        string                     `json:"status"`
+	Mismatches    []DataEntryMismatch        `json:"mismatches,omitempty"`
+	ResolvedBy    string                     `json:"resolved_by,omitempty"`
+	ParticipantID string                     `json:"participant_id,omitempty"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
+
+// IRTCalibration is a stored IRT fit; Result holds the full calibration JSON produced by the
+// IRT service so the store does not need to know its shape.
+type IRTCalibration struct {
+	ScaleID          string          `json:"scale_id"`
+	Version          int             `json:"version"`
+	ScaleFingerprint string          `json:"scale_fingerprint"`
+	Model            string          `json:"model"`
+	Result           json.RawMessage `json:"result"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+// NormTable is a stored norm table; Definition holds the strata and item selection defined by
+// the norms service.
+type NormTable struct {
+	ScaleID    string          `json:"scale_id"`
+	Key        string          `json:"key"`
+	Definition json.RawMessage `json:"definition"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// FeedbackConfig is a scale's participant feedback configuration; Config holds the JSON defined
+// by the feedback service.
+type FeedbackConfig struct {
+	ScaleID   string          `json:"scale_id"`
+	Enabled   bool            `json:"enabled"`
+	Config    json.RawMessage `json:"config"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// AnalyticsAggregate is the running analytics aggregate of a scale; Data holds the JSON defined by
+// services.AnalyticsAggregate.
+type AnalyticsAggregate struct {
+	ScaleID   string          `json:"scale_id"`
+	Data      json.RawMessage `json:"data"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Webhook is a tenant's webhook subscription (see services.Webhook); Secret is the HMAC signing key.
+type Webhook struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	ScaleID   string    `json:"scale_id,omitempty"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one entry of the webhook delivery queue (see services.WebhookDelivery).
+type WebhookDelivery struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	TenantID      string          `json:"tenant_id"`
+	ScaleID       string          `json:"scale_id,omitempty"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at,omitempty"`
+	LastAttemptAt time.Time       `json:"last_attempt_at,omitempty"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   time.Time       `json:"delivered_at,omitempty"`
+}
+
+// MFAConfig is a user's TOTP enrollment (see services.MFAConfig); recovery codes are stored hashed.
+type MFAConfig struct {
+	UserID         string    `json:"user_id"`
+	Secret         string    `json:"secret"`
+	Enabled        bool      `json:"enabled"`
+	RecoveryHashes []string  `json:"recovery_hashes"`
+	LastStep       int64     `json:"last_step"`
+	CreatedAt      time.Time `json:"created_at"`
+	EnabledAt      time.Time `json:"enabled_at,omitempty"`
+}
+
+// EmailToken is a password reset or email verification token (see services.EmailToken); only its hash is stored.
+type EmailToken struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Purpose   string    `json:"purpose"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at,omitempty"`
+}
+
+// LoginThrottle counts recent failed sign-ins of an account or IP (see services.LoginThrottle).
+type LoginThrottle struct {
+	Key           string    `json:"key"`
+	TenantID      string    `json:"tenant_id"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until,omitempty"`
+}
+
+// TenantMember is a user's role in a tenant (see services.Membership). Email and TenantName are
+// filled in by the listings.
+type TenantMember struct {
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	Role       string    `json:"role"`
+	CreatedAt  time.Time `json:"created_at"`
+	Email      string    `json:"-"`
+	TenantName string    `json:"-"`
+}
+
+// LegacyMemberships derives memberships for data written before tenants had members: the first
+// user of each tenant becomes its owner and everybody else an admin, as all of them could
+// administer the tenant before.
+func LegacyMemberships(users []*User) []*TenantMember {
+	sorted := make([]*User, 0, len(users))
+	for _, u := range users {
+		if u != nil && u.TenantID != "" {
+			sorted = append(sorted, u)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	owned := map[string]bool{}
+	out := []*TenantMember{}
+	for _, u := range sorted {
+		role := "admin"
+		if !owned[u.TenantID] {
+			role, owned[u.TenantID] = "owner", true
+		}
+		out = append(out, &TenantMember{TenantID: u.TenantID, UserID: u.ID, Role: role, CreatedAt: u.CreatedAt})
+	}
+	return out
+}
+
+// OIDCConfig is a tenant's OpenID Connect provider (see services.OIDCConfig).
+type OIDCConfig struct {
+	TenantID       string            `json:"tenant_id"`
+	Enabled        bool              `json:"enabled"`
+	Issuer         string            `json:"issuer"`
+	ClientID       string            `json:"client_id"`
+	ClientSecret   string            `json:"client_secret"`
+	Scopes         string            `json:"scopes"`
+	AllowedDomains []string          `json:"allowed_domains"`
+	RoleClaim      string            `json:"role_claim"`
+	RoleMap        map[string]string `json:"role_map"`
+	DefaultRole    string            `json:"default_role"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// OIDCIdentity links a provider subject to a user.
+type OIDCIdentity struct {
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	UserID      string    `json:"user_id"`
+	TenantID    string    `json:"tenant_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// Session is a server-side sign-in (see services.Session); refresh token hashes are stored, not tokens.
+type Session struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"user_id"`
+	TenantID        string    `json:"tenant_id"`
+	Email           string    `json:"email"`
+	UserAgent       string    `json:"user_agent"`
+	IP              string    `json:"ip"`
+	RefreshHash     string    `json:"refresh_hash"`
+	PrevRefreshHash string    `json:"prev_refresh_hash,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	RevokedAt       time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIToken is a personal access token (see services.APIToken); TokenHash is the SHA-256 of the token.
+type APIToken struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	Email      string    `json:"email"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	TokenHash  string    `json:"token_hash"`
+	Scopes     []string  `json:"scopes"`
+	ScaleID    string    `json:"scale_id,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+}
+
+// CATSettings enables adaptive delivery for a scale (see services.CATSettings).
+type CATSettings struct {
+	ScaleID            string    `json:"scale_id"`
+	Enabled            bool      `json:"enabled"`
+	CalibrationVersion int       `json:"calibration_version"`
+	MinItems           int       `json:"min_items"`
+	MaxItems           int       `json:"max_items"`
+	SETarget           float64   `json:"se_target"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// CATStep is one administered item of an adaptive session.
+type CATStep struct {
+	ItemID      string          `json:"item_id"`
+	Raw         json.RawMessage `json:"raw"`
+	Score       int             `json:"score"`
+	Information float64         `json:"information"`
+	Theta       float64         `json:"theta"`
+	SE          float64         `json:"se"`
+	AnsweredAt  time.Time       `json:"answered_at"`
+}
+
+// CATSession stores the state of an adaptive administration; TokenHash is the SHA-256 of the
+// participant's session token.
+type CATSession struct {
+	ID                 string    `json:"id"`
+	ScaleID            string    `json:"scale_id"`
+	TokenHash          string    `json:"token_hash"`
+	CalibrationVersion int       `json:"calibration_version"`
+	Status             string    `json:"status"`
+	StopReason         string    `json:"stop_reason,omitempty"`
+	NextItemID         string    `json:"next_item_id,omitempty"`
+	NextInformation    float64   `json:"next_information,omitempty"`
+	Steps              []CATStep `json:"steps"`
+	Theta              float64   `json:"theta"`
+	SE                 float64   `json:"se"`
+	ParticipantID      string    `json:"participant_id,omitempty"`
+	ParticipantEmail   string    `json:"participant_email,omitempty"`
+	ConsentID          string    `json:"consent_id,omitempty"`
+	Condition          string    `json:"condition,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	FinishedAt         time.Time `json:"finished_at,omitempty"`
 }
 
 type memoryStore struct {
@@ -167,6 +442,7 @@ type memoryStore struct {
 	usersByEmail map[string]*User
 	aiConfigs    map[string]*TenantAIConfig
 	audit        []AuditEntry
+	checkpoints  []AuditCheckpoint
 
 	projectKeys  map[string][]*ProjectKey
 	snapshotPath string
@@ -178,7 +454,33 @@ type memoryStore struct {
 
 	consents []*ConsentRecord
 	collabs  map[string]map[string]*ScaleCollaborator // scale_id -> user_id -> collab
-	invites  map[string]*ScaleInvite
+	invites  map[string]*Invite                       // invite id -> invitation
+
+	dataEntries map[string]*DataEntryForm // scale_id + "/" + form_id -> form
+
+	irtCalibrations map[string][]*IRTCalibration   // scale_id -> calibrations ordered by version
+	catSettings     map[string]*CATSettings        // scale_id -> settings
+	catSessions     map[string]*CATSession         // session id -> session
+	normTables      map[string][]*NormTable        // scale_id -> tables ordered by key
+	feedbackConfigs map[string]*FeedbackConfig     // scale_id -> feedback report config
+	aggregates      map[string]*AnalyticsAggregate // scale_id -> analytics aggregate
+
+	webhooks          map[string]*Webhook         // webhook id -> subscription
+	webhookDeliveries map[string]*WebhookDelivery // delivery id -> queued delivery
+
+	apiTokens map[string]*APIToken  // token id -> personal API token
+	sessions  map[string]*Session   // session id -> server-side session
+	rotated   map[string]string     // rotated refresh hash -> session id
+	mfa       map[string]*MFAConfig // user id -> TOTP enrollment
+
+	oidcConfigs    map[string]*OIDCConfig   // tenant id -> identity provider
+	oidcIdentities map[string]*OIDCIdentity // issuer + "\x00" + subject -> linked user
+
+	emailTokens map[string]*EmailToken // token id -> password reset / verification token
+
+	loginThrottles map[string]*LoginThrottle // "account:<email>" / "ip:<addr>" -> failed sign-ins
+
+	members map[string]*TenantMember // tenant id + "\x00" + user id -> membership
 }
 
 func (s *memoryStore) buildSnapshot() *LegacySnapshot {
@@ -193,116 +495,1012 @@ func (s *memoryStore) buildSnapshot() *LegacySnapshot {
 		Users:         []*User{},
 		AIConfigs:     []*TenantAIConfig{},
 		Audit:         append([]AuditEntry(nil), s.audit...),
+		Checkpoints:   append([]AuditCheckpoint(nil), s.checkpoints...),
+		Memberships:   []*TenantMember{},
+	}
+	for _, sc := range s.scales {
+		snap.Scales = append(snap.Scales, sc)
+	}
+	for _, it := range s.items {
+		snap.Items = append(snap.Items, it)
+	}
+	for _, p := range s.participants {
+		snap.Participants = append(snap.Participants, p)
+	}
+	snap.Responses = append(snap.Responses, s.responses...)
+	snap.ResponsesE2EE = append(snap.ResponsesE2EE, s.e2ee...)
+	for k, v := range s.projectKeys {
+		snap.ProjectKeys[k] = append([]*ProjectKey(nil), v...)
+	}
+	for _, t := range s.tenants {
+		snap.Tenants = append(snap.Tenants, t)
+	}
+	for _, u := range s.usersByEmail {
+		snap.Users = append(snap.Users, u)
+	}
+	for _, a := range s.aiConfigs {
+		snap.AIConfigs = append(snap.AIConfigs, a)
+	}
+	snap.Consents = append(snap.Consents, s.consents...)
+	for _, m := range s.members {
+		cp := *m
+		snap.Memberships = append(snap.Memberships, &cp)
+	}
+	sort.Slice(snap.Memberships, func(i, j int) bool {
+		return memberKey(snap.Memberships[i].TenantID, snap.Memberships[i].UserID) < memberKey(snap.Memberships[j].TenantID, snap.Memberships[j].UserID)
+	})
+	return snap
+}
+
+// --- Collaborators (memory) ---
+func (s *memoryStore) AddScaleCollaborator(scaleID, userID, role string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.collabs == nil {
+		s.collabs = map[string]map[string]*ScaleCollaborator{}
+	}
+	if s.collabs[scaleID] == nil {
+		s.collabs[scaleID] = map[string]*ScaleCollaborator{}
+	}
+	// attempt to find user email from usersByEmail map
+	var email string
+	for e, u := range s.usersByEmail {
+		if u != nil && u.ID == userID {
+			email = e
+			break
+		}
+	}
+	s.collabs[scaleID][userID] = &ScaleCollaborator{ScaleID: scaleID, UserID: userID, Email: email, Role: role, CreatedAt: time.Now().UTC()}
+	return true
+}
+
+func (s *memoryStore) RemoveScaleCollaborator(scaleID, userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m := s.collabs[scaleID]; m != nil {
+		delete(m, userID)
+		return true
+	}
+	return false
+}
+
+func (s *memoryStore) ListScaleCollaborators(scaleID string) []ScaleCollaborator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := s.collabs[scaleID]
+	if m == nil {
+		return nil
+	}
+	out := make([]ScaleCollaborator, 0, len(m))
+	for _, c := range m {
+		out = append(out, *c)
+	}
+	return out
+}
+
+func (s *memoryStore) ListUserCollaborations(userID string) []ScaleCollaborator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []ScaleCollaborator{}
+	for _, m := range s.collabs {
+		if c := m[userID]; c != nil {
+			out = append(out, *c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ScaleID < out[j].ScaleID })
+	return out
+}
+
+func (s *memoryStore) RemoveTenantCollaborations(tenantID, userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for scaleID, m := range s.collabs {
+		if sc := s.scales[scaleID]; sc != nil && sc.TenantID == tenantID {
+			delete(m, userID)
+		}
+	}
+	return true
+}
+
+// --- Invitations (memory) ---
+func (s *memoryStore) SaveInvite(inv *Invite) bool {
+	if inv == nil || inv.ID == "" || inv.TokenHash == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.invites == nil {
+		s.invites = map[string]*Invite{}
+	}
+	cp := *inv
+	s.invites[inv.ID] = &cp
+	return true
+}
+
+func (s *memoryStore) GetInvite(id string) *Invite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if v, ok := s.invites[id]; ok {
+		cp := *v
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) GetInviteByHash(hash string) *Invite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.invites {
+		if v.TokenHash == hash {
+			cp := *v
+			return &cp
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListInvites(tenantID string) []*Invite {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*Invite{}
+	for _, v := range s.invites {
+		if v.TenantID == tenantID {
+			cp := *v
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// --- Double data entry (memory) ---
+func (s *memoryStore) GetDataEntryForm(scaleID, formID string) *DataEntryForm {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if f, ok := s.dataEntries[scaleID+"/"+formID]; ok {
+		cp := *f
+		cp.Mismatches = append([]DataEntryMismatch(nil), f.Mismatches...)
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveDataEntryForm(f *DataEntryForm, prevStatus string) bool {
+	if f == nil || strings.TrimSpace(f.ScaleID) == "" || strings.TrimSpace(f.FormID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveDataEntryFormLocked(f, prevStatus)
+}
+
+func (s *memoryStore) CommitDataEntryForm(f *DataEntryForm, prevStatus string, p *Participant, rs []*Response) bool {
+	if f == nil || p == nil || strings.TrimSpace(f.ScaleID) == "" || strings.TrimSpace(f.FormID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.saveDataEntryFormLocked(f, prevStatus) {
+		return false
+	}
+	if p.SelfToken == "" {
+		rb := make([]byte, 24)
+		_, _ = rand.Read(rb)
+		p.SelfToken = base64.RawURLEncoding.EncodeToString(rb)
+	}
+	s.participants[p.ID] = p
+	s.responses = append(s.responses, rs...)
+	s.saveLocked()
+	return true
+}
+
+// saveDataEntryFormLocked stores f if the stored form still has prevStatus ("" for none).
+func (s *memoryStore) saveDataEntryFormLocked(f *DataEntryForm, prevStatus string) bool {
+	key := f.ScaleID + "/" + f.FormID
+	if cur, ok := s.dataEntries[key]; ok && cur.Status != prevStatus || !ok && prevStatus != "" {
+		return false
+	}
+	if s.dataEntries == nil {
+		s.dataEntries = map[string]*DataEntryForm{}
+	}
+	cp := *f
+	cp.Mismatches = append([]DataEntryMismatch(nil), f.Mismatches...)
+	s.dataEntries[key] = &cp
+	return true
+}
+
+func (s *memoryStore) ListDataEntryForms(scaleID string) []*DataEntryForm {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*DataEntryForm{}
+	for _, f := range s.dataEntries {
+		if f.ScaleID == scaleID {
+			cp := *f
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FormID < out[j].FormID })
+	return out
+}
+
+// --- IRT calibrations (memory) ---
+func (s *memoryStore) SaveIRTCalibration(c *IRTCalibration) bool {
+	if c == nil || strings.TrimSpace(c.ScaleID) == "" || c.Version <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.irtCalibrations == nil {
+		s.irtCalibrations = map[string][]*IRTCalibration{}
+	}
+	for _, existing := range s.irtCalibrations[c.ScaleID] {
+		if existing.Version == c.Version {
+			return false
+		}
+	}
+	cp := *c
+	cp.Result = append(json.RawMessage(nil), c.Result...)
+	list := append(s.irtCalibrations[c.ScaleID], &cp)
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	s.irtCalibrations[c.ScaleID] = list
+	return true
+}
+
+func (s *memoryStore) ListIRTCalibrations(scaleID string) []*IRTCalibration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*IRTCalibration, 0, len(s.irtCalibrations[scaleID]))
+	for _, c := range s.irtCalibrations[scaleID] {
+		cp := *c
+		out = append(out, &cp)
+	}
+	return out
+}
+
+func (s *memoryStore) GetIRTCalibration(scaleID string, version int) *IRTCalibration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.irtCalibrations[scaleID] {
+		if c.Version == version {
+			cp := *c
+			return &cp
+		}
+	}
+	return nil
+}
+
+// --- Adaptive testing (memory) ---
+func (s *memoryStore) GetCATSettings(scaleID string) *CATSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c, ok := s.catSettings[scaleID]; ok {
+		cp := *c
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveCATSettings(c *CATSettings) bool {
+	if c == nil || strings.TrimSpace(c.ScaleID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.catSettings == nil {
+		s.catSettings = map[string]*CATSettings{}
+	}
+	cp := *c
+	s.catSettings[c.ScaleID] = &cp
+	return true
+}
+
+func (s *memoryStore) GetCATSession(id string) *CATSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if sess, ok := s.catSessions[id]; ok {
+		cp := *sess
+		cp.Steps = append([]CATStep(nil), sess.Steps...)
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveCATSession(sess *CATSession, prevStatus string, prevSteps int) bool {
+	if sess == nil || strings.TrimSpace(sess.ID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.catSessions[sess.ID]
+	if ok && (cur.Status != prevStatus || len(cur.Steps) != prevSteps) || !ok && prevStatus != "" {
+		return false
+	}
+	if s.catSessions == nil {
+		s.catSessions = map[string]*CATSession{}
+	}
+	cp := *sess
+	cp.Steps = append([]CATStep(nil), sess.Steps...)
+	s.catSessions[sess.ID] = &cp
+	return true
+}
+
+func (s *memoryStore) ListCATSessions(scaleID string) []*CATSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*CATSession{}
+	for _, sess := range s.catSessions {
+		if sess.ScaleID == scaleID {
+			cp := *sess
+			cp.Steps = append([]CATStep(nil), sess.Steps...)
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// --- Norm tables (memory) ---
+func (s *memoryStore) SaveNormTable(t *NormTable) bool {
+	if t == nil || strings.TrimSpace(t.ScaleID) == "" || strings.TrimSpace(t.Key) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.normTables == nil {
+		s.normTables = map[string][]*NormTable{}
+	}
+	cp := *t
+	cp.Definition = append(json.RawMessage(nil), t.Definition...)
+	list := s.normTables[t.ScaleID]
+	for i, existing := range list {
+		if existing.Key == t.Key {
+			list[i] = &cp
+			return true
+		}
+	}
+	list = append(list, &cp)
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	s.normTables[t.ScaleID] = list
+	return true
+}
+
+func (s *memoryStore) ListNormTables(scaleID string) []*NormTable {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*NormTable, 0, len(s.normTables[scaleID]))
+	for _, t := range s.normTables[scaleID] {
+		cp := *t
+		out = append(out, &cp)
+	}
+	return out
+}
+
+func (s *memoryStore) DeleteNormTable(scaleID, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.normTables[scaleID]
+	for i, t := range list {
+		if t.Key == key {
+			s.normTables[scaleID] = append(list[:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// --- Feedback configs (memory) ---
+func (s *memoryStore) GetFeedbackConfig(scaleID string) *FeedbackConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c, ok := s.feedbackConfigs[scaleID]; ok {
+		cp := *c
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveFeedbackConfig(c *FeedbackConfig) bool {
+	if c == nil || strings.TrimSpace(c.ScaleID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.feedbackConfigs == nil {
+		s.feedbackConfigs = map[string]*FeedbackConfig{}
+	}
+	cp := *c
+	cp.Config = append(json.RawMessage(nil), c.Config...)
+	s.feedbackConfigs[c.ScaleID] = &cp
+	return true
+}
+
+// --- Analytics aggregates (memory) ---
+func (s *memoryStore) GetAnalyticsAggregate(scaleID string) *AnalyticsAggregate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if a, ok := s.aggregates[scaleID]; ok {
+		cp := *a
+		cp.Data = append(json.RawMessage(nil), a.Data...)
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveAnalyticsAggregate(a *AnalyticsAggregate) bool {
+	if a == nil || strings.TrimSpace(a.ScaleID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.scales[a.ScaleID] == nil {
+		return false
+	}
+	if s.aggregates == nil {
+		s.aggregates = map[string]*AnalyticsAggregate{}
+	}
+	cp := *a
+	cp.Data = append(json.RawMessage(nil), a.Data...)
+	s.aggregates[a.ScaleID] = &cp
+	return true
+}
+
+func (s *memoryStore) DeleteAnalyticsAggregate(scaleID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.aggregates[scaleID]; !ok {
+		return false
+	}
+	delete(s.aggregates, scaleID)
+	return true
+}
+
+func (s *memoryStore) ListAnalyticsAggregateScaleIDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]string, 0, len(s.aggregates))
+	for id := range s.aggregates {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// --- Webhooks (memory) ---
+func copyWebhook(w *Webhook) *Webhook {
+	cp := *w
+	cp.Events = append([]string(nil), w.Events...)
+	return &cp
+}
+
+func copyWebhookDelivery(d *WebhookDelivery) *WebhookDelivery {
+	cp := *d
+	cp.Payload = append(json.RawMessage(nil), d.Payload...)
+	return &cp
+}
+
+func (s *memoryStore) SaveWebhook(w *Webhook) bool {
+	if w == nil || strings.TrimSpace(w.ID) == "" || strings.TrimSpace(w.TenantID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.ScaleID != "" && s.scales[w.ScaleID] == nil {
+		return false
+	}
+	if s.webhooks == nil {
+		s.webhooks = map[string]*Webhook{}
+	}
+	s.webhooks[w.ID] = copyWebhook(w)
+	return true
+}
+
+func (s *memoryStore) GetWebhook(id string) *Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if w, ok := s.webhooks[id]; ok {
+		return copyWebhook(w)
+	}
+	return nil
+}
+
+func (s *memoryStore) ListWebhooks(tenantID string) []*Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*Webhook{}
+	for _, w := range s.webhooks {
+		if w.TenantID == tenantID {
+			out = append(out, copyWebhook(w))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *memoryStore) DeleteWebhook(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return false
+	}
+	s.deleteWebhookLocked(id)
+	return true
+}
+
+func (s *memoryStore) deleteWebhookLocked(id string) {
+	delete(s.webhooks, id)
+	for did, d := range s.webhookDeliveries {
+		if d.WebhookID == id {
+			delete(s.webhookDeliveries, did)
+		}
+	}
+}
+
+func (s *memoryStore) SaveWebhookDelivery(d *WebhookDelivery) bool {
+	if d == nil || strings.TrimSpace(d.ID) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.webhooks[d.WebhookID] == nil {
+		return false
+	}
+	if s.webhookDeliveries == nil {
+		s.webhookDeliveries = map[string]*WebhookDelivery{}
+	}
+	s.webhookDeliveries[d.ID] = copyWebhookDelivery(d)
+	return true
+}
+
+func (s *memoryStore) GetWebhookDelivery(id string) *WebhookDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if d, ok := s.webhookDeliveries[id]; ok {
+		return copyWebhookDelivery(d)
+	}
+	return nil
+}
+
+func (s *memoryStore) ListWebhookDeliveries(webhookID string) []*WebhookDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*WebhookDelivery{}
+	for _, d := range s.webhookDeliveries {
+		if d.WebhookID == webhookID {
+			out = append(out, copyWebhookDelivery(d))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+func (s *memoryStore) ListDueWebhookDeliveries(now time.Time, limit int) []*WebhookDelivery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*WebhookDelivery{}
+	for _, d := range s.webhookDeliveries {
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			out = append(out, copyWebhookDelivery(d))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NextAttemptAt.Before(out[j].NextAttemptAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// --- Personal API tokens (memory) ---
+func copyAPIToken(t *APIToken) *APIToken {
+	cp := *t
+	cp.Scopes = append([]string(nil), t.Scopes...)
+	return &cp
+}
+
+func (s *memoryStore) SaveAPIToken(t *APIToken) bool {
+	if t == nil || strings.TrimSpace(t.ID) == "" || strings.TrimSpace(t.TokenHash) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.ScaleID != "" && s.scales[t.ScaleID] == nil {
+		return false
+	}
+	if s.apiTokens == nil {
+		s.apiTokens = map[string]*APIToken{}
+	}
+	for id, other := range s.apiTokens {
+		if id != t.ID && other.TokenHash == t.TokenHash {
+			return false
+		}
+	}
+	s.apiTokens[t.ID] = copyAPIToken(t)
+	return true
+}
+
+func (s *memoryStore) GetAPIToken(id string) *APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.apiTokens[id]; ok {
+		return copyAPIToken(t)
+	}
+	return nil
+}
+
+func (s *memoryStore) GetAPITokenByHash(hash string) *APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, t := range s.apiTokens {
+		if t.TokenHash == hash {
+			return copyAPIToken(t)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) ListAPITokens(tenantID string) []*APIToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*APIToken{}
+	for _, t := range s.apiTokens {
+		if t.TenantID == tenantID {
+			out = append(out, copyAPIToken(t))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *memoryStore) TouchAPIToken(id string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.apiTokens[id]
+	if !ok {
+		return false
+	}
+	t.LastUsedAt = at
+	return true
+}
+
+// --- Sessions (memory) ---
+func (s *memoryStore) SaveSession(sess *Session) bool {
+	if sess == nil || strings.TrimSpace(sess.ID) == "" || strings.TrimSpace(sess.RefreshHash) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = map[string]*Session{}
+	}
+	cp := *sess
+	s.sessions[sess.ID] = &cp
+	return true
+}
+
+func (s *memoryStore) GetSession(id string) *Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if sess, ok := s.sessions[id]; ok {
+		cp := *sess
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) GetSessionByRefreshHash(hash string) *Session {
+	if hash == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if sess, ok := s.sessions[s.rotated[hash]]; ok {
+		cp := *sess
+		return &cp
+	}
+	for _, sess := range s.sessions {
+		if sess.RefreshHash == hash {
+			cp := *sess
+			return &cp
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) RotateSession(sess *Session, oldHash string) bool {
+	if sess == nil || strings.TrimSpace(sess.RefreshHash) == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, ok := s.sessions[sess.ID]
+	if !ok || cur.RefreshHash != oldHash || !cur.RevokedAt.IsZero() {
+		return false
+	}
+	if s.rotated == nil {
+		s.rotated = map[string]string{}
+	}
+	s.rotated[oldHash] = sess.ID
+	cp := *sess
+	s.sessions[sess.ID] = &cp
+	return true
+}
+
+func (s *memoryStore) ListSessionsByUser(userID string) []*Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*Session{}
+	for _, sess := range s.sessions {
+		if sess.UserID == userID {
+			cp := *sess
+			out = append(out, &cp)
+		}
 	}
-	for _, sc := range s.scales {
-		snap.Scales = append(snap.Scales, sc)
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// --- TOTP enrollments (memory) ---
+func (s *memoryStore) GetMFA(userID string) *MFAConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if m, ok := s.mfa[userID]; ok {
+		cp := *m
+		cp.RecoveryHashes = append([]string(nil), m.RecoveryHashes...)
+		return &cp
 	}
-	for _, it := range s.items {
-		snap.Items = append(snap.Items, it)
+	return nil
+}
+
+func (s *memoryStore) SaveMFA(m *MFAConfig) bool {
+	if m == nil || strings.TrimSpace(m.UserID) == "" || m.Secret == "" {
+		return false
 	}
-	for _, p := range s.participants {
-		snap.Participants = append(snap.Participants, p)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mfa == nil {
+		s.mfa = map[string]*MFAConfig{}
 	}
-	snap.Responses = append(snap.Responses, s.responses...)
-	snap.ResponsesE2EE = append(snap.ResponsesE2EE, s.e2ee...)
-	for k, v := range s.projectKeys {
-		snap.ProjectKeys[k] = append([]*ProjectKey(nil), v...)
+	cp := *m
+	cp.RecoveryHashes = append([]string(nil), m.RecoveryHashes...)
+	s.mfa[m.UserID] = &cp
+	return true
+}
+
+func (s *memoryStore) DeleteMFA(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.mfa[userID]
+	delete(s.mfa, userID)
+	return ok
+}
+
+// --- OpenID Connect (memory) ---
+func cloneOIDCConfig(c *OIDCConfig) *OIDCConfig {
+	cp := *c
+	cp.AllowedDomains = append([]string(nil), c.AllowedDomains...)
+	cp.RoleMap = make(map[string]string, len(c.RoleMap))
+	for k, v := range c.RoleMap {
+		cp.RoleMap[k] = v
 	}
-	for _, t := range s.tenants {
-		snap.Tenants = append(snap.Tenants, t)
+	return &cp
+}
+
+func (s *memoryStore) GetOIDCConfig(tenantID string) *OIDCConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c, ok := s.oidcConfigs[tenantID]; ok {
+		return cloneOIDCConfig(c)
 	}
-	for _, u := range s.usersByEmail {
-		snap.Users = append(snap.Users, u)
+	return nil
+}
+
+func (s *memoryStore) ListOIDCConfigs() []*OIDCConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*OIDCConfig, 0, len(s.oidcConfigs))
+	for _, c := range s.oidcConfigs {
+		out = append(out, cloneOIDCConfig(c))
 	}
-	for _, a := range s.aiConfigs {
-		snap.AIConfigs = append(snap.AIConfigs, a)
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out
+}
+
+func (s *memoryStore) SaveOIDCConfig(c *OIDCConfig) bool {
+	if c == nil || strings.TrimSpace(c.TenantID) == "" || c.Issuer == "" || c.ClientID == "" {
+		return false
 	}
-	snap.Consents = append(snap.Consents, s.consents...)
-	return snap
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oidcConfigs == nil {
+		s.oidcConfigs = map[string]*OIDCConfig{}
+	}
+	s.oidcConfigs[c.TenantID] = cloneOIDCConfig(c)
+	return true
 }
 
-// --- Collaborators (memory) ---
-func (s *memoryStore) AddScaleCollaborator(scaleID, userID, role string) bool {
+func (s *memoryStore) DeleteOIDCConfig(tenantID string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.collabs == nil {
-		s.collabs = map[string]map[string]*ScaleCollaborator{}
+	_, ok := s.oidcConfigs[tenantID]
+	delete(s.oidcConfigs, tenantID)
+	return ok
+}
+
+func (s *memoryStore) GetOIDCIdentity(issuer, subject string) *OIDCIdentity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id, ok := s.oidcIdentities[issuer+"\x00"+subject]; ok {
+		cp := *id
+		return &cp
 	}
-	if s.collabs[scaleID] == nil {
-		s.collabs[scaleID] = map[string]*ScaleCollaborator{}
+	return nil
+}
+
+func (s *memoryStore) SaveOIDCIdentity(id *OIDCIdentity) bool {
+	if id == nil || id.Issuer == "" || id.Subject == "" || id.UserID == "" {
+		return false
 	}
-	// attempt to find user email from usersByEmail map
-	var email string
-	for e, u := range s.usersByEmail {
-		if u != nil && u.ID == userID {
-			email = e
-			break
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oidcIdentities == nil {
+		s.oidcIdentities = map[string]*OIDCIdentity{}
 	}
-	s.collabs[scaleID][userID] = &ScaleCollaborator{ScaleID: scaleID, UserID: userID, Email: email, Role: role, CreatedAt: time.Now().UTC()}
+	cp := *id
+	s.oidcIdentities[id.Issuer+"\x00"+id.Subject] = &cp
 	return true
 }
 
-func (s *memoryStore) RemoveScaleCollaborator(scaleID, userID string) bool {
+// --- Email tokens (memory) ---
+func (s *memoryStore) SaveEmailToken(t *EmailToken) bool {
+	if t == nil || t.ID == "" || t.Hash == "" || t.UserID == "" {
+		return false
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if m := s.collabs[scaleID]; m != nil {
-		delete(m, userID)
-		return true
+	if s.emailTokens == nil {
+		s.emailTokens = map[string]*EmailToken{}
 	}
-	return false
+	cp := *t
+	s.emailTokens[t.ID] = &cp
+	return true
 }
 
-func (s *memoryStore) ListScaleCollaborators(scaleID string) []ScaleCollaborator {
+func (s *memoryStore) GetEmailTokenByHash(hash string) *EmailToken {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	m := s.collabs[scaleID]
-	if m == nil {
-		return nil
+	for _, t := range s.emailTokens {
+		if t.Hash == hash {
+			cp := *t
+			return &cp
+		}
 	}
-	out := make([]ScaleCollaborator, 0, len(m))
-	for _, c := range m {
-		out = append(out, *c)
+	return nil
+}
+
+func (s *memoryStore) ListEmailTokens(userID, purpose string) []*EmailToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*EmailToken{}
+	for _, t := range s.emailTokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			cp := *t
+			out = append(out, &cp)
+		}
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
 	return out
 }
 
-// --- Invitations (memory) ---
-func (s *memoryStore) CreateInvite(inv *ScaleInvite) (*ScaleInvite, error) {
+// --- Login throttles (memory) ---
+func (s *memoryStore) GetLoginThrottle(key string) *LoginThrottle {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.loginThrottles[key]; ok {
+		cp := *t
+		return &cp
+	}
+	return nil
+}
+
+func (s *memoryStore) SaveLoginThrottle(t *LoginThrottle) bool {
+	if t == nil || t.Key == "" {
+		return false
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if inv == nil || strings.TrimSpace(inv.Token) == "" {
-		return nil, errors.New("invalid invite")
+	if s.loginThrottles == nil {
+		s.loginThrottles = map[string]*LoginThrottle{}
 	}
-	if s.invites == nil {
-		s.invites = map[string]*ScaleInvite{}
+	cp := *t
+	s.loginThrottles[t.Key] = &cp
+	return true
+}
+
+func (s *memoryStore) DeleteLoginThrottle(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.loginThrottles[key]; !ok {
+		return false
 	}
-	cp := *inv
-	s.invites[cp.Token] = &cp
-	return &cp, nil
+	delete(s.loginThrottles, key)
+	return true
+}
+
+// --- Tenant memberships (memory) ---
+func memberKey(tenantID, userID string) string { return tenantID + "\x00" + userID }
+
+// memberViewLocked copies m with the member's email and the tenant's name.
+func (s *memoryStore) memberViewLocked(m *TenantMember) *TenantMember {
+	cp := *m
+	if u := s.userByIDLocked(m.UserID); u != nil {
+		cp.Email = u.Email
+	}
+	if t := s.tenants[m.TenantID]; t != nil {
+		cp.TenantName = t.Name
+	}
+	return &cp
 }
 
-func (s *memoryStore) GetInvite(token string) *ScaleInvite {
+func (s *memoryStore) GetTenantMember(tenantID, userID string) *TenantMember {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if s.invites == nil {
-		return nil
-	}
-	if v, ok := s.invites[token]; ok {
-		cp := *v
-		return &cp
+	if m, ok := s.members[memberKey(tenantID, userID)]; ok {
+		return s.memberViewLocked(m)
 	}
 	return nil
 }
 
-func (s *memoryStore) MarkInviteAccepted(token string) bool {
+func (s *memoryStore) ListTenantMembers(tenantID string) []*TenantMember {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*TenantMember{}
+	for _, m := range s.members {
+		if m.TenantID == tenantID {
+			out = append(out, s.memberViewLocked(m))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *memoryStore) ListUserTenants(userID string) []*TenantMember {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := []*TenantMember{}
+	for _, m := range s.members {
+		if m.UserID == userID {
+			out = append(out, s.memberViewLocked(m))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *memoryStore) SaveTenantMember(m *TenantMember) bool {
+	if m == nil || m.TenantID == "" || m.UserID == "" {
+		return false
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if v, ok := s.invites[token]; ok {
-		v.AcceptedAt = time.Now().UTC()
-		return true
+	if s.members == nil {
+		s.members = map[string]*TenantMember{}
 	}
-	return false
+	cp := *m
+	cp.Email, cp.TenantName = "", ""
+	s.members[memberKey(m.TenantID, m.UserID)] = &cp
+	s.saveLocked()
+	return true
+}
+
+func (s *memoryStore) DeleteTenantMember(tenantID, userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := memberKey(tenantID, userID)
+	if _, ok := s.members[key]; !ok {
+		return false
+	}
+	delete(s.members, key)
+	s.saveLocked()
+	return true
 }
 
 // MemoryStoreSnapshot returns a clone of all legacy data when backed by memoryStore.
@@ -334,7 +1532,30 @@ func newMemoryStore(path string) *memoryStore {
 		lastExport:   map[string]time.Time{},
 		consents:     []*ConsentRecord{},
 		collabs:      map[string]map[string]*ScaleCollaborator{},
-		invites:      map[string]*ScaleInvite{},
+		invites:      map[string]*Invite{},
+		dataEntries:  map[string]*DataEntryForm{},
+
+		irtCalibrations: map[string][]*IRTCalibration{},
+		catSettings:     map[string]*CATSettings{},
+		catSessions:     map[string]*CATSession{},
+		normTables:      map[string][]*NormTable{},
+		feedbackConfigs: map[string]*FeedbackConfig{},
+		aggregates:      map[string]*AnalyticsAggregate{},
+
+		webhooks:          map[string]*Webhook{},
+		webhookDeliveries: map[string]*WebhookDelivery{},
+
+		apiTokens: map[string]*APIToken{},
+		sessions:  map[string]*Session{},
+		mfa:       map[string]*MFAConfig{},
+
+		oidcConfigs:    map[string]*OIDCConfig{},
+		oidcIdentities: map[string]*OIDCIdentity{},
+
+		emailTokens: map[string]*EmailToken{},
+
+		loginThrottles: map[string]*LoginThrottle{},
+		members:        map[string]*TenantMember{},
 	}
 }
 
@@ -450,6 +1671,26 @@ func (s *memoryStore) DeleteScale(id string) bool {
 	}
 	delete(s.itemsByScale, id)
 	delete(s.scales, id)
+	delete(s.irtCalibrations, id)
+	delete(s.catSettings, id)
+	delete(s.normTables, id)
+	delete(s.feedbackConfigs, id)
+	delete(s.aggregates, id)
+	for wid, w := range s.webhooks {
+		if w.ScaleID == id {
+			s.deleteWebhookLocked(wid)
+		}
+	}
+	for tid, t := range s.apiTokens {
+		if t.ScaleID == id {
+			delete(s.apiTokens, tid)
+		}
+	}
+	for sid, sess := range s.catSessions {
+		if sess.ScaleID == id {
+			delete(s.catSessions, sid)
+		}
+	}
 	// filter responses not belonging to removed items
 	nr := make([]*Response, 0, len(s.responses))
 	for _, r := range s.responses {
@@ -770,6 +2011,22 @@ func (s *memoryStore) ListResponsesByScale(scaleID string) []*Response {
 	return out
 }
 
+func (s *memoryStore) ListParticipantConditions(scaleID string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := map[string]string{}
+	for _, r := range s.responses {
+		it := s.items[r.ItemID]
+		if it == nil || it.ScaleID != scaleID {
+			continue
+		}
+		if p := s.participants[r.ParticipantID]; p != nil && p.Condition != "" {
+			out[p.ID] = p.Condition
+		}
+	}
+	return out
+}
+
 func (s *memoryStore) ListResponsesByParticipant(pid string) []*Response {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -837,10 +2094,48 @@ type AuditEntry struct {
 	Action string    `json:"action"`
 	Target string    `json:"target"`
 	Note   string    `json:"note,omitempty"`
+	// Structured fields, see services.AuditEntry. Entries logged before they existed have none.
+	TenantID  string `json:"tenant_id,omitempty"`
+	ActorID   string `json:"actor_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Result    string `json:"result,omitempty"`
+	// Hash chain: Seq orders the log, Hash covers the entry and PrevHash.
+	Seq      int64  `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// AuditQuery filters the audit log, see services.AuditQuery. Targets, when set, lists the
+// targets an entry may have.
+type AuditQuery struct {
+	TenantID      string
+	LegacyTargets []string
+	Action        string
+	Actor         string
+	Targets       []string
+	Source        string
+	From, To      time.Time
+	Before        int64
+	Limit         int
+}
+
+// AuditCheckpoint is a signed chain head, see services.AuditCheckpoint.
+type AuditCheckpoint struct {
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature"`
 }
 
 func (s *memoryStore) AddAudit(e AuditEntry) {
 	s.mu.Lock()
+	seq, prev := int64(1), ""
+	if n := len(s.audit); n > 0 {
+		seq, prev = s.audit[n-1].Seq+1, s.audit[n-1].Hash
+	}
+	ChainAuditEntry(&e, seq, prev)
 	s.audit = append(s.audit, e)
 	s.mu.Unlock()
 	s.save()
@@ -853,6 +2148,58 @@ func (s *memoryStore) ListAudit() []AuditEntry {
 	return out
 }
 
+func (s *memoryStore) ListAuditChain(afterSeq int64, limit int) []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.audit), func(i int) bool { return s.audit[i].Seq > afterSeq })
+	out := []AuditEntry{}
+	for ; i < len(s.audit) && len(out) < limit; i++ {
+		out = append(out, s.audit[i])
+	}
+	return out
+}
+
+// QueryAudit scans the log backwards from the cursor.
+func (s *memoryStore) QueryAudit(q AuditQuery) []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := len(s.audit)
+	if q.Before > 0 {
+		i = sort.Search(len(s.audit), func(i int) bool { return s.audit[i].Seq >= q.Before })
+	}
+	out := []AuditEntry{}
+	for i--; i >= 0 && len(out) < q.Limit; i-- {
+		if q.Matches(s.audit[i]) {
+			out = append(out, s.audit[i])
+		}
+	}
+	return out
+}
+
+func (s *memoryStore) AuditHead() *AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.audit) == 0 {
+		return nil
+	}
+	e := s.audit[len(s.audit)-1]
+	return &e
+}
+
+func (s *memoryStore) AddAuditCheckpoint(cp AuditCheckpoint) bool {
+	s.mu.Lock()
+	s.checkpoints = append(s.checkpoints, cp)
+	s.mu.Unlock()
+	s.save()
+	return true
+}
+
+func (s *memoryStore) ListAuditCheckpoints() []AuditCheckpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]AuditCheckpoint(nil), s.checkpoints...)
+}
+
 // participant-scope helpers
 func (s *memoryStore) ExportParticipantByEmail(email string) ([]*Response, *Participant) {
 	s.mu.RLock()
@@ -933,11 +2280,12 @@ func (s *memoryStore) DeleteParticipantByID(pid string, hard bool) bool {
 // tenants & users (multi-tenant scaffolding)
 type Tenant struct{ ID, Name string }
 type User struct {
-	ID        string
-	Email     string
-	PassHash  []byte
-	TenantID  string
-	CreatedAt time.Time
+	ID              string
+	Email           string
+	PassHash        []byte
+	TenantID        string
+	CreatedAt       time.Time
+	EmailVerifiedAt time.Time
 }
 
 func (s *memoryStore) AddTenant(t *Tenant) {
@@ -946,10 +2294,24 @@ func (s *memoryStore) AddTenant(t *Tenant) {
 	s.tenants[t.ID] = t
 	s.saveLocked()
 }
+func (s *memoryStore) GetTenant(id string) *Tenant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t, ok := s.tenants[id]; ok {
+		cp := *t
+		return &cp
+	}
+	return nil
+}
 func (s *memoryStore) DeleteTenant(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.tenants, id)
+	for k, m := range s.members {
+		if m.TenantID == id {
+			delete(s.members, k)
+		}
+	}
 	s.saveLocked()
 }
 func (s *memoryStore) AddUser(u *User) {
@@ -963,6 +2325,51 @@ func (s *memoryStore) FindUserByEmail(email string) *User {
 	defer s.mu.RUnlock()
 	return s.usersByEmail[strings.ToLower(email)]
 }
+
+func (s *memoryStore) userByIDLocked(id string) *User {
+	for _, u := range s.usersByEmail {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SetUserPassword(userID string, hash []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.userByIDLocked(userID)
+	if u == nil || len(hash) == 0 {
+		return false
+	}
+	u.PassHash = append([]byte(nil), hash...)
+	s.saveLocked()
+	return true
+}
+
+func (s *memoryStore) SetUserTenant(userID, tenantID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.userByIDLocked(userID)
+	if u == nil || tenantID == "" {
+		return false
+	}
+	u.TenantID = tenantID
+	s.saveLocked()
+	return true
+}
+
+func (s *memoryStore) SetEmailVerified(userID string, at time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.userByIDLocked(userID)
+	if u == nil {
+		return false
+	}
+	u.EmailVerifiedAt = at
+	s.saveLocked()
+	return true
+}
 func (s *memoryStore) ListScalesByTenant(tid string) []*Scale {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -988,7 +2395,9 @@ type LegacySnapshot struct {
 	Users         []*User                  `json:"users"`
 	AIConfigs     []*TenantAIConfig        `json:"ai_configs"`
 	Audit         []AuditEntry             `json:"audit"`
+	Checkpoints   []AuditCheckpoint        `json:"audit_checkpoints,omitempty"`
 	Consents      []*ConsentRecord         `json:"consents"`
+	Memberships   []*TenantMember          `json:"memberships"`
 }
 
 func (s *memoryStore) load() error {
@@ -1052,8 +2461,16 @@ func (s *memoryStore) load() error {
 	for _, a := range snap.AIConfigs {
 		s.aiConfigs[a.TenantID] = a
 	}
-	s.audit = append([]AuditEntry(nil), snap.Audit...)
+	s.audit = SealAuditLog(append([]AuditEntry(nil), snap.Audit...))
+	s.checkpoints = append([]AuditCheckpoint(nil), snap.Checkpoints...)
 	s.consents = append([]*ConsentRecord(nil), snap.Consents...)
+	if snap.Memberships == nil {
+		snap.Memberships = LegacyMemberships(snap.Users)
+	}
+	s.members = map[string]*TenantMember{}
+	for _, m := range snap.Memberships {
+		s.members[memberKey(m.TenantID, m.UserID)] = m
+	}
 	// If file was plaintext and we have encKey, save back encrypted
 	if len(s.encKey) == 32 && !(len(b) > 8 && string(b[:8]) == "SYNAPENC") {
 		s.saveUnlocked()
@@ -22,6 +22,9 @@ type Store interface {
 	DeleteParticipantByID(id string, hard bool) bool
 	DeleteParticipantByEmail(email string, hard bool) bool
 	ExportParticipantByEmail(email string) ([]*Response, *Participant)
+	// ListParticipantConditions maps participant ID to condition label for participants who
+	// answered the scale with a condition set.
+	ListParticipantConditions(scaleID string) map[string]string
 
 	AddResponses(rs []*Response)
 	ListResponsesByScale(scaleID string) []*Response
@@ -41,8 +44,15 @@ type Store interface {
 	AddConsentRecord(cr *ConsentRecord)
 	GetConsentByID(id string) *ConsentRecord
 
+	// AddAudit appends e to the hash chain (see ChainAuditEntry); ListAudit returns recent entries.
 	AddAudit(e AuditEntry)
 	ListAudit() []AuditEntry
+	ListAuditChain(afterSeq int64, limit int) []AuditEntry
+	// QueryAudit returns up to q.Limit entries matching q, newest first.
+	QueryAudit(q AuditQuery) []AuditEntry
+	AuditHead() *AuditEntry
+	AddAuditCheckpoint(cp AuditCheckpoint) bool
+	ListAuditCheckpoints() []AuditCheckpoint
 
 	AllowExport(tid string, minInterval time.Duration) bool
 	CreateExportJob(tid, scaleID, ip string, ttl time.Duration) *ExportJob
@@ -56,16 +66,115 @@ type Store interface {
 	DeleteTenant(id string)
 	AddUser(u *User)
 	FindUserByEmail(email string) *User
+	SetUserPassword(userID string, hash []byte) bool
+	SetEmailVerified(userID string, at time.Time) bool
 
 	// Team collaborators per scale
 	AddScaleCollaborator(scaleID, userID, role string) bool
 	RemoveScaleCollaborator(scaleID, userID string) bool
 	ListScaleCollaborators(scaleID string) []ScaleCollaborator
+	// ListUserCollaborations returns the user's collaborations on scales of any tenant
+	ListUserCollaborations(userID string) []ScaleCollaborator
+	// RemoveTenantCollaborations drops the user's collaborations on the tenant's scales
+	RemoveTenantCollaborations(tenantID, userID string) bool
 
-	// Collaborator invitations
-	CreateInvite(inv *ScaleInvite) (*ScaleInvite, error)
-	GetInvite(token string) *ScaleInvite
-	MarkInviteAccepted(token string) bool
+	// Tenant and scale invitations, looked up by token hash
+	SaveInvite(inv *Invite) bool
+	GetInvite(id string) *Invite
+	GetInviteByHash(hash string) *Invite
+	ListInvites(tenantID string) []*Invite
+
+	// Double data entry of paper forms; see services.DoubleEntryStore for the status checks
+	GetDataEntryForm(scaleID, formID string) *DataEntryForm
+	SaveDataEntryForm(f *DataEntryForm, prevStatus string) bool
+	CommitDataEntryForm(f *DataEntryForm, prevStatus string, p *Participant, rs []*Response) bool
+	ListDataEntryForms(scaleID string) []*DataEntryForm
+
+	// IRT calibrations per scale; versions are unique per scale and listed in ascending order
+	SaveIRTCalibration(c *IRTCalibration) bool
+	ListIRTCalibrations(scaleID string) []*IRTCalibration
+	GetIRTCalibration(scaleID string, version int) *IRTCalibration
+
+	// Computerized adaptive testing: per-scale settings and participant sessions
+	GetCATSettings(scaleID string) *CATSettings
+	SaveCATSettings(c *CATSettings) bool
+	GetCATSession(id string) *CATSession
+	SaveCATSession(sess *CATSession, prevStatus string, prevSteps int) bool // see services.CATStore
+	ListCATSessions(scaleID string) []*CATSession
+
+	// Norm tables per scale, keyed by a slug unique within the scale and listed by key
+	SaveNormTable(t *NormTable) bool
+	ListNormTables(scaleID string) []*NormTable
+	DeleteNormTable(scaleID, key string) bool
+
+	// Participant feedback report configuration per scale
+	GetFeedbackConfig(scaleID string) *FeedbackConfig
+	SaveFeedbackConfig(c *FeedbackConfig) bool
+
+	// Running analytics aggregate per scale (histograms, daily counts, alpha moments)
+	GetAnalyticsAggregate(scaleID string) *AnalyticsAggregate
+	SaveAnalyticsAggregate(a *AnalyticsAggregate) bool
+	DeleteAnalyticsAggregate(scaleID string) bool
+	ListAnalyticsAggregateScaleIDs() []string
+
+	// Outgoing webhooks per tenant and their delivery queue; deleting a webhook (or the scale it
+	// is limited to) removes its deliveries
+	SaveWebhook(w *Webhook) bool
+	GetWebhook(id string) *Webhook
+	ListWebhooks(tenantID string) []*Webhook
+	DeleteWebhook(id string) bool
+	SaveWebhookDelivery(d *WebhookDelivery) bool
+	GetWebhookDelivery(id string) *WebhookDelivery
+	ListWebhookDeliveries(webhookID string) []*WebhookDelivery
+	ListDueWebhookDeliveries(now time.Time, limit int) []*WebhookDelivery
+
+	// Personal API tokens, looked up by the hash of the presented token; a token limited to a
+	// scale is removed with the scale
+	SaveAPIToken(t *APIToken) bool
+	GetAPIToken(id string) *APIToken
+	GetAPITokenByHash(hash string) *APIToken
+	ListAPITokens(tenantID string) []*APIToken
+	TouchAPIToken(id string, at time.Time) bool
+
+	// Server-side sessions; GetSessionByRefreshHash matches the current or any rotated refresh hash,
+	// see services.SessionStore for RotateSession
+	SaveSession(s *Session) bool
+	GetSession(id string) *Session
+	GetSessionByRefreshHash(hash string) *Session
+	RotateSession(s *Session, oldHash string) bool
+	ListSessionsByUser(userID string) []*Session
+
+	// TOTP enrollments for step-up authentication, one per user
+	GetMFA(userID string) *MFAConfig
+	SaveMFA(m *MFAConfig) bool
+	DeleteMFA(userID string) bool
+
+	// OpenID Connect provider per tenant and the provider identities linked to users
+	GetOIDCConfig(tenantID string) *OIDCConfig
+	ListOIDCConfigs() []*OIDCConfig
+	SaveOIDCConfig(c *OIDCConfig) bool
+	DeleteOIDCConfig(tenantID string) bool
+	GetOIDCIdentity(issuer, subject string) *OIDCIdentity
+	SaveOIDCIdentity(id *OIDCIdentity) bool
+
+	// Single-use password reset and email verification tokens, looked up by hash
+	SaveEmailToken(t *EmailToken) bool
+	GetEmailTokenByHash(hash string) *EmailToken
+	ListEmailTokens(userID, purpose string) []*EmailToken
+
+	// Failed sign-in counters per account and IP
+	GetLoginThrottle(key string) *LoginThrottle
+	SaveLoginThrottle(t *LoginThrottle) bool
+	DeleteLoginThrottle(key string) bool
+
+	// Tenant memberships; a user's TenantID is the tenant their sign-in starts in
+	GetTenant(id string) *Tenant
+	SetUserTenant(userID, tenantID string) bool
+	GetTenantMember(tenantID, userID string) *TenantMember
+	ListTenantMembers(tenantID string) []*TenantMember
+	ListUserTenants(userID string) []*TenantMember
+	SaveTenantMember(m *TenantMember) bool
+	DeleteTenantMember(tenantID, userID string) bool
 }
 
 var _ Store = (*memoryStore)(nil)
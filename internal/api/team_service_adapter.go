@@ -22,7 +22,16 @@ func (a *teamStoreAdapter) ListScaleCollaborators(scaleID string) []services.Col
 	list := a.store.ListScaleCollaborators(scaleID)
 	out := make([]services.Collaborator, 0, len(list))
 	for _, c := range list {
-		out = append(out, services.Collaborator{UserID: c.UserID, Email: c.Email, Role: c.Role})
+		out = append(out, services.Collaborator{ScaleID: c.ScaleID, UserID: c.UserID, Email: c.Email, Role: c.Role})
+	}
+	return out
+}
+
+func (a *teamStoreAdapter) ListUserCollaborations(userID string) []services.Collaborator {
+	list := a.store.ListUserCollaborations(userID)
+	out := make([]services.Collaborator, 0, len(list))
+	for _, c := range list {
+		out = append(out, services.Collaborator{ScaleID: c.ScaleID, UserID: c.UserID, Email: c.Email, Role: c.Role})
 	}
 	return out
 }
@@ -36,5 +45,5 @@ func (a *teamStoreAdapter) RemoveScaleCollaborator(scaleID, userID string) bool
 }
 
 func (a *teamStoreAdapter) AddAudit(entry services.AuditEntry) {
-	a.store.AddAudit(AuditEntry{Time: entry.Time, Actor: entry.Actor, Action: entry.Action, Target: entry.Target, Note: entry.Note})
+	recordAudit(a.store, entry)
 }
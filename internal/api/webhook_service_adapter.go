@@ -0,0 +1,104 @@
+package api
+
+import (
+	"time"
+
+	"github.com/soaringjerry/Synap/internal/services"
+)
+
+type webhookStoreAdapter struct {
+	store Store
+}
+
+func newWebhookStoreAdapter(store Store) services.WebhookStore {
+	return &webhookStoreAdapter{store: store}
+}
+
+func (a *webhookStoreAdapter) GetScale(id string) (*services.Scale, error) {
+	sc := a.store.GetScale(id)
+	if sc == nil {
+		return nil, nil
+	}
+	return convertAPIScale(sc), nil
+}
+
+func (a *webhookStoreAdapter) SaveWebhook(w *services.Webhook) error {
+	if w == nil {
+		return services.NewInvalidError("webhook required")
+	}
+	if !a.store.SaveWebhook(&Webhook{ID: w.ID, TenantID: w.TenantID, ScaleID: w.ScaleID, URL: w.URL, Events: w.Events,
+		Secret: w.Secret, Active: w.Active, CreatedAt: w.CreatedAt, UpdatedAt: w.UpdatedAt}) {
+		return services.NewConflictError("unable to save webhook")
+	}
+	return nil
+}
+
+func (a *webhookStoreAdapter) GetWebhook(id string) (*services.Webhook, error) {
+	w := a.store.GetWebhook(id)
+	if w == nil {
+		return nil, nil
+	}
+	return convertAPIWebhook(w), nil
+}
+
+func (a *webhookStoreAdapter) ListWebhooks(tenantID string) ([]*services.Webhook, error) {
+	list := a.store.ListWebhooks(tenantID)
+	out := make([]*services.Webhook, 0, len(list))
+	for _, w := range list {
+		out = append(out, convertAPIWebhook(w))
+	}
+	return out, nil
+}
+
+func (a *webhookStoreAdapter) DeleteWebhook(id string) (bool, error) {
+	return a.store.DeleteWebhook(id), nil
+}
+
+func (a *webhookStoreAdapter) SaveWebhookDelivery(d *services.WebhookDelivery) error {
+	if d == nil {
+		return services.NewInvalidError("delivery required")
+	}
+	if !a.store.SaveWebhookDelivery(&WebhookDelivery{ID: d.ID, WebhookID: d.WebhookID, TenantID: d.TenantID, ScaleID: d.ScaleID,
+		Event: d.Event, Payload: d.Payload, Status: d.Status, Attempts: d.Attempts, NextAttemptAt: d.NextAttemptAt,
+		LastAttemptAt: d.LastAttemptAt, LastStatus: d.LastStatus, LastError: d.LastError, CreatedAt: d.CreatedAt, DeliveredAt: d.DeliveredAt}) {
+		return services.NewConflictError("unable to save webhook delivery")
+	}
+	return nil
+}
+
+func (a *webhookStoreAdapter) GetWebhookDelivery(id string) (*services.WebhookDelivery, error) {
+	d := a.store.GetWebhookDelivery(id)
+	if d == nil {
+		return nil, nil
+	}
+	return convertAPIWebhookDelivery(d), nil
+}
+
+func (a *webhookStoreAdapter) ListWebhookDeliveries(webhookID string) ([]*services.WebhookDelivery, error) {
+	return convertAPIWebhookDeliveries(a.store.ListWebhookDeliveries(webhookID)), nil
+}
+
+func (a *webhookStoreAdapter) ListDueWebhookDeliveries(now time.Time, limit int) ([]*services.WebhookDelivery, error) {
+	return convertAPIWebhookDeliveries(a.store.ListDueWebhookDeliveries(now, limit)), nil
+}
+
+func convertAPIWebhook(w *Webhook) *services.Webhook {
+	return &services.Webhook{ID: w.ID, TenantID: w.TenantID, ScaleID: w.ScaleID, URL: w.URL, Events: w.Events,
+		Secret: w.Secret, Active: w.Active, CreatedAt: w.CreatedAt, UpdatedAt: w.UpdatedAt}
+}
+
+func convertAPIWebhookDelivery(d *WebhookDelivery) *services.WebhookDelivery {
+	return &services.WebhookDelivery{ID: d.ID, WebhookID: d.WebhookID, TenantID: d.TenantID, ScaleID: d.ScaleID,
+		Event: d.Event, Payload: d.Payload, Status: d.Status, Attempts: d.Attempts, NextAttemptAt: d.NextAttemptAt,
+		LastAttemptAt: d.LastAttemptAt, LastStatus: d.LastStatus, LastError: d.LastError, CreatedAt: d.CreatedAt, DeliveredAt: d.DeliveredAt}
+}
+
+func convertAPIWebhookDeliveries(list []*WebhookDelivery) []*services.WebhookDelivery {
+	out := make([]*services.WebhookDelivery, 0, len(list))
+	for _, d := range list {
+		out = append(out, convertAPIWebhookDelivery(d))
+	}
+	return out
+}
+
+var _ services.WebhookStore = (*webhookStoreAdapter)(nil)
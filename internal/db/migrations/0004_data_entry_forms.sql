@@ -0,0 +1,19 @@
+-- Double data entry of paper questionnaires (two operators + reconciliation)
+CREATE TABLE IF NOT EXISTS data_entry_forms (
+  scale_id TEXT NOT NULL,
+  form_id TEXT NOT NULL,
+  first_entry TEXT,
+  first_by TEXT,
+  second_entry TEXT,
+  second_by TEXT,
+  status TEXT NOT NULL DEFAULT 'awaiting_second', -- awaiting_second|conflict|reconciled
+  mismatches TEXT,
+  resolved_by TEXT,
+  participant_id TEXT,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (scale_id, form_id),
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_entry_forms_status ON data_entry_forms(scale_id, status);
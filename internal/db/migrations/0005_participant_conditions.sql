@@ -0,0 +1,7 @@
+-- Experimental condition (arm) labels captured at submission time; kept apart from the
+-- participants table so existing rows need no rewrite.
+CREATE TABLE IF NOT EXISTS participant_conditions (
+  participant_id TEXT PRIMARY KEY,
+  condition TEXT NOT NULL,
+  FOREIGN KEY (participant_id) REFERENCES participants(id) ON DELETE CASCADE
+);
@@ -0,0 +1,12 @@
+-- IRT calibrations (Rasch / graded response model) per scale. result holds the full calibration
+-- JSON; scale_fingerprint identifies the item set (scale version) the model was fitted on.
+CREATE TABLE IF NOT EXISTS irt_calibrations (
+  scale_id TEXT NOT NULL,
+  version INTEGER NOT NULL,
+  scale_fingerprint TEXT NOT NULL,
+  model TEXT NOT NULL,
+  result TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (scale_id, version),
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
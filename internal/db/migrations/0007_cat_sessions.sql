@@ -0,0 +1,36 @@
+-- Computerized adaptive testing: per-scale settings and participant sessions. steps holds the
+-- administered sequence as JSON; only a hash of the participant's session token is stored.
+CREATE TABLE IF NOT EXISTS scale_cat_settings (
+  scale_id TEXT PRIMARY KEY,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  calibration_version INTEGER NOT NULL DEFAULT 0,
+  min_items INTEGER NOT NULL DEFAULT 0,
+  max_items INTEGER NOT NULL DEFAULT 0,
+  se_target REAL NOT NULL DEFAULT 0.3,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS cat_sessions (
+  id TEXT PRIMARY KEY,
+  scale_id TEXT NOT NULL,
+  token_hash TEXT NOT NULL,
+  calibration_version INTEGER NOT NULL,
+  status TEXT NOT NULL DEFAULT 'active', -- active|finished
+  stop_reason TEXT,
+  next_item_id TEXT,
+  next_information REAL NOT NULL DEFAULT 0,
+  steps TEXT NOT NULL DEFAULT '[]',
+  theta REAL NOT NULL DEFAULT 0,
+  se REAL NOT NULL DEFAULT 1,
+  participant_id TEXT,
+  participant_email TEXT,
+  consent_id TEXT,
+  condition TEXT,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at DATETIME,
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_cat_sessions_scale ON cat_sessions(scale_id, created_at);
@@ -0,0 +1,10 @@
+-- Norm tables per scale. definition holds the strata (reference mean/SD, optional percentile
+-- table and matching conditions) and the subscale item selection.
+CREATE TABLE IF NOT EXISTS norm_tables (
+  scale_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  definition TEXT NOT NULL,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (scale_id, key),
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- Participant feedback report configuration per scale (scores, interpretation bands, sample
+-- comparison). config holds the JSON defined by the feedback service.
+CREATE TABLE IF NOT EXISTS scale_feedback_configs (
+  scale_id TEXT PRIMARY KEY,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  config TEXT NOT NULL,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+-- Running analytics aggregate per scale (histograms, daily counts, alpha sums and cross-products),
+-- updated on every response write. data holds the JSON defined by services.AnalyticsAggregate.
+CREATE TABLE IF NOT EXISTS scale_analytics_aggregates (
+  scale_id TEXT PRIMARY KEY,
+  data TEXT NOT NULL,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- Server-side sessions behind short-lived access tokens (jti = id). refresh_hash is the SHA-256 of
+-- the current refresh token; prev_refresh_hash keeps the rotated one so its reuse can be detected.
+CREATE TABLE IF NOT EXISTS sessions (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  tenant_id TEXT NOT NULL,
+  email TEXT NOT NULL,
+  user_agent TEXT,
+  ip TEXT,
+  refresh_hash TEXT NOT NULL UNIQUE,
+  prev_refresh_hash TEXT,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at DATETIME NOT NULL,
+  revoked_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_prev_refresh ON sessions(prev_refresh_hash);
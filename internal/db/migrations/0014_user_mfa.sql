@@ -0,0 +1,13 @@
+-- TOTP enrollments (RFC 6238) for step-up authentication. secret is the base32 shared key;
+-- recovery_hashes holds the SHA-256 of each unused recovery code as JSON. last_step is the last
+-- accepted 30 s time step so a code cannot be used twice.
+CREATE TABLE IF NOT EXISTS user_mfa (
+  user_id TEXT PRIMARY KEY,
+  secret TEXT NOT NULL,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  recovery_hashes TEXT NOT NULL DEFAULT '[]',
+  last_step INTEGER NOT NULL DEFAULT 0,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  enabled_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,32 @@
+-- OpenID Connect single sign-on. One provider per tenant; allowed_domains (JSON) lists the email
+-- domains provisioned into the tenant and role_map (JSON) maps values of role_claim to a role.
+CREATE TABLE IF NOT EXISTS tenant_oidc (
+  tenant_id TEXT PRIMARY KEY,
+  enabled INTEGER NOT NULL DEFAULT 0,
+  issuer TEXT NOT NULL,
+  client_id TEXT NOT NULL,
+  client_secret TEXT NOT NULL DEFAULT '',
+  scopes TEXT NOT NULL DEFAULT '',
+  allowed_domains TEXT NOT NULL DEFAULT '[]',
+  role_claim TEXT NOT NULL DEFAULT '',
+  role_map TEXT NOT NULL DEFAULT '{}',
+  default_role TEXT NOT NULL DEFAULT '',
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+-- Provider identities (issuer + subject) linked to users on their first single sign-on.
+CREATE TABLE IF NOT EXISTS oidc_identities (
+  issuer TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  tenant_id TEXT NOT NULL,
+  email TEXT NOT NULL,
+  role TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_login_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (issuer, subject),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_identities_user ON oidc_identities(user_id);
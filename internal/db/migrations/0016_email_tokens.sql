@@ -0,0 +1,31 @@
+-- Email verification state, kept apart from users so existing rows need no rewrite. Accounts that
+-- existed before verification was introduced count as verified.
+CREATE TABLE IF NOT EXISTS user_email_verifications (
+  user_id TEXT PRIMARY KEY,
+  verified_at DATETIME NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Migrations run on every start, so the backfill is guarded by a marker row and runs only once.
+CREATE TABLE IF NOT EXISTS user_email_verifications_backfill (done INTEGER PRIMARY KEY);
+
+INSERT OR IGNORE INTO user_email_verifications (user_id, verified_at)
+  SELECT id, COALESCE(strftime('%Y-%m-%dT%H:%M:%fZ', created_at), strftime('%Y-%m-%dT%H:%M:%fZ', 'now')) FROM users
+  WHERE NOT EXISTS (SELECT 1 FROM user_email_verifications_backfill);
+
+INSERT OR IGNORE INTO user_email_verifications_backfill (done) VALUES (1);
+
+-- Single-use password reset and email verification tokens; hash is the SHA-256 of the token.
+CREATE TABLE IF NOT EXISTS email_tokens (
+  id TEXT PRIMARY KEY,
+  user_id TEXT NOT NULL,
+  email TEXT NOT NULL,
+  purpose TEXT NOT NULL,
+  hash TEXT NOT NULL UNIQUE,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_tokens_user ON email_tokens(user_id, purpose, created_at);
@@ -0,0 +1,11 @@
+-- Failed sign-in counters per account ("account:<email>") and client IP ("ip:<addr>").
+-- tenant_id is set for accounts of known users so that tenant admins can list and unlock them.
+CREATE TABLE IF NOT EXISTS login_throttles (
+  key TEXT PRIMARY KEY,
+  tenant_id TEXT NOT NULL DEFAULT '',
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at DATETIME NOT NULL,
+  locked_until DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttles_tenant ON login_throttles(tenant_id);
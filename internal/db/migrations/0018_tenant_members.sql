@@ -0,0 +1,41 @@
+-- Users belong to any number of tenants with a role (owner, admin or member); users.tenant_id
+-- remains the tenant a sign-in starts in.
+CREATE TABLE IF NOT EXISTS tenant_members (
+  tenant_id TEXT NOT NULL,
+  user_id TEXT NOT NULL,
+  role TEXT NOT NULL DEFAULT 'member',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (tenant_id, user_id),
+  FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_members_user ON tenant_members(user_id);
+
+-- Existing users could all administer their tenant: the first user of each tenant becomes its
+-- owner and the others admins. Guarded by a marker row like the verification backfill.
+CREATE TABLE IF NOT EXISTS tenant_members_backfill (done INTEGER PRIMARY KEY);
+
+INSERT OR IGNORE INTO tenant_members (tenant_id, user_id, role, created_at)
+  SELECT u.tenant_id, u.id,
+         CASE WHEN u.id = (SELECT f.id FROM users f WHERE f.tenant_id = u.tenant_id ORDER BY f.created_at, f.id LIMIT 1)
+              THEN 'owner' ELSE 'admin' END,
+         u.created_at
+  FROM users u
+  WHERE NOT EXISTS (SELECT 1 FROM tenant_members_backfill);
+
+INSERT OR IGNORE INTO tenant_members_backfill (done) VALUES (1);
+
+-- Invites into a tenant without a scale; scale invites stay in scale_invites.
+CREATE TABLE IF NOT EXISTS tenant_invites (
+  token TEXT PRIMARY KEY,
+  tenant_id TEXT NOT NULL,
+  email TEXT NOT NULL,
+  role TEXT NOT NULL DEFAULT 'member',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at DATETIME NOT NULL,
+  accepted_at DATETIME,
+  FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_invites_tenant ON tenant_invites(tenant_id);
@@ -0,0 +1,30 @@
+-- Tenant and scale invitations share one table. Only the SHA-256 hash of the invite token is
+-- stored; scale_id is NULL for invites into the tenant itself.
+CREATE TABLE IF NOT EXISTS invites (
+  id TEXT PRIMARY KEY,
+  tenant_id TEXT NOT NULL,
+  scale_id TEXT,
+  email TEXT NOT NULL,
+  role TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  invited_by TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL,
+  expires_at DATETIME NOT NULL,
+  accepted_at DATETIME,
+  accepted_by TEXT NOT NULL DEFAULT '',
+  revoked_at DATETIME,
+  FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+  FOREIGN KEY (scale_id) REFERENCES scales(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_invites_tenant ON invites(tenant_id);
+
+-- The old tables kept guessable plaintext tokens; their links stop working and must be resent.
+-- Migrations run on every start, so the cleanup is recorded and happens only once.
+CREATE TABLE IF NOT EXISTS migration_markers (
+  name TEXT PRIMARY KEY
+);
+
+DELETE FROM scale_invites WHERE NOT EXISTS (SELECT 1 FROM migration_markers WHERE name = '0019_invites_cleanup');
+DELETE FROM tenant_invites WHERE NOT EXISTS (SELECT 1 FROM migration_markers WHERE name = '0019_invites_cleanup');
+INSERT OR IGNORE INTO migration_markers (name) VALUES ('0019_invites_cleanup');
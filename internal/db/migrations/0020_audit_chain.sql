@@ -0,0 +1,16 @@
+-- Hash chain over audit_log, kept apart from it so existing rows need no rewrite. hash is the
+-- SHA-256 of the entry and prev_hash (see services.AuditHash), so editing or deleting a row breaks
+-- every later link; audit_checkpoints hold the server's ed25519 signature over the chain head.
+CREATE TABLE IF NOT EXISTS audit_chain (
+  audit_id INTEGER PRIMARY KEY,
+  prev_hash TEXT NOT NULL,
+  hash TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+  seq INTEGER PRIMARY KEY,
+  hash TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  public_key TEXT NOT NULL,
+  signature TEXT NOT NULL
+);
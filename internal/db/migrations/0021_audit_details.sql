@@ -0,0 +1,18 @@
+-- Structured audit fields, kept apart from audit_log like audit_chain. ts repeats audit_log.ts in
+-- a sortable format for time range queries; rows logged before this table get tenant_id ''.
+CREATE TABLE IF NOT EXISTS audit_details (
+  audit_id INTEGER PRIMARY KEY,
+  ts TEXT NOT NULL,
+  tenant_id TEXT NOT NULL DEFAULT '',
+  actor_id TEXT NOT NULL DEFAULT '',
+  ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  result TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_details_tenant ON audit_details(tenant_id, audit_id);
+CREATE INDEX IF NOT EXISTS idx_audit_details_tenant_ts ON audit_details(tenant_id, ts);
+CREATE INDEX IF NOT EXISTS idx_audit_details_actor ON audit_details(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor);
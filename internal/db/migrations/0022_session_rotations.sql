@@ -0,0 +1,12 @@
+-- Every refresh hash a session rotated away from, so that replaying any of them (not only the
+-- previous one) is recognised as reuse and revokes the session.
+CREATE TABLE IF NOT EXISTS session_rotations (
+  refresh_hash TEXT PRIMARY KEY,
+  session_id TEXT NOT NULL,
+  FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_rotations_session ON session_rotations(session_id);
+
+INSERT OR IGNORE INTO session_rotations (refresh_hash, session_id)
+  SELECT prev_refresh_hash, id FROM sessions WHERE prev_refresh_hash IS NOT NULL AND prev_refresh_hash <> '';
@@ -20,6 +20,7 @@ import (
 type SQLiteStore struct {
 	db         *sql.DB
 	q          *sq.Queries
+	auditMu    sync.Mutex // orders audit appends so every entry links to its predecessor
 	exportMu   sync.Mutex
 	exportJobs map[string]*api.ExportJob
 	lastExport map[string]time.Time
@@ -39,12 +40,19 @@ func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
 			return nil, fmt.Errorf("apply sqlite pragma %q: %w", stmt, err)
 		}
 	}
-	return &SQLiteStore{
+	s := &SQLiteStore{
 		db:         db,
 		q:          sq.New(db),
 		exportJobs: map[string]*api.ExportJob{},
 		lastExport: map[string]time.Time{},
-	}, nil
+	}
+	if err := s.sealAuditLog(); err != nil {
+		return nil, fmt.Errorf("seal audit log: %w", err)
+	}
+	if err := s.backfillAuditDetails(); err != nil {
+		return nil, fmt.Errorf("backfill audit details: %w", err)
+	}
+	return s, nil
 }
 
 func NewStore(db *sql.DB) (api.Store, error) {
@@ -59,6 +67,19 @@ func (s *SQLiteStore) logErr(prefix string, err error) {
 
 func contextBg() context.Context { return context.Background() }
 
+// withTx runs fn in a transaction, committing only when it succeeds.
+func (s *SQLiteStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(contextBg(), nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 func boolToInt64(v bool) int64 {
 	if v {
 		return 1
@@ -227,15 +248,23 @@ func (s *SQLiteStore) RemoveScaleCollaborator(scaleID, userID string) bool {
 }
 
 func (s *SQLiteStore) ListScaleCollaborators(scaleID string) []api.ScaleCollaborator {
+	return s.listCollaborators("ListScaleCollaborators", `WHERE sc.scale_id = ? ORDER BY u.email ASC`, scaleID)
+}
+
+func (s *SQLiteStore) ListUserCollaborations(userID string) []api.ScaleCollaborator {
+	return s.listCollaborators("ListUserCollaborations", `WHERE sc.user_id = ? ORDER BY sc.scale_id ASC`, userID)
+}
+
+func (s *SQLiteStore) listCollaborators(op, where, arg string) []api.ScaleCollaborator {
 	rows, err := s.db.Query(`SELECT sc.scale_id, sc.user_id, u.email, sc.role, sc.created_at
-      FROM scale_collaborators sc JOIN users u ON u.id = sc.user_id WHERE sc.scale_id = ? ORDER BY u.email ASC`, scaleID)
+      FROM scale_collaborators sc JOIN users u ON u.id = sc.user_id `+where, arg)
 	if err != nil {
-		s.logErr("ListScaleCollaborators: query", err)
+		s.logErr(op+": query", err)
 		return nil
 	}
 	defer func() {
 		if cerr := rows.Close(); cerr != nil {
-			s.logErr("ListScaleCollaborators: rows.Close", cerr)
+			s.logErr(op+": rows.Close", cerr)
 		}
 	}()
 	out := []api.ScaleCollaborator{}
@@ -250,56 +279,1212 @@ func (s *SQLiteStore) ListScaleCollaborators(scaleID string) []api.ScaleCollabor
 		}
 	}
 	if err := rows.Err(); err != nil {
-		s.logErr("ListScaleCollaborators: rows.Err", err)
+		s.logErr(op+": rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) RemoveTenantCollaborations(tenantID, userID string) bool {
+	_, err := s.db.Exec(`DELETE FROM scale_collaborators WHERE user_id = ? AND scale_id IN (SELECT id FROM scales WHERE tenant_id = ?)`, userID, tenantID)
+	s.logErr("RemoveTenantCollaborations", err)
+	return err == nil
+}
+
+// --- Invitations (sqlite) ---
+const inviteColumns = `id, tenant_id, COALESCE(scale_id, ''), email, role, token_hash, invited_by, created_at, expires_at, accepted_at, accepted_by, revoked_at`
+
+func (s *SQLiteStore) SaveInvite(inv *api.Invite) bool {
+	if inv == nil || inv.ID == "" || inv.TenantID == "" || inv.TokenHash == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO invites (id, tenant_id, scale_id, email, role, token_hash, invited_by, created_at, expires_at, accepted_at, accepted_by, revoked_at)
+      VALUES (?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(id) DO UPDATE SET role = excluded.role, token_hash = excluded.token_hash, created_at = excluded.created_at,
+        expires_at = excluded.expires_at, accepted_at = excluded.accepted_at, accepted_by = excluded.accepted_by, revoked_at = excluded.revoked_at`,
+		inv.ID, inv.TenantID, inv.ScaleID, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy,
+		inv.CreatedAt.UTC().Format(sortableTime), inv.ExpiresAt.UTC().Format(sortableTime), toNullTime(inv.AcceptedAt), inv.AcceptedBy, toNullTime(inv.RevokedAt))
+	s.logErr("SaveInvite", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) GetInvite(id string) *api.Invite {
+	return s.getInvite("GetInvite", `SELECT `+inviteColumns+` FROM invites WHERE id = ?`, id)
+}
+
+func (s *SQLiteStore) GetInviteByHash(hash string) *api.Invite {
+	return s.getInvite("GetInviteByHash", `SELECT `+inviteColumns+` FROM invites WHERE token_hash = ?`, hash)
+}
+
+func (s *SQLiteStore) getInvite(op, query string, arg string) *api.Invite {
+	inv, err := scanInvite(s.db.QueryRow(query, arg).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr(op, err)
+		}
+		return nil
+	}
+	return inv
+}
+
+func (s *SQLiteStore) ListInvites(tenantID string) []*api.Invite {
+	rows, err := s.db.Query(`SELECT `+inviteColumns+` FROM invites WHERE tenant_id = ? ORDER BY created_at DESC`, tenantID)
+	if err != nil {
+		s.logErr("ListInvites: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListInvites: rows.Close", cerr)
+		}
+	}()
+	out := []*api.Invite{}
+	for rows.Next() {
+		inv, err := scanInvite(rows.Scan)
+		if err != nil {
+			s.logErr("ListInvites: scan", err)
+			continue
+		}
+		out = append(out, inv)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListInvites: rows.Err", err)
+	}
+	return out
+}
+
+func scanInvite(scan func(dest ...any) error) (*api.Invite, error) {
+	var inv api.Invite
+	var created, expires string
+	var accepted, revoked sql.NullString
+	if err := scan(&inv.ID, &inv.TenantID, &inv.ScaleID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+		&created, &expires, &accepted, &inv.AcceptedBy, &revoked); err != nil {
+		return nil, err
+	}
+	if v, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		inv.CreatedAt = v
+	}
+	if v, err := time.Parse(time.RFC3339Nano, expires); err == nil {
+		inv.ExpiresAt = v
+	}
+	inv.AcceptedAt = parseNullTime(accepted)
+	inv.RevokedAt = parseNullTime(revoked)
+	return &inv, nil
+}
+
+// --- Double data entry (sqlite) ---
+func (s *SQLiteStore) GetDataEntryForm(scaleID, formID string) *api.DataEntryForm {
+	row := s.db.QueryRow(`SELECT scale_id, form_id, first_entry, first_by, second_entry, second_by, status, mismatches, resolved_by, participant_id, created_at, updated_at
+      FROM data_entry_forms WHERE scale_id = ? AND form_id = ?`, scaleID, formID)
+	f, err := scanDataEntryForm(row.Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetDataEntryForm", err)
+		}
+		return nil
+	}
+	return f
+}
+
+func (s *SQLiteStore) SaveDataEntryForm(f *api.DataEntryForm, prevStatus string) bool {
+	if f == nil || strings.TrimSpace(f.ScaleID) == "" || strings.TrimSpace(f.FormID) == "" {
+		return false
+	}
+	saved, err := saveDataEntryForm(contextBg(), s.db, f, prevStatus)
+	s.logErr("SaveDataEntryForm", err)
+	return err == nil && saved
+}
+
+// CommitDataEntryForm saves f and adds the participant with its responses in one transaction.
+func (s *SQLiteStore) CommitDataEntryForm(f *api.DataEntryForm, prevStatus string, p *api.Participant, rs []*api.Response) bool {
+	if f == nil || p == nil || strings.TrimSpace(f.ScaleID) == "" || strings.TrimSpace(f.FormID) == "" {
+		return false
+	}
+	saved := false
+	err := s.withTx(func(tx *sql.Tx) error {
+		ctx := contextBg()
+		ok, err := saveDataEntryForm(ctx, tx, f, prevStatus)
+		if err != nil || !ok {
+			return err
+		}
+		q := s.q.WithTx(tx)
+		if err := insertParticipant(ctx, tx, q, p); err != nil {
+			return err
+		}
+		if err := insertResponses(ctx, tx, q, rs); err != nil {
+			return err
+		}
+		saved = true
+		return nil
+	})
+	s.logErr("CommitDataEntryForm", err)
+	return err == nil && saved
+}
+
+// saveDataEntryForm inserts f when prevStatus is empty and no form exists, or updates it while
+// its stored status is still prevStatus. It reports whether a row was written.
+func saveDataEntryForm(ctx context.Context, db sq.DBTX, f *api.DataEntryForm, prevStatus string) (bool, error) {
+	first, err := encodeJSON(f.FirstEntry)
+	if err != nil {
+		return false, err
+	}
+	var second, mismatches sql.NullString
+	if f.SecondEntry != nil {
+		if second, err = encodeJSON(f.SecondEntry); err != nil {
+			return false, err
+		}
+	}
+	if len(f.Mismatches) > 0 {
+		if mismatches, err = encodeJSON(f.Mismatches); err != nil {
+			return false, err
+		}
+	}
+	var res sql.Result
+	if prevStatus == "" {
+		res, err = db.ExecContext(ctx, `INSERT INTO data_entry_forms (scale_id, form_id, first_entry, first_by, second_entry, second_by, status, mismatches, resolved_by, participant_id, created_at, updated_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT(scale_id, form_id) DO NOTHING`,
+			f.ScaleID, f.FormID, first, toNullString(f.FirstBy), second, toNullString(f.SecondBy), f.Status, mismatches,
+			toNullString(f.ResolvedBy), toNullString(f.ParticipantID), f.CreatedAt.UTC().Format(time.RFC3339Nano), f.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	} else {
+		res, err = db.ExecContext(ctx, `UPDATE data_entry_forms SET first_entry = ?, first_by = ?, second_entry = ?, second_by = ?, status = ?, mismatches = ?,
+        resolved_by = ?, participant_id = ?, updated_at = ? WHERE scale_id = ? AND form_id = ? AND status = ?`,
+			first, toNullString(f.FirstBy), second, toNullString(f.SecondBy), f.Status, mismatches, toNullString(f.ResolvedBy),
+			toNullString(f.ParticipantID), f.UpdatedAt.UTC().Format(time.RFC3339Nano), f.ScaleID, f.FormID, prevStatus)
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLiteStore) ListDataEntryForms(scaleID string) []*api.DataEntryForm {
+	rows, err := s.db.Query(`SELECT scale_id, form_id, first_entry, first_by, second_entry, second_by, status, mismatches, resolved_by, participant_id, created_at, updated_at
+      FROM data_entry_forms WHERE scale_id = ? ORDER BY form_id ASC`, scaleID)
+	if err != nil {
+		s.logErr("ListDataEntryForms: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListDataEntryForms: rows.Close", cerr)
+		}
+	}()
+	out := []*api.DataEntryForm{}
+	for rows.Next() {
+		f, err := scanDataEntryForm(rows.Scan)
+		if err != nil {
+			s.logErr("ListDataEntryForms: scan", err)
+			continue
+		}
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListDataEntryForms: rows.Err", err)
+	}
+	return out
+}
+
+// --- IRT calibrations (sqlite) ---
+func (s *SQLiteStore) SaveIRTCalibration(c *api.IRTCalibration) bool {
+	if c == nil || strings.TrimSpace(c.ScaleID) == "" || c.Version <= 0 {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO irt_calibrations (scale_id, version, scale_fingerprint, model, result, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		c.ScaleID, c.Version, c.ScaleFingerprint, c.Model, string(c.Result), c.CreatedAt.UTC().Format(time.RFC3339Nano))
+	s.logErr("SaveIRTCalibration", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) ListIRTCalibrations(scaleID string) []*api.IRTCalibration {
+	rows, err := s.db.Query(`SELECT scale_id, version, scale_fingerprint, model, result, created_at
+      FROM irt_calibrations WHERE scale_id = ? ORDER BY version ASC`, scaleID)
+	if err != nil {
+		s.logErr("ListIRTCalibrations: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListIRTCalibrations: rows.Close", cerr)
+		}
+	}()
+	out := []*api.IRTCalibration{}
+	for rows.Next() {
+		var c api.IRTCalibration
+		var result, created string
+		if err := rows.Scan(&c.ScaleID, &c.Version, &c.ScaleFingerprint, &c.Model, &result, &created); err != nil {
+			s.logErr("ListIRTCalibrations: scan", err)
+			continue
+		}
+		c.Result = json.RawMessage(result)
+		if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+			c.CreatedAt = t
+		}
+		out = append(out, &c)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListIRTCalibrations: rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) GetIRTCalibration(scaleID string, version int) *api.IRTCalibration {
+	var c api.IRTCalibration
+	var result, created string
+	err := s.db.QueryRow(`SELECT scale_id, version, scale_fingerprint, model, result, created_at FROM irt_calibrations WHERE scale_id = ? AND version = ?`,
+		scaleID, version).Scan(&c.ScaleID, &c.Version, &c.ScaleFingerprint, &c.Model, &result, &created)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetIRTCalibration", err)
+		}
+		return nil
+	}
+	c.Result = json.RawMessage(result)
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		c.CreatedAt = t
+	}
+	return &c
+}
+
+// --- Norm tables (sqlite) ---
+func (s *SQLiteStore) SaveNormTable(t *api.NormTable) bool {
+	if t == nil || strings.TrimSpace(t.ScaleID) == "" || strings.TrimSpace(t.Key) == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO norm_tables (scale_id, key, definition, updated_at) VALUES (?, ?, ?, ?)
+      ON CONFLICT(scale_id, key) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		t.ScaleID, t.Key, string(t.Definition), t.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	s.logErr("SaveNormTable", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) ListNormTables(scaleID string) []*api.NormTable {
+	rows, err := s.db.Query(`SELECT scale_id, key, definition, updated_at FROM norm_tables WHERE scale_id = ? ORDER BY key ASC`, scaleID)
+	if err != nil {
+		s.logErr("ListNormTables: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListNormTables: rows.Close", cerr)
+		}
+	}()
+	out := []*api.NormTable{}
+	for rows.Next() {
+		var t api.NormTable
+		var def, updated string
+		if err := rows.Scan(&t.ScaleID, &t.Key, &def, &updated); err != nil {
+			s.logErr("ListNormTables: scan", err)
+			continue
+		}
+		t.Definition = json.RawMessage(def)
+		if ts, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+			t.UpdatedAt = ts
+		}
+		out = append(out, &t)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListNormTables: rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) DeleteNormTable(scaleID, key string) bool {
+	res, err := s.db.Exec(`DELETE FROM norm_tables WHERE scale_id = ? AND key = ?`, scaleID, key)
+	if err != nil {
+		s.logErr("DeleteNormTable", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+// --- Feedback configs (sqlite) ---
+func (s *SQLiteStore) GetFeedbackConfig(scaleID string) *api.FeedbackConfig {
+	var c api.FeedbackConfig
+	var enabled int
+	var cfg, updated string
+	err := s.db.QueryRow(`SELECT scale_id, enabled, config, updated_at FROM scale_feedback_configs WHERE scale_id = ?`, scaleID).
+		Scan(&c.ScaleID, &enabled, &cfg, &updated)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetFeedbackConfig", err)
+		}
+		return nil
+	}
+	c.Enabled = enabled != 0
+	c.Config = json.RawMessage(cfg)
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		c.UpdatedAt = t
+	}
+	return &c
+}
+
+func (s *SQLiteStore) SaveFeedbackConfig(c *api.FeedbackConfig) bool {
+	if c == nil || strings.TrimSpace(c.ScaleID) == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO scale_feedback_configs (scale_id, enabled, config, updated_at) VALUES (?, ?, ?, ?)
+      ON CONFLICT(scale_id) DO UPDATE SET enabled = excluded.enabled, config = excluded.config, updated_at = excluded.updated_at`,
+		c.ScaleID, boolToInt64(c.Enabled), string(c.Config), c.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	s.logErr("SaveFeedbackConfig", err)
+	return err == nil
+}
+
+// --- Analytics aggregates (sqlite) ---
+func (s *SQLiteStore) GetAnalyticsAggregate(scaleID string) *api.AnalyticsAggregate {
+	var a api.AnalyticsAggregate
+	var data, updated string
+	err := s.db.QueryRow(`SELECT scale_id, data, updated_at FROM scale_analytics_aggregates WHERE scale_id = ?`, scaleID).
+		Scan(&a.ScaleID, &data, &updated)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetAnalyticsAggregate", err)
+		}
+		return nil
+	}
+	a.Data = json.RawMessage(data)
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		a.UpdatedAt = t
+	}
+	return &a
+}
+
+func (s *SQLiteStore) SaveAnalyticsAggregate(a *api.AnalyticsAggregate) bool {
+	if a == nil || strings.TrimSpace(a.ScaleID) == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO scale_analytics_aggregates (scale_id, data, updated_at) VALUES (?, ?, ?)
+      ON CONFLICT(scale_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		a.ScaleID, string(a.Data), a.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	s.logErr("SaveAnalyticsAggregate", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) DeleteAnalyticsAggregate(scaleID string) bool {
+	res, err := s.db.Exec(`DELETE FROM scale_analytics_aggregates WHERE scale_id = ?`, scaleID)
+	if err != nil {
+		s.logErr("DeleteAnalyticsAggregate", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func (s *SQLiteStore) ListAnalyticsAggregateScaleIDs() []string {
+	rows, err := s.db.Query(`SELECT scale_id FROM scale_analytics_aggregates ORDER BY scale_id`)
+	if err != nil {
+		s.logErr("ListAnalyticsAggregateScaleIDs: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListAnalyticsAggregateScaleIDs: rows.Close", cerr)
+		}
+	}()
+	out := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			s.logErr("ListAnalyticsAggregateScaleIDs: scan", err)
+			continue
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListAnalyticsAggregateScaleIDs: rows.Err", err)
+	}
+	return out
+}
+
+// --- Webhooks (sqlite) ---
+
+// sortableTime is a fixed-width UTC layout, so stored times compare correctly as strings.
+const sortableTime = "2006-01-02T15:04:05.000000000Z"
+
+// placeholders returns "?, ?, ..." for an IN list of n values.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func toNullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(sortableTime), Valid: true}
+}
+
+func parseNullTime(ns sql.NullString) time.Time {
+	if !ns.Valid {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339Nano, ns.String)
+	return t
+}
+
+const webhookColumns = `id, tenant_id, scale_id, url, events, secret, active, created_at, updated_at`
+
+func (s *SQLiteStore) SaveWebhook(w *api.Webhook) bool {
+	if w == nil || strings.TrimSpace(w.ID) == "" || strings.TrimSpace(w.TenantID) == "" {
+		return false
+	}
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		s.logErr("SaveWebhook encode events", err)
+		return false
+	}
+	_, err = s.db.Exec(`INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(id) DO UPDATE SET scale_id = excluded.scale_id, url = excluded.url, events = excluded.events,
+        secret = excluded.secret, active = excluded.active, updated_at = excluded.updated_at`,
+		w.ID, w.TenantID, toNullString(w.ScaleID), w.URL, string(eventsJSON), w.Secret, boolToInt64(w.Active),
+		w.CreatedAt.UTC().Format(sortableTime), w.UpdatedAt.UTC().Format(sortableTime))
+	s.logErr("SaveWebhook", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) GetWebhook(id string) *api.Webhook {
+	w, err := scanWebhook(s.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetWebhook", err)
+		}
+		return nil
+	}
+	return w
+}
+
+func (s *SQLiteStore) ListWebhooks(tenantID string) []*api.Webhook {
+	rows, err := s.db.Query(`SELECT `+webhookColumns+` FROM webhooks WHERE tenant_id = ? ORDER BY created_at ASC`, tenantID)
+	if err != nil {
+		s.logErr("ListWebhooks: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListWebhooks: rows.Close", cerr)
+		}
+	}()
+	out := []*api.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows.Scan)
+		if err != nil {
+			s.logErr("ListWebhooks: scan", err)
+			continue
+		}
+		out = append(out, w)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListWebhooks: rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) DeleteWebhook(id string) bool {
+	res, err := s.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		s.logErr("DeleteWebhook", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func scanWebhook(scan func(dest ...any) error) (*api.Webhook, error) {
+	var w api.Webhook
+	var scaleID sql.NullString
+	var events, created, updated string
+	var active int64
+	if err := scan(&w.ID, &w.TenantID, &scaleID, &w.URL, &events, &w.Secret, &active, &created, &updated); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+		return nil, err
+	}
+	w.ScaleID, w.Active = scaleID.String, int64ToBool(active)
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		w.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		w.UpdatedAt = t
+	}
+	return &w, nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, tenant_id, scale_id, event, payload, status, attempts, next_attempt_at, last_attempt_at,
+      last_status, last_error, created_at, delivered_at`
+
+func (s *SQLiteStore) SaveWebhookDelivery(d *api.WebhookDelivery) bool {
+	if d == nil || strings.TrimSpace(d.ID) == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(id) DO UPDATE SET status = excluded.status, attempts = excluded.attempts, next_attempt_at = excluded.next_attempt_at,
+        last_attempt_at = excluded.last_attempt_at, last_status = excluded.last_status, last_error = excluded.last_error,
+        delivered_at = excluded.delivered_at`,
+		d.ID, d.WebhookID, d.TenantID, toNullString(d.ScaleID), d.Event, string(d.Payload), d.Status, d.Attempts,
+		toNullTime(d.NextAttemptAt), toNullTime(d.LastAttemptAt), d.LastStatus, toNullString(d.LastError),
+		d.CreatedAt.UTC().Format(sortableTime), toNullTime(d.DeliveredAt))
+	s.logErr("SaveWebhookDelivery", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) GetWebhookDelivery(id string) *api.WebhookDelivery {
+	d, err := scanWebhookDelivery(s.db.QueryRow(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetWebhookDelivery", err)
+		}
+		return nil
+	}
+	return d
+}
+
+func (s *SQLiteStore) ListWebhookDeliveries(webhookID string) []*api.WebhookDelivery {
+	return s.queryWebhookDeliveries("ListWebhookDeliveries",
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC`, webhookID)
+}
+
+func (s *SQLiteStore) ListDueWebhookDeliveries(now time.Time, limit int) []*api.WebhookDelivery {
+	if limit <= 0 {
+		limit = -1
+	}
+	return s.queryWebhookDeliveries("ListDueWebhookDeliveries",
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= ?
+      ORDER BY next_attempt_at ASC LIMIT ?`, now.UTC().Format(sortableTime), limit)
+}
+
+func (s *SQLiteStore) queryWebhookDeliveries(op, query string, args ...any) []*api.WebhookDelivery {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		s.logErr(op+": query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr(op+": rows.Close", cerr)
+		}
+	}()
+	out := []*api.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows.Scan)
+		if err != nil {
+			s.logErr(op+": scan", err)
+			continue
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr(op+": rows.Err", err)
+	}
+	return out
+}
+
+func scanWebhookDelivery(scan func(dest ...any) error) (*api.WebhookDelivery, error) {
+	var d api.WebhookDelivery
+	var scaleID, next, last, lastErr, delivered sql.NullString
+	var payload, created string
+	if err := scan(&d.ID, &d.WebhookID, &d.TenantID, &scaleID, &d.Event, &payload, &d.Status, &d.Attempts, &next, &last,
+		&d.LastStatus, &lastErr, &created, &delivered); err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	d.ScaleID, d.LastError = scaleID.String, lastErr.String
+	d.NextAttemptAt, d.LastAttemptAt, d.DeliveredAt = parseNullTime(next), parseNullTime(last), parseNullTime(delivered)
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		d.CreatedAt = t
+	}
+	return &d, nil
+}
+
+// --- Personal API tokens (sqlite) ---
+const apiTokenColumns = `id, tenant_id, user_id, email, name, prefix, token_hash, scopes, scale_id, expires_at, last_used_at,
+      created_at, revoked_at`
+
+func (s *SQLiteStore) SaveAPIToken(t *api.APIToken) bool {
+	if t == nil || strings.TrimSpace(t.ID) == "" || strings.TrimSpace(t.TokenHash) == "" {
+		return false
+	}
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		s.logErr("SaveAPIToken encode scopes", err)
+		return false
+	}
+	_, err = s.db.Exec(`INSERT INTO api_tokens (`+apiTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(id) DO UPDATE SET name = excluded.name, scopes = excluded.scopes, expires_at = excluded.expires_at,
+        last_used_at = excluded.last_used_at, revoked_at = excluded.revoked_at`,
+		t.ID, t.TenantID, t.UserID, t.Email, t.Name, t.Prefix, t.TokenHash, string(scopesJSON), toNullString(t.ScaleID),
+		toNullTime(t.ExpiresAt), toNullTime(t.LastUsedAt), t.CreatedAt.UTC().Format(sortableTime), toNullTime(t.RevokedAt))
+	s.logErr("SaveAPIToken", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) GetAPIToken(id string) *api.APIToken {
+	return s.getAPIToken("GetAPIToken", `id = ?`, id)
+}
+
+func (s *SQLiteStore) GetAPITokenByHash(hash string) *api.APIToken {
+	return s.getAPIToken("GetAPITokenByHash", `token_hash = ?`, hash)
+}
+
+func (s *SQLiteStore) getAPIToken(op, where string, arg string) *api.APIToken {
+	t, err := scanAPIToken(s.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE `+where, arg).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr(op, err)
+		}
+		return nil
+	}
+	return t
+}
+
+func (s *SQLiteStore) ListAPITokens(tenantID string) []*api.APIToken {
+	rows, err := s.db.Query(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE tenant_id = ? ORDER BY created_at ASC`, tenantID)
+	if err != nil {
+		s.logErr("ListAPITokens: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListAPITokens: rows.Close", cerr)
+		}
+	}()
+	out := []*api.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows.Scan)
+		if err != nil {
+			s.logErr("ListAPITokens: scan", err)
+			continue
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListAPITokens: rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) TouchAPIToken(id string, at time.Time) bool {
+	res, err := s.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, at.UTC().Format(sortableTime), id)
+	if err != nil {
+		s.logErr("TouchAPIToken", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func scanAPIToken(scan func(dest ...any) error) (*api.APIToken, error) {
+	var t api.APIToken
+	var scaleID, expires, lastUsed, revoked sql.NullString
+	var scopes, created string
+	if err := scan(&t.ID, &t.TenantID, &t.UserID, &t.Email, &t.Name, &t.Prefix, &t.TokenHash, &scopes, &scaleID,
+		&expires, &lastUsed, &created, &revoked); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+		return nil, err
+	}
+	t.ScaleID = scaleID.String
+	t.ExpiresAt, t.LastUsedAt, t.RevokedAt = parseNullTime(expires), parseNullTime(lastUsed), parseNullTime(revoked)
+	if ts, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		t.CreatedAt = ts
+	}
+	return &t, nil
+}
+
+// --- Sessions (sqlite) ---
+const sessionColumns = `id, user_id, tenant_id, email, user_agent, ip, refresh_hash, prev_refresh_hash, created_at, last_seen_at,
+      expires_at, revoked_at`
+
+func (s *SQLiteStore) SaveSession(sess *api.Session) bool {
+	if sess == nil || strings.TrimSpace(sess.ID) == "" || strings.TrimSpace(sess.RefreshHash) == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO sessions (`+sessionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(id) DO UPDATE SET user_agent = excluded.user_agent, ip = excluded.ip, refresh_hash = excluded.refresh_hash,
+        prev_refresh_hash = excluded.prev_refresh_hash, last_seen_at = excluded.last_seen_at, expires_at = excluded.expires_at,
+        revoked_at = excluded.revoked_at`,
+		sess.ID, sess.UserID, sess.TenantID, sess.Email, toNullString(sess.UserAgent), toNullString(sess.IP), sess.RefreshHash,
+		toNullString(sess.PrevRefreshHash), sess.CreatedAt.UTC().Format(sortableTime), sess.LastSeenAt.UTC().Format(sortableTime),
+		sess.ExpiresAt.UTC().Format(sortableTime), toNullTime(sess.RevokedAt))
+	s.logErr("SaveSession", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) GetSession(id string) *api.Session {
+	sess, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetSession", err)
+		}
+		return nil
+	}
+	return sess
+}
+
+func (s *SQLiteStore) GetSessionByRefreshHash(hash string) *api.Session {
+	if hash == "" {
+		return nil
+	}
+	sess, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM sessions
+      WHERE refresh_hash = ? OR id = (SELECT session_id FROM session_rotations WHERE refresh_hash = ?)`, hash, hash).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetSessionByRefreshHash", err)
+		}
+		return nil
+	}
+	return sess
+}
+
+// RotateSession is a compare-and-swap on refresh_hash, so of two concurrent refreshes with the
+// same token only one succeeds.
+func (s *SQLiteStore) RotateSession(sess *api.Session, oldHash string) bool {
+	if sess == nil || strings.TrimSpace(sess.RefreshHash) == "" {
+		return false
+	}
+	rotated := false
+	err := s.withTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE sessions SET tenant_id = ?, user_agent = ?, ip = ?, refresh_hash = ?, prev_refresh_hash = ?, last_seen_at = ?,
+        expires_at = ? WHERE id = ? AND refresh_hash = ? AND revoked_at IS NULL`,
+			sess.TenantID, toNullString(sess.UserAgent), toNullString(sess.IP), sess.RefreshHash, toNullString(oldHash),
+			sess.LastSeenAt.UTC().Format(sortableTime), sess.ExpiresAt.UTC().Format(sortableTime), sess.ID, oldHash)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n != 1 {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO session_rotations (refresh_hash, session_id) VALUES (?, ?)`, oldHash, sess.ID); err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+	s.logErr("RotateSession", err)
+	return err == nil && rotated
+}
+
+func (s *SQLiteStore) ListSessionsByUser(userID string) []*api.Session {
+	rows, err := s.db.Query(`SELECT `+sessionColumns+` FROM sessions WHERE user_id = ? ORDER BY created_at ASC`, userID)
+	if err != nil {
+		s.logErr("ListSessionsByUser: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListSessionsByUser: rows.Close", cerr)
+		}
+	}()
+	out := []*api.Session{}
+	for rows.Next() {
+		sess, err := scanSession(rows.Scan)
+		if err != nil {
+			s.logErr("ListSessionsByUser: scan", err)
+			continue
+		}
+		out = append(out, sess)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListSessionsByUser: rows.Err", err)
+	}
+	return out
+}
+
+func scanSession(scan func(dest ...any) error) (*api.Session, error) {
+	var sess api.Session
+	var ua, ip, prev, revoked sql.NullString
+	var created, lastSeen, expires string
+	if err := scan(&sess.ID, &sess.UserID, &sess.TenantID, &sess.Email, &ua, &ip, &sess.RefreshHash, &prev, &created, &lastSeen,
+		&expires, &revoked); err != nil {
+		return nil, err
+	}
+	sess.UserAgent, sess.IP, sess.PrevRefreshHash = ua.String, ip.String, prev.String
+	sess.RevokedAt = parseNullTime(revoked)
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		sess.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, lastSeen); err == nil {
+		sess.LastSeenAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, expires); err == nil {
+		sess.ExpiresAt = t
+	}
+	return &sess, nil
+}
+
+// --- TOTP enrollments (sqlite) ---
+func (s *SQLiteStore) GetMFA(userID string) *api.MFAConfig {
+	var m api.MFAConfig
+	var enabled int64
+	var hashes, created string
+	var enabledAt sql.NullString
+	err := s.db.QueryRow(`SELECT user_id, secret, enabled, recovery_hashes, last_step, created_at, enabled_at FROM user_mfa WHERE user_id = ?`,
+		userID).Scan(&m.UserID, &m.Secret, &enabled, &hashes, &m.LastStep, &created, &enabledAt)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetMFA", err)
+		}
+		return nil
+	}
+	if err := json.Unmarshal([]byte(hashes), &m.RecoveryHashes); err != nil {
+		s.logErr("GetMFA decode recovery hashes", err)
+		return nil
+	}
+	m.Enabled, m.EnabledAt = int64ToBool(enabled), parseNullTime(enabledAt)
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		m.CreatedAt = t
+	}
+	return &m
+}
+
+func (s *SQLiteStore) SaveMFA(m *api.MFAConfig) bool {
+	if m == nil || strings.TrimSpace(m.UserID) == "" || m.Secret == "" {
+		return false
+	}
+	hashes := m.RecoveryHashes
+	if hashes == nil {
+		hashes = []string{}
+	}
+	hashesJSON, err := json.Marshal(hashes)
+	if err != nil {
+		s.logErr("SaveMFA encode recovery hashes", err)
+		return false
+	}
+	_, err = s.db.Exec(`INSERT INTO user_mfa (user_id, secret, enabled, recovery_hashes, last_step, created_at, enabled_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, enabled = excluded.enabled, recovery_hashes = excluded.recovery_hashes,
+        last_step = excluded.last_step, created_at = excluded.created_at, enabled_at = excluded.enabled_at`,
+		m.UserID, m.Secret, boolToInt64(m.Enabled), string(hashesJSON), m.LastStep, m.CreatedAt.UTC().Format(sortableTime), toNullTime(m.EnabledAt))
+	s.logErr("SaveMFA", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) DeleteMFA(userID string) bool {
+	res, err := s.db.Exec(`DELETE FROM user_mfa WHERE user_id = ?`, userID)
+	if err != nil {
+		s.logErr("DeleteMFA", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+// --- OpenID Connect (sqlite) ---
+const oidcConfigColumns = `tenant_id, enabled, issuer, client_id, client_secret, scopes, allowed_domains, role_claim, role_map, default_role, updated_at`
+
+func (s *SQLiteStore) GetOIDCConfig(tenantID string) *api.OIDCConfig {
+	c, err := scanOIDCConfig(s.db.QueryRow(`SELECT `+oidcConfigColumns+` FROM tenant_oidc WHERE tenant_id = ?`, tenantID).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetOIDCConfig", err)
+		}
+		return nil
+	}
+	return c
+}
+
+func (s *SQLiteStore) ListOIDCConfigs() []*api.OIDCConfig {
+	rows, err := s.db.Query(`SELECT ` + oidcConfigColumns + ` FROM tenant_oidc ORDER BY tenant_id ASC`)
+	if err != nil {
+		s.logErr("ListOIDCConfigs: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListOIDCConfigs: rows.Close", cerr)
+		}
+	}()
+	out := []*api.OIDCConfig{}
+	for rows.Next() {
+		c, err := scanOIDCConfig(rows.Scan)
+		if err != nil {
+			s.logErr("ListOIDCConfigs: scan", err)
+			continue
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListOIDCConfigs: rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) SaveOIDCConfig(c *api.OIDCConfig) bool {
+	if c == nil || strings.TrimSpace(c.TenantID) == "" || c.Issuer == "" || c.ClientID == "" {
+		return false
+	}
+	domains, roleMap := c.AllowedDomains, c.RoleMap
+	if domains == nil {
+		domains = []string{}
+	}
+	if roleMap == nil {
+		roleMap = map[string]string{}
+	}
+	domainsJSON, err := json.Marshal(domains)
+	if err != nil {
+		s.logErr("SaveOIDCConfig encode domains", err)
+		return false
+	}
+	roleMapJSON, err := json.Marshal(roleMap)
+	if err != nil {
+		s.logErr("SaveOIDCConfig encode role map", err)
+		return false
+	}
+	_, err = s.db.Exec(`INSERT INTO tenant_oidc (`+oidcConfigColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(tenant_id) DO UPDATE SET enabled = excluded.enabled, issuer = excluded.issuer, client_id = excluded.client_id,
+        client_secret = excluded.client_secret, scopes = excluded.scopes, allowed_domains = excluded.allowed_domains,
+        role_claim = excluded.role_claim, role_map = excluded.role_map, default_role = excluded.default_role, updated_at = excluded.updated_at`,
+		c.TenantID, boolToInt64(c.Enabled), c.Issuer, c.ClientID, c.ClientSecret, c.Scopes, string(domainsJSON), c.RoleClaim,
+		string(roleMapJSON), c.DefaultRole, c.UpdatedAt.UTC().Format(sortableTime))
+	s.logErr("SaveOIDCConfig", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) DeleteOIDCConfig(tenantID string) bool {
+	res, err := s.db.Exec(`DELETE FROM tenant_oidc WHERE tenant_id = ?`, tenantID)
+	if err != nil {
+		s.logErr("DeleteOIDCConfig", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func scanOIDCConfig(scan func(dest ...any) error) (*api.OIDCConfig, error) {
+	var c api.OIDCConfig
+	var enabled int64
+	var domains, roleMap, updated string
+	if err := scan(&c.TenantID, &enabled, &c.Issuer, &c.ClientID, &c.ClientSecret, &c.Scopes, &domains, &c.RoleClaim, &roleMap, &c.DefaultRole, &updated); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(domains), &c.AllowedDomains); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(roleMap), &c.RoleMap); err != nil {
+		return nil, err
+	}
+	c.Enabled = int64ToBool(enabled)
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		c.UpdatedAt = t
+	}
+	return &c, nil
+}
+
+func (s *SQLiteStore) GetOIDCIdentity(issuer, subject string) *api.OIDCIdentity {
+	var id api.OIDCIdentity
+	var created, lastLogin string
+	err := s.db.QueryRow(`SELECT issuer, subject, user_id, tenant_id, email, role, created_at, last_login_at FROM oidc_identities WHERE issuer = ? AND subject = ?`,
+		issuer, subject).Scan(&id.Issuer, &id.Subject, &id.UserID, &id.TenantID, &id.Email, &id.Role, &created, &lastLogin)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetOIDCIdentity", err)
+		}
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		id.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, lastLogin); err == nil {
+		id.LastLoginAt = t
+	}
+	return &id
+}
+
+func (s *SQLiteStore) SaveOIDCIdentity(id *api.OIDCIdentity) bool {
+	if id == nil || id.Issuer == "" || id.Subject == "" || id.UserID == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO oidc_identities (issuer, subject, user_id, tenant_id, email, role, created_at, last_login_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(issuer, subject) DO UPDATE SET user_id = excluded.user_id, tenant_id = excluded.tenant_id, email = excluded.email,
+        role = excluded.role, last_login_at = excluded.last_login_at`,
+		id.Issuer, id.Subject, id.UserID, id.TenantID, id.Email, id.Role, id.CreatedAt.UTC().Format(sortableTime), id.LastLoginAt.UTC().Format(sortableTime))
+	s.logErr("SaveOIDCIdentity", err)
+	return err == nil
+}
+
+// --- Adaptive testing (sqlite) ---
+func (s *SQLiteStore) GetCATSettings(scaleID string) *api.CATSettings {
+	var c api.CATSettings
+	var enabled int
+	var updated string
+	err := s.db.QueryRow(`SELECT scale_id, enabled, calibration_version, min_items, max_items, se_target, updated_at FROM scale_cat_settings WHERE scale_id = ?`,
+		scaleID).Scan(&c.ScaleID, &enabled, &c.CalibrationVersion, &c.MinItems, &c.MaxItems, &c.SETarget, &updated)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetCATSettings", err)
+		}
+		return nil
+	}
+	c.Enabled = enabled != 0
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		c.UpdatedAt = t
+	}
+	return &c
+}
+
+func (s *SQLiteStore) SaveCATSettings(c *api.CATSettings) bool {
+	if c == nil || strings.TrimSpace(c.ScaleID) == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO scale_cat_settings (scale_id, enabled, calibration_version, min_items, max_items, se_target, updated_at)
+      VALUES (?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(scale_id) DO UPDATE SET enabled = excluded.enabled, calibration_version = excluded.calibration_version,
+        min_items = excluded.min_items, max_items = excluded.max_items, se_target = excluded.se_target, updated_at = excluded.updated_at`,
+		c.ScaleID, boolToInt64(c.Enabled), c.CalibrationVersion, c.MinItems, c.MaxItems, c.SETarget, c.UpdatedAt.UTC().Format(time.RFC3339Nano))
+	s.logErr("SaveCATSettings", err)
+	return err == nil
+}
+
+const catSessionColumns = `id, scale_id, token_hash, calibration_version, status, stop_reason, next_item_id, next_information, steps, theta, se,
+      participant_id, participant_email, consent_id, condition, created_at, updated_at, finished_at`
+
+func (s *SQLiteStore) GetCATSession(id string) *api.CATSession {
+	sess, err := scanCATSession(s.db.QueryRow(`SELECT `+catSessionColumns+` FROM cat_sessions WHERE id = ?`, id).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetCATSession", err)
+		}
+		return nil
+	}
+	return sess
+}
+
+func (s *SQLiteStore) SaveCATSession(sess *api.CATSession, prevStatus string, prevSteps int) bool {
+	if sess == nil || strings.TrimSpace(sess.ID) == "" {
+		return false
+	}
+	steps := sess.Steps
+	if steps == nil {
+		steps = []api.CATStep{}
+	}
+	stepsJSON, err := json.Marshal(steps)
+	if err != nil {
+		s.logErr("SaveCATSession encode steps", err)
+		return false
+	}
+	var finished sql.NullString
+	if !sess.FinishedAt.IsZero() {
+		finished = sql.NullString{String: sess.FinishedAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+	var res sql.Result
+	if prevStatus == "" {
+		res, err = s.db.Exec(`INSERT INTO cat_sessions (`+catSessionColumns+`)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT(id) DO NOTHING`,
+			sess.ID, sess.ScaleID, sess.TokenHash, sess.CalibrationVersion, sess.Status, toNullString(sess.StopReason), toNullString(sess.NextItemID),
+			sess.NextInformation, string(stepsJSON), sess.Theta, sess.SE, toNullString(sess.ParticipantID), toNullString(sess.ParticipantEmail),
+			toNullString(sess.ConsentID), toNullString(sess.Condition), sess.CreatedAt.UTC().Format(time.RFC3339Nano),
+			sess.UpdatedAt.UTC().Format(time.RFC3339Nano), finished)
+	} else {
+		// the status and the number of answers identify the state the caller read
+		res, err = s.db.Exec(`UPDATE cat_sessions SET status = ?, stop_reason = ?, next_item_id = ?, next_information = ?, steps = ?, theta = ?, se = ?,
+        participant_id = ?, participant_email = ?, updated_at = ?, finished_at = ?
+      WHERE id = ? AND status = ? AND json_array_length(steps) = ?`,
+			sess.Status, toNullString(sess.StopReason), toNullString(sess.NextItemID), sess.NextInformation, string(stepsJSON), sess.Theta, sess.SE,
+			toNullString(sess.ParticipantID), toNullString(sess.ParticipantEmail), sess.UpdatedAt.UTC().Format(time.RFC3339Nano), finished,
+			sess.ID, prevStatus, prevSteps)
+	}
+	if err != nil {
+		s.logErr("SaveCATSession", err)
+		return false
+	}
+	n, err := res.RowsAffected()
+	s.logErr("SaveCATSession", err)
+	return err == nil && n == 1
+}
+
+func (s *SQLiteStore) ListCATSessions(scaleID string) []*api.CATSession {
+	rows, err := s.db.Query(`SELECT `+catSessionColumns+` FROM cat_sessions WHERE scale_id = ? ORDER BY created_at ASC`, scaleID)
+	if err != nil {
+		s.logErr("ListCATSessions: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListCATSessions: rows.Close", cerr)
+		}
+	}()
+	out := []*api.CATSession{}
+	for rows.Next() {
+		sess, err := scanCATSession(rows.Scan)
+		if err != nil {
+			s.logErr("ListCATSessions: scan", err)
+			continue
+		}
+		out = append(out, sess)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListCATSessions: rows.Err", err)
 	}
 	return out
 }
 
-// --- Invitations (sqlite) ---
-func (s *SQLiteStore) CreateInvite(inv *api.ScaleInvite) (*api.ScaleInvite, error) {
-	if inv == nil || strings.TrimSpace(inv.Token) == "" {
-		return nil, errors.New("invalid invite")
+func scanCATSession(scan func(dest ...any) error) (*api.CATSession, error) {
+	var sess api.CATSession
+	var stopReason, nextItem, pid, email, consentID, condition, finished sql.NullString
+	var steps, created, updated string
+	if err := scan(&sess.ID, &sess.ScaleID, &sess.TokenHash, &sess.CalibrationVersion, &sess.Status, &stopReason, &nextItem, &sess.NextInformation,
+		&steps, &sess.Theta, &sess.SE, &pid, &email, &consentID, &condition, &created, &updated, &finished); err != nil {
+		return nil, err
 	}
-	_, err := s.db.Exec(`INSERT INTO scale_invites (token, tenant_id, scale_id, email, role, created_at, expires_at)
-      VALUES (?, ?, ?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), ?)`, inv.Token, inv.TenantID, inv.ScaleID, inv.Email, inv.Role, inv.CreatedAt.UTC().Format(time.RFC3339Nano), inv.ExpiresAt.UTC().Format(time.RFC3339Nano))
-	if err != nil {
+	if err := json.Unmarshal([]byte(steps), &sess.Steps); err != nil {
 		return nil, err
 	}
-	return inv, nil
+	sess.StopReason, sess.NextItemID, sess.ParticipantID = stopReason.String, nextItem.String, pid.String
+	sess.ParticipantEmail, sess.ConsentID, sess.Condition = email.String, consentID.String, condition.String
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		sess.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		sess.UpdatedAt = t
+	}
+	if finished.Valid {
+		if t, err := time.Parse(time.RFC3339Nano, finished.String); err == nil {
+			sess.FinishedAt = t
+		}
+	}
+	return &sess, nil
 }
 
-func (s *SQLiteStore) GetInvite(token string) *api.ScaleInvite {
-	row := s.db.QueryRow(`SELECT token, tenant_id, scale_id, email, role, created_at, expires_at, accepted_at FROM scale_invites WHERE token = ?`, token)
-	var inv api.ScaleInvite
-	var created, expires, accepted sql.NullString
-	if err := row.Scan(&inv.Token, &inv.TenantID, &inv.ScaleID, &inv.Email, &inv.Role, &created, &expires, &accepted); err != nil {
-		if !errors.Is(err, sql.ErrNoRows) {
-			s.logErr("GetInvite", err)
-		}
-		return nil
+func scanDataEntryForm(scan func(dest ...any) error) (*api.DataEntryForm, error) {
+	var f api.DataEntryForm
+	var first, firstBy, second, secondBy, mismatches, resolvedBy, pid sql.NullString
+	var created, updated string
+	if err := scan(&f.ScaleID, &f.FormID, &first, &firstBy, &second, &secondBy, &f.Status, &mismatches, &resolvedBy, &pid, &created, &updated); err != nil {
+		return nil, err
 	}
-	if created.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, created.String); err == nil {
-			inv.CreatedAt = t
+	if first.Valid {
+		if err := json.Unmarshal([]byte(first.String), &f.FirstEntry); err != nil {
+			return nil, err
 		}
 	}
-	if expires.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, expires.String); err == nil {
-			inv.ExpiresAt = t
+	if second.Valid {
+		if err := json.Unmarshal([]byte(second.String), &f.SecondEntry); err != nil {
+			return nil, err
 		}
 	}
-	if accepted.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, accepted.String); err == nil {
-			inv.AcceptedAt = t
+	if mismatches.Valid {
+		if err := json.Unmarshal([]byte(mismatches.String), &f.Mismatches); err != nil {
+			return nil, err
 		}
 	}
-	return &inv
-}
-
-func (s *SQLiteStore) MarkInviteAccepted(token string) bool {
-	_, err := s.db.Exec(`UPDATE scale_invites SET accepted_at = CURRENT_TIMESTAMP WHERE token = ?`, token)
-	s.logErr("MarkInviteAccepted", err)
-	return err == nil
+	f.FirstBy, f.SecondBy, f.ResolvedBy, f.ParticipantID = firstBy.String, secondBy.String, resolvedBy.String, pid.String
+	if t, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		f.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339Nano, updated); err == nil {
+		f.UpdatedAt = t
+	}
+	return &f, nil
 }
 
 func convertItem(rec sq.Item) *api.Item {
@@ -757,7 +1942,11 @@ func (s *SQLiteStore) AddParticipant(p *api.Participant) {
 	if p == nil {
 		return
 	}
-	ctx := contextBg()
+	s.logErr("AddParticipant", insertParticipant(contextBg(), s.db, s.q, p))
+}
+
+// insertParticipant writes p and its condition through db and q, which may belong to a transaction.
+func insertParticipant(ctx context.Context, db sq.DBTX, q *sq.Queries, p *api.Participant) error {
 	token := strings.TrimSpace(p.SelfToken)
 	if token == "" {
 		token = generateToken(24)
@@ -770,7 +1959,43 @@ func (s *SQLiteStore) AddParticipant(p *api.Participant) {
 		ConsentID: toNullString(p.ConsentID),
 		Column5:   time.Now().UTC(),
 	}
-	s.logErr("AddParticipant", s.q.CreateParticipant(ctx, params))
+	if err := q.CreateParticipant(ctx, params); err != nil {
+		return err
+	}
+	if c := strings.TrimSpace(p.Condition); c != "" {
+		_, err := db.ExecContext(ctx, `INSERT INTO participant_conditions (participant_id, condition) VALUES (?, ?)
+      ON CONFLICT(participant_id) DO UPDATE SET condition = excluded.condition`, p.ID, c)
+		return err
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListParticipantConditions(scaleID string) map[string]string {
+	out := map[string]string{}
+	rows, err := s.db.Query(`SELECT DISTINCT pc.participant_id, pc.condition FROM participant_conditions pc
+      JOIN responses r ON r.participant_id = pc.participant_id WHERE r.scale_id = ?`, scaleID)
+	if err != nil {
+		s.logErr("ListParticipantConditions", err)
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pid, cond string
+		if err := rows.Scan(&pid, &cond); err != nil {
+			s.logErr("ListParticipantConditions scan", err)
+			continue
+		}
+		out[pid] = cond
+	}
+	return out
+}
+
+func (s *SQLiteStore) participantCondition(id string) string {
+	var cond string
+	if err := s.db.QueryRow(`SELECT condition FROM participant_conditions WHERE participant_id = ?`, id).Scan(&cond); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.logErr("participantCondition", err)
+	}
+	return cond
 }
 
 func (s *SQLiteStore) GetParticipant(id string) *api.Participant {
@@ -785,7 +2010,9 @@ func (s *SQLiteStore) GetParticipant(id string) *api.Participant {
 		s.logErr("GetParticipant", err)
 		return nil
 	}
-	return convertParticipant(rec)
+	p := convertParticipant(rec)
+	p.Condition = s.participantCondition(p.ID)
+	return p
 }
 
 func (s *SQLiteStore) GetParticipantByEmail(email string) *api.Participant {
@@ -856,7 +2083,12 @@ func (s *SQLiteStore) ListResponsesByParticipant(pid string) []*api.Response {
 }
 
 func (s *SQLiteStore) AddResponses(rs []*api.Response) {
-	ctx := contextBg()
+	s.logErr("AddResponses", insertResponses(contextBg(), s.db, s.q, rs))
+}
+
+// insertResponses writes rs through db and q, which may belong to a transaction. Responses to
+// unknown items are skipped.
+func insertResponses(ctx context.Context, db sq.DBTX, q *sq.Queries, rs []*api.Response) error {
 	itemScale := map[string]string{}
 	for _, r := range rs {
 		if r == nil {
@@ -864,10 +2096,11 @@ func (s *SQLiteStore) AddResponses(rs []*api.Response) {
 		}
 		scaleID := itemScale[r.ItemID]
 		if scaleID == "" {
-			row := s.db.QueryRowContext(ctx, "SELECT scale_id FROM items WHERE id = ?", r.ItemID)
-			if err := row.Scan(&scaleID); err != nil {
-				s.logErr("AddResponses resolve scale", err)
+			row := db.QueryRowContext(ctx, "SELECT scale_id FROM items WHERE id = ?", r.ItemID)
+			if err := row.Scan(&scaleID); errors.Is(err, sql.ErrNoRows) {
 				continue
+			} else if err != nil {
+				return err
 			}
 			itemScale[r.ItemID] = scaleID
 		}
@@ -885,8 +2118,11 @@ func (s *SQLiteStore) AddResponses(rs []*api.Response) {
 			SubmittedAt:   r.SubmittedAt,
 			RawJson:       toNullString(r.RawJSON),
 		}
-		s.logErr("InsertResponse", s.q.InsertResponse(ctx, params))
+		if err := q.InsertResponse(ctx, params); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (s *SQLiteStore) ListResponsesByScale(scaleID string) []*api.Response {
@@ -1092,18 +2328,108 @@ func (s *SQLiteStore) GetConsentByID(id string) *api.ConsentRecord {
 // --- Audit log ---
 
 func (s *SQLiteStore) AddAudit(e api.AuditEntry) {
-	ts := e.Time
-	if ts.IsZero() {
-		ts = time.Now().UTC()
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+	s.logErr("AddAudit", s.withTx(func(tx *sql.Tx) error {
+		var prev string
+		err := tx.QueryRow(`SELECT hash FROM audit_chain ORDER BY audit_id DESC LIMIT 1`).Scan(&prev)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return appendAudit(tx, &e, prev)
+	}))
+}
+
+// appendAudit inserts e and its chain link; the audit_log id becomes the sequence number.
+func appendAudit(tx *sql.Tx, e *api.AuditEntry, prev string) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	res, err := tx.Exec(`INSERT INTO audit_log (ts, actor, action, target, note) VALUES (?, ?, ?, ?, ?)`,
+		e.Time, e.Actor, e.Action, toNullString(e.Target), toNullString(e.Note))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	api.ChainAuditEntry(e, id, prev)
+	if _, err := tx.Exec(`INSERT INTO audit_chain (audit_id, prev_hash, hash) VALUES (?, ?, ?)`, id, e.PrevHash, e.Hash); err != nil {
+		return err
 	}
-	params := sq.InsertAuditParams{
-		Column1: ts,
-		Actor:   e.Actor,
-		Action:  e.Action,
-		Target:  toNullString(e.Target),
-		Note:    toNullString(e.Note),
+	_, err = tx.Exec(`INSERT INTO audit_details (audit_id, ts, tenant_id, actor_id, ip, user_agent, result) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, e.Time.Format(sortableTime), e.TenantID, e.ActorID, e.IP, e.UserAgent, e.Result)
+	return err
+}
+
+// sealAuditLog chains the rows written before the audit log was hash-chained, see api.SealAuditLog.
+func (s *SQLiteStore) sealAuditLog() error {
+	var chained int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM audit_chain`).Scan(&chained); err != nil || chained > 0 {
+		return err
 	}
-	s.logErr("AddAudit", s.q.InsertAudit(contextBg(), params))
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT ` + auditChainColumns + ` FROM ` + auditFrom + ` ORDER BY a.id`)
+		if err != nil {
+			return err
+		}
+		var entries []api.AuditEntry
+		for rows.Next() {
+			e, err := scanAuditEntry(rows.Scan)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+			entries = append(entries, *e)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		prev := ""
+		for _, e := range entries {
+			api.ChainAuditEntry(&e, e.Seq, prev)
+			if _, err := tx.Exec(`INSERT INTO audit_chain (audit_id, prev_hash, hash) VALUES (?, ?, ?)`, e.Seq, e.PrevHash, e.Hash); err != nil {
+				return err
+			}
+			prev = e.Hash
+		}
+		return nil
+	})
+}
+
+// backfillAuditDetails gives rows logged before audit_details existed a details row, so that time
+// range queries see them. They keep an empty tenant and match by target only.
+func (s *SQLiteStore) backfillAuditDetails() error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT a.id, a.ts FROM audit_log a LEFT JOIN audit_details d ON d.audit_id = a.id WHERE d.audit_id IS NULL`)
+		if err != nil {
+			return err
+		}
+		type missing struct {
+			id int64
+			ts time.Time
+		}
+		var list []missing
+		for rows.Next() {
+			var m missing
+			if err := rows.Scan(&m.id, &m.ts); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			list = append(list, m)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		for _, m := range list {
+			if _, err := tx.Exec(`INSERT INTO audit_details (audit_id, ts) VALUES (?, ?)`, m.id, m.ts.UTC().Format(sortableTime)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (s *SQLiteStore) ListAudit() []api.AuditEntry {
@@ -1125,6 +2451,146 @@ func (s *SQLiteStore) ListAudit() []api.AuditEntry {
 	return out
 }
 
+const (
+	auditChainColumns = `a.id, a.ts, a.actor, a.action, a.target, a.note, COALESCE(d.tenant_id, ''), COALESCE(d.actor_id, ''),
+		COALESCE(d.ip, ''), COALESCE(d.user_agent, ''), COALESCE(d.result, ''), COALESCE(c.prev_hash, ''), COALESCE(c.hash, '')`
+	auditFrom = `audit_log a LEFT JOIN audit_chain c ON c.audit_id = a.id LEFT JOIN audit_details d ON d.audit_id = a.id`
+)
+
+func scanAuditEntry(scan func(dest ...any) error) (*api.AuditEntry, error) {
+	var (
+		e            api.AuditEntry
+		target, note sql.NullString
+	)
+	if err := scan(&e.Seq, &e.Time, &e.Actor, &e.Action, &target, &note, &e.TenantID, &e.ActorID, &e.IP, &e.UserAgent, &e.Result,
+		&e.PrevHash, &e.Hash); err != nil {
+		return nil, err
+	}
+	e.Target, e.Note = target.String, note.String
+	return &e, nil
+}
+
+// ListAuditChain returns entries in id order. Rows missing from audit_chain come back without a
+// hash, which verification reports as a broken link.
+func (s *SQLiteStore) ListAuditChain(afterSeq int64, limit int) []api.AuditEntry {
+	rows, err := s.db.Query(`SELECT `+auditChainColumns+` FROM `+auditFrom+` WHERE a.id > ? ORDER BY a.id LIMIT ?`, afterSeq, limit)
+	if err != nil {
+		s.logErr("ListAuditChain", err)
+		return nil
+	}
+	defer rows.Close()
+	out := []api.AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows.Scan)
+		if err != nil {
+			s.logErr("ListAuditChain", err)
+			return out
+		}
+		out = append(out, *e)
+	}
+	s.logErr("ListAuditChain", rows.Err())
+	return out
+}
+
+// QueryAudit pages backwards through the log by id. Entries without a tenant, logged before the
+// tenant was recorded, match through q.LegacyTargets.
+func (s *SQLiteStore) QueryAudit(q api.AuditQuery) []api.AuditEntry {
+	where, args := []string{"d.tenant_id = ?"}, []any{q.TenantID}
+	if len(q.LegacyTargets) > 0 {
+		where[0] = "(d.tenant_id = ? OR (COALESCE(d.tenant_id, '') = '' AND a.target IN (" + placeholders(len(q.LegacyTargets)) + ")))"
+		for _, t := range q.LegacyTargets {
+			args = append(args, t)
+		}
+	}
+	if q.Action != "" {
+		where, args = append(where, "a.action = ?"), append(args, q.Action)
+	}
+	if q.Actor != "" {
+		where, args = append(where, "(a.actor = ? COLLATE NOCASE OR d.actor_id = ?)"), append(args, q.Actor, q.Actor)
+	}
+	if len(q.Targets) > 0 {
+		where = append(where, "a.target IN ("+placeholders(len(q.Targets))+")")
+		for _, t := range q.Targets {
+			args = append(args, t)
+		}
+	}
+	switch q.Source {
+	case "admin":
+		where = append(where, "a.actor <> 'participant' COLLATE NOCASE")
+	case "participant":
+		where = append(where, "a.actor = 'participant' COLLATE NOCASE")
+	}
+	if !q.From.IsZero() {
+		where, args = append(where, "d.ts >= ?"), append(args, q.From.UTC().Format(sortableTime))
+	}
+	if !q.To.IsZero() {
+		where, args = append(where, "d.ts < ?"), append(args, q.To.UTC().Format(sortableTime))
+	}
+	if q.Before > 0 {
+		where, args = append(where, "a.id < ?"), append(args, q.Before)
+	}
+	rows, err := s.db.Query(`SELECT `+auditChainColumns+` FROM `+auditFrom+` WHERE `+strings.Join(where, " AND ")+` ORDER BY a.id DESC LIMIT ?`,
+		append(args, q.Limit)...)
+	if err != nil {
+		s.logErr("QueryAudit", err)
+		return nil
+	}
+	defer rows.Close()
+	out := []api.AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows.Scan)
+		if err != nil {
+			s.logErr("QueryAudit", err)
+			return out
+		}
+		out = append(out, *e)
+	}
+	s.logErr("QueryAudit", rows.Err())
+	return out
+}
+
+func (s *SQLiteStore) AuditHead() *api.AuditEntry {
+	e, err := scanAuditEntry(s.db.QueryRow(`SELECT ` + auditChainColumns + ` FROM ` + auditFrom + ` ORDER BY a.id DESC LIMIT 1`).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("AuditHead", err)
+		}
+		return nil
+	}
+	return e
+}
+
+func (s *SQLiteStore) AddAuditCheckpoint(cp api.AuditCheckpoint) bool {
+	_, err := s.db.Exec(`INSERT INTO audit_checkpoints (seq, hash, created_at, public_key, signature) VALUES (?, ?, ?, ?, ?)`,
+		cp.Seq, cp.Hash, cp.CreatedAt.UTC().Format(time.RFC3339Nano), cp.PublicKey, cp.Signature)
+	s.logErr("AddAuditCheckpoint", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) ListAuditCheckpoints() []api.AuditCheckpoint {
+	rows, err := s.db.Query(`SELECT seq, hash, created_at, public_key, signature FROM audit_checkpoints ORDER BY seq`)
+	if err != nil {
+		s.logErr("ListAuditCheckpoints", err)
+		return nil
+	}
+	defer rows.Close()
+	out := []api.AuditCheckpoint{}
+	for rows.Next() {
+		var (
+			cp      api.AuditCheckpoint
+			created string
+		)
+		if err := rows.Scan(&cp.Seq, &cp.Hash, &created, &cp.PublicKey, &cp.Signature); err != nil {
+			s.logErr("ListAuditCheckpoints", err)
+			return out
+		}
+		cp.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		out = append(out, cp)
+	}
+	s.logErr("ListAuditCheckpoints", rows.Err())
+	return out
+}
+
 // --- Export throttling ---
 
 func (s *SQLiteStore) CreateExportJob(tid, scaleID, ip string, ttl time.Duration) *api.ExportJob {
@@ -1246,7 +2712,13 @@ func (s *SQLiteStore) AddUser(u *api.User) {
 		return
 	}
 	params := sq.CreateUserParams{ID: u.ID, Email: u.Email, PassHash: u.PassHash, TenantID: u.TenantID, Column5: time.Now().UTC()}
-	s.logErr("AddUser", s.q.CreateUser(contextBg(), params))
+	if err := s.q.CreateUser(contextBg(), params); err != nil {
+		s.logErr("AddUser", err)
+		return
+	}
+	if !u.EmailVerifiedAt.IsZero() {
+		s.SetEmailVerified(u.ID, u.EmailVerifiedAt)
+	}
 }
 
 func (s *SQLiteStore) FindUserByEmail(email string) *api.User {
@@ -1258,5 +2730,259 @@ func (s *SQLiteStore) FindUserByEmail(email string) *api.User {
 		s.logErr("FindUserByEmail", err)
 		return nil
 	}
-	return &api.User{ID: rec.ID, Email: rec.Email, PassHash: rec.PassHash, TenantID: rec.TenantID, CreatedAt: rec.CreatedAt}
+	u := &api.User{ID: rec.ID, Email: rec.Email, PassHash: rec.PassHash, TenantID: rec.TenantID, CreatedAt: rec.CreatedAt}
+	var verified string
+	err = s.db.QueryRow(`SELECT verified_at FROM user_email_verifications WHERE user_id = ?`, rec.ID).Scan(&verified)
+	if err == nil {
+		if t, perr := time.Parse(time.RFC3339Nano, verified); perr == nil {
+			u.EmailVerifiedAt = t
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		s.logErr("FindUserByEmail verification", err)
+	}
+	return u
+}
+
+func (s *SQLiteStore) SetUserPassword(userID string, hash []byte) bool {
+	if len(hash) == 0 {
+		return false
+	}
+	res, err := s.db.Exec(`UPDATE users SET pass_hash = ? WHERE id = ?`, hash, userID)
+	if err != nil {
+		s.logErr("SetUserPassword", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func (s *SQLiteStore) SetEmailVerified(userID string, at time.Time) bool {
+	_, err := s.db.Exec(`INSERT INTO user_email_verifications (user_id, verified_at) VALUES (?, ?)
+      ON CONFLICT(user_id) DO UPDATE SET verified_at = excluded.verified_at`, userID, at.UTC().Format(sortableTime))
+	s.logErr("SetEmailVerified", err)
+	return err == nil
+}
+
+// --- Email tokens (sqlite) ---
+const emailTokenColumns = `id, user_id, email, purpose, hash, created_at, expires_at, used_at`
+
+func (s *SQLiteStore) SaveEmailToken(t *api.EmailToken) bool {
+	if t == nil || t.ID == "" || t.Hash == "" || t.UserID == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO email_tokens (`+emailTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+      ON CONFLICT(id) DO UPDATE SET used_at = excluded.used_at, expires_at = excluded.expires_at`,
+		t.ID, t.UserID, t.Email, t.Purpose, t.Hash, t.CreatedAt.UTC().Format(sortableTime), t.ExpiresAt.UTC().Format(sortableTime), toNullTime(t.UsedAt))
+	s.logErr("SaveEmailToken", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) GetEmailTokenByHash(hash string) *api.EmailToken {
+	t, err := scanEmailToken(s.db.QueryRow(`SELECT `+emailTokenColumns+` FROM email_tokens WHERE hash = ?`, hash).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetEmailTokenByHash", err)
+		}
+		return nil
+	}
+	return t
+}
+
+func (s *SQLiteStore) ListEmailTokens(userID, purpose string) []*api.EmailToken {
+	rows, err := s.db.Query(`SELECT `+emailTokenColumns+` FROM email_tokens WHERE user_id = ? AND purpose = ? ORDER BY created_at ASC`, userID, purpose)
+	if err != nil {
+		s.logErr("ListEmailTokens: query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr("ListEmailTokens: rows.Close", cerr)
+		}
+	}()
+	out := []*api.EmailToken{}
+	for rows.Next() {
+		t, err := scanEmailToken(rows.Scan)
+		if err != nil {
+			s.logErr("ListEmailTokens: scan", err)
+			continue
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr("ListEmailTokens: rows.Err", err)
+	}
+	return out
+}
+
+func scanEmailToken(scan func(dest ...any) error) (*api.EmailToken, error) {
+	var t api.EmailToken
+	var created, expires string
+	var used sql.NullString
+	if err := scan(&t.ID, &t.UserID, &t.Email, &t.Purpose, &t.Hash, &created, &expires, &used); err != nil {
+		return nil, err
+	}
+	if v, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		t.CreatedAt = v
+	}
+	if v, err := time.Parse(time.RFC3339Nano, expires); err == nil {
+		t.ExpiresAt = v
+	}
+	t.UsedAt = parseNullTime(used)
+	return &t, nil
+}
+
+// --- Login throttles (sqlite) ---
+const loginThrottleColumns = `key, tenant_id, failures, last_failure_at, locked_until`
+
+func (s *SQLiteStore) GetLoginThrottle(key string) *api.LoginThrottle {
+	t, err := scanLoginThrottle(s.db.QueryRow(`SELECT `+loginThrottleColumns+` FROM login_throttles WHERE key = ?`, key).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetLoginThrottle", err)
+		}
+		return nil
+	}
+	return t
+}
+
+func (s *SQLiteStore) SaveLoginThrottle(t *api.LoginThrottle) bool {
+	if t == nil || t.Key == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO login_throttles (`+loginThrottleColumns+`) VALUES (?, ?, ?, ?, ?)
+      ON CONFLICT(key) DO UPDATE SET tenant_id = excluded.tenant_id, failures = excluded.failures,
+        last_failure_at = excluded.last_failure_at, locked_until = excluded.locked_until`,
+		t.Key, t.TenantID, t.Failures, t.LastFailureAt.UTC().Format(sortableTime), toNullTime(t.LockedUntil))
+	s.logErr("SaveLoginThrottle", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) DeleteLoginThrottle(key string) bool {
+	res, err := s.db.Exec(`DELETE FROM login_throttles WHERE key = ?`, key)
+	if err != nil {
+		s.logErr("DeleteLoginThrottle", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func scanLoginThrottle(scan func(dest ...any) error) (*api.LoginThrottle, error) {
+	var t api.LoginThrottle
+	var last string
+	var locked sql.NullString
+	if err := scan(&t.Key, &t.TenantID, &t.Failures, &last, &locked); err != nil {
+		return nil, err
+	}
+	if v, err := time.Parse(time.RFC3339Nano, last); err == nil {
+		t.LastFailureAt = v
+	}
+	t.LockedUntil = parseNullTime(locked)
+	return &t, nil
+}
+
+// --- Tenant memberships (sqlite) ---
+const tenantMemberSelect = `SELECT m.tenant_id, m.user_id, m.role, m.created_at, COALESCE(u.email, ''), COALESCE(t.name, '')
+  FROM tenant_members m LEFT JOIN users u ON u.id = m.user_id LEFT JOIN tenants t ON t.id = m.tenant_id`
+
+func (s *SQLiteStore) GetTenant(id string) *api.Tenant {
+	var t api.Tenant
+	if err := s.db.QueryRow(`SELECT id, name FROM tenants WHERE id = ?`, id).Scan(&t.ID, &t.Name); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetTenant", err)
+		}
+		return nil
+	}
+	return &t
+}
+
+func (s *SQLiteStore) SetUserTenant(userID, tenantID string) bool {
+	if tenantID == "" {
+		return false
+	}
+	res, err := s.db.Exec(`UPDATE users SET tenant_id = ? WHERE id = ?`, tenantID, userID)
+	if err != nil {
+		s.logErr("SetUserTenant", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func (s *SQLiteStore) GetTenantMember(tenantID, userID string) *api.TenantMember {
+	m, err := scanTenantMember(s.db.QueryRow(tenantMemberSelect+` WHERE m.tenant_id = ? AND m.user_id = ?`, tenantID, userID).Scan)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.logErr("GetTenantMember", err)
+		}
+		return nil
+	}
+	return m
+}
+
+func (s *SQLiteStore) ListTenantMembers(tenantID string) []*api.TenantMember {
+	return s.listTenantMembers("ListTenantMembers", tenantMemberSelect+` WHERE m.tenant_id = ? ORDER BY m.created_at`, tenantID)
+}
+
+func (s *SQLiteStore) ListUserTenants(userID string) []*api.TenantMember {
+	return s.listTenantMembers("ListUserTenants", tenantMemberSelect+` WHERE m.user_id = ? ORDER BY m.created_at`, userID)
+}
+
+func (s *SQLiteStore) listTenantMembers(op, query string, arg string) []*api.TenantMember {
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		s.logErr(op+": query", err)
+		return nil
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			s.logErr(op+": rows.Close", cerr)
+		}
+	}()
+	out := []*api.TenantMember{}
+	for rows.Next() {
+		m, err := scanTenantMember(rows.Scan)
+		if err != nil {
+			s.logErr(op+": scan", err)
+			continue
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		s.logErr(op+": rows.Err", err)
+	}
+	return out
+}
+
+func (s *SQLiteStore) SaveTenantMember(m *api.TenantMember) bool {
+	if m == nil || m.TenantID == "" || m.UserID == "" {
+		return false
+	}
+	_, err := s.db.Exec(`INSERT INTO tenant_members (tenant_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+      ON CONFLICT(tenant_id, user_id) DO UPDATE SET role = excluded.role`,
+		m.TenantID, m.UserID, m.Role, m.CreatedAt.UTC().Format(sortableTime))
+	s.logErr("SaveTenantMember", err)
+	return err == nil
+}
+
+func (s *SQLiteStore) DeleteTenantMember(tenantID, userID string) bool {
+	res, err := s.db.Exec(`DELETE FROM tenant_members WHERE tenant_id = ? AND user_id = ?`, tenantID, userID)
+	if err != nil {
+		s.logErr("DeleteTenantMember", err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func scanTenantMember(scan func(dest ...any) error) (*api.TenantMember, error) {
+	var m api.TenantMember
+	var created string
+	if err := scan(&m.TenantID, &m.UserID, &m.Role, &created, &m.Email, &m.TenantName); err != nil {
+		return nil, err
+	}
+	if v, err := time.Parse(time.RFC3339Nano, created); err == nil {
+		m.CreatedAt = v
+	}
+	return &m, nil
 }
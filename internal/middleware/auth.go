@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
@@ -19,33 +19,70 @@ type Claims struct {
 	UID   string `json:"uid"`
 	TID   string `json:"tid"`
 	Email string `json:"email"`
+	// StepUp marks a short-lived token issued after a TOTP or recovery code check.
+	StepUp bool `json:"stepup,omitempty"`
+	// Set only for personal API tokens (see WithScope); session tokens carry no scopes.
+	TokenID string   `json:"-"`
+	Scopes  []string `json:"-"`
+	ScaleID string   `json:"-"`
+	// GuestRole is set while a collaborator from another tenant acts on a shared scale; TID is
+	// then the scale's tenant.
+	GuestRole string `json:"-"`
 	jwt.RegisteredClaims
 }
 
-func secret() []byte {
-	s := os.Getenv("SYNAP_JWT_SECRET")
-	if s == "" {
-		s = "synap-dev-secret"
-	}
-	return []byte(s)
+func SignToken(uid, tid, email string, ttl time.Duration) (string, error) {
+	return SignSessionToken(uid, tid, email, "", ttl)
 }
 
-func SignToken(uid, tid, email string, ttl time.Duration) (string, error) {
+// SignSessionToken signs an access token for a server-side session; jti is the session ID.
+func SignSessionToken(uid, tid, email, jti string, ttl time.Duration) (string, error) {
+	return signToken(Claims{UID: uid, TID: tid, Email: email}, jti, ttl)
+}
+
+// SignStepUpToken signs a session access token carrying the step-up claim.
+func SignStepUpToken(uid, tid, email, jti string, ttl time.Duration) (string, error) {
+	return signToken(Claims{UID: uid, TID: tid, Email: email, StepUp: true}, jti, ttl)
+}
+
+func signToken(claims Claims, jti string, ttl time.Duration) (string, error) {
 	now := time.Now()
-	claims := Claims{UID: uid, TID: tid, Email: email, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(now.Add(ttl))}}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret())
+	claims.RegisteredClaims = jwt.RegisteredClaims{ID: jti, IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(now.Add(ttl))}
+	kr, err := keys()
+	if err != nil {
+		return "", err
+	}
+	return kr.sign(claims)
+}
+
+// RevocationCheck reports whether the session behind a token ID has been revoked.
+type RevocationCheck func(jti string) bool
+
+var revocationCheck atomic.Value // RevocationCheck
+
+// SetRevocationCheck makes parseToken reject tokens whose ID is revoked. Once set, tokens without
+// an ID (issued before sessions existed) are rejected as well.
+func SetRevocationCheck(check RevocationCheck) {
+	revocationCheck.Store(check)
 }
 
 func parseToken(tok string) (*Claims, error) {
-	t, err := jwt.ParseWithClaims(tok, &Claims{}, func(token *jwt.Token) (interface{}, error) { return secret(), nil })
+	kr, err := keys()
+	if err != nil {
+		return nil, err
+	}
+	t, err := jwt.ParseWithClaims(tok, &Claims{}, kr.keyFunc, jwt.WithValidMethods([]string{"EdDSA", "ES256", "HS256"}))
 	if err != nil {
 		return nil, err
 	}
-	if c, ok := t.Claims.(*Claims); ok && t.Valid {
-		return c, nil
+	c, ok := t.Claims.(*Claims)
+	if !ok || !t.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if check, _ := revocationCheck.Load().(RevocationCheck); check != nil && (c.ID == "" || check(c.ID)) {
+		return nil, errors.New("token revoked")
 	}
-	return nil, errors.New("invalid token")
+	return c, nil
 }
 
 // Attach auth claims to context if Authorization header present and valid.
@@ -90,6 +127,17 @@ func TenantIDFromContext(ctx context.Context) (string, bool) {
 	return "", false
 }
 
+// HasStepUp reports whether the request was made with a step-up token.
+func HasStepUp(ctx context.Context) bool {
+	c, ok := ctx.Value(authKey).(*Claims)
+	return ok && c.StepUp && c.TokenID == ""
+}
+
+// ContextWithClaims returns ctx authenticated as c.
+func ContextWithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, authKey, c)
+}
+
 // ClaimsFromContext returns the auth claims if present.
 func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
 	c, ok := ctx.Value(authKey).(*Claims)
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+const devSecret = "synap-dev-secret"
+
+// signingKey is one asymmetric JWT key. Keys loaded from a public key PEM have no private half
+// and only verify tokens signed before a rotation.
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	priv   crypto.Signer
+	pub    crypto.PublicKey
+	jwk    map[string]string
+}
+
+// keyRing holds the configured JWT keys. keys[0] signs new tokens when present; secret verifies
+// HS256 tokens without a kid and signs them only when no asymmetric key is configured.
+type keyRing struct {
+	keys   []*signingKey
+	secret []byte
+}
+
+var ring struct {
+	once sync.Once
+	keys *keyRing
+	err  error
+}
+
+func keys() (*keyRing, error) {
+	ring.once.Do(func() { ring.keys, ring.err = loadKeyRing() })
+	return ring.keys, ring.err
+}
+
+// ProductionMode reports whether SYNAP_ENV is set to production.
+func ProductionMode() bool {
+	env := strings.ToLower(strings.TrimSpace(os.Getenv("SYNAP_ENV")))
+	return env == "production" || env == "prod"
+}
+
+// LoadKeys reads the JWT keys from SYNAP_JWT_KEYS and SYNAP_JWT_SECRET. It fails on unreadable
+// keys and, in production mode, when tokens would be signed with the built-in development secret.
+func LoadKeys() error {
+	_, err := keys()
+	return err
+}
+
+func loadKeyRing() (*keyRing, error) {
+	kr := &keyRing{}
+	for _, path := range strings.Split(os.Getenv("SYNAP_JWT_KEYS"), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		k, err := readSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range kr.keys {
+			if other.kid == k.kid {
+				return nil, fmt.Errorf("%s: duplicate key %s", path, k.kid)
+			}
+		}
+		kr.keys = append(kr.keys, k)
+	}
+	if len(kr.keys) > 0 && kr.keys[0].priv == nil {
+		return nil, errors.New("SYNAP_JWT_KEYS: the first key signs tokens and must be a private key")
+	}
+	s := os.Getenv("SYNAP_JWT_SECRET")
+	if ProductionMode() && (s == devSecret || (s == "" && len(kr.keys) == 0)) {
+		return nil, errors.New("refusing to run in production with the default JWT secret; set SYNAP_JWT_KEYS or SYNAP_JWT_SECRET")
+	}
+	if s == "" && len(kr.keys) == 0 {
+		s = devSecret
+	}
+	if s != "" {
+		kr.secret = []byte(s)
+	}
+	return kr, nil
+}
+
+// readSigningKey parses a PEM file holding an Ed25519 or P-256 key, either a private key
+// (PKCS#8, or SEC 1 for EC) or a public key (PKIX).
+func readSigningKey(path string) (*signingKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	k := &signingKey{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		k.priv, parsed = signer, signer.Public()
+	}
+	switch pub := parsed.(type) {
+	case ed25519.PublicKey:
+		k.method, k.pub = jwt.SigningMethodEdDSA, pub
+		k.jwk = map[string]string{"crv": "Ed25519", "kty": "OKP", "x": b64(pub)}
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s: only P-256 EC keys are supported", path)
+		}
+		k.method, k.pub = jwt.SigningMethodES256, pub
+		k.jwk = map[string]string{"crv": "P-256", "kty": "EC", "x": b64(pub.X.FillBytes(make([]byte, 32))), "y": b64(pub.Y.FillBytes(make([]byte, 32)))}
+	default:
+		return nil, fmt.Errorf("%s: only Ed25519 and P-256 keys are supported", path)
+	}
+	// The kid is the RFC 7638 thumbprint, so it never has to be configured and stays stable
+	// across restarts and instances. json.Marshal sorts the required members as the RFC asks.
+	canonical, _ := json.Marshal(k.jwk)
+	sum := sha256.Sum256(canonical)
+	k.kid = b64(sum[:])
+	return k, nil
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func (kr *keyRing) sign(claims Claims) (string, error) {
+	if len(kr.keys) == 0 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(kr.secret)
+	}
+	k := kr.keys[0]
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.kid
+	if ec, ok := k.priv.(*ecdsa.PrivateKey); ok {
+		return token.SignedString(ec)
+	}
+	return token.SignedString(k.priv)
+}
+
+// keyFunc picks the verification key by kid and pins the algorithm to the key type, so a token
+// cannot switch a public key into an HMAC secret.
+func (kr *keyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if kr.secret == nil || token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, errors.New("unknown signing key")
+		}
+		return kr.secret, nil
+	}
+	for _, k := range kr.keys {
+		if k.kid == kid {
+			if token.Method.Alg() != k.method.Alg() {
+				return nil, errors.New("signing method does not match key")
+			}
+			return k.pub, nil
+		}
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// JWKS serves the public verification keys as a JSON Web Key Set. Retired keys stay listed until
+// they are removed from SYNAP_JWT_KEYS; the HS256 secret is never published.
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	kr, err := keys()
+	if err != nil {
+		http.Error(w, "signing keys unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	set := make([]map[string]string, 0, len(kr.keys))
+	for _, k := range kr.keys {
+		jwk := map[string]string{"kid": k.kid, "use": "sig", "alg": k.method.Alg()}
+		for name, v := range k.jwk {
+			jwk[name] = v
+		}
+		set = append(set, jwk)
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"keys": set})
+}
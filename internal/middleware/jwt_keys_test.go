@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// useKeys points the key ring at the given environment and reloads it; the ring is reset again
+// when the test ends.
+func useKeys(t *testing.T, env map[string]string) error {
+	t.Helper()
+	for _, name := range []string{"SYNAP_ENV", "SYNAP_JWT_KEYS", "SYNAP_JWT_SECRET"} {
+		t.Setenv(name, env[name])
+	}
+	resetRing := func() {
+		ring.once = sync.Once{}
+		ring.keys, ring.err = nil, nil
+	}
+	resetRing()
+	t.Cleanup(resetRing)
+	return LoadKeys()
+}
+
+func writePEM(t *testing.T, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), strings.ReplaceAll(strings.ToLower(typ), " ", "_")+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newEd25519Files writes an Ed25519 key pair and returns the private and public PEM paths.
+func newEd25519Files(t *testing.T) (string, string, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+	return writePEM(t, "PRIVATE KEY", privDER), writePEM(t, "PUBLIC KEY", pubDER), priv
+}
+
+func newP256File(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalECPrivateKey(key)
+	return writePEM(t, "EC PRIVATE KEY", der)
+}
+
+func tokenHeader(t *testing.T, tok string) map[string]any {
+	t.Helper()
+	p, _, err := jwt.NewParser().ParseUnverified(tok, &Claims{})
+	if err != nil {
+		t.Fatalf("parse %q: %v", tok, err)
+	}
+	return p.Header
+}
+
+func testClaims() Claims {
+	now := time.Now()
+	return Claims{UID: "u1", TID: "t1", Email: "a@b", RegisteredClaims: jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(now), ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}}
+}
+
+func TestRetiredKeyStillVerifies(t *testing.T) {
+	oldPriv, oldPub, _ := newEd25519Files(t)
+	if err := useKeys(t, map[string]string{"SYNAP_JWT_KEYS": oldPriv}); err != nil {
+		t.Fatal(err)
+	}
+	old, err := SignToken("u1", "t1", "a@b", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKid := tokenHeader(t, old)["kid"]
+
+	// rotate: a new P-256 key signs, the old one is only published for verification
+	newPriv := newP256File(t)
+	if err := useKeys(t, map[string]string{"SYNAP_JWT_KEYS": newPriv + "," + oldPub}); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := parseToken(old); err != nil || c.UID != "u1" {
+		t.Fatalf("token of the retired key: %+v %v", c, err)
+	}
+	fresh, err := SignToken("u2", "t1", "b@c", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := tokenHeader(t, fresh); h["alg"] != "ES256" || h["kid"] == oldKid || h["kid"] == "" {
+		t.Fatalf("new tokens must be signed by the new key: %v", h)
+	}
+	if _, err := parseToken(fresh); err != nil {
+		t.Fatalf("token of the new key: %v", err)
+	}
+
+	// once the old key is dropped its tokens stop verifying
+	if err := useKeys(t, map[string]string{"SYNAP_JWT_KEYS": newPriv}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseToken(old); err == nil {
+		t.Fatal("token of a removed key verified")
+	}
+
+	// a public key cannot sign
+	if err := useKeys(t, map[string]string{"SYNAP_JWT_KEYS": oldPub + "," + newPriv}); err == nil {
+		t.Fatal("public key accepted as the signing key")
+	}
+}
+
+func TestForeignAlgorithmsForKnownKidAreRejected(t *testing.T) {
+	privPath, pubPath, priv := newEd25519Files(t)
+	if err := useKeys(t, map[string]string{"SYNAP_JWT_KEYS": privPath, "SYNAP_JWT_SECRET": "legacy-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	kr, _ := keys()
+	kid := kr.keys[0].kid
+	pubPEM, _ := os.ReadFile(pubPath)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	sign := func(method jwt.SigningMethod, key any) string {
+		t.Helper()
+		tok := jwt.NewWithClaims(method, testClaims())
+		tok.Header["kid"] = kid
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatalf("sign %s: %v", method.Alg(), err)
+		}
+		return s
+	}
+	forged := map[string]string{
+		"none":                      sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+		"HS256 with the secret":     sign(jwt.SigningMethodHS256, []byte("legacy-secret")),
+		"HS256 with the public key": sign(jwt.SigningMethodHS256, pubPEM),
+		"HS256 with raw key bytes":  sign(jwt.SigningMethodHS256, []byte(priv.Public().(ed25519.PublicKey))),
+		"ES256 with another key":    sign(jwt.SigningMethodES256, ecKey),
+	}
+	for name, tok := range forged {
+		if _, err := parseToken(tok); err == nil {
+			t.Fatalf("%s: accepted a token for EdDSA kid %s", name, kid)
+		}
+	}
+
+	// the genuine key verifies, and legacy HS256 tokens without a kid still do
+	if _, err := parseToken(sign(jwt.SigningMethodEdDSA, priv)); err != nil {
+		t.Fatalf("EdDSA token: %v", err)
+	}
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("legacy-secret"))
+	if _, err := parseToken(legacy); err != nil {
+		t.Fatalf("legacy HS256 token: %v", err)
+	}
+	unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	unknown.Header["kid"] = "not-a-key"
+	s, _ := unknown.SignedString(priv)
+	if _, err := parseToken(s); err == nil {
+		t.Fatal("accepted an unknown kid")
+	}
+}
+
+func TestJWKSIsStable(t *testing.T) {
+	edPriv, edPub, priv := newEd25519Files(t)
+	ecPriv := newP256File(t)
+	env := map[string]string{"SYNAP_JWT_KEYS": edPriv + "," + ecPriv, "SYNAP_JWT_SECRET": "never-published"}
+	fetch := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/jwk-set+json" {
+			t.Fatalf("JWKS: %d %v", rec.Code, rec.Header())
+		}
+		return rec.Body.String()
+	}
+	if err := useKeys(t, env); err != nil {
+		t.Fatal(err)
+	}
+	first := fetch()
+	if fetch() != first {
+		t.Fatal("JWKS changed between requests")
+	}
+	// a restart, or a public-only copy of the key on another instance, publishes the same kid
+	if err := useKeys(t, env); err != nil {
+		t.Fatal(err)
+	}
+	if fetch() != first {
+		t.Fatal("JWKS changed across restarts")
+	}
+
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(first), &set); err != nil || len(set.Keys) != 2 {
+		t.Fatalf("JWKS body %s: %v", first, err)
+	}
+	ed := set.Keys[0]
+	canonical, _ := json.Marshal(map[string]string{"crv": "Ed25519", "kty": "OKP", "x": b64(priv.Public().(ed25519.PublicKey))})
+	sum := sha256.Sum256(canonical)
+	if ed["kid"] != b64(sum[:]) || ed["alg"] != "EdDSA" || ed["use"] != "sig" {
+		t.Fatalf("Ed25519 JWK %v is not keyed by its RFC 7638 thumbprint", ed)
+	}
+	if ec := set.Keys[1]; ec["alg"] != "ES256" || ec["crv"] != "P-256" || ec["x"] == "" || ec["y"] == "" {
+		t.Fatalf("P-256 JWK %v", ec)
+	}
+	for _, k := range set.Keys {
+		if _, ok := k["d"]; ok {
+			t.Fatalf("private key material published: %v", k)
+		}
+	}
+	if strings.Contains(first, "never-published") {
+		t.Fatal("HS256 secret published")
+	}
+	if err := useKeys(t, map[string]string{"SYNAP_JWT_KEYS": ecPriv + "," + edPub}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(fetch(), `"kid":"`+ed["kid"]+`"`) {
+		t.Fatal("public-only key published under another kid")
+	}
+}
+
+func TestProductionRequiresConfiguredKeys(t *testing.T) {
+	privPath, _, _ := newEd25519Files(t)
+	cases := []struct {
+		name string
+		env  map[string]string
+		ok   bool
+	}{
+		{"production without keys", map[string]string{"SYNAP_ENV": "production"}, false},
+		{"prod alias without keys", map[string]string{"SYNAP_ENV": " Prod "}, false},
+		{"production with the dev secret", map[string]string{"SYNAP_ENV": "production", "SYNAP_JWT_SECRET": devSecret}, false},
+		{"production with keys and the dev secret", map[string]string{"SYNAP_ENV": "production", "SYNAP_JWT_KEYS": privPath, "SYNAP_JWT_SECRET": devSecret}, false},
+		{"production with keys", map[string]string{"SYNAP_ENV": "production", "SYNAP_JWT_KEYS": privPath}, true},
+		{"production with a secret", map[string]string{"SYNAP_ENV": "production", "SYNAP_JWT_SECRET": "a-real-secret"}, true},
+		{"development falls back to the dev secret", map[string]string{}, true},
+		{"unreadable key", map[string]string{"SYNAP_JWT_KEYS": filepath.Join(t.TempDir(), "missing.pem")}, false},
+	}
+	for _, tc := range cases {
+		err := useKeys(t, tc.env)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%s: LoadKeys = %v", tc.name, err)
+		}
+		if !tc.ok {
+			if _, err := SignToken("u1", "t1", "a@b", time.Hour); err == nil {
+				t.Fatalf("%s: signed a token after LoadKeys failed", tc.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most Limit requests per client IP within a sliding window. State is kept in
+// memory, so every server instance counts on its own.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	mu     sync.Mutex
+	hits   map[string][]time.Time
+	swept  time.Time
+	now    func() time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, hits: map[string][]time.Time{}, now: time.Now}
+}
+
+// Allow records a request for key. When the limit is reached it returns false and how long the
+// caller has to wait.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.swept) > l.window {
+		for k, ts := range l.hits {
+			if len(ts) == 0 || now.Sub(ts[len(ts)-1]) >= l.window {
+				delete(l.hits, k)
+			}
+		}
+		l.swept = now
+	}
+	ts := l.hits[key]
+	i := 0
+	for i < len(ts) && now.Sub(ts[i]) >= l.window {
+		i++
+	}
+	ts = ts[i:]
+	if len(ts) >= l.limit {
+		l.hits[key] = ts
+		return false, ts[0].Add(l.window).Sub(now)
+	}
+	l.hits[key] = append(ts, now)
+	return true, 0
+}
+
+// Limit rejects requests over the limit with 429 and a Retry-After header.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.Allow(ClientIP(r)); !ok {
+			WriteTooManyRequests(w, wait, "too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteTooManyRequests answers 429 with Retry-After rounded up to whole seconds.
+func WriteTooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	secs := int((wait + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": msg, "retry_after": secs})
+}
+
+var (
+	trustedOnce    sync.Once
+	trustedProxies []*net.IPNet
+)
+
+// loadTrustedProxies reads SYNAP_TRUSTED_PROXIES (comma-separated CIDRs or IPs, "none" to trust no
+// proxy). The default trusts loopback and private networks, where Caddy or nginx normally run.
+func loadTrustedProxies() {
+	spec := strings.TrimSpace(os.Getenv("SYNAP_TRUSTED_PROXIES"))
+	if spec == "" {
+		spec = "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"
+	}
+	if strings.EqualFold(spec, "none") {
+		return
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			if ip := net.ParseIP(part); ip != nil && ip.To4() != nil {
+				part += "/32"
+			} else {
+				part += "/128"
+			}
+		}
+		if _, n, err := net.ParseCIDR(part); err == nil {
+			trustedProxies = append(trustedProxies, n)
+		}
+	}
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	trustedOnce.Do(loadTrustedProxies)
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only honoured when the connection
+// comes from a trusted proxy; the rightmost untrusted hop is the client, so prepended values
+// cannot be used to spoof another address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isTrustedProxy(ip) {
+		return host
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		if !isTrustedProxy(hop) || i == 0 {
+			return hop.String()
+		}
+	}
+	if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil {
+		return real.String()
+	}
+	return host
+}
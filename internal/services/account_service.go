@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	EmailTokenReset  = "password_reset"
+	EmailTokenVerify = "email_verify"
+
+	// EmailTokenPrefix marks password reset and verification tokens.
+	EmailTokenPrefix = "synap_et_"
+
+	resetTokenTTL    = time.Hour
+	verifyTokenTTL   = 48 * time.Hour
+	emailResendDelay = time.Minute
+)
+
+// AccountStore persists single-use email tokens (stored as SHA-256 hashes) and account changes.
+type AccountStore interface {
+	FindUserByEmail(email string) (*User, error)
+	SetUserPassword(userID string, hash []byte) error
+	SetEmailVerified(userID string, at time.Time) error
+	SaveEmailToken(t *EmailToken) error
+	GetEmailTokenByHash(hash string) (*EmailToken, error)
+	ListEmailTokens(userID, purpose string) ([]*EmailToken, error)
+	AddAudit(entry AuditEntry)
+}
+
+// EmailToken is a password reset or email verification link. Issuing a new token of the same
+// purpose invalidates the older ones.
+type EmailToken struct {
+	ID        string
+	UserID    string
+	Email     string
+	Purpose   string
+	Hash      string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
+
+// AccountService handles password resets and email verification over a Mailer.
+type AccountService struct {
+	store    AccountStore
+	mailer   Mailer
+	sessions *SessionService
+	now      func() time.Time
+	newToken func() string
+}
+
+func NewAccountService(store AccountStore, mailer Mailer) *AccountService {
+	return &AccountService{
+		store:    store,
+		mailer:   mailer,
+		now:      time.Now,
+		newToken: func() string { return EmailTokenPrefix + randomBytes(32) },
+	}
+}
+
+// WithSessions signs the user out everywhere after a password reset.
+func (s *AccountService) WithSessions(sessions *SessionService) {
+	s.sessions = sessions
+}
+
+// RequestPasswordReset mails a reset link to the address if it belongs to an account. It reports
+// success either way so that accounts cannot be discovered through it.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email, baseURL string) error {
+	email = strings.TrimSpace(email)
+	if !isValidEmail(email) {
+		return NewInvalidError("invalid email format")
+	}
+	u, err := s.store.FindUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return nil
+	}
+	token, err := s.issue(u, EmailTokenReset, resetTokenTTL)
+	if err != nil || token == "" {
+		return err
+	}
+	link := baseURL + "/auth/reset?token=" + url.QueryEscape(token)
+	text := fmt.Sprintf("Someone asked to reset the password of your Synap account %s.\n\nOpen this link within %d minutes to choose a new password:\n%s\n\nIf this wasn't you, ignore this message; your password stays unchanged.\n",
+		u.Email, int(resetTokenTTL.Minutes()), link)
+	if err := s.mailer.Send(ctx, Mail{To: u.Email, Subject: "Reset your Synap password", Text: text}); err != nil {
+		// not surfaced: the answer must not differ between known and unknown addresses
+		log.Printf("password reset mail to %s: %v", u.Email, err)
+		return nil
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: u.TenantID, Actor: u.Email, ActorID: u.ID, Action: "password_reset_request", Target: u.ID})
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and ends all sessions of the user. Using the
+// link also proves ownership of the address.
+func (s *AccountService) ResetPassword(token, password string) error {
+	if !isStrongPassword(password) {
+		return NewInvalidError("weak password")
+	}
+	t, u, err := s.consume(token, EmailTokenReset)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetUserPassword(u.ID, hash); err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	if u.EmailVerifiedAt.IsZero() {
+		if err := s.store.SetEmailVerified(u.ID, now); err != nil {
+			return err
+		}
+	}
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeAll(u.ID, ""); err != nil {
+			return err
+		}
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: u.TenantID, Actor: t.Email, ActorID: u.ID, Action: "password_reset", Target: u.ID})
+	return nil
+}
+
+// SendVerification mails a verification link unless the address is already verified.
+func (s *AccountService) SendVerification(ctx context.Context, email, baseURL string) error {
+	u, err := s.store.FindUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return NewNotFoundError("user not found")
+	}
+	if !u.EmailVerifiedAt.IsZero() {
+		return NewConflictError("email already verified")
+	}
+	token, err := s.issue(u, EmailTokenVerify, verifyTokenTTL)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return NewTooManyRequestsError("verification mail sent recently; try again in a minute")
+	}
+	link := baseURL + "/auth/verify?token=" + url.QueryEscape(token)
+	text := fmt.Sprintf("Please confirm that %s is your email address by opening this link within %d hours:\n%s\n\nExports and invitations are available once the address is confirmed.\n",
+		u.Email, int(verifyTokenTTL.Hours()), link)
+	if err := s.mailer.Send(ctx, Mail{To: u.Email, Subject: "Confirm your email for Synap", Text: text}); err != nil {
+		log.Printf("verification mail to %s: %v", u.Email, err)
+		return NewBadGatewayError("unable to send mail")
+	}
+	return nil
+}
+
+// VerifyEmail marks the address of the token's account as verified.
+func (s *AccountService) VerifyEmail(token string) error {
+	t, u, err := s.consume(token, EmailTokenVerify)
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	if err := s.store.SetEmailVerified(u.ID, now); err != nil {
+		return err
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: u.TenantID, Actor: t.Email, ActorID: u.ID, Action: "email_verified", Target: u.ID})
+	return nil
+}
+
+// IsVerified reports whether the user behind email has confirmed the address.
+func (s *AccountService) IsVerified(email string) (bool, error) {
+	u, err := s.store.FindUserByEmail(email)
+	if err != nil || u == nil {
+		return false, err
+	}
+	return !u.EmailVerifiedAt.IsZero(), nil
+}
+
+// issue creates a token of purpose and invalidates older ones. It returns "" when the last token
+// was issued less than emailResendDelay ago.
+func (s *AccountService) issue(u *User, purpose string, ttl time.Duration) (string, error) {
+	now := s.now().UTC()
+	prev, err := s.store.ListEmailTokens(u.ID, purpose)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range prev {
+		if t.UsedAt.IsZero() && now.Sub(t.CreatedAt) < emailResendDelay {
+			return "", nil
+		}
+	}
+	for _, t := range prev {
+		if t.UsedAt.IsZero() && now.Before(t.ExpiresAt) {
+			t.UsedAt = now
+			if err := s.store.SaveEmailToken(t); err != nil {
+				return "", err
+			}
+		}
+	}
+	token := s.newToken()
+	t := &EmailToken{ID: "et" + randomID(10), UserID: u.ID, Email: u.Email, Purpose: purpose, Hash: HashAPIToken(token), CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	if err := s.store.SaveEmailToken(t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AccountService) consume(token, purpose string) (*EmailToken, *User, error) {
+	token = strings.TrimSpace(token)
+	invalid := NewInvalidError("invalid or expired link")
+	if !strings.HasPrefix(token, EmailTokenPrefix) {
+		return nil, nil, invalid
+	}
+	t, err := s.store.GetEmailTokenByHash(HashAPIToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.now().UTC()
+	if t == nil || t.Purpose != purpose || !t.UsedAt.IsZero() || !now.Before(t.ExpiresAt) {
+		return nil, nil, invalid
+	}
+	u, err := s.store.FindUserByEmail(t.Email)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u == nil || u.ID != t.UserID {
+		return nil, nil, invalid
+	}
+	t.UsedAt = now
+	if err := s.store.SaveEmailToken(t); err != nil {
+		return nil, nil, err
+	}
+	return t, u, nil
+}
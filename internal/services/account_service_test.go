@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type accountStubStore struct {
+	*authStubStore
+	tokens map[string]*EmailToken
+	audit  []AuditEntry
+}
+
+func (s *accountStubStore) SetUserPassword(userID string, hash []byte) error {
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.PassHash = hash
+			return nil
+		}
+	}
+	return NewNotFoundError("user not found")
+}
+
+func (s *accountStubStore) SetEmailVerified(userID string, at time.Time) error {
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.EmailVerifiedAt = at
+			return nil
+		}
+	}
+	return NewNotFoundError("user not found")
+}
+
+func (s *accountStubStore) SaveEmailToken(t *EmailToken) error {
+	cp := *t
+	s.tokens[t.ID] = &cp
+	return nil
+}
+
+func (s *accountStubStore) GetEmailTokenByHash(hash string) (*EmailToken, error) {
+	for _, t := range s.tokens {
+		if t.Hash == hash {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *accountStubStore) ListEmailTokens(userID, purpose string) ([]*EmailToken, error) {
+	out := []*EmailToken{}
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			cp := *t
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *accountStubStore) AddAudit(entry AuditEntry) { s.audit = append(s.audit, entry) }
+
+type captureMailer struct {
+	sent []Mail
+	err  error
+}
+
+func (m *captureMailer) Send(_ context.Context, msg Mail) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// linkToken extracts the token from the link in the last mail.
+func (m *captureMailer) linkToken(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatalf("no mail sent")
+	}
+	text := m.sent[len(m.sent)-1].Text
+	i := strings.Index(text, "token=")
+	if i < 0 {
+		t.Fatalf("no link in mail: %q", text)
+	}
+	raw := strings.Fields(text[i+len("token="):])[0]
+	token, err := url.QueryUnescape(raw)
+	if err != nil {
+		t.Fatalf("bad token %q: %v", raw, err)
+	}
+	return token
+}
+
+func newTestAccountService() (*AccountService, *accountStubStore, *captureMailer, *time.Time) {
+	store := &accountStubStore{authStubStore: newAuthStubStore(), tokens: map[string]*EmailToken{}}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Secret123"), bcrypt.MinCost)
+	store.users["user@example.com"] = &User{ID: "U1", Email: "user@example.com", PassHash: hash, TenantID: "T1"}
+	mailer := &captureMailer{}
+	svc := NewAccountService(store, mailer)
+	now := time.Date(2025, 9, 24, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	return svc, store, mailer, &now
+}
+
+func TestAccountPasswordReset(t *testing.T) {
+	svc, store, mailer, now := newTestAccountService()
+	sessions, _, _ := newTestSessionService()
+	svc.WithSessions(sessions)
+	a, _ := sessions.Start("U1", "T1", "user@example.com", SessionClient{})
+
+	if err := svc.RequestPasswordReset(context.Background(), "user@example.com", "https://synap.test"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if !strings.Contains(mailer.sent[0].Text, "https://synap.test/auth/reset?token=") {
+		t.Fatalf("unexpected mail: %q", mailer.sent[0].Text)
+	}
+	token := mailer.linkToken(t)
+	for _, tok := range store.tokens {
+		if tok.Hash == token || strings.Contains(tok.Hash, EmailTokenPrefix) {
+			t.Fatalf("token must be stored hashed")
+		}
+	}
+
+	if err := svc.ResetPassword(token, "weak"); err == nil {
+		t.Fatalf("weak password must be rejected")
+	}
+	if err := svc.ResetPassword(token, "NewSecret456"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	u := store.users["user@example.com"]
+	if bcrypt.CompareHashAndPassword(u.PassHash, []byte("NewSecret456")) != nil {
+		t.Fatalf("password not changed")
+	}
+	if u.EmailVerifiedAt.IsZero() {
+		t.Fatalf("reset link must verify the address")
+	}
+	if !sessions.IsRevoked(a.SessionID) {
+		t.Fatalf("reset must end existing sessions")
+	}
+	if err := svc.ResetPassword(token, "Another789"); err == nil {
+		t.Fatalf("reset token must be single use")
+	}
+
+	*now = now.Add(2 * time.Minute)
+	_ = svc.RequestPasswordReset(context.Background(), "user@example.com", "https://synap.test")
+	expired := mailer.linkToken(t)
+	*now = now.Add(resetTokenTTL + time.Second)
+	if err := svc.ResetPassword(expired, "Another789"); err == nil {
+		t.Fatalf("expired token must be rejected")
+	}
+}
+
+func TestAccountResetDoesNotRevealAccounts(t *testing.T) {
+	svc, _, mailer, _ := newTestAccountService()
+	if err := svc.RequestPasswordReset(context.Background(), "nobody@example.com", "https://synap.test"); err != nil {
+		t.Fatalf("unknown address must not fail: %v", err)
+	}
+	if len(mailer.sent) != 0 {
+		t.Fatalf("no mail for unknown address")
+	}
+	mailer.err = errors.New("smtp down")
+	if err := svc.RequestPasswordReset(context.Background(), "user@example.com", "https://synap.test"); err != nil {
+		t.Fatalf("mail failure must not be surfaced: %v", err)
+	}
+	if err := svc.RequestPasswordReset(context.Background(), "not-an-email", "https://synap.test"); err == nil {
+		t.Fatalf("invalid address must be rejected")
+	}
+}
+
+func TestAccountResetThrottleAndInvalidate(t *testing.T) {
+	svc, _, mailer, now := newTestAccountService()
+	ctx := context.Background()
+	_ = svc.RequestPasswordReset(ctx, "user@example.com", "")
+	first := mailer.linkToken(t)
+	_ = svc.RequestPasswordReset(ctx, "user@example.com", "")
+	if len(mailer.sent) != 1 {
+		t.Fatalf("second request within a minute must not send mail: %d", len(mailer.sent))
+	}
+	*now = now.Add(emailResendDelay)
+	_ = svc.RequestPasswordReset(ctx, "user@example.com", "")
+	second := mailer.linkToken(t)
+	if first == second {
+		t.Fatalf("expected a new token")
+	}
+	if err := svc.ResetPassword(first, "NewSecret456"); err == nil {
+		t.Fatalf("older token must be invalidated by a newer one")
+	}
+	if err := svc.ResetPassword(second, "NewSecret456"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+}
+
+func TestAccountVerifyEmail(t *testing.T) {
+	svc, store, mailer, now := newTestAccountService()
+	ctx := context.Background()
+	if ok, _ := svc.IsVerified("user@example.com"); ok {
+		t.Fatalf("new user must not be verified")
+	}
+	if err := svc.SendVerification(ctx, "user@example.com", "https://synap.test"); err != nil {
+		t.Fatalf("SendVerification: %v", err)
+	}
+	var se *ServiceError
+	if err := svc.SendVerification(ctx, "user@example.com", "https://synap.test"); !errors.As(err, &se) || se.Code != ErrorTooManyRequests {
+		t.Fatalf("resend within a minute = %v", err)
+	}
+	token := mailer.linkToken(t)
+	if err := svc.ResetPassword(token, "NewSecret456"); err == nil {
+		t.Fatalf("verification token must not reset the password")
+	}
+	if err := svc.VerifyEmail(token); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if ok, _ := svc.IsVerified("user@example.com"); !ok {
+		t.Fatalf("user must be verified")
+	}
+	if got := store.users["user@example.com"].EmailVerifiedAt; !got.Equal(*now) {
+		t.Fatalf("verified at = %v", got)
+	}
+	*now = now.Add(time.Hour)
+	if err := svc.SendVerification(ctx, "user@example.com", "https://synap.test"); !errors.As(err, &se) || se.Code != ErrorConflict {
+		t.Fatalf("verified address = %v", err)
+	}
+	if len(store.audit) == 0 || store.audit[len(store.audit)-1].Action != "email_verified" {
+		t.Fatalf("missing audit entry: %+v", store.audit)
+	}
+}
@@ -1,8 +1,15 @@
 package services
 
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
 type AIConfigStore interface {
 	GetAIConfig(tenantID string) (*TenantAIConfig, error)
 	UpsertAIConfig(cfg *TenantAIConfig) error
+	AddAudit(entry AuditEntry)
 }
 
 type AIConfigService struct{ store AIConfigStore }
@@ -20,9 +27,27 @@ func (s *AIConfigService) Get(tenantID string) (*TenantAIConfig, error) {
 	return cfg, nil
 }
 
-func (s *AIConfigService) Update(in *TenantAIConfig) error {
+// Update saves the tenant's AI config and audits which settings changed; the key itself is never logged.
+func (s *AIConfigService) Update(in *TenantAIConfig, actor, actorID string, client SessionClient) error {
 	if in == nil || in.TenantID == "" {
 		return NewInvalidError("tenant_id required")
 	}
-	return s.store.UpsertAIConfig(in)
+	cur, err := s.Get(in.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.UpsertAIConfig(in); err != nil {
+		return err
+	}
+	changed := []string{}
+	for name, diff := range map[string]bool{"openai_key": in.OpenAIKey != cur.OpenAIKey, "openai_base": in.OpenAIBase != cur.OpenAIBase,
+		"allow_external": in.AllowExternal != cur.AllowExternal, "store_logs": in.StoreLogs != cur.StoreLogs} {
+		if diff {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	s.store.AddAudit(AuditEntry{Time: time.Now().UTC(), TenantID: in.TenantID, Actor: actor, ActorID: actorID, Action: "ai_config_update",
+		Target: in.TenantID, Note: strings.Join(changed, " "), IP: client.IP, UserAgent: client.UserAgent, Result: AuditResultSuccess})
+	return nil
 }
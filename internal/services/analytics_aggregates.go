@@ -0,0 +1,505 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AnalyticsAggregateStore persists the running analytics aggregates of each scale and reads the
+// responses they summarise.
+type AnalyticsAggregateStore interface {
+	GetScale(id string) (*Scale, error)
+	GetItem(id string) (*Item, error)
+	ListItems(scaleID string) ([]*Item, error)
+	ListResponsesByScale(scaleID string) ([]*Response, error)
+	ListResponsesByParticipant(id string) ([]*Response, error)
+	GetAnalyticsAggregate(scaleID string) (*AnalyticsAggregate, error)
+	SaveAnalyticsAggregate(a *AnalyticsAggregate) error
+	DeleteAnalyticsAggregate(scaleID string) error
+	ListAnalyticsAggregateScaleIDs() ([]string, error)
+}
+
+// ItemAggregate counts the answers to one item. Histogram (Likert items only) counts every stored
+// response by score, like the summary histogram. N and Values describe each participant's latest
+// answer: scored values for numeric items, labels for choice items and lengths for text items.
+type ItemAggregate struct {
+	Histogram []int          `json:"histogram,omitempty"`
+	Total     int            `json:"total,omitempty"`
+	N         int            `json:"n"`
+	Values    map[string]int `json:"values,omitempty"`
+}
+
+// AnalyticsAggregate holds what the analytics summary needs as sums over participants, so one
+// participant's responses can be added or subtracted without rereading the rest of the scale. The
+// alpha block keeps the sufficient statistics (row count, column sums and cross-products) of the
+// complete Likert rows, columns in AlphaItems order. Fingerprint covers Points and the item IDs and
+// types; an aggregate built for a different item set is rebuilt instead of updated.
+type AnalyticsAggregate struct {
+	ScaleID       string                    `json:"scale_id"`
+	Fingerprint   string                    `json:"fingerprint"`
+	Points        int                       `json:"points"`
+	Responses     int                       `json:"responses"`
+	Participants  int                       `json:"participants"`
+	Items         map[string]*ItemAggregate `json:"items"`
+	Daily         map[string]int            `json:"daily,omitempty"`
+	AlphaItems    []string                  `json:"alpha_items"`
+	AlphaN        int                       `json:"alpha_n"`
+	Sums          []float64                 `json:"sums"`
+	CrossProducts [][]float64               `json:"cross_products"`
+	UpdatedAt     time.Time                 `json:"updated_at"`
+}
+
+// AggregateCheck reports one rebuild: whether an aggregate was stored and whether it matched the
+// aggregate recomputed from the responses.
+type AggregateCheck struct {
+	ScaleID      string `json:"scale_id"`
+	Stored       bool   `json:"stored"`
+	Consistent   bool   `json:"consistent"`
+	Responses    int    `json:"responses"`
+	Participants int    `json:"participants"`
+}
+
+// AnalyticsAggregates keeps the stored aggregates in step with response writes. Writes made through
+// Track and Reset are serialised with rebuilds so that no change is counted twice.
+type AnalyticsAggregates struct {
+	store AnalyticsAggregateStore
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+func NewAnalyticsAggregates(store AnalyticsAggregateStore) *AnalyticsAggregates {
+	return &AnalyticsAggregates{store: store, now: time.Now}
+}
+
+// Current returns the aggregate of sc, rebuilding it from the responses when none is stored or the
+// items changed since it was built.
+func (a *AnalyticsAggregates) Current(sc *Scale, items []*Item) (*AnalyticsAggregate, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	points := analyticsPoints(sc)
+	agg, err := a.store.GetAnalyticsAggregate(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	if agg != nil && agg.Fingerprint == aggregateFingerprint(points, items) {
+		return agg, nil
+	}
+	return a.rebuildLocked(sc.ID, points, items)
+}
+
+// Rebuild recomputes the aggregate of a scale from all of its responses, stores it and reports
+// whether the aggregate it replaces was consistent.
+func (a *AnalyticsAggregates) Rebuild(scaleID string) (*AggregateCheck, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sc, err := a.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, NewNotFoundError("scale not found")
+	}
+	items, err := a.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := a.store.GetAnalyticsAggregate(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	agg, err := a.rebuildLocked(scaleID, analyticsPoints(sc), items)
+	if err != nil {
+		return nil, err
+	}
+	return &AggregateCheck{
+		ScaleID:      scaleID,
+		Stored:       prev != nil,
+		Consistent:   prev != nil && sameAggregate(prev, agg),
+		Responses:    agg.Responses,
+		Participants: agg.Participants,
+	}, nil
+}
+
+// RebuildAll rebuilds every stored aggregate; aggregates of scales that no longer exist are dropped.
+func (a *AnalyticsAggregates) RebuildAll() ([]*AggregateCheck, error) {
+	ids, err := a.store.ListAnalyticsAggregateScaleIDs()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*AggregateCheck, 0, len(ids))
+	for _, id := range ids {
+		sc, err := a.store.GetScale(id)
+		if err != nil {
+			return nil, err
+		}
+		if sc == nil {
+			if err := a.store.DeleteAnalyticsAggregate(id); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		check, err := a.Rebuild(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, check)
+	}
+	return out, nil
+}
+
+// Track runs write, which changes the responses of one participant, and applies the difference to
+// the aggregates of the scales involved. Scales without a stored aggregate are left to be built on
+// first read. An aggregate that cannot be updated is dropped so the next read rebuilds it.
+// A nil receiver just runs write.
+func (a *AnalyticsAggregates) Track(participantID string, write func() error) error {
+	if a == nil {
+		return write()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	before, err := a.store.ListResponsesByParticipant(participantID)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	after, listErr := a.store.ListResponsesByParticipant(participantID)
+	if listErr != nil {
+		after = nil
+	}
+	changes, err := a.groupByScale(before, after)
+	if err != nil {
+		return err
+	}
+	for scaleID, change := range changes {
+		if listErr != nil || a.applyLocked(scaleID, change[0], change[1]) != nil {
+			if err := a.store.DeleteAnalyticsAggregate(scaleID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reset runs write, which removes every response of a scale, and stores an empty aggregate for it.
+// A nil receiver just runs write.
+func (a *AnalyticsAggregates) Reset(scaleID string, write func() error) error {
+	if a == nil {
+		return write()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := write(); err != nil {
+		return err
+	}
+	sc, err := a.store.GetScale(scaleID)
+	if err != nil || sc == nil {
+		return a.store.DeleteAnalyticsAggregate(scaleID)
+	}
+	items, err := a.store.ListItems(scaleID)
+	if err != nil {
+		return a.store.DeleteAnalyticsAggregate(scaleID)
+	}
+	agg := buildAnalyticsAggregate(scaleID, analyticsPoints(sc), items, nil)
+	agg.UpdatedAt = a.now().UTC()
+	if err := a.store.SaveAnalyticsAggregate(agg); err != nil {
+		return a.store.DeleteAnalyticsAggregate(scaleID)
+	}
+	return nil
+}
+
+func (a *AnalyticsAggregates) rebuildLocked(scaleID string, points int, items []*Item) (*AnalyticsAggregate, error) {
+	responses, err := a.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	agg := buildAnalyticsAggregate(scaleID, points, items, responses)
+	agg.UpdatedAt = a.now().UTC()
+	if err := a.store.SaveAnalyticsAggregate(agg); err != nil {
+		return nil, err
+	}
+	return agg, nil
+}
+
+// groupByScale splits a participant's responses before and after a write by the scale of each item.
+func (a *AnalyticsAggregates) groupByScale(before, after []*Response) (map[string][2][]*Response, error) {
+	scaleOf := map[string]string{}
+	out := map[string][2][]*Response{}
+	for side, rs := range [][]*Response{before, after} {
+		for _, r := range rs {
+			scaleID, ok := scaleOf[r.ItemID]
+			if !ok {
+				it, err := a.store.GetItem(r.ItemID)
+				if err != nil {
+					return nil, err
+				}
+				if it != nil {
+					scaleID = it.ScaleID
+				}
+				scaleOf[r.ItemID] = scaleID
+			}
+			if scaleID == "" {
+				continue
+			}
+			change := out[scaleID]
+			change[side] = append(change[side], r)
+			out[scaleID] = change
+		}
+	}
+	return out, nil
+}
+
+func (a *AnalyticsAggregates) applyLocked(scaleID string, before, after []*Response) error {
+	agg, err := a.store.GetAnalyticsAggregate(scaleID)
+	if err != nil || agg == nil {
+		return err
+	}
+	sc, err := a.store.GetScale(scaleID)
+	if err != nil {
+		return err
+	}
+	if sc == nil {
+		return a.store.DeleteAnalyticsAggregate(scaleID)
+	}
+	items, err := a.store.ListItems(scaleID)
+	if err != nil {
+		return err
+	}
+	if agg.Fingerprint != aggregateFingerprint(analyticsPoints(sc), items) {
+		return a.store.DeleteAnalyticsAggregate(scaleID)
+	}
+	agg.add(items, before, -1)
+	agg.add(items, after, 1)
+	agg.UpdatedAt = a.now().UTC()
+	return a.store.SaveAnalyticsAggregate(agg)
+}
+
+func analyticsPoints(sc *Scale) int {
+	if sc == nil || sc.Points <= 0 {
+		return 5
+	}
+	return sc.Points
+}
+
+func analyticsItemType(it *Item) string {
+	if it.Type == "" {
+		return "likert"
+	}
+	return it.Type
+}
+
+func aggregateFingerprint(points int, items []*Item) string {
+	parts := make([]string, 0, len(items))
+	for _, it := range items {
+		parts = append(parts, it.ID+":"+analyticsItemType(it))
+	}
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(strconv.Itoa(points) + "|" + strings.Join(parts, ";")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func buildAnalyticsAggregate(scaleID string, points int, items []*Item, responses []*Response) *AnalyticsAggregate {
+	agg := &AnalyticsAggregate{
+		ScaleID:     scaleID,
+		Fingerprint: aggregateFingerprint(points, items),
+		Points:      points,
+		Items:       make(map[string]*ItemAggregate, len(items)),
+		Daily:       map[string]int{},
+	}
+	for _, it := range items {
+		ia := &ItemAggregate{Values: map[string]int{}}
+		if analyticsItemType(it) == "likert" {
+			ia.Histogram = make([]int, points)
+			agg.AlphaItems = append(agg.AlphaItems, it.ID)
+		}
+		agg.Items[it.ID] = ia
+	}
+	sort.Strings(agg.AlphaItems)
+	k := len(agg.AlphaItems)
+	agg.Sums = make([]float64, k)
+	agg.CrossProducts = make([][]float64, k)
+	for j := range agg.CrossProducts {
+		agg.CrossProducts[j] = make([]float64, k)
+	}
+	agg.add(items, responses, 1)
+	return agg
+}
+
+// add folds the responses of any number of participants into the aggregate with the given sign:
+// +1 adds them, -1 removes a state that was added before. All counts are integers, so removal is exact.
+func (a *AnalyticsAggregate) add(items []*Item, responses []*Response, sign int) {
+	types := make(map[string]string, len(items))
+	for _, it := range items {
+		types[it.ID] = analyticsItemType(it)
+		if ia := a.Items[it.ID]; ia != nil && ia.Values == nil {
+			ia.Values = map[string]int{}
+		}
+	}
+	if a.Daily == nil {
+		a.Daily = map[string]int{}
+	}
+	byParticipant := map[string][]*Response{}
+	for _, r := range responses {
+		byParticipant[r.ParticipantID] = append(byParticipant[r.ParticipantID], r)
+	}
+	for _, rs := range byParticipant {
+		a.addParticipant(types, rs, sign)
+	}
+}
+
+func (a *AnalyticsAggregate) addParticipant(types map[string]string, rs []*Response, sign int) {
+	a.Participants += sign
+	a.Responses += sign * len(rs)
+	latest := map[string]*Response{}
+	for _, r := range rs {
+		bumpCount(a.Daily, r.SubmittedAt.UTC().Format("2006-01-02"), sign)
+		if ia := a.Items[r.ItemID]; ia != nil && ia.Histogram != nil {
+			if v := r.ScoreValue; v >= 1 && v <= a.Points {
+				ia.Histogram[v-1] += sign
+				ia.Total += sign
+			}
+		}
+		latest[r.ItemID] = r
+	}
+	for id, r := range latest {
+		ia := a.Items[id]
+		if ia == nil {
+			continue
+		}
+		switch itemType := types[id]; itemType {
+		case "likert", "rating", "slider", "numeric":
+			if v, ok := scoredNumericValue(itemType, r, a.Points); ok {
+				ia.N += sign
+				bumpCount(ia.Values, strconv.FormatFloat(v, 'g', -1, 64), sign)
+			}
+		case "single", "dropdown", "multiple":
+			if vals := decodeChoiceValues(r.RawJSON); len(vals) > 0 {
+				ia.N += sign
+				seen := map[string]bool{}
+				for _, v := range vals {
+					if !seen[v] {
+						bumpCount(ia.Values, v, sign)
+						seen[v] = true
+					}
+				}
+			}
+		case "short_text", "long_text":
+			if txt := decodeTextValue(r.RawJSON); txt != "" {
+				ia.N += sign
+				bumpCount(ia.Values, strconv.Itoa(utf8.RuneCountInString(txt)), sign)
+			}
+		default:
+			if decodeTextValue(r.RawJSON) != "" {
+				ia.N += sign
+			}
+		}
+	}
+	if len(a.AlphaItems) == 0 {
+		return
+	}
+	row := make([]float64, len(a.AlphaItems))
+	for j, id := range a.AlphaItems {
+		r, ok := latest[id]
+		if !ok {
+			return
+		}
+		row[j] = float64(r.ScoreValue)
+	}
+	s := float64(sign)
+	a.AlphaN += sign
+	for j, v := range row {
+		a.Sums[j] += s * v
+		for l, w := range row {
+			a.CrossProducts[j][l] += s * v * w
+		}
+	}
+}
+
+func bumpCount(m map[string]int, key string, sign int) {
+	if m[key] += sign; m[key] == 0 {
+		delete(m, key)
+	}
+}
+
+// histograms returns the Likert items of the summary in item order.
+func (a *AnalyticsAggregate) histograms(items []*Item) []AnalyticsItem {
+	out := make([]AnalyticsItem, 0, len(items))
+	for _, it := range filterLikertItems(items) {
+		ai := AnalyticsItem{ID: it.ID, StemI18n: it.StemI18n, Reverse: it.ReverseScored, Histogram: make([]int, a.Points)}
+		if ia := a.Items[it.ID]; ia != nil {
+			copy(ai.Histogram, ia.Histogram)
+			ai.Total = ia.Total
+		}
+		out = append(out, ai)
+	}
+	return out
+}
+
+// descriptives reports descriptive statistics for every item. Missing is counted against all
+// participants who answered at least one item of the scale.
+func (a *AnalyticsAggregate) descriptives(items []*Item) []ItemDescriptive {
+	out := make([]ItemDescriptive, 0, len(items))
+	for _, it := range items {
+		itemType := analyticsItemType(it)
+		d := ItemDescriptive{ID: it.ID, Type: itemType, StemI18n: it.StemI18n, Reverse: it.ReverseScored}
+		ia := a.Items[it.ID]
+		if ia == nil {
+			ia = &ItemAggregate{}
+		}
+		d.N = ia.N
+		switch itemType {
+		case "likert", "rating", "slider", "numeric":
+			d.Stats = Describe(expandCounts(ia.Values))
+		case "single", "dropdown", "multiple":
+			d.Frequencies = buildFrequencies(it, ia.Values, ia.N)
+		case "short_text", "long_text":
+			d.TextLength = Describe(expandCounts(ia.Values))
+		}
+		if d.Missing = a.Participants - d.N; d.Missing < 0 {
+			d.Missing = 0
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// reliability evaluates the reliability block from the alpha sufficient statistics.
+func (a *AnalyticsAggregate) reliability(seed int64) *Reliability {
+	return reliabilityFromMoments(a.AlphaN, a.Sums, a.CrossProducts, seed)
+}
+
+// expandCounts turns numeric value counts back into the list of values.
+func expandCounts(counts map[string]int) []float64 {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var out []float64
+	for _, k := range keys {
+		v, err := strconv.ParseFloat(k, 64)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < counts[k]; i++ {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sameAggregate(x, y *AnalyticsAggregate) bool {
+	cx, cy := *x, *y
+	cx.UpdatedAt, cy.UpdatedAt = time.Time{}, time.Time{}
+	bx, errX := json.Marshal(cx)
+	by, errY := json.Marshal(cy)
+	return errX == nil && errY == nil && string(bx) == string(by)
+}
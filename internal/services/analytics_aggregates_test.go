@@ -0,0 +1,234 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+type stubAggregateStore struct {
+	stubAnalyticsStore
+	stored map[string][]byte
+}
+
+func (s *stubAggregateStore) GetItem(id string) (*Item, error) {
+	for _, it := range s.items {
+		if it.ID == id {
+			cp := *it
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubAggregateStore) ListResponsesByParticipant(id string) ([]*Response, error) {
+	out := []*Response{}
+	for _, r := range s.responses {
+		if r.ParticipantID == id {
+			cp := *r
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubAggregateStore) GetAnalyticsAggregate(scaleID string) (*AnalyticsAggregate, error) {
+	b, ok := s.stored[scaleID]
+	if !ok {
+		return nil, nil
+	}
+	var agg AnalyticsAggregate
+	if err := json.Unmarshal(b, &agg); err != nil {
+		return nil, err
+	}
+	return &agg, nil
+}
+
+func (s *stubAggregateStore) SaveAnalyticsAggregate(a *AnalyticsAggregate) error {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	s.stored[a.ScaleID] = b
+	return nil
+}
+
+func (s *stubAggregateStore) DeleteAnalyticsAggregate(scaleID string) error {
+	delete(s.stored, scaleID)
+	return nil
+}
+
+func (s *stubAggregateStore) ListAnalyticsAggregateScaleIDs() ([]string, error) {
+	out := []string{}
+	for id := range s.stored {
+		out = append(out, id)
+	}
+	return out, nil
+}
+
+func (s *stubAggregateStore) removeParticipant(pid string) error {
+	kept := s.responses[:0]
+	for _, r := range s.responses {
+		if r.ParticipantID != pid {
+			kept = append(kept, r)
+		}
+	}
+	s.responses = kept
+	return nil
+}
+
+func newAggregateFixture() *stubAggregateStore {
+	day1 := time.Date(2025, 9, 18, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	store := &stubAggregateStore{
+		stubAnalyticsStore: stubAnalyticsStore{
+			scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
+			items: []*Item{
+				{ID: "L1", ScaleID: "S1"},
+				{ID: "L2", ScaleID: "S1", Type: "likert"},
+				{ID: "L3", ScaleID: "S1", Type: "likert", ReverseScored: true},
+				{ID: "N1", ScaleID: "S1", Type: "numeric"},
+				{ID: "C1", ScaleID: "S1", Type: "multiple", OptionsI18n: map[string][]string{"en": {"Red", "Blue"}}},
+				{ID: "X1", ScaleID: "S1", Type: "short_text"},
+			},
+		},
+		stored: map[string][]byte{},
+	}
+	add := func(pid string, at time.Time, l1, l2, l3, n1 int, color, text string) {
+		store.responses = append(store.responses, participantResponses(pid, at, l1, l2, l3, n1, color, text)...)
+	}
+	add("P1", day1, 4, 5, 4, 30, `["Red","Blue"]`, `"hi"`)
+	add("P2", day1, 2, 2, 3, 41, `["Blue"]`, `""`)
+	add("P3", day2, 5, 4, 5, 30, `["Green"]`, `"hello"`)
+	add("P4", day2, 3, 0, 2, 25, `["Red"]`, `"ok"`) // L2 left unanswered
+	return store
+}
+
+func participantResponses(pid string, at time.Time, l1, l2, l3, n1 int, color, text string) []*Response {
+	out := []*Response{}
+	for id, v := range map[string]int{"L1": l1, "L2": l2, "L3": l3} {
+		if v > 0 {
+			out = append(out, &Response{ParticipantID: pid, ItemID: id, RawValue: v, ScoreValue: v, RawJSON: itoa(v), SubmittedAt: at})
+		}
+	}
+	return append(out,
+		&Response{ParticipantID: pid, ItemID: "N1", RawValue: n1, ScoreValue: n1, RawJSON: itoa(n1), SubmittedAt: at},
+		&Response{ParticipantID: pid, ItemID: "C1", RawJSON: color, SubmittedAt: at},
+		&Response{ParticipantID: pid, ItemID: "X1", RawJSON: text, SubmittedAt: at},
+	)
+}
+
+func assertAggregateMatchesScan(t *testing.T, store *stubAggregateStore, when string) {
+	t.Helper()
+	stored, err := store.GetAnalyticsAggregate("S1")
+	if err != nil || stored == nil {
+		t.Fatalf("%s: no stored aggregate (%v)", when, err)
+	}
+	fresh := buildAnalyticsAggregate("S1", 5, store.items, store.responses)
+	if !sameAggregate(stored, fresh) {
+		got, _ := json.Marshal(stored)
+		want, _ := json.Marshal(fresh)
+		t.Fatalf("%s: incremental aggregate differs from a full scan:\n%s\n%s", when, got, want)
+	}
+}
+
+func TestAnalyticsAggregatesFollowWrites(t *testing.T) {
+	store := newAggregateFixture()
+	aggregates := NewAnalyticsAggregates(store)
+	if _, err := aggregates.Current(store.scale, store.items); err != nil {
+		t.Fatalf("Current: %v", err)
+	}
+	assertAggregateMatchesScan(t, store, "initial build")
+
+	late := time.Date(2025, 9, 21, 8, 0, 0, 0, time.UTC)
+	err := aggregates.Track("P5", func() error {
+		store.responses = append(store.responses, participantResponses("P5", late, 1, 2, 1, 52, `["Red"]`, `"late"`)...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Track add: %v", err)
+	}
+	assertAggregateMatchesScan(t, store, "after adding P5")
+
+	if err := aggregates.Track("P1", func() error { return store.removeParticipant("P1") }); err != nil {
+		t.Fatalf("Track delete: %v", err)
+	}
+	assertAggregateMatchesScan(t, store, "after deleting P1")
+	agg, _ := store.GetAnalyticsAggregate("S1")
+	if agg.Participants != 4 || agg.Responses != 23 || agg.AlphaN != 3 || agg.Daily["2025-09-21"] != 6 {
+		t.Fatalf("unexpected aggregate counts %+v", agg)
+	}
+
+	check, err := aggregates.Rebuild("S1")
+	if err != nil || !check.Stored || !check.Consistent || check.Responses != 23 {
+		t.Fatalf("rebuild of an up-to-date aggregate: %+v %v", check, err)
+	}
+	// a write that bypasses the aggregates is detected and repaired by the rebuild
+	store.responses = append(store.responses, participantResponses("P6", late, 5, 5, 5, 20, `["Blue"]`, `"x"`)...)
+	check, err = aggregates.Rebuild("S1")
+	if err != nil || check.Consistent {
+		t.Fatalf("rebuild should report drift: %+v %v", check, err)
+	}
+	assertAggregateMatchesScan(t, store, "after rebuild")
+
+	if err := aggregates.Reset("S1", func() error { store.responses = nil; return nil }); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	assertAggregateMatchesScan(t, store, "after purge")
+
+	// changing the item set invalidates the stored aggregate instead of updating it
+	store.responses = participantResponses("P7", late, 3, 3, 3, 10, `["Red"]`, `"y"`)
+	store.items = append(store.items, &Item{ID: "L4", ScaleID: "S1"})
+	if err := aggregates.Track("P7", func() error { return nil }); err != nil {
+		t.Fatalf("Track after item change: %v", err)
+	}
+	if agg, _ := store.GetAnalyticsAggregate("S1"); agg != nil {
+		t.Fatalf("stale aggregate should have been dropped")
+	}
+}
+
+func TestAnalyticsSummaryFromAggregates(t *testing.T) {
+	store := newAggregateFixture()
+	opts := ReliabilityOptions{Seed: 7}
+	plain, err := NewAnalyticsService(store).SummaryWithOptions("T1", "S1", opts)
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	svc := NewAnalyticsService(store)
+	svc.WithAggregates(NewAnalyticsAggregates(store))
+	fromAggregate, err := svc.SummaryWithOptions("T1", "S1", opts)
+	if err != nil {
+		t.Fatalf("Summary with aggregates: %v", err)
+	}
+	if len(store.stored) != 1 {
+		t.Fatalf("summary should have stored the aggregate")
+	}
+	a, _ := json.Marshal(plain)
+	b, _ := json.Marshal(fromAggregate)
+	if string(a) != string(b) {
+		t.Fatalf("summaries differ:\n%s\n%s", a, b)
+	}
+	if count, err := svc.ResponseCount("T1", "S1"); err != nil || count != 23 {
+		t.Fatalf("ResponseCount = %d, %v", count, err)
+	}
+
+	// the moment-based coefficients agree with the matrix-based ones
+	matrix, _ := buildAlphaMatrix(filterLikertItems(store.items), store.responses)
+	want := ComputeReliability(matrix, opts)
+	got := fromAggregate.Reliability
+	for name, pair := range map[string][2]float64{
+		"alpha":      {got.Alpha, want.Alpha},
+		"omega":      {got.Omega, want.Omega},
+		"lambda6":    {got.Lambda6, want.Lambda6},
+		"odd_even":   {got.SplitHalfOddEven, want.SplitHalfOddEven},
+		"split_rand": {got.SplitHalfRandom, want.SplitHalfRandom},
+	} {
+		if math.Abs(pair[0]-pair[1]) > 1e-9 {
+			t.Errorf("%s: moments give %v, matrix gives %v", name, pair[0], pair[1])
+		}
+	}
+	if got.N != 3 || want.N != 3 {
+		t.Fatalf("expected 3 complete rows, got %d and %d", got.N, want.N)
+	}
+}
@@ -1,15 +1,23 @@
 package services
 
-import "sort"
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type AnalyticsStore interface {
 	GetScale(id string) (*Scale, error)
 	ListItems(scaleID string) ([]*Item, error)
 	ListResponsesByScale(scaleID string) ([]*Response, error)
+	ListParticipantConditions(scaleID string) (map[string]string, error)
 }
 
 type AnalyticsService struct {
-	store AnalyticsStore
+	store      AnalyticsStore
+	aggregates *AnalyticsAggregates
 }
 
 type AnalyticsItem struct {
@@ -20,6 +28,30 @@ type AnalyticsItem struct {
 	Total     int               `json:"total"`
 }
 
+// FrequencyCount is one row of a choice item's frequency table. Percent is relative to
+// the item's N (respondents who answered), so multiple-choice rows may sum to more than 100.
+type FrequencyCount struct {
+	Value   string  `json:"value"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// ItemDescriptive summarises one item of any type. Numeric items (likert, rating, slider,
+// numeric) report Stats on the scored value, so reverse-keyed Likert items are already reversed
+// and out-of-range answers count as missing. Choice items report Frequencies and text items
+// report TextLength (characters of the trimmed answer).
+type ItemDescriptive struct {
+	ID          string            `json:"id"`
+	Type        string            `json:"type"`
+	StemI18n    map[string]string `json:"stem_i18n,omitempty"`
+	Reverse     bool              `json:"reverse_scored"`
+	N           int               `json:"n"`
+	Missing     int               `json:"missing"`
+	Stats       *NumericStats     `json:"stats,omitempty"`
+	Frequencies []FrequencyCount  `json:"frequencies,omitempty"`
+	TextLength  *NumericStats     `json:"text_length,omitempty"`
+}
+
 type AnalyticsTimeseries struct {
 	Date  string `json:"date"`
 	Count int    `json:"count"`
@@ -33,13 +65,26 @@ type AnalyticsSummary struct {
 	Timeseries     []AnalyticsTimeseries `json:"timeseries"`
 	Alpha          float64               `json:"alpha"`
 	N              int                   `json:"n"`
+	Descriptives   []ItemDescriptive     `json:"descriptives"`
+	Reliability    *Reliability          `json:"reliability"`
 }
 
 func NewAnalyticsService(store AnalyticsStore) *AnalyticsService {
 	return &AnalyticsService{store: store}
 }
 
+// WithAggregates makes the summary, alpha and response counts read the maintained aggregates
+// instead of rescanning every response.
+func (s *AnalyticsService) WithAggregates(a *AnalyticsAggregates) {
+	s.aggregates = a
+}
+
 func (s *AnalyticsService) Summary(tenantID, scaleID string) (*AnalyticsSummary, error) {
+	return s.SummaryWithOptions(tenantID, scaleID, ReliabilityOptions{})
+}
+
+// SummaryWithOptions is Summary with bootstrap confidence intervals for the reliability block.
+func (s *AnalyticsService) SummaryWithOptions(tenantID, scaleID string, opts ReliabilityOptions) (*AnalyticsSummary, error) {
 	sc, err := s.store.GetScale(scaleID)
 	if err != nil {
 		return nil, err
@@ -51,42 +96,404 @@ func (s *AnalyticsService) Summary(tenantID, scaleID string) (*AnalyticsSummary,
 	if err != nil {
 		return nil, err
 	}
-	responses, err := s.store.ListResponsesByScale(scaleID)
+	agg, err := s.aggregate(scaleID, sc, items)
 	if err != nil {
 		return nil, err
 	}
-	points := sc.Points
-	if points <= 0 {
-		points = 5
+	reliability, err := s.reliability(scaleID, items, agg, opts)
+	if err != nil {
+		return nil, err
 	}
-	filtered := filterLikertItems(items)
-	analyticsItems, countsByDay := buildAnalyticsItems(filtered, responses, points)
-	matrix, n := buildAlphaMatrix(filtered, responses)
-	alpha := CronbachAlpha(matrix)
-	series := buildTimeseries(countsByDay)
 	return &AnalyticsSummary{
 		ScaleID:        scaleID,
-		Points:         points,
-		TotalResponses: len(responses),
-		Items:          analyticsItems,
-		Timeseries:     series,
-		Alpha:          alpha,
-		N:              n,
+		Points:         agg.Points,
+		TotalResponses: agg.Responses,
+		Items:          agg.histograms(items),
+		Timeseries:     buildTimeseries(agg.Daily),
+		Alpha:          reliability.Alpha,
+		N:              reliability.N,
+		Descriptives:   agg.descriptives(items),
+		Reliability:    reliability,
 	}, nil
 }
 
-func (s *AnalyticsService) Alpha(scaleID string) (float64, int, error) {
+// ResponseCount returns the number of stored plaintext responses of a scale.
+func (s *AnalyticsService) ResponseCount(tenantID, scaleID string) (int, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return 0, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return 0, NewForbiddenError("forbidden")
+	}
 	items, err := s.store.ListItems(scaleID)
 	if err != nil {
-		return 0, 0, err
+		return 0, err
+	}
+	agg, err := s.aggregate(scaleID, sc, items)
+	if err != nil {
+		return 0, err
+	}
+	return agg.Responses, nil
+}
+
+// RebuildAggregates recomputes the stored analytics aggregate of a scale and reports whether it had
+// drifted from the responses.
+func (s *AnalyticsService) RebuildAggregates(tenantID, scaleID string) (*AggregateCheck, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	if s.aggregates == nil {
+		return nil, NewInvalidError("analytics aggregates are not enabled")
+	}
+	return s.aggregates.Rebuild(scaleID)
+}
+
+// aggregate returns the maintained aggregate of the scale, or builds one from a full scan when
+// aggregates are not enabled (or the scale is unknown).
+func (s *AnalyticsService) aggregate(scaleID string, sc *Scale, items []*Item) (*AnalyticsAggregate, error) {
+	if s.aggregates != nil && sc != nil {
+		return s.aggregates.Current(sc, items)
+	}
+	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	return buildAnalyticsAggregate(scaleID, analyticsPoints(sc), items, responses), nil
+}
+
+// reliability takes the point estimates from the aggregate; bootstrap intervals resample
+// participants and therefore still need the full matrix.
+func (s *AnalyticsService) reliability(scaleID string, items []*Item, agg *AnalyticsAggregate, opts ReliabilityOptions) (*Reliability, error) {
+	if opts.Seed == 0 {
+		opts.Seed = scaleReliabilitySeed(scaleID)
+	}
+	if opts.Bootstrap <= 0 {
+		return agg.reliability(opts.Seed), nil
 	}
 	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	matrix, _ := buildAlphaMatrix(filterLikertItems(items), responses)
+	return ComputeReliability(matrix, opts), nil
+}
+
+func (s *AnalyticsService) Alpha(tenantID, scaleID string) (float64, int, error) {
+	rel, err := s.Reliability(tenantID, scaleID, ReliabilityOptions{})
 	if err != nil {
 		return 0, 0, err
 	}
-	filtered := filterLikertItems(items)
-	matrix, n := buildAlphaMatrix(filtered, responses)
-	return CronbachAlpha(matrix), n, nil
+	return rel.Alpha, rel.N, nil
+}
+
+// Reliability reports alpha together with omega, lambda-6 and split-half coefficients for the
+// Likert items of a scale, using listwise deletion like Alpha.
+func (s *AnalyticsService) Reliability(tenantID, scaleID string, opts ReliabilityOptions) (*Reliability, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	agg, err := s.aggregate(scaleID, sc, items)
+	if err != nil {
+		return nil, err
+	}
+	return s.reliability(scaleID, items, agg, opts)
+}
+
+// ItemAnalysis reports item-level reliability diagnostics for the Likert items of a scale.
+// With pairwise=false incomplete participants are dropped (as for Alpha); with pairwise=true
+// every correlation uses all participants who answered both items.
+func (s *AnalyticsService) ItemAnalysis(tenantID, scaleID string, pairwise bool) (*ItemAnalysis, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	ids, _, matrix := buildItemMatrix(filterLikertItems(items), responses)
+	if !pairwise {
+		matrix, _ = buildAlphaMatrix(filterLikertItems(items), responses)
+	}
+	out := AnalyzeItems(ids, matrix)
+	out.ScaleID = scaleID
+	out.Missing = "listwise"
+	if pairwise {
+		out.Missing = "pairwise"
+	}
+	return out, nil
+}
+
+// Grouping sources for Compare.
+const (
+	GroupByCondition = "condition"
+	GroupByItem      = "item"
+)
+
+// GroupCompareOptions defines the groups (the participant's condition label, or the answer to a
+// single-choice item) and the outcome (an item, or the Likert total score when OutcomeItemID is empty).
+type GroupCompareOptions struct {
+	GroupBy       string
+	GroupItemID   string
+	OutcomeItemID string
+}
+
+type GroupSummary struct {
+	Group  string  `json:"group"`
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	SD     float64 `json:"sd"`
+	Median float64 `json:"median"`
+}
+
+type ContingencyTable struct {
+	Groups []string `json:"groups"`
+	Values []string `json:"values"`
+	Counts [][]int  `json:"counts"`
+}
+
+// GroupComparison holds the tests that apply to the outcome type: numeric outcomes get group
+// descriptives, ANOVA and Kruskal-Wallis (plus Welch's t and Mann-Whitney for exactly two groups);
+// choice outcomes get a contingency table with a chi-square test of independence.
+type GroupComparison struct {
+	ScaleID       string               `json:"scale_id"`
+	GroupBy       string               `json:"group_by"`
+	GroupItemID   string               `json:"group_item_id,omitempty"`
+	Outcome       string               `json:"outcome"`
+	OutcomeType   string               `json:"outcome_type"`
+	Groups        []GroupSummary       `json:"groups,omitempty"`
+	Welch         *WelchTTest          `json:"welch_t,omitempty"`
+	MannWhitney   *MannWhitneyResult   `json:"mann_whitney,omitempty"`
+	ANOVA         *ANOVAResult         `json:"anova,omitempty"`
+	KruskalWallis *KruskalWallisResult `json:"kruskal_wallis,omitempty"`
+	Contingency   *ContingencyTable    `json:"contingency,omitempty"`
+	ChiSquare     *ChiSquareResult     `json:"chi_square,omitempty"`
+}
+
+// Compare runs group comparisons for one outcome of a scale.
+func (s *AnalyticsService) Compare(tenantID, scaleID string, opts GroupCompareOptions) (*GroupComparison, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	points := sc.Points
+	if points <= 0 {
+		points = 5
+	}
+	itemByID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		itemByID[it.ID] = it
+	}
+	out := &GroupComparison{ScaleID: scaleID, GroupBy: opts.GroupBy}
+	var groupOf map[string]string
+	switch opts.GroupBy {
+	case GroupByCondition:
+		if groupOf, err = s.store.ListParticipantConditions(scaleID); err != nil {
+			return nil, err
+		}
+	case GroupByItem:
+		it := itemByID[opts.GroupItemID]
+		if it == nil || (it.Type != "single" && it.Type != "dropdown") {
+			return nil, NewInvalidError("group_item must be a single-choice item of this scale")
+		}
+		out.GroupItemID = it.ID
+		groupOf = map[string]string{}
+		for _, resp := range responses {
+			if resp.ItemID != it.ID {
+				continue
+			}
+			if vals := decodeChoiceValues(resp.RawJSON); len(vals) > 0 {
+				groupOf[resp.ParticipantID] = vals[0]
+			}
+		}
+	default:
+		return nil, NewInvalidError("group_by must be condition or item")
+	}
+
+	if opts.OutcomeItemID == "" {
+		out.Outcome, out.OutcomeType = "total", "numeric"
+		_, pids, rows := buildItemMatrix(filterLikertItems(items), responses)
+		values := map[string]float64{}
+		for i, row := range rows {
+			var total float64
+			complete := true
+			for _, v := range row {
+				if math.IsNaN(v) {
+					complete = false
+					break
+				}
+				total += v
+			}
+			if complete {
+				values[pids[i]] = total
+			}
+		}
+		if err := compareNumeric(out, groupOf, values); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	it := itemByID[opts.OutcomeItemID]
+	if it == nil {
+		return nil, NewInvalidError("outcome item not found in this scale")
+	}
+	if it.ID == out.GroupItemID {
+		return nil, NewInvalidError("outcome must differ from the grouping item")
+	}
+	out.Outcome = it.ID
+	itemType := it.Type
+	if itemType == "" {
+		itemType = "likert"
+	}
+	switch itemType {
+	case "likert", "rating", "slider", "numeric":
+		out.OutcomeType = "numeric"
+		values := map[string]float64{}
+		for _, resp := range responses {
+			if resp.ItemID != it.ID {
+				continue
+			}
+			if v, ok := scoredNumericValue(itemType, resp, points); ok {
+				values[resp.ParticipantID] = v
+			}
+		}
+		if err := compareNumeric(out, groupOf, values); err != nil {
+			return nil, err
+		}
+		return out, nil
+	case "single", "dropdown":
+		out.OutcomeType = "choice"
+		labels := map[string]string{}
+		for _, resp := range responses {
+			if resp.ItemID != it.ID {
+				continue
+			}
+			if vals := decodeChoiceValues(resp.RawJSON); len(vals) > 0 {
+				labels[resp.ParticipantID] = vals[0]
+			}
+		}
+		if err := compareChoice(out, it, groupOf, labels); err != nil {
+			return nil, err
+		}
+		return out, nil
+	default:
+		return nil, NewInvalidError("outcome must be a numeric or single-choice item")
+	}
+}
+
+func compareNumeric(out *GroupComparison, groupOf map[string]string, values map[string]float64) error {
+	byGroup := map[string][]float64{}
+	for pid, v := range values {
+		if g, ok := groupOf[pid]; ok && g != "" {
+			byGroup[g] = append(byGroup[g], v)
+		}
+	}
+	if len(byGroup) < 2 {
+		return NewInvalidError("at least two groups with data required")
+	}
+	names := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	groups := make([][]float64, 0, len(names))
+	for _, g := range names {
+		vals := byGroup[g]
+		sort.Float64s(vals)
+		st := Describe(vals)
+		out.Groups = append(out.Groups, GroupSummary{Group: g, N: len(vals), Mean: st.Mean, SD: st.SD, Median: st.Median})
+		groups = append(groups, vals)
+	}
+	if len(groups) == 2 {
+		out.Welch = WelchT(groups[0], groups[1])
+		out.MannWhitney = MannWhitney(groups[0], groups[1])
+	}
+	out.ANOVA = OneWayANOVA(groups)
+	out.KruskalWallis = KruskalWallis(groups)
+	return nil
+}
+
+func compareChoice(out *GroupComparison, item *Item, groupOf, labels map[string]string) error {
+	groupSet := map[string]bool{}
+	valueSet := map[string]bool{}
+	for pid, v := range labels {
+		if g, ok := groupOf[pid]; ok && g != "" {
+			groupSet[g] = true
+			valueSet[v] = true
+		}
+	}
+	if len(groupSet) < 2 {
+		return NewInvalidError("at least two groups with data required")
+	}
+	groups := make([]string, 0, len(groupSet))
+	for g := range groupSet {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	values := []string{}
+	for _, opt := range choiceOptionLabels(item) {
+		if valueSet[opt] {
+			values = append(values, opt)
+			delete(valueSet, opt)
+		}
+	}
+	extra := make([]string, 0, len(valueSet))
+	for v := range valueSet {
+		extra = append(extra, v)
+	}
+	sort.Strings(extra)
+	values = append(values, extra...)
+	gi := make(map[string]int, len(groups))
+	for i, g := range groups {
+		gi[g] = i
+	}
+	vi := make(map[string]int, len(values))
+	for i, v := range values {
+		vi[v] = i
+	}
+	counts := make([][]int, len(groups))
+	for i := range counts {
+		counts[i] = make([]int, len(values))
+	}
+	for pid, v := range labels {
+		if g, ok := groupOf[pid]; ok && g != "" {
+			counts[gi[g]][vi[v]]++
+		}
+	}
+	out.Contingency = &ContingencyTable{Groups: groups, Values: values, Counts: counts}
+	out.ChiSquare = ChiSquareIndependence(counts)
+	return nil
 }
 
 func filterLikertItems(items []*Item) []*Item {
@@ -99,34 +506,28 @@ func filterLikertItems(items []*Item) []*Item {
 	return out
 }
 
-func buildAnalyticsItems(items []*Item, responses []*Response, points int) ([]AnalyticsItem, map[string]int) {
-	itemIndex := make(map[string]int)
-	analyticsItems := make([]AnalyticsItem, 0, len(items))
-	for i, it := range items {
-		analyticsItems = append(analyticsItems, AnalyticsItem{
-			ID:        it.ID,
-			StemI18n:  it.StemI18n,
-			Reverse:   it.ReverseScored,
-			Histogram: make([]int, points),
-		})
-		itemIndex[it.ID] = i
-	}
-	countsByDay := map[string]int{}
-	for _, resp := range responses {
-		if idx, ok := itemIndex[resp.ItemID]; ok {
-			v := resp.ScoreValue
-			if v >= 1 && v <= points {
-				analyticsItems[idx].Histogram[v-1]++
-				analyticsItems[idx].Total++
+func buildAlphaMatrix(items []*Item, responses []*Response) ([][]float64, int) {
+	_, _, rows := buildItemMatrix(items, responses)
+	matrix := make([][]float64, 0, len(rows))
+	for _, row := range rows {
+		complete := true
+		for _, v := range row {
+			if math.IsNaN(v) {
+				complete = false
+				break
 			}
 		}
-		day := resp.SubmittedAt.UTC().Format("2006-01-02")
-		countsByDay[day]++
+		if complete {
+			matrix = append(matrix, row)
+		}
 	}
-	return analyticsItems, countsByDay
+	return matrix, len(matrix)
 }
 
-func buildAlphaMatrix(items []*Item, responses []*Response) ([][]float64, int) {
+// buildItemMatrix returns the participant × item score matrix (columns in sorted item ID order,
+// rows in participant ID order, with the participant IDs alongside). Unanswered cells are NaN;
+// participants who answered none of the items are omitted.
+func buildItemMatrix(items []*Item, responses []*Response) ([]string, []string, [][]float64) {
 	mp := map[string]map[string]float64{}
 	for _, resp := range responses {
 		if mp[resp.ParticipantID] == nil {
@@ -139,23 +540,32 @@ func buildAlphaMatrix(items []*Item, responses []*Response) ([][]float64, int) {
 		ids = append(ids, it.ID)
 	}
 	sort.Strings(ids)
-	matrix := make([][]float64, 0, len(mp))
-	for _, m := range mp {
-		row := make([]float64, 0, len(ids))
-		complete := true
-		for _, id := range ids {
+	pids := make([]string, 0, len(mp))
+	for pid := range mp {
+		pids = append(pids, pid)
+	}
+	sort.Strings(pids)
+	matrix := make([][]float64, 0, len(pids))
+	rowPIDs := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		m := mp[pid]
+		row := make([]float64, len(ids))
+		answered := false
+		for j, id := range ids {
 			v, ok := m[id]
 			if !ok {
-				complete = false
-				break
+				row[j] = math.NaN()
+				continue
 			}
-			row = append(row, v)
+			row[j] = v
+			answered = true
 		}
-		if complete {
+		if answered {
 			matrix = append(matrix, row)
+			rowPIDs = append(rowPIDs, pid)
 		}
 	}
-	return matrix, len(matrix)
+	return ids, rowPIDs, matrix
 }
 
 func buildTimeseries(counts map[string]int) []AnalyticsTimeseries {
@@ -170,3 +580,104 @@ func buildTimeseries(counts map[string]int) []AnalyticsTimeseries {
 	}
 	return out
 }
+
+// scoredNumericValue returns the stored score when it is a valid answer. Likert scores must lie
+// within [1, points]; other numeric types were rejected at submission when out of range, which
+// leaves a zero score next to the original raw payload.
+func scoredNumericValue(itemType string, resp *Response, points int) (float64, bool) {
+	if itemType == "likert" {
+		if resp.ScoreValue >= 1 && resp.ScoreValue <= points {
+			return float64(resp.ScoreValue), true
+		}
+		return 0, false
+	}
+	if strings.TrimSpace(resp.RawJSON) == "" {
+		return float64(resp.ScoreValue), true
+	}
+	raw := strings.Trim(strings.TrimSpace(resp.RawJSON), `"`)
+	n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || int(n) != resp.RawValue {
+		return 0, false
+	}
+	return float64(resp.ScoreValue), true
+}
+
+func decodeChoiceValues(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var arr []string
+	if err := json.Unmarshal([]byte(raw), &arr); err == nil {
+		out := make([]string, 0, len(arr))
+		for _, v := range arr {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	if v := decodeTextValue(raw); v != "" {
+		return []string{v}
+	}
+	return nil
+}
+
+func decodeTextValue(raw string) string {
+	if strings.TrimSpace(raw) == "" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal([]byte(raw), &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	if raw = strings.TrimSpace(raw); raw == "null" {
+		return ""
+	}
+	return raw
+}
+
+// buildFrequencies lists the configured options first (English labels, in item order, including
+// unchosen ones) followed by any other values seen in the data. Counts are per answer (a value
+// chosen once per participant) and Percent is relative to the n answers.
+func buildFrequencies(item *Item, counts map[string]int, n int) []FrequencyCount {
+	order := []string{}
+	listed := map[string]bool{}
+	for _, opt := range choiceOptionLabels(item) {
+		if !listed[opt] {
+			order = append(order, opt)
+			listed[opt] = true
+		}
+	}
+	extra := []string{}
+	for v := range counts {
+		if !listed[v] {
+			extra = append(extra, v)
+		}
+	}
+	sort.Strings(extra)
+	order = append(order, extra...)
+	out := make([]FrequencyCount, 0, len(order))
+	for _, v := range order {
+		fc := FrequencyCount{Value: v, Count: counts[v]}
+		if n > 0 {
+			fc.Percent = float64(counts[v]) * 100 / float64(n)
+		}
+		out = append(out, fc)
+	}
+	return out
+}
+
+func choiceOptionLabels(item *Item) []string {
+	if item == nil || len(item.OptionsI18n) == 0 {
+		return nil
+	}
+	if en, ok := item.OptionsI18n["en"]; ok {
+		return en
+	}
+	langs := make([]string, 0, len(item.OptionsI18n))
+	for lang := range item.OptionsI18n {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return item.OptionsI18n[langs[0]]
+}
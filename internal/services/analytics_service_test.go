@@ -6,9 +6,10 @@ import (
 )
 
 type stubAnalyticsStore struct {
-	scale     *Scale
-	items     []*Item
-	responses []*Response
+	scale      *Scale
+	items      []*Item
+	responses  []*Response
+	conditions map[string]string
 }
 
 func (s *stubAnalyticsStore) GetScale(id string) (*Scale, error) {
@@ -41,6 +42,10 @@ func (s *stubAnalyticsStore) ListResponsesByScale(scaleID string) ([]*Response,
 	return out, nil
 }
 
+func (s *stubAnalyticsStore) ListParticipantConditions(scaleID string) (map[string]string, error) {
+	return s.conditions, nil
+}
+
 func TestAnalyticsSummary(t *testing.T) {
 	store := &stubAnalyticsStore{
 		scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
@@ -76,6 +81,7 @@ func TestAnalyticsSummary(t *testing.T) {
 
 func TestAnalyticsAlpha(t *testing.T) {
 	store := &stubAnalyticsStore{
+		scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
 		items: []*Item{{ID: "I1", ScaleID: "S1"}, {ID: "I2", ScaleID: "S1"}},
 		responses: []*Response{
 			{ParticipantID: "P1", ItemID: "I1", ScoreValue: 3},
@@ -85,7 +91,7 @@ func TestAnalyticsAlpha(t *testing.T) {
 		},
 	}
 	svc := NewAnalyticsService(store)
-	alpha, n, err := svc.Alpha("S1")
+	alpha, n, err := svc.Alpha("T1", "S1")
 	if err != nil {
 		t.Fatalf("Alpha error: %v", err)
 	}
@@ -103,4 +109,154 @@ func TestAnalyticsSummaryForbidden(t *testing.T) {
 	if _, err := svc.Summary("T1", "S1"); err == nil {
 		t.Fatalf("expected forbidden error")
 	}
+	if _, err := svc.Reliability("T1", "S1", ReliabilityOptions{Bootstrap: 100}); err == nil {
+		t.Fatalf("expected forbidden reliability for another tenant's scale")
+	}
+}
+
+func TestAnalyticsSummaryDescriptives(t *testing.T) {
+	at := time.Date(2025, 9, 18, 0, 0, 0, 0, time.UTC)
+	store := &stubAnalyticsStore{
+		scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
+		items: []*Item{
+			{ID: "L1", ScaleID: "S1", ReverseScored: true},
+			{ID: "N1", ScaleID: "S1", Type: "numeric", Min: 0, Max: 10},
+			{ID: "C1", ScaleID: "S1", Type: "multiple", OptionsI18n: map[string][]string{"en": {"Red", "Blue", "Green"}}},
+			{ID: "X1", ScaleID: "S1", Type: "short_text"},
+		},
+		responses: []*Response{
+			// reverse-scored likert: raw 1 and 2 stored as 5 and 4; a 9 is outside Points
+			{ParticipantID: "P1", ItemID: "L1", RawValue: 1, ScoreValue: 5, RawJSON: "1", SubmittedAt: at},
+			{ParticipantID: "P2", ItemID: "L1", RawValue: 2, ScoreValue: 4, RawJSON: "2", SubmittedAt: at},
+			{ParticipantID: "P3", ItemID: "L1", RawJSON: "9", SubmittedAt: at},
+			{ParticipantID: "P1", ItemID: "N1", RawValue: 0, ScoreValue: 0, RawJSON: "0", SubmittedAt: at},
+			{ParticipantID: "P2", ItemID: "N1", RawValue: 10, ScoreValue: 10, RawJSON: "10", SubmittedAt: at},
+			{ParticipantID: "P3", ItemID: "N1", RawJSON: "12", SubmittedAt: at},
+			{ParticipantID: "P1", ItemID: "C1", RawJSON: `["Red","Green"]`, SubmittedAt: at},
+			{ParticipantID: "P2", ItemID: "C1", RawJSON: `["Red"]`, SubmittedAt: at},
+			{ParticipantID: "P3", ItemID: "C1", RawJSON: `["Purple"]`, SubmittedAt: at},
+			{ParticipantID: "P1", ItemID: "X1", RawJSON: `"héllo "`, SubmittedAt: at},
+			{ParticipantID: "P2", ItemID: "X1", RawJSON: `""`, SubmittedAt: at},
+		},
+	}
+	summary, err := NewAnalyticsService(store).Summary("T1", "S1")
+	if err != nil {
+		t.Fatalf("Summary error: %v", err)
+	}
+	if len(summary.Descriptives) != 4 {
+		t.Fatalf("expected descriptives for all items, got %d", len(summary.Descriptives))
+	}
+	l := summary.Descriptives[0]
+	if l.N != 2 || l.Missing != 1 || l.Stats == nil || l.Stats.Mean != 4.5 || l.Stats.Max != 5 {
+		t.Fatalf("unexpected likert descriptives: %+v %+v", l, l.Stats)
+	}
+	num := summary.Descriptives[1]
+	if num.N != 2 || num.Missing != 1 || num.Stats.Min != 0 || num.Stats.Median != 5 {
+		t.Fatalf("unexpected numeric descriptives: %+v %+v", num, num.Stats)
+	}
+	c := summary.Descriptives[2]
+	if c.N != 3 || len(c.Frequencies) != 4 {
+		t.Fatalf("unexpected choice descriptives: %+v", c)
+	}
+	if c.Frequencies[0].Value != "Red" || c.Frequencies[0].Count != 2 || c.Frequencies[1].Count != 0 || c.Frequencies[3].Value != "Purple" {
+		t.Fatalf("unexpected frequency table: %+v", c.Frequencies)
+	}
+	x := summary.Descriptives[3]
+	if x.N != 1 || x.Missing != 2 || x.TextLength == nil || x.TextLength.Mean != 5 {
+		t.Fatalf("unexpected text descriptives: %+v %+v", x, x.TextLength)
+	}
+}
+
+func TestAnalyticsItemAnalysisMissingModes(t *testing.T) {
+	store := &stubAnalyticsStore{
+		scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
+		items: []*Item{{ID: "I1", ScaleID: "S1"}, {ID: "I2", ScaleID: "S1"}, {ID: "T1", ScaleID: "S1", Type: "short_text"}},
+		responses: []*Response{
+			{ParticipantID: "P1", ItemID: "I1", ScoreValue: 1},
+			{ParticipantID: "P1", ItemID: "I2", ScoreValue: 2},
+			{ParticipantID: "P2", ItemID: "I1", ScoreValue: 3},
+			{ParticipantID: "P2", ItemID: "I2", ScoreValue: 3},
+			{ParticipantID: "P3", ItemID: "I1", ScoreValue: 5},
+		},
+	}
+	svc := NewAnalyticsService(store)
+	listwise, err := svc.ItemAnalysis("T1", "S1", false)
+	if err != nil {
+		t.Fatalf("ItemAnalysis error: %v", err)
+	}
+	if listwise.N != 2 || listwise.Missing != "listwise" || len(listwise.ItemIDs) != 2 {
+		t.Fatalf("unexpected listwise result: %+v", listwise)
+	}
+	pairwise, err := svc.ItemAnalysis("T1", "S1", true)
+	if err != nil {
+		t.Fatalf("ItemAnalysis error: %v", err)
+	}
+	if pairwise.N != 3 || pairwise.Items[0].N != 3 || pairwise.PairN[0][1] != 2 {
+		t.Fatalf("unexpected pairwise result: %+v", pairwise)
+	}
+	if _, err := svc.ItemAnalysis("T2", "S1", false); err == nil {
+		t.Fatalf("expected forbidden error")
+	}
+}
+
+func TestAnalyticsCompareGroups(t *testing.T) {
+	store := &stubAnalyticsStore{
+		scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
+		items: []*Item{
+			{ID: "I1", ScaleID: "S1"},
+			{ID: "I2", ScaleID: "S1"},
+			{ID: "G", ScaleID: "S1", Type: "single", OptionsI18n: map[string][]string{"en": {"Male", "Female"}}},
+			{ID: "C", ScaleID: "S1", Type: "dropdown"},
+		},
+		conditions: map[string]string{"P1": "control", "P2": "control", "P3": "treatment", "P4": "treatment"},
+	}
+	add := func(pid, item string, score int, raw string) {
+		store.responses = append(store.responses, &Response{ParticipantID: pid, ItemID: item, ScoreValue: score, RawValue: score, RawJSON: raw})
+	}
+	add("P1", "I1", 1, "1")
+	add("P1", "I2", 2, "2")
+	add("P2", "I1", 2, "2")
+	add("P2", "I2", 2, "2")
+	add("P3", "I1", 4, "4")
+	add("P3", "I2", 5, "5")
+	add("P4", "I1", 5, "5")
+	add("P4", "I2", 4, "4")
+	add("P1", "G", 0, `"Male"`)
+	add("P2", "G", 0, `"Female"`)
+	add("P3", "G", 0, `"Male"`)
+	add("P4", "G", 0, `"Female"`)
+	add("P1", "C", 0, `"yes"`)
+	add("P2", "C", 0, `"yes"`)
+	add("P3", "C", 0, `"no"`)
+	add("P4", "C", 0, `"no"`)
+	svc := NewAnalyticsService(store)
+
+	res, err := svc.Compare("T1", "S1", GroupCompareOptions{GroupBy: GroupByCondition})
+	if err != nil {
+		t.Fatalf("Compare error: %v", err)
+	}
+	if res.Outcome != "total" || len(res.Groups) != 2 || res.Groups[0].Group != "control" || res.Groups[0].Mean != 3.5 || res.Groups[1].Mean != 9 {
+		t.Fatalf("unexpected group summaries: %+v", res.Groups)
+	}
+	if res.Welch == nil || res.MannWhitney == nil || res.ANOVA == nil || res.KruskalWallis == nil || res.Welch.T >= 0 {
+		t.Fatalf("expected two-group tests: %+v", res)
+	}
+
+	byItem, err := svc.Compare("T1", "S1", GroupCompareOptions{GroupBy: GroupByItem, GroupItemID: "G", OutcomeItemID: "C"})
+	if err != nil {
+		t.Fatalf("Compare error: %v", err)
+	}
+	if byItem.OutcomeType != "choice" || byItem.ChiSquare == nil || byItem.ChiSquare.ChiSquare != 0 {
+		t.Fatalf("unexpected chi-square comparison: %+v", byItem)
+	}
+	if byItem.Contingency.Groups[0] != "Female" || byItem.Contingency.Counts[0][0]+byItem.Contingency.Counts[0][1] != 2 {
+		t.Fatalf("unexpected contingency table: %+v", byItem.Contingency)
+	}
+
+	if _, err := svc.Compare("T1", "S1", GroupCompareOptions{GroupBy: GroupByItem, GroupItemID: "I1"}); err == nil {
+		t.Fatalf("expected error for non-choice grouping item")
+	}
+	if _, err := svc.Compare("T2", "S1", GroupCompareOptions{GroupBy: GroupByCondition}); err == nil {
+		t.Fatalf("expected forbidden error")
+	}
 }
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Personal API token scopes.
+const (
+	ScopeScalesRead      = "scales:read"
+	ScopeScalesWrite     = "scales:write"
+	ScopeResponsesExport = "responses:export"
+	ScopeAuditRead       = "audit:read"
+
+	// APITokenPrefix marks personal API tokens so they are never mistaken for session JWTs.
+	APITokenPrefix = "synap_pat_"
+
+	maxAPITokenDays = 366
+)
+
+// APITokenScopes lists the scopes a personal API token can carry.
+var APITokenScopes = []string{ScopeScalesRead, ScopeScalesWrite, ScopeResponsesExport, ScopeAuditRead}
+
+// APITokenStore persists personal API tokens; only the SHA-256 of a token is stored.
+type APITokenStore interface {
+	GetScale(id string) (*Scale, error)
+	SaveAPIToken(t *APIToken) error
+	GetAPIToken(id string) (*APIToken, error)
+	GetAPITokenByHash(hash string) (*APIToken, error)
+	ListAPITokens(tenantID string) ([]*APIToken, error)
+	TouchAPIToken(id string, at time.Time) error
+	AddAudit(entry AuditEntry)
+}
+
+// APIToken is a personal access token of one user for scripts and integrations. It acts as its
+// owner within the tenant, limited to Scopes and, when ScaleID is set, to that scale.
+type APIToken struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id"`
+	Email      string    `json:"email"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	TokenHash  string    `json:"-"`
+	Scopes     []string  `json:"scopes"`
+	ScaleID    string    `json:"scale_id,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+}
+
+// APITokenInput creates a token. ExpiresInDays 0 means no expiry.
+type APITokenInput struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ScaleID       string   `json:"scale_id"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// APITokenCreated returns the new token; Token is shown only once.
+type APITokenCreated struct {
+	APIToken *APIToken `json:"api_token"`
+	Token    string    `json:"token"`
+}
+
+type APITokenService struct {
+	store    APITokenStore
+	now      func() time.Time
+	newID    func() string
+	newToken func() string
+}
+
+func NewAPITokenService(store APITokenStore) *APITokenService {
+	return &APITokenService{
+		store:    store,
+		now:      time.Now,
+		newID:    func() string { return "pat" + randomID(10) },
+		newToken: func() string { return APITokenPrefix + randomBytes(32) },
+	}
+}
+
+// HashAPIToken is the lookup key a token is stored under.
+func HashAPIToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+func (s *APITokenService) Create(tenantID, userID, email string, in APITokenInput) (*APITokenCreated, error) {
+	if tenantID == "" || userID == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return nil, NewInvalidError("name required (max 100 characters)")
+	}
+	scopes := []string{}
+	for _, sc := range in.Scopes {
+		sc = strings.TrimSpace(sc)
+		if !containsString(APITokenScopes, sc) {
+			return nil, NewInvalidError("unknown scope " + sc)
+		}
+		if !containsString(scopes, sc) {
+			scopes = append(scopes, sc)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, NewInvalidError("scopes required")
+	}
+	sort.Strings(scopes)
+	if in.ExpiresInDays < 0 || in.ExpiresInDays > maxAPITokenDays {
+		return nil, NewInvalidError("expires_in_days must be between 0 and 366")
+	}
+	scaleID := strings.TrimSpace(in.ScaleID)
+	if scaleID != "" {
+		sc, err := s.store.GetScale(scaleID)
+		if err != nil {
+			return nil, err
+		}
+		if sc == nil || sc.TenantID != tenantID {
+			return nil, NewForbiddenError("forbidden")
+		}
+	}
+	now := s.now().UTC()
+	t := &APIToken{ID: s.newID(), TenantID: tenantID, UserID: userID, Email: email, Name: name, Scopes: scopes, ScaleID: scaleID, CreatedAt: now}
+	if in.ExpiresInDays > 0 {
+		t.ExpiresAt = now.AddDate(0, 0, in.ExpiresInDays)
+	}
+	secret := s.newToken()
+	t.TokenHash = HashAPIToken(secret)
+	t.Prefix = secret[:len(APITokenPrefix)+6]
+	if err := s.store.SaveAPIToken(t); err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: tenantID, Actor: email, ActorID: userID, Action: "api_token_create", Target: t.ID, Note: strings.Join(scopes, " ")})
+	return &APITokenCreated{APIToken: t, Token: secret}, nil
+}
+
+// List returns the user's tokens, including revoked and expired ones.
+func (s *APITokenService) List(tenantID, userID string) ([]*APIToken, error) {
+	all, err := s.store.ListAPITokens(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := []*APIToken{}
+	for _, t := range all {
+		if t.UserID == userID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s *APITokenService) Revoke(tenantID, userID, email, id string) (*APIToken, error) {
+	t, err := s.store.GetAPIToken(id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.TenantID != tenantID || t.UserID != userID {
+		return nil, NewNotFoundError("token not found")
+	}
+	if t.RevokedAt.IsZero() {
+		t.RevokedAt = s.now().UTC()
+		if err := s.store.SaveAPIToken(t); err != nil {
+			return nil, err
+		}
+		s.store.AddAudit(AuditEntry{Time: t.RevokedAt, TenantID: tenantID, Actor: email, ActorID: userID, Action: "api_token_revoke", Target: t.ID})
+	}
+	return t, nil
+}
+
+// Authenticate resolves a presented token, records its use (last used time and an audit entry
+// naming the request and the client) and returns it. Revoked, expired and unknown tokens are unauthorized.
+func (s *APITokenService) Authenticate(token, request string, client SessionClient) (*APIToken, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil, NewUnauthorizedError("invalid api token")
+	}
+	t, err := s.store.GetAPITokenByHash(HashAPIToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if t == nil || !t.RevokedAt.IsZero() || (!t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)) {
+		return nil, NewUnauthorizedError("invalid api token")
+	}
+	if err := s.store.TouchAPIToken(t.ID, now); err != nil {
+		return nil, err
+	}
+	t.LastUsedAt = now
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: t.TenantID, Actor: t.Email, ActorID: t.UserID, Action: "api_token_use", Target: t.ID, Note: request,
+		IP: client.IP, UserAgent: client.UserAgent})
+	return t, nil
+}
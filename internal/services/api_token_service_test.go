@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubAPITokenStore struct {
+	scales map[string]*Scale
+	tokens map[string]*APIToken
+	audit  []AuditEntry
+}
+
+func newStubAPITokenStore() *stubAPITokenStore {
+	return &stubAPITokenStore{
+		scales: map[string]*Scale{"S1": {ID: "S1", TenantID: "T1"}, "X1": {ID: "X1", TenantID: "T2"}},
+		tokens: map[string]*APIToken{},
+	}
+}
+
+func (s *stubAPITokenStore) GetScale(id string) (*Scale, error) { return s.scales[id], nil }
+
+func (s *stubAPITokenStore) SaveAPIToken(t *APIToken) error {
+	cp := *t
+	s.tokens[t.ID] = &cp
+	return nil
+}
+
+func (s *stubAPITokenStore) GetAPIToken(id string) (*APIToken, error) {
+	if t, ok := s.tokens[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubAPITokenStore) GetAPITokenByHash(hash string) (*APIToken, error) {
+	for _, t := range s.tokens {
+		if t.TokenHash == hash {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubAPITokenStore) ListAPITokens(tenantID string) ([]*APIToken, error) {
+	out := []*APIToken{}
+	for _, t := range s.tokens {
+		if t.TenantID == tenantID {
+			cp := *t
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubAPITokenStore) TouchAPIToken(id string, at time.Time) error {
+	s.tokens[id].LastUsedAt = at
+	return nil
+}
+
+func (s *stubAPITokenStore) AddAudit(entry AuditEntry) { s.audit = append(s.audit, entry) }
+
+func TestAPITokenCreateValidates(t *testing.T) {
+	store := newStubAPITokenStore()
+	svc := NewAPITokenService(store)
+	cases := []APITokenInput{
+		{Scopes: []string{ScopeScalesRead}},
+		{Name: "ci", Scopes: []string{"admin"}},
+		{Name: "ci"},
+		{Name: "ci", Scopes: []string{ScopeScalesRead}, ExpiresInDays: 400},
+		{Name: "ci", Scopes: []string{ScopeScalesRead}, ScaleID: "X1"},
+	}
+	for i, in := range cases {
+		if _, err := svc.Create("T1", "U1", "a@b", in); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+	created, err := svc.Create("T1", "U1", "a@b", APITokenInput{Name: " export job ", Scopes: []string{ScopeResponsesExport, ScopeScalesRead, ScopeScalesRead}, ScaleID: "S1", ExpiresInDays: 30})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	tok := created.APIToken
+	if !strings.HasPrefix(created.Token, APITokenPrefix) || !strings.HasPrefix(created.Token, tok.Prefix) || tok.Name != "export job" {
+		t.Fatalf("unexpected token %+v", created)
+	}
+	if len(tok.Scopes) != 2 || tok.Scopes[0] != ScopeResponsesExport || tok.ExpiresAt.Sub(tok.CreatedAt) != 30*24*time.Hour {
+		t.Fatalf("unexpected scopes or expiry %+v", tok)
+	}
+	if stored := store.tokens[tok.ID]; stored.TokenHash != HashAPIToken(created.Token) || strings.Contains(stored.TokenHash, created.Token) {
+		t.Fatalf("token must be stored hashed")
+	}
+	b, _ := json.Marshal(tok)
+	if strings.Contains(string(b), tok.TokenHash) {
+		t.Fatalf("token JSON must not include the hash")
+	}
+	if len(store.audit) != 1 || store.audit[0].Action != "api_token_create" || store.audit[0].Target != tok.ID {
+		t.Fatalf("unexpected audit %+v", store.audit)
+	}
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	store := newStubAPITokenStore()
+	svc := NewAPITokenService(store)
+	now := time.Date(2025, 9, 21, 8, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	created, err := svc.Create("T1", "U1", "a@b", APITokenInput{Name: "ci", Scopes: []string{ScopeScalesRead}, ExpiresInDays: 1})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := svc.Authenticate(APITokenPrefix+"unknown", "GET /api/admin/scales", SessionClient{}); err == nil {
+		t.Fatalf("unknown token must be rejected")
+	}
+	now = now.Add(time.Hour)
+	tok, err := svc.Authenticate(created.Token, "GET /api/admin/scales", SessionClient{})
+	if err != nil || tok.ID != created.APIToken.ID || tok.UserID != "U1" {
+		t.Fatalf("Authenticate: %+v %v", tok, err)
+	}
+	if !store.tokens[tok.ID].LastUsedAt.Equal(now) {
+		t.Fatalf("last use not recorded")
+	}
+	last := store.audit[len(store.audit)-1]
+	if last.Action != "api_token_use" || last.Target != tok.ID || last.Note != "GET /api/admin/scales" || last.Actor != "a@b" {
+		t.Fatalf("unexpected use audit %+v", last)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if _, err := svc.Authenticate(created.Token, "GET /api/admin/scales", SessionClient{}); err == nil {
+		t.Fatalf("expired token must be rejected")
+	}
+
+	other, _ := svc.Create("T1", "U1", "a@b", APITokenInput{Name: "other", Scopes: []string{ScopeAuditRead}})
+	if _, err := svc.Revoke("T1", "U2", "c@d", other.APIToken.ID); err == nil {
+		t.Fatalf("another user must not revoke the token")
+	}
+	if _, err := svc.Revoke("T1", "U1", "a@b", other.APIToken.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Authenticate(other.Token, "GET /api/admin/audit", SessionClient{}); err == nil {
+		t.Fatalf("revoked token must be rejected")
+	}
+	list, _ := svc.List("T1", "U1")
+	if len(list) != 2 {
+		t.Fatalf("List returned %d tokens", len(list))
+	}
+}
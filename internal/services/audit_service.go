@@ -0,0 +1,526 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AuditExportFormat names the JSON Lines layout written by AuditService.Export.
+	AuditExportFormat = "synap-audit-export/1"
+
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+	AuditResultDenied  = "denied"
+
+	auditPageSize       = 500
+	auditDefaultLimit   = 100
+	auditSourceAdmin    = "admin"
+	auditSourceSubjects = "participant"
+)
+
+// AuditStore reads the hash-chained audit log and its signed checkpoints.
+type AuditStore interface {
+	// ListAuditChain returns up to limit entries with Seq > afterSeq in ascending order.
+	ListAuditChain(afterSeq int64, limit int) ([]AuditEntry, error)
+	// QueryAudit returns up to q.Limit entries matching q, newest first.
+	QueryAudit(q AuditQuery) ([]AuditEntry, error)
+	AuditHead() (*AuditEntry, error)
+	SaveAuditCheckpoint(cp *AuditCheckpoint) error
+	ListAuditCheckpoints() ([]*AuditCheckpoint, error)
+}
+
+// AuditCheckpoint is the server's ed25519 signature over the chain head at Seq.
+type AuditCheckpoint struct {
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature"`
+}
+
+// AuditBreak is the first place where the audit log no longer matches its hash chain.
+type AuditBreak struct {
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// AuditVerification reports the result of walking the chain. Scope is "chain" for the whole log
+// and "tenant" when only one tenant's entries are reported.
+type AuditVerification struct {
+	OK             bool             `json:"ok"`
+	Scope          string           `json:"scope"`
+	Entries        int              `json:"entries"`
+	HeadSeq        int64            `json:"head_seq,omitempty"`
+	HeadHash       string           `json:"head_hash,omitempty"`
+	Checkpoints    int              `json:"checkpoints"`
+	LastCheckpoint *AuditCheckpoint `json:"last_checkpoint,omitempty"`
+	Broken         *AuditBreak      `json:"broken,omitempty"`
+}
+
+// auditContent is the canonical form hashed for an entry. Fields added later must be omitempty
+// so that the hashes of older entries stay valid.
+type auditContent struct {
+	Seq       int64  `json:"seq"`
+	Time      string `json:"time"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Target    string `json:"target,omitempty"`
+	Note      string `json:"note,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	ActorID   string `json:"actor_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Result    string `json:"result,omitempty"`
+	PrevHash  string `json:"prev_hash,omitempty"`
+}
+
+func newAuditContent(e AuditEntry) auditContent {
+	return auditContent{Seq: e.Seq, Time: e.Time.UTC().Format(time.RFC3339Nano), Actor: e.Actor, Action: e.Action, Target: e.Target, Note: e.Note,
+		TenantID: e.TenantID, ActorID: e.ActorID, IP: e.IP, UserAgent: e.UserAgent, Result: e.Result, PrevHash: e.PrevHash}
+}
+
+// AuditHash returns the hex SHA-256 of e's canonical JSON, which includes e.PrevHash.
+func AuditHash(e AuditEntry) string {
+	raw, _ := json.Marshal(newAuditContent(e))
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditQuery selects audit entries of one tenant. Empty filters match everything.
+type AuditQuery struct {
+	TenantID string
+	// LegacyTargets lets entries logged before tenants were recorded match by target (the
+	// tenant's scales and API tokens).
+	LegacyTargets []string
+	Action        string
+	// Actor matches the actor's email or user ID.
+	Actor string
+	// Targets, when set, lists the targets an entry may have (a scale and its API tokens, say).
+	Targets []string
+	// Source is "admin" or "participant" to keep only entries by staff or by participants.
+	Source string
+	From   time.Time // inclusive
+	To     time.Time // exclusive
+	// Before is the pagination cursor: only entries with Seq < Before.
+	Before int64
+	Limit  int
+}
+
+// Matches reports whether e satisfies q, ignoring the cursor and limit.
+func (q *AuditQuery) Matches(e AuditEntry) bool {
+	if e.TenantID != q.TenantID && (e.TenantID != "" || !containsString(q.LegacyTargets, e.Target)) {
+		return false
+	}
+	switch {
+	case q.Action != "" && e.Action != q.Action,
+		q.Actor != "" && !strings.EqualFold(e.Actor, q.Actor) && e.ActorID != q.Actor,
+		len(q.Targets) > 0 && !containsString(q.Targets, e.Target),
+		q.Source == auditSourceAdmin && strings.EqualFold(e.Actor, auditSourceSubjects),
+		q.Source == auditSourceSubjects && !strings.EqualFold(e.Actor, auditSourceSubjects),
+		!q.From.IsZero() && e.Time.Before(q.From),
+		!q.To.IsZero() && !e.Time.Before(q.To):
+		return false
+	}
+	return true
+}
+
+// AuditPage is one page of a search; NextCursor is passed back as Before for the next page.
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// AuditCheckpointMessage returns the bytes a checkpoint signature covers.
+func AuditCheckpointMessage(cp *AuditCheckpoint) []byte {
+	return []byte("synap-audit-checkpoint/1\n" + strconv.FormatInt(cp.Seq, 10) + "\n" + cp.Hash + "\n" + cp.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+type AuditService struct {
+	store AuditStore
+	key   ed25519.PrivateKey
+	// trusted are the public keys checkpoints may be signed with: key's own and earlier ones.
+	trusted []ed25519.PublicKey
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewAuditService verifies the audit chain; key signs checkpoints and may be nil for read-only use.
+// It must be a persistent key (SYNAP_SIGN_SEED): checkpoints only count when signed by a trusted key.
+func NewAuditService(store AuditStore, key ed25519.PrivateKey) *AuditService {
+	s := &AuditService{store: store, key: key, now: time.Now}
+	if key != nil {
+		s.trusted = append(s.trusted, key.Public().(ed25519.PublicKey))
+	}
+	return s
+}
+
+// WithTrustedKeys adds public keys whose checkpoints verify, e.g. the keys of seeds used before a
+// rotation.
+func (s *AuditService) WithTrustedKeys(keys ...ed25519.PublicKey) *AuditService {
+	s.trusted = append(s.trusted, keys...)
+	return s
+}
+
+func (s *AuditService) publicKey() string {
+	if s.key == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Checkpoint signs the current chain head. It returns nil when nothing was logged since the last
+// checkpoint.
+func (s *AuditService) Checkpoint() (*AuditCheckpoint, error) {
+	if s.key == nil {
+		return nil, NewInvalidError("no signing key configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	head, err := s.store.AuditHead()
+	if err != nil || head == nil || head.Hash == "" {
+		return nil, err
+	}
+	cps, err := s.store.ListAuditCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	if n := len(cps); n > 0 && cps[n-1].Seq >= head.Seq {
+		return nil, nil
+	}
+	cp := &AuditCheckpoint{Seq: head.Seq, Hash: head.Hash, CreatedAt: s.now().UTC(), PublicKey: s.publicKey()}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, AuditCheckpointMessage(cp)))
+	if err := s.store.SaveAuditCheckpoint(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Run writes a checkpoint every interval until ctx is done. Without a signing key it reports that
+// once and returns.
+func (s *AuditService) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	if s.key == nil {
+		if onError != nil {
+			onError(NewInvalidError("no signing key configured; checkpoints are disabled"))
+		}
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.Checkpoint(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Verify walks the chain from the first entry and reports the first broken link: an entry whose
+// content no longer matches its hash, whose predecessor was removed or altered, or that disagrees
+// with a signed checkpoint. A checkpoint counts only when it is signed by a trusted key; one signed
+// by any other key is a break, since whoever can write the log can also sign with a key of their own.
+func (s *AuditService) Verify() (*AuditVerification, error) {
+	cps, err := s.store.ListAuditCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	res := &AuditVerification{Scope: "chain", Checkpoints: len(cps)}
+	fail := func(seq int64, reason string) {
+		if res.Broken == nil || seq < res.Broken.Seq {
+			res.Broken = &AuditBreak{Seq: seq, Reason: reason}
+		}
+	}
+	for _, cp := range cps {
+		if !s.verifyCheckpoint(cp) {
+			fail(cp.Seq, "checkpoint is not signed by a trusted key")
+		}
+	}
+	if n := len(cps); n > 0 {
+		res.LastCheckpoint = cps[n-1]
+	}
+	next, prev, intact := 0, "", true
+	err = s.walk(func(e AuditEntry) bool {
+		res.Entries++
+		reason := ""
+		switch {
+		case next < len(cps) && cps[next].Seq < e.Seq:
+			fail(cps[next].Seq, "checkpointed entry is missing")
+			intact = false
+			return false
+		case e.Hash == "":
+			reason = "entry is not chained"
+		case e.PrevHash != prev:
+			reason = "previous hash does not match; an earlier entry was removed or altered"
+		case AuditHash(e) != e.Hash:
+			reason = "content hash does not match; the entry was altered"
+		case next < len(cps) && cps[next].Seq == e.Seq && cps[next].Hash != e.Hash:
+			reason = "hash differs from the signed checkpoint"
+		}
+		if reason != "" {
+			fail(e.Seq, reason)
+			intact = false
+			return false
+		}
+		if next < len(cps) && cps[next].Seq == e.Seq {
+			next++
+		}
+		prev, res.HeadSeq, res.HeadHash = e.Hash, e.Seq, e.Hash
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if intact && next < len(cps) {
+		fail(cps[next].Seq, "checkpointed entry is missing; the log was truncated")
+	}
+	res.OK = res.Broken == nil
+	return res, nil
+}
+
+// VerifyTenant verifies the whole chain but reports only on the entries visible selects: how many
+// there are, the last of them, and a break only when it falls at or before one of them, so a
+// tenant learns whether its own entries are intact without seeing the rest of the log.
+func (s *AuditService) VerifyTenant(visible func(AuditEntry) bool) (*AuditVerification, error) {
+	full, err := s.Verify()
+	if err != nil {
+		return nil, err
+	}
+	res := &AuditVerification{Scope: "tenant", Checkpoints: full.Checkpoints, LastCheckpoint: full.LastCheckpoint}
+	err = s.walk(func(e AuditEntry) bool {
+		if !visible(e) {
+			return true
+		}
+		res.Entries++
+		res.HeadSeq, res.HeadHash = e.Seq, e.Hash
+		if res.Broken == nil && full.Broken != nil && e.Seq >= full.Broken.Seq {
+			if e.Seq == full.Broken.Seq {
+				res.Broken = full.Broken
+			} else {
+				res.Broken = &AuditBreak{Seq: e.Seq, Reason: "an earlier entry of the log was removed or altered"}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.OK = res.Broken == nil
+	return res, nil
+}
+
+// verifyCheckpoint checks cp against the trusted keys; the public key stored with it only picks
+// which one, as it is as easy to rewrite as the log.
+func (s *AuditService) verifyCheckpoint(cp *AuditCheckpoint) bool {
+	sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return false
+	}
+	for _, pub := range s.trusted {
+		if base64.StdEncoding.EncodeToString(pub) == cp.PublicKey {
+			return ed25519.Verify(pub, AuditCheckpointMessage(cp), sig)
+		}
+	}
+	return false
+}
+
+// walk calls fn for every entry in order until fn returns false.
+func (s *AuditService) walk(fn func(AuditEntry) bool) error {
+	var after int64
+	for {
+		page, err := s.store.ListAuditChain(after, auditPageSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range page {
+			if !fn(e) {
+				return nil
+			}
+			after = e.Seq
+		}
+		if len(page) < auditPageSize {
+			return nil
+		}
+	}
+}
+
+type auditExportEntry struct {
+	Type string `json:"type"`
+	auditContent
+	Hash string `json:"hash"`
+}
+
+// auditExportLink stands in for an entry the reader may not see: its hashes keep the chain
+// connected from the reader's entries to the signed checkpoints without disclosing the content.
+type auditExportLink struct {
+	Type     string `json:"type"`
+	Seq      int64  `json:"seq"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash"`
+}
+
+// Export writes the audit trail as JSON Lines: a header with the verification result and the
+// server's public key, the chain in order, then the signed checkpoints. With a nil visible it is
+// the whole chain for the operator; otherwise the entries visible selects are written in full,
+// every other one as a link with only its hashes, and the header carries VerifyTenant.
+func (s *AuditService) Export(w io.Writer, visible func(AuditEntry) bool) error {
+	var (
+		report *AuditVerification
+		err    error
+	)
+	if visible == nil {
+		visible = func(AuditEntry) bool { return true }
+		report, err = s.Verify()
+	} else {
+		report, err = s.VerifyTenant(visible)
+	}
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(map[string]any{"type": "header", "format": AuditExportFormat, "generated_at": s.now().UTC(),
+		"public_key": s.publicKey(), "verification": report}); err != nil {
+		return err
+	}
+	var werr error
+	err = s.walk(func(e AuditEntry) bool {
+		if visible(e) {
+			werr = enc.Encode(auditExportEntry{Type: "entry", auditContent: newAuditContent(e), Hash: e.Hash})
+		} else {
+			werr = enc.Encode(auditExportLink{Type: "link", Seq: e.Seq, PrevHash: e.PrevHash, Hash: e.Hash})
+		}
+		return werr == nil
+	})
+	if err == nil {
+		err = werr
+	}
+	if err != nil {
+		return err
+	}
+	cps, err := s.store.ListAuditCheckpoints()
+	if err != nil {
+		return err
+	}
+	for _, cp := range cps {
+		if err := enc.Encode(struct {
+			Type string `json:"type"`
+			*AuditCheckpoint
+		}{"checkpoint", cp}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the filters and clamps Limit to the page size.
+func (q *AuditQuery) Validate() error {
+	switch q.Source {
+	case "", auditSourceAdmin, auditSourceSubjects:
+	default:
+		return NewInvalidError("source must be admin or participant")
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return NewInvalidError("from must be before to")
+	}
+	if q.Limit <= 0 {
+		q.Limit = auditDefaultLimit
+	}
+	if q.Limit > auditPageSize {
+		q.Limit = auditPageSize
+	}
+	return nil
+}
+
+// Search returns one page of the tenant's audit log, newest first.
+func (s *AuditService) Search(q AuditQuery) (*AuditPage, error) {
+	if q.TenantID == "" {
+		return nil, NewUnauthorizedError("tenant required")
+	}
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	q.Limit++
+	list, err := s.store.QueryAudit(q)
+	if err != nil {
+		return nil, err
+	}
+	page := &AuditPage{Entries: list}
+	if len(list) > limit {
+		page.Entries = list[:limit]
+		page.NextCursor = strconv.FormatInt(list[limit-1].Seq, 10)
+	}
+	return page, nil
+}
+
+// ExportFiltered writes every entry matching q, newest first, as "csv" or "jsonl". Unlike
+// Export it carries no proofs; the chain hashes are included for reference.
+func (s *AuditService) ExportFiltered(w io.Writer, format string, q AuditQuery) error {
+	if q.TenantID == "" {
+		return NewUnauthorizedError("tenant required")
+	}
+	if format != "csv" && format != "jsonl" {
+		return NewInvalidError("format must be csv or jsonl")
+	}
+	q.Limit = auditPageSize
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	var (
+		cw  *csv.Writer
+		enc = json.NewEncoder(w)
+	)
+	if format == "csv" {
+		cw = csv.NewWriter(w)
+		if err := cw.Write([]string{"seq", "time", "tenant_id", "actor", "actor_id", "action", "target", "note", "ip", "user_agent", "result", "hash"}); err != nil {
+			return err
+		}
+	}
+	for {
+		list, err := s.store.QueryAudit(q)
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			if cw != nil {
+				err = cw.Write([]string{strconv.FormatInt(e.Seq, 10), e.Time.UTC().Format(time.RFC3339Nano), e.TenantID, csvSafe(e.Actor), e.ActorID,
+					csvSafe(e.Action), csvSafe(e.Target), csvSafe(e.Note), e.IP, csvSafe(e.UserAgent), e.Result, e.Hash})
+			} else {
+				err = enc.Encode(e)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(list) < q.Limit {
+			break
+		}
+		q.Before = list[len(list)-1].Seq
+	}
+	if cw != nil {
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+// csvSafe keeps spreadsheet apps from evaluating logged values (user agents, notes) as formulas.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type auditStubStore struct {
+	entries     []AuditEntry
+	checkpoints []*AuditCheckpoint
+}
+
+func (s *auditStubStore) add(actor, action, target string) {
+	e := AuditEntry{Time: time.Date(2025, 1, 1, 0, 0, len(s.entries), 0, time.UTC), Actor: actor, Action: action, Target: target, Seq: int64(len(s.entries) + 1)}
+	if n := len(s.entries); n > 0 {
+		e.PrevHash = s.entries[n-1].Hash
+	}
+	e.Hash = AuditHash(e)
+	s.entries = append(s.entries, e)
+}
+
+// rechain rehashes every entry, as if the log had been written with its current content.
+func (s *auditStubStore) rechain() {
+	prev := ""
+	for i := range s.entries {
+		s.entries[i].PrevHash = prev
+		s.entries[i].Hash = AuditHash(s.entries[i])
+		prev = s.entries[i].Hash
+	}
+}
+
+func (s *auditStubStore) ListAuditChain(afterSeq int64, limit int) ([]AuditEntry, error) {
+	out := []AuditEntry{}
+	for _, e := range s.entries {
+		if e.Seq > afterSeq && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *auditStubStore) QueryAudit(q AuditQuery) ([]AuditEntry, error) {
+	out := []AuditEntry{}
+	for i := len(s.entries) - 1; i >= 0 && len(out) < q.Limit; i-- {
+		if e := s.entries[i]; (q.Before == 0 || e.Seq < q.Before) && q.Matches(e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *auditStubStore) AuditHead() (*AuditEntry, error) {
+	if len(s.entries) == 0 {
+		return nil, nil
+	}
+	e := s.entries[len(s.entries)-1]
+	return &e, nil
+}
+
+func (s *auditStubStore) SaveAuditCheckpoint(cp *AuditCheckpoint) error {
+	s.checkpoints = append(s.checkpoints, cp)
+	return nil
+}
+
+func (s *auditStubStore) ListAuditCheckpoints() ([]*AuditCheckpoint, error) {
+	return s.checkpoints, nil
+}
+
+func newTestAudit(t *testing.T) (*AuditService, *auditStubStore) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &auditStubStore{}
+	for i := 0; i < 5; i++ {
+		store.add("a@example.com", "update_scale", "S1")
+	}
+	return NewAuditService(store, key), store
+}
+
+func expectBroken(t *testing.T, svc *AuditService, seq int64) {
+	t.Helper()
+	res, err := svc.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OK || res.Broken == nil || res.Broken.Seq != seq {
+		t.Fatalf("expected break at %d, got %+v", seq, res)
+	}
+}
+
+func TestAuditChainVerifies(t *testing.T) {
+	svc, store := newTestAudit(t)
+	cp, err := svc.Checkpoint()
+	if err != nil || cp == nil || cp.Seq != 5 {
+		t.Fatalf("Checkpoint = %+v, %v", cp, err)
+	}
+	if again, err := svc.Checkpoint(); err != nil || again != nil {
+		t.Fatalf("no new entries, no new checkpoint: %+v, %v", again, err)
+	}
+	store.add("b@example.com", "delete_scale", "S2")
+	res, err := svc.Verify()
+	if err != nil || !res.OK || res.Entries != 6 || res.HeadSeq != 6 || res.Checkpoints != 1 {
+		t.Fatalf("Verify = %+v, %v", res, err)
+	}
+
+	// a verifier without the key trusts no checkpoint; one given the public key does
+	if res, _ := NewAuditService(store, nil).Verify(); res.OK || res.Broken.Seq != 5 {
+		t.Fatalf("untrusted checkpoint: %+v", res)
+	}
+	pub := svc.key.Public().(ed25519.PublicKey)
+	if res, _ := NewAuditService(store, nil).WithTrustedKeys(pub).Verify(); !res.OK {
+		t.Fatalf("trusted key: %+v", res)
+	}
+}
+
+func TestAuditCheckpointSignedByAnotherKeyBreaks(t *testing.T) {
+	svc, store := newTestAudit(t)
+	if _, err := svc.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	// rewrite the log and re-sign the checkpoint with a key of one's own
+	store.entries[1].Note = "changed"
+	store.rechain()
+	_, forged, _ := ed25519.GenerateKey(rand.Reader)
+	store.checkpoints = nil
+	if _, err := NewAuditService(store, forged).Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	res, err := svc.Verify()
+	if err != nil || res.OK || res.Broken.Seq != 5 || !strings.Contains(res.Broken.Reason, "trusted key") {
+		t.Fatalf("re-signed checkpoint: %+v, %v", res, err)
+	}
+
+	// naming our public key with a forged signature does not help either
+	store.checkpoints[0].PublicKey = svc.publicKey()
+	if res, _ := svc.Verify(); res.OK {
+		t.Fatalf("forged signature accepted: %+v", res)
+	}
+}
+
+func TestAuditCheckpointNeedsSigningKey(t *testing.T) {
+	_, store := newTestAudit(t)
+	svc := NewAuditService(store, nil)
+	if _, err := svc.Checkpoint(); err == nil {
+		t.Fatal("checkpoint without a key")
+	}
+	var got error
+	svc.Run(context.Background(), time.Millisecond, func(err error) { got = err })
+	if got == nil || len(store.checkpoints) != 0 {
+		t.Fatalf("Run without a key = %v, %d checkpoints", got, len(store.checkpoints))
+	}
+}
+
+func TestAuditChainReportsFirstBrokenLink(t *testing.T) {
+	svc, store := newTestAudit(t)
+	if _, err := svc.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := append([]AuditEntry(nil), store.entries...)
+	store.entries[2].Note = "changed"
+	expectBroken(t, svc, 3)
+
+	// rehashing the edited entry breaks the link from its successor instead
+	store.entries[2].Hash = AuditHash(store.entries[2])
+	expectBroken(t, svc, 4)
+
+	store.entries = append(append([]AuditEntry(nil), edited[:1]...), edited[2:]...)
+	expectBroken(t, svc, 3)
+
+	store.entries = edited[:4]
+	expectBroken(t, svc, 5)
+
+	store.entries = edited
+	store.checkpoints[0].Hash = edited[3].Hash
+	expectBroken(t, svc, 5)
+}
+
+func TestAuditExportScopesToVisibleEntries(t *testing.T) {
+	svc, store := newTestAudit(t)
+	store.add("b@example.com", "delete_scale", "S2")
+	store.add("b@example.com", "delete_scale", "S2")
+	store.entries[2].Target = "S3" // a foreign entry between the visible ones
+	store.rechain()
+	if _, err := svc.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := svc.Export(&buf, func(e AuditEntry) bool { return e.Target == "S1" }); err != nil {
+		t.Fatal(err)
+	}
+	types, prev := []string{}, ""
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, line["type"].(string))
+		if line["type"] == "link" && (line["target"] != nil || line["actor"] != nil) {
+			t.Fatalf("link discloses content: %s", sc.Text())
+		}
+		if line["type"] == "entry" || line["type"] == "link" {
+			// every line continues the chain of the one before
+			if p, _ := line["prev_hash"].(string); p != prev {
+				t.Fatalf("chain disconnected at %s", sc.Text())
+			}
+			prev = line["hash"].(string)
+		}
+		if line["type"] == "checkpoint" && line["hash"] != prev {
+			t.Fatalf("checkpoint does not match the chain head: %s", sc.Text())
+		}
+		if line["type"] == "entry" && line["target"] != "S1" {
+			t.Fatalf("export discloses another entry: %s", sc.Text())
+		}
+		if line["type"] == "header" {
+			v := line["verification"].(map[string]any)
+			if !v["ok"].(bool) || v["scope"] != "tenant" || v["entries"].(float64) != 4 || v["head_seq"].(float64) != 5 {
+				t.Fatalf("header must carry the scoped verification: %s", sc.Text())
+			}
+		}
+		if line["type"] == "entry" {
+			// the entry line minus type and hash is the hashed content
+			delete(line, "type")
+			hash := line["hash"]
+			delete(line, "hash")
+			raw, _ := json.Marshal(line)
+			var content auditContent
+			_ = json.Unmarshal(raw, &content)
+			ts, _ := time.Parse(time.RFC3339Nano, content.Time)
+			e := AuditEntry{Seq: content.Seq, Time: ts, Actor: content.Actor, Action: content.Action, Target: content.Target, Note: content.Note, PrevHash: content.PrevHash}
+			if AuditHash(e) != hash {
+				t.Fatalf("exported entry does not match its hash: %s", sc.Text())
+			}
+		}
+	}
+	if got := strings.Join(types, ","); got != "header,entry,entry,link,entry,entry,link,link,checkpoint" {
+		t.Fatalf("export lines = %s", got)
+	}
+
+	// the operator's export holds the whole chain
+	buf.Reset()
+	if err := svc.Export(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 9 || !strings.Contains(lines[0], `"scope":"chain"`) || !strings.Contains(lines[6], `"delete_scale"`) {
+		t.Fatalf("chain export = %s", buf.String())
+	}
+}
+
+func TestAuditVerifyTenantReportsOwnSegment(t *testing.T) {
+	svc, store := newTestAudit(t)
+	for i := range store.entries {
+		store.entries[i].TenantID = "T1"
+	}
+	store.add("b@example.com", "delete_scale", "S2") // seq 6, another tenant
+	store.entries[5].TenantID = "T2"
+	store.rechain()
+	own := func(e AuditEntry) bool { return e.TenantID == "T1" }
+	res, err := svc.VerifyTenant(own)
+	if err != nil || !res.OK || res.Scope != "tenant" || res.Entries != 5 || res.HeadSeq != 5 {
+		t.Fatalf("VerifyTenant = %+v, %v", res, err)
+	}
+
+	// a break after the tenant's last entry is not its concern
+	store.entries[5].Note = "changed"
+	if res, _ := svc.VerifyTenant(own); !res.OK {
+		t.Fatalf("break outside the segment reported: %+v", res)
+	}
+	if res, _ := svc.VerifyTenant(func(e AuditEntry) bool { return e.TenantID == "T2" }); res.OK || res.Broken.Seq != 6 {
+		t.Fatalf("break of the tenant's own entry: %+v", res)
+	}
+	store.entries[5].Note = ""
+
+	// a break before a tenant entry is reported at that entry without naming the foreign one
+	store.entries[1].TenantID = "T2"
+	res, _ = svc.VerifyTenant(own)
+	if res.OK || res.Broken.Seq != 3 || res.Entries != 4 {
+		t.Fatalf("break before the segment: %+v", res)
+	}
+}
+
+func TestAuditSearchFiltersAndPages(t *testing.T) {
+	svc, store := newTestAudit(t)
+	for i := range store.entries {
+		store.entries[i].TenantID = "T1"
+	}
+	store.add("participant", "submit_response", "S1") // seq 6, legacy entry without a tenant
+	store.add("b@example.com", "login", "U2")         // seq 7
+	store.entries[6].TenantID, store.entries[6].ActorID, store.entries[6].UserAgent = "T2", "U2", "=cmd()"
+
+	page, err := svc.Search(AuditQuery{TenantID: "T1", Limit: 2})
+	if err != nil || len(page.Entries) != 2 || page.Entries[0].Seq != 5 || page.NextCursor != "4" {
+		t.Fatalf("first page = %+v, %v", page, err)
+	}
+	page, _ = svc.Search(AuditQuery{TenantID: "T1", Before: 2, Limit: 2})
+	if len(page.Entries) != 1 || page.Entries[0].Seq != 1 || page.NextCursor != "" {
+		t.Fatalf("last page = %+v", page)
+	}
+	page, _ = svc.Search(AuditQuery{TenantID: "T1", LegacyTargets: []string{"S1"}, Source: "participant"})
+	if len(page.Entries) != 1 || page.Entries[0].Seq != 6 {
+		t.Fatalf("legacy participant entry = %+v", page)
+	}
+	page, _ = svc.Search(AuditQuery{TenantID: "T2", Actor: "U2", From: store.entries[6].Time, To: store.entries[6].Time.Add(time.Second)})
+	if len(page.Entries) != 1 || page.Entries[0].Action != "login" {
+		t.Fatalf("tenant T2 = %+v", page)
+	}
+	if _, err := svc.Search(AuditQuery{TenantID: "T1", Source: "robots"}); err == nil {
+		t.Fatal("unknown source must be rejected")
+	}
+
+	var buf bytes.Buffer
+	if err := svc.ExportFiltered(&buf, "csv", AuditQuery{TenantID: "T2"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], ",'=cmd(),") {
+		t.Fatalf("csv export = %q", buf.String())
+	}
+}
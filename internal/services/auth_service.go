@@ -22,12 +22,19 @@ type AuthService struct {
 	idGen     func(prefix string, n int) string
 	signToken TokenSigner
 	tokenTTL  time.Duration
+	sessions  *SessionService
+	guard     *LoginGuard
+	members   *MembershipService
 }
 
+// AuthResult is a successful sign-in. With sessions enabled Token is a short-lived access token
+// and RefreshToken renews it.
 type AuthResult struct {
-	Token    string
-	TenantID string
-	UserID   string
+	Token        string
+	RefreshToken string
+	SessionID    string
+	TenantID     string
+	UserID       string
 }
 
 func NewAuthService(store AuthStore, signer TokenSigner) *AuthService {
@@ -40,7 +47,23 @@ func NewAuthService(store AuthStore, signer TokenSigner) *AuthService {
 	}
 }
 
-func (s *AuthService) Register(email, password, tenantName string) (*AuthResult, error) {
+// WithSessions issues session-bound access and refresh tokens instead of long-lived tokens.
+func (s *AuthService) WithSessions(sessions *SessionService) {
+	s.sessions = sessions
+}
+
+// WithLoginGuard throttles and audits failed password sign-ins.
+func (s *AuthService) WithLoginGuard(guard *LoginGuard) {
+	s.guard = guard
+}
+
+// WithMemberships records tenant roles for new accounts and starts sign-ins in a tenant the user
+// still belongs to.
+func (s *AuthService) WithMemberships(members *MembershipService) {
+	s.members = members
+}
+
+func (s *AuthService) Register(email, password, tenantName string, client SessionClient) (*AuthResult, error) {
 	email = strings.TrimSpace(email)
 	if email == "" || strings.TrimSpace(password) == "" {
 		return nil, NewInvalidError("email/password required")
@@ -73,19 +96,17 @@ func (s *AuthService) Register(email, password, tenantName string) (*AuthResult,
 		_ = s.store.DeleteTenant(tenantID)
 		return nil, err
 	}
-	if s.signToken == nil {
-		return nil, NewInvalidError("token signer not configured")
-	}
-	token, err := s.signToken(userID, tenantID, email, s.tokenTTL)
-	if err != nil {
-		return nil, err
+	if s.members != nil {
+		if err := s.members.Grant(tenantID, &User{ID: userID, Email: email, TenantID: tenantID}, TenantRoleOwner, email); err != nil {
+			return nil, err
+		}
 	}
-	return &AuthResult{Token: token, TenantID: tenantID, UserID: userID}, nil
+	return s.issue(userID, tenantID, email, client)
 }
 
-// RegisterWithTenant creates a user under an existing tenant (used for invitations).
-// It does not create a new tenant.
-func (s *AuthService) RegisterWithTenant(email, password, tenantID string) (*AuthResult, error) {
+// RegisterWithTenant creates a user under an existing tenant with the given tenant role (used for
+// invitations). It does not create a new tenant.
+func (s *AuthService) RegisterWithTenant(email, password, tenantID, role string, client SessionClient) (*AuthResult, error) {
 	email = strings.TrimSpace(email)
 	if email == "" || strings.TrimSpace(password) == "" {
 		return nil, NewInvalidError("email/password required")
@@ -109,42 +130,91 @@ func (s *AuthService) RegisterWithTenant(email, password, tenantID string) (*Aut
 	if err := s.store.AddUser(&User{ID: userID, Email: email, PassHash: hash, TenantID: tenantID, CreatedAt: now}); err != nil {
 		return nil, err
 	}
-	if s.signToken == nil {
-		return nil, NewInvalidError("token signer not configured")
-	}
-	token, err := s.signToken(userID, tenantID, email, s.tokenTTL)
-	if err != nil {
-		return nil, err
+	if s.members != nil {
+		if role = normalizeTenantRole(role); role == "" {
+			role = TenantRoleMember
+		}
+		if err := s.members.Grant(tenantID, &User{ID: userID, Email: email, TenantID: tenantID}, role, "system:invite"); err != nil {
+			return nil, err
+		}
 	}
-	return &AuthResult{Token: token, TenantID: tenantID, UserID: userID}, nil
+	return s.issue(userID, tenantID, email, client)
 }
 
-func (s *AuthService) Login(email, password string) (*AuthResult, error) {
+func (s *AuthService) Login(email, password string, client SessionClient) (*AuthResult, error) {
 	email = strings.TrimSpace(email)
 	if email == "" || strings.TrimSpace(password) == "" {
 		return nil, NewInvalidError("email/password required")
 	}
+	if s.guard != nil {
+		if err := s.guard.Check(email, client.IP); err != nil {
+			return nil, err
+		}
+	}
 	u, err := s.store.FindUserByEmail(email)
 	if err != nil {
 		return nil, err
 	}
 	if u == nil {
-		return nil, NewUnauthorizedError("invalid credentials")
+		return nil, s.loginFailed(email, client, "unknown account")
 	}
 	if err := bcrypt.CompareHashAndPassword(u.PassHash, []byte(password)); err != nil {
-		return nil, NewUnauthorizedError("invalid credentials")
+		return nil, s.loginFailed(email, client, "wrong password")
+	}
+	if s.guard != nil {
+		if err := s.guard.Success(u.Email); err != nil {
+			return nil, err
+		}
+	}
+	tenantID := u.TenantID
+	if s.members != nil {
+		if tenantID, err = s.members.SignInTenant(u); err != nil {
+			return nil, err
+		}
+	}
+	return s.issue(u.ID, tenantID, u.Email, client)
+}
+
+func (s *AuthService) loginFailed(email string, client SessionClient, reason string) error {
+	if s.guard != nil {
+		if err := s.guard.Failure(email, client.IP, reason); err != nil {
+			return err
+		}
+	}
+	return NewUnauthorizedError("invalid credentials")
+}
+
+// IssueFor signs in a user authenticated elsewhere (single sign-on).
+func (s *AuthService) IssueFor(userID, tenantID, email string, client SessionClient) (*AuthResult, error) {
+	if userID == "" || tenantID == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	return s.issue(userID, tenantID, email, client)
+}
+
+func (s *AuthService) issue(userID, tenantID, email string, client SessionClient) (*AuthResult, error) {
+	if s.sessions != nil {
+		st, err := s.sessions.Start(userID, tenantID, email, client)
+		if err != nil {
+			return nil, err
+		}
+		return &AuthResult{Token: st.AccessToken, RefreshToken: st.RefreshToken, SessionID: st.SessionID, TenantID: tenantID, UserID: userID}, nil
 	}
 	if s.signToken == nil {
 		return nil, NewInvalidError("token signer not configured")
 	}
-	token, err := s.signToken(u.ID, u.TenantID, u.Email, s.tokenTTL)
+	token, err := s.signToken(userID, tenantID, email, s.tokenTTL)
 	if err != nil {
 		return nil, err
 	}
-	return &AuthResult{Token: token, TenantID: u.TenantID, UserID: u.ID}, nil
+	return &AuthResult{Token: token, TenantID: tenantID, UserID: userID}, nil
 }
 
+// TokenTTL is the lifetime of the token in AuthResult.Token.
 func (s *AuthService) TokenTTL() time.Duration {
+	if s.sessions != nil {
+		return s.sessions.AccessTTL()
+	}
 	return s.tokenTTL
 }
 
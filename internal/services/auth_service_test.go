@@ -51,7 +51,7 @@ func TestAuthRegisterAndLogin(t *testing.T) {
 	svc.now = func() time.Time { return time.Unix(0, 0) }
 	svc.idGen = func(prefix string, n int) string { return prefix + "1234567" }
 
-	res, err := svc.Register("user@example.com", "Secret123", "Acme")
+	res, err := svc.Register("user@example.com", "Secret123", "Acme", SessionClient{})
 	if err != nil {
 		t.Fatalf("Register returned error: %v", err)
 	}
@@ -62,11 +62,11 @@ func TestAuthRegisterAndLogin(t *testing.T) {
 		t.Fatalf("unexpected token %q", res.Token)
 	}
 
-	if _, err = svc.Register("user@example.com", "Secret123", "Acme"); err == nil {
+	if _, err = svc.Register("user@example.com", "Secret123", "Acme", SessionClient{}); err == nil {
 		t.Fatalf("expected conflict error on duplicate registration")
 	}
 
-	loginRes, err := svc.Login("user@example.com", "Secret123")
+	loginRes, err := svc.Login("user@example.com", "Secret123", SessionClient{})
 	if err != nil {
 		t.Fatalf("Login returned error: %v", err)
 	}
@@ -74,10 +74,10 @@ func TestAuthRegisterAndLogin(t *testing.T) {
 		t.Fatalf("expected token in login response")
 	}
 
-	if _, err := svc.Login("user@example.com", "wrong"); err == nil {
+	if _, err := svc.Login("user@example.com", "wrong", SessionClient{}); err == nil {
 		t.Fatalf("expected error for wrong password")
 	}
-	if _, err := svc.Login("missing@example.com", "Secret123"); err == nil {
+	if _, err := svc.Login("missing@example.com", "Secret123", SessionClient{}); err == nil {
 		t.Fatalf("expected error for missing user")
 	}
 }
@@ -88,10 +88,10 @@ func TestAuthValidation(t *testing.T) {
 		return "tok", nil
 	})
 
-	if _, err := svc.Register("", "", ""); err == nil {
+	if _, err := svc.Register("", "", "", SessionClient{}); err == nil {
 		t.Fatalf("expected validation error")
 	}
-	if _, err := svc.Login("", ""); err == nil {
+	if _, err := svc.Login("", "", SessionClient{}); err == nil {
 		t.Fatalf("expected validation error on login")
 	}
 }
@@ -99,13 +99,13 @@ func TestAuthValidation(t *testing.T) {
 func TestAuthRegisterStrengthAndEmail(t *testing.T) {
 	store := newAuthStubStore()
 	svc := NewAuthService(store, func(uid, tid, email string, ttl time.Duration) (string, error) { return "tok", nil })
-	if _, err := svc.Register("invalid-email", "Secret123", "Acme"); err == nil {
+	if _, err := svc.Register("invalid-email", "Secret123", "Acme", SessionClient{}); err == nil {
 		t.Fatalf("expected invalid email format error")
 	}
-	if _, err := svc.Register("user@example.com", "weak", "Acme"); err == nil {
+	if _, err := svc.Register("user@example.com", "weak", "Acme", SessionClient{}); err == nil {
 		t.Fatalf("expected weak password error")
 	}
-	if _, err := svc.Register("user@example.com", "Strong123", "Acme"); err != nil {
+	if _, err := svc.Register("user@example.com", "Strong123", "Acme", SessionClient{}); err != nil {
 		t.Fatalf("unexpected error for valid creds: %v", err)
 	}
 }
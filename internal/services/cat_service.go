@@ -0,0 +1,542 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCATSETarget = 0.3
+
+	CATStatusActive = "active"
+	// CATStatusFinishing marks a session whose responses are being stored.
+	CATStatusFinishing = "finishing"
+	CATStatusFinished  = "finished"
+
+	CATStopSE            = "se_target"
+	CATStopMaxItems      = "max_items"
+	CATStopPoolExhausted = "pool_exhausted"
+)
+
+// CATStore persists adaptive testing settings and sessions.
+type CATStore interface {
+	GetScale(id string) (*Scale, error)
+	ListItems(scaleID string) ([]*Item, error)
+	GetIRTCalibration(scaleID string, version int) (*IRTCalibration, error)
+	ListIRTCalibrations(scaleID string) ([]*IRTCalibration, error)
+	GetCATSettings(scaleID string) (*CATSettings, error)
+	SaveCATSettings(s *CATSettings) error
+	GetCATSession(id string) (*CATSession, error)
+	// SaveCATSession stores sess only while the stored session still has prevStatus and prevSteps
+	// answers; an empty prevStatus only creates a new session. It reports false when another
+	// request changed the session first.
+	SaveCATSession(sess *CATSession, prevStatus string, prevSteps int) (bool, error)
+	ListCATSessions(scaleID string) ([]*CATSession, error)
+}
+
+// CATSettings switches a scale to adaptive delivery. CalibrationVersion 0 follows the latest
+// calibration of the current scale version; SETarget stops once SE(θ) is at or below it (after
+// MinItems) and MaxItems (0 = whole pool) caps the test length.
+type CATSettings struct {
+	ScaleID            string    `json:"scale_id"`
+	Enabled            bool      `json:"enabled"`
+	CalibrationVersion int       `json:"calibration_version"`
+	MinItems           int       `json:"min_items"`
+	MaxItems           int       `json:"max_items"`
+	SETarget           float64   `json:"se_target"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// CATStep records one administered item: the answer, the information the item had at the θ it
+// was selected for, and the estimate after scoring it.
+type CATStep struct {
+	ItemID      string          `json:"item_id"`
+	Raw         json.RawMessage `json:"raw"`
+	Score       int             `json:"score"`
+	Information float64         `json:"information"`
+	Theta       float64         `json:"theta"`
+	SE          float64         `json:"se"`
+	AnsweredAt  time.Time       `json:"answered_at"`
+}
+
+// CATSession is the server-side state of one adaptive administration. The participant holds the
+// session token; only its hash is stored.
+type CATSession struct {
+	ID                 string    `json:"id"`
+	ScaleID            string    `json:"scale_id"`
+	TokenHash          string    `json:"-"`
+	CalibrationVersion int       `json:"calibration_version"`
+	Status             string    `json:"status"`
+	StopReason         string    `json:"stop_reason,omitempty"`
+	NextItemID         string    `json:"next_item_id,omitempty"`
+	NextInformation    float64   `json:"next_information,omitempty"`
+	Steps              []CATStep `json:"steps"`
+	Theta              float64   `json:"theta"`
+	SE                 float64   `json:"se"`
+	ParticipantID      string    `json:"participant_id,omitempty"`
+	ParticipantEmail   string    `json:"-"`
+	ConsentID          string    `json:"consent_id,omitempty"`
+	Condition          string    `json:"condition,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	FinishedAt         time.Time `json:"finished_at,omitempty"`
+}
+
+// CATStartRequest carries the participant details that ProcessBulkResponses needs once the
+// session finishes.
+type CATStartRequest struct {
+	ScaleID          string
+	Lang             string
+	ParticipantEmail string
+	ConsentID        string
+	Condition        string
+	TurnstileToken   string
+	VerifyTurnstile  func(token string) (bool, error)
+}
+
+// CATState is what the participant sees after each step. Token is only returned on start and
+// SelfToken only once the responses have been stored.
+type CATState struct {
+	SessionID     string         `json:"session_id"`
+	Token         string         `json:"token,omitempty"`
+	Status        string         `json:"status"`
+	StopReason    string         `json:"stop_reason,omitempty"`
+	Answered      int            `json:"answered"`
+	Theta         float64        `json:"theta"`
+	SE            float64        `json:"se"`
+	NextItem      *ScaleItemView `json:"next_item,omitempty"`
+	ParticipantID string         `json:"participant_id,omitempty"`
+	SelfToken     string         `json:"self_token,omitempty"`
+}
+
+// errCATRace is returned to the loser of two concurrent answers to one session.
+var errCATRace = NewConflictError("session was changed by another answer; reload it")
+
+// CATService runs computerized adaptive tests on calibrated Likert items. Finished sessions are
+// stored as regular responses through the bulk submission workflow.
+type CATService struct {
+	store     CATStore
+	responses *ResponseService
+	now       func() time.Time
+	newID     func() string
+	newToken  func() string
+}
+
+func NewCATService(store CATStore, responses *ResponseService) *CATService {
+	return &CATService{
+		store:     store,
+		responses: responses,
+		now:       func() time.Time { return time.Now().UTC() },
+		newID:     defaultParticipantID,
+		newToken:  defaultCATToken,
+	}
+}
+
+func defaultCATToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashCATToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *CATService) GetSettings(tenantID, scaleID string) (*CATSettings, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	cfg, err := s.store.GetCATSettings(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = &CATSettings{ScaleID: scaleID, SETarget: defaultCATSETarget}
+	}
+	return cfg, nil
+}
+
+// UpdateSettings validates and stores the CAT configuration; enabling requires a calibration.
+func (s *CATService) UpdateSettings(tenantID, scaleID string, in CATSettings) (*CATSettings, error) {
+	sc, err := s.authorizedScale(tenantID, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if in.MinItems < 0 || in.MaxItems < 0 || in.CalibrationVersion < 0 || in.SETarget < 0 {
+		return nil, NewInvalidError("cat settings must not be negative")
+	}
+	if in.MaxItems > 0 && in.MinItems > in.MaxItems {
+		return nil, NewInvalidError("min_items exceeds max_items")
+	}
+	if in.SETarget == 0 {
+		in.SETarget = defaultCATSETarget
+	}
+	if in.Enabled {
+		if sc.E2EEEnabled {
+			return nil, NewInvalidError("adaptive testing is not available for E2EE projects")
+		}
+		if _, err := s.calibration(sc, in.CalibrationVersion); err != nil {
+			return nil, err
+		}
+	}
+	in.ScaleID = scaleID
+	in.UpdatedAt = s.now()
+	if err := s.store.SaveCATSettings(&in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func (s *CATService) ListSessions(tenantID, scaleID string) ([]*CATSession, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	return s.store.ListCATSessions(scaleID)
+}
+
+// StartSession opens a session and selects the first item at the prior mean θ = 0.
+func (s *CATService) StartSession(req CATStartRequest) (*CATState, error) {
+	sc, err := s.store.GetScale(req.ScaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, NewNotFoundError("scale not found")
+	}
+	if err := requireTurnstileIfNeeded(sc, req.TurnstileToken, req.VerifyTurnstile); err != nil {
+		return nil, NewInvalidError(err.Error())
+	}
+	if sc.E2EEEnabled {
+		return nil, NewInvalidError(ErrPlaintextDisabled.Error())
+	}
+	cfg, err := s.store.GetCATSettings(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || !cfg.Enabled {
+		return nil, NewInvalidError("adaptive testing is not enabled for this scale")
+	}
+	cal, err := s.calibration(sc, cfg.CalibrationVersion)
+	if err != nil {
+		return nil, err
+	}
+	token := s.newToken()
+	now := s.now()
+	sess := &CATSession{
+		ID:                 s.newID(),
+		ScaleID:            sc.ID,
+		TokenHash:          hashCATToken(token),
+		CalibrationVersion: cal.Version,
+		Status:             CATStatusActive,
+		Steps:              []CATStep{},
+		SE:                 1,
+		ParticipantEmail:   strings.TrimSpace(req.ParticipantEmail),
+		ConsentID:          req.ConsentID,
+		Condition:          normalizeCondition(req.Condition),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	items, err := s.store.ListItems(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.advance(sc, cfg, cal, items, sess, nil, req.Lang)
+	if err != nil {
+		return nil, err
+	}
+	state.Token = token
+	return state, nil
+}
+
+// Answer scores the answer to the pending item, re-estimates θ and either selects the next item
+// or finishes the session and stores the responses.
+func (s *CATService) Answer(sessionID, token, lang string, ans BulkAnswer) (*CATState, error) {
+	sess, sc, err := s.session(sessionID, token)
+	if err != nil {
+		return nil, err
+	}
+	if sess.Status != CATStatusActive {
+		return nil, NewConflictError("session already finished")
+	}
+	if ans.ItemID != sess.NextItemID {
+		return nil, NewInvalidError("answer must be for the presented item " + sess.NextItemID)
+	}
+	items, err := s.store.ListItems(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	var item *Item
+	for _, it := range items {
+		if it.ID == ans.ItemID {
+			item = it
+		}
+	}
+	if item == nil {
+		return nil, NewInvalidError("item no longer exists")
+	}
+	now := s.now()
+	resp := buildResponseForItem(ans, item, sc.Points, now, "")
+	if resp.ScoreValue < 1 || resp.ScoreValue > sc.Points {
+		return nil, NewInvalidError("answer must be between 1 and the number of scale points")
+	}
+	raw := ans.Raw
+	if len(raw) == 0 {
+		raw = json.RawMessage(resp.RawJSON)
+	}
+	prev := *sess
+	prev.Steps = append([]CATStep(nil), sess.Steps...)
+	sess.Steps = append(sess.Steps, CATStep{ItemID: item.ID, Raw: raw, Score: resp.ScoreValue, Information: sess.NextInformation, AnsweredAt: now})
+	cfg, err := s.store.GetCATSettings(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = &CATSettings{ScaleID: sc.ID, SETarget: defaultCATSETarget}
+	}
+	cal, err := s.store.GetIRTCalibration(sc.ID, sess.CalibrationVersion)
+	if err != nil {
+		return nil, err
+	}
+	if cal == nil {
+		return nil, NewNotFoundError("calibration not found")
+	}
+	return s.advance(sc, cfg, cal, items, sess, &prev, lang)
+}
+
+// GetSession returns the current state, e.g. to resume after a page reload.
+func (s *CATService) GetSession(sessionID, token, lang string) (*CATState, error) {
+	sess, _, err := s.session(sessionID, token)
+	if err != nil {
+		return nil, err
+	}
+	state := catState(sess)
+	if sess.Status == CATStatusActive {
+		items, err := s.store.ListItems(sess.ScaleID)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			if it.ID == sess.NextItemID {
+				view := buildItemView(it, defaultLang(lang))
+				state.NextItem = &view
+			}
+		}
+	}
+	return state, nil
+}
+
+// advance re-estimates θ from the answers so far, applies the stopping rules and selects the
+// unadministered item with maximum Fisher information at the current estimate. prev is the
+// session as it was loaded (nil for a new one); it is only saved over if it is still stored.
+func (s *CATService) advance(sc *Scale, cfg *CATSettings, cal *IRTCalibration, items []*Item, sess, prev *CATSession, lang string) (*CATState, error) {
+	byID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	params := map[string]irtItem{}
+	for _, p := range cal.Items {
+		if _, ok := byID[p.ItemID]; ok {
+			params[p.ItemID] = irtItem{model: cal.Model, a: p.Discrimination, b: p.Thresholds}
+		}
+	}
+	answered := map[string]bool{}
+	pattern := make([]irtItem, 0, len(sess.Steps))
+	row := make([]int, 0, len(sess.Steps))
+	for _, st := range sess.Steps {
+		answered[st.ItemID] = true
+		if p, ok := params[st.ItemID]; ok {
+			pattern = append(pattern, p)
+			row = append(row, st.Score-1)
+		}
+	}
+	nodes, weights := irtQuadrature()
+	sess.Theta, sess.SE = irtEAP(irtProbTable(pattern, nodes), row, nodes, weights, make([]float64, len(nodes)))
+	if n := len(sess.Steps); n > 0 {
+		sess.Steps[n-1].Theta, sess.Steps[n-1].SE = sess.Theta, sess.SE
+	}
+
+	maxItems := cfg.MaxItems
+	if maxItems <= 0 || maxItems > len(params) {
+		maxItems = len(params)
+	}
+	next, info := "", -1.0
+	for _, p := range cal.Items {
+		it, ok := params[p.ItemID]
+		if !ok || answered[p.ItemID] {
+			continue
+		}
+		if v := it.information(sess.Theta); v > info {
+			next, info = p.ItemID, v
+		}
+	}
+	switch {
+	case len(sess.Steps) >= cfg.MinItems && len(sess.Steps) > 0 && sess.SE <= cfg.SETarget:
+		sess.StopReason = CATStopSE
+	case len(sess.Steps) >= maxItems:
+		sess.StopReason = CATStopMaxItems
+	case next == "":
+		sess.StopReason = CATStopPoolExhausted
+	}
+	sess.UpdatedAt = s.now()
+	if sess.StopReason != "" {
+		return s.finish(sc, sess, prev)
+	}
+	sess.NextItemID, sess.NextInformation = next, info
+	if err := s.save(sess, prev); err != nil {
+		return nil, err
+	}
+	state := catState(sess)
+	view := buildItemView(byID[next], defaultLang(lang))
+	state.NextItem = &view
+	return state, nil
+}
+
+// finish stores the responses once. An existing session is first claimed by moving it to
+// finishing, so of two concurrent final answers only one gets to store them; if storing fails
+// the session goes back to prev and the answer can be sent again.
+func (s *CATService) finish(sc *Scale, sess, prev *CATSession) (*CATState, error) {
+	sess.NextItemID, sess.NextInformation = "", 0
+	claimed := prev
+	if prev != nil {
+		sess.Status = CATStatusFinishing
+		if err := s.save(sess, prev); err != nil {
+			return nil, err
+		}
+		cp := *sess
+		claimed = &cp
+	}
+	answers := make([]BulkAnswer, 0, len(sess.Steps))
+	for _, st := range sess.Steps {
+		answers = append(answers, BulkAnswer{ItemID: st.ItemID, Raw: st.Raw})
+	}
+	res, err := s.responses.ProcessBulkResponses(BulkResponsesRequest{
+		ScaleID:          sc.ID,
+		ParticipantEmail: sess.ParticipantEmail,
+		ConsentID:        sess.ConsentID,
+		Condition:        sess.Condition,
+		Answers:          answers,
+		// Turnstile was verified when the session started
+		VerifyTurnstile: func(string) (bool, error) { return true, nil },
+	})
+	if err != nil {
+		if prev != nil {
+			_ = s.save(prev, claimed)
+		}
+		return nil, err
+	}
+	sess.Status = CATStatusFinished
+	sess.ParticipantID = res.ParticipantID
+	sess.ParticipantEmail = ""
+	sess.FinishedAt = sess.UpdatedAt
+	if err := s.save(sess, claimed); err != nil {
+		return nil, err
+	}
+	state := catState(sess)
+	state.SelfToken = res.SelfToken
+	return state, nil
+}
+
+// save stores sess if the stored session is still prev (nil for a new one).
+func (s *CATService) save(sess, prev *CATSession) error {
+	prevStatus, prevSteps := "", 0
+	if prev != nil {
+		prevStatus, prevSteps = prev.Status, len(prev.Steps)
+	}
+	ok, err := s.store.SaveCATSession(sess, prevStatus, prevSteps)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCATRace
+	}
+	return nil
+}
+
+func (s *CATService) session(sessionID, token string) (*CATSession, *Scale, error) {
+	sess, err := s.store.GetCATSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sess == nil || subtle.ConstantTimeCompare([]byte(sess.TokenHash), []byte(hashCATToken(token))) != 1 {
+		return nil, nil, NewNotFoundError("session not found")
+	}
+	sc, err := s.store.GetScale(sess.ScaleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sc == nil {
+		return nil, nil, NewNotFoundError("scale not found")
+	}
+	return sess, sc, nil
+}
+
+// calibration resolves the configured calibration; version 0 means the latest one fitted on the
+// current scale version.
+func (s *CATService) calibration(sc *Scale, version int) (*IRTCalibration, error) {
+	if version > 0 {
+		cal, err := s.store.GetIRTCalibration(sc.ID, version)
+		if err != nil {
+			return nil, err
+		}
+		if cal == nil {
+			return nil, NewInvalidError("calibration version not found")
+		}
+		return cal, nil
+	}
+	items, err := s.store.ListItems(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	cals, err := s.store.ListIRTCalibrations(sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	cal := latestIRTCalibration(cals, ScaleFingerprint(sc.Points, filterLikertItems(items)))
+	if cal == nil {
+		return nil, NewInvalidError("no IRT calibration for the current scale version")
+	}
+	return cal, nil
+}
+
+func (s *CATService) authorizedScale(tenantID, scaleID string) (*Scale, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	return sc, nil
+}
+
+func catState(sess *CATSession) *CATState {
+	return &CATState{
+		SessionID:     sess.ID,
+		Status:        sess.Status,
+		StopReason:    sess.StopReason,
+		Answered:      len(sess.Steps),
+		Theta:         roundTo(sess.Theta, 4),
+		SE:            roundTo(sess.SE, 4),
+		ParticipantID: sess.ParticipantID,
+	}
+}
+
+func roundTo(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
+
+func defaultLang(lang string) string {
+	if lang == "" {
+		return "en"
+	}
+	return lang
+}
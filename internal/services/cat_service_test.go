@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type stubCATStore struct {
+	stubIRTStore
+	mu       sync.Mutex
+	settings map[string]*CATSettings
+	sessions map[string]*CATSession
+}
+
+func (s *stubCATStore) GetIRTCalibration(scaleID string, version int) (*IRTCalibration, error) {
+	for _, c := range s.calibrations {
+		if c.ScaleID == scaleID && c.Version == version {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubCATStore) GetCATSettings(scaleID string) (*CATSettings, error) {
+	return s.settings[scaleID], nil
+}
+
+func (s *stubCATStore) SaveCATSettings(c *CATSettings) error {
+	cp := *c
+	s.settings[c.ScaleID] = &cp
+	return nil
+}
+
+func (s *stubCATStore) GetCATSession(id string) (*CATSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sess, ok := s.sessions[id]; ok {
+		cp := *sess
+		cp.Steps = append([]CATStep(nil), sess.Steps...)
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubCATStore) SaveCATSession(sess *CATSession, prevStatus string, prevSteps int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.sessions[sess.ID]; ok && (cur.Status != prevStatus || len(cur.Steps) != prevSteps) || !ok && prevStatus != "" {
+		return false, nil
+	}
+	cp := *sess
+	cp.Steps = append([]CATStep(nil), sess.Steps...)
+	s.sessions[sess.ID] = &cp
+	return true, nil
+}
+
+func (s *stubCATStore) ListCATSessions(scaleID string) ([]*CATSession, error) {
+	out := []*CATSession{}
+	for _, sess := range s.sessions {
+		if sess.ScaleID == scaleID {
+			out = append(out, sess)
+		}
+	}
+	return out, nil
+}
+
+func newCATFixture(t *testing.T) (*CATService, *stubCATStore, *stubBulkStore) {
+	t.Helper()
+	truth := make([]irtItem, 10)
+	for j := range truth {
+		shift := float64(j%5)*0.5 - 1
+		truth[j] = irtItem{a: 1 + float64(j%3)*0.6, b: []float64{shift - 1.5, shift - 0.5, shift + 0.5, shift + 1.5}}
+	}
+	items, responses, _ := simulateIRT(IRTModelGRM, truth, 400, 5)
+	scale := &Scale{ID: "S1", TenantID: "T1", Points: 5}
+	cal, err := CalibrateIRT(5, items, responses, IRTOptions{})
+	if err != nil {
+		t.Fatalf("CalibrateIRT: %v", err)
+	}
+	cal.ScaleID, cal.Version = "S1", 1
+	store := &stubCATStore{
+		stubIRTStore: stubIRTStore{
+			stubAnalyticsStore: stubAnalyticsStore{scale: scale, items: items},
+			calibrations:       []*IRTCalibration{cal},
+		},
+		settings: map[string]*CATSettings{},
+		sessions: map[string]*CATSession{},
+	}
+	bulk := &stubBulkStore{scale: scale, items: map[string]*Item{}}
+	for _, it := range items {
+		bulk.items[it.ID] = it
+	}
+	n := 0
+	svc := NewCATService(store, NewResponseService(bulk))
+	svc.newID = func() string { n++; return fmt.Sprintf("sess%d", n) }
+	svc.newToken = func() string { return "secret" }
+	return svc, store, bulk
+}
+
+func TestCATSessionRunsToStoppingRule(t *testing.T) {
+	svc, store, bulk := newCATFixture(t)
+	if _, err := svc.StartSession(CATStartRequest{ScaleID: "S1"}); err == nil {
+		t.Fatalf("expected error while CAT is disabled")
+	}
+	if _, err := svc.UpdateSettings("T2", "S1", CATSettings{Enabled: true}); err == nil {
+		t.Fatalf("expected forbidden for another tenant")
+	}
+	if _, err := svc.UpdateSettings("T1", "S1", CATSettings{Enabled: true, MaxItems: 6, SETarget: 0.2}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+
+	state, err := svc.StartSession(CATStartRequest{ScaleID: "S1", Condition: "A"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if state.Token != "secret" || state.NextItem == nil || state.Status != CATStatusActive {
+		t.Fatalf("unexpected start state %+v", state)
+	}
+	// the first item is the most informative one at θ = 0
+	cal := store.calibrations[0]
+	best, bestInfo := "", -1.0
+	for _, p := range cal.Items {
+		if v := (irtItem{model: cal.Model, a: p.Discrimination, b: p.Thresholds}).information(0); v > bestInfo {
+			best, bestInfo = p.ItemID, v
+		}
+	}
+	if state.NextItem.ID != best {
+		t.Fatalf("first item %s, want max-information item %s", state.NextItem.ID, best)
+	}
+
+	if _, err := svc.Answer(state.SessionID, "wrong", "", BulkAnswer{ItemID: best, RawInt: intPtr(5)}); err == nil {
+		t.Fatalf("expected error for a wrong token")
+	}
+	if _, err := svc.Answer(state.SessionID, "secret", "", BulkAnswer{ItemID: "nope", RawInt: intPtr(5)}); err == nil {
+		t.Fatalf("expected error for an item that was not presented")
+	}
+	seen := map[string]bool{}
+	prevTheta := state.Theta
+	for state.Status == CATStatusActive {
+		id := state.NextItem.ID
+		if seen[id] {
+			t.Fatalf("item %s administered twice", id)
+		}
+		seen[id] = true
+		state, err = svc.Answer(state.SessionID, "secret", "", BulkAnswer{ItemID: id, RawInt: intPtr(5)})
+		if err != nil {
+			t.Fatalf("Answer: %v", err)
+		}
+		if state.Theta <= prevTheta {
+			t.Fatalf("θ should rise with top answers: %v -> %v", prevTheta, state.Theta)
+		}
+		prevTheta = state.Theta
+	}
+	if state.Answered != 6 || state.StopReason != CATStopMaxItems {
+		t.Fatalf("expected max-length stop after 6 items, got %d (%s)", state.Answered, state.StopReason)
+	}
+	if state.ParticipantID == "" || state.SelfToken == "" || len(bulk.responses) != 6 {
+		t.Fatalf("finished session should store responses: %+v (%d responses)", state, len(bulk.responses))
+	}
+	if bulk.participants[0].Condition != "A" {
+		t.Fatalf("condition not carried over: %+v", bulk.participants[0])
+	}
+	sessions, _ := svc.ListSessions("T1", "S1")
+	if len(sessions) != 1 || len(sessions[0].Steps) != 6 || sessions[0].Theta != sessions[0].Steps[5].Theta {
+		t.Fatalf("administered sequence not recorded: %+v", sessions)
+	}
+	if _, err := svc.Answer(state.SessionID, "secret", "", BulkAnswer{ItemID: best, RawInt: intPtr(5)}); err == nil {
+		t.Fatalf("expected error after the session finished")
+	}
+}
+
+func TestCATSessionStopsOnStandardError(t *testing.T) {
+	svc, _, _ := newCATFixture(t)
+	if _, err := svc.UpdateSettings("T1", "S1", CATSettings{Enabled: true, SETarget: 0.45, MinItems: 2}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	state, err := svc.StartSession(CATStartRequest{ScaleID: "S1"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	for state.Status == CATStatusActive {
+		state, err = svc.Answer(state.SessionID, "secret", "en", BulkAnswer{ItemID: state.NextItem.ID, RawInt: intPtr(3)})
+		if err != nil {
+			t.Fatalf("Answer: %v", err)
+		}
+	}
+	if state.StopReason != CATStopSE || state.SE > 0.45 || state.Answered < 2 || state.Answered >= 10 {
+		t.Fatalf("expected SE stop before exhausting the pool, got %+v", state)
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestCATConcurrentAnswersAdvanceOnce(t *testing.T) {
+	svc, store, bulk := newCATFixture(t)
+	if _, err := svc.UpdateSettings("T1", "S1", CATSettings{Enabled: true, MaxItems: 2, SETarget: 0.01}); err != nil {
+		t.Fatalf("UpdateSettings: %v", err)
+	}
+	// a loser either read the session before the winner saved it (conflict) or after (the item is
+	// no longer the presented one)
+	race := func(sessionID, itemID string) (ok, rejected int) {
+		const n = 8
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+		start := make(chan struct{})
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, err := svc.Answer(sessionID, "secret", "", BulkAnswer{ItemID: itemID, RawInt: intPtr(4)})
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}()
+		}
+		close(start)
+		wg.Wait()
+		for _, err := range errs {
+			if err == nil {
+				ok++
+			} else if se, isSE := AsServiceError(err); isSE && (se.Code == ErrorConflict || se.Code == ErrorInvalid) {
+				rejected++
+			} else {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return ok, rejected
+	}
+
+	state, err := svc.StartSession(CATStartRequest{ScaleID: "S1"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	if ok, rejected := race(state.SessionID, state.NextItem.ID); ok != 1 || rejected != 7 {
+		t.Fatalf("racing answers: %d ok, %d rejected", ok, rejected)
+	}
+	if sess := store.sessions[state.SessionID]; len(sess.Steps) != 1 || sess.Status != CATStatusActive {
+		t.Fatalf("one answer must be recorded: %+v", sess)
+	}
+
+	// storing the responses fails: the session goes back to its last question
+	state, _ = svc.GetSession(state.SessionID, "secret", "")
+	scale := bulk.scale
+	bulk.scale = nil
+	if _, err := svc.Answer(state.SessionID, "secret", "", BulkAnswer{ItemID: state.NextItem.ID, RawInt: intPtr(4)}); err == nil {
+		t.Fatal("expected the failed submission to be reported")
+	}
+	if sess := store.sessions[state.SessionID]; len(sess.Steps) != 1 || sess.Status != CATStatusActive || sess.NextItemID != state.NextItem.ID {
+		t.Fatalf("session must be restored after a failed finish: %+v", sess)
+	}
+	bulk.scale = scale
+
+	// the final answer sent twice at once stores the responses once
+	if ok, rejected := race(state.SessionID, state.NextItem.ID); ok != 1 || rejected != 7 {
+		t.Fatalf("racing final answers: %d ok, %d rejected", ok, rejected)
+	}
+	if sess := store.sessions[state.SessionID]; sess.Status != CATStatusFinished || len(bulk.participants) != 1 || len(bulk.responses) != 2 {
+		t.Fatalf("finished once: %+v, %d participants, %d responses", sess, len(bulk.participants), len(bulk.responses))
+	}
+}
@@ -16,9 +16,10 @@ type ConsentStore interface {
 }
 
 type ConsentService struct {
-	store ConsentStore
-	now   func() time.Time
-	idGen func() string
+	store  ConsentStore
+	now    func() time.Time
+	idGen  func() string
+	events *EventBus
 }
 
 type ConsentSignRequest struct {
@@ -44,6 +45,11 @@ func NewConsentService(store ConsentStore) *ConsentService {
 	}
 }
 
+// WithEvents announces signed consents on the bus.
+func (s *ConsentService) WithEvents(bus *EventBus) {
+	s.events = bus
+}
+
 func (s *ConsentService) Sign(req ConsentSignRequest) (*ConsentSignResult, error) {
 	if req.ScaleID == "" {
 		return nil, NewInvalidError("scale_id required")
@@ -72,7 +78,8 @@ func (s *ConsentService) Sign(req ConsentSignRequest) (*ConsentSignResult, error
 	if err := s.store.AddConsentRecord(cr); err != nil {
 		return nil, err
 	}
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: "participant", Action: "consent_sign", Target: req.ScaleID, Note: id})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: sc.TenantID, Actor: "participant", Action: "consent_sign", Target: req.ScaleID, Note: id})
+	s.events.Publish(Event{Type: EventConsentSigned, ScaleID: req.ScaleID, ConsentID: id})
 	return &ConsentSignResult{ID: id, Hash: hash}, nil
 }
 
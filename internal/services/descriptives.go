@@ -0,0 +1,74 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+// NumericStats holds univariate descriptive statistics.
+// SD uses the sample (n-1) estimator; skewness and kurtosis are the bias-adjusted
+// G1/G2 estimates (as reported by SPSS), with kurtosis expressed as excess kurtosis.
+type NumericStats struct {
+	Mean     float64 `json:"mean"`
+	SD       float64 `json:"sd"`
+	Median   float64 `json:"median"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Skewness float64 `json:"skewness"`
+	Kurtosis float64 `json:"kurtosis"`
+}
+
+// Describe computes NumericStats for values. It returns nil for an empty slice.
+// Skewness needs n >= 3 and kurtosis n >= 4; otherwise they are reported as 0.
+func Describe(values []float64) *NumericStats {
+	n := len(values)
+	if n == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	st := &NumericStats{Min: sorted[0], Max: sorted[n-1], Median: median(sorted)}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	st.Mean = sum / float64(n)
+	var m2, m3, m4 float64
+	for _, v := range values {
+		d := v - st.Mean
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	if n > 1 {
+		st.SD = math.Sqrt(m2 / float64(n-1))
+	}
+	if m2 == 0 {
+		return st
+	}
+	nf := float64(n)
+	m2 /= nf
+	m3 /= nf
+	m4 /= nf
+	if n >= 3 {
+		g1 := m3 / math.Pow(m2, 1.5)
+		st.Skewness = g1 * math.Sqrt(nf*(nf-1)) / (nf - 2)
+	}
+	if n >= 4 {
+		g2 := m4/(m2*m2) - 3
+		st.Kurtosis = ((nf+1)*g2 + 6) * (nf - 1) / ((nf - 2) * (nf - 3))
+	}
+	return st
+}
+
+// median expects sorted input.
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	st := Describe([]float64{1, 2, 3, 4, 10})
+	want := NumericStats{Mean: 4, SD: 3.5355, Median: 3, Min: 1, Max: 10, Skewness: 1.6971, Kurtosis: 3.152}
+	got := []float64{st.Mean, st.SD, st.Median, st.Min, st.Max, st.Skewness, st.Kurtosis}
+	exp := []float64{want.Mean, want.SD, want.Median, want.Min, want.Max, want.Skewness, want.Kurtosis}
+	for i := range got {
+		if math.Abs(got[i]-exp[i]) > 1e-3 {
+			t.Fatalf("stat %d: got %f, want %f", i, got[i], exp[i])
+		}
+	}
+}
+
+func TestDescribeDegenerate(t *testing.T) {
+	if Describe(nil) != nil {
+		t.Fatalf("expected nil stats for empty input")
+	}
+	st := Describe([]float64{2, 2, 2, 2})
+	if st.SD != 0 || st.Skewness != 0 || st.Kurtosis != 0 || st.Median != 2 {
+		t.Fatalf("unexpected stats for constant input: %+v", st)
+	}
+}
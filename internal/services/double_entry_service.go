@@ -0,0 +1,387 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Double data entry statuses for paper forms.
+const (
+	DataEntryAwaitingSecond = "awaiting_second"
+	DataEntryConflict       = "conflict"
+	DataEntryReconciled     = "reconciled"
+)
+
+// DataEntryMismatch describes one item whose two independent entries disagree.
+type DataEntryMismatch struct {
+	ItemID   string          `json:"item_id"`
+	First    json.RawMessage `json:"first,omitempty"`
+	Second   json.RawMessage `json:"second,omitempty"`
+	Resolved json.RawMessage `json:"resolved,omitempty"`
+}
+
+// DataEntryForm tracks the two independent entries of one paper form and their reconciliation.
+type DataEntryForm struct {
+	ScaleID       string                     `json:"scale_id"`
+	FormID        string                     `json:"form_id"`
+	FirstEntry    map[string]json.RawMessage `json:"first_entry,omitempty"`
+	FirstBy       string                     `json:"first_by,omitempty"`
+	SecondEntry   map[string]json.RawMessage `json:"second_entry,omitempty"`
+	SecondBy      string                     `json:"second_by,omitempty"`
+	Status        string                     `json:"status"`
+	Mismatches    []DataEntryMismatch        `json:"mismatches,omitempty"`
+	ResolvedBy    string                     `json:"resolved_by,omitempty"`
+	ParticipantID string                     `json:"participant_id,omitempty"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
+
+type DoubleEntryStore interface {
+	GetScale(id string) (*Scale, error)
+	ListItems(scaleID string) ([]*Item, error)
+	GetDataEntryForm(scaleID, formID string) (*DataEntryForm, error)
+	// SaveDataEntryForm stores f only while the stored form still has prevStatus; an empty
+	// prevStatus only creates a form that does not exist yet. It reports false when another entry
+	// got there first.
+	SaveDataEntryForm(f *DataEntryForm, prevStatus string) (bool, error)
+	// CommitDataEntryForm saves f like SaveDataEntryForm and adds p with its responses in the same
+	// transaction, so a form is committed once or not at all.
+	CommitDataEntryForm(f *DataEntryForm, prevStatus string, p *Participant, rs []*Response) (bool, error)
+	ListDataEntryForms(scaleID string) ([]*DataEntryForm, error)
+	AddAudit(entry AuditEntry)
+}
+
+// errDataEntryRace is returned to the loser of two concurrent entries or resolutions of one form.
+var errDataEntryRace = NewConflictError("form was changed by another entry; reload it and try again")
+
+// DoubleEntryService implements the two-operator verification workflow for paper questionnaires.
+// Only reconciled forms are scored (via the same path as /api/responses/bulk) and stored as responses.
+type DoubleEntryService struct {
+	store       DoubleEntryStore
+	now         func() time.Time
+	idGenerator func() string
+	aggregates  *AnalyticsAggregates
+}
+
+func NewDoubleEntryService(store DoubleEntryStore) *DoubleEntryService {
+	return &DoubleEntryService{
+		store:       store,
+		now:         func() time.Time { return time.Now().UTC() },
+		idGenerator: defaultParticipantID,
+	}
+}
+
+// WithAggregates keeps the scale's analytics aggregates up to date with committed forms.
+func (s *DoubleEntryService) WithAggregates(a *AnalyticsAggregates) {
+	s.aggregates = a
+}
+
+// SubmitEntry records one operator's entry of a form. The second entry must come from a
+// different operator and triggers the item-by-item comparison.
+func (s *DoubleEntryService) SubmitEntry(tenantID, scaleID, formID, operator string, answers []BulkAnswer) (*DataEntryForm, error) {
+	formID = strings.TrimSpace(formID)
+	if formID == "" {
+		return nil, NewInvalidError("form_id required")
+	}
+	if strings.TrimSpace(operator) == "" {
+		return nil, NewUnauthorizedError("operator required")
+	}
+	sc, items, err := s.scaleWithItems(tenantID, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc.E2EEEnabled {
+		return nil, NewInvalidError(ErrPlaintextDisabled.Error())
+	}
+	entry, err := normalizeEntry(answers, items)
+	if err != nil {
+		return nil, err
+	}
+	form, err := s.store.GetDataEntryForm(scaleID, formID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	switch {
+	case form == nil:
+		form = &DataEntryForm{ScaleID: scaleID, FormID: formID, FirstEntry: entry, FirstBy: operator, Status: DataEntryAwaitingSecond, CreatedAt: now, UpdatedAt: now}
+		if err := s.save(form, ""); err != nil {
+			return nil, err
+		}
+		s.store.AddAudit(AuditEntry{Time: now, TenantID: sc.TenantID, Actor: operator, Action: "double_entry.first", Target: scaleID, Note: formID})
+		return form, nil
+	case form.Status != DataEntryAwaitingSecond:
+		return nil, NewConflictError("form already entered twice")
+	case strings.EqualFold(form.FirstBy, operator):
+		return nil, NewConflictError("second entry must come from a different operator")
+	}
+	form.SecondEntry = entry
+	form.SecondBy = operator
+	form.Mismatches = compareEntries(form.FirstEntry, form.SecondEntry)
+	form.UpdatedAt = now
+	if len(form.Mismatches) > 0 {
+		form.Status = DataEntryConflict
+		err = s.save(form, DataEntryAwaitingSecond)
+	} else {
+		err = s.commit(sc, items, form, form.FirstEntry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: sc.TenantID, Actor: operator, Action: "double_entry.second", Target: scaleID, Note: formID})
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: sc.TenantID, Actor: "system", Action: "double_entry.compare", Target: scaleID, Note: formID + ":" + strconv.Itoa(len(form.Mismatches)) + " mismatches"})
+	if form.Status == DataEntryReconciled {
+		s.auditCommit(sc, form, operator)
+	}
+	return form, nil
+}
+
+// Resolve settles every mismatch of a conflicting form. The resolver must be a third person
+// distinct from both operators; agreed values are kept as entered.
+func (s *DoubleEntryService) Resolve(tenantID, scaleID, formID, resolver string, values map[string]json.RawMessage) (*DataEntryForm, error) {
+	if strings.TrimSpace(resolver) == "" {
+		return nil, NewUnauthorizedError("resolver required")
+	}
+	sc, items, err := s.scaleWithItems(tenantID, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	form, err := s.store.GetDataEntryForm(scaleID, formID)
+	if err != nil {
+		return nil, err
+	}
+	if form == nil {
+		return nil, NewNotFoundError("form not found")
+	}
+	if form.Status != DataEntryConflict {
+		return nil, NewConflictError("form has no open discrepancies")
+	}
+	if strings.EqualFold(resolver, form.FirstBy) || strings.EqualFold(resolver, form.SecondBy) {
+		return nil, NewForbiddenError("discrepancies must be resolved by a third person")
+	}
+	itemByID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		itemByID[it.ID] = it
+	}
+	final := make(map[string]json.RawMessage, len(form.FirstEntry))
+	for id, v := range form.FirstEntry {
+		final[id] = v
+	}
+	for i, m := range form.Mismatches {
+		v, ok := values[m.ItemID]
+		if !ok {
+			return nil, NewInvalidError("resolution missing for item " + m.ItemID)
+		}
+		if len(bytes.TrimSpace(v)) == 0 || bytes.Equal(bytes.TrimSpace(v), []byte("null")) {
+			delete(final, m.ItemID)
+		} else {
+			final[m.ItemID] = canonicalRaw(v, itemByID[m.ItemID])
+		}
+		form.Mismatches[i].Resolved = final[m.ItemID]
+	}
+	form.ResolvedBy = resolver
+	form.UpdatedAt = s.now()
+	if err := s.commit(sc, items, form, final); err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: form.UpdatedAt, TenantID: sc.TenantID, Actor: resolver, Action: "double_entry.resolve", Target: scaleID, Note: formID + ":" + strconv.Itoa(len(form.Mismatches)) + " resolved"})
+	s.auditCommit(sc, form, resolver)
+	return form, nil
+}
+
+// ListForms returns the forms of a scale, optionally filtered by status.
+func (s *DoubleEntryService) ListForms(tenantID, scaleID, status string) ([]*DataEntryForm, error) {
+	if _, _, err := s.scaleWithItems(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	forms, err := s.store.ListDataEntryForms(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*DataEntryForm, 0, len(forms))
+	for _, f := range forms {
+		if status == "" || f.Status == status {
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FormID < out[j].FormID })
+	return out, nil
+}
+
+func (s *DoubleEntryService) GetForm(tenantID, scaleID, formID string) (*DataEntryForm, error) {
+	if _, _, err := s.scaleWithItems(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	form, err := s.store.GetDataEntryForm(scaleID, formID)
+	if err != nil {
+		return nil, err
+	}
+	if form == nil {
+		return nil, NewNotFoundError("form not found")
+	}
+	return form, nil
+}
+
+func (s *DoubleEntryService) scaleWithItems(tenantID, scaleID string) (*Scale, []*Item, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sc == nil {
+		return nil, nil, NewNotFoundError("scale not found")
+	}
+	if sc.TenantID != tenantID {
+		return nil, nil, NewForbiddenError("forbidden")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sc, items, nil
+}
+
+// save stores form if its status is still prevStatus.
+func (s *DoubleEntryService) save(form *DataEntryForm, prevStatus string) error {
+	ok, err := s.store.SaveDataEntryForm(form, prevStatus)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errDataEntryRace
+	}
+	return nil
+}
+
+// commit turns the reconciled answers into a participant with scored responses. The form moves
+// from its current status to reconciled in the same transaction, so of two concurrent commits
+// only one writes anything.
+func (s *DoubleEntryService) commit(sc *Scale, items []*Item, form *DataEntryForm, answers map[string]json.RawMessage) error {
+	participant := &Participant{ID: s.idGenerator()}
+	itemByID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		itemByID[it.ID] = it
+	}
+	submittedAt := s.now()
+	responses := make([]*Response, 0, len(answers))
+	for _, it := range items {
+		raw, ok := answers[it.ID]
+		if !ok {
+			continue
+		}
+		responses = append(responses, buildResponseForItem(BulkAnswer{ItemID: it.ID, Raw: raw}, itemByID[it.ID], sc.Points, submittedAt, participant.ID))
+	}
+	prevStatus := form.Status
+	form.Status = DataEntryReconciled
+	form.ParticipantID = participant.ID
+	form.UpdatedAt = submittedAt
+	return s.aggregates.Track(participant.ID, func() error {
+		ok, err := s.store.CommitDataEntryForm(form, prevStatus, participant, responses)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errDataEntryRace
+		}
+		return nil
+	})
+}
+
+func (s *DoubleEntryService) auditCommit(sc *Scale, form *DataEntryForm, actor string) {
+	s.store.AddAudit(AuditEntry{Time: form.UpdatedAt, TenantID: sc.TenantID, Actor: actor, Action: "double_entry.commit", Target: sc.ID, Note: form.FormID + ":" + form.ParticipantID})
+}
+
+// normalizeEntry validates item IDs and canonicalises raw JSON so that formatting
+// differences between operators do not count as discrepancies.
+func normalizeEntry(answers []BulkAnswer, items []*Item) (map[string]json.RawMessage, error) {
+	known := make(map[string]*Item, len(items))
+	for _, it := range items {
+		known[it.ID] = it
+	}
+	out := make(map[string]json.RawMessage, len(answers))
+	for _, a := range answers {
+		if a.ItemID == "" {
+			continue
+		}
+		if known[a.ItemID] == nil {
+			return nil, NewInvalidError("unknown item " + a.ItemID)
+		}
+		raw := a.Raw
+		if a.RawInt != nil {
+			raw = json.RawMessage(strconv.Itoa(*a.RawInt))
+		}
+		if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			continue
+		}
+		out[a.ItemID] = canonicalRaw(raw, known[a.ItemID])
+	}
+	return out, nil
+}
+
+// canonicalRaw compacts raw. On numeric items (Likert, rating, slider, numeric) "3", "03" and 3
+// are the same answer on paper and become one number; on other items strings are compared as typed,
+// apart from surrounding whitespace, so "01" and "1" or "3" and 3 differ.
+func canonicalRaw(raw json.RawMessage, it *Item) json.RawMessage {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	if it != nil && numericItemType(it) {
+		var f float64
+		var err error
+		switch x := v.(type) {
+		case float64:
+			f = x
+		case string:
+			f, err = strconv.ParseFloat(strings.TrimSpace(x), 64)
+		default:
+			err = strconv.ErrSyntax
+		}
+		if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return json.RawMessage(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	}
+	if str, ok := v.(string); ok {
+		v = strings.TrimSpace(str)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+func numericItemType(it *Item) bool {
+	switch it.Type {
+	case "", "likert", "rating", "slider", "numeric":
+		return true
+	}
+	return false
+}
+
+func compareEntries(first, second map[string]json.RawMessage) []DataEntryMismatch {
+	ids := map[string]struct{}{}
+	for id := range first {
+		ids[id] = struct{}{}
+	}
+	for id := range second {
+		ids[id] = struct{}{}
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	out := []DataEntryMismatch{}
+	for _, id := range sorted {
+		a, b := first[id], second[id]
+		if !bytes.Equal(a, b) {
+			out = append(out, DataEntryMismatch{ItemID: id, First: a, Second: b})
+		}
+	}
+	return out
+}
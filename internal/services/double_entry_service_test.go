@@ -0,0 +1,326 @@
+package services
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type stubDoubleEntryStore struct {
+	mu           sync.Mutex
+	scale        *Scale
+	items        []*Item
+	forms        map[string]*DataEntryForm
+	participants []*Participant
+	responses    []*Response
+	audit        []AuditEntry
+}
+
+func (s *stubDoubleEntryStore) GetScale(id string) (*Scale, error) {
+	if s.scale != nil && s.scale.ID == id {
+		return s.scale, nil
+	}
+	return nil, nil
+}
+
+func (s *stubDoubleEntryStore) ListItems(scaleID string) ([]*Item, error) { return s.items, nil }
+
+func (s *stubDoubleEntryStore) GetDataEntryForm(scaleID, formID string) (*DataEntryForm, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.forms[scaleID+"/"+formID]; ok {
+		cp := *f
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubDoubleEntryStore) SaveDataEntryForm(f *DataEntryForm, prevStatus string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked(f, prevStatus), nil
+}
+
+func (s *stubDoubleEntryStore) CommitDataEntryForm(f *DataEntryForm, prevStatus string, p *Participant, rs []*Response) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.saveLocked(f, prevStatus) {
+		return false, nil
+	}
+	s.participants = append(s.participants, p)
+	s.responses = append(s.responses, rs...)
+	return true, nil
+}
+
+func (s *stubDoubleEntryStore) saveLocked(f *DataEntryForm, prevStatus string) bool {
+	key := f.ScaleID + "/" + f.FormID
+	if cur, ok := s.forms[key]; ok && cur.Status != prevStatus || !ok && prevStatus != "" {
+		return false
+	}
+	if s.forms == nil {
+		s.forms = map[string]*DataEntryForm{}
+	}
+	cp := *f
+	s.forms[key] = &cp
+	return true
+}
+
+func (s *stubDoubleEntryStore) ListDataEntryForms(scaleID string) ([]*DataEntryForm, error) {
+	out := []*DataEntryForm{}
+	for _, f := range s.forms {
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func (s *stubDoubleEntryStore) AddAudit(entry AuditEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = append(s.audit, entry)
+}
+
+func newDoubleEntryFixture() (*DoubleEntryService, *stubDoubleEntryStore) {
+	store := &stubDoubleEntryStore{
+		scale: &Scale{ID: "S1", TenantID: "T1", Points: 5},
+		items: []*Item{
+			{ID: "I1", ScaleID: "S1", Type: "likert"},
+			{ID: "I2", ScaleID: "S1", Type: "likert", ReverseScored: true},
+			{ID: "I3", ScaleID: "S1", Type: "short_text"},
+		},
+	}
+	svc := NewDoubleEntryService(store)
+	svc.now = func() time.Time { return time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC) }
+	svc.idGenerator = func() string { return "PAPER0000001" }
+	return svc, store
+}
+
+func rawAnswers(kv ...string) []BulkAnswer {
+	out := []BulkAnswer{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		out = append(out, BulkAnswer{ItemID: kv[i], Raw: json.RawMessage(kv[i+1])})
+	}
+	return out
+}
+
+func TestDoubleEntryMatchingFormsAreCommitted(t *testing.T) {
+	svc, store := newDoubleEntryFixture()
+	if _, err := svc.SubmitEntry("T1", "S1", "F1", "alice", rawAnswers("I1", "3", "I2", "2", "I3", `"hello "`)); err != nil {
+		t.Fatalf("first entry: %v", err)
+	}
+	if len(store.responses) != 0 {
+		t.Fatalf("responses must not be written before reconciliation")
+	}
+	form, err := svc.SubmitEntry("T1", "S1", "F1", "bob", rawAnswers("I1", `"3"`, "I2", "2", "I3", `"hello"`))
+	if err != nil {
+		t.Fatalf("second entry: %v", err)
+	}
+	if form.Status != DataEntryReconciled || form.ParticipantID != "PAPER0000001" {
+		t.Fatalf("unexpected form state: %+v", form)
+	}
+	if len(store.responses) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(store.responses))
+	}
+	for _, r := range store.responses {
+		if r.ItemID == "I2" && r.ScoreValue != 4 {
+			t.Fatalf("reverse scoring not applied: %+v", r)
+		}
+	}
+}
+
+func TestDoubleEntryRequiresDistinctOperators(t *testing.T) {
+	svc, _ := newDoubleEntryFixture()
+	if _, err := svc.SubmitEntry("T1", "S1", "F1", "alice", rawAnswers("I1", "3")); err != nil {
+		t.Fatalf("first entry: %v", err)
+	}
+	_, err := svc.SubmitEntry("T1", "S1", "F1", "alice", rawAnswers("I1", "3"))
+	if se, ok := AsServiceError(err); !ok || se.Code != ErrorConflict {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
+
+func TestDoubleEntryMismatchResolution(t *testing.T) {
+	svc, store := newDoubleEntryFixture()
+	_, _ = svc.SubmitEntry("T1", "S1", "F2", "alice", rawAnswers("I1", "3", "I2", "5"))
+	form, err := svc.SubmitEntry("T1", "S1", "F2", "bob", rawAnswers("I1", "4", "I2", "5", "I3", `"note"`))
+	if err != nil {
+		t.Fatalf("second entry: %v", err)
+	}
+	if form.Status != DataEntryConflict || len(form.Mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %+v", form)
+	}
+	if len(store.responses) != 0 {
+		t.Fatalf("conflicting forms must not produce responses")
+	}
+	if _, err := svc.Resolve("T1", "S1", "F2", "bob", map[string]json.RawMessage{"I1": json.RawMessage("4"), "I3": json.RawMessage("null")}); err == nil {
+		t.Fatalf("operator must not resolve own discrepancies")
+	}
+	if _, err := svc.Resolve("T1", "S1", "F2", "carol", map[string]json.RawMessage{"I1": json.RawMessage("4")}); err == nil {
+		t.Fatalf("expected error for missing resolution")
+	}
+	form, err = svc.Resolve("T1", "S1", "F2", "carol", map[string]json.RawMessage{"I1": json.RawMessage("4"), "I3": json.RawMessage("null")})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if form.Status != DataEntryReconciled || form.ResolvedBy != "carol" {
+		t.Fatalf("unexpected form after resolve: %+v", form)
+	}
+	if len(store.responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(store.responses))
+	}
+	actions := map[string]int{}
+	for _, e := range store.audit {
+		actions[e.Action]++
+	}
+	for _, a := range []string{"double_entry.first", "double_entry.second", "double_entry.compare", "double_entry.resolve", "double_entry.commit"} {
+		if actions[a] != 1 {
+			t.Fatalf("expected one %s audit entry, got %d", a, actions[a])
+		}
+	}
+}
+
+func TestDoubleEntryTenantScope(t *testing.T) {
+	svc, _ := newDoubleEntryFixture()
+	if _, err := svc.SubmitEntry("T2", "S1", "F1", "alice", rawAnswers("I1", "3")); err == nil {
+		t.Fatalf("expected forbidden for other tenant")
+	}
+	if _, err := svc.SubmitEntry("T1", "S1", "F1", "alice", rawAnswers("X9", "3")); err == nil {
+		t.Fatalf("expected error for unknown item")
+	}
+}
+
+func TestDoubleEntryAuditsGuestsInTheScaleTenant(t *testing.T) {
+	// guests from another tenant act with the scale's tenant; their entries must land in its log
+	svc, store := newDoubleEntryFixture()
+	if _, err := svc.SubmitEntry("T1", "S1", "F1", "guest@other.example", rawAnswers("I1", "3")); err != nil {
+		t.Fatalf("first entry: %v", err)
+	}
+	if _, err := svc.SubmitEntry("T1", "S1", "F1", "alice", rawAnswers("I1", "3")); err != nil {
+		t.Fatalf("second entry: %v", err)
+	}
+	if len(store.audit) != 4 {
+		t.Fatalf("audit %+v", store.audit)
+	}
+	for _, e := range store.audit {
+		if e.TenantID != "T1" {
+			t.Fatalf("%s audited in tenant %q", e.Action, e.TenantID)
+		}
+	}
+}
+
+func TestDoubleEntryComparesByItemType(t *testing.T) {
+	svc, store := newDoubleEntryFixture()
+	store.items = append(store.items, &Item{ID: "I4", ScaleID: "S1", Type: "numeric"})
+	cases := []struct {
+		item, first, second string
+		agree               bool
+	}{
+		{"I1", `3`, `"3"`, true},
+		{"I1", `"01"`, `1`, true},
+		{"I4", `"2.50"`, `2.5`, true},
+		{"I4", `2.5`, `2.05`, false},
+		{"I3", `"01"`, `"1"`, false},
+		{"I3", `"3"`, `3`, false},
+		{"I3", `"1.0"`, `"1"`, false},
+		{"I3", `"yes"`, `"yes"`, true},
+	}
+	for i, c := range cases {
+		form := "C" + strconv.Itoa(i)
+		if _, err := svc.SubmitEntry("T1", "S1", form, "alice", rawAnswers(c.item, c.first)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := svc.SubmitEntry("T1", "S1", form, "bob", rawAnswers(c.item, c.second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if agree := got.Status == DataEntryReconciled; agree != c.agree {
+			t.Fatalf("%s %s vs %s: reconciled=%v, want %v (%+v)", c.item, c.first, c.second, agree, c.agree, got.Mismatches)
+		}
+	}
+}
+
+func TestDoubleEntryConcurrentEntriesCommitOnce(t *testing.T) {
+	svc, store := newDoubleEntryFixture()
+	var ids atomic.Int64
+	svc.idGenerator = func() string { return "PAPER" + strconv.FormatInt(ids.Add(1), 10) }
+	const n = 8
+	race := func(fn func(i int) error) (ok, conflicts int) {
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			errs []error
+		)
+		start := make(chan struct{})
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				err := fn(i)
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		for _, err := range errs {
+			if err == nil {
+				ok++
+			} else if se, isSE := AsServiceError(err); isSE && se.Code == ErrorConflict {
+				conflicts++
+			} else {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return ok, conflicts
+	}
+	commits := func() int {
+		c := 0
+		for _, e := range store.audit {
+			if e.Action == "double_entry.commit" {
+				c++
+			}
+		}
+		return c
+	}
+
+	// first entries racing: one creates the form, one more may complete it, the rest conflict
+	race(func(i int) error {
+		_, err := svc.SubmitEntry("T1", "S1", "F1", "op"+strconv.Itoa(i), rawAnswers("I1", "3", "I2", "2"))
+		return err
+	})
+	if len(store.participants) > 1 || len(store.responses) != 2*len(store.participants) || commits() != len(store.participants) {
+		t.Fatalf("racing first entries: %d participants, %d responses, %d commits", len(store.participants), len(store.responses), commits())
+	}
+
+	// second entries racing on one form: exactly one commits
+	if _, err := svc.SubmitEntry("T1", "S1", "F2", "alice", rawAnswers("I1", "3", "I2", "2")); err != nil {
+		t.Fatal(err)
+	}
+	before := len(store.participants)
+	if ok, conflicts := race(func(i int) error {
+		_, err := svc.SubmitEntry("T1", "S1", "F2", "op"+strconv.Itoa(i), rawAnswers("I1", "3", "I2", "2"))
+		return err
+	}); ok != 1 || conflicts != n-1 || len(store.participants) != before+1 {
+		t.Fatalf("racing second entries: %d ok, %d conflicts, %d new participants", ok, conflicts, len(store.participants)-before)
+	}
+
+	// resolutions racing on one form: exactly one commits
+	_, _ = svc.SubmitEntry("T1", "S1", "F3", "alice", rawAnswers("I1", "3"))
+	if f, err := svc.SubmitEntry("T1", "S1", "F3", "bob", rawAnswers("I1", "4")); err != nil || f.Status != DataEntryConflict {
+		t.Fatalf("conflicting entry: %+v, %v", f, err)
+	}
+	before = len(store.participants)
+	if ok, conflicts := race(func(i int) error {
+		_, err := svc.Resolve("T1", "S1", "F3", "resolver"+strconv.Itoa(i), map[string]json.RawMessage{"I1": json.RawMessage("4")})
+		return err
+	}); ok != 1 || conflicts != n-1 || len(store.participants) != before+1 {
+		t.Fatalf("racing resolutions: %d ok, %d conflicts, %d new participants", ok, conflicts, len(store.participants)-before)
+	}
+	if commits() != len(store.participants) {
+		t.Fatalf("%d commits audited for %d participants", commits(), len(store.participants))
+	}
+}
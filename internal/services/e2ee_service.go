@@ -34,6 +34,7 @@ type E2EEService struct {
 	sign           ExportSigner
 	idGenerator    func() string
 	tokenGenerator func() (string, error)
+	events         *EventBus
 }
 
 type ProjectKeyInput struct {
@@ -119,6 +120,11 @@ func (s *E2EEService) WithSigner(fn ExportSigner) {
 	s.sign = fn
 }
 
+// WithEvents announces encrypted submissions (response ID only) and bundle downloads on the bus.
+func (s *E2EEService) WithEvents(bus *EventBus) {
+	s.events = bus
+}
+
 func randomID(n int) string {
 	// generate n bytes; base64url-encode yields length >= n for any n
 	r := randomBytes(n)
@@ -200,6 +206,7 @@ func (s *E2EEService) IntakeResponse(in IntakeResponseInput, verifier TurnstileV
 	if err := s.store.AddE2EEResponse(e2); err != nil {
 		return nil, err
 	}
+	s.events.Publish(Event{Type: EventE2EEResponseSubmitted, ScaleID: in.ScaleID, ResponseID: rid, Count: 1})
 	return &IntakeResponseResult{ResponseID: rid, SelfToken: tok}, nil
 }
 
@@ -215,7 +222,7 @@ func (s *E2EEService) RequestExport(params E2EEExportRequest) (*ExportRequestRes
 		return nil, err
 	} else if job != nil {
 		url := fmt.Sprintf("/api/exports/e2ee?job=%s&token=%s", job.ID, job.Token)
-		s.store.AddAudit(AuditEntry{Time: s.now(), Actor: params.Actor, Action: "export_e2ee_reuse", Target: params.ScaleID, Note: job.ID})
+		s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: params.TenantID, Actor: params.Actor, Action: "export_e2ee_reuse", Target: params.ScaleID, Note: job.ID})
 		return &ExportRequestResult{URL: url, ExpiresAt: job.ExpiresAt}, nil
 	}
 	allowed, err := s.store.AllowExport(params.TenantID, 5*time.Second)
@@ -229,7 +236,7 @@ func (s *E2EEService) RequestExport(params E2EEExportRequest) (*ExportRequestRes
 	if err != nil {
 		return nil, err
 	}
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: params.Actor, Action: "export_e2ee_request", Target: params.ScaleID, Note: job.ID})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: params.TenantID, Actor: params.Actor, Action: "export_e2ee_request", Target: params.ScaleID, Note: job.ID})
 	url := fmt.Sprintf("/api/exports/e2ee?job=%s&token=%s", job.ID, job.Token)
 	return &ExportRequestResult{URL: url, ExpiresAt: job.ExpiresAt}, nil
 }
@@ -252,7 +259,7 @@ func (s *E2EEService) DownloadExport(params E2EEDownloadRequest) (*ExportBundle,
 	if err != nil {
 		return nil, err
 	}
-	return s.buildBundle(params.Actor, params.ScaleID, rs)
+	return s.buildBundle(params.TenantID, params.Actor, params.ScaleID, rs)
 }
 
 func (s *E2EEService) downloadJob(params E2EEDownloadRequest) (*ExportBundle, error) {
@@ -267,10 +274,10 @@ func (s *E2EEService) downloadJob(params E2EEDownloadRequest) (*ExportBundle, er
 	if err != nil {
 		return nil, err
 	}
-	return s.buildBundle(params.Actor, job.ScaleID, rs)
+	return s.buildBundle(params.TenantID, params.Actor, job.ScaleID, rs)
 }
 
-func (s *E2EEService) buildBundle(actor, scaleID string, responses []*E2EEResponse) (*ExportBundle, error) {
+func (s *E2EEService) buildBundle(tenantID, actor, scaleID string, responses []*E2EEResponse) (*ExportBundle, error) {
 	manifest := map[string]any{
 		"version":    1,
 		"type":       "e2ee-bundle",
@@ -289,7 +296,8 @@ func (s *E2EEService) buildBundle(actor, scaleID string, responses []*E2EERespon
 		}
 	}
 	h := sha256Sum(mb)
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: actor, Action: "export_e2ee_download", Target: scaleID, Note: base64.StdEncoding.EncodeToString(h[:])})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: tenantID, Actor: actor, Action: "export_e2ee_download", Target: scaleID, Note: base64.StdEncoding.EncodeToString(h[:])})
+	s.events.Publish(Event{Type: EventExportDownloaded, ScaleID: scaleID, Format: "e2ee", Count: len(responses)})
 	return &ExportBundle{Manifest: manifest, Signature: sig, Responses: responses}, nil
 }
 
@@ -327,7 +335,7 @@ func (s *E2EEService) SubmitRewrap(tenantID, scaleID, actor string, items []Rewr
 			return NewNotFoundError("response not found")
 		}
 	}
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: actor, Action: "rewrap_submit", Target: scaleID, Note: toFP})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: tenantID, Actor: actor, Action: "rewrap_submit", Target: scaleID, Note: toFP})
 	return nil
 }
 
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	defaultParallelIterations = 100
+	maxParallelIterations     = 1000
+)
+
+// EFAOptions selects the items (a subscale; empty means all Likert items), the number of factors
+// (0 uses the parallel-analysis suggestion) and the extraction/rotation methods.
+type EFAOptions struct {
+	ItemIDs    []string
+	Factors    int
+	Extraction string
+	Rotation   string
+	Iterations int
+	Seed       int64
+}
+
+type BartlettTest struct {
+	ChiSquare float64 `json:"chi_square"`
+	DF        int     `json:"df"`
+	PValue    float64 `json:"p_value"`
+}
+
+type ParallelAnalysisResult struct {
+	Iterations int       `json:"iterations"`
+	Seed       int64     `json:"seed"`
+	RandomMean []float64 `json:"random_mean"`
+	Random95   []float64 `json:"random_p95"`
+	// Suggested is never below 1 so that it can serve as the default number of factors.
+	Suggested int `json:"suggested_factors"`
+}
+
+// EFAResult is the JSON shape of an exploratory factor analysis. Loadings is the (rotated) pattern
+// matrix [item][factor]; Structure and FactorCorrelations are only set for oblique rotations.
+type EFAResult struct {
+	ScaleID            string                 `json:"scale_id"`
+	ItemIDs            []string               `json:"item_ids"`
+	N                  int                    `json:"n"`
+	KMO                float64                `json:"kmo"`
+	MSA                []float64              `json:"msa"`
+	Bartlett           BartlettTest           `json:"bartlett"`
+	Eigenvalues        []float64              `json:"eigenvalues"`
+	Parallel           ParallelAnalysisResult `json:"parallel_analysis"`
+	Factors            int                    `json:"factors"`
+	Extraction         string                 `json:"extraction"`
+	Rotation           string                 `json:"rotation"`
+	Loadings           [][]float64            `json:"loadings"`
+	Structure          [][]float64            `json:"structure,omitempty"`
+	FactorCorrelations [][]float64            `json:"factor_correlations,omitempty"`
+	Communalities      []float64              `json:"communalities"`
+	Uniquenesses       []float64              `json:"uniquenesses"`
+	SSLoadings         []float64              `json:"ss_loadings"`
+	VarianceExplained  []float64              `json:"variance_explained"`
+}
+
+// EFAService runs exploratory factor analyses over the Likert item matrix of a scale.
+type EFAService struct {
+	store AnalyticsStore
+}
+
+func NewEFAService(store AnalyticsStore) *EFAService {
+	return &EFAService{store: store}
+}
+
+func (s *EFAService) Run(tenantID, scaleID string, opts EFAOptions) (*EFAResult, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	switch opts.Extraction {
+	case "":
+		opts.Extraction = ExtractionPAF
+	case ExtractionPAF, ExtractionMinres:
+	default:
+		return nil, NewInvalidError("extraction must be paf or minres")
+	}
+	switch opts.Rotation {
+	case "":
+		opts.Rotation = RotationVarimax
+	case RotationNone, RotationVarimax, RotationOblimin:
+	default:
+		return nil, NewInvalidError("rotation must be none, varimax or oblimin")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectItems(filterLikertItems(items), opts.ItemIDs)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	ids, _, _ := buildItemMatrix(selected, responses)
+	matrix, n := buildAlphaMatrix(selected, responses)
+	res, err := ExploratoryFactorAnalysis(matrix, opts)
+	if err != nil {
+		return nil, err
+	}
+	res.ScaleID = scaleID
+	res.ItemIDs = ids
+	res.N = n
+	return res, nil
+}
+
+// ExploratoryFactorAnalysis analyses a complete participant × item matrix.
+func ExploratoryFactorAnalysis(matrix [][]float64, opts EFAOptions) (*EFAResult, error) {
+	if len(matrix) == 0 || len(matrix[0]) < 3 {
+		return nil, NewInvalidError("at least 3 items with complete responses required")
+	}
+	n, k := len(matrix), len(matrix[0])
+	if n <= k {
+		return nil, NewInvalidError("more complete responses than items required")
+	}
+	corr, ok := completeCorrelation(matrix)
+	if !ok {
+		return nil, NewInvalidError("every item needs non-zero variance")
+	}
+	res := &EFAResult{Extraction: opts.Extraction, Rotation: opts.Rotation}
+	if res.Extraction == "" {
+		res.Extraction = ExtractionPAF
+	}
+	if res.Rotation == "" {
+		res.Rotation = RotationVarimax
+	}
+	res.KMO, res.MSA = KMO(corr)
+	chi2, df, p := BartlettSphericity(corr, n)
+	res.Bartlett = BartlettTest{ChiSquare: chi2, DF: df, PValue: p}
+	res.Eigenvalues, _ = symmetricEigen(corr)
+
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = defaultParallelIterations
+	}
+	if iterations > maxParallelIterations {
+		iterations = maxParallelIterations
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	mean, p95, suggested := ParallelAnalysis(res.Eigenvalues, n, k, iterations, rand.New(rand.NewSource(seed)))
+	res.Parallel = ParallelAnalysisResult{Iterations: iterations, Seed: seed, RandomMean: mean, Random95: p95, Suggested: suggested}
+	if res.Parallel.Suggested < 1 {
+		res.Parallel.Suggested = 1
+	}
+
+	m := opts.Factors
+	if m <= 0 {
+		m = res.Parallel.Suggested
+	}
+	if m >= k {
+		return nil, NewInvalidError("factors must be fewer than items")
+	}
+	res.Factors = m
+	unrotated := extractFactors(corr, m, res.Extraction)
+	pattern, phi := rotateLoadings(unrotated, res.Rotation)
+	oblique := res.Rotation == RotationOblimin && m > 1
+	pattern, phi = orderFactors(pattern, phi)
+	res.Loadings = pattern
+	if oblique {
+		res.Structure = matMul(pattern, phi)
+		res.FactorCorrelations = phi
+	}
+	res.Communalities = make([]float64, k)
+	res.Uniquenesses = make([]float64, k)
+	for i := range unrotated {
+		res.Communalities[i] = rowSS(unrotated[i])
+		res.Uniquenesses[i] = 1 - res.Communalities[i]
+	}
+	res.SSLoadings = make([]float64, m)
+	res.VarianceExplained = make([]float64, m)
+	for f := 0; f < m; f++ {
+		for i := range pattern {
+			if oblique {
+				// pattern × structure products add up to the total communality
+				res.SSLoadings[f] += pattern[i][f] * res.Structure[i][f]
+			} else {
+				res.SSLoadings[f] += pattern[i][f] * pattern[i][f]
+			}
+		}
+		res.VarianceExplained[f] = res.SSLoadings[f] / float64(k)
+	}
+	return res, nil
+}
+
+// orderFactors reflects every factor so that its loadings sum to a positive value and sorts the
+// factors by their sum of squared loadings, keeping the factor correlations aligned.
+func orderFactors(pattern, phi [][]float64) ([][]float64, [][]float64) {
+	m := len(phi)
+	sign := make([]float64, m)
+	ss := make([]float64, m)
+	for f := 0; f < m; f++ {
+		var sum float64
+		for i := range pattern {
+			sum += pattern[i][f]
+			ss[f] += pattern[i][f] * pattern[i][f]
+		}
+		sign[f] = 1
+		if sum < 0 {
+			sign[f] = -1
+		}
+	}
+	order := seqIndex(m)
+	sort.SliceStable(order, func(a, b int) bool { return ss[order[a]] > ss[order[b]] })
+	outP := make([][]float64, len(pattern))
+	for i := range pattern {
+		outP[i] = make([]float64, m)
+		for to, from := range order {
+			outP[i][to] = pattern[i][from] * sign[from]
+		}
+	}
+	outPhi := make([][]float64, m)
+	for a, fa := range order {
+		outPhi[a] = make([]float64, m)
+		for b, fb := range order {
+			outPhi[a][b] = phi[fa][fb] * sign[fa] * sign[fb]
+		}
+	}
+	return outP, outPhi
+}
+
+func selectItems(items []*Item, ids []string) ([]*Item, error) {
+	if len(ids) == 0 {
+		return items, nil
+	}
+	byID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	out := make([]*Item, 0, len(ids))
+	seen := map[string]bool{}
+	for _, id := range ids {
+		it, ok := byID[id]
+		if !ok {
+			return nil, NewInvalidError("unknown likert item " + id)
+		}
+		if !seen[id] {
+			out = append(out, it)
+			seen[id] = true
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// twoFactorData simulates 6 items: items 1-3 load .8 on the first factor and items 4-6 load .7
+// on the second; the factors correlate at r.
+func twoFactorData(n int, r float64) [][]float64 {
+	rng := rand.New(rand.NewSource(11))
+	out := make([][]float64, n)
+	for i := range out {
+		f1 := rng.NormFloat64()
+		f2 := r*f1 + math.Sqrt(1-r*r)*rng.NormFloat64()
+		row := make([]float64, 6)
+		for j := 0; j < 3; j++ {
+			row[j] = 0.8*f1 + 0.6*rng.NormFloat64()
+		}
+		for j := 3; j < 6; j++ {
+			row[j] = 0.7*f2 + math.Sqrt(0.51)*rng.NormFloat64()
+		}
+		out[i] = row
+	}
+	return out
+}
+
+func assertSimpleStructure(t *testing.T, loadings [][]float64) {
+	t.Helper()
+	primary := func(i int) int {
+		if math.Abs(loadings[i][0]) > math.Abs(loadings[i][1]) {
+			return 0
+		}
+		return 1
+	}
+	for i := 0; i < 6; i++ {
+		same := i / 3 * 3
+		if primary(i) != primary(same) {
+			t.Fatalf("item %d loads on the wrong factor: %v", i, loadings)
+		}
+		if math.Abs(loadings[i][primary(i)]) < 0.5 || math.Abs(loadings[i][1-primary(i)]) > 0.25 {
+			t.Fatalf("item %d lacks simple structure: %v", i, loadings[i])
+		}
+	}
+	if primary(0) == primary(3) {
+		t.Fatalf("both item groups load on the same factor: %v", loadings)
+	}
+}
+
+func TestExploratoryFactorAnalysisVarimax(t *testing.T) {
+	res, err := ExploratoryFactorAnalysis(twoFactorData(400, 0), EFAOptions{Seed: 3, Iterations: 50})
+	if err != nil {
+		t.Fatalf("EFA error: %v", err)
+	}
+	if res.Parallel.Suggested != 2 || res.Factors != 2 {
+		t.Fatalf("expected parallel analysis to suggest 2 factors, got %+v", res.Parallel)
+	}
+	if res.KMO < 0.5 || res.Bartlett.PValue > 0.001 || res.Bartlett.DF != 15 {
+		t.Fatalf("unexpected adequacy tests: kmo=%f bartlett=%+v", res.KMO, res.Bartlett)
+	}
+	assertSimpleStructure(t, res.Loadings)
+	for i, h := range res.Communalities {
+		var ss float64
+		for _, l := range res.Loadings[i] {
+			ss += l * l
+		}
+		if math.Abs(ss-h) > 1e-6 {
+			t.Fatalf("orthogonal rotation must preserve communality of item %d: %f vs %f", i, ss, h)
+		}
+	}
+}
+
+func TestExploratoryFactorAnalysisMinresOblimin(t *testing.T) {
+	data := twoFactorData(400, 0.5)
+	res, err := ExploratoryFactorAnalysis(data, EFAOptions{Factors: 2, Extraction: ExtractionMinres, Rotation: RotationOblimin, Seed: 3, Iterations: 20})
+	if err != nil {
+		t.Fatalf("EFA error: %v", err)
+	}
+	assertSimpleStructure(t, res.Loadings)
+	if phi := res.FactorCorrelations[0][1]; phi < 0.3 || phi > 0.7 {
+		t.Fatalf("expected factor correlation near .5, got %f", phi)
+	}
+	paf, _ := ExploratoryFactorAnalysis(data, EFAOptions{Factors: 2, Rotation: RotationNone, Seed: 3, Iterations: 20})
+	for i := range paf.Communalities {
+		if math.Abs(paf.Communalities[i]-res.Communalities[i]) > 0.02 {
+			t.Fatalf("minres and PAF communalities diverge for item %d: %f vs %f", i, res.Communalities[i], paf.Communalities[i])
+		}
+	}
+}
+
+func TestEFAServiceSubscale(t *testing.T) {
+	data := twoFactorData(60, 0)
+	store := &stubAnalyticsStore{scale: &Scale{ID: "S1", TenantID: "T1", Points: 5}}
+	ids := []string{"A1", "A2", "A3", "B1", "B2", "B3"}
+	for _, id := range ids {
+		store.items = append(store.items, &Item{ID: id, ScaleID: "S1"})
+	}
+	for p, row := range data {
+		for j, v := range row {
+			store.responses = append(store.responses, &Response{ParticipantID: string(rune('a'+p/26)) + string(rune('a'+p%26)), ItemID: ids[j], ScoreValue: int(math.Round(v*1.2)) + 3})
+		}
+	}
+	svc := NewEFAService(store)
+	res, err := svc.Run("T1", "S1", EFAOptions{ItemIDs: []string{"A1", "A2", "A3"}, Factors: 1, Seed: 1, Iterations: 10})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(res.ItemIDs) != 3 || len(res.Loadings) != 3 || res.N != 60 {
+		t.Fatalf("unexpected subscale result: %+v", res)
+	}
+	if _, err := svc.Run("T1", "S1", EFAOptions{ItemIDs: []string{"A1", "ZZ"}}); err == nil {
+		t.Fatalf("expected error for unknown item")
+	}
+	if _, err := svc.Run("T1", "S1", EFAOptions{Rotation: "promax"}); err == nil {
+		t.Fatalf("expected error for unsupported rotation")
+	}
+	if _, err := svc.Run("T2", "S1", EFAOptions{}); err == nil {
+		t.Fatalf("expected forbidden error")
+	}
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	cases := []struct{ x, df, want float64 }{
+		{3.841459, 1, 0.05},
+		{5.991465, 2, 0.05},
+		{18.307038, 10, 0.05},
+		{2, 4, 0.7357589},
+	}
+	for _, c := range cases {
+		if got := ChiSquareSurvival(c.x, c.df); math.Abs(got-c.want) > 1e-5 {
+			t.Fatalf("ChiSquareSurvival(%f,%f)=%f, want %f", c.x, c.df, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published on the EventBus.
+const (
+	EventResponseSubmitted     = "response.submitted"
+	EventE2EEResponseSubmitted = "e2ee_response.submitted"
+	EventConsentSigned         = "consent.signed"
+	EventParticipantDeleted    = "participant.deleted"
+	EventE2EEResponseDeleted   = "e2ee_response.deleted"
+	EventExportDownloaded      = "export.downloaded"
+)
+
+// eventBufferSize is how many undelivered events a subscriber may fall behind before it misses some.
+const eventBufferSize = 64
+
+// Event announces a change to (or an export of) a scale's data. It carries identifiers and counts
+// only, never answer content: Count is the number of responses stored, removed or exported.
+type Event struct {
+	ID            uint64    `json:"id"`
+	Type          string    `json:"type"`
+	ScaleID       string    `json:"scale_id"`
+	ParticipantID string    `json:"participant_id,omitempty"`
+	ResponseID    string    `json:"response_id,omitempty"`
+	ConsentID     string    `json:"consent_id,omitempty"`
+	Format        string    `json:"format,omitempty"`
+	Count         int       `json:"count,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// EventBus fans scale events out to in-process subscribers (e.g. live feeds). Publishing never
+// blocks: a subscriber whose buffer is full misses the event. A nil bus drops every event.
+type EventBus struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[string]map[chan Event]struct{} // scale_id -> subscriber channels
+	obs    []func(Event)
+	now    func() time.Time
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[string]map[chan Event]struct{}{}, now: time.Now}
+}
+
+// Publish assigns the event its sequence number and time, delivers it to the scale's subscribers and
+// then hands it to every observer.
+func (b *EventBus) Publish(ev Event) {
+	if b == nil || ev.ScaleID == "" {
+		return
+	}
+	b.mu.Lock()
+	b.nextID++
+	ev.ID = b.nextID
+	if ev.Time.IsZero() {
+		ev.Time = b.now().UTC()
+	}
+	for ch := range b.subs[ev.ScaleID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	obs := b.obs
+	b.mu.Unlock()
+	for _, fn := range obs {
+		fn(ev)
+	}
+}
+
+// Observe registers fn for the events of all scales. Unlike subscribers, observers run synchronously
+// in the publishing goroutine and never miss an event, so they must return quickly.
+func (b *EventBus) Observe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.obs = append(b.obs[:len(b.obs):len(b.obs)], fn)
+}
+
+// Subscribe returns the events of one scale and a function that ends the subscription and closes
+// the channel.
+func (b *EventBus) Subscribe(scaleID string) (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	if b.subs[scaleID] == nil {
+		b.subs[scaleID] = map[chan Event]struct{}{}
+	}
+	b.subs[scaleID][ch] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[scaleID], ch)
+			if len(b.subs[scaleID]) == 0 {
+				delete(b.subs, scaleID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
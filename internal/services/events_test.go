@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventBusDeliversPerScale(t *testing.T) {
+	bus := NewEventBus()
+	s1, cancel1 := bus.Subscribe("S1")
+	s2, cancel2 := bus.Subscribe("S2")
+	defer cancel2()
+
+	bus.Publish(Event{Type: EventConsentSigned, ScaleID: "S1", ConsentID: "C1"})
+	bus.Publish(Event{Type: EventConsentSigned, ScaleID: "S2", ConsentID: "C2"})
+
+	ev := <-s1
+	if ev.ID != 1 || ev.ConsentID != "C1" || ev.Time.IsZero() {
+		t.Fatalf("unexpected S1 event %+v", ev)
+	}
+	if ev = <-s2; ev.ID != 2 || ev.ConsentID != "C2" {
+		t.Fatalf("unexpected S2 event %+v", ev)
+	}
+
+	cancel1()
+	cancel1() // idempotent
+	if _, open := <-s1; open {
+		t.Fatalf("cancelled subscription should be closed")
+	}
+	bus.Publish(Event{Type: EventConsentSigned, ScaleID: "S1"}) // no subscriber left: must not panic
+
+	// a subscriber that stops reading misses events instead of blocking publishers
+	for i := 0; i < eventBufferSize+10; i++ {
+		bus.Publish(Event{Type: EventResponseSubmitted, ScaleID: "S2", Count: 1})
+	}
+	if len(s2) != eventBufferSize {
+		t.Fatalf("buffered %d events, want %d", len(s2), eventBufferSize)
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(Event{Type: EventConsentSigned, ScaleID: "S1"})
+}
+
+func TestServicesPublishEvents(t *testing.T) {
+	bus := NewEventBus()
+	feed, cancel := bus.Subscribe("S1")
+	defer cancel()
+
+	store := &stubBulkStore{
+		scale: &Scale{ID: "S1", Points: 5},
+		items: map[string]*Item{"I1": {ID: "I1", Type: "likert"}},
+	}
+	responses := NewResponseService(store)
+	responses.WithEvents(bus)
+	responses.idGenerator = func() string { return "P1" }
+	raw := 3
+	if _, err := responses.ProcessBulkResponses(BulkResponsesRequest{ScaleID: "S1", Answers: []BulkAnswer{{ItemID: "I1", RawInt: &raw}}}); err != nil {
+		t.Fatalf("ProcessBulkResponses: %v", err)
+	}
+	ev := <-feed
+	if ev.Type != EventResponseSubmitted || ev.ParticipantID != "P1" || ev.Count != 1 {
+		t.Fatalf("unexpected submission event %+v", ev)
+	}
+
+	pstore := newStubParticipantStore()
+	pstore.participants["P1"] = &Participant{ID: "P1", SelfToken: "tok"}
+	pstore.responses["P1"] = []*Response{{ParticipantID: "P1", ItemID: "I1"}, {ParticipantID: "P1", ItemID: "I2"}}
+	pstore.e2ee["R1"] = &E2EEResponse{ScaleID: "S1", ResponseID: "R1", SelfToken: "tok2", CreatedAt: time.Now()}
+	participants := NewParticipantDataService(pstore)
+	participants.WithEvents(bus)
+	if err := participants.DeleteParticipant("P1", "tok", true); err != nil {
+		t.Fatalf("DeleteParticipant: %v", err)
+	}
+	if ev = <-feed; ev.Type != EventParticipantDeleted || ev.ParticipantID != "P1" || ev.Count != 2 {
+		t.Fatalf("unexpected deletion event %+v", ev)
+	}
+	if err := participants.DeleteE2EE("R1", "tok2"); err != nil {
+		t.Fatalf("DeleteE2EE: %v", err)
+	}
+	if ev = <-feed; ev.Type != EventE2EEResponseDeleted || ev.ResponseID != "R1" {
+		t.Fatalf("unexpected E2EE deletion event %+v", ev)
+	}
+
+	// events never carry answer content
+	b, _ := json.Marshal(ev)
+	var fields map[string]any
+	_ = json.Unmarshal(b, &fields)
+	for k := range fields {
+		switch k {
+		case "id", "type", "scale_id", "participant_id", "response_id", "consent_id", "count", "time":
+		default:
+			t.Fatalf("unexpected event field %q", k)
+		}
+	}
+}
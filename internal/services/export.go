@@ -142,6 +142,35 @@ func ExportScoreCSV(inputs map[string][]int) ([]byte, error) {
 	return buf.Bytes(), w.Error()
 }
 
+// ExportScoreColumnsCSV extends the score export with derived columns (IRT θ, norm scores);
+// participants without a value for a column get an empty cell.
+func ExportScoreColumnsCSV(inputs map[string][]int, columns []string, values map[string]map[string]string) ([]byte, error) {
+	pids := make([]string, 0, len(inputs))
+	for pid := range inputs {
+		pids = append(pids, pid)
+	}
+	sort.Strings(pids)
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	_ = w.Write(append([]string{"participant_id", "total_score"}, columns...))
+	for _, pid := range pids {
+		sum := 0
+		for _, v := range inputs[pid] {
+			sum += v
+		}
+		row := []string{pid, itoa(sum)}
+		for _, col := range columns {
+			row = append(row, values[pid][col])
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
 func itoa(i int) string {
 	// local small int->string to avoid importing strconv everywhere
 	// handles small ints typical for Likert scores
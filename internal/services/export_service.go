@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -13,9 +14,12 @@ type ExportStore interface {
 	ListResponsesByScale(scaleID string) ([]*Response, error)
 	GetParticipant(id string) (*Participant, error)
 	GetConsentByID(id string) (*ConsentRecord, error)
+	ListIRTCalibrations(scaleID string) ([]*IRTCalibration, error)
+	ListNormTables(scaleID string) ([]*NormTable, error)
 }
 
 type ExportParams struct {
+	TenantID      string // the caller's tenant; the scale must belong to it
 	ScaleID       string
 	Format        string
 	ConsentHeader string
@@ -31,14 +35,33 @@ type ExportResult struct {
 }
 
 type ExportService struct {
-	store ExportStore
+	store  ExportStore
+	events *EventBus
 }
 
 func NewExportService(store ExportStore) *ExportService {
 	return &ExportService{store: store}
 }
 
+// WithEvents announces completed exports on the bus.
+func (s *ExportService) WithEvents(bus *EventBus) {
+	s.events = bus
+}
+
 func (s *ExportService) ExportCSV(params ExportParams) (*ExportResult, error) {
+	res, err := s.exportCSV(params)
+	if err != nil {
+		return nil, err
+	}
+	format := params.Format
+	if format == "" {
+		format = "long"
+	}
+	s.events.Publish(Event{Type: EventExportDownloaded, ScaleID: params.ScaleID, Format: format})
+	return res, nil
+}
+
+func (s *ExportService) exportCSV(params ExportParams) (*ExportResult, error) {
 	if params.ScaleID == "" {
 		return nil, NewInvalidError("scale_id required")
 	}
@@ -50,6 +73,9 @@ func (s *ExportService) ExportCSV(params ExportParams) (*ExportResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	if sc == nil || sc.TenantID != params.TenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
 	items, err := s.store.ListItems(params.ScaleID)
 	if err != nil {
 		return nil, err
@@ -133,7 +159,16 @@ func (s *ExportService) ExportCSV(params ExportParams) (*ExportResult, error) {
 			return nil, err
 		}
 		totals := buildTotals(items, rs)
-		b, err := ExportScoreCSV(totals)
+		columns, values, err := s.scoreColumns(sc, items, rs)
+		if err != nil {
+			return nil, err
+		}
+		var b []byte
+		if len(columns) > 0 {
+			b, err = ExportScoreColumnsCSV(totals, columns, values)
+		} else {
+			b, err = ExportScoreCSV(totals)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -143,6 +178,53 @@ func (s *ExportService) ExportCSV(params ExportParams) (*ExportResult, error) {
 	}
 }
 
+// scoreColumns derives the optional score export columns: θ when an IRT calibration exists for the
+// current scale version, and z/T/percentile per norm table.
+func (s *ExportService) scoreColumns(sc *Scale, items []*Item, rs []*Response) ([]string, map[string]map[string]string, error) {
+	var columns []string
+	values := map[string]map[string]string{}
+	set := func(pid, col, v string) {
+		if values[pid] == nil {
+			values[pid] = map[string]string{}
+		}
+		values[pid][col] = v
+	}
+	if sc == nil {
+		return nil, values, nil
+	}
+	cals, err := s.store.ListIRTCalibrations(sc.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	likert := filterLikertItems(items)
+	if cal := latestIRTCalibration(cals, ScaleFingerprint(sc.Points, likert)); cal != nil {
+		columns = append(columns, "theta", "theta_se")
+		for pid, p := range ScoreIRT(cal, likert, rs) {
+			set(pid, "theta", strconv.FormatFloat(p.Theta, 'f', 4, 64))
+			set(pid, "theta_se", strconv.FormatFloat(p.SE, 'f', 4, 64))
+		}
+	}
+	tables, err := s.store.ListNormTables(sc.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range tables {
+		prefix := "norm." + t.Key + "."
+		columns = append(columns, prefix+"raw", prefix+"stratum", prefix+"z", prefix+"t", prefix+"percentile")
+	}
+	for pid, scores := range ApplyNorms(tables, items, rs) {
+		for _, ns := range scores {
+			prefix := "norm." + ns.Key + "."
+			set(pid, prefix+"raw", strconv.FormatFloat(ns.Raw, 'f', -1, 64))
+			set(pid, prefix+"stratum", ns.Stratum)
+			set(pid, prefix+"z", strconv.FormatFloat(ns.Z, 'f', 4, 64))
+			set(pid, prefix+"t", strconv.FormatFloat(ns.T, 'f', 2, 64))
+			set(pid, prefix+"percentile", strconv.FormatFloat(ns.Percentile, 'f', 2, 64))
+		}
+	}
+	return columns, values, nil
+}
+
 // applyEnglishItemHeaders renames map keys (item IDs) to English stems consistently across participants.
 // If multiple items share the same English stem, suffix " (2)", "(3)" etc. to keep headers unique.
 func applyEnglishItemHeaders(mp map[string]map[string]int, items []*Item) {
@@ -13,6 +13,8 @@ type exportStubStore struct {
 	responses    []*Response
 	participants map[string]*Participant
 	consents     map[string]*ConsentRecord
+	calibrations []*IRTCalibration
+	normTables   []*NormTable
 }
 
 func newExportStubStore() *exportStubStore {
@@ -65,6 +67,26 @@ func (s *exportStubStore) GetConsentByID(id string) (*ConsentRecord, error) {
 	return nil, nil
 }
 
+func (s *exportStubStore) ListIRTCalibrations(scaleID string) ([]*IRTCalibration, error) {
+	out := []*IRTCalibration{}
+	for _, c := range s.calibrations {
+		if c.ScaleID == scaleID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (s *exportStubStore) ListNormTables(scaleID string) ([]*NormTable, error) {
+	out := []*NormTable{}
+	for _, t := range s.normTables {
+		if t.ScaleID == scaleID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
 func TestExportServiceLongWithConsent(t *testing.T) {
 	store := newExportStubStore()
 	store.scale = &Scale{ID: "S1", ConsentConfig: &ConsentConfig{Options: []ConsentOptionConf{{Key: "agree", LabelI18n: map[string]string{"en": "Agree", "zh": "同意"}}}}}
@@ -147,6 +169,22 @@ func TestExportServiceRejectsE2EE(t *testing.T) {
 	}
 }
 
+func TestExportServiceRejectsOtherTenants(t *testing.T) {
+	store := newExportStubStore()
+	store.scale = &Scale{ID: "S1", TenantID: "T1"}
+	store.items = []*Item{{ID: "I1", ScaleID: "S1"}}
+	svc := NewExportService(store)
+	for _, format := range []string{"long", "wide", "score", "items"} {
+		_, err := svc.ExportCSV(ExportParams{TenantID: "T2", ScaleID: "S1", Format: format})
+		if se, ok := AsServiceError(err); !ok || se.Code != ErrorForbidden {
+			t.Fatalf("%s export of another tenant's scale: %v", format, err)
+		}
+	}
+	if _, err := svc.ExportCSV(ExportParams{TenantID: "T1", ScaleID: "S1", Format: "long"}); err != nil {
+		t.Fatalf("own scale: %v", err)
+	}
+}
+
 func TestExportItemsCSVAllowsE2EE(t *testing.T) {
 	store := newExportStubStore()
 	store.scale = &Scale{ID: "S1", E2EEEnabled: true}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Factor extraction and rotation methods accepted by ExploratoryFactorAnalysis.
+const (
+	ExtractionPAF    = "paf"
+	ExtractionMinres = "minres"
+	RotationNone     = "none"
+	RotationVarimax  = "varimax"
+	RotationOblimin  = "oblimin"
+)
+
+// KMO returns the overall Kaiser-Meyer-Olkin measure of sampling adequacy and the per-item MSA.
+func KMO(corr [][]float64) (float64, []float64) {
+	k := len(corr)
+	inv := robustInverse(corr)
+	msa := make([]float64, k)
+	var sumR, sumP float64
+	for i := 0; i < k; i++ {
+		var ri, pi float64
+		for j := 0; j < k; j++ {
+			if i == j {
+				continue
+			}
+			p := -inv[i][j] / math.Sqrt(inv[i][i]*inv[j][j])
+			ri += corr[i][j] * corr[i][j]
+			pi += p * p
+		}
+		if ri+pi > 0 {
+			msa[i] = ri / (ri + pi)
+		}
+		sumR += ri
+		sumP += pi
+	}
+	if sumR+sumP == 0 {
+		return 0, msa
+	}
+	return sumR / (sumR + sumP), msa
+}
+
+// BartlettSphericity tests whether the correlation matrix is an identity matrix.
+func BartlettSphericity(corr [][]float64, n int) (chi2 float64, df int, p float64) {
+	k := len(corr)
+	values, _ := symmetricEigen(corr)
+	var logDet float64
+	for _, v := range values {
+		logDet += math.Log(math.Max(v, 1e-12))
+	}
+	chi2 = -(float64(n) - 1 - float64(2*k+5)/6) * logDet
+	df = k * (k - 1) / 2
+	return chi2, df, ChiSquareSurvival(chi2, float64(df))
+}
+
+// ParallelAnalysis implements Horn's method: eigenvalues of the observed correlation matrix are
+// compared with those of correlation matrices of normally distributed random data of the same
+// shape. It returns the mean and 95th percentile random eigenvalues and the number of leading
+// observed eigenvalues that exceed the 95th percentile.
+func ParallelAnalysis(observed []float64, n, k, iterations int, rng *rand.Rand) ([]float64, []float64, int) {
+	draws := make([][]float64, k)
+	data := make([][]float64, n)
+	for i := range data {
+		data[i] = make([]float64, k)
+	}
+	for it := 0; it < iterations; it++ {
+		for i := range data {
+			for j := range data[i] {
+				data[i][j] = rng.NormFloat64()
+			}
+		}
+		_, corr, _ := pairwiseMoments(data, k)
+		values, _ := symmetricEigen(corr)
+		for j, v := range values {
+			draws[j] = append(draws[j], v)
+		}
+	}
+	mean := make([]float64, k)
+	p95 := make([]float64, k)
+	for j := range draws {
+		sort.Float64s(draws[j])
+		for _, v := range draws[j] {
+			mean[j] += v
+		}
+		if len(draws[j]) > 0 {
+			mean[j] /= float64(len(draws[j]))
+		}
+		p95[j] = quantile(draws[j], 0.95)
+	}
+	suggested := 0
+	for j := 0; j < k && j < len(observed); j++ {
+		if observed[j] <= p95[j] {
+			break
+		}
+		suggested++
+	}
+	return mean, p95, suggested
+}
+
+// extractFactors returns unrotated loadings [k][m] by iterated principal axis factoring or by
+// Harman's row-wise minimum residual method. Communalities are capped below 1.
+func extractFactors(corr [][]float64, m int, method string) [][]float64 {
+	k := len(corr)
+	h2 := squaredMultipleCorrelations(corr)
+	loadings := principalAxes(corr, h2, m)
+	if method != ExtractionMinres {
+		for iter := 0; iter < 500; iter++ {
+			var delta float64
+			for i := range loadings {
+				next := rowSS(loadings[i])
+				delta = math.Max(delta, math.Abs(next-h2[i]))
+				h2[i] = next
+			}
+			if delta < 1e-6 {
+				break
+			}
+			loadings = principalAxes(corr, h2, m)
+		}
+		return loadings
+	}
+	for sweep := 0; sweep < 500; sweep++ {
+		var delta float64
+		for i := 0; i < k; i++ {
+			a := make([][]float64, m)
+			for r := range a {
+				a[r] = make([]float64, m)
+			}
+			b := make([]float64, m)
+			for j := 0; j < k; j++ {
+				if j == i {
+					continue
+				}
+				for r := 0; r < m; r++ {
+					b[r] += corr[i][j] * loadings[j][r]
+					for c := 0; c < m; c++ {
+						a[r][c] += loadings[j][r] * loadings[j][c]
+					}
+				}
+			}
+			inv := robustInverse(a)
+			next := make([]float64, m)
+			for r := 0; r < m; r++ {
+				for c := 0; c < m; c++ {
+					next[r] += inv[r][c] * b[c]
+				}
+			}
+			if ss := rowSS(next); ss > 0.995 {
+				f := math.Sqrt(0.995 / ss)
+				for r := range next {
+					next[r] *= f
+				}
+			}
+			for r := range next {
+				delta = math.Max(delta, math.Abs(next[r]-loadings[i][r]))
+			}
+			loadings[i] = next
+		}
+		if delta < 1e-6 {
+			break
+		}
+	}
+	// return the minres solution in its principal-axes orientation
+	for i := range h2 {
+		h2[i] = rowSS(loadings[i])
+	}
+	return principalAxes(corr, h2, m)
+}
+
+func principalAxes(corr [][]float64, h2 []float64, m int) [][]float64 {
+	k := len(corr)
+	reduced := make([][]float64, k)
+	for i := range corr {
+		reduced[i] = append([]float64(nil), corr[i]...)
+		reduced[i][i] = math.Max(0, math.Min(0.995, h2[i]))
+	}
+	values, vectors := symmetricEigen(reduced)
+	loadings := make([][]float64, k)
+	for i := range loadings {
+		loadings[i] = make([]float64, m)
+		for f := 0; f < m; f++ {
+			loadings[i][f] = vectors[f][i] * math.Sqrt(math.Max(0, values[f]))
+		}
+	}
+	return loadings
+}
+
+func squaredMultipleCorrelations(corr [][]float64) []float64 {
+	inv := robustInverse(corr)
+	h2 := make([]float64, len(corr))
+	for j := range h2 {
+		h2[j] = math.Max(0, 1-1/inv[j][j])
+	}
+	return h2
+}
+
+// robustInverse inverts a matrix, regularising it with a small ridge when it is singular.
+func robustInverse(a [][]float64) [][]float64 {
+	if inv, ok := invertMatrix(a); ok {
+		return inv
+	}
+	var trace float64
+	for i := range a {
+		trace += math.Abs(a[i][i])
+	}
+	eps := 1e-6 * math.Max(trace/float64(len(a)), 1e-12)
+	for {
+		if inv, ok := invertMatrix(withRidge(a, eps)); ok {
+			return inv
+		}
+		eps *= 10
+	}
+}
+
+// rotateLoadings rotates loadings [k][m] and returns the pattern matrix and the factor correlation
+// matrix (identity for orthogonal rotations). Varimax uses Kaiser normalisation; oblimin is the
+// direct quartimin criterion (gamma = 0). Both are fitted by gradient projection (Jennrich 2001/2002).
+func rotateLoadings(a [][]float64, rotation string) ([][]float64, [][]float64) {
+	m := 0
+	if len(a) > 0 {
+		m = len(a[0])
+	}
+	if m < 2 || rotation == RotationNone || rotation == "" {
+		return a, identity(m)
+	}
+	if rotation == RotationVarimax {
+		weights := make([]float64, len(a))
+		norm := make([][]float64, len(a))
+		for i, row := range a {
+			weights[i] = math.Sqrt(rowSS(row))
+			norm[i] = make([]float64, m)
+			for f := range row {
+				if weights[i] > 0 {
+					norm[i][f] = row[f] / weights[i]
+				}
+			}
+		}
+		t := gpaOrthogonal(norm, varimaxCriterion)
+		l := matMul(a, t)
+		return l, identity(m)
+	}
+	t := gpaOblique(a, obliminCriterion)
+	return matMul(a, transpose(robustInverse(t))), matMul(transpose(t), t)
+}
+
+type rotationCriterion func(l [][]float64) (float64, [][]float64)
+
+func varimaxCriterion(l [][]float64) (float64, [][]float64) {
+	k, m := len(l), len(l[0])
+	means := make([]float64, m)
+	for i := 0; i < k; i++ {
+		for f := 0; f < m; f++ {
+			means[f] += l[i][f] * l[i][f] / float64(k)
+		}
+	}
+	var f float64
+	gq := make([][]float64, k)
+	for i := 0; i < k; i++ {
+		gq[i] = make([]float64, m)
+		for c := 0; c < m; c++ {
+			ql := l[i][c]*l[i][c] - means[c]
+			f += ql * ql
+			gq[i][c] = -l[i][c] * ql
+		}
+	}
+	return -f / 4, gq
+}
+
+func obliminCriterion(l [][]float64) (float64, [][]float64) {
+	k, m := len(l), len(l[0])
+	var f float64
+	gq := make([][]float64, k)
+	for i := 0; i < k; i++ {
+		gq[i] = make([]float64, m)
+		var rowSq float64
+		for c := 0; c < m; c++ {
+			rowSq += l[i][c] * l[i][c]
+		}
+		for c := 0; c < m; c++ {
+			others := rowSq - l[i][c]*l[i][c]
+			f += l[i][c] * l[i][c] * others
+			gq[i][c] = l[i][c] * others
+		}
+	}
+	return f / 4, gq
+}
+
+func gpaOrthogonal(a [][]float64, crit rotationCriterion) [][]float64 {
+	m := len(a[0])
+	t := identity(m)
+	f, gq := crit(matMul(a, t))
+	g := matMul(transpose(a), gq)
+	al := 1.0
+	for iter := 0; iter < 1000; iter++ {
+		mm := matMul(transpose(t), g)
+		gp := make([][]float64, m)
+		for i := range gp {
+			gp[i] = make([]float64, m)
+		}
+		sym := make([][]float64, m)
+		for i := range sym {
+			sym[i] = make([]float64, m)
+			for j := range sym[i] {
+				sym[i][j] = (mm[i][j] + mm[j][i]) / 2
+			}
+		}
+		ts := matMul(t, sym)
+		var s float64
+		for i := range gp {
+			for j := range gp[i] {
+				gp[i][j] = g[i][j] - ts[i][j]
+				s += gp[i][j] * gp[i][j]
+			}
+		}
+		s = math.Sqrt(s)
+		if s < 1e-6 {
+			break
+		}
+		al *= 2
+		var tt [][]float64
+		var ft float64
+		var gqt [][]float64
+		for step := 0; step <= 10; step++ {
+			x := matAdd(t, gp, -al)
+			tt = polarFactor(x)
+			ft, gqt = crit(matMul(a, tt))
+			if f-ft > 0.5*s*s*al {
+				break
+			}
+			al /= 2
+		}
+		t, f = tt, ft
+		g = matMul(transpose(a), gqt)
+	}
+	return t
+}
+
+func gpaOblique(a [][]float64, crit rotationCriterion) [][]float64 {
+	m := len(a[0])
+	t := identity(m)
+	gradient := func(t [][]float64) (float64, [][]float64) {
+		inv := robustInverse(t)
+		l := matMul(a, transpose(inv))
+		f, gq := crit(l)
+		return f, scaleMatrix(transpose(matMul(matMul(transpose(l), gq), inv)), -1)
+	}
+	f, g := gradient(t)
+	al := 1.0
+	for iter := 0; iter < 1000; iter++ {
+		gp := make([][]float64, m)
+		var s float64
+		for i := range gp {
+			gp[i] = make([]float64, m)
+		}
+		for c := 0; c < m; c++ {
+			var d float64
+			for r := 0; r < m; r++ {
+				d += t[r][c] * g[r][c]
+			}
+			for r := 0; r < m; r++ {
+				gp[r][c] = g[r][c] - t[r][c]*d
+				s += gp[r][c] * gp[r][c]
+			}
+		}
+		s = math.Sqrt(s)
+		if s < 1e-6 {
+			break
+		}
+		al *= 2
+		var tt, gt [][]float64
+		var ft float64
+		for step := 0; step <= 10; step++ {
+			x := matAdd(t, gp, -al)
+			for c := 0; c < m; c++ {
+				var norm float64
+				for r := 0; r < m; r++ {
+					norm += x[r][c] * x[r][c]
+				}
+				norm = math.Sqrt(norm)
+				for r := 0; r < m; r++ {
+					x[r][c] /= norm
+				}
+			}
+			tt = x
+			ft, gt = gradient(tt)
+			if f-ft > 0.5*s*s*al {
+				break
+			}
+			al /= 2
+		}
+		t, f, g = tt, ft, gt
+	}
+	return t
+}
+
+// polarFactor returns the orthogonal factor X (XᵀX)^(-1/2) of a square matrix.
+func polarFactor(x [][]float64) [][]float64 {
+	values, vectors := symmetricEigen(matMul(transpose(x), x))
+	m := len(x)
+	invSqrt := make([][]float64, m)
+	for i := range invSqrt {
+		invSqrt[i] = make([]float64, m)
+		for j := range invSqrt[i] {
+			for e := range values {
+				invSqrt[i][j] += vectors[e][i] * vectors[e][j] / math.Sqrt(math.Max(values[e], 1e-12))
+			}
+		}
+	}
+	return matMul(x, invSqrt)
+}
+
+func rowSS(row []float64) float64 {
+	var s float64
+	for _, v := range row {
+		s += v * v
+	}
+	return s
+}
+
+func identity(n int) [][]float64 {
+	out := make([][]float64, n)
+	for i := range out {
+		out[i] = make([]float64, n)
+		out[i][i] = 1
+	}
+	return out
+}
+
+func transpose(a [][]float64) [][]float64 {
+	if len(a) == 0 {
+		return nil
+	}
+	out := make([][]float64, len(a[0]))
+	for j := range out {
+		out[j] = make([]float64, len(a))
+		for i := range a {
+			out[j][i] = a[i][j]
+		}
+	}
+	return out
+}
+
+func matMul(a, b [][]float64) [][]float64 {
+	out := make([][]float64, len(a))
+	for i := range a {
+		out[i] = make([]float64, len(b[0]))
+		for k := range b {
+			if a[i][k] == 0 {
+				continue
+			}
+			for j := range b[k] {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+// matAdd returns a + f·b.
+func matAdd(a, b [][]float64, f float64) [][]float64 {
+	out := make([][]float64, len(a))
+	for i := range a {
+		out[i] = make([]float64, len(a[i]))
+		for j := range a[i] {
+			out[i][j] = a[i][j] + f*b[i][j]
+		}
+	}
+	return out
+}
+
+func scaleMatrix(a [][]float64, f float64) [][]float64 {
+	return matAdd(make2D(len(a), len(a[0])), a, f)
+}
+
+func make2D(r, c int) [][]float64 {
+	out := make([][]float64, r)
+	for i := range out {
+		out[i] = make([]float64, c)
+	}
+	return out
+}
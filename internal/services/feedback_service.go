@@ -0,0 +1,542 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FeedbackSpecVersion identifies the report algorithm described by FeedbackSpec; clients computing
+// reports locally (E2EE projects) must refuse specs with an unknown version.
+const FeedbackSpecVersion = 1
+
+// Values a FeedbackScore's bands can be defined on.
+const (
+	FeedbackBasisRaw        = "raw"
+	FeedbackBasisZ          = "z"
+	FeedbackBasisT          = "t"
+	FeedbackBasisPercentile = "percentile"
+)
+
+// FeedbackStore provides the configuration and data behind participant feedback reports.
+type FeedbackStore interface {
+	GetScale(id string) (*Scale, error)
+	GetItem(id string) (*Item, error)
+	ListItems(scaleID string) ([]*Item, error)
+	ListResponsesByScale(scaleID string) ([]*Response, error)
+	GetParticipant(id string) (*Participant, error)
+	ListResponsesByParticipant(id string) ([]*Response, error)
+	ListNormTables(scaleID string) ([]*NormTable, error)
+	GetFeedbackConfig(scaleID string) (*FeedbackConfig, error)
+	SaveFeedbackConfig(c *FeedbackConfig) error
+}
+
+// FeedbackBand is an interpretation range: Min <= value < Max, either bound may be open.
+type FeedbackBand struct {
+	Min       *float64          `json:"min,omitempty"`
+	Max       *float64          `json:"max,omitempty"`
+	LabelI18n map[string]string `json:"label_i18n"`
+	TextI18n  map[string]string `json:"text_i18n,omitempty"`
+}
+
+// FeedbackScore selects one (sub)scale score for the report. ItemIDs default to all Likert items;
+// Norm names a norm table whose standardized score is reported alongside. Bands are evaluated on
+// Basis (raw by default; z, t and percentile require Norm) and the first matching band wins.
+type FeedbackScore struct {
+	Key       string            `json:"key"`
+	LabelI18n map[string]string `json:"label_i18n,omitempty"`
+	ItemIDs   []string          `json:"item_ids,omitempty"`
+	Norm      string            `json:"norm,omitempty"`
+	Basis     string            `json:"basis,omitempty"`
+	Bands     []FeedbackBand    `json:"bands,omitempty"`
+}
+
+// FeedbackConfig is the per-scale feedback report configuration.
+type FeedbackConfig struct {
+	ScaleID        string            `json:"scale_id"`
+	Enabled        bool              `json:"enabled"`
+	IntroI18n      map[string]string `json:"intro_i18n,omitempty"`
+	Scores         []FeedbackScore   `json:"scores"`
+	ShowSampleMean bool              `json:"show_sample_mean"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// FeedbackSample summarises a score over the current sample; Difference is the participant's raw
+// score minus Mean and is only set in reports.
+type FeedbackSample struct {
+	N          int      `json:"n"`
+	Mean       float64  `json:"mean"`
+	SD         float64  `json:"sd"`
+	Difference *float64 `json:"difference,omitempty"`
+}
+
+// FeedbackSpecItem carries the scoring metadata of an item. OptionsI18n is only included for items
+// that norm strata match on, so clients can map localized answers to the English labels.
+type FeedbackSpecItem struct {
+	ID            string              `json:"id"`
+	Type          string              `json:"type"`
+	ReverseScored bool                `json:"reverse_scored,omitempty"`
+	Min           int                 `json:"min,omitempty"`
+	Max           int                 `json:"max,omitempty"`
+	OptionsI18n   map[string][]string `json:"options_i18n,omitempty"`
+}
+
+// FeedbackSpec is everything needed to compute a report from a participant's own answers. Item
+// scores are the stored score values: Likert answers within 1..points (reverse-coded as
+// points+1-raw when reverse_scored), numeric/rating/slider integers within min..max (bounds of 0
+// are open), anything else 0. Scores sum the answered items; norms and bands follow the norm table
+// and FeedbackBand rules. Fingerprint is the SHA-256 prefix of the spec's JSON with an empty
+// fingerprint.
+type FeedbackSpec struct {
+	Version     int                       `json:"version"`
+	ScaleID     string                    `json:"scale_id"`
+	Points      int                       `json:"points"`
+	Items       []FeedbackSpecItem        `json:"items"`
+	IntroI18n   map[string]string         `json:"intro_i18n,omitempty"`
+	Scores      []FeedbackScore           `json:"scores"`
+	Norms       []*NormTable              `json:"norms,omitempty"`
+	Sample      map[string]FeedbackSample `json:"sample,omitempty"`
+	Fingerprint string                    `json:"fingerprint"`
+}
+
+type FeedbackScoreResult struct {
+	Key       string          `json:"key"`
+	Label     string          `json:"label"`
+	Raw       float64         `json:"raw"`
+	Answered  int             `json:"answered"`
+	Items     int             `json:"items"`
+	Norm      *NormScore      `json:"norm,omitempty"`
+	BandValue *float64        `json:"band_value,omitempty"`
+	Band      string          `json:"band,omitempty"`
+	BandText  string          `json:"band_text,omitempty"`
+	Sample    *FeedbackSample `json:"sample,omitempty"`
+}
+
+// FeedbackReport is the localized feedback shown to a participant.
+type FeedbackReport struct {
+	ScaleID         string                `json:"scale_id"`
+	ParticipantID   string                `json:"participant_id,omitempty"`
+	Lang            string                `json:"lang"`
+	Intro           string                `json:"intro,omitempty"`
+	Scores          []FeedbackScoreResult `json:"scores"`
+	SpecFingerprint string                `json:"spec_fingerprint"`
+}
+
+// FeedbackService configures and renders personalized participant feedback.
+type FeedbackService struct {
+	store FeedbackStore
+	now   func() time.Time
+}
+
+func NewFeedbackService(store FeedbackStore) *FeedbackService {
+	return &FeedbackService{store: store, now: time.Now}
+}
+
+// GetConfig returns the scale's configuration, or a disabled empty one.
+func (s *FeedbackService) GetConfig(tenantID, scaleID string) (*FeedbackConfig, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	cfg, err := s.store.GetFeedbackConfig(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = &FeedbackConfig{ScaleID: scaleID, Scores: []FeedbackScore{}}
+	}
+	return cfg, nil
+}
+
+func (s *FeedbackService) UpdateConfig(tenantID, scaleID string, in FeedbackConfig) (*FeedbackConfig, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := s.store.ListNormTables(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFeedbackConfig(&in, items, tables); err != nil {
+		return nil, err
+	}
+	in.ScaleID = scaleID
+	in.UpdatedAt = s.now().UTC()
+	if err := s.store.SaveFeedbackConfig(&in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// Spec returns the public report spec of a scale with enabled feedback. The sample summary is
+// omitted for E2EE projects, whose answers the server cannot read.
+func (s *FeedbackService) Spec(scaleID string) (*FeedbackSpec, error) {
+	spec, err := s.buildSpec(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if spec == nil {
+		return nil, NewNotFoundError("feedback not enabled")
+	}
+	return spec, nil
+}
+
+// ReportFor renders the report of a participant who just submitted; it returns nil when feedback
+// is not enabled for the scale.
+func (s *FeedbackService) ReportFor(scaleID, participantID, lang string) (*FeedbackReport, error) {
+	spec, err := s.buildSpec(scaleID)
+	if err != nil || spec == nil {
+		return nil, err
+	}
+	rs, err := s.store.ListResponsesByParticipant(participantID)
+	if err != nil {
+		return nil, err
+	}
+	report := EvaluateFeedback(spec, rs, lang)
+	report.ParticipantID = participantID
+	return report, nil
+}
+
+// SelfReport renders the report for a participant authenticated by their self token.
+func (s *FeedbackService) SelfReport(pid, token, lang string) (*FeedbackReport, error) {
+	if pid == "" || token == "" {
+		return nil, NewInvalidError("pid/token required")
+	}
+	p, err := s.store.GetParticipant(pid)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.SelfToken == "" || token != p.SelfToken {
+		return nil, NewForbiddenError("forbidden")
+	}
+	rs, err := s.store.ListResponsesByParticipant(pid)
+	if err != nil {
+		return nil, err
+	}
+	scaleID := ""
+	for _, r := range rs {
+		it, err := s.store.GetItem(r.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if it != nil {
+			scaleID = it.ScaleID
+			break
+		}
+	}
+	if scaleID == "" {
+		return nil, NewNotFoundError("no responses")
+	}
+	report, err := s.ReportFor(scaleID, pid, lang)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, NewNotFoundError("feedback not enabled")
+	}
+	return report, nil
+}
+
+func (s *FeedbackService) authorizedScale(tenantID, scaleID string) (*Scale, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	return sc, nil
+}
+
+// buildSpec resolves the enabled configuration into a spec; nil means feedback is off.
+func (s *FeedbackService) buildSpec(scaleID string) (*FeedbackSpec, error) {
+	cfg, err := s.store.GetFeedbackConfig(scaleID)
+	if err != nil || cfg == nil || !cfg.Enabled {
+		return nil, err
+	}
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, NewNotFoundError("scale not found")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := s.store.ListNormTables(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	spec := &FeedbackSpec{Version: FeedbackSpecVersion, ScaleID: scaleID, Points: sc.Points, IntroI18n: cfg.IntroI18n}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	var likert []string
+	for _, it := range items {
+		itemType := it.Type
+		if itemType == "" {
+			itemType = "likert"
+		}
+		spec.Items = append(spec.Items, FeedbackSpecItem{ID: it.ID, Type: itemType, ReverseScored: it.ReverseScored, Min: it.Min, Max: it.Max})
+		if itemType == "likert" {
+			likert = append(likert, it.ID)
+		}
+	}
+	usedNorms := map[string]bool{}
+	for _, score := range cfg.Scores {
+		usedNorms[score.Norm] = true
+	}
+	conditionItems := map[string]bool{}
+	for _, t := range tables {
+		if !usedNorms[t.Key] {
+			continue
+		}
+		spec.Norms = append(spec.Norms, t)
+		for _, st := range t.Strata {
+			for _, c := range st.Conditions {
+				conditionItems[c.ItemID] = true
+			}
+		}
+	}
+	for i, it := range items {
+		if conditionItems[it.ID] {
+			spec.Items[i].OptionsI18n = it.OptionsI18n
+		}
+	}
+	for _, score := range cfg.Scores {
+		if len(score.ItemIDs) == 0 {
+			score.ItemIDs = likert
+		}
+		if score.Basis == "" {
+			score.Basis = FeedbackBasisRaw
+		}
+		spec.Scores = append(spec.Scores, score)
+	}
+	if cfg.ShowSampleMean && !sc.E2EEEnabled {
+		rs, err := s.store.ListResponsesByScale(scaleID)
+		if err != nil {
+			return nil, err
+		}
+		spec.Sample = feedbackSample(spec.Scores, rs)
+	}
+	spec.Fingerprint = feedbackSpecFingerprint(spec)
+	return spec, nil
+}
+
+func validateFeedbackConfig(cfg *FeedbackConfig, items []*Item, tables []*NormTable) error {
+	byID := make(map[string]bool, len(items))
+	for _, it := range items {
+		byID[it.ID] = true
+	}
+	norms := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		norms[t.Key] = true
+	}
+	if cfg.Enabled && len(cfg.Scores) == 0 {
+		return NewInvalidError("at least one score required")
+	}
+	seen := map[string]bool{}
+	for i := range cfg.Scores {
+		sc := &cfg.Scores[i]
+		sc.Key = strings.ToLower(strings.TrimSpace(sc.Key))
+		if !normKeyPattern.MatchString(sc.Key) {
+			return NewInvalidError("score key must be 1-40 lowercase letters, digits, '-' or '_'")
+		}
+		if seen[sc.Key] {
+			return NewInvalidError("duplicate score key " + sc.Key)
+		}
+		seen[sc.Key] = true
+		for _, id := range sc.ItemIDs {
+			if !byID[id] {
+				return NewInvalidError("score " + sc.Key + ": unknown item " + id)
+			}
+		}
+		if sc.Norm != "" && !norms[sc.Norm] {
+			return NewInvalidError("score " + sc.Key + ": unknown norm table " + sc.Norm)
+		}
+		switch sc.Basis {
+		case "", FeedbackBasisRaw:
+		case FeedbackBasisZ, FeedbackBasisT, FeedbackBasisPercentile:
+			if sc.Norm == "" {
+				return NewInvalidError("score " + sc.Key + ": basis " + sc.Basis + " requires a norm table")
+			}
+		default:
+			return NewInvalidError("score " + sc.Key + ": basis must be raw, z, t or percentile")
+		}
+		for _, b := range sc.Bands {
+			if len(b.LabelI18n) == 0 {
+				return NewInvalidError("score " + sc.Key + ": every band needs a label")
+			}
+			if b.Min != nil && b.Max != nil && *b.Min >= *b.Max {
+				return NewInvalidError("score " + sc.Key + ": band min must be below max")
+			}
+		}
+	}
+	return nil
+}
+
+// EvaluateFeedback computes a participant's report from a spec and their responses. It implements
+// the algorithm documented on FeedbackSpec so server and client reports agree.
+func EvaluateFeedback(spec *FeedbackSpec, responses []*Response, lang string) *FeedbackReport {
+	lang = defaultLang(lang)
+	report := &FeedbackReport{
+		ScaleID:         spec.ScaleID,
+		Lang:            lang,
+		Intro:           pickI18n(spec.IntroI18n, lang),
+		Scores:          []FeedbackScoreResult{},
+		SpecFingerprint: spec.Fingerprint,
+	}
+	items := make([]*Item, 0, len(spec.Items))
+	for _, it := range spec.Items {
+		items = append(items, &Item{ID: it.ID, ScaleID: spec.ScaleID, Type: it.Type, ReverseScored: it.ReverseScored})
+	}
+	norms := make(map[string]*NormTable, len(spec.Norms))
+	for _, t := range spec.Norms {
+		norms[t.Key] = t
+	}
+	for _, score := range spec.Scores {
+		raw, answered := feedbackRawScore(score.ItemIDs, responses)
+		if answered == 0 {
+			continue
+		}
+		res := FeedbackScoreResult{
+			Key:      score.Key,
+			Label:    pickI18n(score.LabelI18n, lang),
+			Raw:      raw,
+			Answered: answered,
+			Items:    len(score.ItemIDs),
+		}
+		if res.Label == "" {
+			res.Label = score.Key
+		}
+		if t := norms[score.Norm]; t != nil {
+			if ns := NormScoresFor([]*NormTable{t}, items, responses); len(ns) == 1 {
+				res.Norm = &ns[0]
+			}
+		}
+		if v, ok := feedbackBandValue(score.Basis, raw, res.Norm); ok {
+			res.BandValue = &v
+			for _, b := range score.Bands {
+				if (b.Min == nil || v >= *b.Min) && (b.Max == nil || v < *b.Max) {
+					res.Band = pickI18n(b.LabelI18n, lang)
+					res.BandText = pickI18n(b.TextI18n, lang)
+					break
+				}
+			}
+		}
+		if smp, ok := spec.Sample[score.Key]; ok && smp.N > 0 {
+			diff := roundTo(raw-smp.Mean, 4)
+			smp.Difference = &diff
+			res.Sample = &smp
+		}
+		report.Scores = append(report.Scores, res)
+	}
+	return report
+}
+
+func feedbackRawScore(itemIDs []string, responses []*Response) (float64, int) {
+	want := make(map[string]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		want[id] = true
+	}
+	var raw float64
+	answered := 0
+	seen := map[string]bool{}
+	for _, r := range responses {
+		if want[r.ItemID] && !seen[r.ItemID] {
+			seen[r.ItemID] = true
+			raw += float64(r.ScoreValue)
+			answered++
+		}
+	}
+	return raw, answered
+}
+
+func feedbackBandValue(basis string, raw float64, norm *NormScore) (float64, bool) {
+	switch basis {
+	case "", FeedbackBasisRaw:
+		return raw, true
+	case FeedbackBasisZ, FeedbackBasisT, FeedbackBasisPercentile:
+		if norm == nil {
+			return 0, false
+		}
+		switch basis {
+		case FeedbackBasisZ:
+			return norm.Z, true
+		case FeedbackBasisT:
+			return norm.T, true
+		}
+		return norm.Percentile, true
+	}
+	return 0, false
+}
+
+// feedbackSample summarises each score over all participants who answered at least one of its items.
+func feedbackSample(scores []FeedbackScore, responses []*Response) map[string]FeedbackSample {
+	byParticipant := map[string][]*Response{}
+	for _, r := range responses {
+		byParticipant[r.ParticipantID] = append(byParticipant[r.ParticipantID], r)
+	}
+	out := map[string]FeedbackSample{}
+	for _, score := range scores {
+		var values []float64
+		for _, rs := range byParticipant {
+			if raw, answered := feedbackRawScore(score.ItemIDs, rs); answered > 0 {
+				values = append(values, raw)
+			}
+		}
+		if len(values) == 0 {
+			out[score.Key] = FeedbackSample{}
+			continue
+		}
+		var sum, ss float64
+		for _, v := range values {
+			sum += v
+		}
+		mean := sum / float64(len(values))
+		for _, v := range values {
+			ss += (v - mean) * (v - mean)
+		}
+		sd := 0.0
+		if len(values) > 1 {
+			sd = math.Sqrt(ss / float64(len(values)-1))
+		}
+		out[score.Key] = FeedbackSample{N: len(values), Mean: roundTo(mean, 4), SD: roundTo(sd, 4)}
+	}
+	return out
+}
+
+func feedbackSpecFingerprint(spec *FeedbackSpec) string {
+	cp := *spec
+	cp.Fingerprint = ""
+	b, _ := json.Marshal(&cp)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// pickI18n returns the text for lang, falling back to English and then to any translation.
+func pickI18n(m map[string]string, lang string) string {
+	if v := m[lang]; v != "" {
+		return v
+	}
+	if v := m["en"]; v != "" {
+		return v
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if m[k] != "" {
+			return m[k]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type stubFeedbackStore struct {
+	stubNormStore
+	participants map[string]*Participant
+	config       *FeedbackConfig
+}
+
+func (s *stubFeedbackStore) GetItem(id string) (*Item, error) {
+	for _, it := range s.items {
+		if it.ID == id {
+			cp := *it
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubFeedbackStore) GetParticipant(id string) (*Participant, error) {
+	return s.participants[id], nil
+}
+
+func (s *stubFeedbackStore) ListResponsesByParticipant(id string) ([]*Response, error) {
+	out := []*Response{}
+	for _, r := range s.responses {
+		if r.ParticipantID == id {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubFeedbackStore) GetFeedbackConfig(string) (*FeedbackConfig, error) {
+	return s.config, nil
+}
+
+func (s *stubFeedbackStore) SaveFeedbackConfig(c *FeedbackConfig) error {
+	cp := *c
+	s.config = &cp
+	return nil
+}
+
+func newFeedbackFixture(t *testing.T) (*FeedbackService, *stubFeedbackStore) {
+	t.Helper()
+	store := &stubFeedbackStore{
+		stubNormStore: *newNormFixture(),
+		participants:  map[string]*Participant{"p1": {ID: "p1", SelfToken: "tok"}},
+	}
+	store.tables = []*NormTable{{
+		ScaleID: "S1",
+		Key:     "sum",
+		ItemIDs: []string{"q1", "q2"},
+		Strata:  []NormStratum{{Label: "adults", Mean: 6, SD: 2}},
+	}}
+	return NewFeedbackService(store), store
+}
+
+func feedbackTestConfig() FeedbackConfig {
+	return FeedbackConfig{
+		Enabled:        true,
+		IntroI18n:      map[string]string{"en": "Your results", "zh": "你的结果"},
+		ShowSampleMean: true,
+		Scores: []FeedbackScore{
+			{
+				Key:       "Wellbeing",
+				LabelI18n: map[string]string{"en": "Wellbeing", "zh": "幸福感"},
+				Norm:      "sum",
+				Basis:     FeedbackBasisT,
+				Bands: []FeedbackBand{
+					{Max: floatPtr(40), LabelI18n: map[string]string{"en": "Low"}},
+					{Min: floatPtr(40), Max: floatPtr(60), LabelI18n: map[string]string{"en": "Average", "zh": "一般"}},
+					{Min: floatPtr(60), LabelI18n: map[string]string{"en": "High", "zh": "高"}, TextI18n: map[string]string{"en": "Above most people."}},
+				},
+			},
+			{Key: "first", ItemIDs: []string{"q1"}},
+		},
+	}
+}
+
+func TestFeedbackConfigValidation(t *testing.T) {
+	svc, _ := newFeedbackFixture(t)
+	if _, err := svc.UpdateConfig("T2", "S1", feedbackTestConfig()); err == nil {
+		t.Fatalf("expected forbidden for another tenant")
+	}
+	bad := map[string]func(c *FeedbackConfig){
+		"no scores":     func(c *FeedbackConfig) { c.Scores = nil },
+		"duplicate key": func(c *FeedbackConfig) { c.Scores[1].Key = "wellbeing" },
+		"unknown item":  func(c *FeedbackConfig) { c.Scores[1].ItemIDs = []string{"nope"} },
+		"unknown norm":  func(c *FeedbackConfig) { c.Scores[0].Norm = "other" },
+		"t without norm": func(c *FeedbackConfig) {
+			c.Scores[1].Basis = FeedbackBasisT
+		},
+		"unlabeled band": func(c *FeedbackConfig) { c.Scores[0].Bands[0].LabelI18n = nil },
+		"inverted band":  func(c *FeedbackConfig) { c.Scores[0].Bands[1].Min = floatPtr(70) },
+	}
+	for name, mutate := range bad {
+		cfg := feedbackTestConfig()
+		mutate(&cfg)
+		if _, err := svc.UpdateConfig("T1", "S1", cfg); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	if _, err := svc.Spec("S1"); err == nil {
+		t.Fatalf("expected not found before feedback is enabled")
+	}
+	saved, err := svc.UpdateConfig("T1", "S1", feedbackTestConfig())
+	if err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if saved.Scores[0].Key != "wellbeing" || saved.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected saved config %+v", saved)
+	}
+}
+
+func TestFeedbackReport(t *testing.T) {
+	svc, store := newFeedbackFixture(t)
+	if report, err := svc.ReportFor("S1", "p1", "en"); err != nil || report != nil {
+		t.Fatalf("disabled feedback should yield no report: %+v %v", report, err)
+	}
+	if _, err := svc.UpdateConfig("T1", "S1", feedbackTestConfig()); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	report, err := svc.ReportFor("S1", "p1", "zh")
+	if err != nil {
+		t.Fatalf("ReportFor: %v", err)
+	}
+	if report.Intro != "你的结果" || len(report.Scores) != 2 || report.SpecFingerprint == "" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	wb := report.Scores[0]
+	// p1 answered 4 + 4 on the two Likert items: T = 60 is the lower edge of "High"
+	if wb.Label != "幸福感" || wb.Raw != 8 || wb.Items != 2 || wb.Norm == nil || wb.Norm.T != 60 || wb.Band != "高" {
+		t.Fatalf("unexpected wellbeing score %+v", wb)
+	}
+	if wb.BandText != "Above most people." {
+		t.Fatalf("band text should fall back to English, got %q", wb.BandText)
+	}
+	// sample raw totals are 8, 5 and 10
+	if wb.Sample == nil || wb.Sample.N != 3 || wb.Sample.Mean != 7.6667 || *wb.Sample.Difference != 0.3333 {
+		t.Fatalf("unexpected sample comparison %+v", wb.Sample)
+	}
+	if first := report.Scores[1]; first.Label != "first" || first.Raw != 4 || first.Band != "" || first.Norm != nil {
+		t.Fatalf("unexpected unbanded score %+v", first)
+	}
+
+	if _, err := svc.SelfReport("p1", "wrong", "en"); err == nil {
+		t.Fatalf("expected forbidden for a wrong self token")
+	}
+	self, err := svc.SelfReport("p1", "tok", "en")
+	if err != nil || self.Scores[0].Band != "High" {
+		t.Fatalf("SelfReport: %+v %v", self, err)
+	}
+
+	// a client evaluating the published spec on the same answers reaches the same report
+	spec, err := svc.Spec("S1")
+	if err != nil {
+		t.Fatalf("Spec: %v", err)
+	}
+	b, _ := json.Marshal(spec)
+	var decoded FeedbackSpec
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	if decoded.Fingerprint != feedbackSpecFingerprint(&decoded) {
+		t.Fatalf("fingerprint does not survive a JSON round trip")
+	}
+	local := EvaluateFeedback(&decoded, store.responses[:4], "en")
+	wantJSON, _ := json.Marshal(self.Scores)
+	gotJSON, _ := json.Marshal(local.Scores)
+	if string(wantJSON) != string(gotJSON) {
+		t.Fatalf("local evaluation differs:\n%s\n%s", gotJSON, wantJSON)
+	}
+
+	store.scale.E2EEEnabled = true
+	spec, err = svc.Spec("S1")
+	if err != nil || spec.Sample != nil {
+		t.Fatalf("E2EE spec must not carry sample statistics: %+v %v", spec, err)
+	}
+}
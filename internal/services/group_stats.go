@@ -0,0 +1,245 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+type WelchTTest struct {
+	T      float64 `json:"t"`
+	DF     float64 `json:"df"`
+	P      float64 `json:"p"`
+	CohenD float64 `json:"cohen_d"`
+}
+
+type ANOVAResult struct {
+	F          float64 `json:"f"`
+	DFBetween  int     `json:"df_between"`
+	DFWithin   int     `json:"df_within"`
+	P          float64 `json:"p"`
+	EtaSquared float64 `json:"eta_squared"`
+}
+
+type MannWhitneyResult struct {
+	U float64 `json:"u"`
+	Z float64 `json:"z"`
+	P float64 `json:"p"`
+}
+
+type KruskalWallisResult struct {
+	H  float64 `json:"h"`
+	DF int     `json:"df"`
+	P  float64 `json:"p"`
+}
+
+type ChiSquareResult struct {
+	ChiSquare float64 `json:"chi_square"`
+	DF        int     `json:"df"`
+	P         float64 `json:"p"`
+	CramersV  float64 `json:"cramers_v"`
+}
+
+// WelchT compares two means without assuming equal variances. Cohen's d uses the pooled SD.
+func WelchT(a, b []float64) *WelchTTest {
+	na, nb := float64(len(a)), float64(len(b))
+	if na < 2 || nb < 2 {
+		return nil
+	}
+	sa, sb := Describe(a), Describe(b)
+	va, vb := sa.SD*sa.SD, sb.SD*sb.SD
+	se2 := va/na + vb/nb
+	res := &WelchTTest{}
+	if pooled := math.Sqrt(((na-1)*va + (nb-1)*vb) / (na + nb - 2)); pooled > 0 {
+		res.CohenD = (sa.Mean - sb.Mean) / pooled
+	}
+	if se2 == 0 {
+		res.P = 1
+		return res
+	}
+	res.T = (sa.Mean - sb.Mean) / math.Sqrt(se2)
+	res.DF = se2 * se2 / ((va/na)*(va/na)/(na-1) + (vb/nb)*(vb/nb)/(nb-1))
+	res.P = StudentTTwoSided(res.T, res.DF)
+	return res
+}
+
+// OneWayANOVA tests equality of group means; η² is SS_between / SS_total.
+func OneWayANOVA(groups [][]float64) *ANOVAResult {
+	var n int
+	var grand float64
+	for _, g := range groups {
+		for _, v := range g {
+			grand += v
+		}
+		n += len(g)
+	}
+	k := len(groups)
+	if k < 2 || n <= k {
+		return nil
+	}
+	grand /= float64(n)
+	var ssb, ssw float64
+	for _, g := range groups {
+		if len(g) == 0 {
+			continue
+		}
+		st := Describe(g)
+		ssb += float64(len(g)) * (st.Mean - grand) * (st.Mean - grand)
+		for _, v := range g {
+			ssw += (v - st.Mean) * (v - st.Mean)
+		}
+	}
+	res := &ANOVAResult{DFBetween: k - 1, DFWithin: n - k, P: 1}
+	if ssb+ssw > 0 {
+		res.EtaSquared = ssb / (ssb + ssw)
+	}
+	if ssw == 0 {
+		return res
+	}
+	res.F = (ssb / float64(k-1)) / (ssw / float64(n-k))
+	res.P = FSurvival(res.F, float64(k-1), float64(n-k))
+	return res
+}
+
+// MannWhitney reports U for the first group with a tie-corrected normal approximation
+// (with continuity correction).
+func MannWhitney(a, b []float64) *MannWhitneyResult {
+	na, nb := float64(len(a)), float64(len(b))
+	if na == 0 || nb == 0 {
+		return nil
+	}
+	ranks, tieSum := rankAll([][]float64{a, b})
+	var ra float64
+	for _, r := range ranks[0] {
+		ra += r
+	}
+	u := ra - na*(na+1)/2
+	n := na + nb
+	mu := na * nb / 2
+	sigma := math.Sqrt(na * nb / 12 * ((n + 1) - tieSum/(n*(n-1))))
+	res := &MannWhitneyResult{U: u, P: 1}
+	if sigma == 0 {
+		return res
+	}
+	d := u - mu
+	switch {
+	case d > 0.5:
+		d -= 0.5
+	case d < -0.5:
+		d += 0.5
+	default:
+		d = 0
+	}
+	res.Z = d / sigma
+	res.P = NormalTwoSided(res.Z)
+	return res
+}
+
+// KruskalWallis is the rank-based one-way test with the usual tie correction.
+func KruskalWallis(groups [][]float64) *KruskalWallisResult {
+	var n float64
+	for _, g := range groups {
+		n += float64(len(g))
+	}
+	if len(groups) < 2 || n < 2 {
+		return nil
+	}
+	ranks, tieSum := rankAll(groups)
+	var h float64
+	for _, rs := range ranks {
+		if len(rs) == 0 {
+			continue
+		}
+		var sum float64
+		for _, r := range rs {
+			sum += r
+		}
+		h += sum * sum / float64(len(rs))
+	}
+	h = 12/(n*(n+1))*h - 3*(n+1)
+	res := &KruskalWallisResult{DF: len(groups) - 1, P: 1}
+	correction := 1 - tieSum/(n*n*n-n)
+	if correction <= 0 {
+		return res
+	}
+	res.H = h / correction
+	res.P = ChiSquareSurvival(res.H, float64(res.DF))
+	return res
+}
+
+// ChiSquareIndependence tests a rows × columns contingency table. Rows or columns that are
+// empty are ignored for the degrees of freedom.
+func ChiSquareIndependence(table [][]int) *ChiSquareResult {
+	rows := make([]float64, len(table))
+	var cols []float64
+	var n float64
+	for i, row := range table {
+		if cols == nil {
+			cols = make([]float64, len(row))
+		}
+		for j, v := range row {
+			rows[i] += float64(v)
+			cols[j] += float64(v)
+			n += float64(v)
+		}
+	}
+	nonEmpty := func(xs []float64) int {
+		c := 0
+		for _, x := range xs {
+			if x > 0 {
+				c++
+			}
+		}
+		return c
+	}
+	r, c := nonEmpty(rows), nonEmpty(cols)
+	if r < 2 || c < 2 {
+		return nil
+	}
+	var chi2 float64
+	for i, row := range table {
+		for j, v := range row {
+			if rows[i] == 0 || cols[j] == 0 {
+				continue
+			}
+			e := rows[i] * cols[j] / n
+			chi2 += (float64(v) - e) * (float64(v) - e) / e
+		}
+	}
+	res := &ChiSquareResult{ChiSquare: chi2, DF: (r - 1) * (c - 1)}
+	res.P = ChiSquareSurvival(chi2, float64(res.DF))
+	res.CramersV = math.Sqrt(chi2 / (n * float64(min(r, c)-1)))
+	return res
+}
+
+// rankAll assigns average ranks over the pooled groups and returns them per group together
+// with Σ(t³ - t) over tie groups.
+func rankAll(groups [][]float64) ([][]float64, float64) {
+	type obs struct {
+		v    float64
+		g, i int
+	}
+	var all []obs
+	out := make([][]float64, len(groups))
+	for g, vals := range groups {
+		out[g] = make([]float64, len(vals))
+		for i, v := range vals {
+			all = append(all, obs{v, g, i})
+		}
+	}
+	sort.SliceStable(all, func(a, b int) bool { return all[a].v < all[b].v })
+	var tieSum float64
+	for start := 0; start < len(all); {
+		end := start
+		for end+1 < len(all) && all[end+1].v == all[start].v {
+			end++
+		}
+		rank := float64(start+end)/2 + 1
+		for k := start; k <= end; k++ {
+			out[all[k].g][all[k].i] = rank
+		}
+		t := float64(end - start + 1)
+		tieSum += t*t*t - t
+		start = end + 1
+	}
+	return out, tieSum
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tol float64) bool { return math.Abs(a-b) <= tol }
+
+func TestWelchT(t *testing.T) {
+	res := WelchT([]float64{1, 2, 3, 4, 5}, []float64{2, 4, 6, 8, 10})
+	if !near(res.T, -1.8974, 1e-4) || !near(res.DF, 5.8824, 1e-4) || !near(res.P, 0.1076, 1e-3) {
+		t.Fatalf("unexpected Welch result: %+v", res)
+	}
+	if !near(res.CohenD, -3/math.Sqrt(6.25), 1e-9) {
+		t.Fatalf("unexpected Cohen's d: %f", res.CohenD)
+	}
+}
+
+func TestOneWayANOVAAndKruskalWallis(t *testing.T) {
+	groups := [][]float64{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	a := OneWayANOVA(groups)
+	if !near(a.F, 27, 1e-9) || a.DFBetween != 2 || a.DFWithin != 6 || !near(a.P, 0.001, 1e-9) || !near(a.EtaSquared, 0.9, 1e-9) {
+		t.Fatalf("unexpected ANOVA result: %+v", a)
+	}
+	kw := KruskalWallis(groups)
+	if !near(kw.H, 7.2, 1e-9) || kw.DF != 2 || !near(kw.P, math.Exp(-3.6), 1e-9) {
+		t.Fatalf("unexpected Kruskal-Wallis result: %+v", kw)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	res := MannWhitney([]float64{1, 2, 3}, []float64{4, 5, 6})
+	if res.U != 0 || !near(res.Z, -1.7457, 1e-4) || !near(res.P, 0.08086, 1e-4) {
+		t.Fatalf("unexpected Mann-Whitney result: %+v", res)
+	}
+}
+
+func TestChiSquareIndependence(t *testing.T) {
+	res := ChiSquareIndependence([][]int{{10, 20}, {20, 10}})
+	if !near(res.ChiSquare, 20.0/3, 1e-9) || res.DF != 1 || !near(res.P, 0.009823, 1e-5) || !near(res.CramersV, 1.0/3, 1e-9) {
+		t.Fatalf("unexpected chi-square result: %+v", res)
+	}
+	if ChiSquareIndependence([][]int{{5, 5}}) != nil {
+		t.Fatalf("expected nil for a single group")
+	}
+}
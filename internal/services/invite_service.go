@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// InviteTokenPrefix marks invitation tokens.
+	InviteTokenPrefix = "synap_inv_"
+
+	inviteTTL = 7 * 24 * time.Hour
+
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusRevoked  = "revoked"
+	InviteStatusExpired  = "expired"
+)
+
+// InviteStore persists invitations. Only the SHA-256 hash of an invite token is stored.
+type InviteStore interface {
+	SaveInvite(inv *Invite) error
+	GetInvite(id string) (*Invite, error)
+	GetInviteByHash(hash string) (*Invite, error)
+	ListInvites(tenantID string) ([]*Invite, error)
+	GetScale(id string) (*Scale, error)
+	GetTenant(id string) (*Tenant, error)
+	FindUserByEmail(email string) (*User, error)
+	AddScaleCollaborator(scaleID, userID, role string) error
+	AddAudit(entry AuditEntry)
+}
+
+// Invite asks someone to join a tenant with a tenant role or, with ScaleID, to collaborate on a
+// scale with a scale role. Token is only set right after it was issued.
+type Invite struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	ScaleID    string    `json:"scale_id,omitempty"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	InvitedBy  string    `json:"invited_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	AcceptedAt time.Time `json:"accepted_at,omitempty"`
+	AcceptedBy string    `json:"accepted_by,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+	Status     string    `json:"status"`
+	Token      string    `json:"token,omitempty"`
+	TokenHash  string    `json:"-"`
+}
+
+// InviteInfo is what the holder of an invite link may see before accepting it.
+type InviteInfo struct {
+	Email         string    `json:"email"`
+	Role          string    `json:"role"`
+	TenantID      string    `json:"tenant_id"`
+	TenantName    string    `json:"tenant_name"`
+	ScaleID       string    `json:"scale_id,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	AccountExists bool      `json:"account_exists"`
+}
+
+func (inv *Invite) status(now time.Time) string {
+	switch {
+	case !inv.AcceptedAt.IsZero():
+		return InviteStatusAccepted
+	case !inv.RevokedAt.IsZero():
+		return InviteStatusRevoked
+	case !now.Before(inv.ExpiresAt):
+		return InviteStatusExpired
+	}
+	return InviteStatusPending
+}
+
+type InviteService struct {
+	store    InviteStore
+	members  *MembershipService
+	auth     *AuthService
+	mailer   Mailer
+	now      func() time.Time
+	newToken func() string
+}
+
+func NewInviteService(store InviteStore, members *MembershipService, auth *AuthService, mailer Mailer) *InviteService {
+	return &InviteService{
+		store:    store,
+		members:  members,
+		auth:     auth,
+		mailer:   mailer,
+		now:      time.Now,
+		newToken: func() string { return InviteTokenPrefix + randomBytes(32) },
+	}
+}
+
+// requireManager checks that actorID may manage invites of the tenant (admins) or of a scale in
+// it (any member).
+func (s *InviteService) requireManager(tenantID, scaleID, actorID string) error {
+	if scaleID == "" {
+		return s.members.Require(tenantID, actorID, TenantRoleAdmin)
+	}
+	if err := s.members.Require(tenantID, actorID, TenantRoleMember); err != nil {
+		return err
+	}
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return NewForbiddenError("forbidden")
+	}
+	return nil
+}
+
+// Create invites email into the tenant (scaleID "") or onto a scale and mails the link when a
+// mailer is configured. A pending invite for the same address and target is replaced.
+func (s *InviteService) Create(ctx context.Context, tenantID, scaleID, actorID, actor, email, role, baseURL string) (*Invite, error) {
+	scaleID = strings.TrimSpace(scaleID)
+	if err := s.requireManager(tenantID, scaleID, actorID); err != nil {
+		return nil, err
+	}
+	email = strings.TrimSpace(email)
+	if !isValidEmail(email) {
+		return nil, NewInvalidError("invalid email format")
+	}
+	if scaleID == "" {
+		if role = normalizeTenantRole(role); role == "" {
+			return nil, NewInvalidError("role must be admin or member")
+		}
+		u, err := s.store.FindUserByEmail(email)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			if current, err := s.members.Role(tenantID, u.ID); err != nil {
+				return nil, err
+			} else if current != "" {
+				return nil, NewConflictError("already a member")
+			}
+		}
+	} else if role = strings.ToLower(strings.TrimSpace(role)); role != "viewer" {
+		role = "editor"
+	}
+	list, err := s.store.ListInvites(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	for _, prev := range list {
+		if prev.ScaleID == scaleID && strings.EqualFold(prev.Email, email) && prev.status(now) == InviteStatusPending {
+			prev.RevokedAt = now
+			if err := s.store.SaveInvite(prev); err != nil {
+				return nil, err
+			}
+		}
+	}
+	inv := &Invite{ID: "inv" + randomID(10), TenantID: tenantID, ScaleID: scaleID, Email: email, Role: role, InvitedBy: actor, CreatedAt: now}
+	if err := s.issue(ctx, inv, baseURL); err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: inv.TenantID, Actor: actor, ActorID: actorID, Action: "invite.create", Target: inv.ID, Note: email + ":" + role})
+	return inv, nil
+}
+
+// issue gives inv a fresh token and expiry, saves it and mails the link. Mail failures are only
+// logged: the caller also receives the link to pass on.
+func (s *InviteService) issue(ctx context.Context, inv *Invite, baseURL string) error {
+	token := s.newToken()
+	inv.TokenHash = HashAPIToken(token)
+	inv.ExpiresAt = s.now().UTC().Add(inviteTTL)
+	inv.RevokedAt = time.Time{}
+	if err := s.store.SaveInvite(inv); err != nil {
+		return err
+	}
+	inv.Token = token
+	inv.Status = InviteStatusPending
+	if s.mailer == nil {
+		return nil
+	}
+	target := "the workspace"
+	if t, err := s.store.GetTenant(inv.TenantID); err == nil && t != nil && t.Name != "" {
+		target = t.Name
+	}
+	if inv.ScaleID != "" {
+		target = "scale " + inv.ScaleID + " in " + target
+	}
+	text := fmt.Sprintf("%s invited you to %s on Synap as %s.\nOpen this link within %d days to accept:\n%s\n",
+		inv.InvitedBy, target, inv.Role, int(inviteTTL.Hours()/24), InviteURL(baseURL, token))
+	if err := s.mailer.Send(ctx, Mail{To: inv.Email, Subject: "You are invited to Synap", Text: text}); err != nil {
+		log.Printf("invite mail to %s: %v", inv.Email, err)
+	}
+	return nil
+}
+
+// InviteURL is the page that accepts an invite.
+func InviteURL(baseURL, token string) string {
+	return baseURL + "/invite?token=" + url.QueryEscape(token)
+}
+
+// List returns the tenant's invites (or those of one scale), newest first.
+func (s *InviteService) List(tenantID, scaleID, actorID string) ([]*Invite, error) {
+	scaleID = strings.TrimSpace(scaleID)
+	if err := s.requireManager(tenantID, scaleID, actorID); err != nil {
+		return nil, err
+	}
+	list, err := s.store.ListInvites(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	out := []*Invite{}
+	for _, inv := range list {
+		if scaleID != "" && inv.ScaleID != scaleID {
+			continue
+		}
+		inv.Status = inv.status(now)
+		out = append(out, inv)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *InviteService) managed(tenantID, actorID, id string) (*Invite, error) {
+	inv, err := s.store.GetInvite(id)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.TenantID != tenantID {
+		return nil, NewNotFoundError("invite not found")
+	}
+	if err := s.requireManager(tenantID, inv.ScaleID, actorID); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Revoke invalidates a pending invite.
+func (s *InviteService) Revoke(tenantID, actorID, actor, id string) error {
+	inv, err := s.managed(tenantID, actorID, id)
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	if inv.status(now) != InviteStatusPending {
+		return NewConflictError("invite is not pending")
+	}
+	inv.RevokedAt = now
+	if err := s.store.SaveInvite(inv); err != nil {
+		return err
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: inv.TenantID, Actor: actor, ActorID: actorID, Action: "invite.revoke", Target: inv.ID, Note: inv.Email})
+	return nil
+}
+
+// Resend issues a new link for a pending, revoked or expired invite; the previous link stops
+// working.
+func (s *InviteService) Resend(ctx context.Context, tenantID, actorID, actor, id, baseURL string) (*Invite, error) {
+	inv, err := s.managed(tenantID, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !inv.AcceptedAt.IsZero() {
+		return nil, NewConflictError("invite already accepted")
+	}
+	if err := s.issue(ctx, inv, baseURL); err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: inv.TenantID, Actor: actor, ActorID: actorID, Action: "invite.resend", Target: inv.ID, Note: inv.Email})
+	return inv, nil
+}
+
+// pending returns the invite behind token while it can still be accepted.
+func (s *InviteService) pending(token string) (*Invite, error) {
+	token = strings.TrimSpace(token)
+	invalid := NewInvalidError("invalid or expired invite")
+	if !strings.HasPrefix(token, InviteTokenPrefix) {
+		return nil, invalid
+	}
+	inv, err := s.store.GetInviteByHash(HashAPIToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil || inv.status(s.now().UTC()) != InviteStatusPending {
+		return nil, invalid
+	}
+	return inv, nil
+}
+
+// Lookup describes a pending invite to the holder of its link.
+func (s *InviteService) Lookup(token string) (*InviteInfo, error) {
+	inv, err := s.pending(token)
+	if err != nil {
+		return nil, err
+	}
+	info := &InviteInfo{Email: inv.Email, Role: inv.Role, TenantID: inv.TenantID, ScaleID: inv.ScaleID, ExpiresAt: inv.ExpiresAt}
+	if t, err := s.store.GetTenant(inv.TenantID); err != nil {
+		return nil, err
+	} else if t != nil {
+		info.TenantName = t.Name
+	}
+	u, err := s.store.FindUserByEmail(inv.Email)
+	if err != nil {
+		return nil, err
+	}
+	info.AccountExists = u != nil
+	return info, nil
+}
+
+// Accept applies the invite to the signed-in user u, whose address must be the invited one.
+func (s *InviteService) Accept(token string, u *User) (*Invite, error) {
+	inv, err := s.pending(token)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || !strings.EqualFold(u.Email, inv.Email) {
+		return nil, NewForbiddenError("the invite is for another email address")
+	}
+	if err := s.apply(inv, u); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Register creates the account of the invited address and applies the invite. email may be
+// empty; otherwise it must match the invite.
+func (s *InviteService) Register(token, email, password string, client SessionClient) (*AuthResult, error) {
+	inv, err := s.pending(token)
+	if err != nil {
+		return nil, err
+	}
+	if email = strings.TrimSpace(email); email != "" && !strings.EqualFold(email, inv.Email) {
+		return nil, NewInvalidError("invite email mismatch")
+	}
+	if u, err := s.store.FindUserByEmail(inv.Email); err != nil {
+		return nil, err
+	} else if u != nil {
+		return nil, NewConflictError("an account with this email exists; sign in to accept the invite")
+	}
+	// a scale invite gives the new account its own tenant; the scale is shared with it as a guest
+	var res *AuthResult
+	if inv.ScaleID != "" {
+		res, err = s.auth.Register(inv.Email, password, "", client)
+	} else {
+		res, err = s.auth.RegisterWithTenant(inv.Email, password, inv.TenantID, inv.Role, client)
+	}
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.store.FindUserByEmail(inv.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(inv, u); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// apply grants the invited access and marks the invite accepted. Scale invites only share the
+// scale: users outside its tenant collaborate as guests.
+func (s *InviteService) apply(inv *Invite, u *User) error {
+	if u == nil {
+		return NewNotFoundError("user not found")
+	}
+	if inv.ScaleID == "" {
+		if err := s.members.Grant(inv.TenantID, u, inv.Role, "system:invite"); err != nil {
+			return err
+		}
+	} else if err := s.store.AddScaleCollaborator(inv.ScaleID, u.ID, inv.Role); err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	inv.AcceptedAt, inv.AcceptedBy = now, u.ID
+	if err := s.store.SaveInvite(inv); err != nil {
+		return err
+	}
+	inv.Status = InviteStatusAccepted
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: inv.TenantID, Actor: u.Email, ActorID: u.ID, Action: "invite.accept", Target: inv.ID, Note: inv.Role})
+	return nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inviteStubStore struct {
+	*membershipStubStore
+	invites map[string]*Invite
+	scales  map[string]*Scale
+	collabs map[string]string // scale id + "/" + user id -> role
+}
+
+func (s *inviteStubStore) SaveInvite(inv *Invite) error {
+	cp := *inv
+	cp.Token, cp.Status = "", ""
+	s.invites[inv.ID] = &cp
+	return nil
+}
+
+func (s *inviteStubStore) GetInvite(id string) (*Invite, error) {
+	if inv, ok := s.invites[id]; ok {
+		cp := *inv
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *inviteStubStore) GetInviteByHash(hash string) (*Invite, error) {
+	for _, inv := range s.invites {
+		if inv.TokenHash == hash {
+			cp := *inv
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *inviteStubStore) ListInvites(tenantID string) ([]*Invite, error) {
+	out := []*Invite{}
+	for _, inv := range s.invites {
+		if inv.TenantID == tenantID {
+			cp := *inv
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *inviteStubStore) GetScale(id string) (*Scale, error) {
+	if sc, ok := s.scales[id]; ok {
+		return sc, nil
+	}
+	return nil, nil
+}
+
+func (s *inviteStubStore) AddScaleCollaborator(scaleID, userID, role string) error {
+	s.collabs[scaleID+"/"+userID] = role
+	return nil
+}
+
+type inviteFixture struct {
+	auth    *AuthService
+	members *MembershipService
+	invites *InviteService
+	store   *inviteStubStore
+	mailer  *captureMailer
+	owner   *AuthResult
+	now     *time.Time
+}
+
+func newInviteFixture(t *testing.T) *inviteFixture {
+	t.Helper()
+	auth, members, ms := newTestMemberships(t)
+	store := &inviteStubStore{membershipStubStore: ms, invites: map[string]*Invite{}, scales: map[string]*Scale{}, collabs: map[string]string{}}
+	mailer := &captureMailer{}
+	svc := NewInviteService(store, members, auth, mailer)
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	owner := register(t, auth, "owner@example.com", "Acme")
+	store.scales["S1"] = &Scale{ID: "S1", TenantID: owner.TenantID}
+	return &inviteFixture{auth: auth, members: members, invites: svc, store: store, mailer: mailer, owner: owner, now: &now}
+}
+
+func (f *inviteFixture) create(t *testing.T, scaleID, email, role string) *Invite {
+	t.Helper()
+	inv, err := f.invites.Create(context.Background(), f.owner.TenantID, scaleID, f.owner.UserID, "owner@example.com", email, role, "https://synap.test")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return inv
+}
+
+func TestInviteTokensAreHashedAndMailed(t *testing.T) {
+	f := newInviteFixture(t)
+	inv := f.create(t, "S1", "new@example.com", "viewer")
+	if !strings.HasPrefix(inv.Token, InviteTokenPrefix) || inv.Status != InviteStatusPending {
+		t.Fatalf("unexpected invite %+v", inv)
+	}
+	stored := f.store.invites[inv.ID]
+	if stored.TokenHash != HashAPIToken(inv.Token) || strings.Contains(stored.TokenHash, inv.Token) {
+		t.Fatalf("only the token hash may be stored: %+v", stored)
+	}
+	if got := f.mailer.linkToken(t); got != inv.Token || f.mailer.sent[0].To != "new@example.com" {
+		t.Fatalf("mail must carry the invite link: %+v", f.mailer.sent)
+	}
+	if other := f.create(t, "S1", "other@example.com", "viewer"); other.Token == inv.Token {
+		t.Fatalf("tokens must be unique")
+	}
+
+	// inviting the same address again replaces the pending invite
+	again := f.create(t, "S1", "NEW@example.com", "editor")
+	if _, err := f.invites.Lookup(inv.Token); err == nil {
+		t.Fatalf("the replaced link must stop working")
+	}
+	if info, err := f.invites.Lookup(again.Token); err != nil || info.Role != "editor" || info.AccountExists || info.TenantName != "Acme" {
+		t.Fatalf("Lookup = %+v, %v", info, err)
+	}
+}
+
+func TestInviteAcceptNewAndExistingUser(t *testing.T) {
+	f := newInviteFixture(t)
+	scaleInv := f.create(t, "S1", "new@example.com", "viewer")
+	if _, err := f.invites.Register(scaleInv.Token, "other@example.com", "Secret123", SessionClient{}); err == nil {
+		t.Fatalf("another address must not use the invite")
+	}
+	res, err := f.invites.Register(scaleInv.Token, "", "Secret123", SessionClient{})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if f.store.collabs["S1/"+res.UserID] != "viewer" {
+		t.Fatalf("scale invite must add the collaborator with the invited role: %+v", f.store.collabs)
+	}
+	if role, _ := f.members.Role(f.owner.TenantID, res.UserID); role != "" || res.TenantID == f.owner.TenantID {
+		t.Fatalf("scale invite must share the scale with a guest in their own tenant, got role %q", role)
+	}
+	if _, err := f.invites.Register(scaleInv.Token, "", "Secret123", SessionClient{}); err == nil {
+		t.Fatalf("an invite is single-use")
+	}
+
+	bob := register(t, f.auth, "bob@example.com", "Bob Inc")
+	tenantInv := f.create(t, "", "bob@example.com", "admin")
+	if _, err := f.invites.Register(tenantInv.Token, "", "Secret123", SessionClient{}); err == nil {
+		t.Fatalf("existing accounts must sign in to accept")
+	}
+	if _, err := f.invites.Accept(tenantInv.Token, f.store.users["new@example.com"]); err == nil {
+		t.Fatalf("only the invited address may accept")
+	}
+	inv, err := f.invites.Accept(tenantInv.Token, f.store.users["bob@example.com"])
+	if err != nil || inv.Status != InviteStatusAccepted || inv.AcceptedBy != bob.UserID {
+		t.Fatalf("Accept = %+v, %v", inv, err)
+	}
+	if role, _ := f.members.Role(f.owner.TenantID, bob.UserID); role != TenantRoleAdmin {
+		t.Fatalf("tenant invite must grant the invited role, got %q", role)
+	}
+	_, err = f.invites.Create(context.Background(), f.owner.TenantID, "", f.owner.UserID, "owner@example.com", "bob@example.com", "member", "")
+	expectCode(t, err, ErrorConflict)
+}
+
+func TestInviteRevokeResendAndExpiry(t *testing.T) {
+	f := newInviteFixture(t)
+	inv := f.create(t, "", "new@example.com", "member")
+	bob := register(t, f.auth, "bob@example.com", "Bob Inc")
+	join(t, f.members, f.store.membershipStubStore, f.owner.TenantID, "bob@example.com", TenantRoleMember)
+
+	// members manage scale invites only
+	if _, err := f.invites.List(f.owner.TenantID, "", bob.UserID); err == nil {
+		t.Fatalf("members must not list tenant invites")
+	}
+	expectCode(t, f.invites.Revoke(f.owner.TenantID, bob.UserID, "bob@example.com", inv.ID), ErrorForbidden)
+	expectCode(t, f.invites.Revoke("other", f.owner.UserID, "owner@example.com", inv.ID), ErrorNotFound)
+
+	if err := f.invites.Revoke(f.owner.TenantID, f.owner.UserID, "owner@example.com", inv.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := f.invites.Register(inv.Token, "", "Secret123", SessionClient{}); err == nil {
+		t.Fatalf("revoked invites must not be accepted")
+	}
+	expectCode(t, f.invites.Revoke(f.owner.TenantID, f.owner.UserID, "owner@example.com", inv.ID), ErrorConflict)
+
+	resent, err := f.invites.Resend(context.Background(), f.owner.TenantID, f.owner.UserID, "owner@example.com", inv.ID, "")
+	if err != nil || resent.Token == inv.Token || resent.Status != InviteStatusPending {
+		t.Fatalf("Resend = %+v, %v", resent, err)
+	}
+	if f.mailer.linkToken(t) != resent.Token {
+		t.Fatalf("resend must mail the new link")
+	}
+
+	*f.now = f.now.Add(inviteTTL)
+	if _, err := f.invites.Register(resent.Token, "", "Secret123", SessionClient{}); err == nil {
+		t.Fatalf("expired invites must not be accepted")
+	}
+	list, err := f.invites.List(f.owner.TenantID, "", f.owner.UserID)
+	if err != nil || len(list) != 1 || list[0].Status != InviteStatusExpired || list[0].Token != "" {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+	resent, err = f.invites.Resend(context.Background(), f.owner.TenantID, f.owner.UserID, "owner@example.com", inv.ID, "")
+	if err != nil {
+		t.Fatalf("expired invites can be resent: %v", err)
+	}
+	if _, err := f.invites.Register(resent.Token, "", "Secret123", SessionClient{}); err != nil {
+		t.Fatalf("Register after resend: %v", err)
+	}
+	_, err = f.invites.Resend(context.Background(), f.owner.TenantID, f.owner.UserID, "owner@example.com", inv.ID, "")
+	expectCode(t, err, ErrorConflict)
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	IRTModelRasch = "rasch"
+	IRTModelGRM   = "grm"
+
+	irtQuadraturePoints = 41
+	irtQuadratureBound  = 5.0
+	irtTolerance        = 1e-4
+	irtNewtonSteps      = 3
+	// Weak priors keep thresholds of unobserved categories and slopes of near-constant items finite:
+	// b ~ N(0, 3²) and log a ~ N(0, 1).
+	irtThresholdPriorVar = 9.0
+	irtSlopePriorVar     = 1.0
+)
+
+// irtItem holds the parameters of one item. For the graded response model b are the ordered
+// category boundaries; for the Rasch (partial credit) model they are the step difficulties and a is
+// the discrimination shared by all items.
+type irtItem struct {
+	model string
+	a     float64
+	b     []float64
+}
+
+// probs fills out (len(b)+1 categories) with P(X = c | θ).
+func (it irtItem) probs(theta float64, out []float64) {
+	k := len(it.b) + 1
+	if it.model == IRTModelGRM {
+		prev := 1.0
+		for c := 0; c < k; c++ {
+			next := 0.0
+			if c < k-1 {
+				next = logistic(it.a * (theta - it.b[c]))
+			}
+			out[c] = prev - next
+			prev = next
+		}
+		return
+	}
+	// partial credit: P(X = c) ∝ exp(Σ_{s≤c} a(θ - b_s))
+	out[0] = 0
+	maxLogit := 0.0
+	for c := 1; c < k; c++ {
+		out[c] = out[c-1] + it.a*(theta-it.b[c-1])
+		maxLogit = math.Max(maxLogit, out[c])
+	}
+	var sum float64
+	for c := 0; c < k; c++ {
+		out[c] = math.Exp(out[c] - maxLogit)
+		sum += out[c]
+	}
+	for c := 0; c < k; c++ {
+		out[c] /= sum
+	}
+}
+
+// information is the Fisher information of the item at θ.
+func (it irtItem) information(theta float64) float64 {
+	k := len(it.b) + 1
+	p := make([]float64, k)
+	it.probs(theta, p)
+	if it.model == IRTModelGRM {
+		// P_c' = a[P*_c(1-P*_c) - P*_{c+1}(1-P*_{c+1})] with P*_0 = 1 and P*_k = 0
+		deriv := func(c int) float64 {
+			if c == 0 || c == k {
+				return 0
+			}
+			s := logistic(it.a * (theta - it.b[c-1]))
+			return it.a * s * (1 - s)
+		}
+		var info float64
+		for c := 0; c < k; c++ {
+			if p[c] <= 0 {
+				continue
+			}
+			d := deriv(c) - deriv(c+1)
+			info += d * d / p[c]
+		}
+		return info
+	}
+	var mean, sq float64
+	for c, pc := range p {
+		mean += float64(c) * pc
+		sq += float64(c*c) * pc
+	}
+	return it.a * it.a * (sq - mean*mean)
+}
+
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// irtQuadrature returns equally spaced nodes on [-5, 5] with normalised standard normal weights.
+func irtQuadrature() ([]float64, []float64) {
+	nodes := make([]float64, irtQuadraturePoints)
+	weights := make([]float64, irtQuadraturePoints)
+	var sum float64
+	for q := range nodes {
+		nodes[q] = -irtQuadratureBound + 2*irtQuadratureBound*float64(q)/float64(irtQuadraturePoints-1)
+		weights[q] = math.Exp(-nodes[q] * nodes[q] / 2)
+		sum += weights[q]
+	}
+	for q := range weights {
+		weights[q] /= sum
+	}
+	return nodes, weights
+}
+
+// irtProbTable caches P(X = c | θ_q) as [item][node][category].
+func irtProbTable(items []irtItem, nodes []float64) [][][]float64 {
+	out := make([][][]float64, len(items))
+	for j, it := range items {
+		out[j] = make([][]float64, len(nodes))
+		for q, theta := range nodes {
+			out[j][q] = make([]float64, len(it.b)+1)
+			it.probs(theta, out[j][q])
+		}
+	}
+	return out
+}
+
+// irtPosterior writes the posterior weights of one response pattern (category per item, -1 when
+// missing) over the quadrature nodes into post and returns the log marginal likelihood.
+func irtPosterior(table [][][]float64, row []int, weights, post []float64) float64 {
+	maxLog := math.Inf(-1)
+	for q := range weights {
+		lp := math.Log(weights[q])
+		for j, x := range row {
+			if x >= 0 {
+				lp += math.Log(math.Max(table[j][q][x], 1e-300))
+			}
+		}
+		post[q] = lp
+		maxLog = math.Max(maxLog, lp)
+	}
+	var sum float64
+	for q := range post {
+		post[q] = math.Exp(post[q] - maxLog)
+		sum += post[q]
+	}
+	for q := range post {
+		post[q] /= sum
+	}
+	return maxLog + math.Log(sum)
+}
+
+// irtFit is the outcome of a marginal maximum likelihood calibration.
+type irtFit struct {
+	items         []irtItem
+	logLikelihood float64
+	iterations    int
+	converged     bool
+}
+
+// fitIRT calibrates the items by marginal maximum likelihood using the Bock-Aitkin EM algorithm
+// with θ ~ N(0, 1). rows hold category indices 0..k-1 (-1 = missing). The M-step takes a few damped
+// Newton steps per item; for the Rasch model the common discrimination is updated afterwards.
+func fitIRT(model string, rows [][]int, nItems, k, maxIter int) *irtFit {
+	nodes, weights := irtQuadrature()
+	items := irtStartValues(model, rows, nItems, k)
+	fit := &irtFit{items: items, logLikelihood: math.Inf(-1)}
+	post := make([]float64, len(nodes))
+	for iter := 1; iter <= maxIter; iter++ {
+		table := irtProbTable(items, nodes)
+		counts := make([][][]float64, nItems)
+		for j := range counts {
+			counts[j] = make2D(len(nodes), k)
+		}
+		var ll float64
+		for _, row := range rows {
+			ll += irtPosterior(table, row, weights, post)
+			for j, x := range row {
+				if x < 0 {
+					continue
+				}
+				for q, w := range post {
+					counts[j][q][x] += w
+				}
+			}
+		}
+		fit.iterations = iter
+		improvement := ll - fit.logLikelihood
+		fit.logLikelihood = ll
+
+		var maxDelta float64
+		for j := range items {
+			j := j
+			if model == IRTModelGRM {
+				x := append([]float64{math.Log(items[j].a)}, items[j].b...)
+				next := newtonMaximize(func(v []float64) float64 {
+					return irtItemObjective(model, counts[j], nodes, math.Exp(v[0]), v[1:]) - v[0]*v[0]/(2*irtSlopePriorVar)
+				}, x, irtNewtonSteps)
+				maxDelta = math.Max(maxDelta, maxAbsDiff(x, next))
+				items[j].a, items[j].b = math.Exp(next[0]), next[1:]
+				continue
+			}
+			a := items[j].a
+			next := newtonMaximize(func(v []float64) float64 {
+				return irtItemObjective(model, counts[j], nodes, a, v)
+			}, items[j].b, irtNewtonSteps)
+			maxDelta = math.Max(maxDelta, maxAbsDiff(items[j].b, next))
+			items[j].b = next
+		}
+		if model == IRTModelRasch {
+			la := []float64{math.Log(items[0].a)}
+			next := newtonMaximize(func(v []float64) float64 {
+				a := math.Exp(v[0])
+				total := -v[0] * v[0] / (2 * irtSlopePriorVar)
+				for j := range items {
+					total += irtItemObjective(model, counts[j], nodes, a, items[j].b)
+				}
+				return total
+			}, la, irtNewtonSteps)
+			maxDelta = math.Max(maxDelta, math.Abs(next[0]-la[0]))
+			for j := range items {
+				items[j].a = math.Exp(next[0])
+			}
+		}
+		if maxDelta < irtTolerance && math.Abs(improvement) < irtTolerance*math.Max(1, math.Abs(ll)) {
+			fit.converged = true
+			break
+		}
+	}
+	return fit
+}
+
+// irtItemObjective is the expected complete-data log-likelihood of one item given the E-step
+// counts [node][category], plus the threshold prior. Invalid parameters yield -Inf.
+func irtItemObjective(model string, counts [][]float64, nodes []float64, a float64, b []float64) float64 {
+	if model == IRTModelGRM {
+		for c := 1; c < len(b); c++ {
+			if b[c] <= b[c-1] {
+				return math.Inf(-1)
+			}
+		}
+	}
+	it := irtItem{model: model, a: a, b: b}
+	p := make([]float64, len(b)+1)
+	var ll float64
+	for q, theta := range nodes {
+		it.probs(theta, p)
+		for c, n := range counts[q] {
+			if n == 0 {
+				continue
+			}
+			if p[c] <= 0 {
+				return math.Inf(-1)
+			}
+			ll += n * math.Log(p[c])
+		}
+	}
+	for _, v := range b {
+		ll -= v * v / (2 * irtThresholdPriorVar)
+	}
+	return ll
+}
+
+// irtStartValues derives starting thresholds from the smoothed category proportions of each item.
+func irtStartValues(model string, rows [][]int, nItems, k int) []irtItem {
+	items := make([]irtItem, nItems)
+	for j := range items {
+		freq := make([]float64, k)
+		for c := range freq {
+			freq[c] = 0.5
+		}
+		for _, row := range rows {
+			if x := row[j]; x >= 0 {
+				freq[x]++
+			}
+		}
+		var total float64
+		for _, f := range freq {
+			total += f
+		}
+		b := make([]float64, k-1)
+		above := total
+		for c := 1; c < k; c++ {
+			if model == IRTModelGRM {
+				above -= freq[c-1]
+				p := above / total
+				b[c-1] = -math.Log(p / (1 - p))
+			} else {
+				b[c-1] = math.Log(freq[c-1] / freq[c])
+			}
+		}
+		items[j] = irtItem{model: model, a: 1, b: b}
+	}
+	return items
+}
+
+// newtonMaximize improves f from x with Newton steps on finite-difference derivatives. The Hessian
+// is shifted until it is negative definite and steps that do not increase f are halved.
+func newtonMaximize(f func([]float64) float64, x []float64, steps int) []float64 {
+	const h = 1e-4
+	d := len(x)
+	x = append([]float64(nil), x...)
+	fx := f(x)
+	if math.IsInf(fx, 0) || math.IsNaN(fx) {
+		return x
+	}
+	shift := func(i int, by float64) []float64 {
+		y := append([]float64(nil), x...)
+		y[i] += by
+		return y
+	}
+	for s := 0; s < steps; s++ {
+		grad := make([]float64, d)
+		hess := make2D(d, d)
+		for i := 0; i < d; i++ {
+			fp, fm := f(shift(i, h)), f(shift(i, -h))
+			grad[i] = (fp - fm) / (2 * h)
+			hess[i][i] = (fp - 2*fx + fm) / (h * h)
+			for j := 0; j < i; j++ {
+				pp := append([]float64(nil), x...)
+				pp[i] += h
+				pp[j] += h
+				pm := append([]float64(nil), pp...)
+				pm[j] -= 2 * h
+				mp := append([]float64(nil), pp...)
+				mp[i] -= 2 * h
+				mm := append([]float64(nil), mp...)
+				mm[j] -= 2 * h
+				hess[i][j] = (f(pp) - f(pm) - f(mp) + f(mm)) / (4 * h * h)
+				hess[j][i] = hess[i][j]
+			}
+		}
+		if !finiteSlice(grad) || !finiteSlice(hess...) {
+			return x
+		}
+		var step []float64
+		for lambda := 0.0; step == nil && lambda < 1e8; lambda = math.Max(1e-6, lambda*10) {
+			neg := scaleMatrix(hess, -1)
+			for i := range neg {
+				neg[i][i] += lambda
+			}
+			if values, _ := symmetricEigen(neg); values[len(values)-1] <= 1e-12 {
+				continue
+			}
+			inv, ok := invertMatrix(neg)
+			if !ok {
+				continue
+			}
+			step = make([]float64, d)
+			for i := range step {
+				for j := range grad {
+					step[i] += inv[i][j] * grad[j]
+				}
+			}
+		}
+		if step == nil {
+			return x
+		}
+		improved := false
+		for t := 1.0; t > 1e-6; t /= 2 {
+			cand := make([]float64, d)
+			for i := range cand {
+				cand[i] = x[i] + t*step[i]
+			}
+			if fc := f(cand); fc > fx {
+				x, fx, improved = cand, fc, true
+				break
+			}
+		}
+		if !improved {
+			return x
+		}
+	}
+	return x
+}
+
+func finiteSlice(rows ...[]float64) bool {
+	for _, row := range rows {
+		for _, v := range row {
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func maxAbsDiff(a, b []float64) float64 {
+	var out float64
+	for i := range a {
+		out = math.Max(out, math.Abs(a[i]-b[i]))
+	}
+	return out
+}
+
+// irtCategories converts a participant × item score matrix (NaN = missing) to category indices for
+// a k-point scale; values outside 1..k are treated as missing.
+func irtCategories(matrix [][]float64, k int) [][]int {
+	out := make([][]int, len(matrix))
+	for i, row := range matrix {
+		out[i] = make([]int, len(row))
+		for j, v := range row {
+			c := int(v) - 1
+			if math.IsNaN(v) || v != math.Trunc(v) || c < 0 || c >= k {
+				c = -1
+			}
+			out[i][j] = c
+		}
+	}
+	return out
+}
+
+// irtEAP returns the expected a posteriori θ and its posterior SD for one response pattern.
+func irtEAP(table [][][]float64, row []int, nodes, weights, post []float64) (float64, float64) {
+	irtPosterior(table, row, weights, post)
+	var mean, sq float64
+	for q, w := range post {
+		mean += w * nodes[q]
+		sq += w * nodes[q] * nodes[q]
+	}
+	return mean, math.Sqrt(math.Max(0, sq-mean*mean))
+}
+
+// IRTThetaGrid is the θ grid on which item and test information curves are reported.
+func IRTThetaGrid() []float64 {
+	out := make([]float64, 0, 33)
+	for i := -16; i <= 16; i++ {
+		out = append(out, float64(i)/4)
+	}
+	return out
+}
+
+// ScaleFingerprint identifies a version of a scale's Likert item set: the number of points and the
+// (ID, reverse-scored) pairs. Calibrations only apply to the version they were fitted on.
+func ScaleFingerprint(points int, items []*Item) string {
+	parts := make([]string, 0, len(items))
+	for _, it := range items {
+		parts = append(parts, it.ID+":"+strconv.FormatBool(it.ReverseScored))
+	}
+	sort.Strings(parts)
+	sum := sha256.Sum256([]byte(strconv.Itoa(points) + "|" + strings.Join(parts, ";")))
+	return hex.EncodeToString(sum[:8])
+}
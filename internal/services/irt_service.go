@@ -0,0 +1,350 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	defaultIRTIterations = 500
+	maxIRTIterations     = 5000
+	minIRTParticipants   = 10
+)
+
+// IRTStore persists calibrations next to the scale data they were fitted on.
+type IRTStore interface {
+	GetScale(id string) (*Scale, error)
+	ListItems(scaleID string) ([]*Item, error)
+	ListResponsesByScale(scaleID string) ([]*Response, error)
+	SaveIRTCalibration(c *IRTCalibration) error
+	// ListIRTCalibrations returns the calibrations of a scale ordered by version.
+	ListIRTCalibrations(scaleID string) ([]*IRTCalibration, error)
+}
+
+// IRTOptions selects the model (rasch|grm, default grm) and caps the EM iterations.
+type IRTOptions struct {
+	Model         string
+	MaxIterations int
+}
+
+// IRTItemParams reports one calibrated item. Thresholds are on the θ scale (GRM boundary locations
+// or Rasch step difficulties); Difficulty is their mean. Information is evaluated on ThetaGrid.
+type IRTItemParams struct {
+	ItemID         string    `json:"item_id"`
+	Discrimination float64   `json:"discrimination"`
+	Difficulty     float64   `json:"difficulty"`
+	Thresholds     []float64 `json:"thresholds"`
+	Information    []float64 `json:"information"`
+}
+
+type IRTPerson struct {
+	ParticipantID string  `json:"participant_id"`
+	Theta         float64 `json:"theta"`
+	SE            float64 `json:"se"`
+	Answered      int     `json:"answered"`
+}
+
+// IRTCalibration is one stored IRT fit. Version numbers increase per scale; ScaleFingerprint ties
+// the calibration to the item set (scale version) it was fitted on.
+type IRTCalibration struct {
+	ScaleID          string          `json:"scale_id"`
+	Version          int             `json:"version"`
+	ScaleFingerprint string          `json:"scale_fingerprint"`
+	Model            string          `json:"model"`
+	Categories       int             `json:"categories"`
+	N                int             `json:"n"`
+	LogLikelihood    float64         `json:"log_likelihood"`
+	Iterations       int             `json:"iterations"`
+	Converged        bool            `json:"converged"`
+	Items            []IRTItemParams `json:"items"`
+	ThetaGrid        []float64       `json:"theta_grid"`
+	TestInformation  []float64       `json:"test_information"`
+	TestSE           []float64       `json:"test_se"`
+	Persons          []IRTPerson     `json:"persons"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
+// IRTCalibrationSummary is the list view of a calibration; Current marks calibrations that match
+// the scale's present item set.
+type IRTCalibrationSummary struct {
+	Version          int       `json:"version"`
+	ScaleFingerprint string    `json:"scale_fingerprint"`
+	Model            string    `json:"model"`
+	N                int       `json:"n"`
+	Converged        bool      `json:"converged"`
+	Current          bool      `json:"current"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type IRTCalibrationList struct {
+	ScaleID          string                  `json:"scale_id"`
+	ScaleFingerprint string                  `json:"scale_fingerprint"`
+	Calibrations     []IRTCalibrationSummary `json:"calibrations"`
+}
+
+// irtSaveAttempts bounds how often Calibrate picks a new version after losing it to a concurrent
+// calibration of the same scale.
+const irtSaveAttempts = 5
+
+// IRTService calibrates Rasch and graded response models on the Likert items of a scale.
+type IRTService struct {
+	store IRTStore
+	now   func() time.Time
+}
+
+func NewIRTService(store IRTStore) *IRTService {
+	return &IRTService{store: store, now: time.Now}
+}
+
+func (s *IRTService) Calibrate(tenantID, scaleID string, opts IRTOptions) (*IRTCalibration, error) {
+	sc, err := s.authorizedScale(tenantID, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	cal, err := CalibrateIRT(sc.Points, filterLikertItems(items), responses, opts)
+	if err != nil {
+		return nil, err
+	}
+	cal.ScaleID = scaleID
+	// versions are unique per scale: when a concurrent calibration saved the next version first,
+	// the save conflicts and the version is read again
+	for attempt := 1; ; attempt++ {
+		existing, err := s.store.ListIRTCalibrations(scaleID)
+		if err != nil {
+			return nil, err
+		}
+		cal.Version = 1
+		if len(existing) > 0 {
+			cal.Version = existing[len(existing)-1].Version + 1
+		}
+		cal.CreatedAt = s.now().UTC()
+		err = s.store.SaveIRTCalibration(cal)
+		if err == nil {
+			return cal, nil
+		}
+		if se, ok := AsServiceError(err); !ok || se.Code != ErrorConflict || attempt == irtSaveAttempts {
+			return nil, err
+		}
+	}
+}
+
+func (s *IRTService) ListCalibrations(tenantID, scaleID string) (*IRTCalibrationList, error) {
+	sc, err := s.authorizedScale(tenantID, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := s.currentFingerprint(sc)
+	if err != nil {
+		return nil, err
+	}
+	cals, err := s.store.ListIRTCalibrations(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	out := &IRTCalibrationList{ScaleID: scaleID, ScaleFingerprint: fingerprint, Calibrations: make([]IRTCalibrationSummary, 0, len(cals))}
+	for _, c := range cals {
+		out.Calibrations = append(out.Calibrations, IRTCalibrationSummary{
+			Version:          c.Version,
+			ScaleFingerprint: c.ScaleFingerprint,
+			Model:            c.Model,
+			N:                c.N,
+			Converged:        c.Converged,
+			Current:          c.ScaleFingerprint == fingerprint,
+			CreatedAt:        c.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// GetCalibration returns a stored calibration; version 0 selects the latest calibration of the
+// current scale version.
+func (s *IRTService) GetCalibration(tenantID, scaleID string, version int) (*IRTCalibration, error) {
+	sc, err := s.authorizedScale(tenantID, scaleID)
+	if err != nil {
+		return nil, err
+	}
+	cals, err := s.store.ListIRTCalibrations(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		fingerprint, err := s.currentFingerprint(sc)
+		if err != nil {
+			return nil, err
+		}
+		if c := latestIRTCalibration(cals, fingerprint); c != nil {
+			return c, nil
+		}
+		return nil, NewNotFoundError("no calibration for the current scale version")
+	}
+	for _, c := range cals {
+		if c.Version == version {
+			return c, nil
+		}
+	}
+	return nil, NewNotFoundError("calibration not found")
+}
+
+func (s *IRTService) authorizedScale(tenantID, scaleID string) (*Scale, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	return sc, nil
+}
+
+func (s *IRTService) currentFingerprint(sc *Scale) (string, error) {
+	items, err := s.store.ListItems(sc.ID)
+	if err != nil {
+		return "", err
+	}
+	return ScaleFingerprint(sc.Points, filterLikertItems(items)), nil
+}
+
+// CalibrateIRT fits the selected model to the Likert responses of a points-category scale and scores
+// every participant who answered at least one item.
+func CalibrateIRT(points int, items []*Item, responses []*Response, opts IRTOptions) (*IRTCalibration, error) {
+	model := opts.Model
+	switch model {
+	case "":
+		model = IRTModelGRM
+	case IRTModelRasch, IRTModelGRM:
+	default:
+		return nil, NewInvalidError("model must be rasch or grm")
+	}
+	if points < 2 {
+		return nil, NewInvalidError("scale needs at least 2 points")
+	}
+	if len(items) < 2 {
+		return nil, NewInvalidError("at least 2 likert items required")
+	}
+	maxIter := opts.MaxIterations
+	if maxIter <= 0 {
+		maxIter = defaultIRTIterations
+	}
+	if maxIter > maxIRTIterations {
+		maxIter = maxIRTIterations
+	}
+	ids, pids, matrix := buildItemMatrix(items, responses)
+	rows := irtCategories(matrix, points)
+	// drop participants without any in-range answer
+	keptPIDs := make([]string, 0, len(pids))
+	keptRows := make([][]int, 0, len(rows))
+	for i, row := range rows {
+		for _, x := range row {
+			if x >= 0 {
+				keptPIDs = append(keptPIDs, pids[i])
+				keptRows = append(keptRows, row)
+				break
+			}
+		}
+	}
+	if len(keptRows) < minIRTParticipants {
+		return nil, NewInvalidError("at least 10 participants with responses required")
+	}
+	fit := fitIRT(model, keptRows, len(ids), points, maxIter)
+	cal := &IRTCalibration{
+		ScaleFingerprint: ScaleFingerprint(points, items),
+		Model:            model,
+		Categories:       points,
+		N:                len(keptRows),
+		LogLikelihood:    fit.logLikelihood,
+		Iterations:       fit.iterations,
+		Converged:        fit.converged,
+		ThetaGrid:        IRTThetaGrid(),
+	}
+	cal.TestInformation = make([]float64, len(cal.ThetaGrid))
+	for j, it := range fit.items {
+		p := IRTItemParams{ItemID: ids[j], Discrimination: it.a, Thresholds: append([]float64(nil), it.b...)}
+		for _, b := range it.b {
+			p.Difficulty += b / float64(len(it.b))
+		}
+		p.Information = make([]float64, len(cal.ThetaGrid))
+		for g, theta := range cal.ThetaGrid {
+			p.Information[g] = it.information(theta)
+			cal.TestInformation[g] += p.Information[g]
+		}
+		cal.Items = append(cal.Items, p)
+	}
+	cal.TestSE = make([]float64, len(cal.TestInformation))
+	for g, info := range cal.TestInformation {
+		// the N(0, 1) prior contributes unit information, matching the EAP posterior SD scale
+		cal.TestSE[g] = 1 / math.Sqrt(info+1)
+	}
+	cal.Persons = scoreIRTRows(fit.items, keptPIDs, keptRows)
+	return cal, nil
+}
+
+// ScoreIRT estimates θ (EAP) and its standard error (posterior SD) for every participant with at
+// least one answered calibrated item, using the stored item parameters.
+func ScoreIRT(cal *IRTCalibration, items []*Item, responses []*Response) map[string]IRTPerson {
+	out := map[string]IRTPerson{}
+	if cal == nil || len(cal.Items) == 0 {
+		return out
+	}
+	byID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	calItems := make([]*Item, 0, len(cal.Items))
+	params := make([]irtItem, 0, len(cal.Items))
+	for _, p := range cal.Items {
+		it, ok := byID[p.ItemID]
+		if !ok {
+			continue
+		}
+		calItems = append(calItems, it)
+		params = append(params, irtItem{model: cal.Model, a: p.Discrimination, b: p.Thresholds})
+	}
+	// buildItemMatrix orders columns by item ID, as does the calibration itself
+	sort.Slice(calItems, func(i, j int) bool { return calItems[i].ID < calItems[j].ID })
+	_, pids, matrix := buildItemMatrix(calItems, responses)
+	for _, p := range scoreIRTRows(params, pids, irtCategories(matrix, cal.Categories)) {
+		out[p.ParticipantID] = p
+	}
+	return out
+}
+
+func scoreIRTRows(items []irtItem, pids []string, rows [][]int) []IRTPerson {
+	nodes, weights := irtQuadrature()
+	table := irtProbTable(items, nodes)
+	post := make([]float64, len(nodes))
+	out := make([]IRTPerson, 0, len(rows))
+	for i, row := range rows {
+		answered := 0
+		for _, x := range row {
+			if x >= 0 {
+				answered++
+			}
+		}
+		if answered == 0 {
+			continue
+		}
+		theta, se := irtEAP(table, row, nodes, weights, post)
+		out = append(out, IRTPerson{ParticipantID: pids[i], Theta: theta, SE: se, Answered: answered})
+	}
+	return out
+}
+
+// latestIRTCalibration returns the highest version fitted on the given scale fingerprint.
+func latestIRTCalibration(cals []*IRTCalibration, fingerprint string) *IRTCalibration {
+	var out *IRTCalibration
+	for _, c := range cals {
+		if c.ScaleFingerprint == fingerprint && (out == nil || c.Version > out.Version) {
+			out = c
+		}
+	}
+	return out
+}
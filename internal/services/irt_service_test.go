@@ -0,0 +1,286 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type stubIRTStore struct {
+	stubAnalyticsStore
+	mu           sync.Mutex
+	calibrations []*IRTCalibration
+	// beforeSave runs ahead of each save, standing in for a concurrent calibration
+	beforeSave func(c *IRTCalibration)
+}
+
+func (s *stubIRTStore) SaveIRTCalibration(c *IRTCalibration) error {
+	if s.beforeSave != nil {
+		s.beforeSave(c)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.calibrations {
+		if existing.ScaleID == c.ScaleID && existing.Version == c.Version {
+			return NewConflictError("unable to save calibration")
+		}
+	}
+	cp := *c
+	s.calibrations = append(s.calibrations, &cp)
+	return nil
+}
+
+func (s *stubIRTStore) ListIRTCalibrations(scaleID string) ([]*IRTCalibration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []*IRTCalibration{}
+	for _, c := range s.calibrations {
+		if c.ScaleID == scaleID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// simulateIRT draws responses of n participants (θ ~ N(0, 1)) to the given items on a
+// len(b)+1 point scale; scores are 1-based like stored Likert answers.
+func simulateIRT(model string, params []irtItem, n int, seed int64) ([]*Item, []*Response, map[string]float64) {
+	rng := rand.New(rand.NewSource(seed))
+	items := make([]*Item, len(params))
+	for j := range params {
+		params[j].model = model
+		items[j] = &Item{ID: fmt.Sprintf("i%d", j+1), ScaleID: "S1", Type: "likert"}
+	}
+	thetas := map[string]float64{}
+	var responses []*Response
+	for i := 0; i < n; i++ {
+		pid := fmt.Sprintf("p%03d", i)
+		theta := rng.NormFloat64()
+		thetas[pid] = theta
+		for j, it := range params {
+			p := make([]float64, len(it.b)+1)
+			it.probs(theta, p)
+			u, c := rng.Float64(), 0
+			for ; c < len(p)-1 && u > p[c]; c++ {
+				u -= p[c]
+			}
+			responses = append(responses, &Response{ParticipantID: pid, ItemID: items[j].ID, RawValue: c + 1, ScoreValue: c + 1})
+		}
+	}
+	return items, responses, thetas
+}
+
+func correlation(a, b []float64) float64 {
+	m := make([][]float64, len(a))
+	for i := range a {
+		m[i] = []float64{a[i], b[i]}
+	}
+	_, corr, _ := pairwiseMoments(m, 2)
+	return corr[0][1]
+}
+
+func TestCalibrateIRTRecoversGRMParameters(t *testing.T) {
+	truth := []irtItem{
+		{a: 1.8, b: []float64{-1.5, -0.5, 0.4, 1.3}},
+		{a: 1.2, b: []float64{-1.0, 0.0, 0.8, 1.8}},
+		{a: 2.2, b: []float64{-2.0, -1.0, 0.0, 1.0}},
+		{a: 0.9, b: []float64{-0.8, 0.2, 1.0, 2.0}},
+		{a: 1.5, b: []float64{-1.8, -0.6, 0.5, 1.5}},
+	}
+	items, responses, thetas := simulateIRT(IRTModelGRM, truth, 800, 7)
+	cal, err := CalibrateIRT(5, items, responses, IRTOptions{Model: IRTModelGRM})
+	if err != nil {
+		t.Fatalf("CalibrateIRT: %v", err)
+	}
+	if !cal.Converged {
+		t.Fatalf("expected convergence within %d iterations", cal.Iterations)
+	}
+	if cal.N != 800 || len(cal.Items) != 5 || len(cal.Persons) != 800 {
+		t.Fatalf("unexpected shape: n=%d items=%d persons=%d", cal.N, len(cal.Items), len(cal.Persons))
+	}
+	for j, p := range cal.Items {
+		if math.Abs(p.Discrimination-truth[j].a) > 0.4 {
+			t.Errorf("item %s: a=%.3f want ≈%.2f", p.ItemID, p.Discrimination, truth[j].a)
+		}
+		for c, b := range p.Thresholds {
+			if math.Abs(b-truth[j].b[c]) > 0.35 {
+				t.Errorf("item %s: b%d=%.3f want ≈%.2f", p.ItemID, c+1, b, truth[j].b[c])
+			}
+		}
+		if len(p.Information) != len(cal.ThetaGrid) {
+			t.Fatalf("information curve length %d, grid %d", len(p.Information), len(cal.ThetaGrid))
+		}
+	}
+	var est, actual []float64
+	for _, p := range cal.Persons {
+		est = append(est, p.Theta)
+		actual = append(actual, thetas[p.ParticipantID])
+		if p.SE <= 0 || p.SE >= 1 {
+			t.Fatalf("posterior SD should lie in (0, 1), got %v", p.SE)
+		}
+	}
+	if r := correlation(est, actual); r < 0.85 {
+		t.Fatalf("θ estimates correlate %.3f with the generating values", r)
+	}
+	// test information peaks inside the thresholds and SE follows it
+	mid := len(cal.ThetaGrid) / 2
+	if cal.TestInformation[mid] <= cal.TestInformation[0] || cal.TestSE[mid] >= cal.TestSE[0] {
+		t.Fatalf("expected more information at θ=0 than at the grid edge")
+	}
+}
+
+func TestCalibrateIRTRasch(t *testing.T) {
+	truth := []irtItem{
+		{a: 1, b: []float64{-1.0, 0.0, 1.0}},
+		{a: 1, b: []float64{-0.5, 0.5, 1.5}},
+		{a: 1, b: []float64{-1.5, -0.5, 0.5}},
+		{a: 1, b: []float64{-1.0, 0.5, 1.0}},
+		{a: 1, b: []float64{0.0, 0.5, 2.0}},
+		{a: 1, b: []float64{-2.0, -1.0, 0.0}},
+	}
+	items, responses, _ := simulateIRT(IRTModelRasch, truth, 800, 11)
+	cal, err := CalibrateIRT(4, items, responses, IRTOptions{Model: IRTModelRasch})
+	if err != nil {
+		t.Fatalf("CalibrateIRT: %v", err)
+	}
+	for j, p := range cal.Items {
+		if p.Discrimination != cal.Items[0].Discrimination {
+			t.Fatalf("rasch discrimination must be shared, got %v and %v", p.Discrimination, cal.Items[0].Discrimination)
+		}
+		if math.Abs(p.Discrimination-1) > 0.2 {
+			t.Errorf("common a=%.3f want ≈1", p.Discrimination)
+		}
+		for c, b := range p.Thresholds {
+			if math.Abs(b-truth[j].b[c]) > 0.35 {
+				t.Errorf("item %s: step %d=%.3f want ≈%.2f", p.ItemID, c+1, b, truth[j].b[c])
+			}
+		}
+	}
+}
+
+func TestCalibrateIRTValidation(t *testing.T) {
+	items, responses, _ := simulateIRT(IRTModelGRM, []irtItem{{a: 1, b: []float64{0}}, {a: 1, b: []float64{0}}}, 5, 1)
+	if _, err := CalibrateIRT(2, items, responses, IRTOptions{}); err == nil {
+		t.Fatalf("expected error for too few participants")
+	}
+	if _, err := CalibrateIRT(2, items, responses, IRTOptions{Model: "3pl"}); err == nil {
+		t.Fatalf("expected error for unknown model")
+	}
+}
+
+func TestIRTServiceVersionsAndScoreExport(t *testing.T) {
+	truth := []irtItem{
+		{a: 1.5, b: []float64{-1, 0, 1}},
+		{a: 1.0, b: []float64{-0.5, 0.5, 1.5}},
+		{a: 2.0, b: []float64{-1.5, -0.5, 0.5}},
+	}
+	items, responses, _ := simulateIRT(IRTModelGRM, truth, 200, 3)
+	store := &stubIRTStore{stubAnalyticsStore: stubAnalyticsStore{
+		scale:     &Scale{ID: "S1", TenantID: "T1", Points: 4},
+		items:     items,
+		responses: responses,
+	}}
+	svc := NewIRTService(store)
+	if _, err := svc.Calibrate("T2", "S1", IRTOptions{}); err == nil {
+		t.Fatalf("expected forbidden for another tenant")
+	}
+	if _, err := svc.GetCalibration("T1", "S1", 0); err == nil {
+		t.Fatalf("expected not found before calibrating")
+	}
+	first, err := svc.Calibrate("T1", "S1", IRTOptions{Model: IRTModelRasch})
+	if err != nil {
+		t.Fatalf("Calibrate: %v", err)
+	}
+	second, err := svc.Calibrate("T1", "S1", IRTOptions{})
+	if err != nil {
+		t.Fatalf("Calibrate: %v", err)
+	}
+	if first.Version != 1 || second.Version != 2 || second.Model != IRTModelGRM {
+		t.Fatalf("unexpected versions %d/%d model %s", first.Version, second.Version, second.Model)
+	}
+	latest, err := svc.GetCalibration("T1", "S1", 0)
+	if err != nil || latest.Version != 2 {
+		t.Fatalf("latest calibration: %+v %v", latest, err)
+	}
+
+	exportStore := newExportStubStore()
+	exportStore.scale = store.scale
+	exportStore.items = items
+	exportStore.responses = responses
+	exportStore.calibrations = store.calibrations
+	res, err := NewExportService(exportStore).ExportCSV(ExportParams{TenantID: "T1", ScaleID: "S1", Format: "score"})
+	if err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(res.Data))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if strings.Join(rows[0], ",") != "participant_id,total_score,theta,theta_se" || len(rows) != 201 {
+		t.Fatalf("unexpected score export header %v (%d rows)", rows[0], len(rows))
+	}
+	if rows[1][2] == "" || rows[1][3] == "" {
+		t.Fatalf("expected θ for %s", rows[1][0])
+	}
+
+	// a new item makes it a different scale version: θ is no longer exported and the stored
+	// calibrations are no longer current
+	store.items = append(store.items, &Item{ID: "i9", ScaleID: "S1", Type: "likert"})
+	exportStore.items = store.items
+	res, err = NewExportService(exportStore).ExportCSV(ExportParams{TenantID: "T1", ScaleID: "S1", Format: "score"})
+	if err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	if !strings.HasPrefix(string(res.Data), "participant_id,total_score\n") {
+		t.Fatalf("expected plain score export, got %q", strings.SplitN(string(res.Data), "\n", 2)[0])
+	}
+	list, err := svc.ListCalibrations("T1", "S1")
+	if err != nil {
+		t.Fatalf("ListCalibrations: %v", err)
+	}
+	if len(list.Calibrations) != 2 || list.Calibrations[0].Current {
+		t.Fatalf("unexpected calibration list %+v", list)
+	}
+	if _, err := svc.GetCalibration("T1", "S1", 1); err != nil {
+		t.Fatalf("older versions stay retrievable: %v", err)
+	}
+}
+
+func TestIRTCalibrateRetriesTakenVersion(t *testing.T) {
+	items, responses, _ := simulateIRT(IRTModelRasch, []irtItem{{a: 1, b: []float64{0}}, {a: 1, b: []float64{0.5}}}, 100, 5)
+	store := &stubIRTStore{stubAnalyticsStore: stubAnalyticsStore{
+		scale:     &Scale{ID: "S1", TenantID: "T1", Points: 2},
+		items:     items,
+		responses: responses,
+	}}
+	// another calibration saves the same version first, twice
+	taken := 0
+	store.beforeSave = func(c *IRTCalibration) {
+		if taken < 2 {
+			taken++
+			store.calibrations = append(store.calibrations, &IRTCalibration{ScaleID: c.ScaleID, Version: c.Version})
+		}
+	}
+	svc := NewIRTService(store)
+	cal, err := svc.Calibrate("T1", "S1", IRTOptions{Model: IRTModelRasch})
+	if err != nil {
+		t.Fatalf("Calibrate: %v", err)
+	}
+	if cal.Version != 3 || len(store.calibrations) != 3 {
+		t.Fatalf("expected version 3 after two lost races, got %d (%d stored)", cal.Version, len(store.calibrations))
+	}
+
+	store.beforeSave = func(c *IRTCalibration) {
+		store.calibrations = append(store.calibrations, &IRTCalibration{ScaleID: c.ScaleID, Version: c.Version})
+	}
+	if _, err := svc.Calibrate("T1", "S1", IRTOptions{Model: IRTModelRasch}); err == nil {
+		t.Fatalf("expected a conflict once the attempts run out")
+	}
+	if len(store.calibrations) != 3+irtSaveAttempts {
+		t.Fatalf("expected %d attempts, store has %d", irtSaveAttempts, len(store.calibrations))
+	}
+}
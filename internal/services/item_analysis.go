@@ -0,0 +1,172 @@
+package services
+
+import "math"
+
+// ItemAnalysisRow holds the diagnostics of one item.
+type ItemAnalysisRow struct {
+	ID       string  `json:"id"`
+	N        int     `json:"n"`
+	Mean     float64 `json:"mean"`
+	SD       float64 `json:"sd"`
+	ItemRest float64 `json:"corrected_item_total"`
+	// AlphaIfDeleted is the alpha of the remaining items, bounded to [0,1] like CronbachAlpha.
+	AlphaIfDeleted float64 `json:"alpha_if_deleted"`
+	// MeanInterItem is the mean correlation of this item with every other item.
+	MeanInterItem float64 `json:"mean_inter_item_r"`
+}
+
+// ItemAnalysis is the result of AnalyzeItems. Correlations follows the order of ItemIDs;
+// correlations that are undefined (an item without variance) are reported as 0.
+type ItemAnalysis struct {
+	ScaleID       string            `json:"scale_id"`
+	Missing       string            `json:"missing"`
+	N             int               `json:"n"`
+	Alpha         float64           `json:"alpha"`
+	MeanInterItem float64           `json:"mean_inter_item_r"`
+	ItemIDs       []string          `json:"item_ids"`
+	Correlations  [][]float64       `json:"correlations"`
+	PairN         [][]int           `json:"pair_n"`
+	Items         []ItemAnalysisRow `json:"items"`
+}
+
+// AnalyzeItems computes corrected item-total correlations, alpha-if-item-deleted and the
+// inter-item correlation matrix from a participant × item matrix whose missing cells are NaN.
+// Alpha and item-rest correlations are derived from pairwise covariances, so a complete
+// (listwise) matrix yields the classical statistics and an incomplete one yields their
+// pairwise-deletion counterparts.
+func AnalyzeItems(ids []string, matrix [][]float64) *ItemAnalysis {
+	k := len(ids)
+	cov, corr, pairN := pairwiseMoments(matrix, k)
+	out := &ItemAnalysis{
+		N:            len(matrix),
+		ItemIDs:      ids,
+		Correlations: corr,
+		PairN:        pairN,
+		Items:        make([]ItemAnalysisRow, k),
+	}
+	out.Alpha = alphaFromCovariance(cov, seqIndex(k))
+	var sumR float64
+	var pairs int
+	for i := 0; i < k; i++ {
+		row := ItemAnalysisRow{ID: ids[i], N: pairN[i][i]}
+		var values []float64
+		for _, r := range matrix {
+			if !math.IsNaN(r[i]) {
+				values = append(values, r[i])
+			}
+		}
+		if st := Describe(values); st != nil {
+			row.Mean, row.SD = st.Mean, st.SD
+		}
+		rest := make([]int, 0, k-1)
+		var restCov, itemR float64
+		for j := 0; j < k; j++ {
+			if j == i {
+				continue
+			}
+			rest = append(rest, j)
+			restCov += cov[i][j]
+			itemR += out.Correlations[i][j]
+			if j > i {
+				sumR += out.Correlations[i][j]
+				pairs++
+			}
+		}
+		if k > 1 {
+			row.MeanInterItem = itemR / float64(k-1)
+		}
+		restVar := sumCovariance(cov, rest)
+		if cov[i][i] > 0 && restVar > 0 {
+			row.ItemRest = restCov / math.Sqrt(cov[i][i]*restVar)
+		}
+		row.AlphaIfDeleted = alphaFromCovariance(cov, rest)
+		out.Items[i] = row
+	}
+	if pairs > 0 {
+		out.MeanInterItem = sumR / float64(pairs)
+	}
+	return out
+}
+
+// pairwiseMoments returns sample covariances and Pearson correlations, each computed over the
+// participants who answered both items, together with the number of such participants.
+func pairwiseMoments(matrix [][]float64, k int) ([][]float64, [][]float64, [][]int) {
+	cov := make([][]float64, k)
+	corr := make([][]float64, k)
+	pairN := make([][]int, k)
+	for i := 0; i < k; i++ {
+		cov[i] = make([]float64, k)
+		corr[i] = make([]float64, k)
+		pairN[i] = make([]int, k)
+	}
+	for i := 0; i < k; i++ {
+		for j := 0; j <= i; j++ {
+			var n int
+			var si, sj float64
+			for _, r := range matrix {
+				if math.IsNaN(r[i]) || math.IsNaN(r[j]) {
+					continue
+				}
+				n++
+				si += r[i]
+				sj += r[j]
+			}
+			var sxy, sxx, syy float64
+			if n > 1 {
+				mi, mj := si/float64(n), sj/float64(n)
+				for _, r := range matrix {
+					if math.IsNaN(r[i]) || math.IsNaN(r[j]) {
+						continue
+					}
+					sxy += (r[i] - mi) * (r[j] - mj)
+					sxx += (r[i] - mi) * (r[i] - mi)
+					syy += (r[j] - mj) * (r[j] - mj)
+				}
+				sxy /= float64(n - 1)
+			}
+			var r float64
+			if sxx > 0 && syy > 0 {
+				r = math.Max(-1, math.Min(1, sxy*float64(n-1)/math.Sqrt(sxx*syy)))
+			}
+			cov[i][j], cov[j][i] = sxy, sxy
+			corr[i][j], corr[j][i] = r, r
+			pairN[i][j], pairN[j][i] = n, n
+		}
+	}
+	return cov, corr, pairN
+}
+
+func sumCovariance(cov [][]float64, idx []int) float64 {
+	var total float64
+	for _, i := range idx {
+		for _, j := range idx {
+			total += cov[i][j]
+		}
+	}
+	return total
+}
+
+// alphaFromCovariance computes Cronbach's alpha for the items in idx.
+func alphaFromCovariance(cov [][]float64, idx []int) float64 {
+	k := len(idx)
+	if k < 2 {
+		return 0
+	}
+	total := sumCovariance(cov, idx)
+	if total <= 0 {
+		return 0
+	}
+	var itemVars float64
+	for _, i := range idx {
+		itemVars += cov[i][i]
+	}
+	kf := float64(k)
+	alpha := (kf / (kf - 1)) * (1 - itemVars/total)
+	if alpha < 0 {
+		return 0
+	}
+	if alpha > 1 {
+		return 1
+	}
+	return alpha
+}
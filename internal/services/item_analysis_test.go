@@ -0,0 +1,81 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func pearson(x, y []float64) float64 {
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(len(x))
+	my /= float64(len(y))
+	var sxy, sxx, syy float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+func TestAnalyzeItemsListwise(t *testing.T) {
+	data := [][]float64{
+		{1, 2, 1},
+		{2, 2, 3},
+		{3, 4, 2},
+		{4, 5, 5},
+		{5, 4, 3},
+	}
+	res := AnalyzeItems([]string{"A", "B", "C"}, data)
+	if math.Abs(res.Alpha-CronbachAlpha(data)) > 1e-9 {
+		t.Fatalf("alpha mismatch: %f vs %f", res.Alpha, CronbachAlpha(data))
+	}
+	rest := make([]float64, len(data))
+	a := make([]float64, len(data))
+	for i, r := range data {
+		a[i] = r[0]
+		rest[i] = r[1] + r[2]
+	}
+	if want := pearson(a, rest); math.Abs(res.Items[0].ItemRest-want) > 1e-9 {
+		t.Fatalf("corrected item-total: got %f, want %f", res.Items[0].ItemRest, want)
+	}
+	without := make([][]float64, len(data))
+	for i, r := range data {
+		without[i] = []float64{r[1], r[2]}
+	}
+	if want := CronbachAlpha(without); math.Abs(res.Items[0].AlphaIfDeleted-want) > 1e-9 {
+		t.Fatalf("alpha if deleted: got %f, want %f", res.Items[0].AlphaIfDeleted, want)
+	}
+	b := []float64{2, 2, 4, 5, 4}
+	if want := pearson(a, b); math.Abs(res.Correlations[0][1]-want) > 1e-9 || res.Correlations[1][0] != res.Correlations[0][1] {
+		t.Fatalf("correlation matrix: got %f, want %f", res.Correlations[0][1], want)
+	}
+	mean := (res.Correlations[0][1] + res.Correlations[0][2] + res.Correlations[1][2]) / 3
+	if math.Abs(res.MeanInterItem-mean) > 1e-9 {
+		t.Fatalf("mean inter-item r: got %f, want %f", res.MeanInterItem, mean)
+	}
+}
+
+func TestAnalyzeItemsPairwise(t *testing.T) {
+	nan := math.NaN()
+	data := [][]float64{
+		{1, 1, nan},
+		{2, 2, 1},
+		{3, 3, 2},
+		{4, nan, 3},
+	}
+	res := AnalyzeItems([]string{"A", "B", "C"}, data)
+	if res.PairN[0][1] != 3 || res.PairN[0][2] != 3 || res.PairN[1][2] != 2 || res.PairN[0][0] != 4 {
+		t.Fatalf("unexpected pair counts: %+v", res.PairN)
+	}
+	if math.Abs(res.Correlations[0][1]-1) > 1e-9 || math.Abs(res.Correlations[0][2]-1) > 1e-9 {
+		t.Fatalf("expected perfect pairwise correlations, got %+v", res.Correlations)
+	}
+	if res.Items[0].N != 4 || res.Items[0].Mean != 2.5 {
+		t.Fatalf("unexpected item row: %+v", res.Items[0])
+	}
+}
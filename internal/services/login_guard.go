@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Failed sign-ins are counted per account and per client IP. After a few free attempts every
+// failure makes the caller wait twice as long before the next one; at the lock threshold the key is
+// locked out for loginLockDuration. Failures are forgotten after loginFailureWindow without one.
+const (
+	loginAccountFreeFailures = 3
+	loginAccountLockFailures = 10
+	loginIPFreeFailures      = 10
+	loginIPLockFailures      = 50
+	loginMaxDelay            = 5 * time.Minute
+	loginLockDuration        = 15 * time.Minute
+	loginFailureWindow       = 15 * time.Minute
+	// loginUnknownAccounts caps the counters kept in memory for emails without an account.
+	loginUnknownAccounts = 10000
+
+	loginKeyAccount = "account:"
+	loginKeyIP      = "ip:"
+)
+
+// LoginGuardStore keeps failed sign-in counters so that lockouts survive restarts.
+type LoginGuardStore interface {
+	GetLoginThrottle(key string) (*LoginThrottle, error)
+	SaveLoginThrottle(t *LoginThrottle) error
+	DeleteLoginThrottle(key string) (bool, error)
+	ListTenantMembers(tenantID string) ([]*Membership, error)
+	FindUserByEmail(email string) (*User, error)
+	AddAudit(entry AuditEntry)
+}
+
+// LoginThrottle counts recent failures for one account ("account:<email>") or IP ("ip:<addr>").
+type LoginThrottle struct {
+	Key           string
+	TenantID      string // account keys of known users only
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Lockout is the admin view of an account with recent failed sign-ins.
+type Lockout struct {
+	Email         string    `json:"email"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until,omitempty"`
+	Locked        bool      `json:"locked"`
+}
+
+type LoginGuard struct {
+	store   LoginGuardStore
+	members *MembershipService
+	now     func() time.Time
+	mu      sync.Mutex
+	// unknown counts failures for emails without an account. It is kept in memory and bounded, so
+	// guessing addresses fills neither the database nor the audit log, while such emails are
+	// throttled like real accounts and do not stand out.
+	unknown map[string]*LoginThrottle
+}
+
+func NewLoginGuard(store LoginGuardStore) *LoginGuard {
+	return &LoginGuard{store: store, now: time.Now, unknown: map[string]*LoginThrottle{}}
+}
+
+// WithMemberships lets tenant admins manage the lockouts of every member of their tenant, not only
+// of users whose sign-in starts there.
+func (g *LoginGuard) WithMemberships(members *MembershipService) {
+	g.members = members
+}
+
+func accountKey(email string) string {
+	return loginKeyAccount + strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check refuses the attempt while the account or the IP has to wait.
+func (g *LoginGuard) Check(email, ip string) error {
+	now := g.now().UTC()
+	for _, key := range []string{accountKey(email), loginKeyIP + ip} {
+		t, err := g.store.GetLoginThrottle(key)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			t = g.unknownThrottle(key)
+		}
+		if t != nil && now.Before(t.LockedUntil) {
+			wait := t.LockedUntil.Sub(now)
+			return NewRetryLaterError(fmt.Sprintf("too many failed sign-ins; try again in %s", wait.Round(time.Second)), wait)
+		}
+	}
+	return nil
+}
+
+// Failure records a failed attempt and audits it. reason is kept in the audit note only. For an
+// email without an account only the first failure in a window is audited.
+func (g *LoginGuard) Failure(email, ip, reason string) error {
+	now := g.now().UTC()
+	u, err := g.store.FindUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	audit := AuditEntry{Time: now, Actor: email, Action: "login_failed", Note: reason, IP: ip, Result: AuditResultFailure}
+	if u != nil {
+		audit.TenantID, audit.ActorID, audit.Target = u.TenantID, u.ID, u.ID
+		g.store.AddAudit(audit)
+		if _, err := g.record(accountKey(email), u.TenantID, email, loginAccountFreeFailures, loginAccountLockFailures, now); err != nil {
+			return err
+		}
+	} else if t := g.recordUnknown(accountKey(email), email, now); t.Failures == 1 {
+		g.store.AddAudit(audit)
+	}
+	if ip != "" {
+		if _, err := g.record(loginKeyIP+ip, "", ip, loginIPFreeFailures, loginIPLockFailures, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *LoginGuard) record(key, tenantID, actor string, free, lock int, now time.Time) (*LoginThrottle, error) {
+	t, err := g.store.GetLoginThrottle(key)
+	if err != nil {
+		return nil, err
+	}
+	t = g.count(t, key, tenantID, actor, free, lock, now)
+	if err := g.store.SaveLoginThrottle(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// recordUnknown counts a failure for an email without an account in memory. When the table is
+// full, expired counters go first, then the one idle the longest.
+func (g *LoginGuard) recordUnknown(key, actor string, now time.Time) *LoginThrottle {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.unknown[key]; !ok && len(g.unknown) >= loginUnknownAccounts {
+		oldest := ""
+		for k, t := range g.unknown {
+			if loginThrottleExpired(t, now) {
+				delete(g.unknown, k)
+			} else if oldest == "" || t.LastFailureAt.Before(g.unknown[oldest].LastFailureAt) {
+				oldest = k
+			}
+		}
+		if len(g.unknown) >= loginUnknownAccounts {
+			delete(g.unknown, oldest)
+		}
+	}
+	t := g.count(g.unknown[key], key, "", actor, loginAccountFreeFailures, loginAccountLockFailures, now)
+	g.unknown[key] = t
+	cp := *t
+	return &cp
+}
+
+func (g *LoginGuard) unknownThrottle(key string) *LoginThrottle {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if t, ok := g.unknown[key]; ok {
+		cp := *t
+		return &cp
+	}
+	return nil
+}
+
+func loginThrottleExpired(t *LoginThrottle, now time.Time) bool {
+	return now.Sub(t.LastFailureAt) > loginFailureWindow && !now.Before(t.LockedUntil)
+}
+
+// count adds a failure to t (nil or expired starts over) and sets the wait that follows it.
+func (g *LoginGuard) count(t *LoginThrottle, key, tenantID, actor string, free, lock int, now time.Time) *LoginThrottle {
+	if t == nil || loginThrottleExpired(t, now) {
+		t = &LoginThrottle{Key: key}
+	}
+	t.TenantID = tenantID
+	t.Failures++
+	t.LastFailureAt = now
+	switch {
+	case t.Failures >= lock:
+		t.LockedUntil = now.Add(loginLockDuration)
+		if t.Failures == lock {
+			g.store.AddAudit(AuditEntry{Time: now, TenantID: tenantID, Actor: actor, Action: "login_locked", Target: key, Note: fmt.Sprintf("%d failed sign-ins", t.Failures),
+				Result: AuditResultDenied})
+		}
+	case t.Failures >= free:
+		delay := loginMaxDelay
+		if shift := t.Failures - free; shift < 16 && time.Second<<uint(shift) < loginMaxDelay {
+			delay = time.Second << uint(shift)
+		}
+		t.LockedUntil = now.Add(delay)
+	}
+	return t
+}
+
+// Success clears the account's counter. The IP counter is kept so that an attacker holding one valid
+// account cannot reset it.
+func (g *LoginGuard) Success(email string) error {
+	_, err := g.store.DeleteLoginThrottle(accountKey(email))
+	return err
+}
+
+// ListLockouts returns the tenant members with recent failed sign-ins, locked ones first.
+func (g *LoginGuard) ListLockouts(tenantID string) ([]Lockout, error) {
+	members, err := g.store.ListTenantMembers(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	now := g.now().UTC()
+	out := []Lockout{}
+	for _, m := range members {
+		t, err := g.store.GetLoginThrottle(accountKey(m.Email))
+		if err != nil {
+			return nil, err
+		}
+		if t == nil || loginThrottleExpired(t, now) {
+			continue
+		}
+		l := Lockout{Email: strings.TrimPrefix(t.Key, loginKeyAccount), Failures: t.Failures, LastFailureAt: t.LastFailureAt, Locked: now.Before(t.LockedUntil)}
+		if l.Locked {
+			l.LockedUntil = t.LockedUntil
+		}
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Locked != out[j].Locked {
+			return out[i].Locked
+		}
+		return out[i].LastFailureAt.After(out[j].LastFailureAt)
+	})
+	return out, nil
+}
+
+// Unlock clears the failed sign-ins of a member of the admin's tenant.
+func (g *LoginGuard) Unlock(tenantID, actor, email string) error {
+	u, err := g.store.FindUserByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return NewNotFoundError("user not found")
+	}
+	member := u.TenantID == tenantID
+	if g.members != nil {
+		role, err := g.members.Role(tenantID, u.ID)
+		if err != nil {
+			return err
+		}
+		member = role != ""
+	}
+	if !member {
+		return NewNotFoundError("user not found")
+	}
+	ok, err := g.store.DeleteLoginThrottle(accountKey(u.Email))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewNotFoundError("account is not locked")
+	}
+	g.store.AddAudit(AuditEntry{Time: g.now().UTC(), TenantID: tenantID, Actor: actor, Action: "login_unlocked", Target: u.ID, Note: u.Email})
+	return nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type loginGuardStubStore struct {
+	*membershipStubStore
+	throttles map[string]*LoginThrottle
+	audit     []AuditEntry
+}
+
+func (s *loginGuardStubStore) GetLoginThrottle(key string) (*LoginThrottle, error) {
+	if t, ok := s.throttles[key]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *loginGuardStubStore) SaveLoginThrottle(t *LoginThrottle) error {
+	cp := *t
+	s.throttles[t.Key] = &cp
+	return nil
+}
+
+func (s *loginGuardStubStore) DeleteLoginThrottle(key string) (bool, error) {
+	_, ok := s.throttles[key]
+	delete(s.throttles, key)
+	return ok, nil
+}
+
+// FindUserByEmail ignores case like the real stores.
+func (s *loginGuardStubStore) FindUserByEmail(email string) (*User, error) {
+	return s.membershipStubStore.FindUserByEmail(strings.ToLower(email))
+}
+
+func (s *loginGuardStubStore) AddAudit(entry AuditEntry) { s.audit = append(s.audit, entry) }
+
+func (s *loginGuardStubStore) countAudit(action string) int {
+	n := 0
+	for _, e := range s.audit {
+		if e.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestGuardedAuth(t *testing.T) (*AuthService, *LoginGuard, *loginGuardStubStore, *time.Time) {
+	t.Helper()
+	store := &loginGuardStubStore{membershipStubStore: newMembershipStubStore(), throttles: map[string]*LoginThrottle{}}
+	svc := NewAuthService(store, func(uid, tid, email string, ttl time.Duration) (string, error) {
+		return "token:" + uid, nil
+	})
+	members := NewMembershipService(store)
+	svc.WithMemberships(members)
+	if _, err := svc.Register("user@example.com", "Secret123", "Acme", SessionClient{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	guard := NewLoginGuard(store)
+	guard.WithMemberships(members)
+	now := time.Date(2025, 9, 26, 9, 0, 0, 0, time.UTC)
+	guard.now = func() time.Time { return now }
+	svc.WithLoginGuard(guard)
+	return svc, guard, store, &now
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var se *ServiceError
+	if !errors.As(err, &se) || se.Code != ErrorTooManyRequests {
+		t.Fatalf("expected too many requests, got %v", err)
+	}
+	return se.RetryAfter
+}
+
+func TestLoginGuardProgressiveDelayAndLockout(t *testing.T) {
+	svc, _, store, now := newTestGuardedAuth(t)
+	client := SessionClient{IP: "203.0.113.7"}
+	for i := 1; i < loginAccountFreeFailures; i++ {
+		if _, err := svc.Login("user@example.com", "wrong", client); err == nil {
+			t.Fatalf("wrong password accepted")
+		}
+	}
+	// the third failure starts the delay: 1s, then 2s, 4s, ...
+	var prev time.Duration
+	for i := loginAccountFreeFailures; i < loginAccountLockFailures; i++ {
+		if _, err := svc.Login("user@example.com", "wrong", client); err == nil {
+			t.Fatalf("wrong password accepted")
+		}
+		wait := retryAfter(t, svc.guard.Check("user@example.com", client.IP))
+		if wait <= prev {
+			t.Fatalf("delay must grow: %v after %v", wait, prev)
+		}
+		prev = wait
+		if _, err := svc.Login("user@example.com", "Secret123", client); retryAfter(t, err) != wait {
+			t.Fatalf("the correct password must also wait")
+		}
+		*now = now.Add(wait)
+	}
+	if _, err := svc.Login("USER@example.com", "wrong", client); err == nil {
+		t.Fatalf("wrong password accepted")
+	}
+	if wait := retryAfter(t, svc.guard.Check("user@example.com", "")); wait != loginLockDuration {
+		t.Fatalf("lockout = %v", wait)
+	}
+	if store.countAudit("login_failed") != loginAccountLockFailures || store.countAudit("login_locked") != 1 {
+		t.Fatalf("unexpected audit: %+v", store.audit)
+	}
+	for _, e := range store.audit {
+		if e.Action == "login_failed" && (e.IP != client.IP || e.Result != AuditResultFailure || (e.Target != "" && e.TenantID == "")) {
+			t.Fatalf("failed sign-in must record the client, tenant and result: %+v", e)
+		}
+		if e.Action == "login_locked" && e.Result != AuditResultDenied {
+			t.Fatalf("lockout must be audited as denied: %+v", e)
+		}
+	}
+	*now = now.Add(loginLockDuration)
+	if _, err := svc.Login("user@example.com", "Secret123", client); err != nil {
+		t.Fatalf("login after the lockout: %v", err)
+	}
+	if _, ok := store.throttles[accountKey("user@example.com")]; ok {
+		t.Fatalf("success must clear the account counter")
+	}
+	if _, ok := store.throttles[loginKeyIP+client.IP]; !ok {
+		t.Fatalf("success must keep the IP counter")
+	}
+}
+
+func TestLoginGuardPerIP(t *testing.T) {
+	svc, _, store, now := newTestGuardedAuth(t)
+	client := SessionClient{IP: "198.51.100.9"}
+	// spraying many accounts from one address
+	for i := 0; i < loginIPFreeFailures; i++ {
+		if err := svc.guard.Check("", client.IP); err != nil {
+			t.Fatalf("attempt %d refused: %v", i, err)
+		}
+		_, _ = svc.Login("nobody"+string(rune('a'+i))+"@example.com", "whatever1", client)
+	}
+	if err := svc.guard.Check("user@example.com", client.IP); err == nil {
+		t.Fatalf("IP must be throttled")
+	}
+	if _, err := svc.Login("user@example.com", "Secret123", SessionClient{IP: "192.0.2.1"}); err != nil {
+		t.Fatalf("other addresses must not be affected: %v", err)
+	}
+	if _, ok := store.throttles[accountKey("nobodya@example.com")]; ok {
+		t.Fatalf("unknown accounts must not be stored")
+	}
+	*now = now.Add(loginFailureWindow + loginMaxDelay + time.Second)
+	if err := svc.guard.Check("user@example.com", client.IP); err != nil {
+		t.Fatalf("throttle must expire: %v", err)
+	}
+}
+
+func TestLoginGuardUnknownAccounts(t *testing.T) {
+	svc, guard, store, now := newTestGuardedAuth(t)
+	// every attempt comes from a new address, so only the account counter applies
+	for i := 0; i < loginAccountLockFailures; i++ {
+		if _, err := svc.Login("ghost@example.com", "whatever1", SessionClient{IP: fmt.Sprintf("192.0.2.%d", i+1)}); err == nil {
+			t.Fatalf("unknown account accepted")
+		}
+		if i < loginAccountLockFailures-1 {
+			*now = now.Add(loginMaxDelay)
+		}
+	}
+	if wait := retryAfter(t, guard.Check("ghost@example.com", "")); wait != loginLockDuration {
+		t.Fatalf("unknown accounts must lock like real ones: %v", wait)
+	}
+	if store.countAudit("login_failed") != 1 {
+		t.Fatalf("repeated failures for an unknown account must be audited once: %+v", store.audit)
+	}
+	for i := 0; i < loginUnknownAccounts+10; i++ {
+		_ = guard.Failure(fmt.Sprintf("guess%d@example.com", i), "", "unknown account")
+	}
+	if len(guard.unknown) > loginUnknownAccounts {
+		t.Fatalf("unknown accounts must be bounded: %d", len(guard.unknown))
+	}
+	for k := range store.throttles {
+		if strings.HasPrefix(k, loginKeyAccount) {
+			t.Fatalf("unknown accounts must not be stored: %s", k)
+		}
+	}
+}
+
+func TestLoginGuardUnlock(t *testing.T) {
+	svc, guard, store, _ := newTestGuardedAuth(t)
+	u := store.users["user@example.com"]
+	for i := 0; i < loginAccountLockFailures; i++ {
+		_ = guard.Failure(u.Email, "", "wrong password")
+	}
+	list, err := guard.ListLockouts(u.TenantID)
+	if err != nil || len(list) != 1 || !list[0].Locked || list[0].Failures != loginAccountLockFailures {
+		t.Fatalf("ListLockouts = %+v, %v", list, err)
+	}
+	other, err := svc.Register("admin@other.example", "Secret123", "Other", SessionClient{})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if list, _ := guard.ListLockouts(other.TenantID); len(list) != 0 {
+		t.Fatalf("lockouts must be tenant scoped: %+v", list)
+	}
+	if err := guard.Unlock(other.TenantID, "admin@other.example", u.Email); err == nil {
+		t.Fatalf("another tenant must not unlock the account")
+	}
+	// membership, not the tenant sign-in starts in, decides
+	if err := guard.members.Grant(other.TenantID, u, TenantRoleMember, "admin@other.example"); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if list, _ := guard.ListLockouts(other.TenantID); len(list) != 1 {
+		t.Fatalf("members must be listed: %+v", list)
+	}
+	if err := guard.Unlock(other.TenantID, "admin@other.example", u.Email); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := svc.Login(u.Email, "Secret123", SessionClient{}); err != nil {
+		t.Fatalf("login after unlock: %v", err)
+	}
+	if err := guard.Unlock(u.TenantID, "admin@example.com", u.Email); err == nil {
+		t.Fatalf("unlocking an unlocked account must fail")
+	}
+	if store.countAudit("login_unlocked") != 1 {
+		t.Fatalf("missing audit entry: %+v", store.audit)
+	}
+}
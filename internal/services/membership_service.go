@@ -0,0 +1,336 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tenant roles. Owners and admins manage members and tenant settings; members work with the
+// tenant's scales. Every tenant has exactly one owner.
+const (
+	TenantRoleOwner  = "owner"
+	TenantRoleAdmin  = "admin"
+	TenantRoleMember = "member"
+)
+
+func tenantRoleRank(role string) int {
+	switch role {
+	case TenantRoleOwner:
+		return 3
+	case TenantRoleAdmin:
+		return 2
+	case TenantRoleMember:
+		return 1
+	}
+	return 0
+}
+
+// MembershipStore persists which users belong to which tenants. A user's TenantID is the tenant
+// the next sign-in starts in and always refers to one of its memberships.
+type MembershipStore interface {
+	GetMembership(tenantID, userID string) (*Membership, error)
+	ListTenantMembers(tenantID string) ([]*Membership, error)
+	ListUserMemberships(userID string) ([]*Membership, error)
+	SaveMembership(m *Membership) error
+	DeleteMembership(tenantID, userID string) (bool, error)
+	GetTenant(id string) (*Tenant, error)
+	AddTenant(t *Tenant) error
+	FindUserByEmail(email string) (*User, error)
+	SetUserTenant(userID, tenantID string) error
+	RemoveTenantCollaborations(tenantID, userID string) error
+	AddAudit(entry AuditEntry)
+}
+
+// Membership is a user's role in a tenant. Email and TenantName are filled in by listings.
+type Membership struct {
+	TenantID   string    `json:"tenant_id"`
+	TenantName string    `json:"tenant_name,omitempty"`
+	UserID     string    `json:"user_id"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type MembershipService struct {
+	store    MembershipStore
+	sessions *SessionService
+	now      func() time.Time
+}
+
+func NewMembershipService(store MembershipStore) *MembershipService {
+	return &MembershipService{store: store, now: time.Now}
+}
+
+// WithSessions ends a removed member's sessions in the tenant.
+func (s *MembershipService) WithSessions(sessions *SessionService) {
+	s.sessions = sessions
+}
+
+// Role returns the user's role in the tenant, or "" when the user is not a member.
+func (s *MembershipService) Role(tenantID, userID string) (string, error) {
+	if tenantID == "" || userID == "" {
+		return "", nil
+	}
+	m, err := s.store.GetMembership(tenantID, userID)
+	if err != nil || m == nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+// Require fails unless the user holds at least role min in the tenant.
+func (s *MembershipService) Require(tenantID, userID, min string) error {
+	role, err := s.Role(tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return NewForbiddenError("not a member of this tenant")
+	}
+	if tenantRoleRank(role) < tenantRoleRank(min) {
+		return NewForbiddenError("requires tenant role " + min)
+	}
+	return nil
+}
+
+// Grant makes u a member of the tenant with role, or changes the role of an existing member.
+// Owners keep their role; ownership only moves through TransferOwnership.
+func (s *MembershipService) Grant(tenantID string, u *User, role, actor string) error {
+	if u == nil || tenantID == "" {
+		return NewInvalidError("user and tenant required")
+	}
+	m, err := s.store.GetMembership(tenantID, u.ID)
+	if err != nil {
+		return err
+	}
+	if m != nil && (m.Role == role || m.Role == TenantRoleOwner) {
+		return nil
+	}
+	action := "member.role"
+	if m == nil {
+		m = &Membership{TenantID: tenantID, UserID: u.ID, Email: u.Email, CreatedAt: s.now().UTC()}
+		action = "member.add"
+	}
+	m.Role = role
+	if err := s.store.SaveMembership(m); err != nil {
+		return err
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: actor, Action: action, Target: tenantID, Note: u.Email + ":" + role})
+	return nil
+}
+
+// ListMembers returns the tenant's members, owner first.
+func (s *MembershipService) ListMembers(tenantID, actorID string) ([]*Membership, error) {
+	if err := s.Require(tenantID, actorID, TenantRoleMember); err != nil {
+		return nil, err
+	}
+	list, err := s.store.ListTenantMembers(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if ri, rj := tenantRoleRank(list[i].Role), tenantRoleRank(list[j].Role); ri != rj {
+			return ri > rj
+		}
+		return list[i].Email < list[j].Email
+	})
+	return list, nil
+}
+
+// ChangeRole sets a member's role to admin or member.
+func (s *MembershipService) ChangeRole(tenantID, actorID, actor, userID, role string) (*Membership, error) {
+	if err := s.Require(tenantID, actorID, TenantRoleAdmin); err != nil {
+		return nil, err
+	}
+	if role = normalizeTenantRole(role); role == "" {
+		return nil, NewInvalidError("role must be admin or member")
+	}
+	m, err := s.store.GetMembership(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, NewNotFoundError("member not found")
+	}
+	if m.Role == TenantRoleOwner {
+		return nil, NewForbiddenError("transfer ownership to change the owner's role")
+	}
+	if m.Role != role {
+		m.Role = role
+		if err := s.store.SaveMembership(m); err != nil {
+			return nil, err
+		}
+		s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: actor, ActorID: actorID, Action: "member.role", Target: tenantID, Note: m.Email + ":" + role})
+	}
+	return m, nil
+}
+
+// Remove takes a member out of the tenant and ends their sessions there. Members may remove
+// themselves; the owner has to transfer ownership first.
+func (s *MembershipService) Remove(tenantID, actorID, actor, userID string) error {
+	if actorID != userID {
+		if err := s.Require(tenantID, actorID, TenantRoleAdmin); err != nil {
+			return err
+		}
+	}
+	m, err := s.store.GetMembership(tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return NewNotFoundError("member not found")
+	}
+	if m.Role == TenantRoleOwner {
+		return NewForbiddenError("the owner cannot be removed; transfer ownership first")
+	}
+	if _, err := s.store.DeleteMembership(tenantID, userID); err != nil {
+		return err
+	}
+	// collaborations would otherwise keep the former member in as a guest
+	if err := s.store.RemoveTenantCollaborations(tenantID, userID); err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeTenant(userID, tenantID); err != nil {
+			return err
+		}
+	}
+	if err := s.rehome(m, tenantID); err != nil {
+		return err
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: actor, ActorID: actorID, Action: "member.remove", Target: tenantID, Note: m.Email})
+	return nil
+}
+
+// rehome moves the user's sign-in tenant away from a tenant they left. Someone who leaves their
+// last tenant gets a new personal one so that the account stays usable.
+func (s *MembershipService) rehome(m *Membership, leftTenantID string) error {
+	u, err := s.store.FindUserByEmail(m.Email)
+	if err != nil || u == nil || u.TenantID != leftTenantID {
+		return err
+	}
+	_, err = s.SignInTenant(u)
+	return err
+}
+
+// TransferOwnership makes another member the owner; the previous owner becomes an admin.
+func (s *MembershipService) TransferOwnership(tenantID, actorID, actor, userID string) error {
+	if err := s.Require(tenantID, actorID, TenantRoleOwner); err != nil {
+		return err
+	}
+	if userID == actorID {
+		return NewInvalidError("already the owner")
+	}
+	next, err := s.store.GetMembership(tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return NewNotFoundError("member not found")
+	}
+	prev, err := s.store.GetMembership(tenantID, actorID)
+	if err != nil {
+		return err
+	}
+	next.Role = TenantRoleOwner
+	if err := s.store.SaveMembership(next); err != nil {
+		return err
+	}
+	prev.Role = TenantRoleAdmin
+	if err := s.store.SaveMembership(prev); err != nil {
+		return err
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: actor, ActorID: actorID, Action: "tenant.transfer", Target: tenantID, Note: next.Email})
+	return nil
+}
+
+// ListTenants returns the tenants the user belongs to, with their names and the user's role.
+func (s *MembershipService) ListTenants(userID string) ([]*Membership, error) {
+	list, err := s.store.ListUserMemberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+// CreateTenant creates a tenant owned by u.
+func (s *MembershipService) CreateTenant(u *User, name string) (*Membership, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, NewInvalidError("tenant name required")
+	}
+	now := s.now().UTC()
+	t := &Tenant{ID: "t" + shortID(7), Name: name}
+	if err := s.store.AddTenant(t); err != nil {
+		return nil, err
+	}
+	m := &Membership{TenantID: t.ID, TenantName: t.Name, UserID: u.ID, Email: u.Email, Role: TenantRoleOwner, CreatedAt: now}
+	if err := s.store.SaveMembership(m); err != nil {
+		return nil, err
+	}
+	// the new tenant only becomes the sign-in tenant when the current one is not usable
+	if home, err := s.Role(u.TenantID, u.ID); err != nil {
+		return nil, err
+	} else if home == "" {
+		if err := s.store.SetUserTenant(u.ID, t.ID); err != nil {
+			return nil, err
+		}
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: t.ID, Actor: u.Email, ActorID: u.ID, Action: "tenant.create", Target: t.ID, Note: t.Name})
+	return m, nil
+}
+
+// SignInTenant returns the tenant a sign-in of u starts in: the user's TenantID while they are a
+// member there, otherwise their oldest membership.
+func (s *MembershipService) SignInTenant(u *User) (string, error) {
+	if role, err := s.Role(u.TenantID, u.ID); err != nil || role != "" {
+		return u.TenantID, err
+	}
+	list, err := s.ListTenants(u.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		m, err := s.CreateTenant(u, "Personal")
+		if err != nil {
+			return "", err
+		}
+		return m.TenantID, nil
+	}
+	if err := s.store.SetUserTenant(u.ID, list[0].TenantID); err != nil {
+		return "", err
+	}
+	return list[0].TenantID, nil
+}
+
+// Switch checks that the user may act in tenantID and makes it the tenant of their next sign-in.
+func (s *MembershipService) Switch(u *User, tenantID string) (*Membership, error) {
+	m, err := s.store.GetMembership(tenantID, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, NewForbiddenError("not a member of this tenant")
+	}
+	if u.TenantID != tenantID {
+		if err := s.store.SetUserTenant(u.ID, tenantID); err != nil {
+			return nil, err
+		}
+	}
+	if t, err := s.store.GetTenant(tenantID); err == nil && t != nil {
+		m.TenantName = t.Name
+	}
+	return m, nil
+}
+
+func normalizeTenantRole(role string) string {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case TenantRoleAdmin:
+		return TenantRoleAdmin
+	case TenantRoleMember, "":
+		return TenantRoleMember
+	}
+	return ""
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type membershipStubStore struct {
+	*authStubStore
+	members        map[string]*Membership
+	audit          []AuditEntry
+	removedCollabs []string
+}
+
+func newMembershipStubStore() *membershipStubStore {
+	return &membershipStubStore{authStubStore: newAuthStubStore(), members: map[string]*Membership{}}
+}
+
+func (s *membershipStubStore) view(m *Membership) *Membership {
+	cp := *m
+	if t, ok := s.tenants[m.TenantID]; ok {
+		cp.TenantName = t.Name
+	}
+	return &cp
+}
+
+func (s *membershipStubStore) GetMembership(tenantID, userID string) (*Membership, error) {
+	if m, ok := s.members[tenantID+"/"+userID]; ok {
+		return s.view(m), nil
+	}
+	return nil, nil
+}
+
+func (s *membershipStubStore) ListTenantMembers(tenantID string) ([]*Membership, error) {
+	out := []*Membership{}
+	for _, m := range s.members {
+		if m.TenantID == tenantID {
+			out = append(out, s.view(m))
+		}
+	}
+	return out, nil
+}
+
+func (s *membershipStubStore) ListUserMemberships(userID string) ([]*Membership, error) {
+	out := []*Membership{}
+	for _, m := range s.members {
+		if m.UserID == userID {
+			out = append(out, s.view(m))
+		}
+	}
+	return out, nil
+}
+
+func (s *membershipStubStore) SaveMembership(m *Membership) error {
+	cp := *m
+	s.members[m.TenantID+"/"+m.UserID] = &cp
+	return nil
+}
+
+func (s *membershipStubStore) DeleteMembership(tenantID, userID string) (bool, error) {
+	_, ok := s.members[tenantID+"/"+userID]
+	delete(s.members, tenantID+"/"+userID)
+	return ok, nil
+}
+
+func (s *membershipStubStore) GetTenant(id string) (*Tenant, error) {
+	if t, ok := s.tenants[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *membershipStubStore) SetUserTenant(userID, tenantID string) error {
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.TenantID = tenantID
+			return nil
+		}
+	}
+	return NewNotFoundError("user not found")
+}
+
+func (s *membershipStubStore) RemoveTenantCollaborations(tenantID, userID string) error {
+	s.removedCollabs = append(s.removedCollabs, tenantID+"/"+userID)
+	return nil
+}
+
+func (s *membershipStubStore) AddAudit(entry AuditEntry) { s.audit = append(s.audit, entry) }
+
+func newTestMemberships(t *testing.T) (*AuthService, *MembershipService, *membershipStubStore) {
+	t.Helper()
+	store := newMembershipStubStore()
+	auth := NewAuthService(store, func(uid, tid, email string, ttl time.Duration) (string, error) {
+		return "token:" + uid + ":" + tid, nil
+	})
+	members := NewMembershipService(store)
+	auth.WithMemberships(members)
+	return auth, members, store
+}
+
+func register(t *testing.T, auth *AuthService, email, tenant string) *AuthResult {
+	t.Helper()
+	res, err := auth.Register(email, "Secret123", tenant, SessionClient{})
+	if err != nil {
+		t.Fatalf("Register %s: %v", email, err)
+	}
+	return res
+}
+
+func join(t *testing.T, members *MembershipService, store *membershipStubStore, tenantID, email, role string) {
+	t.Helper()
+	if err := members.Grant(tenantID, store.users[email], role, "test"); err != nil {
+		t.Fatalf("Grant %s: %v", email, err)
+	}
+}
+
+func expectCode(t *testing.T, err error, code ErrorCode) {
+	t.Helper()
+	var se *ServiceError
+	if !errors.As(err, &se) || se.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
+
+func TestMembershipRoles(t *testing.T) {
+	auth, members, store := newTestMemberships(t)
+	owner := register(t, auth, "owner@example.com", "Acme")
+	other := register(t, auth, "bob@example.com", "Bob Inc")
+	if role, _ := members.Role(owner.TenantID, owner.UserID); role != TenantRoleOwner {
+		t.Fatalf("registration must make the owner, got %q", role)
+	}
+
+	join(t, members, store, owner.TenantID, "bob@example.com", TenantRoleMember)
+	if err := members.Grant(owner.TenantID, store.users["owner@example.com"], TenantRoleMember, "test"); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if role, _ := members.Role(owner.TenantID, owner.UserID); role != TenantRoleOwner {
+		t.Fatalf("Grant must not change the owner, got %q", role)
+	}
+
+	// members cannot manage members
+	_, err := members.ChangeRole(owner.TenantID, other.UserID, "bob@example.com", other.UserID, "admin")
+	expectCode(t, err, ErrorForbidden)
+	if list, err := members.ListMembers(owner.TenantID, other.UserID); err != nil || len(list) != 2 || list[0].Role != TenantRoleOwner {
+		t.Fatalf("ListMembers = %+v, %v", list, err)
+	}
+
+	if _, err := members.ChangeRole(owner.TenantID, owner.UserID, "owner@example.com", other.UserID, "admin"); err != nil {
+		t.Fatalf("ChangeRole: %v", err)
+	}
+	if err := members.Require(owner.TenantID, other.UserID, TenantRoleAdmin); err != nil {
+		t.Fatalf("promoted member must be admin: %v", err)
+	}
+	_, err = members.ChangeRole(owner.TenantID, other.UserID, "bob@example.com", owner.UserID, "member")
+	expectCode(t, err, ErrorForbidden)
+	expectCode(t, members.Remove(owner.TenantID, other.UserID, "bob@example.com", owner.UserID), ErrorForbidden)
+	_, err = members.ListMembers(owner.TenantID, "stranger")
+	expectCode(t, err, ErrorForbidden)
+}
+
+func TestMembershipTransferAndSwitch(t *testing.T) {
+	auth, members, store := newTestMemberships(t)
+	owner := register(t, auth, "owner@example.com", "Acme")
+	bob := register(t, auth, "bob@example.com", "Bob Inc")
+	join(t, members, store, owner.TenantID, "bob@example.com", TenantRoleMember)
+
+	expectCode(t, members.TransferOwnership(owner.TenantID, bob.UserID, "bob@example.com", bob.UserID), ErrorForbidden)
+	if err := members.TransferOwnership(owner.TenantID, owner.UserID, "owner@example.com", bob.UserID); err != nil {
+		t.Fatalf("TransferOwnership: %v", err)
+	}
+	if role, _ := members.Role(owner.TenantID, bob.UserID); role != TenantRoleOwner {
+		t.Fatalf("new owner role = %q", role)
+	}
+	if role, _ := members.Role(owner.TenantID, owner.UserID); role != TenantRoleAdmin {
+		t.Fatalf("previous owner must become admin, got %q", role)
+	}
+
+	bobUser := store.users["bob@example.com"]
+	if list, _ := members.ListTenants(bob.UserID); len(list) != 2 {
+		t.Fatalf("bob must belong to two tenants: %+v", list)
+	}
+	m, err := members.Switch(bobUser, owner.TenantID)
+	if err != nil || m.TenantName != "Acme" || bobUser.TenantID != owner.TenantID {
+		t.Fatalf("Switch = %+v, %v (home %s)", m, err, bobUser.TenantID)
+	}
+	if _, err := members.Switch(bobUser, "t-unknown"); err == nil {
+		t.Fatalf("switching into a foreign tenant must fail")
+	}
+	res, err := auth.Login("bob@example.com", "Secret123", SessionClient{})
+	if err != nil || res.TenantID != owner.TenantID {
+		t.Fatalf("sign-in must start in the switched tenant: %+v %v", res, err)
+	}
+}
+
+func TestMembershipRemoveRehomes(t *testing.T) {
+	auth, members, store := newTestMemberships(t)
+	owner := register(t, auth, "owner@example.com", "Acme")
+	res, err := auth.RegisterWithTenant("carol@example.com", "Secret123", owner.TenantID, "admin", SessionClient{})
+	if err != nil {
+		t.Fatalf("RegisterWithTenant: %v", err)
+	}
+	if role, _ := members.Role(owner.TenantID, res.UserID); role != TenantRoleAdmin {
+		t.Fatalf("invited role = %q", role)
+	}
+	if err := members.Remove(owner.TenantID, owner.UserID, "owner@example.com", res.UserID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if len(store.removedCollabs) != 1 || store.removedCollabs[0] != owner.TenantID+"/"+res.UserID {
+		t.Fatalf("removal must end the member's collaborations: %v", store.removedCollabs)
+	}
+	carol := store.users["carol@example.com"]
+	if carol.TenantID == owner.TenantID || carol.TenantID == "" {
+		t.Fatalf("removed user must be moved to another tenant, got %q", carol.TenantID)
+	}
+	if role, _ := members.Role(carol.TenantID, carol.ID); role != TenantRoleOwner {
+		t.Fatalf("a user without tenants gets a personal one, role %q", role)
+	}
+	login, err := auth.Login("carol@example.com", "Secret123", SessionClient{})
+	if err != nil || login.TenantID != carol.TenantID {
+		t.Fatalf("Login after removal = %+v, %v", login, err)
+	}
+	expectCode(t, members.Remove(owner.TenantID, owner.UserID, "owner@example.com", res.UserID), ErrorNotFound)
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpPeriod         = 30
+	totpDigits         = 6
+	totpSkew           = 1 // accept codes of the previous and next 30 s step
+	recoveryCodeCount  = 10
+	stepUpWindow       = 10 * time.Minute
+	mfaIssuer          = "Synap"
+	mfaSecretBytes     = 20
+	recoveryCodeLength = 10
+
+	// Wrong codes of an enabled enrollment are counted per account like failed sign-ins; at
+	// mfaLockFailures the account cannot verify codes for loginLockDuration.
+	mfaLockFailures = 5
+	mfaKeyPrefix    = "mfa:"
+)
+
+// MFAStore persists TOTP enrollments; recovery codes are stored as SHA-256 hashes. Failed
+// verifications share the sign-in throttle table under "mfa:<user id>" keys.
+type MFAStore interface {
+	GetMFA(userID string) (*MFAConfig, error)
+	SaveMFA(m *MFAConfig) error
+	DeleteMFA(userID string) error
+	GetLoginThrottle(key string) (*LoginThrottle, error)
+	SaveLoginThrottle(t *LoginThrottle) error
+	DeleteLoginThrottle(key string) (bool, error)
+	AddAudit(entry AuditEntry)
+}
+
+// MFAConfig is a user's TOTP enrollment. It only counts once Enabled, after a first code confirmed it.
+type MFAConfig struct {
+	UserID         string
+	Secret         string // base32, as shown to the authenticator app
+	Enabled        bool
+	RecoveryHashes []string
+	LastStep       int64 // last accepted TOTP time step; codes cannot be replayed
+	CreatedAt      time.Time
+	EnabledAt      time.Time
+}
+
+// MFAEnrollment is shown once to set up an authenticator app.
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// MFAStatus describes a user's enrollment.
+type MFAStatus struct {
+	Enabled           bool      `json:"enabled"`
+	Pending           bool      `json:"pending"`
+	RecoveryCodesLeft int       `json:"recovery_codes_left"`
+	EnabledAt         time.Time `json:"enabled_at,omitempty"`
+}
+
+// StepUpResult is an access token carrying the step-up claim.
+type StepUpResult struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Method    string    `json:"method"` // totp|recovery
+}
+
+// StepUpSigner signs an access token for session jti that carries the step-up claim.
+type StepUpSigner func(uid, tid, email, jti string, ttl time.Duration) (string, error)
+
+type MFAService struct {
+	store  MFAStore
+	signer StepUpSigner
+	now    func() time.Time
+}
+
+func NewMFAService(store MFAStore, signer StepUpSigner) *MFAService {
+	return &MFAService{store: store, signer: signer, now: time.Now}
+}
+
+// StepUpWindow is how long a step-up claim stays valid.
+func (s *MFAService) StepUpWindow() time.Duration { return stepUpWindow }
+
+func (s *MFAService) Status(userID string) (*MFAStatus, error) {
+	m, err := s.store.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return &MFAStatus{}, nil
+	}
+	return &MFAStatus{Enabled: m.Enabled, Pending: !m.Enabled, RecoveryCodesLeft: len(m.RecoveryHashes), EnabledAt: m.EnabledAt}, nil
+}
+
+// Enroll starts (or restarts) a TOTP enrollment. An enabled enrollment must be disabled first.
+func (s *MFAService) Enroll(userID, email string) (*MFAEnrollment, error) {
+	if userID == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	m, err := s.store.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil && m.Enabled {
+		return nil, NewConflictError("two-factor authentication already enabled")
+	}
+	raw := make([]byte, mfaSecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+	if err := s.store.SaveMFA(&MFAConfig{UserID: userID, Secret: secret, CreatedAt: s.now().UTC()}); err != nil {
+		return nil, err
+	}
+	label := url.PathEscape(mfaIssuer + ":" + email)
+	q := url.Values{"secret": {secret}, "issuer": {mfaIssuer}, "period": {fmt.Sprint(totpPeriod)}, "digits": {fmt.Sprint(totpDigits)}}
+	return &MFAEnrollment{Secret: secret, OTPAuthURL: "otpauth://totp/" + label + "?" + q.Encode()}, nil
+}
+
+// Confirm enables a pending enrollment with a first code and returns the recovery codes (shown once).
+func (s *MFAService) Confirm(userID, tenantID, email, code string) ([]string, error) {
+	m, err := s.store.GetMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil || m.Enabled {
+		return nil, NewInvalidError("no pending enrollment")
+	}
+	if !s.checkTOTP(m, code) {
+		return nil, NewUnauthorizedError("invalid code")
+	}
+	codes, err := s.newRecoveryCodes(m)
+	if err != nil {
+		return nil, err
+	}
+	m.Enabled, m.EnabledAt = true, s.now().UTC()
+	if err := s.store.SaveMFA(m); err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: m.EnabledAt, TenantID: tenantID, Actor: email, Action: "mfa_enable", Target: userID})
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a current code.
+func (s *MFAService) RegenerateRecoveryCodes(userID, tenantID, email, code string) ([]string, error) {
+	m, method, err := s.verify(userID, tenantID, email, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.newRecoveryCodes(m)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveMFA(m); err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: email, Action: "mfa_recovery_codes", Target: userID, Note: method})
+	return codes, nil
+}
+
+// Disable removes the enrollment after verifying a current code.
+func (s *MFAService) Disable(userID, tenantID, email, code string) error {
+	_, method, err := s.verify(userID, tenantID, email, code)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteMFA(userID); err != nil {
+		return err
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: email, Action: "mfa_disable", Target: userID, Note: method})
+	return nil
+}
+
+// StepUp verifies a TOTP or recovery code and issues a short-lived access token for the same
+// session that carries the step-up claim.
+func (s *MFAService) StepUp(userID, tenantID, email, sessionID, code string) (*StepUpResult, error) {
+	if sessionID == "" {
+		return nil, NewForbiddenError("step-up requires a signed-in session")
+	}
+	m, method, err := s.verify(userID, tenantID, email, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SaveMFA(m); err != nil {
+		return nil, err
+	}
+	if s.signer == nil {
+		return nil, NewInvalidError("token signer not configured")
+	}
+	token, err := s.signer(userID, tenantID, email, sessionID, stepUpWindow)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: tenantID, Actor: email, Action: "step_up", Target: userID, Note: method})
+	return &StepUpResult{Token: token, ExpiresAt: now.Add(stepUpWindow), Method: method}, nil
+}
+
+// verify checks a TOTP code or consumes a recovery code. The caller saves the returned config.
+// Wrong codes are audited and counted; a locked account is refused before the code is checked.
+func (s *MFAService) verify(userID, tenantID, email, code string) (*MFAConfig, string, error) {
+	m, err := s.store.GetMFA(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if m == nil || !m.Enabled {
+		return nil, "", NewForbiddenError("two-factor authentication not enabled")
+	}
+	now := s.now().UTC()
+	key := mfaKeyPrefix + userID
+	t, err := s.store.GetLoginThrottle(key)
+	if err != nil {
+		return nil, "", err
+	}
+	if t != nil && now.Before(t.LockedUntil) {
+		wait := t.LockedUntil.Sub(now)
+		return nil, "", NewRetryLaterError(fmt.Sprintf("too many invalid codes; try again in %s", wait.Round(time.Second)), wait)
+	}
+	code = strings.TrimSpace(code)
+	method := ""
+	if s.checkTOTP(m, code) {
+		method = "totp"
+	} else {
+		hash := HashAPIToken(normalizeRecoveryCode(code))
+		for i, h := range m.RecoveryHashes {
+			if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+				m.RecoveryHashes = append(m.RecoveryHashes[:i], m.RecoveryHashes[i+1:]...)
+				method = "recovery"
+				break
+			}
+		}
+	}
+	if method != "" {
+		if t != nil {
+			if _, err := s.store.DeleteLoginThrottle(key); err != nil {
+				return nil, "", err
+			}
+		}
+		return m, method, nil
+	}
+	if err := s.failure(t, key, userID, tenantID, email, now); err != nil {
+		return nil, "", err
+	}
+	return nil, "", NewUnauthorizedError("invalid code")
+}
+
+// failure audits a wrong code and locks the account at mfaLockFailures.
+func (s *MFAService) failure(t *LoginThrottle, key, userID, tenantID, email string, now time.Time) error {
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: tenantID, Actor: email, ActorID: userID, Action: "mfa_failed", Target: userID,
+		Result: AuditResultFailure})
+	if t == nil || (now.Sub(t.LastFailureAt) > loginFailureWindow && !now.Before(t.LockedUntil)) {
+		t = &LoginThrottle{Key: key}
+	}
+	t.TenantID = tenantID
+	t.Failures++
+	t.LastFailureAt = now
+	if t.Failures >= mfaLockFailures {
+		t.LockedUntil = now.Add(loginLockDuration)
+		if t.Failures == mfaLockFailures {
+			s.store.AddAudit(AuditEntry{Time: now, TenantID: tenantID, Actor: email, ActorID: userID, Action: "mfa_locked", Target: userID,
+				Note: fmt.Sprintf("%d invalid codes", t.Failures), Result: AuditResultDenied})
+		}
+	}
+	return s.store.SaveLoginThrottle(t)
+}
+
+// checkTOTP accepts a code of the current step ± totpSkew that is newer than the last accepted one.
+func (s *MFAService) checkTOTP(m *MFAConfig, code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(m.Secret)
+	if err != nil {
+		return false
+	}
+	step := s.now().Unix() / totpPeriod
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		if step+d <= m.LastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(TOTPCode(key, step+d, totpDigits)), []byte(code)) == 1 {
+			m.LastStep = step + d
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MFAService) newRecoveryCodes(m *MFAConfig) ([]string, error) {
+	const alphabet = "abcdefghijkmnpqrstuvwxyz23456789" // 32 symbols without l/o/0/1
+	codes := make([]string, 0, recoveryCodeCount)
+	m.RecoveryHashes = make([]string, 0, recoveryCodeCount)
+	buf := make([]byte, recoveryCodeLength)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for j, c := range buf {
+			if j == recoveryCodeLength/2 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[c%32])
+		}
+		codes = append(codes, b.String())
+		m.RecoveryHashes = append(m.RecoveryHashes, HashAPIToken(normalizeRecoveryCode(b.String())))
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// TOTPCode computes the RFC 6238 code (HMAC-SHA1) for a time step.
+func TOTPCode(key []byte, step int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, v%mod)
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubMFAStore struct {
+	configs   map[string]*MFAConfig
+	throttles map[string]*LoginThrottle
+	audit     []AuditEntry
+}
+
+func (s *stubMFAStore) GetMFA(userID string) (*MFAConfig, error) {
+	if m, ok := s.configs[userID]; ok {
+		cp := *m
+		cp.RecoveryHashes = append([]string(nil), m.RecoveryHashes...)
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubMFAStore) SaveMFA(m *MFAConfig) error {
+	cp := *m
+	cp.RecoveryHashes = append([]string(nil), m.RecoveryHashes...)
+	s.configs[m.UserID] = &cp
+	return nil
+}
+
+func (s *stubMFAStore) DeleteMFA(userID string) error {
+	delete(s.configs, userID)
+	return nil
+}
+
+func (s *stubMFAStore) GetLoginThrottle(key string) (*LoginThrottle, error) {
+	if t, ok := s.throttles[key]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubMFAStore) SaveLoginThrottle(t *LoginThrottle) error {
+	if s.throttles == nil {
+		s.throttles = map[string]*LoginThrottle{}
+	}
+	cp := *t
+	s.throttles[t.Key] = &cp
+	return nil
+}
+
+func (s *stubMFAStore) DeleteLoginThrottle(key string) (bool, error) {
+	_, ok := s.throttles[key]
+	delete(s.throttles, key)
+	return ok, nil
+}
+
+func (s *stubMFAStore) AddAudit(entry AuditEntry) { s.audit = append(s.audit, entry) }
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := map[int64]string{59: "94287082", 1111111109: "07081804", 1234567890: "89005924", 2000000000: "69279037"}
+	for ts, want := range cases {
+		if got := TOTPCode(key, ts/30, 8); got != want {
+			t.Fatalf("T=%d: got %s want %s", ts, got, want)
+		}
+	}
+}
+
+func currentCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	return TOTPCode(key, at.Unix()/30, 6)
+}
+
+func TestMFAEnrollAndStepUp(t *testing.T) {
+	store := &stubMFAStore{configs: map[string]*MFAConfig{}}
+	var signedTTL time.Duration
+	svc := NewMFAService(store, func(uid, tid, email, jti string, ttl time.Duration) (string, error) {
+		signedTTL = ttl
+		return "stepup:" + uid + ":" + jti, nil
+	})
+	now := time.Date(2025, 9, 23, 10, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", "123456"); err == nil {
+		t.Fatalf("step-up must fail without an enrollment")
+	}
+	enr, err := svc.Enroll("U1", "a@b")
+	if err != nil || !strings.HasPrefix(enr.OTPAuthURL, "otpauth://totp/Synap:a@b?") || !strings.Contains(enr.OTPAuthURL, "secret="+enr.Secret) {
+		t.Fatalf("Enroll: %+v %v", enr, err)
+	}
+	if st, _ := svc.Status("U1"); st.Enabled || !st.Pending {
+		t.Fatalf("enrollment must stay pending until confirmed: %+v", st)
+	}
+	if _, err := svc.Confirm("U1", "T1", "a@b", "000000"); err == nil {
+		t.Fatalf("wrong code must not confirm")
+	}
+	codes, err := svc.Confirm("U1", "T1", "a@b", currentCode(t, enr.Secret, now))
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("Confirm: %d codes, %v", len(codes), err)
+	}
+	if _, err := svc.Enroll("U1", "a@b"); err == nil {
+		t.Fatalf("re-enrolling an enabled user must fail")
+	}
+
+	// the confirmation code cannot be replayed; the next step's code works once
+	if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", currentCode(t, enr.Secret, now)); err == nil {
+		t.Fatalf("replayed code must be rejected")
+	}
+	now = now.Add(30 * time.Second)
+	if _, err := svc.StepUp("U1", "T1", "a@b", "", currentCode(t, enr.Secret, now)); err == nil {
+		t.Fatalf("step-up needs a session")
+	}
+	res, err := svc.StepUp("U1", "T1", "a@b", "ses1", currentCode(t, enr.Secret, now))
+	if err != nil || res.Token != "stepup:U1:ses1" || res.Method != "totp" || signedTTL != stepUpWindow || !res.ExpiresAt.Equal(now.Add(stepUpWindow)) {
+		t.Fatalf("StepUp: %+v %v", res, err)
+	}
+
+	// recovery codes work once, with or without the dash
+	code := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+	if res, err := svc.StepUp("U1", "T1", "a@b", "ses1", code); err != nil || res.Method != "recovery" {
+		t.Fatalf("recovery step-up: %+v %v", res, err)
+	}
+	if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", codes[0]); err == nil {
+		t.Fatalf("recovery code must be single-use")
+	}
+	if st, _ := svc.Status("U1"); st.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	// the reused code was audited as a failure after the recovery step-up
+	n := len(store.audit)
+	if last := store.audit[n-2]; last.Action != "step_up" || last.Note != "recovery" || last.TenantID != "T1" {
+		t.Fatalf("unexpected audit %+v", last)
+	}
+	if failed := store.audit[n-1]; failed.Action != "mfa_failed" || failed.Result != AuditResultFailure || failed.TenantID != "T1" {
+		t.Fatalf("failure not audited: %+v", failed)
+	}
+
+	if err := svc.Disable("U1", "T1", "a@b", "999999"); err == nil {
+		t.Fatalf("disable needs a valid code")
+	}
+	if err := svc.Disable("U1", "T1", "a@b", codes[1]); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if st, _ := svc.Status("U1"); st.Enabled || st.Pending {
+		t.Fatalf("enrollment must be removed: %+v", st)
+	}
+}
+
+func TestMFALocksAfterRepeatedInvalidCodes(t *testing.T) {
+	store := &stubMFAStore{configs: map[string]*MFAConfig{}}
+	svc := NewMFAService(store, func(uid, tid, email, jti string, ttl time.Duration) (string, error) { return "stepup", nil })
+	now := time.Date(2025, 9, 23, 10, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	enr, err := svc.Enroll("U1", "a@b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Confirm("U1", "T1", "a@b", currentCode(t, enr.Secret, now)); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(30 * time.Second)
+
+	// a success clears the counter, so only consecutive failures lock
+	for i := 0; i < mfaLockFailures-1; i++ {
+		if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", "000000"); err == nil {
+			t.Fatal("wrong code accepted")
+		}
+	}
+	if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", currentCode(t, enr.Secret, now)); err != nil {
+		t.Fatalf("valid code before the lock: %v", err)
+	}
+	if len(store.throttles) != 0 {
+		t.Fatalf("counter kept after success: %+v", store.throttles)
+	}
+	for i := 0; i < mfaLockFailures; i++ {
+		if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", "000000"); err == nil {
+			t.Fatal("wrong code accepted")
+		}
+	}
+
+	// the next valid code is refused while locked, also through disable and recovery-code rotation
+	now = now.Add(30 * time.Second)
+	code := currentCode(t, enr.Secret, now)
+	_, err = svc.StepUp("U1", "T1", "a@b", "ses1", code)
+	if se, ok := err.(*ServiceError); !ok || se.Code != ErrorTooManyRequests {
+		t.Fatalf("expected lockout, got %v", err)
+	}
+	if err := svc.Disable("U1", "T1", "a@b", code); err == nil {
+		t.Fatal("disable while locked")
+	}
+	if _, err := svc.RegenerateRecoveryCodes("U1", "T1", "a@b", code); err == nil {
+		t.Fatal("regenerate while locked")
+	}
+	locked := 0
+	for _, e := range store.audit {
+		if e.Action == "mfa_locked" {
+			locked++
+		}
+	}
+	if locked != 1 {
+		t.Fatalf("expected one mfa_locked entry, got %d", locked)
+	}
+
+	now = now.Add(loginLockDuration + time.Second)
+	if _, err := svc.StepUp("U1", "T1", "a@b", "ses1", currentCode(t, enr.Secret, now)); err != nil {
+		t.Fatalf("after the lock: %v", err)
+	}
+}
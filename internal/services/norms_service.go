@@ -0,0 +1,350 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var normKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,39}$`)
+
+// NormStore persists the norm tables of a scale.
+type NormStore interface {
+	GetScale(id string) (*Scale, error)
+	ListItems(scaleID string) ([]*Item, error)
+	ListResponsesByScale(scaleID string) ([]*Response, error)
+	ListNormTables(scaleID string) ([]*NormTable, error)
+	SaveNormTable(t *NormTable) error
+	DeleteNormTable(scaleID, key string) (bool, error)
+}
+
+// NormCondition restricts a stratum by the participant's answer to an item: Equals lists accepted
+// choice answers (English labels, case-insensitive); Min/Max bound numeric answers inclusively.
+type NormCondition struct {
+	ItemID string   `json:"item_id"`
+	Equals []string `json:"equals,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+// NormPoint maps a raw total to its percentile rank in the norm sample.
+type NormPoint struct {
+	Raw        float64 `json:"raw"`
+	Percentile float64 `json:"percentile"`
+}
+
+// NormStratum holds the reference distribution of one group (e.g. women aged 18-29). A stratum
+// without conditions is the fallback. Without Percentiles the rank is read from the normal curve.
+type NormStratum struct {
+	Label       string          `json:"label"`
+	Conditions  []NormCondition `json:"conditions,omitempty"`
+	N           int             `json:"n,omitempty"`
+	Mean        float64         `json:"mean"`
+	SD          float64         `json:"sd"`
+	Percentiles []NormPoint     `json:"percentiles,omitempty"`
+}
+
+// NormTable standardises the raw total of a scale or subscale (ItemIDs; empty means the score
+// export total). Strata are matched in order and the first match wins.
+type NormTable struct {
+	ScaleID   string        `json:"scale_id"`
+	Key       string        `json:"key"`
+	Name      string        `json:"name,omitempty"`
+	Source    string        `json:"source,omitempty"`
+	ItemIDs   []string      `json:"item_ids,omitempty"`
+	Strata    []NormStratum `json:"strata"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// NormScore is one participant's standing on one norm table.
+type NormScore struct {
+	Key        string  `json:"key"`
+	Name       string  `json:"name,omitempty"`
+	Stratum    string  `json:"stratum"`
+	Raw        float64 `json:"raw"`
+	Z          float64 `json:"z"`
+	T          float64 `json:"t"`
+	Percentile float64 `json:"percentile"`
+}
+
+// NormService manages norm tables and converts raw totals into standardized scores.
+type NormService struct {
+	store NormStore
+	now   func() time.Time
+}
+
+func NewNormService(store NormStore) *NormService {
+	return &NormService{store: store, now: time.Now}
+}
+
+func (s *NormService) ListTables(tenantID, scaleID string) ([]*NormTable, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	return s.store.ListNormTables(scaleID)
+}
+
+// SaveTable validates and stores (or replaces) the table with the same key.
+func (s *NormService) SaveTable(tenantID, scaleID string, t *NormTable) (*NormTable, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, NewInvalidError("norm table required")
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNormTable(t, items); err != nil {
+		return nil, err
+	}
+	t.ScaleID = scaleID
+	t.UpdatedAt = s.now().UTC()
+	if err := s.store.SaveNormTable(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *NormService) DeleteTable(tenantID, scaleID, key string) error {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return err
+	}
+	ok, err := s.store.DeleteNormTable(scaleID, key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewNotFoundError("norm table not found")
+	}
+	return nil
+}
+
+// ScoreParticipants returns the standardized scores of every participant of the scale.
+func (s *NormService) ScoreParticipants(tenantID, scaleID string) (map[string][]NormScore, error) {
+	if _, err := s.authorizedScale(tenantID, scaleID); err != nil {
+		return nil, err
+	}
+	tables, err := s.store.ListNormTables(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.store.ListResponsesByScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyNorms(tables, items, responses), nil
+}
+
+// ScoreResponses standardizes one participant's responses against the scale's norm tables. It does
+// no tenant check and is meant for participant-facing feedback on their own submission.
+func (s *NormService) ScoreResponses(scaleID string, responses []*Response) ([]NormScore, error) {
+	tables, err := s.store.ListNormTables(scaleID)
+	if err != nil || len(tables) == 0 {
+		return nil, err
+	}
+	items, err := s.store.ListItems(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	return NormScoresFor(tables, items, responses), nil
+}
+
+func (s *NormService) authorizedScale(tenantID, scaleID string) (*Scale, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil || sc.TenantID != tenantID {
+		return nil, NewForbiddenError("forbidden")
+	}
+	return sc, nil
+}
+
+func validateNormTable(t *NormTable, items []*Item) error {
+	t.Key = strings.ToLower(strings.TrimSpace(t.Key))
+	if !normKeyPattern.MatchString(t.Key) {
+		return NewInvalidError("key must be 1-40 lowercase letters, digits, '-' or '_'")
+	}
+	byID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	for _, id := range t.ItemIDs {
+		if byID[id] == nil {
+			return NewInvalidError("unknown item " + id)
+		}
+	}
+	if len(t.Strata) == 0 {
+		return NewInvalidError("at least one stratum required")
+	}
+	for i := range t.Strata {
+		st := &t.Strata[i]
+		if strings.TrimSpace(st.Label) == "" {
+			return NewInvalidError("every stratum needs a label")
+		}
+		if st.SD <= 0 || math.IsNaN(st.Mean) || math.IsInf(st.Mean, 0) {
+			return NewInvalidError("stratum " + st.Label + ": sd must be positive and mean finite")
+		}
+		for _, c := range st.Conditions {
+			if byID[c.ItemID] == nil {
+				return NewInvalidError("stratum " + st.Label + ": unknown item " + c.ItemID)
+			}
+			if len(c.Equals) == 0 && c.Min == nil && c.Max == nil {
+				return NewInvalidError("stratum " + st.Label + ": condition needs equals, min or max")
+			}
+		}
+		sort.Slice(st.Percentiles, func(a, b int) bool { return st.Percentiles[a].Raw < st.Percentiles[b].Raw })
+		for j, p := range st.Percentiles {
+			if p.Percentile < 0 || p.Percentile > 100 || (j > 0 && p.Percentile < st.Percentiles[j-1].Percentile) {
+				return NewInvalidError("stratum " + st.Label + ": percentiles must be within 0-100 and non-decreasing")
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyNorms standardizes every participant's raw totals; participants without a matching stratum
+// or without any answer to the table's items are left out of that table.
+func ApplyNorms(tables []*NormTable, items []*Item, responses []*Response) map[string][]NormScore {
+	out := map[string][]NormScore{}
+	if len(tables) == 0 {
+		return out
+	}
+	byParticipant := map[string][]*Response{}
+	for _, r := range responses {
+		byParticipant[r.ParticipantID] = append(byParticipant[r.ParticipantID], r)
+	}
+	for pid, rs := range byParticipant {
+		if scores := NormScoresFor(tables, items, rs); len(scores) > 0 {
+			out[pid] = scores
+		}
+	}
+	return out
+}
+
+// NormScoresFor standardizes the responses of a single participant against each table.
+func NormScoresFor(tables []*NormTable, items []*Item, responses []*Response) []NormScore {
+	byID := make(map[string]*Item, len(items))
+	for _, it := range items {
+		byID[it.ID] = it
+	}
+	answers := make(map[string]*Response, len(responses))
+	for _, r := range responses {
+		answers[r.ItemID] = r
+	}
+	var out []NormScore
+	for _, t := range tables {
+		raw, ok := normRawTotal(t, answers)
+		if !ok {
+			continue
+		}
+		st := matchStratum(t.Strata, byID, answers)
+		if st == nil {
+			continue
+		}
+		z := (raw - st.Mean) / st.SD
+		out = append(out, NormScore{
+			Key:        t.Key,
+			Name:       t.Name,
+			Stratum:    st.Label,
+			Raw:        raw,
+			Z:          roundTo(z, 4),
+			T:          roundTo(50+10*z, 2),
+			Percentile: roundTo(normPercentile(st, raw, z), 2),
+		})
+	}
+	return out
+}
+
+func normRawTotal(t *NormTable, answers map[string]*Response) (float64, bool) {
+	var sum float64
+	answered := false
+	if len(t.ItemIDs) == 0 {
+		// same total as the score export
+		for _, r := range answers {
+			sum += float64(r.ScoreValue)
+			answered = true
+		}
+		return sum, answered
+	}
+	for _, id := range t.ItemIDs {
+		if r, ok := answers[id]; ok {
+			sum += float64(r.ScoreValue)
+			answered = true
+		}
+	}
+	return sum, answered
+}
+
+func matchStratum(strata []NormStratum, items map[string]*Item, answers map[string]*Response) *NormStratum {
+	for i := range strata {
+		matched := true
+		for _, c := range strata[i].Conditions {
+			if !normConditionHolds(c, items[c.ItemID], answers[c.ItemID]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &strata[i]
+		}
+	}
+	return nil
+}
+
+func normConditionHolds(c NormCondition, item *Item, resp *Response) bool {
+	if item == nil || resp == nil {
+		return false
+	}
+	if len(c.Equals) > 0 {
+		found := false
+		for _, v := range decodeChoiceValues(resp.RawJSON) {
+			for _, want := range c.Equals {
+				if strings.EqualFold(strings.TrimSpace(want), v) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.Min != nil || c.Max != nil {
+		itemType := item.Type
+		if itemType == "" {
+			itemType = "likert"
+		}
+		v, ok := scoredNumericValue(itemType, resp, math.MaxInt32)
+		if !ok || (c.Min != nil && v < *c.Min) || (c.Max != nil && v > *c.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// normPercentile interpolates the stratum's percentile table (clamped at its ends) or falls back to
+// the normal distribution.
+func normPercentile(st *NormStratum, raw, z float64) float64 {
+	pts := st.Percentiles
+	if len(pts) == 0 {
+		return 50 * math.Erfc(-z/math.Sqrt2)
+	}
+	if raw <= pts[0].Raw {
+		return pts[0].Percentile
+	}
+	for i := 1; i < len(pts); i++ {
+		if raw <= pts[i].Raw {
+			lo, hi := pts[i-1], pts[i]
+			return lo.Percentile + (raw-lo.Raw)/(hi.Raw-lo.Raw)*(hi.Percentile-lo.Percentile)
+		}
+	}
+	return pts[len(pts)-1].Percentile
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/csv"
+	"math"
+	"strings"
+	"testing"
+)
+
+type stubNormStore struct {
+	stubAnalyticsStore
+	tables []*NormTable
+}
+
+func (s *stubNormStore) ListNormTables(scaleID string) ([]*NormTable, error) {
+	out := []*NormTable{}
+	for _, t := range s.tables {
+		if t.ScaleID == scaleID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubNormStore) SaveNormTable(t *NormTable) error {
+	for i, existing := range s.tables {
+		if existing.ScaleID == t.ScaleID && existing.Key == t.Key {
+			s.tables[i] = t
+			return nil
+		}
+	}
+	s.tables = append(s.tables, t)
+	return nil
+}
+
+func (s *stubNormStore) DeleteNormTable(scaleID, key string) (bool, error) {
+	for i, t := range s.tables {
+		if t.ScaleID == scaleID && t.Key == key {
+			s.tables = append(s.tables[:i], s.tables[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func newNormFixture() *stubNormStore {
+	items := []*Item{
+		{ID: "q1", ScaleID: "S1", Type: "likert"},
+		{ID: "q2", ScaleID: "S1", Type: "likert"},
+		{ID: "gender", ScaleID: "S1", Type: "single"},
+		{ID: "age", ScaleID: "S1", Type: "numeric"},
+	}
+	answers := func(pid string, q1, q2 int, gender string, age int) []*Response {
+		return []*Response{
+			{ParticipantID: pid, ItemID: "q1", RawValue: q1, ScoreValue: q1},
+			{ParticipantID: pid, ItemID: "q2", RawValue: q2, ScoreValue: q2},
+			{ParticipantID: pid, ItemID: "gender", RawJSON: `"` + gender + `"`},
+			{ParticipantID: pid, ItemID: "age", RawValue: age, ScoreValue: age, RawJSON: itoa(age)},
+		}
+	}
+	var responses []*Response
+	responses = append(responses, answers("p1", 4, 4, "Female", 25)...)
+	responses = append(responses, answers("p2", 2, 3, "Male", 40)...)
+	responses = append(responses, answers("p3", 5, 5, "Other", 70)...)
+	return &stubNormStore{stubAnalyticsStore: stubAnalyticsStore{
+		scale:     &Scale{ID: "S1", TenantID: "T1", Points: 5},
+		items:     items,
+		responses: responses,
+	}}
+}
+
+func TestNormServiceValidatesTables(t *testing.T) {
+	svc := NewNormService(newNormFixture())
+	valid := NormTable{Key: "Total", ItemIDs: []string{"q1", "q2"}, Strata: []NormStratum{{Label: "all", Mean: 6, SD: 2}}}
+	if _, err := svc.SaveTable("T2", "S1", &valid); err == nil {
+		t.Fatalf("expected forbidden for another tenant")
+	}
+	cases := map[string]NormTable{
+		"bad key":       {Key: "a b", Strata: valid.Strata},
+		"unknown item":  {Key: "x", ItemIDs: []string{"nope"}, Strata: valid.Strata},
+		"no strata":     {Key: "x"},
+		"zero sd":       {Key: "x", Strata: []NormStratum{{Label: "all", Mean: 6}}},
+		"empty cond":    {Key: "x", Strata: []NormStratum{{Label: "all", SD: 1, Conditions: []NormCondition{{ItemID: "age"}}}}},
+		"bad pct order": {Key: "x", Strata: []NormStratum{{Label: "all", SD: 1, Percentiles: []NormPoint{{Raw: 2, Percentile: 10}, {Raw: 4, Percentile: 5}}}}},
+	}
+	for name, tbl := range cases {
+		tbl := tbl
+		if _, err := svc.SaveTable("T1", "S1", &tbl); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+	saved, err := svc.SaveTable("T1", "S1", &valid)
+	if err != nil {
+		t.Fatalf("SaveTable: %v", err)
+	}
+	if saved.Key != "total" || saved.ScaleID != "S1" || saved.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected saved table %+v", saved)
+	}
+	if err := svc.DeleteTable("T1", "S1", "missing"); err == nil {
+		t.Fatalf("expected not found for an unknown key")
+	}
+	if err := svc.DeleteTable("T1", "S1", "total"); err != nil {
+		t.Fatalf("DeleteTable: %v", err)
+	}
+}
+
+func TestApplyNormsStratifiesAndStandardizes(t *testing.T) {
+	store := newNormFixture()
+	svc := NewNormService(store)
+	table := &NormTable{
+		Key:     "sum",
+		ItemIDs: []string{"q1", "q2"},
+		Strata: []NormStratum{
+			{
+				Label:       "women 18-29",
+				Conditions:  []NormCondition{{ItemID: "gender", Equals: []string{"female"}}, {ItemID: "age", Min: floatPtr(18), Max: floatPtr(29)}},
+				Mean:        6,
+				SD:          2,
+				Percentiles: []NormPoint{{Raw: 10, Percentile: 99}, {Raw: 2, Percentile: 1}, {Raw: 6, Percentile: 50}},
+			},
+			{Label: "men", Conditions: []NormCondition{{ItemID: "gender", Equals: []string{"Male"}}}, Mean: 7, SD: 1},
+		},
+	}
+	if _, err := svc.SaveTable("T1", "S1", table); err != nil {
+		t.Fatalf("SaveTable: %v", err)
+	}
+	scores, err := svc.ScoreParticipants("T1", "S1")
+	if err != nil {
+		t.Fatalf("ScoreParticipants: %v", err)
+	}
+	if _, ok := scores["p3"]; ok {
+		t.Fatalf("p3 matches no stratum and should not be scored")
+	}
+	p1 := scores["p1"][0]
+	// raw 8 in the interpolated table between 6 (50) and 10 (99)
+	if p1.Stratum != "women 18-29" || p1.Raw != 8 || p1.Z != 1 || p1.T != 60 || p1.Percentile != 74.5 {
+		t.Fatalf("unexpected p1 norm score %+v", p1)
+	}
+	p2 := scores["p2"][0]
+	// without a percentile table the normal curve is used: z = -2
+	if p2.Stratum != "men" || p2.Z != -2 || p2.T != 30 || math.Abs(p2.Percentile-2.28) > 0.01 {
+		t.Fatalf("unexpected p2 norm score %+v", p2)
+	}
+
+	own, err := svc.ScoreResponses("S1", store.responses[4:8])
+	if err != nil || len(own) != 1 || own[0] != p2 {
+		t.Fatalf("participant-facing scores %+v %v, want %+v", own, err, p2)
+	}
+
+	exportStore := newExportStubStore()
+	exportStore.scale = store.scale
+	exportStore.items = store.items
+	exportStore.responses = store.responses
+	exportStore.normTables = store.tables
+	res, err := NewExportService(exportStore).ExportCSV(ExportParams{TenantID: "T1", ScaleID: "S1", Format: "score"})
+	if err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(res.Data))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := "participant_id,total_score,norm.sum.raw,norm.sum.stratum,norm.sum.z,norm.sum.t,norm.sum.percentile"
+	if strings.Join(rows[0], ",") != want {
+		t.Fatalf("unexpected header %v", rows[0])
+	}
+	if strings.Join(rows[1], ",") != "p1,33,8,women 18-29,1.0000,60.00,74.50" {
+		t.Fatalf("unexpected p1 row %v", rows[1])
+	}
+	if strings.Join(rows[3][2:], "") != "" {
+		t.Fatalf("unmatched participant should have empty norm cells: %v", rows[3])
+	}
+}
@@ -0,0 +1,847 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	OIDCRoleAdmin  = "admin"
+	OIDCRoleMember = "member"
+
+	oidcLoginTTL     = 10 * time.Minute
+	oidcProviderTTL  = time.Hour
+	oidcHTTPTimeout  = 10 * time.Second
+	oidcMaxBody      = 1 << 20
+	oidcMaxPending   = 10000
+	oidcDefaultScope = "openid email profile"
+)
+
+// OIDCStore persists per-tenant OpenID Connect settings and the identities linked to users.
+type OIDCStore interface {
+	GetOIDCConfig(tenantID string) (*OIDCConfig, error)
+	ListOIDCConfigs() ([]*OIDCConfig, error)
+	SaveOIDCConfig(c *OIDCConfig) error
+	DeleteOIDCConfig(tenantID string) (bool, error)
+	GetOIDCIdentity(issuer, subject string) (*OIDCIdentity, error)
+	SaveOIDCIdentity(id *OIDCIdentity) error
+	FindUserByEmail(email string) (*User, error)
+	AddUser(u *User) error
+	SetEmailVerified(userID string, at time.Time) error
+	AddAudit(entry AuditEntry)
+}
+
+// OIDCConfig is a tenant's identity provider. Users of AllowedDomains are provisioned into the
+// tenant on their first login; an enabled provider needs at least one domain. RoleClaim values are mapped to a role through
+// RoleMap; without a match DefaultRole applies, and an empty DefaultRole refuses the login.
+type OIDCConfig struct {
+	TenantID        string            `json:"tenant_id"`
+	Enabled         bool              `json:"enabled"`
+	Issuer          string            `json:"issuer"`
+	ClientID        string            `json:"client_id"`
+	ClientSecret    string            `json:"client_secret,omitempty"`
+	HasClientSecret bool              `json:"has_client_secret"`
+	Scopes          string            `json:"scopes,omitempty"`
+	AllowedDomains  []string          `json:"allowed_domains"`
+	RoleClaim       string            `json:"role_claim,omitempty"`
+	RoleMap         map[string]string `json:"role_map,omitempty"`
+	DefaultRole     string            `json:"default_role"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// OIDCIdentity links an (issuer, subject) pair to a user.
+type OIDCIdentity struct {
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	UserID      string    `json:"user_id"`
+	TenantID    string    `json:"tenant_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// OIDCLogin is a started login: the browser is sent to URL and comes back with State.
+type OIDCLogin struct {
+	URL   string
+	State string
+}
+
+// OIDCResult is a completed login.
+type OIDCResult struct {
+	*AuthResult
+	Email       string
+	Role        string
+	Provisioned bool
+	ReturnTo    string
+}
+
+type oidcPending struct {
+	tenantID    string
+	verifier    string
+	nonce       string
+	redirectURI string
+	returnTo    string
+	linkUserID  string
+	expires     time.Time
+}
+
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	keys                  map[string]any
+	fetched               time.Time
+}
+
+// OIDCService runs the authorization code flow with PKCE (S256) against a tenant's identity
+// provider and signs the user in through the AuthService.
+type OIDCService struct {
+	store   OIDCStore
+	auth    *AuthService
+	client  *http.Client
+	local   bool
+	now     func() time.Time
+	members *MembershipService
+
+	mu        sync.Mutex
+	pending   map[string]*oidcPending
+	providers map[string]*oidcProvider
+}
+
+func NewOIDCService(store OIDCStore, auth *AuthService, client *http.Client) *OIDCService {
+	if client == nil {
+		client = NewOIDCClient(false)
+	}
+	return &OIDCService{
+		store:     store,
+		auth:      auth,
+		client:    client,
+		now:       time.Now,
+		pending:   map[string]*oidcPending{},
+		providers: map[string]*oidcProvider{},
+	}
+}
+
+// WithMemberships applies the provider's role to the user's membership in the tenant on every
+// sign-in, so OIDC role claims manage who administers the tenant.
+func (s *OIDCService) WithMemberships(members *MembershipService) {
+	s.members = members
+}
+
+// GetConfig returns the tenant's settings without the client secret.
+func (s *OIDCService) GetConfig(tenantID string) (*OIDCConfig, error) {
+	c, err := s.store.GetOIDCConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return &OIDCConfig{TenantID: tenantID, AllowedDomains: []string{}}, nil
+	}
+	return redactOIDCConfig(c), nil
+}
+
+// NewOIDCClient returns the client identity providers are reached with. Any tenant admin names the
+// issuer, and discovery names further URLs, so like webhooks it only connects to public addresses.
+// allowPrivate lifts the check for a provider on the local network in development.
+func NewOIDCClient(allowPrivate bool) *http.Client {
+	return publicOnlyClient(oidcHTTPTimeout, allowPrivate)
+}
+
+// WithLocalProviders accepts plain http issuers on localhost (development only, together with
+// NewOIDCClient(true)).
+func (s *OIDCService) WithLocalProviders() {
+	s.local = true
+}
+
+// UpdateConfig validates and stores the tenant's settings. An empty client secret keeps the stored one.
+func (s *OIDCService) UpdateConfig(tenantID, actor string, in *OIDCConfig) (*OIDCConfig, error) {
+	if tenantID == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	if in == nil {
+		return nil, NewInvalidError("config required")
+	}
+	issuer := strings.TrimRight(strings.TrimSpace(in.Issuer), "/")
+	if err := validateIssuer(issuer, s.local); err != nil {
+		return nil, err
+	}
+	clientID := strings.TrimSpace(in.ClientID)
+	if clientID == "" {
+		return nil, NewInvalidError("client_id required")
+	}
+	if in.DefaultRole != "" && !isOIDCRole(in.DefaultRole) {
+		return nil, NewInvalidError("default_role must be admin or member")
+	}
+	roleMap := map[string]string{}
+	for value, role := range in.RoleMap {
+		if !isOIDCRole(role) {
+			return nil, NewInvalidError(fmt.Sprintf("role_map %q: role must be admin or member", value))
+		}
+		roleMap[value] = role
+	}
+	if len(roleMap) > 0 && strings.TrimSpace(in.RoleClaim) == "" {
+		return nil, NewInvalidError("role_claim required with role_map")
+	}
+	domains := []string{}
+	for _, d := range in.AllowedDomains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+		if d == "" {
+			continue
+		}
+		if !strings.Contains(d, ".") || strings.ContainsAny(d, "@/ ") {
+			return nil, NewInvalidError("invalid domain: " + d)
+		}
+		if !containsString(domains, d) {
+			domains = append(domains, d)
+		}
+	}
+	if in.Enabled && len(domains) == 0 {
+		return nil, NewInvalidError("allowed_domains required to enable single sign-on")
+	}
+	if in.Enabled {
+		for _, other := range s.listEnabled() {
+			if other.TenantID == tenantID {
+				continue
+			}
+			for _, d := range domains {
+				if containsString(other.AllowedDomains, d) {
+					return nil, NewConflictError("domain already used by another tenant: " + d)
+				}
+			}
+		}
+	}
+	prev, err := s.store.GetOIDCConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	secret := in.ClientSecret
+	if secret == "" && prev != nil {
+		secret = prev.ClientSecret
+	}
+	cfg := &OIDCConfig{
+		TenantID:       tenantID,
+		Enabled:        in.Enabled,
+		Issuer:         issuer,
+		ClientID:       clientID,
+		ClientSecret:   secret,
+		Scopes:         strings.TrimSpace(in.Scopes),
+		AllowedDomains: domains,
+		RoleClaim:      strings.TrimSpace(in.RoleClaim),
+		RoleMap:        roleMap,
+		DefaultRole:    in.DefaultRole,
+		UpdatedAt:      s.now().UTC(),
+	}
+	if err := s.store.SaveOIDCConfig(cfg); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	delete(s.providers, issuer)
+	s.mu.Unlock()
+	s.store.AddAudit(AuditEntry{Time: cfg.UpdatedAt, TenantID: tenantID, Actor: actor, Action: "oidc_config_update", Target: tenantID, Note: issuer})
+	return redactOIDCConfig(cfg), nil
+}
+
+// DeleteConfig turns single sign-on off for the tenant. Linked identities are kept.
+func (s *OIDCService) DeleteConfig(tenantID, actor string) error {
+	ok, err := s.store.DeleteOIDCConfig(tenantID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewNotFoundError("oidc not configured")
+	}
+	s.store.AddAudit(AuditEntry{Time: s.now().UTC(), TenantID: tenantID, Actor: actor, Action: "oidc_config_delete", Target: tenantID})
+	return nil
+}
+
+// Discover returns the tenant whose enabled provider accepts the email's domain.
+func (s *OIDCService) Discover(email string) (string, error) {
+	domain := emailDomain(email)
+	if domain == "" {
+		return "", NewInvalidError("invalid email format")
+	}
+	for _, c := range s.listEnabled() {
+		if containsString(c.AllowedDomains, domain) {
+			return c.TenantID, nil
+		}
+	}
+	return "", NewNotFoundError("no single sign-on for this domain")
+}
+
+// Begin starts a login for the tenant. redirectURI is the callback registered at the provider;
+// returnTo is a local path the browser is sent back to afterwards.
+func (s *OIDCService) Begin(ctx context.Context, tenantID, redirectURI, returnTo string) (*OIDCLogin, error) {
+	return s.begin(ctx, tenantID, "", redirectURI, returnTo)
+}
+
+// BeginLink starts a login that links the provider's identity to the signed-in user instead of
+// signing someone in. Accounts that are not auto-linked on their first single sign-on (admins,
+// owners and members of other tenants) connect their identity this way.
+func (s *OIDCService) BeginLink(ctx context.Context, tenantID, userID, redirectURI, returnTo string) (*OIDCLogin, error) {
+	if userID == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	return s.begin(ctx, tenantID, userID, redirectURI, returnTo)
+}
+
+func (s *OIDCService) begin(ctx context.Context, tenantID, linkUserID, redirectURI, returnTo string) (*OIDCLogin, error) {
+	cfg, err := s.enabledConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.provider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") {
+		returnTo = "/admin"
+	}
+	state, nonce, verifier := randomBytes(24), randomBytes(24), randomBytes(32)
+	challenge := sha256.Sum256([]byte(verifier))
+	now := s.now()
+	s.mu.Lock()
+	for k, v := range s.pending {
+		if now.After(v.expires) {
+			delete(s.pending, k)
+		}
+	}
+	if len(s.pending) >= oidcMaxPending {
+		s.mu.Unlock()
+		return nil, NewTooManyRequestsError("too many pending sign-ins")
+	}
+	s.pending[state] = &oidcPending{tenantID: tenantID, verifier: verifier, nonce: nonce, redirectURI: redirectURI, returnTo: returnTo, linkUserID: linkUserID, expires: now.Add(oidcLoginTTL)}
+	s.mu.Unlock()
+	scopes := cfg.Scopes
+	if scopes == "" {
+		scopes = oidcDefaultScope
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return &OIDCLogin{URL: p.AuthorizationEndpoint + sep + q.Encode(), State: state}, nil
+}
+
+// Complete redeems the authorization code, verifies the ID token and signs the user in: a known
+// (issuer, subject) signs in its user, otherwise an existing account with the same verified email
+// is linked if it may be (see linkable) or a new user is provisioned into the tenant. A login
+// started by BeginLink links the identity to that user.
+func (s *OIDCService) Complete(ctx context.Context, state, code string, client SessionClient) (*OIDCResult, error) {
+	s.mu.Lock()
+	pend := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if pend == nil || s.now().After(pend.expires) {
+		return nil, NewUnauthorizedError("invalid or expired sign-in state")
+	}
+	if strings.TrimSpace(code) == "" {
+		return nil, NewInvalidError("code required")
+	}
+	cfg, err := s.enabledConfig(pend.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.provider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := s.exchange(ctx, p, cfg, pend, code)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.verifyIDToken(ctx, cfg, rawIDToken, pend.nonce)
+	if err != nil {
+		return nil, err
+	}
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if sub == "" {
+		return nil, NewUnauthorizedError("id token without subject")
+	}
+	if email == "" || !isValidEmail(email) || !claimTrue(claims["email_verified"]) {
+		return nil, NewForbiddenError("a verified email is required")
+	}
+	if len(cfg.AllowedDomains) > 0 && !containsString(cfg.AllowedDomains, emailDomain(email)) {
+		return nil, NewForbiddenError("email domain not allowed")
+	}
+	role := mapOIDCRole(cfg, claims[cfg.RoleClaim])
+	if role == "" {
+		return nil, NewForbiddenError("no role granted for this account")
+	}
+	var (
+		user        *User
+		provisioned bool
+	)
+	if pend.linkUserID != "" {
+		user, err = s.linkUser(cfg, pend.linkUserID, sub, email)
+	} else {
+		user, provisioned, err = s.resolveUser(cfg, sub, email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	ident, err := s.store.GetOIDCIdentity(cfg.Issuer, sub)
+	if err != nil {
+		return nil, err
+	}
+	if ident == nil {
+		ident = &OIDCIdentity{Issuer: cfg.Issuer, Subject: sub, UserID: user.ID, TenantID: cfg.TenantID, CreatedAt: now}
+	}
+	ident.Email, ident.Role, ident.LastLoginAt = email, role, now
+	if err := s.store.SaveOIDCIdentity(ident); err != nil {
+		return nil, err
+	}
+	if s.members != nil {
+		if err := s.members.Grant(cfg.TenantID, user, role, "system:oidc"); err != nil {
+			return nil, err
+		}
+	}
+	res, err := s.auth.IssueFor(user.ID, cfg.TenantID, user.Email, client)
+	if err != nil {
+		return nil, err
+	}
+	note := cfg.Issuer
+	switch {
+	case provisioned:
+		note += " (provisioned)"
+	case pend.linkUserID != "":
+		note += " (linked)"
+	}
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: cfg.TenantID, Actor: user.Email, ActorID: user.ID, Action: "oidc_login", Target: user.ID, Note: note})
+	return &OIDCResult{AuthResult: res, Email: user.Email, Role: role, Provisioned: provisioned, ReturnTo: pend.returnTo}, nil
+}
+
+func (s *OIDCService) resolveUser(cfg *OIDCConfig, sub, email string) (*User, bool, error) {
+	ident, err := s.store.GetOIDCIdentity(cfg.Issuer, sub)
+	if err != nil {
+		return nil, false, err
+	}
+	if ident != nil {
+		u, err := s.store.FindUserByEmail(ident.Email)
+		if err != nil {
+			return nil, false, err
+		}
+		if u != nil && u.ID == ident.UserID {
+			return u, false, nil
+		}
+	}
+	u, err := s.store.FindUserByEmail(email)
+	if err != nil {
+		return nil, false, err
+	}
+	if u != nil {
+		if err := s.linkable(cfg, u); err != nil {
+			return nil, false, err
+		}
+		if err := s.markVerified(u); err != nil {
+			return nil, false, err
+		}
+		return u, false, nil
+	}
+	// Provisioned users sign in through the provider; the random password is never handed out.
+	hash, err := bcrypt.GenerateFromPassword([]byte(randomBytes(32)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, false, err
+	}
+	now := s.now().UTC()
+	u = &User{ID: "u" + shortID(7), Email: email, PassHash: hash, TenantID: cfg.TenantID, CreatedAt: now, EmailVerifiedAt: now}
+	if err := s.store.AddUser(u); err != nil {
+		return nil, false, err
+	}
+	return u, true, nil
+}
+
+// linkable allows a provider to take over an existing account on its first login only when the
+// account is a plain member of the provider's tenant and nothing else: a provider must not be able
+// to claim admins, owners or users of other tenants just by asserting their email.
+func (s *OIDCService) linkable(cfg *OIDCConfig, u *User) error {
+	if len(cfg.AllowedDomains) == 0 {
+		return NewConflictError("an account with this email exists; sign in and link single sign-on")
+	}
+	if s.members == nil {
+		if u.TenantID != cfg.TenantID {
+			return NewConflictError("account belongs to another tenant")
+		}
+		return nil
+	}
+	list, err := s.members.ListTenants(u.ID)
+	if err != nil {
+		return err
+	}
+	member := false
+	for _, m := range list {
+		if m.TenantID != cfg.TenantID {
+			return NewConflictError("account belongs to another tenant; sign in and link single sign-on")
+		}
+		if m.Role == TenantRoleOwner || m.Role == TenantRoleAdmin {
+			return NewConflictError("tenant admins must sign in and link single sign-on")
+		}
+		member = true
+	}
+	if !member {
+		return NewConflictError("account belongs to another tenant")
+	}
+	return nil
+}
+
+// linkUser resolves the signed-in user of an explicit link. The user must belong to the
+// provider's tenant, the provider must vouch for the account's own address and the identity must
+// not belong to someone else.
+func (s *OIDCService) linkUser(cfg *OIDCConfig, userID, sub, email string) (*User, error) {
+	u, err := s.store.FindUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.ID != userID {
+		return nil, NewForbiddenError("the provider's email does not match your account")
+	}
+	member := u.TenantID == cfg.TenantID
+	if s.members != nil {
+		role, err := s.members.Role(cfg.TenantID, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		member = role != ""
+	}
+	if !member {
+		return nil, NewForbiddenError("not a member of this tenant")
+	}
+	ident, err := s.store.GetOIDCIdentity(cfg.Issuer, sub)
+	if err != nil {
+		return nil, err
+	}
+	if ident != nil && ident.UserID != u.ID {
+		return nil, NewConflictError("identity already linked to another account")
+	}
+	if err := s.markVerified(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// markVerified records that the provider vouched for the address.
+func (s *OIDCService) markVerified(u *User) error {
+	if !u.EmailVerifiedAt.IsZero() {
+		return nil
+	}
+	u.EmailVerifiedAt = s.now().UTC()
+	return s.store.SetEmailVerified(u.ID, u.EmailVerifiedAt)
+}
+
+func (s *OIDCService) exchange(ctx context.Context, p *oidcProvider, cfg *OIDCConfig, pend *oidcPending, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {pend.redirectURI},
+		"client_id":     {cfg.ClientID},
+		"code_verifier": {pend.verifier},
+	}
+	if cfg.ClientSecret != "" {
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var out struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+		Desc    string `json:"error_description"`
+	}
+	status, err := s.doJSON(req, &out)
+	if err != nil {
+		log.Printf("oidc token exchange with %s: %v", cfg.Issuer, err)
+		return "", NewBadGatewayError("token exchange failed")
+	}
+	if status != http.StatusOK || out.IDToken == "" {
+		log.Printf("oidc token exchange with %s: status %d: %s %s", cfg.Issuer, status, out.Error, out.Desc)
+		return "", NewUnauthorizedError("token exchange failed")
+	}
+	return out.IDToken, nil
+}
+
+func (s *OIDCService) verifyIDToken(ctx context.Context, cfg *OIDCConfig, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	keyFunc := func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.key(ctx, cfg.Issuer, kid)
+	}
+	_, err := jwt.ParseWithClaims(raw, claims, keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithTimeFunc(s.now))
+	if err != nil {
+		return nil, NewUnauthorizedError("invalid id token: " + err.Error())
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, NewUnauthorizedError("invalid id token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+// key finds a verification key by kid, refetching the provider's key set once for unknown kids.
+func (s *OIDCService) key(ctx context.Context, issuer, kid string) (any, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		p, err := s.provider(ctx, issuer)
+		if err != nil {
+			return nil, err
+		}
+		if k, ok := p.keys[kid]; ok {
+			return k, nil
+		}
+		if kid == "" && len(p.keys) == 1 {
+			for _, k := range p.keys {
+				return k, nil
+			}
+		}
+		s.mu.Lock()
+		delete(s.providers, issuer)
+		s.mu.Unlock()
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// provider loads the discovery document and key set of an issuer, cached for an hour.
+func (s *OIDCService) provider(ctx context.Context, issuer string) (*oidcProvider, error) {
+	s.mu.Lock()
+	p := s.providers[issuer]
+	s.mu.Unlock()
+	if p != nil && s.now().Sub(p.fetched) < oidcProviderTTL {
+		return p, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	p = &oidcProvider{}
+	if status, err := s.doJSON(req, p); err != nil || status != http.StatusOK {
+		// details stay in the server log: the issuer is chosen by a tenant admin
+		log.Printf("oidc discovery at %s: status %d: %v", issuer, status, err)
+		return nil, NewBadGatewayError("identity provider discovery failed")
+	}
+	if strings.TrimRight(p.Issuer, "/") != issuer || p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, NewBadGatewayError("identity provider discovery document is incomplete or names another issuer")
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if status, err := s.doJSON(req, &set); err != nil || status != http.StatusOK {
+		log.Printf("oidc key set of %s: status %d: %v", issuer, status, err)
+		return nil, NewBadGatewayError("identity provider key set unavailable")
+	}
+	p.keys = map[string]any{}
+	for _, k := range set.Keys {
+		if pub, err := k.publicKey(); err == nil {
+			p.keys[k.Kid] = pub
+		}
+	}
+	p.fetched = s.now()
+	s.mu.Lock()
+	s.providers[issuer] = p
+	s.mu.Unlock()
+	return p, nil
+}
+
+func (s *OIDCService) doJSON(req *http.Request, out any) (int, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxBody))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *OIDCService) enabledConfig(tenantID string) (*OIDCConfig, error) {
+	cfg, err := s.store.GetOIDCConfig(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil || !cfg.Enabled {
+		return nil, NewNotFoundError("single sign-on not enabled")
+	}
+	return cfg, nil
+}
+
+func (s *OIDCService) listEnabled() []*OIDCConfig {
+	all, err := s.store.ListOIDCConfigs()
+	if err != nil {
+		return nil
+	}
+	out := []*OIDCConfig{}
+	for _, c := range all {
+		if c.Enabled {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out
+}
+
+// jwk is a public key of a provider's key set (RSA or P-256).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (any, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("key %q is not a signing key", k.Kid)
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("point not on curve")
+		}
+		return pub, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// mapOIDCRole picks the strongest mapped role among the claim's values, or the default role.
+func mapOIDCRole(cfg *OIDCConfig, claim any) string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	role := ""
+	for _, v := range values {
+		switch cfg.RoleMap[v] {
+		case OIDCRoleAdmin:
+			return OIDCRoleAdmin
+		case OIDCRoleMember:
+			role = OIDCRoleMember
+		}
+	}
+	if role == "" {
+		role = cfg.DefaultRole
+	}
+	return role
+}
+
+func redactOIDCConfig(c *OIDCConfig) *OIDCConfig {
+	cp := *c
+	cp.HasClientSecret = c.ClientSecret != ""
+	cp.ClientSecret = ""
+	if cp.AllowedDomains == nil {
+		cp.AllowedDomains = []string{}
+	}
+	return &cp
+}
+
+func validateIssuer(issuer string, allowLocal bool) error {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return NewInvalidError("issuer must be an absolute URL")
+	}
+	host := u.Hostname()
+	local := allowLocal && (host == "localhost" || host == "127.0.0.1" || host == "::1")
+	if u.Scheme != "https" && !(u.Scheme == "http" && local) {
+		return NewInvalidError("issuer must use https")
+	}
+	return nil
+}
+
+func isOIDCRole(role string) bool { return role == OIDCRoleAdmin || role == OIDCRoleMember }
+
+func emailDomain(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !isValidEmail(email) {
+		return ""
+	}
+	return email[strings.LastIndex(email, "@")+1:]
+}
+
+// claimTrue accepts true and "true"; some providers send email_verified as a string.
+func claimTrue(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
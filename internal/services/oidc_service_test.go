@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type oidcStubStore struct {
+	*authStubStore
+	configs    map[string]*OIDCConfig
+	identities map[string]*OIDCIdentity
+	audit      []AuditEntry
+}
+
+func (s *oidcStubStore) GetOIDCConfig(tenantID string) (*OIDCConfig, error) {
+	if c, ok := s.configs[tenantID]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *oidcStubStore) ListOIDCConfigs() ([]*OIDCConfig, error) {
+	out := []*OIDCConfig{}
+	for _, c := range s.configs {
+		cp := *c
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *oidcStubStore) SaveOIDCConfig(c *OIDCConfig) error {
+	cp := *c
+	s.configs[c.TenantID] = &cp
+	return nil
+}
+
+func (s *oidcStubStore) DeleteOIDCConfig(tenantID string) (bool, error) {
+	_, ok := s.configs[tenantID]
+	delete(s.configs, tenantID)
+	return ok, nil
+}
+
+func (s *oidcStubStore) GetOIDCIdentity(issuer, subject string) (*OIDCIdentity, error) {
+	if id, ok := s.identities[issuer+" "+subject]; ok {
+		cp := *id
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *oidcStubStore) SaveOIDCIdentity(id *OIDCIdentity) error {
+	cp := *id
+	s.identities[id.Issuer+" "+id.Subject] = &cp
+	return nil
+}
+
+func (s *oidcStubStore) SetEmailVerified(userID string, at time.Time) error {
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.EmailVerifiedAt = at
+		}
+	}
+	return nil
+}
+
+func (s *oidcStubStore) AddAudit(entry AuditEntry) { s.audit = append(s.audit, entry) }
+
+// mockIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint that checks PKCE.
+type mockIdP struct {
+	srv   *httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]mockGrant
+	hits  int // discovery requests served
+}
+
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		idp.hits++
+		idp.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := idp.key.PublicKey
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		idp.mu.Lock()
+		g, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		idp.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, g.claims)
+		tok.Header["kid"] = "k1"
+		signed, _ := tok.SignedString(idp.key)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+// authorize plays the user signing in at the provider and returns the code for the callback.
+func (idp *mockIdP) authorize(t *testing.T, loginURL string, claims jwt.MapClaims) (state, code string) {
+	t.Helper()
+	u, err := url.Parse(loginURL)
+	if err != nil || !strings.HasPrefix(loginURL, idp.srv.URL+"/authorize?") {
+		t.Fatalf("login url %q", loginURL)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" || q.Get("nonce") == "" {
+		t.Fatalf("authorize params %v", q)
+	}
+	now := time.Now()
+	full := jwt.MapClaims{"iss": idp.srv.URL, "aud": q.Get("client_id"), "nonce": q.Get("nonce"),
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix()}
+	for k, v := range claims {
+		full[k] = v
+	}
+	code = randomBytes(12)
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{challenge: q.Get("code_challenge"), claims: full}
+	idp.mu.Unlock()
+	return q.Get("state"), code
+}
+
+func newTestOIDC(t *testing.T) (*OIDCService, *oidcStubStore, *mockIdP) {
+	idp := newMockIdP(t)
+	store := &oidcStubStore{authStubStore: newAuthStubStore(), configs: map[string]*OIDCConfig{}, identities: map[string]*OIDCIdentity{}}
+	auth := NewAuthService(store, func(uid, tid, email string, ttl time.Duration) (string, error) { return "tok:" + uid + ":" + tid, nil })
+	svc := NewOIDCService(store, auth, NewOIDCClient(true))
+	svc.WithLocalProviders()
+	_, err := svc.UpdateConfig("t1", "owner@uni.edu", &OIDCConfig{Enabled: true, Issuer: idp.srv.URL + "/", ClientID: "synap", ClientSecret: "s3cret",
+		AllowedDomains: []string{"@Uni.edu"}, RoleClaim: "groups", RoleMap: map[string]string{"faculty": OIDCRoleAdmin}, DefaultRole: OIDCRoleMember})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc, store, idp
+}
+
+func TestOIDCLoginProvisionsAndLinks(t *testing.T) {
+	svc, store, idp := newTestOIDC(t)
+	ctx := context.Background()
+
+	login, err := svc.Begin(ctx, "t1", "https://synap.test/api/auth/oidc/callback", "/admin/scales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, login.URL, jwt.MapClaims{"sub": "alice-1", "email": "Alice@uni.edu", "email_verified": true, "groups": []string{"staff", "faculty"}})
+	if state != login.State {
+		t.Fatalf("state %q != %q", state, login.State)
+	}
+	res, err := svc.Complete(ctx, state, code, SessionClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Provisioned || res.Role != OIDCRoleAdmin || res.TenantID != "t1" || res.Email != "alice@uni.edu" || res.ReturnTo != "/admin/scales" {
+		t.Fatalf("result %+v", res)
+	}
+	if u := store.users["alice@uni.edu"]; u == nil || u.TenantID != "t1" || len(u.PassHash) == 0 {
+		t.Fatalf("provisioned user %+v", u)
+	}
+	// the state is single-use
+	if _, err := svc.Complete(ctx, state, code, SessionClient{}); err == nil {
+		t.Fatal("state reused")
+	}
+
+	// second login signs in the linked user; a member without mapped group falls back to the default role
+	login, _ = svc.Begin(ctx, "t1", "https://synap.test/cb", "https://evil.example")
+	state, code = idp.authorize(t, login.URL, jwt.MapClaims{"sub": "alice-1", "email": "alice@uni.edu", "email_verified": "true"})
+	res2, err := svc.Complete(ctx, state, code, SessionClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res2.Provisioned || res2.UserID != res.UserID || res2.Role != OIDCRoleMember || res2.ReturnTo != "/admin" {
+		t.Fatalf("second login %+v", res2)
+	}
+
+	// an existing password account with the same verified email is linked, not duplicated
+	store.users["bob@uni.edu"] = &User{ID: "u-bob", Email: "bob@uni.edu", PassHash: []byte("x"), TenantID: "t1"}
+	login, _ = svc.Begin(ctx, "t1", "https://synap.test/cb", "")
+	state, code = idp.authorize(t, login.URL, jwt.MapClaims{"sub": "bob-9", "email": "bob@uni.edu", "email_verified": true})
+	res3, err := svc.Complete(ctx, state, code, SessionClient{})
+	if err != nil || res3.UserID != "u-bob" || res3.Provisioned {
+		t.Fatalf("link: %+v %v", res3, err)
+	}
+	if store.users["bob@uni.edu"].EmailVerifiedAt.IsZero() || store.users["alice@uni.edu"].EmailVerifiedAt.IsZero() {
+		t.Fatal("single sign-on did not verify the email")
+	}
+	if id := store.identities[idp.srv.URL+" bob-9"]; id == nil || id.UserID != "u-bob" {
+		t.Fatalf("identity %+v", id)
+	}
+	if len(store.audit) == 0 || store.audit[len(store.audit)-1].Action != "oidc_login" {
+		t.Fatalf("audit %+v", store.audit)
+	}
+	// every sign-in is logged in the provider's tenant, under the account it signed into
+	for _, e := range store.audit {
+		if e.Action == "oidc_login" && (e.TenantID != "t1" || e.ActorID != e.Target) {
+			t.Fatalf("oidc_login audit %+v", e)
+		}
+	}
+}
+
+func TestOIDCRefusesInternalProviders(t *testing.T) {
+	idp := newMockIdP(t)
+	store := &oidcStubStore{authStubStore: newAuthStubStore(), configs: map[string]*OIDCConfig{}, identities: map[string]*OIDCIdentity{}}
+	auth := NewAuthService(store, func(uid, tid, email string, ttl time.Duration) (string, error) { return "tok:" + uid + ":" + tid, nil })
+	svc := NewOIDCService(store, auth, nil)
+	cfg := func(issuer string) *OIDCConfig {
+		return &OIDCConfig{Enabled: true, Issuer: issuer, ClientID: "synap", AllowedDomains: []string{"uni.edu"}, DefaultRole: OIDCRoleMember}
+	}
+	// plain http on loopback needs the development opt-in
+	if _, err := svc.UpdateConfig("t1", "a", cfg(idp.srv.URL)); err == nil {
+		t.Fatal("accepted an http issuer on loopback")
+	}
+	// an issuer that resolves to an internal address is never contacted, even when accepted
+	svc.WithLocalProviders()
+	if _, err := svc.UpdateConfig("t1", "a", cfg(idp.srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := svc.Begin(context.Background(), "t1", "https://synap.test/cb", "")
+	if se, ok := AsServiceError(err); !ok || se.Code != ErrorBadGateway || se.Message != "identity provider discovery failed" {
+		t.Fatalf("discovery of an internal issuer: %v", err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	if idp.hits != 0 {
+		t.Fatal("the internal provider was contacted")
+	}
+}
+
+func TestOIDCLoginRejections(t *testing.T) {
+	svc, store, idp := newTestOIDC(t)
+	ctx := context.Background()
+	store.users["carol@uni.edu"] = &User{ID: "u-carol", Email: "carol@uni.edu", PassHash: []byte("x"), TenantID: "other"}
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+		code   ErrorCode
+	}{
+		{"unverified email", jwt.MapClaims{"sub": "x", "email": "x@uni.edu", "email_verified": false}, ErrorForbidden},
+		{"foreign domain", jwt.MapClaims{"sub": "x", "email": "x@gmail.com", "email_verified": true}, ErrorForbidden},
+		{"other tenant", jwt.MapClaims{"sub": "c", "email": "carol@uni.edu", "email_verified": true}, ErrorConflict},
+		{"wrong audience", jwt.MapClaims{"sub": "x", "email": "x@uni.edu", "email_verified": true, "aud": "someone-else"}, ErrorUnauthorized},
+		{"wrong nonce", jwt.MapClaims{"sub": "x", "email": "x@uni.edu", "email_verified": true, "nonce": "replayed"}, ErrorUnauthorized},
+		{"expired", jwt.MapClaims{"sub": "x", "email": "x@uni.edu", "email_verified": true, "exp": time.Now().Add(-time.Hour).Unix()}, ErrorUnauthorized},
+	}
+	for _, tc := range cases {
+		login, err := svc.Begin(ctx, "t1", "https://synap.test/cb", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, code := idp.authorize(t, login.URL, tc.claims)
+		_, err = svc.Complete(ctx, state, code, SessionClient{})
+		if se, ok := err.(*ServiceError); !ok || se.Code != tc.code {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.code, err)
+		}
+	}
+
+	// a code redeemed without the matching PKCE verifier is refused by the provider
+	login, _ := svc.Begin(ctx, "t1", "https://synap.test/cb", "")
+	state, code := idp.authorize(t, login.URL, jwt.MapClaims{"sub": "x", "email": "x@uni.edu", "email_verified": true})
+	svc.mu.Lock()
+	svc.pending[state].verifier = "tampered"
+	svc.mu.Unlock()
+	// the provider's answer stays in the server log
+	if _, err := svc.Complete(ctx, state, code, SessionClient{}); err == nil || err.Error() != "token exchange failed" {
+		t.Fatalf("pkce: %v", err)
+	}
+
+	// without a default role, unmapped users are refused
+	cfg := store.configs["t1"]
+	cfg.DefaultRole = ""
+	login, _ = svc.Begin(ctx, "t1", "https://synap.test/cb", "")
+	state, code = idp.authorize(t, login.URL, jwt.MapClaims{"sub": "y", "email": "y@uni.edu", "email_verified": true, "groups": "student"})
+	if _, err := svc.Complete(ctx, state, code, SessionClient{}); err == nil {
+		t.Fatal("expected refusal without role")
+	}
+	if _, ok := store.users["y@uni.edu"]; ok {
+		t.Fatal("user provisioned without role")
+	}
+}
+
+func TestOIDCConfigValidation(t *testing.T) {
+	svc, store, idp := newTestOIDC(t)
+	got, err := svc.GetConfig("t1")
+	if err != nil || got.ClientSecret != "" || !got.HasClientSecret || got.Issuer != idp.srv.URL || got.AllowedDomains[0] != "uni.edu" {
+		t.Fatalf("config %+v %v", got, err)
+	}
+	// an empty secret keeps the stored one
+	if _, err := svc.UpdateConfig("t1", "a", &OIDCConfig{Enabled: true, Issuer: idp.srv.URL, ClientID: "synap", AllowedDomains: []string{"uni.edu"}, DefaultRole: OIDCRoleMember}); err != nil {
+		t.Fatal(err)
+	}
+	if store.configs["t1"].ClientSecret != "s3cret" {
+		t.Fatal("secret dropped")
+	}
+	bad := []*OIDCConfig{
+		{Issuer: "http://idp.example.com", ClientID: "c"},
+		{Issuer: "https://idp.example.com", ClientID: ""},
+		{Issuer: "https://idp.example.com", ClientID: "c", DefaultRole: "owner"},
+		{Issuer: "https://idp.example.com", ClientID: "c", RoleMap: map[string]string{"x": "admin"}},
+		{Issuer: "https://idp.example.com", ClientID: "c", Enabled: true, AllowedDomains: []string{"uni.edu"}},
+		{Issuer: "https://idp.example.com", ClientID: "c", Enabled: true, DefaultRole: OIDCRoleMember},
+	}
+	for i, in := range bad {
+		if _, err := svc.UpdateConfig("t2", "a", in); err == nil {
+			t.Fatalf("case %d accepted", i)
+		}
+	}
+	if tid, err := svc.Discover("someone@UNI.edu"); err != nil || tid != "t1" {
+		t.Fatalf("discover %q %v", tid, err)
+	}
+	if _, err := svc.Discover("someone@other.org"); err == nil {
+		t.Fatal("discover other domain")
+	}
+	if err := svc.DeleteConfig("t1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Begin(context.Background(), "t1", "https://synap.test/cb", ""); err == nil {
+		t.Fatal("login after delete")
+	}
+}
+
+func TestOIDCCannotClaimAccountsBeyondPlainMembers(t *testing.T) {
+	svc, store, idp := newTestOIDC(t)
+	ms := &membershipStubStore{authStubStore: store.authStubStore, members: map[string]*Membership{}}
+	members := NewMembershipService(ms)
+	svc.WithMemberships(members)
+	ctx := context.Background()
+	add := func(id, email string, roles map[string]string) {
+		store.users[email] = &User{ID: id, Email: email, PassHash: []byte("x"), TenantID: "t1"}
+		for tid, role := range roles {
+			ms.members[tid+"/"+id] = &Membership{TenantID: tid, UserID: id, Email: email, Role: role}
+		}
+	}
+	add("u-owner", "owner@uni.edu", map[string]string{"tB": TenantRoleOwner})
+	add("u-admin", "admin@uni.edu", map[string]string{"t1": TenantRoleAdmin})
+	add("u-both", "both@uni.edu", map[string]string{"t1": TenantRoleMember, "tB": TenantRoleOwner})
+	add("u-plain", "plain@uni.edu", map[string]string{"t1": TenantRoleMember})
+	login := func(claims jwt.MapClaims) (*OIDCResult, error) {
+		l, err := svc.Begin(ctx, "t1", "https://synap.test/cb", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, code := idp.authorize(t, l.URL, claims)
+		return svc.Complete(ctx, state, code, SessionClient{})
+	}
+
+	// tenant t1's provider asserts the addresses of tenant B's owner, an admin and a shared member
+	for _, email := range []string{"owner@uni.edu", "admin@uni.edu", "both@uni.edu"} {
+		_, err := login(jwt.MapClaims{"sub": "evil-" + email, "email": email, "email_verified": true, "groups": "faculty"})
+		if se, ok := err.(*ServiceError); !ok || se.Code != ErrorConflict {
+			t.Fatalf("%s: expected conflict, got %v", email, err)
+		}
+		if id := store.identities[idp.srv.URL+" evil-"+email]; id != nil {
+			t.Fatalf("%s: identity linked %+v", email, id)
+		}
+	}
+	if _, ok := ms.members["t1/u-owner"]; ok {
+		t.Fatal("owner of tenant B was added to t1")
+	}
+	if ms.members["t1/u-admin"].Role != TenantRoleAdmin {
+		t.Fatal("admin role changed")
+	}
+
+	// a plain member of the tenant only is linked on the first sign-on
+	if res, err := login(jwt.MapClaims{"sub": "plain-1", "email": "plain@uni.edu", "email_verified": true}); err != nil || res.UserID != "u-plain" {
+		t.Fatalf("plain member: %+v %v", res, err)
+	}
+
+	// the shared member links explicitly while signed in, and then signs in through the provider
+	l, err := svc.BeginLink(ctx, "t1", "u-both", "https://synap.test/cb", "/admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, l.URL, jwt.MapClaims{"sub": "both-1", "email": "both@uni.edu", "email_verified": true})
+	if res, err := svc.Complete(ctx, state, code, SessionClient{}); err != nil || res.UserID != "u-both" {
+		t.Fatalf("link: %+v %v", res, err)
+	}
+	if last := store.audit[len(store.audit)-1]; last.Action != "oidc_login" || last.TenantID != "t1" || !strings.HasSuffix(last.Note, "(linked)") {
+		t.Fatalf("audit %+v", last)
+	}
+	if res, err := login(jwt.MapClaims{"sub": "both-1", "email": "both@uni.edu", "email_verified": true}); err != nil || res.UserID != "u-both" {
+		t.Fatalf("linked sign-in: %+v %v", res, err)
+	}
+
+	// an explicit link needs the account's own address and a membership in the provider's tenant
+	l, _ = svc.BeginLink(ctx, "t1", "u-both", "https://synap.test/cb", "")
+	state, code = idp.authorize(t, l.URL, jwt.MapClaims{"sub": "both-2", "email": "plain@uni.edu", "email_verified": true})
+	if _, err := svc.Complete(ctx, state, code, SessionClient{}); err == nil {
+		t.Fatal("linked another account's address")
+	}
+	l, _ = svc.BeginLink(ctx, "t1", "u-owner", "https://synap.test/cb", "")
+	state, code = idp.authorize(t, l.URL, jwt.MapClaims{"sub": "owner-1", "email": "owner@uni.edu", "email_verified": true})
+	if _, err := svc.Complete(ctx, state, code, SessionClient{}); err == nil {
+		t.Fatal("linked a non-member")
+	}
+
+	// a provider stored without domains never takes over existing accounts
+	store.configs["t1"].AllowedDomains = nil
+	add("u-plain2", "plain2@uni.edu", map[string]string{"t1": TenantRoleMember})
+	if _, err := login(jwt.MapClaims{"sub": "plain-2", "email": "plain2@uni.edu", "email_verified": true}); err == nil {
+		t.Fatal("auto-linked without allowed domains")
+	}
+}
@@ -1,11 +1,16 @@
 package services
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type ParticipantStore interface {
 	GetParticipant(id string) (*Participant, error)
 	GetParticipantByEmail(email string) (*Participant, error)
 	ListResponsesByParticipant(id string) ([]*Response, error)
+	GetItem(id string) (*Item, error)
+	GetScale(id string) (*Scale, error)
 	DeleteParticipantByID(id string, hard bool) (bool, error)
 	GetE2EEResponse(id string) (*E2EEResponse, error)
 	DeleteE2EEResponse(id string) (bool, error)
@@ -25,13 +30,121 @@ type E2EEResponse struct {
 }
 
 type ParticipantDataService struct {
-	store ParticipantStore
+	store      ParticipantStore
+	aggregates *AnalyticsAggregates
+	events     *EventBus
 }
 
 func NewParticipantDataService(store ParticipantStore) *ParticipantDataService {
 	return &ParticipantDataService{store: store}
 }
 
+// WithAggregates removes deleted participants from the analytics aggregates of their scales.
+func (s *ParticipantDataService) WithAggregates(a *AnalyticsAggregates) {
+	s.aggregates = a
+}
+
+// WithEvents announces deleted participants and encrypted responses on the bus.
+func (s *ParticipantDataService) WithEvents(bus *EventBus) {
+	s.events = bus
+}
+
+// deleteParticipant deletes the participant's data through the aggregates so the analytics stay in
+// step, and announces the deletion on every scale the participant had answered.
+func (s *ParticipantDataService) deleteParticipant(pid string, hard bool) (bool, error) {
+	var removed map[string]int
+	if s.events != nil {
+		var err error
+		if removed, err = s.responsesPerScale(pid); err != nil {
+			return false, err
+		}
+	}
+	var ok bool
+	err := s.aggregates.Track(pid, func() error {
+		var err error
+		ok, err = s.store.DeleteParticipantByID(pid, hard)
+		return err
+	})
+	if err != nil || !ok {
+		return ok, err
+	}
+	for scaleID, n := range removed {
+		s.events.Publish(Event{Type: EventParticipantDeleted, ScaleID: scaleID, ParticipantID: pid, Count: n})
+	}
+	return true, nil
+}
+
+func (s *ParticipantDataService) responsesPerScale(pid string) (map[string]int, error) {
+	rs, err := s.store.ListResponsesByParticipant(pid)
+	if err != nil {
+		return nil, err
+	}
+	scaleOf := map[string]string{}
+	out := map[string]int{}
+	for _, r := range rs {
+		scaleID, seen := scaleOf[r.ItemID]
+		if !seen {
+			it, err := s.store.GetItem(r.ItemID)
+			if err != nil {
+				return nil, err
+			}
+			if it != nil {
+				scaleID = it.ScaleID
+			}
+			scaleOf[r.ItemID] = scaleID
+		}
+		if scaleID != "" {
+			out[scaleID]++
+		}
+	}
+	return out, nil
+}
+
+// tenantsOf returns the tenants whose scales the participant answered: what happens to the
+// participant's data belongs in their audit logs.
+func (s *ParticipantDataService) tenantsOf(pid string) ([]string, error) {
+	perScale, err := s.responsesPerScale(pid)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var out []string
+	for scaleID := range perScale {
+		sc, err := s.store.GetScale(scaleID)
+		if err != nil {
+			return nil, err
+		}
+		if sc != nil && !seen[sc.TenantID] {
+			seen[sc.TenantID] = true
+			out = append(out, sc.TenantID)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// audit writes e to the log of every tenant in tenants, or once without a tenant when the
+// participant has no answers left to attribute it by.
+func (s *ParticipantDataService) audit(tenants []string, e AuditEntry) {
+	if len(tenants) == 0 {
+		s.store.AddAudit(e)
+		return
+	}
+	for _, tenantID := range tenants {
+		e.TenantID = tenantID
+		s.store.AddAudit(e)
+	}
+}
+
+// scaleTenant returns the tenant of the scale an encrypted response was submitted to.
+func (s *ParticipantDataService) scaleTenant(scaleID string) (string, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil || sc == nil {
+		return "", err
+	}
+	return sc.TenantID, nil
+}
+
 type ParticipantExport struct {
 	Participant map[string]any `json:"participant"`
 	Responses   []*Response    `json:"responses"`
@@ -52,7 +165,11 @@ func (s *ParticipantDataService) ExportParticipant(pid, token string) (*Particip
 	if err != nil {
 		return nil, err
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now(), Actor: "participant", Action: "self_export", Target: pid})
+	tenants, err := s.tenantsOf(pid)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(tenants, AuditEntry{Time: time.Now(), Actor: "participant", Action: "self_export", Target: pid})
 	return &ParticipantExport{Participant: map[string]any{"id": p.ID, "email": p.Email}, Responses: rs}, nil
 }
 
@@ -67,19 +184,23 @@ func (s *ParticipantDataService) DeleteParticipant(pid, token string, hard bool)
 	if p == nil || p.SelfToken == "" || token != p.SelfToken {
 		return NewForbiddenError("forbidden")
 	}
-	ok, err := s.store.DeleteParticipantByID(pid, hard)
+	tenants, err := s.tenantsOf(pid)
+	if err != nil {
+		return err
+	}
+	ok, err := s.deleteParticipant(pid, hard)
 	if err != nil {
 		return err
 	}
 	if !ok {
 		return NewNotFoundError("not found")
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now(), Actor: "participant", Action: map[bool]string{true: "self_delete_hard", false: "self_delete_soft"}[hard], Target: pid})
+	s.audit(tenants, AuditEntry{Time: time.Now(), Actor: "participant", Action: map[bool]string{true: "self_delete_hard", false: "self_delete_soft"}[hard], Target: pid})
 	return nil
 }
 
-// Admin operations (by email)
-func (s *ParticipantDataService) AdminExportByEmail(email, actor string) (*ParticipantExport, error) {
+// Admin operations (by email); tenantID is the admin's tenant, where the action is audited.
+func (s *ParticipantDataService) AdminExportByEmail(tenantID, email, actor string) (*ParticipantExport, error) {
 	if email == "" {
 		return nil, NewInvalidError("email required")
 	}
@@ -94,11 +215,11 @@ func (s *ParticipantDataService) AdminExportByEmail(email, actor string) (*Parti
 	if err != nil {
 		return nil, err
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now(), Actor: actor, Action: "export_participant", Target: email})
+	s.store.AddAudit(AuditEntry{Time: time.Now(), TenantID: tenantID, Actor: actor, Action: "export_participant", Target: email})
 	return &ParticipantExport{Participant: map[string]any{"id": p.ID, "email": p.Email}, Responses: rs}, nil
 }
 
-func (s *ParticipantDataService) AdminDeleteByEmail(email string, hard bool, actor string) error {
+func (s *ParticipantDataService) AdminDeleteByEmail(tenantID, email string, hard bool, actor string) error {
 	if email == "" {
 		return NewInvalidError("email required")
 	}
@@ -109,14 +230,14 @@ func (s *ParticipantDataService) AdminDeleteByEmail(email string, hard bool, act
 	if p == nil {
 		return NewNotFoundError("not found")
 	}
-	ok, err := s.store.DeleteParticipantByID(p.ID, hard)
+	ok, err := s.deleteParticipant(p.ID, hard)
 	if err != nil {
 		return err
 	}
 	if !ok {
 		return NewNotFoundError("not found")
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now(), Actor: actor, Action: "delete_participant", Target: email, Note: map[bool]string{true: "hard", false: "soft"}[hard]})
+	s.store.AddAudit(AuditEntry{Time: time.Now(), TenantID: tenantID, Actor: actor, Action: "delete_participant", Target: email, Note: map[bool]string{true: "hard", false: "soft"}[hard]})
 	return nil
 }
 
@@ -131,7 +252,11 @@ func (s *ParticipantDataService) ExportE2EE(responseID, token string) (*E2EEResp
 	if r == nil || r.SelfToken == "" || token != r.SelfToken {
 		return nil, NewForbiddenError("forbidden")
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now(), Actor: "participant", Action: "self_export_e2ee", Target: responseID})
+	tenantID, err := s.scaleTenant(r.ScaleID)
+	if err != nil {
+		return nil, err
+	}
+	s.store.AddAudit(AuditEntry{Time: time.Now(), TenantID: tenantID, Actor: "participant", Action: "self_export_e2ee", Target: responseID})
 	return r, nil
 }
 
@@ -146,6 +271,10 @@ func (s *ParticipantDataService) DeleteE2EE(responseID, token string) error {
 	if r == nil || r.SelfToken == "" || token != r.SelfToken {
 		return NewForbiddenError("forbidden")
 	}
+	tenantID, err := s.scaleTenant(r.ScaleID)
+	if err != nil {
+		return err
+	}
 	ok, err := s.store.DeleteE2EEResponse(responseID)
 	if err != nil {
 		return err
@@ -153,6 +282,7 @@ func (s *ParticipantDataService) DeleteE2EE(responseID, token string) error {
 	if !ok {
 		return NewNotFoundError("not found")
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now(), Actor: "participant", Action: "self_delete_e2ee", Target: responseID})
+	s.store.AddAudit(AuditEntry{Time: time.Now(), TenantID: tenantID, Actor: "participant", Action: "self_delete_e2ee", Target: responseID})
+	s.events.Publish(Event{Type: EventE2EEResponseDeleted, ScaleID: r.ScaleID, ResponseID: responseID, Count: 1})
 	return nil
 }
@@ -10,6 +10,7 @@ type stubParticipantStore struct {
 	responses    map[string][]*Response
 	e2ee         map[string]*E2EEResponse
 	deleted      map[string]bool
+	audits       []AuditEntry
 }
 
 func newStubParticipantStore() *stubParticipantStore {
@@ -43,6 +44,17 @@ func (s *stubParticipantStore) ListResponsesByParticipant(id string) ([]*Respons
 	return s.responses[id], nil
 }
 
+func (s *stubParticipantStore) GetItem(id string) (*Item, error) {
+	return &Item{ID: id, ScaleID: "S1"}, nil
+}
+
+func (s *stubParticipantStore) GetScale(id string) (*Scale, error) {
+	if id != "S1" {
+		return nil, nil
+	}
+	return &Scale{ID: id, TenantID: "T1"}, nil
+}
+
 func (s *stubParticipantStore) DeleteParticipantByID(id string, hard bool) (bool, error) {
 	if _, ok := s.participants[id]; !ok {
 		return false, nil
@@ -69,16 +81,20 @@ func (s *stubParticipantStore) DeleteE2EEResponse(id string) (bool, error) {
 	return true, nil
 }
 
-func (s *stubParticipantStore) AddAudit(entry AuditEntry) {}
+func (s *stubParticipantStore) AddAudit(entry AuditEntry) { s.audits = append(s.audits, entry) }
 
 func TestParticipantDataService(t *testing.T) {
 	store := newStubParticipantStore()
 	store.participants["P1"] = &Participant{ID: "P1", Email: "p@example.com", SelfToken: "tok"}
 	store.responses["P1"] = []*Response{{ParticipantID: "P1", ItemID: "I1", ScoreValue: 3}}
-	store.e2ee["R1"] = &E2EEResponse{ResponseID: "R1", SelfToken: "tok2", CreatedAt: time.Now()}
+	store.e2ee["R1"] = &E2EEResponse{ScaleID: "S1", ResponseID: "R1", SelfToken: "tok2", CreatedAt: time.Now()}
 
 	svc := NewParticipantDataService(store)
 
+	if _, err := svc.AdminExportByEmail("T2", "p@example.com", "admin@b"); err != nil {
+		t.Fatalf("AdminExportByEmail error: %v", err)
+	}
+
 	exp, err := svc.ExportParticipant("P1", "tok")
 	if err != nil {
 		t.Fatalf("ExportParticipant error: %v", err)
@@ -109,4 +125,15 @@ func TestParticipantDataService(t *testing.T) {
 	if err := svc.DeleteE2EE("R1", "tok2"); err == nil || err.Error() != "forbidden" {
 		t.Fatalf("expected forbidden after deletion, got %v", err)
 	}
+
+	// self-service actions land in the log of the scale's tenant, admin ones in the admin's
+	want := []string{"export_participant:T2", "self_export:T1", "self_delete_hard:T1", "self_export_e2ee:T1", "self_delete_e2ee:T1"}
+	if len(store.audits) != len(want) {
+		t.Fatalf("audit entries %+v", store.audits)
+	}
+	for i, e := range store.audits {
+		if got := e.Action + ":" + e.TenantID; got != want[i] {
+			t.Fatalf("audit %d = %s, want %s", i, got, want[i])
+		}
+	}
 }
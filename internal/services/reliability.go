@@ -0,0 +1,412 @@
+package services
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	defaultBootstrapConfidence = 0.95
+	// MaxBootstrapSamples is the most resamples a reliability request may ask for.
+	MaxBootstrapSamples = 5000
+	// maxBootstrapCells bounds the work of one request: resamples × participants × items.
+	maxBootstrapCells = 20_000_000
+)
+
+// ReliabilityOptions controls the bootstrap confidence intervals. Bootstrap=0 disables them;
+// Seed=0 picks a random seed (AnalyticsService uses one derived from the scale instead). The seed
+// is always reported back so the random split and the resamples can be reproduced.
+type ReliabilityOptions struct {
+	Bootstrap  int
+	Seed       int64
+	Confidence float64
+}
+
+type ReliabilityCI struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// Reliability bundles the internal-consistency coefficients computed from one participant × item
+// matrix. CI is keyed by coefficient name (alpha, omega, lambda6, split_half_odd_even,
+// split_half_random) and only present when bootstrapping was requested.
+type Reliability struct {
+	N                int                      `json:"n"`
+	Alpha            float64                  `json:"alpha"`
+	Omega            float64                  `json:"omega"`
+	Lambda6          float64                  `json:"lambda6"`
+	SplitHalfOddEven float64                  `json:"split_half_odd_even"`
+	SplitHalfRandom  float64                  `json:"split_half_random"`
+	Bootstrap        int                      `json:"bootstrap,omitempty"`
+	Seed             int64                    `json:"seed,omitempty"`
+	Confidence       float64                  `json:"confidence,omitempty"`
+	CI               map[string]ReliabilityCI `json:"ci,omitempty"`
+}
+
+// ComputeReliability evaluates every coefficient on a complete matrix shaped [nParticipants][nItems].
+// The random split is drawn once from the seed and kept fixed across bootstrap resamples.
+func ComputeReliability(matrix [][]float64, opts ReliabilityOptions) *Reliability {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	k := 0
+	if len(matrix) > 0 {
+		k = len(matrix[0])
+	}
+	evenItems, randomHalf := reliabilityHalves(k, rng)
+	coefficients := func(m [][]float64) map[string]float64 {
+		return map[string]float64{
+			"alpha":               CronbachAlpha(m),
+			"omega":               OmegaTotal(m),
+			"lambda6":             GuttmanLambda6(m),
+			"split_half_odd_even": SplitHalf(m, evenItems),
+			"split_half_random":   SplitHalf(m, randomHalf),
+		}
+	}
+	point := coefficients(matrix)
+	out := &Reliability{
+		N:                len(matrix),
+		Seed:             seed,
+		Alpha:            point["alpha"],
+		Omega:            point["omega"],
+		Lambda6:          point["lambda6"],
+		SplitHalfOddEven: point["split_half_odd_even"],
+		SplitHalfRandom:  point["split_half_random"],
+	}
+	if opts.Bootstrap <= 0 || len(matrix) < 2 {
+		return out
+	}
+	b := opts.Bootstrap
+	if b > MaxBootstrapSamples {
+		b = MaxBootstrapSamples
+	}
+	if limit := maxBootstrapCells / (len(matrix) * max(k, 1)); b > limit {
+		b = max(limit, 1)
+	}
+	conf := opts.Confidence
+	if conf <= 0 || conf >= 1 {
+		conf = defaultBootstrapConfidence
+	}
+	samples := map[string][]float64{}
+	resample := make([][]float64, len(matrix))
+	for i := 0; i < b; i++ {
+		for r := range resample {
+			resample[r] = matrix[rng.Intn(len(matrix))]
+		}
+		for name, v := range coefficients(resample) {
+			samples[name] = append(samples[name], v)
+		}
+	}
+	out.Bootstrap, out.Confidence = b, conf
+	out.CI = make(map[string]ReliabilityCI, len(samples))
+	for name, vals := range samples {
+		sort.Float64s(vals)
+		out.CI[name] = ReliabilityCI{Lower: quantile(vals, (1-conf)/2), Upper: quantile(vals, 1-(1-conf)/2)}
+	}
+	return out
+}
+
+// reliabilityFromMoments evaluates the point estimates from the sufficient statistics of a complete
+// matrix (row count, column sums and cross-products), as kept by AnalyticsAggregate. Every
+// coefficient depends on the data only through the covariance matrix, so no rows are needed.
+func reliabilityFromMoments(n int, sums []float64, cross [][]float64, seed int64) *Reliability {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	out := &Reliability{N: n, Seed: seed}
+	k := len(sums)
+	if n < 2 || k < 2 {
+		return out
+	}
+	evenItems, randomHalf := reliabilityHalves(k, rand.New(rand.NewSource(seed)))
+	cov := covarianceFromMoments(n, sums, cross)
+	all := seqIndex(k)
+	out.Alpha = alphaFromCovariance(cov, all)
+	if corr, ok := correlationFromCovariance(cov); ok {
+		out.Omega = omegaFromCorrelation(corr)
+	}
+	out.Lambda6 = lambda6FromCovariance(cov)
+	out.SplitHalfOddEven = splitHalfFromCovariance(cov, evenItems)
+	out.SplitHalfRandom = splitHalfFromCovariance(cov, randomHalf)
+	return out
+}
+
+// reliabilityHalves returns the odd/even split (items 2, 4, 6, ... versus 1, 3, 5, ...) and a
+// random half drawn from rng.
+func reliabilityHalves(k int, rng *rand.Rand) ([]int, []int) {
+	randomHalf := rng.Perm(k)
+	if len(randomHalf) > k/2 {
+		randomHalf = randomHalf[:k/2]
+	}
+	evenItems := make([]int, 0, k/2)
+	for j := 1; j < k; j += 2 {
+		evenItems = append(evenItems, j)
+	}
+	return evenItems, randomHalf
+}
+
+// covarianceFromMoments returns the sample covariance matrix. The numerator n·Σxy − Σx·Σy stays
+// exact for integer scores, so constant items get a variance of exactly zero.
+func covarianceFromMoments(n int, sums []float64, cross [][]float64) [][]float64 {
+	k := len(sums)
+	nf := float64(n)
+	cov := make([][]float64, k)
+	for i := range cov {
+		cov[i] = make([]float64, k)
+		for j := range cov[i] {
+			cov[i][j] = (nf*cross[i][j] - sums[i]*sums[j]) / (nf * (nf - 1))
+		}
+	}
+	return cov
+}
+
+// correlationFromCovariance fails, like completeCorrelation, when an item has no variance.
+func correlationFromCovariance(cov [][]float64) ([][]float64, bool) {
+	k := len(cov)
+	corr := make([][]float64, k)
+	for i := range corr {
+		if cov[i][i] <= 0 {
+			return nil, false
+		}
+		corr[i] = make([]float64, k)
+	}
+	for i := range corr {
+		for j := range corr[i] {
+			corr[i][j] = math.Max(-1, math.Min(1, cov[i][j]/math.Sqrt(cov[i][i]*cov[j][j])))
+		}
+	}
+	return corr, true
+}
+
+// splitHalfFromCovariance is SplitHalf computed from the item covariance matrix.
+func splitHalfFromCovariance(cov [][]float64, half []int) float64 {
+	k := len(cov)
+	if len(half) == 0 || len(half) >= k {
+		return 0
+	}
+	inHalf := make(map[int]bool, len(half))
+	for _, j := range half {
+		inHalf[j] = true
+	}
+	var varA, varB, covAB float64
+	for i := 0; i < k; i++ {
+		for j := 0; j < k; j++ {
+			switch {
+			case inHalf[i] && inHalf[j]:
+				varA += cov[i][j]
+			case !inHalf[i] && !inHalf[j]:
+				varB += cov[i][j]
+			case inHalf[i]:
+				covAB += cov[i][j]
+			}
+		}
+	}
+	if varA <= 0 || varB <= 0 {
+		return 0
+	}
+	r := math.Max(-1, math.Min(1, covAB/math.Sqrt(varA*varB)))
+	if r <= -1 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, 2*r/(1+r)))
+}
+
+// OmegaTotal computes McDonald's omega total from a one-factor principal axis solution of the
+// item correlation matrix: (Σλ)² / ((Σλ)² + Σ(1-λ²)). Items are expected to be reverse-scored
+// already, so loadings share the sign of the general factor.
+func OmegaTotal(matrix [][]float64) float64 {
+	corr, ok := completeCorrelation(matrix)
+	if !ok {
+		return 0
+	}
+	return omegaFromCorrelation(corr)
+}
+
+func omegaFromCorrelation(corr [][]float64) float64 {
+	if len(corr) < 2 {
+		return 0
+	}
+	loadings := oneFactorLoadings(corr)
+	var sum, uniq float64
+	for _, l := range loadings {
+		sum += l
+		uniq += math.Max(0, 1-l*l)
+	}
+	if sum < 0 {
+		sum = -sum
+	}
+	if sum == 0 {
+		return 0
+	}
+	return (sum * sum) / (sum*sum + uniq)
+}
+
+// GuttmanLambda6 computes 1 - Σe²ⱼ / σ²ₓ where e²ⱼ is the residual variance of item j regressed
+// on the remaining items. A singular covariance matrix (e.g. an item that is a linear combination
+// of others, common in small bootstrap resamples) is regularised with a tiny ridge, which gives
+// such items a residual variance of ~0 as their limit.
+func GuttmanLambda6(matrix [][]float64) float64 {
+	if len(matrix) < 2 || len(matrix[0]) < 2 {
+		return 0
+	}
+	cov, _, _ := pairwiseMoments(matrix, len(matrix[0]))
+	return lambda6FromCovariance(cov)
+}
+
+func lambda6FromCovariance(cov [][]float64) float64 {
+	k := len(cov)
+	if k < 2 {
+		return 0
+	}
+	total := sumCovariance(cov, seqIndex(k))
+	if total <= 0 {
+		return 0
+	}
+	inv, ok := invertMatrix(cov)
+	if !ok {
+		inv, ok = invertMatrix(withRidge(cov, 1e-9*total/float64(k)))
+		if !ok {
+			return 0
+		}
+	}
+	var residual float64
+	for j := 0; j < k; j++ {
+		if inv[j][j] <= 0 {
+			return 0
+		}
+		residual += 1 / inv[j][j]
+	}
+	return math.Max(0, math.Min(1, 1-residual/total))
+}
+
+// SplitHalf correlates the sums of two item halves (the items in half versus the rest) and
+// applies the Spearman-Brown correction 2r/(1+r).
+func SplitHalf(matrix [][]float64, half []int) float64 {
+	if len(matrix) < 2 || len(half) == 0 || len(half) >= len(matrix[0]) {
+		return 0
+	}
+	inHalf := make(map[int]bool, len(half))
+	for _, j := range half {
+		inHalf[j] = true
+	}
+	sums := make([][]float64, len(matrix))
+	for i, row := range matrix {
+		sums[i] = make([]float64, 2)
+		for j, v := range row {
+			if inHalf[j] {
+				sums[i][0] += v
+			} else {
+				sums[i][1] += v
+			}
+		}
+	}
+	_, corr, _ := pairwiseMoments(sums, 2)
+	r := corr[0][1]
+	if r <= -1 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, 2*r/(1+r)))
+}
+
+// oneFactorLoadings extracts a single factor by iterated principal axis factoring, starting from
+// squared multiple correlations. Communalities are capped below 1 to avoid Heywood cases.
+func oneFactorLoadings(corr [][]float64) []float64 {
+	k := len(corr)
+	h2 := make([]float64, k)
+	if inv, ok := invertMatrix(corr); ok {
+		for j := range h2 {
+			h2[j] = 1 - 1/inv[j][j]
+		}
+	} else {
+		for j := range h2 {
+			for i := range corr {
+				if i != j && math.Abs(corr[i][j]) > h2[j] {
+					h2[j] = math.Abs(corr[i][j])
+				}
+			}
+		}
+	}
+	loadings := make([]float64, k)
+	reduced := make([][]float64, k)
+	for i := range reduced {
+		reduced[i] = append([]float64(nil), corr[i]...)
+	}
+	for iter := 0; iter < 200; iter++ {
+		for j := range h2 {
+			reduced[j][j] = math.Max(0, math.Min(0.995, h2[j]))
+		}
+		values, vectors := symmetricEigen(reduced)
+		scale := math.Sqrt(math.Max(0, values[0]))
+		var delta float64
+		for j := range loadings {
+			loadings[j] = vectors[0][j] * scale
+			next := loadings[j] * loadings[j]
+			delta = math.Max(delta, math.Abs(next-h2[j]))
+			h2[j] = next
+		}
+		if delta < 1e-6 {
+			break
+		}
+	}
+	return loadings
+}
+
+func withRidge(a [][]float64, eps float64) [][]float64 {
+	out := make([][]float64, len(a))
+	for i := range a {
+		out[i] = append([]float64(nil), a[i]...)
+		out[i][i] += eps
+	}
+	return out
+}
+
+func completeCorrelation(matrix [][]float64) ([][]float64, bool) {
+	if len(matrix) < 2 || len(matrix[0]) == 0 {
+		return nil, false
+	}
+	_, corr, _ := pairwiseMoments(matrix, len(matrix[0]))
+	for j := range corr {
+		if corr[j][j] == 0 {
+			return nil, false
+		}
+	}
+	return corr, true
+}
+
+func seqIndex(k int) []int {
+	out := make([]int, k)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+// quantile expects sorted input and interpolates linearly between order statistics.
+func quantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// scaleReliabilitySeed is the default seed of a scale, so that its random split stays the same
+// from one request to the next.
+func scaleReliabilitySeed(scaleID string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(scaleID))
+	if seed := int64(h.Sum64() &^ (1 << 63)); seed != 0 {
+		return seed
+	}
+	return 1
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+var reliabilityData = [][]float64{
+	{4, 5, 4, 5},
+	{2, 3, 2, 2},
+	{3, 3, 4, 3},
+	{5, 4, 5, 5},
+	{1, 2, 1, 2},
+	{3, 4, 3, 3},
+	{2, 1, 2, 3},
+	{4, 4, 5, 4},
+}
+
+func TestSymmetricEigen(t *testing.T) {
+	values, vectors := symmetricEigen([][]float64{{2, 1}, {1, 2}})
+	if math.Abs(values[0]-3) > 1e-9 || math.Abs(values[1]-1) > 1e-9 {
+		t.Fatalf("unexpected eigenvalues: %v", values)
+	}
+	if math.Abs(math.Abs(vectors[0][0])-math.Sqrt(0.5)) > 1e-9 || vectors[0][0]*vectors[0][1] <= 0 {
+		t.Fatalf("unexpected leading eigenvector: %v", vectors[0])
+	}
+}
+
+func TestSplitHalfAndLambda6TwoItems(t *testing.T) {
+	data := [][]float64{{1, 2}, {2, 1}, {3, 4}, {4, 3}, {5, 5}}
+	a := []float64{1, 2, 3, 4, 5}
+	b := []float64{2, 1, 4, 3, 5}
+	r := pearson(a, b)
+	if got, want := SplitHalf(data, []int{1}), 2*r/(1+r); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("split-half: got %f, want %f", got, want)
+	}
+	// with two items each residual variance is var(x)(1-r²); both items have variance 2.5
+	total := 2.5 + 2.5 + 2*r*2.5
+	want := 1 - 2*2.5*(1-r*r)/total
+	if got := GuttmanLambda6(data); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("lambda6: got %f, want %f", got, want)
+	}
+}
+
+func TestOmegaTotal(t *testing.T) {
+	perfect := [][]float64{{1, 1, 1}, {2, 2, 2}, {3, 3, 3}, {4, 4, 4}}
+	if got := OmegaTotal(perfect); got < 0.99 {
+		t.Fatalf("omega expected ~1 for perfectly correlated items, got %f", got)
+	}
+	omega := OmegaTotal(reliabilityData)
+	alpha := CronbachAlpha(reliabilityData)
+	if omega <= 0 || omega > 1 || omega < alpha-0.05 {
+		t.Fatalf("omega %f implausible next to alpha %f", omega, alpha)
+	}
+}
+
+func TestComputeReliabilityBootstrapIsReproducible(t *testing.T) {
+	opts := ReliabilityOptions{Bootstrap: 200, Seed: 7}
+	first := ComputeReliability(reliabilityData, opts)
+	second := ComputeReliability(reliabilityData, opts)
+	if first.Seed != 7 || first.Bootstrap != 200 || first.Confidence != 0.95 {
+		t.Fatalf("unexpected bootstrap metadata: %+v", first)
+	}
+	for _, name := range []string{"alpha", "omega", "lambda6", "split_half_odd_even", "split_half_random"} {
+		ci, ok := first.CI[name]
+		if !ok {
+			t.Fatalf("missing CI for %s", name)
+		}
+		if ci != second.CI[name] {
+			t.Fatalf("CI for %s not reproducible: %+v vs %+v", name, ci, second.CI[name])
+		}
+		if ci.Lower > ci.Upper {
+			t.Fatalf("CI for %s inverted: %+v", name, ci)
+		}
+	}
+	if first.CI["alpha"].Lower > first.Alpha || first.CI["alpha"].Upper < first.Alpha {
+		t.Fatalf("alpha %f outside its CI %+v", first.Alpha, first.CI["alpha"])
+	}
+	if first.SplitHalfRandom != second.SplitHalfRandom {
+		t.Fatalf("random split not reproducible")
+	}
+	if none := ComputeReliability(reliabilityData, ReliabilityOptions{}); none.CI != nil {
+		t.Fatalf("expected no CI without bootstrap")
+	}
+}
+
+func TestReliabilitySeedIsReportedAndBootstrapCapped(t *testing.T) {
+	if rel := ComputeReliability(reliabilityData, ReliabilityOptions{}); rel.Seed == 0 {
+		t.Fatalf("seed of the random split must be reported: %+v", rel)
+	}
+	if rel := reliabilityFromMoments(3, []float64{1, 2}, [][]float64{{1, 1}, {1, 2}}, 0); rel.Seed == 0 {
+		t.Fatalf("aggregate path must report its seed: %+v", rel)
+	}
+	if scaleReliabilitySeed("S1") != scaleReliabilitySeed("S1") || scaleReliabilitySeed("S1") == scaleReliabilitySeed("S2") {
+		t.Fatal("default seed must be stable per scale")
+	}
+
+	// 4000 participants × 4 items leave room for only 1250 of the 5000 resamples
+	big := make([][]float64, 4000)
+	for i := range big {
+		big[i] = reliabilityData[i%len(reliabilityData)]
+	}
+	k := len(reliabilityData[0])
+	rel := ComputeReliability(big, ReliabilityOptions{Bootstrap: MaxBootstrapSamples, Seed: 1})
+	if want := maxBootstrapCells / (len(big) * k); rel.Bootstrap != min(want, MaxBootstrapSamples) || len(rel.CI) == 0 {
+		t.Fatalf("bootstrap = %d, want %d", rel.Bootstrap, min(want, MaxBootstrapSamples))
+	}
+}
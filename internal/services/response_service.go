@@ -31,6 +31,7 @@ type BulkResponsesRequest struct {
 	ScaleID          string
 	ParticipantEmail string
 	ConsentID        string
+	Condition        string
 	TurnstileToken   string
 	Answers          []BulkAnswer
 	VerifyTurnstile  func(token string) (bool, error)
@@ -57,6 +58,8 @@ type ResponseService struct {
 	store       BulkResponseStore
 	now         func() time.Time
 	idGenerator func() string
+	aggregates  *AnalyticsAggregates
+	events      *EventBus
 }
 
 // NewResponseService constructs a service bound to the provided persistence interface.
@@ -68,6 +71,16 @@ func NewResponseService(store BulkResponseStore) *ResponseService {
 	}
 }
 
+// WithAggregates keeps the scale's analytics aggregates up to date with each submission.
+func (s *ResponseService) WithAggregates(a *AnalyticsAggregates) {
+	s.aggregates = a
+}
+
+// WithEvents announces each submission on the bus.
+func (s *ResponseService) WithEvents(bus *EventBus) {
+	s.events = bus
+}
+
 func defaultParticipantID() string {
 	return strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
 }
@@ -108,9 +121,10 @@ func (s *ResponseService) ProcessBulkResponses(req BulkResponsesRequest) (*BulkR
 		responses = append(responses, resp)
 	}
 
-	if err := s.store.AddResponses(responses); err != nil {
+	if err := s.aggregates.Track(participant.ID, func() error { return s.store.AddResponses(responses) }); err != nil {
 		return nil, err
 	}
+	s.events.Publish(Event{Type: EventResponseSubmitted, ScaleID: scale.ID, ParticipantID: participant.ID, Count: len(responses)})
 
 	return &BulkResponsesResult{
 		ParticipantID:  participant.ID,
@@ -133,7 +147,7 @@ func requireTurnstileIfNeeded(scale *Scale, token string, verify func(string) (b
 }
 
 func (s *ResponseService) createParticipant(req BulkResponsesRequest, scaleID string) (*Participant, error) {
-	participant := &Participant{ID: s.idGenerator(), Email: req.ParticipantEmail}
+	participant := &Participant{ID: s.idGenerator(), Email: req.ParticipantEmail, Condition: normalizeCondition(req.Condition)}
 	if req.ConsentID != "" {
 		if consent := s.store.GetConsentByID(req.ConsentID); consent != nil && consent.ScaleID == scaleID {
 			participant.ConsentID = req.ConsentID
@@ -266,6 +280,17 @@ func normalizeRawToEnglish(item *Item, raw json.RawMessage) string {
 	return string(raw)
 }
 
+// maxConditionLength bounds the experimental condition label stored with a participant.
+const maxConditionLength = 64
+
+func normalizeCondition(c string) string {
+	c = strings.TrimSpace(c)
+	if r := []rune(c); len(r) > maxConditionLength {
+		c = string(r[:maxConditionLength])
+	}
+	return c
+}
+
 func parseNumericAnswer(ans BulkAnswer) (int, bool) {
 	if ans.RawInt != nil {
 		return *ans.RawInt, true
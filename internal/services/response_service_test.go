@@ -203,3 +203,22 @@ func TestNumericRangeValidation(t *testing.T) {
 		t.Fatalf("numeric out-of-range not preserved as raw json; got (%d,%d,%q)", store.responses[1].RawValue, store.responses[1].ScoreValue, store.responses[1].RawJSON)
 	}
 }
+
+func TestProcessBulkResponsesStoresCondition(t *testing.T) {
+	store := &stubBulkStore{
+		scale: &Scale{ID: "S1", Points: 5},
+		items: map[string]*Item{"I1": {ID: "I1", Type: "likert"}},
+	}
+	svc := NewResponseService(store)
+	raw := 3
+	if _, err := svc.ProcessBulkResponses(BulkResponsesRequest{
+		ScaleID:   "S1",
+		Condition: "  treatment ",
+		Answers:   []BulkAnswer{{ItemID: "I1", RawInt: &raw}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.participants) != 1 || store.participants[0].Condition != "treatment" {
+		t.Fatalf("condition not stored: %+v", store.participants)
+	}
+}
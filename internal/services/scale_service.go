@@ -26,6 +26,8 @@ const (
 type ServiceError struct {
 	Code    ErrorCode
 	Message string
+	// RetryAfter tells throttled callers when to try again (ErrorTooManyRequests only).
+	RetryAfter time.Duration
 }
 
 func (e *ServiceError) Error() string { return e.Message }
@@ -44,6 +46,10 @@ func NewTooManyRequestsError(msg string) error {
 	return &ServiceError{Code: ErrorTooManyRequests, Message: msg}
 }
 
+func NewRetryLaterError(msg string, wait time.Duration) error {
+	return &ServiceError{Code: ErrorTooManyRequests, Message: msg, RetryAfter: wait}
+}
+
 func AsServiceError(err error) (*ServiceError, bool) {
 	var se *ServiceError
 	if errors.As(err, &se) {
@@ -67,8 +73,9 @@ type ScaleStore interface {
 }
 
 type ScaleService struct {
-	store ScaleStore
-	now   func() time.Time
+	store      ScaleStore
+	now        func() time.Time
+	aggregates *AnalyticsAggregates
 }
 
 type ScaleItemView struct {
@@ -93,6 +100,11 @@ func NewScaleService(store ScaleStore) *ScaleService {
 	}
 }
 
+// WithAggregates resets the analytics aggregates when a scale's responses are purged.
+func (s *ScaleService) WithAggregates(a *AnalyticsAggregates) {
+	s.aggregates = a
+}
+
 func (s *ScaleService) CreateScale(tenantID string, raw map[string]any) (*Scale, error) {
 	if tenantID == "" {
 		return nil, NewForbiddenError("unauthorized")
@@ -210,11 +222,15 @@ func (s *ScaleService) DeleteScaleResponses(tenantID, scaleID, actor string) (in
 	if sc.TenantID != tenantID {
 		return 0, NewForbiddenError("forbidden")
 	}
-	removed, err := s.store.DeleteResponsesByScale(scaleID)
+	var removed int
+	err = s.aggregates.Reset(scaleID, func() error {
+		removed, err = s.store.DeleteResponsesByScale(scaleID)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: actor, Action: "purge_responses", Target: scaleID, Note: strconv.Itoa(removed)})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: sc.TenantID, Actor: actor, Action: "purge_responses", Target: scaleID, Note: strconv.Itoa(removed)})
 	return removed, nil
 }
 
@@ -462,15 +478,22 @@ func (s *ScaleService) ImportItemsCSV(tenantID, scaleID string, data []byte) (in
 		}
 		created++
 	}
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: "admin", Action: "import_items", Target: scaleID, Note: strconv.Itoa(created)})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: sc.TenantID, Actor: "admin", Action: "import_items", Target: scaleID, Note: strconv.Itoa(created)})
 	return created, nil
 }
 
 func (s *ScaleService) DeleteScale(id, actor string) error {
+	sc, err := s.store.GetScale(id)
+	if err != nil {
+		return err
+	}
+	if sc == nil {
+		return NewNotFoundError("scale not found")
+	}
 	if err := s.store.DeleteScale(id); err != nil {
 		return err
 	}
-	s.store.AddAudit(AuditEntry{Time: s.now(), Actor: actor, Action: "delete_scale", Target: id})
+	s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: sc.TenantID, Actor: actor, Action: "delete_scale", Target: id})
 	return nil
 }
 
@@ -496,49 +519,54 @@ func (s *ScaleService) BuildItemViews(scaleID, lang string) ([]ScaleItemView, er
 	}
 	out := make([]ScaleItemView, 0, len(items))
 	for _, it := range items {
-		stem := it.StemI18n[lang]
-		if stem == "" {
-			stem = it.StemI18n["en"]
-		}
-		options := []string(nil)
-		if it.OptionsI18n != nil {
-			if v := it.OptionsI18n[lang]; len(v) > 0 {
-				options = v
-			} else if v := it.OptionsI18n["en"]; len(v) > 0 {
-				options = v
-			}
+		out = append(out, buildItemView(it, lang))
+	}
+	return out, nil
+}
+
+// buildItemView localises one item for participants, falling back to English.
+func buildItemView(it *Item, lang string) ScaleItemView {
+	stem := it.StemI18n[lang]
+	if stem == "" {
+		stem = it.StemI18n["en"]
+	}
+	options := []string(nil)
+	if it.OptionsI18n != nil {
+		if v := it.OptionsI18n[lang]; len(v) > 0 {
+			options = v
+		} else if v := it.OptionsI18n["en"]; len(v) > 0 {
+			options = v
 		}
-		placeholder := ""
-		if it.PlaceholderI18n != nil {
-			placeholder = it.PlaceholderI18n[lang]
-			if placeholder == "" {
-				placeholder = it.PlaceholderI18n["en"]
-			}
+	}
+	placeholder := ""
+	if it.PlaceholderI18n != nil {
+		placeholder = it.PlaceholderI18n[lang]
+		if placeholder == "" {
+			placeholder = it.PlaceholderI18n["en"]
 		}
-		likertLabels := []string(nil)
-		if it.Type == "likert" && it.LikertLabelsI18n != nil {
-			if v := it.LikertLabelsI18n[lang]; len(v) > 0 {
-				likertLabels = v
-			} else if v := it.LikertLabelsI18n["en"]; len(v) > 0 {
-				likertLabels = v
-			}
+	}
+	likertLabels := []string(nil)
+	if it.Type == "likert" && it.LikertLabelsI18n != nil {
+		if v := it.LikertLabelsI18n[lang]; len(v) > 0 {
+			likertLabels = v
+		} else if v := it.LikertLabelsI18n["en"]; len(v) > 0 {
+			likertLabels = v
 		}
-		out = append(out, ScaleItemView{
-			ID:                it.ID,
-			ReverseScored:     it.ReverseScored,
-			Stem:              stem,
-			Type:              it.Type,
-			Options:           options,
-			Min:               it.Min,
-			Max:               it.Max,
-			Step:              it.Step,
-			Required:          it.Required,
-			Placeholder:       placeholder,
-			LikertLabels:      likertLabels,
-			LikertShowNumbers: it.LikertShowNumbers,
-		})
 	}
-	return out, nil
+	return ScaleItemView{
+		ID:                it.ID,
+		ReverseScored:     it.ReverseScored,
+		Stem:              stem,
+		Type:              it.Type,
+		Options:           options,
+		Min:               it.Min,
+		Max:               it.Max,
+		Step:              it.Step,
+		Required:          it.Required,
+		Placeholder:       placeholder,
+		LikertLabels:      likertLabels,
+		LikertShowNumbers: it.LikertShowNumbers,
+	}
 }
 
 func (s *ScaleService) UpdateScale(id string, raw map[string]any, actor string) error {
@@ -592,7 +620,7 @@ func (s *ScaleService) UpdateScale(id string, raw map[string]any, actor string)
 		return err
 	}
 	if updated.Region != "" && updated.Region != old.Region {
-		s.store.AddAudit(AuditEntry{Time: s.now(), Actor: actor, Action: "region_change", Target: id, Note: updated.Region})
+		s.store.AddAudit(AuditEntry{Time: s.now(), TenantID: old.TenantID, Actor: actor, Action: "region_change", Target: id, Note: updated.Region})
 	}
 	return nil
 }
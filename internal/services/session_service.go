@@ -0,0 +1,295 @@
+package services
+
+import (
+	"strings"
+	"time"
+)
+
+// RefreshTokenPrefix marks session refresh tokens.
+const RefreshTokenPrefix = "synap_rt_"
+
+// SessionStore persists server-side sessions; refresh tokens are stored as SHA-256 hashes.
+type SessionStore interface {
+	SaveSession(s *Session) error
+	GetSession(id string) (*Session, error)
+	// GetSessionByRefreshHash matches the current refresh token hash or any the session rotated away from.
+	GetSessionByRefreshHash(hash string) (*Session, error)
+	// RotateSession saves s only while the stored session is unrevoked and its refresh hash is still
+	// oldHash, and remembers oldHash as rotated. It reports false when another rotation came first.
+	RotateSession(s *Session, oldHash string) (bool, error)
+	ListSessionsByUser(userID string) ([]*Session, error)
+	AddAudit(entry AuditEntry)
+}
+
+// Session is one sign-in of a user. Access tokens carry its ID as jti and stop working as soon as
+// it is revoked; the refresh token is rotated on every refresh.
+type Session struct {
+	ID              string    `json:"id"`
+	UserID          string    `json:"user_id"`
+	TenantID        string    `json:"tenant_id"`
+	Email           string    `json:"email"`
+	UserAgent       string    `json:"user_agent"`
+	IP              string    `json:"ip"`
+	RefreshHash     string    `json:"-"`
+	PrevRefreshHash string    `json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	RevokedAt       time.Time `json:"revoked_at,omitempty"`
+	Current         bool      `json:"current"`
+}
+
+// SessionClient describes the device a session is used from.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionTokens is the result of a sign-in or refresh.
+type SessionTokens struct {
+	SessionID    string
+	UserID       string
+	TenantID     string
+	AccessToken  string
+	RefreshToken string
+	AccessTTL    time.Duration
+	RefreshTTL   time.Duration
+}
+
+// SessionSigner signs an access token whose jti is the session ID.
+type SessionSigner func(uid, tid, email, jti string, ttl time.Duration) (string, error)
+
+type SessionService struct {
+	store      SessionStore
+	signer     SessionSigner
+	now        func() time.Time
+	newID      func() string
+	newToken   func() string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewSessionService(store SessionStore, signer SessionSigner) *SessionService {
+	return &SessionService{
+		store:      store,
+		signer:     signer,
+		now:        time.Now,
+		newID:      func() string { return "ses" + randomID(12) },
+		newToken:   func() string { return RefreshTokenPrefix + randomBytes(32) },
+		accessTTL:  15 * time.Minute,
+		refreshTTL: 30 * 24 * time.Hour,
+	}
+}
+
+func (s *SessionService) AccessTTL() time.Duration  { return s.accessTTL }
+func (s *SessionService) RefreshTTL() time.Duration { return s.refreshTTL }
+
+// Start opens a session for a user who just signed in.
+func (s *SessionService) Start(uid, tid, email string, client SessionClient) (*SessionTokens, error) {
+	if uid == "" || tid == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	now := s.now().UTC()
+	sess := &Session{ID: s.newID(), UserID: uid, TenantID: tid, Email: email, CreatedAt: now}
+	st, err := s.issue(sess, client, now)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(sess, "login", client, now)
+	return st, nil
+}
+
+// Refresh rotates a refresh token and issues a new access token. Presenting any refresh token the
+// session already rotated away from revokes the session, since one of the copies must have leaked;
+// so does losing the race against a concurrent refresh with the same token.
+func (s *SessionService) Refresh(refreshToken string, client SessionClient) (*SessionTokens, error) {
+	refreshToken = strings.TrimSpace(refreshToken)
+	if !strings.HasPrefix(refreshToken, RefreshTokenPrefix) {
+		return nil, NewUnauthorizedError("invalid refresh token")
+	}
+	hash := HashAPIToken(refreshToken)
+	sess, err := s.store.GetSessionByRefreshHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if sess == nil || !sess.RevokedAt.IsZero() || !now.Before(sess.ExpiresAt) {
+		return nil, NewUnauthorizedError("invalid refresh token")
+	}
+	if sess.RefreshHash == hash {
+		st, err := s.issue(sess, client, now)
+		if err != errRotationLost {
+			return st, err
+		}
+	}
+	if err := s.revokeReused(sess.ID, now); err != nil {
+		return nil, err
+	}
+	return nil, NewUnauthorizedError("refresh token reused; session revoked")
+}
+
+// errRotationLost is returned by issue when another rotation of the session came first.
+var errRotationLost = NewConflictError("session was refreshed concurrently")
+
+// revokeReused revokes the session as stored now, which may have been rotated since it was read.
+func (s *SessionService) revokeReused(id string, now time.Time) error {
+	sess, err := s.store.GetSession(id)
+	if err != nil || sess == nil || !sess.RevokedAt.IsZero() {
+		return err
+	}
+	sess.RevokedAt = now
+	return s.store.SaveSession(sess)
+}
+
+func (s *SessionService) issue(sess *Session, client SessionClient, now time.Time) (*SessionTokens, error) {
+	if s.signer == nil {
+		return nil, NewInvalidError("token signer not configured")
+	}
+	refresh := s.newToken()
+	oldHash := sess.RefreshHash
+	sess.PrevRefreshHash, sess.RefreshHash = oldHash, HashAPIToken(refresh)
+	sess.LastSeenAt, sess.ExpiresAt = now, now.Add(s.refreshTTL)
+	if client.UserAgent != "" || client.IP != "" {
+		sess.UserAgent, sess.IP = client.UserAgent, client.IP
+		if len(sess.UserAgent) > 300 {
+			sess.UserAgent = sess.UserAgent[:300]
+		}
+	}
+	if oldHash == "" {
+		if err := s.store.SaveSession(sess); err != nil {
+			return nil, err
+		}
+	} else if ok, err := s.store.RotateSession(sess, oldHash); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errRotationLost
+	}
+	access, err := s.signer(sess.UserID, sess.TenantID, sess.Email, sess.ID, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{SessionID: sess.ID, UserID: sess.UserID, TenantID: sess.TenantID, AccessToken: access, RefreshToken: refresh, AccessTTL: s.accessTTL, RefreshTTL: s.refreshTTL}, nil
+}
+
+// List returns the user's active sessions, marking currentID.
+func (s *SessionService) List(userID, currentID string) ([]*Session, error) {
+	all, err := s.store.ListSessionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	out := []*Session{}
+	for _, sess := range all {
+		if sess.RevokedAt.IsZero() && now.Before(sess.ExpiresAt) {
+			sess.Current = sess.ID == currentID
+			out = append(out, sess)
+		}
+	}
+	return out, nil
+}
+
+// Revoke ends one of the user's sessions.
+func (s *SessionService) Revoke(userID, id string) error {
+	sess, err := s.store.GetSession(id)
+	if err != nil {
+		return err
+	}
+	if sess == nil || sess.UserID != userID {
+		return NewNotFoundError("session not found")
+	}
+	return s.revoke(sess)
+}
+
+// RevokeAll ends all of the user's sessions except exceptID ("" signs out everywhere) and returns
+// how many were revoked.
+func (s *SessionService) RevokeAll(userID, exceptID string) (int, error) {
+	all, err := s.store.ListSessionsByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, sess := range all {
+		if sess.ID == exceptID || !sess.RevokedAt.IsZero() {
+			continue
+		}
+		if err := s.revoke(sess); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RevokeTenant ends the user's sessions in one tenant, e.g. after they were removed from it.
+func (s *SessionService) RevokeTenant(userID, tenantID string) (int, error) {
+	all, err := s.store.ListSessionsByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, sess := range all {
+		if sess.TenantID != tenantID || !sess.RevokedAt.IsZero() {
+			continue
+		}
+		if err := s.revoke(sess); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// SwitchTenant moves one of the user's sessions to another tenant and issues new tokens for it.
+// The caller checks the membership.
+func (s *SessionService) SwitchTenant(userID, sessionID, tenantID string, client SessionClient) (*SessionTokens, error) {
+	sess, err := s.store.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	if sess == nil || sess.UserID != userID || !sess.RevokedAt.IsZero() || !now.Before(sess.ExpiresAt) {
+		return nil, NewUnauthorizedError("session not found")
+	}
+	sess.TenantID = tenantID
+	st, err := s.issue(sess, client, now)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(sess, "tenant.switch", client, now)
+	return st, nil
+}
+
+func (s *SessionService) audit(sess *Session, action string, client SessionClient, now time.Time) {
+	s.store.AddAudit(AuditEntry{Time: now, TenantID: sess.TenantID, Actor: sess.Email, ActorID: sess.UserID, Action: action, Target: sess.ID,
+		IP: client.IP, UserAgent: client.UserAgent, Result: AuditResultSuccess})
+}
+
+// RevokeRefresh ends the session a refresh token belongs to (logout without a valid access token).
+func (s *SessionService) RevokeRefresh(refreshToken string) error {
+	if !strings.HasPrefix(strings.TrimSpace(refreshToken), RefreshTokenPrefix) {
+		return nil
+	}
+	sess, err := s.store.GetSessionByRefreshHash(HashAPIToken(strings.TrimSpace(refreshToken)))
+	if err != nil || sess == nil {
+		return err
+	}
+	return s.revoke(sess)
+}
+
+func (s *SessionService) revoke(sess *Session) error {
+	if !sess.RevokedAt.IsZero() {
+		return nil
+	}
+	sess.RevokedAt = s.now().UTC()
+	return s.store.SaveSession(sess)
+}
+
+// IsRevoked reports whether access tokens of session id must be rejected: the session is unknown,
+// revoked or past its refresh expiry.
+func (s *SessionService) IsRevoked(id string) bool {
+	sess, err := s.store.GetSession(id)
+	if err != nil || sess == nil {
+		return true
+	}
+	return !sess.RevokedAt.IsZero() || !s.now().Before(sess.ExpiresAt)
+}
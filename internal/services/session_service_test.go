@@ -0,0 +1,216 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubSessionStore struct {
+	sessions map[string]*Session
+	rotated  map[string]string // rotated refresh hash -> session ID
+	audit    []AuditEntry
+	// onLookup runs inside GetSessionByRefreshHash, to interleave a concurrent refresh
+	onLookup func()
+}
+
+func (s *stubSessionStore) AddAudit(e AuditEntry) { s.audit = append(s.audit, e) }
+
+func (s *stubSessionStore) SaveSession(sess *Session) error {
+	cp := *sess
+	s.sessions[sess.ID] = &cp
+	return nil
+}
+
+func (s *stubSessionStore) GetSession(id string) (*Session, error) {
+	if sess, ok := s.sessions[id]; ok {
+		cp := *sess
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubSessionStore) GetSessionByRefreshHash(hash string) (*Session, error) {
+	var found *Session
+	for _, sess := range s.sessions {
+		if sess.RefreshHash == hash || s.rotated[hash] == sess.ID {
+			cp := *sess
+			found = &cp
+		}
+	}
+	if hook := s.onLookup; hook != nil {
+		s.onLookup = nil
+		hook()
+	}
+	return found, nil
+}
+
+func (s *stubSessionStore) RotateSession(sess *Session, oldHash string) (bool, error) {
+	cur := s.sessions[sess.ID]
+	if cur == nil || cur.RefreshHash != oldHash || !cur.RevokedAt.IsZero() {
+		return false, nil
+	}
+	s.rotated[oldHash] = sess.ID
+	return true, s.SaveSession(sess)
+}
+
+func (s *stubSessionStore) ListSessionsByUser(userID string) ([]*Session, error) {
+	out := []*Session{}
+	for _, sess := range s.sessions {
+		if sess.UserID == userID {
+			cp := *sess
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func newTestSessionService() (*SessionService, *stubSessionStore, *time.Time) {
+	store := &stubSessionStore{sessions: map[string]*Session{}, rotated: map[string]string{}}
+	svc := NewSessionService(store, func(uid, tid, email, jti string, ttl time.Duration) (string, error) {
+		return "access:" + uid + ":" + jti + ":" + ttl.String(), nil
+	})
+	now := time.Date(2025, 9, 22, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	n := 0
+	svc.newID = func() string { n++; return "ses" + strconv.Itoa(n) }
+	return svc, store, &now
+}
+
+func TestSessionRefreshRotates(t *testing.T) {
+	svc, store, now := newTestSessionService()
+	st, err := svc.Start("U1", "T1", "a@b", SessionClient{UserAgent: "curl/8", IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if st.AccessToken != "access:U1:ses1:15m0s" || !strings.HasPrefix(st.RefreshToken, RefreshTokenPrefix) {
+		t.Fatalf("unexpected tokens %+v", st)
+	}
+	if stored := store.sessions["ses1"]; stored.RefreshHash != HashAPIToken(st.RefreshToken) || stored.UserAgent != "curl/8" || stored.IP != "10.0.0.1" {
+		t.Fatalf("unexpected stored session %+v", stored)
+	}
+	if len(store.audit) != 1 || store.audit[0].Action != "login" || store.audit[0].TenantID != "T1" || store.audit[0].ActorID != "U1" ||
+		store.audit[0].IP != "10.0.0.1" || store.audit[0].UserAgent != "curl/8" {
+		t.Fatalf("sign-in must be audited with the client: %+v", store.audit)
+	}
+	if svc.IsRevoked("ses1") || !svc.IsRevoked("unknown") {
+		t.Fatalf("unexpected revocation state")
+	}
+
+	*now = now.Add(time.Hour)
+	next, err := svc.Refresh(st.RefreshToken, SessionClient{UserAgent: "curl/9", IP: "10.0.0.2"})
+	if err != nil || next.SessionID != "ses1" || next.RefreshToken == st.RefreshToken {
+		t.Fatalf("Refresh: %+v %v", next, err)
+	}
+	if stored := store.sessions["ses1"]; !stored.LastSeenAt.Equal(*now) || !stored.ExpiresAt.Equal(now.Add(svc.RefreshTTL())) || stored.IP != "10.0.0.2" {
+		t.Fatalf("refresh not recorded %+v", stored)
+	}
+
+	// the rotated token is single-use: replaying it revokes the whole session
+	if _, err := svc.Refresh(st.RefreshToken, SessionClient{}); err == nil {
+		t.Fatalf("reused refresh token must be rejected")
+	}
+	if !svc.IsRevoked("ses1") {
+		t.Fatalf("reuse must revoke the session")
+	}
+	if _, err := svc.Refresh(next.RefreshToken, SessionClient{}); err == nil {
+		t.Fatalf("refresh of a revoked session must fail")
+	}
+
+	expiring, _ := svc.Start("U1", "T1", "a@b", SessionClient{})
+	*now = now.Add(svc.RefreshTTL())
+	if _, err := svc.Refresh(expiring.RefreshToken, SessionClient{}); err == nil || !svc.IsRevoked(expiring.SessionID) {
+		t.Fatalf("expired session must not refresh")
+	}
+}
+
+func TestSessionRevoke(t *testing.T) {
+	svc, _, _ := newTestSessionService()
+	a, _ := svc.Start("U1", "T1", "a@b", SessionClient{})
+	b, _ := svc.Start("U1", "T1", "a@b", SessionClient{})
+	c, _ := svc.Start("U1", "T1", "a@b", SessionClient{})
+	other, _ := svc.Start("U2", "T1", "c@d", SessionClient{})
+
+	list, err := svc.List("U1", b.SessionID)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("List: %d %v", len(list), err)
+	}
+	for _, s := range list {
+		if s.Current != (s.ID == b.SessionID) {
+			t.Fatalf("wrong current flag on %+v", s)
+		}
+	}
+	if err := svc.Revoke("U2", a.SessionID); err == nil {
+		t.Fatalf("another user must not revoke the session")
+	}
+	if err := svc.Revoke("U1", a.SessionID); err != nil || !svc.IsRevoked(a.SessionID) {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if n, err := svc.RevokeAll("U1", b.SessionID); err != nil || n != 1 {
+		t.Fatalf("RevokeAll = %d, %v", n, err)
+	}
+	if svc.IsRevoked(b.SessionID) || !svc.IsRevoked(c.SessionID) || svc.IsRevoked(other.SessionID) {
+		t.Fatalf("RevokeAll must keep the current session and other users' sessions")
+	}
+	if err := svc.RevokeRefresh(b.RefreshToken); err != nil || !svc.IsRevoked(b.SessionID) {
+		t.Fatalf("RevokeRefresh: %v", err)
+	}
+	if list, _ := svc.List("U1", ""); len(list) != 0 {
+		t.Fatalf("revoked sessions must not be listed: %d", len(list))
+	}
+}
+
+func TestAuthIssuesSessionTokens(t *testing.T) {
+	sessions, store, _ := newTestSessionService()
+	auth := NewAuthService(newAuthStubStore(), nil)
+	auth.WithSessions(sessions)
+	res, err := auth.Register("user@example.com", "Secret123", "Acme", SessionClient{UserAgent: "ua"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if res.SessionID == "" || res.RefreshToken == "" || !strings.HasPrefix(res.Token, "access:"+res.UserID+":"+res.SessionID) {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if auth.TokenTTL() != sessions.AccessTTL() || store.sessions[res.SessionID].UserAgent != "ua" {
+		t.Fatalf("session not used for sign-in")
+	}
+}
+
+func TestSessionRefreshDetectsReuseOfAnyRotatedToken(t *testing.T) {
+	svc, _, _ := newTestSessionService()
+	first, _ := svc.Start("U1", "T1", "a@b", SessionClient{})
+	second, err := svc.Refresh(first.RefreshToken, SessionClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Refresh(second.RefreshToken, SessionClient{}); err != nil {
+		t.Fatal(err)
+	}
+	// two rotations old is still reuse, not just an unknown token
+	if _, err := svc.Refresh(first.RefreshToken, SessionClient{}); err == nil || !svc.IsRevoked(first.SessionID) {
+		t.Fatalf("replaying an old token must revoke the session: %v", err)
+	}
+}
+
+func TestSessionConcurrentRefreshRevokes(t *testing.T) {
+	svc, store, _ := newTestSessionService()
+	st, _ := svc.Start("U1", "T1", "a@b", SessionClient{})
+	var winner *SessionTokens
+	store.onLookup = func() {
+		var err error
+		if winner, err = svc.Refresh(st.RefreshToken, SessionClient{}); err != nil {
+			t.Errorf("first refresh: %v", err)
+		}
+	}
+	// both requests read the session before either rotated it; only one may rotate
+	if _, err := svc.Refresh(st.RefreshToken, SessionClient{}); err == nil {
+		t.Fatalf("the losing refresh must fail")
+	}
+	if !svc.IsRevoked(st.SessionID) {
+		t.Fatalf("a raced refresh token must revoke the session")
+	}
+	if _, err := svc.Refresh(winner.RefreshToken, SessionClient{}); err == nil {
+		t.Fatalf("the winner's token must die with the session")
+	}
+}
@@ -5,34 +5,73 @@ import (
 	"time"
 )
 
+const (
+	ScaleRoleEditor = "editor"
+	ScaleRoleViewer = "viewer"
+)
+
 type Collaborator struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	ScaleID string `json:"scale_id,omitempty"`
+	UserID  string `json:"user_id"`
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	// Guest marks collaborators who are not members of the scale's tenant.
+	Guest bool `json:"guest,omitempty"`
+}
+
+// ScaleAccess is what a user may do on a scale. Members of the scale's tenant have full access;
+// collaborators from other tenants (Guest) are limited to their Role.
+type ScaleAccess struct {
+	ScaleID  string
+	TenantID string
+	Role     string
+	Guest    bool
 }
 
 type TeamStore interface {
 	GetScale(id string) (*Scale, error)
 	FindUserByEmail(email string) *User
 	ListScaleCollaborators(scaleID string) []Collaborator
+	ListUserCollaborations(userID string) []Collaborator
 	AddScaleCollaborator(scaleID, userID, role string) bool
 	RemoveScaleCollaborator(scaleID, userID string) bool
 	AddAudit(entry AuditEntry)
 }
 
-type TeamService struct{ store TeamStore }
+type TeamService struct {
+	store   TeamStore
+	members *MembershipService
+}
 
 func NewTeamService(store TeamStore) *TeamService { return &TeamService{store: store} }
 
+// WithMemberships accepts any member of the tenant as collaborator, not only users whose sign-in
+// tenant it is.
+func (s *TeamService) WithMemberships(members *MembershipService) {
+	s.members = members
+}
+
 func normalizeRole(role string) string {
 	switch strings.ToLower(strings.TrimSpace(role)) {
-	case "viewer":
-		return "viewer"
+	case ScaleRoleViewer:
+		return ScaleRoleViewer
 	default:
-		return "editor"
+		return ScaleRoleEditor
 	}
 }
 
+// isMember reports whether u belongs to tenantID.
+func (s *TeamService) isMember(tenantID string, u *User) (bool, error) {
+	if s.members == nil {
+		return u.TenantID == tenantID, nil
+	}
+	role, err := s.members.Role(tenantID, u.ID)
+	if err != nil {
+		return false, err
+	}
+	return role != "", nil
+}
+
 // List returns all collaborators for a scale after tenant check.
 func (s *TeamService) List(tenantID, scaleID string) ([]Collaborator, error) {
 	sc, err := s.store.GetScale(scaleID)
@@ -42,10 +81,67 @@ func (s *TeamService) List(tenantID, scaleID string) ([]Collaborator, error) {
 	if sc == nil || sc.TenantID != tenantID {
 		return nil, NewForbiddenError("forbidden")
 	}
-	return s.store.ListScaleCollaborators(scaleID), nil
+	list := s.store.ListScaleCollaborators(scaleID)
+	for i := range list {
+		if s.members == nil {
+			continue
+		}
+		role, err := s.members.Role(tenantID, list[i].UserID)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Guest = role == ""
+	}
+	return list, nil
+}
+
+// Access resolves what userID may do on a scale: full access as a member of its tenant, otherwise
+// the role of a collaboration shared with the user.
+func (s *TeamService) Access(userID, scaleID string) (*ScaleAccess, error) {
+	sc, err := s.store.GetScale(scaleID)
+	if err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, NewNotFoundError("scale not found")
+	}
+	if s.members != nil {
+		role, err := s.members.Role(sc.TenantID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role != "" {
+			return &ScaleAccess{ScaleID: sc.ID, TenantID: sc.TenantID}, nil
+		}
+	}
+	for _, c := range s.store.ListScaleCollaborators(scaleID) {
+		if c.UserID == userID {
+			return &ScaleAccess{ScaleID: sc.ID, TenantID: sc.TenantID, Role: normalizeRole(c.Role), Guest: true}, nil
+		}
+	}
+	return nil, NewForbiddenError("forbidden")
 }
 
-// Add adds a collaborator by email (must be in the same tenant) with role.
+// Shared lists the scales of other tenants than tenantID that userID collaborates on.
+func (s *TeamService) Shared(tenantID, userID string) ([]Collaborator, error) {
+	out := []Collaborator{}
+	for _, c := range s.store.ListUserCollaborations(userID) {
+		sc, err := s.store.GetScale(c.ScaleID)
+		if err != nil {
+			return nil, err
+		}
+		if sc == nil || sc.TenantID == tenantID {
+			continue
+		}
+		c.Role = normalizeRole(c.Role)
+		c.Guest = true
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Add adds a collaborator by email with role. Users of other tenants become guests, who need an
+// explicit viewer or editor role.
 func (s *TeamService) Add(tenantID, scaleID, email, role, actor string) (*Collaborator, error) {
 	sc, err := s.store.GetScale(scaleID)
 	if err != nil {
@@ -55,15 +151,28 @@ func (s *TeamService) Add(tenantID, scaleID, email, role, actor string) (*Collab
 		return nil, NewForbiddenError("forbidden")
 	}
 	u := s.store.FindUserByEmail(email)
-	if u == nil || u.TenantID != tenantID {
-		return nil, NewInvalidError("user not found in tenant")
+	if u == nil {
+		return nil, NewInvalidError("user not found")
+	}
+	member, err := s.isMember(tenantID, u)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		if r := strings.ToLower(strings.TrimSpace(role)); r != ScaleRoleViewer && r != ScaleRoleEditor {
+			return nil, NewInvalidError("role must be viewer or editor for users of other tenants")
+		}
 	}
 	role = normalizeRole(role)
 	if ok := s.store.AddScaleCollaborator(scaleID, u.ID, role); !ok {
 		return nil, NewInvalidError("unable to add collaborator")
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now().UTC(), Actor: actor, Action: "collab.add", Target: scaleID, Note: u.Email + ":" + role})
-	return &Collaborator{UserID: u.ID, Email: u.Email, Role: role}, nil
+	note := u.Email + ":" + role
+	if !member {
+		note += ":guest"
+	}
+	s.store.AddAudit(AuditEntry{Time: time.Now().UTC(), TenantID: tenantID, Actor: actor, Action: "collab.add", Target: scaleID, Note: note})
+	return &Collaborator{ScaleID: scaleID, UserID: u.ID, Email: u.Email, Role: role, Guest: !member}, nil
 }
 
 func (s *TeamService) Remove(tenantID, scaleID, userID, actor string) error {
@@ -77,6 +186,6 @@ func (s *TeamService) Remove(tenantID, scaleID, userID, actor string) error {
 	if ok := s.store.RemoveScaleCollaborator(scaleID, userID); !ok {
 		return NewNotFoundError("collaborator not found")
 	}
-	s.store.AddAudit(AuditEntry{Time: time.Now().UTC(), Actor: actor, Action: "collab.remove", Target: scaleID, Note: userID})
+	s.store.AddAudit(AuditEntry{Time: time.Now().UTC(), TenantID: tenantID, Actor: actor, Action: "collab.remove", Target: scaleID, Note: userID})
 	return nil
 }
@@ -0,0 +1,98 @@
+package services
+
+import "testing"
+
+type teamStubStore struct {
+	*membershipStubStore
+	scales  map[string]*Scale
+	collabs []Collaborator
+}
+
+func (s *teamStubStore) GetScale(id string) (*Scale, error) { return s.scales[id], nil }
+
+func (s *teamStubStore) FindUserByEmail(email string) *User { return s.users[email] }
+
+func (s *teamStubStore) ListScaleCollaborators(scaleID string) []Collaborator {
+	out := []Collaborator{}
+	for _, c := range s.collabs {
+		if c.ScaleID == scaleID {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *teamStubStore) ListUserCollaborations(userID string) []Collaborator {
+	out := []Collaborator{}
+	for _, c := range s.collabs {
+		if c.UserID == userID {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *teamStubStore) AddScaleCollaborator(scaleID, userID, role string) bool {
+	s.RemoveScaleCollaborator(scaleID, userID)
+	s.collabs = append(s.collabs, Collaborator{ScaleID: scaleID, UserID: userID, Role: role})
+	return true
+}
+
+func (s *teamStubStore) RemoveScaleCollaborator(scaleID, userID string) bool {
+	for i, c := range s.collabs {
+		if c.ScaleID == scaleID && c.UserID == userID {
+			s.collabs = append(s.collabs[:i], s.collabs[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func TestTeamSharesScalesAcrossTenants(t *testing.T) {
+	auth, members, ms := newTestMemberships(t)
+	store := &teamStubStore{membershipStubStore: ms, scales: map[string]*Scale{}}
+	team := NewTeamService(store)
+	team.WithMemberships(members)
+	owner := register(t, auth, "owner@example.com", "Uni A")
+	guest := register(t, auth, "guest@example.com", "Uni B")
+	store.scales["S1"] = &Scale{ID: "S1", TenantID: owner.TenantID}
+	store.scales["S2"] = &Scale{ID: "S2", TenantID: guest.TenantID}
+
+	if _, err := team.Access(guest.UserID, "S1"); err == nil {
+		t.Fatalf("scales are not shared by default")
+	}
+	_, err := team.Add(owner.TenantID, "S1", "nobody@example.com", "viewer", "owner@example.com")
+	expectCode(t, err, ErrorInvalid)
+	_, err = team.Add(owner.TenantID, "S1", "guest@example.com", "", "owner@example.com")
+	expectCode(t, err, ErrorInvalid)
+	_, err = team.Add(guest.TenantID, "S1", "guest@example.com", "viewer", "guest@example.com")
+	expectCode(t, err, ErrorForbidden)
+
+	c, err := team.Add(owner.TenantID, "S1", "guest@example.com", "viewer", "owner@example.com")
+	if err != nil || !c.Guest || c.Role != ScaleRoleViewer {
+		t.Fatalf("Add = %+v, %v", c, err)
+	}
+	access, err := team.Access(guest.UserID, "S1")
+	if err != nil || !access.Guest || access.Role != ScaleRoleViewer || access.TenantID != owner.TenantID {
+		t.Fatalf("Access = %+v, %v", access, err)
+	}
+	if access, err := team.Access(owner.UserID, "S1"); err != nil || access.Guest {
+		t.Fatalf("members have full access: %+v, %v", access, err)
+	}
+	if list, _ := team.List(owner.TenantID, "S1"); len(list) != 1 || !list[0].Guest {
+		t.Fatalf("List must mark guests: %+v", list)
+	}
+
+	// the guest's own scales are listed by tenant; only the other tenant's scale is shared
+	store.AddScaleCollaborator("S2", guest.UserID, ScaleRoleEditor)
+	shared, err := team.Shared(guest.TenantID, guest.UserID)
+	if err != nil || len(shared) != 1 || shared[0].ScaleID != "S1" || shared[0].Role != ScaleRoleViewer {
+		t.Fatalf("Shared = %+v, %v", shared, err)
+	}
+
+	// members need no explicit role and are not guests
+	join(t, members, ms, owner.TenantID, "guest@example.com", TenantRoleMember)
+	if c, err := team.Add(owner.TenantID, "S1", "guest@example.com", "", "owner@example.com"); err != nil || c.Guest || c.Role != ScaleRoleEditor {
+		t.Fatalf("Add member = %+v, %v", c, err)
+	}
+}
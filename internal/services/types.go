@@ -52,11 +52,22 @@ type Item struct {
 }
 
 type AuditEntry struct {
-	Time   time.Time
-	Actor  string
-	Action string
-	Target string
-	Note   string
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Action string    `json:"action"`
+	Target string    `json:"target"`
+	Note   string    `json:"note,omitempty"`
+	// TenantID scopes the entry; ActorID is the acting user, IP and UserAgent the client when the
+	// action came from a request, and Result one of the AuditResult values.
+	TenantID  string `json:"tenant_id,omitempty"`
+	ActorID   string `json:"actor_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Result    string `json:"result,omitempty"`
+	// Set by the store: position in the audit chain, the predecessor's hash and AuditHash.
+	Seq      int64  `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 type Tenant struct {
@@ -65,11 +76,12 @@ type Tenant struct {
 }
 
 type User struct {
-	ID        string
-	Email     string
-	PassHash  []byte
-	TenantID  string
-	CreatedAt time.Time
+	ID              string
+	Email           string
+	PassHash        []byte
+	TenantID        string
+	CreatedAt       time.Time
+	EmailVerifiedAt time.Time
 }
 
 type TenantAIConfig struct {
@@ -85,6 +97,7 @@ type Participant struct {
 	Email     string
 	ConsentID string
 	SelfToken string
+	Condition string
 }
 
 type Response struct {
@@ -0,0 +1,612 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusDead      = "dead"
+
+	// WebhookEventPing is only sent by the test endpoint.
+	WebhookEventPing = "ping"
+
+	// WebhookSignatureHeader carries "v1=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+	WebhookSignatureHeader = "X-Synap-Signature"
+	WebhookTimestampHeader = "X-Synap-Timestamp"
+
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+	webhookTimeout      = 10 * time.Second
+	webhookBatchSize    = 50
+	webhookErrorMaxSize = 512
+	webhookDrainSize    = 4 << 10
+)
+
+// errPrivateDestination is returned when a receiver resolves to an address the server may not reach.
+var errPrivateDestination = errors.New("destination address not allowed")
+
+// webhookBlockedNets are non-public ranges the net.IP predicates do not cover.
+var webhookBlockedNets = func() []*net.IPNet {
+	var out []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		out = append(out, n)
+	}
+	return out
+}()
+
+// WebhookEvents lists the event types a webhook can subscribe to; "*" subscribes to all of them.
+var WebhookEvents = []string{
+	EventResponseSubmitted,
+	EventE2EEResponseSubmitted,
+	EventConsentSigned,
+	EventParticipantDeleted,
+	EventE2EEResponseDeleted,
+	EventExportDownloaded,
+}
+
+// WebhookStore persists webhook subscriptions and their delivery queue.
+type WebhookStore interface {
+	GetScale(id string) (*Scale, error)
+	SaveWebhook(w *Webhook) error
+	GetWebhook(id string) (*Webhook, error)
+	ListWebhooks(tenantID string) ([]*Webhook, error)
+	DeleteWebhook(id string) (bool, error)
+	SaveWebhookDelivery(d *WebhookDelivery) error
+	GetWebhookDelivery(id string) (*WebhookDelivery, error)
+	ListWebhookDeliveries(webhookID string) ([]*WebhookDelivery, error)
+	ListDueWebhookDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error)
+}
+
+// Webhook subscribes a URL to events of one tenant, optionally narrowed to a single scale. The
+// signing secret is only returned when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	ScaleID   string    `json:"scale_id,omitempty"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one queued notification. Pending deliveries are retried with exponential
+// backoff until they succeed or run out of attempts and are dead-lettered.
+type WebhookDelivery struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	TenantID      string          `json:"tenant_id"`
+	ScaleID       string          `json:"scale_id,omitempty"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at,omitempty"`
+	LastAttemptAt time.Time       `json:"last_attempt_at,omitempty"`
+	LastStatus    int             `json:"last_status,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   time.Time       `json:"delivered_at,omitempty"`
+}
+
+// WebhookInput creates or updates a webhook. Active defaults to true on create.
+type WebhookInput struct {
+	ScaleID string   `json:"scale_id"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Active  *bool    `json:"active"`
+}
+
+// WebhookCreated returns the new webhook together with its signing secret.
+type WebhookCreated struct {
+	Webhook *Webhook `json:"webhook"`
+	Secret  string   `json:"secret"`
+}
+
+// webhookPayload is the JSON body POSTed to the receiver.
+type webhookPayload struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TenantID  string    `json:"tenant_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      Event     `json:"data"`
+}
+
+// WebhookService manages webhook subscriptions, queues a delivery for every matching event and
+// sends the queue with signed requests.
+type WebhookService struct {
+	store    WebhookStore
+	client   HTTPClient
+	now      func() time.Time
+	newID    func() string
+	secret   func() string
+	mu       sync.Mutex      // guards the queue; never held while a request is in flight
+	inflight map[string]bool // deliveries being sent, so no one else picks them up
+	wake     chan struct{}
+}
+
+// NewWebhookService sends with client, or with NewWebhookClient(false) when it is nil.
+func NewWebhookService(store WebhookStore, client HTTPClient) *WebhookService {
+	if client == nil {
+		client = NewWebhookClient(false)
+	}
+	return &WebhookService{
+		store:    store,
+		client:   client,
+		now:      time.Now,
+		newID:    func() string { return "wh" + randomID(12) },
+		secret:   newWebhookSecret,
+		inflight: map[string]bool{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// NewWebhookClient returns the client deliveries are sent with; see publicOnlyClient. allowPrivate
+// lifts the address check for local receivers in development and tests.
+func NewWebhookClient(allowPrivate bool) *http.Client {
+	return publicOnlyClient(webhookTimeout, allowPrivate)
+}
+
+// publicOnlyClient returns a client for URLs chosen by tenants. It connects only to public
+// addresses, checked after DNS resolution so a hostname cannot point it at internal services,
+// bypasses proxies and does not follow redirects.
+func publicOnlyClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = publicDialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicDialControl runs on the resolved address right before each connection is made.
+func publicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errPrivateDestination
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return errPrivateDestination
+	}
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range webhookBlockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func newWebhookSecret() string {
+	return "whsec_" + randomBytes(24)
+}
+
+// SignWebhookPayload computes the signature header value for a body sent at the given Unix time.
+// Receivers recompute it with their copy of the secret and compare in constant time.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff is the wait after the given number of failed attempts: 30s doubling up to 6h.
+func WebhookBackoff(attempts int) time.Duration {
+	d := webhookBaseBackoff
+	for i := 1; i < attempts && d < webhookMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > webhookMaxBackoff {
+		d = webhookMaxBackoff
+	}
+	return d
+}
+
+func (s *WebhookService) validate(tenantID string, in WebhookInput) (*WebhookInput, error) {
+	out := in
+	out.URL = strings.TrimSpace(in.URL)
+	u, err := url.Parse(out.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, NewInvalidError("url must be an absolute http(s) URL")
+	}
+	out.ScaleID = strings.TrimSpace(in.ScaleID)
+	if out.ScaleID != "" {
+		sc, err := s.store.GetScale(out.ScaleID)
+		if err != nil {
+			return nil, err
+		}
+		if sc == nil || sc.TenantID != tenantID {
+			return nil, NewForbiddenError("forbidden")
+		}
+	}
+	seen := map[string]bool{}
+	out.Events = nil
+	for _, ev := range in.Events {
+		ev = strings.TrimSpace(ev)
+		if ev == "" || seen[ev] {
+			continue
+		}
+		if ev != "*" && !containsString(WebhookEvents, ev) {
+			return nil, NewInvalidError("unknown event " + ev)
+		}
+		seen[ev] = true
+		out.Events = append(out.Events, ev)
+	}
+	if len(out.Events) == 0 {
+		return nil, NewInvalidError("events required")
+	}
+	sort.Strings(out.Events)
+	return &out, nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *WebhookService) Create(tenantID string, in WebhookInput) (*WebhookCreated, error) {
+	if tenantID == "" {
+		return nil, NewUnauthorizedError("unauthorized")
+	}
+	v, err := s.validate(tenantID, in)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	w := &Webhook{ID: s.newID(), TenantID: tenantID, ScaleID: v.ScaleID, URL: v.URL, Events: v.Events, Secret: s.secret(), Active: true, CreatedAt: now, UpdatedAt: now}
+	if v.Active != nil {
+		w.Active = *v.Active
+	}
+	if err := s.store.SaveWebhook(w); err != nil {
+		return nil, err
+	}
+	return &WebhookCreated{Webhook: w, Secret: w.Secret}, nil
+}
+
+func (s *WebhookService) List(tenantID string) ([]*Webhook, error) {
+	return s.store.ListWebhooks(tenantID)
+}
+
+func (s *WebhookService) get(tenantID, id string) (*Webhook, error) {
+	w, err := s.store.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil || w.TenantID != tenantID {
+		return nil, NewNotFoundError("webhook not found")
+	}
+	return w, nil
+}
+
+// Update replaces the URL, scale and events of a webhook; the secret is kept.
+func (s *WebhookService) Update(tenantID, id string, in WebhookInput) (*Webhook, error) {
+	w, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.validate(tenantID, in)
+	if err != nil {
+		return nil, err
+	}
+	w.ScaleID, w.URL, w.Events = v.ScaleID, v.URL, v.Events
+	if v.Active != nil {
+		w.Active = *v.Active
+	}
+	w.UpdatedAt = s.now().UTC()
+	if err := s.store.SaveWebhook(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Delete removes a webhook together with its delivery history.
+func (s *WebhookService) Delete(tenantID, id string) error {
+	if _, err := s.get(tenantID, id); err != nil {
+		return err
+	}
+	_, err := s.store.DeleteWebhook(id)
+	return err
+}
+
+// Deliveries lists a webhook's deliveries, newest first, optionally filtered by status.
+func (s *WebhookService) Deliveries(tenantID, id, status string) ([]*WebhookDelivery, error) {
+	if _, err := s.get(tenantID, id); err != nil {
+		return nil, err
+	}
+	all, err := s.store.ListWebhookDeliveries(id)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return all, nil
+	}
+	out := []*WebhookDelivery{}
+	for _, d := range all {
+		if d.Status == status {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Redeliver puts a delivery (typically a dead-lettered one) back in the queue with a fresh set of
+// attempts. The payload is sent unchanged, so receivers can deduplicate on its ID.
+func (s *WebhookService) Redeliver(tenantID, webhookID, deliveryID string) (*WebhookDelivery, error) {
+	if _, err := s.get(tenantID, webhookID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.store.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil || d.WebhookID != webhookID {
+		return nil, NewNotFoundError("delivery not found")
+	}
+	if s.inflight[d.ID] {
+		return nil, NewConflictError("delivery is being sent")
+	}
+	d.Status, d.Attempts, d.NextAttemptAt, d.LastError = WebhookStatusPending, 0, s.now().UTC(), ""
+	if err := s.store.SaveWebhookDelivery(d); err != nil {
+		return nil, err
+	}
+	s.Notify()
+	return d, nil
+}
+
+// Test sends a ping to the webhook right away and returns the delivery with the outcome. A failed
+// ping is retried like any other delivery.
+func (s *WebhookService) Test(tenantID, id string) (*WebhookDelivery, error) {
+	w, err := s.get(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	d, err := s.enqueue(w, Event{Type: WebhookEventPing, ScaleID: w.ScaleID, Time: s.now().UTC()})
+	if err == nil {
+		s.inflight[d.ID] = true
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attempt(w, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Handle queues a delivery of ev for every active webhook of the scale's tenant that subscribes to
+// it. It is meant to observe the EventBus.
+func (s *WebhookService) Handle(ev Event) error {
+	sc, err := s.store.GetScale(ev.ScaleID)
+	if err != nil || sc == nil || sc.TenantID == "" {
+		return err
+	}
+	hooks, err := s.store.ListWebhooks(sc.TenantID)
+	if err != nil {
+		return err
+	}
+	queued := false
+	for _, w := range hooks {
+		if !w.Active || (w.ScaleID != "" && w.ScaleID != ev.ScaleID) {
+			continue
+		}
+		if !containsString(w.Events, "*") && !containsString(w.Events, ev.Type) {
+			continue
+		}
+		if _, err := s.enqueue(w, ev); err != nil {
+			return err
+		}
+		queued = true
+	}
+	if queued {
+		s.Notify()
+	}
+	return nil
+}
+
+func (s *WebhookService) enqueue(w *Webhook, ev Event) (*WebhookDelivery, error) {
+	now := s.now().UTC()
+	d := &WebhookDelivery{ID: s.newID(), WebhookID: w.ID, TenantID: w.TenantID, ScaleID: ev.ScaleID, Event: ev.Type,
+		Status: WebhookStatusPending, NextAttemptAt: now, CreatedAt: now}
+	body, err := json.Marshal(webhookPayload{ID: d.ID, Type: ev.Type, TenantID: w.TenantID, CreatedAt: now, Data: ev})
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = body
+	if err := s.store.SaveWebhookDelivery(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Notify wakes the dispatcher so freshly queued deliveries go out without waiting for the next tick.
+func (s *WebhookService) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ProcessDue attempts every delivery whose next attempt is due and returns how many it attempted.
+func (s *WebhookService) ProcessDue() (int, error) {
+	claimed, hooks, err := s.claimDue()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i, d := range claimed {
+		if err := s.attempt(hooks[d.WebhookID], d); err != nil {
+			s.release(claimed[i+1:])
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// claimDue marks the due deliveries of existing webhooks as in flight and returns them with
+// their webhooks.
+func (s *WebhookService) claimDue() ([]*WebhookDelivery, map[string]*Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due, err := s.store.ListDueWebhookDeliveries(s.now().UTC(), webhookBatchSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	hooks := map[string]*Webhook{}
+	claimed := []*WebhookDelivery{}
+	for _, d := range due {
+		if s.inflight[d.ID] {
+			continue
+		}
+		w, ok := hooks[d.WebhookID]
+		if !ok {
+			if w, err = s.store.GetWebhook(d.WebhookID); err != nil {
+				return nil, nil, err
+			}
+			hooks[d.WebhookID] = w
+		}
+		if w == nil {
+			continue
+		}
+		s.inflight[d.ID] = true
+		claimed = append(claimed, d)
+	}
+	return claimed, hooks, nil
+}
+
+func (s *WebhookService) release(list []*WebhookDelivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range list {
+		delete(s.inflight, d.ID)
+	}
+}
+
+// Run sends due deliveries every interval (and whenever new ones are queued) until ctx is done.
+// Store errors are reported to onError and retried on the next round.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.ProcessDue()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+			if err != nil || n < webhookBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// attempt sends a claimed delivery without holding s.mu, then records the outcome and releases
+// the claim.
+func (s *WebhookService) attempt(w *Webhook, d *WebhookDelivery) error {
+	now := s.now().UTC()
+	status, sendErr := s.send(w, d, now)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, d.ID)
+	d.Attempts++
+	d.LastAttemptAt, d.LastStatus = now, status
+	switch {
+	case sendErr == nil:
+		d.Status, d.DeliveredAt, d.LastError, d.NextAttemptAt = WebhookStatusDelivered, now, "", time.Time{}
+	case d.Attempts >= webhookMaxAttempts:
+		d.Status, d.LastError, d.NextAttemptAt = WebhookStatusDead, sendErr.Error(), time.Time{}
+	default:
+		d.LastError, d.NextAttemptAt = sendErr.Error(), now.Add(WebhookBackoff(d.Attempts))
+	}
+	return s.store.SaveWebhookDelivery(d)
+}
+
+func (s *WebhookService) send(w *Webhook, d *WebhookDelivery, now time.Time) (int, error) {
+	if !w.Active {
+		return 0, fmt.Errorf("webhook disabled")
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Synap-Webhooks/1")
+	req.Header.Set("X-Synap-Event", d.Event)
+	req.Header.Set("X-Synap-Delivery", d.ID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(w.Secret, ts, d.Payload))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, errPrivateDestination) {
+			return 0, errPrivateDestination
+		}
+		return 0, truncateError(err.Error())
+	}
+	defer resp.Body.Close()
+	// the body is drained for connection reuse but never stored: it is the receiver's to tell
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookDrainSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncateError(msg string) error {
+	if len(msg) > webhookErrorMaxSize {
+		msg = msg[:webhookErrorMaxSize]
+	}
+	return fmt.Errorf("%s", msg)
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubWebhookStore struct {
+	scales     map[string]*Scale
+	hooks      map[string]*Webhook
+	deliveries map[string]*WebhookDelivery
+}
+
+func newStubWebhookStore() *stubWebhookStore {
+	return &stubWebhookStore{
+		scales: map[string]*Scale{
+			"S1": {ID: "S1", TenantID: "T1"},
+			"S2": {ID: "S2", TenantID: "T1"},
+			"X1": {ID: "X1", TenantID: "T2"},
+		},
+		hooks:      map[string]*Webhook{},
+		deliveries: map[string]*WebhookDelivery{},
+	}
+}
+
+func (s *stubWebhookStore) GetScale(id string) (*Scale, error) { return s.scales[id], nil }
+
+func (s *stubWebhookStore) SaveWebhook(w *Webhook) error {
+	cp := *w
+	s.hooks[w.ID] = &cp
+	return nil
+}
+
+func (s *stubWebhookStore) GetWebhook(id string) (*Webhook, error) {
+	if w, ok := s.hooks[id]; ok {
+		cp := *w
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubWebhookStore) ListWebhooks(tenantID string) ([]*Webhook, error) {
+	out := []*Webhook{}
+	for _, w := range s.hooks {
+		if w.TenantID == tenantID {
+			cp := *w
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubWebhookStore) DeleteWebhook(id string) (bool, error) {
+	_, ok := s.hooks[id]
+	delete(s.hooks, id)
+	return ok, nil
+}
+
+func (s *stubWebhookStore) SaveWebhookDelivery(d *WebhookDelivery) error {
+	cp := *d
+	s.deliveries[d.ID] = &cp
+	return nil
+}
+
+func (s *stubWebhookStore) GetWebhookDelivery(id string) (*WebhookDelivery, error) {
+	if d, ok := s.deliveries[id]; ok {
+		cp := *d
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (s *stubWebhookStore) ListWebhookDeliveries(webhookID string) ([]*WebhookDelivery, error) {
+	out := []*WebhookDelivery{}
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, nil
+}
+
+func (s *stubWebhookStore) ListDueWebhookDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	out := []*WebhookDelivery{}
+	for _, d := range s.deliveries {
+		if d.Status == WebhookStatusPending && !d.NextAttemptAt.After(now) {
+			cp := *d
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+type receivedHook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver is a local HTTP endpoint that answers with the configured status code.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	received []receivedHook
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.received = append(rcv.received, receivedHook{header: r.Header.Clone(), body: body})
+	w.WriteHeader(rcv.status)
+}
+
+func newTestWebhookService(store *stubWebhookStore) (*WebhookService, *time.Time) {
+	now := time.Date(2025, 9, 20, 12, 0, 0, 0, time.UTC)
+	svc := NewWebhookService(store, NewWebhookClient(true))
+	svc.now = func() time.Time { return now }
+	n := 0
+	svc.newID = func() string { n++; return "id" + strconv.Itoa(100+n) }
+	svc.secret = func() string { return "whsec_test" }
+	return svc, &now
+}
+
+func TestWebhookCreateValidates(t *testing.T) {
+	svc, _ := newTestWebhookService(newStubWebhookStore())
+	cases := []WebhookInput{
+		{URL: "ftp://example.com", Events: []string{EventConsentSigned}},
+		{URL: "http://example.com", Events: []string{"response.updated"}},
+		{URL: "http://example.com"},
+		{URL: "http://example.com", Events: []string{"*"}, ScaleID: "X1"},
+	}
+	for i, in := range cases {
+		if _, err := svc.Create("T1", in); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+	created, err := svc.Create("T1", WebhookInput{URL: " http://127.0.0.1:9/hook ", Events: []string{EventResponseSubmitted, EventConsentSigned, EventConsentSigned}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Secret != "whsec_test" || !created.Webhook.Active || len(created.Webhook.Events) != 2 || created.Webhook.URL != "http://127.0.0.1:9/hook" {
+		t.Fatalf("unexpected webhook %+v", created)
+	}
+	b, _ := json.Marshal(created.Webhook)
+	var fields map[string]any
+	_ = json.Unmarshal(b, &fields)
+	if _, leaked := fields["secret"]; leaked {
+		t.Fatalf("webhook JSON must not include the secret")
+	}
+	if _, err := svc.Update("T2", created.Webhook.ID, WebhookInput{URL: "http://example.com", Events: []string{"*"}}); err == nil {
+		t.Fatalf("another tenant must not update the webhook")
+	}
+}
+
+func TestWebhookDeliveryQueue(t *testing.T) {
+	rcv := &webhookReceiver{status: http.StatusOK}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	store := newStubWebhookStore()
+	svc, now := newTestWebhookService(store)
+	all, _ := svc.Create("T1", WebhookInput{URL: srv.URL, Events: []string{EventResponseSubmitted}})
+	_, _ = svc.Create("T1", WebhookInput{URL: srv.URL, Events: []string{"*"}, ScaleID: "S2"})
+	_, _ = svc.Create("T2", WebhookInput{URL: srv.URL, Events: []string{"*"}})
+
+	if err := svc.Handle(Event{ID: 7, Type: EventResponseSubmitted, ScaleID: "S1", ParticipantID: "P1", Count: 3}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	_ = svc.Handle(Event{Type: EventConsentSigned, ScaleID: "S1"}) // no subscriber
+	if len(store.deliveries) != 1 {
+		t.Fatalf("queued %d deliveries, want 1", len(store.deliveries))
+	}
+	if n, err := svc.ProcessDue(); err != nil || n != 1 {
+		t.Fatalf("ProcessDue = %d, %v", n, err)
+	}
+	if len(rcv.received) != 1 {
+		t.Fatalf("receiver got %d requests", len(rcv.received))
+	}
+	got := rcv.received[0]
+	ts, _ := strconv.ParseInt(got.header.Get(WebhookTimestampHeader), 10, 64)
+	if ts != now.Unix() || !hmac.Equal([]byte(got.header.Get(WebhookSignatureHeader)), []byte(SignWebhookPayload("whsec_test", ts, got.body))) {
+		t.Fatalf("bad signature headers %v", got.header)
+	}
+	if got.header.Get("X-Synap-Event") != EventResponseSubmitted {
+		t.Fatalf("event header = %q", got.header.Get("X-Synap-Event"))
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(got.body, &payload); err != nil || payload.Data.ParticipantID != "P1" || payload.Data.Count != 3 || payload.TenantID != "T1" {
+		t.Fatalf("unexpected payload %s (%v)", got.body, err)
+	}
+	deliveries, _ := svc.Deliveries("T1", all.Webhook.ID, WebhookStatusDelivered)
+	if len(deliveries) != 1 || deliveries[0].Attempts != 1 || deliveries[0].LastStatus != http.StatusOK {
+		t.Fatalf("unexpected deliveries %+v", deliveries)
+	}
+
+	// failures back off exponentially and end in the dead-letter state
+	rcv.status = http.StatusServiceUnavailable
+	_ = svc.Handle(Event{Type: EventResponseSubmitted, ScaleID: "S1"})
+	var failing *WebhookDelivery
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		if n, _ := svc.ProcessDue(); n != 1 {
+			t.Fatalf("attempt %d: processed %d", attempt, n)
+		}
+		if n, _ := svc.ProcessDue(); n != 0 {
+			t.Fatalf("attempt %d: retried before the backoff elapsed", attempt)
+		}
+		pending, _ := svc.Deliveries("T1", all.Webhook.ID, "")
+		failing = pending[0]
+		if attempt < webhookMaxAttempts {
+			if failing.Status != WebhookStatusPending || !failing.NextAttemptAt.Equal(now.Add(WebhookBackoff(attempt))) {
+				t.Fatalf("attempt %d: %+v", attempt, failing)
+			}
+			*now = failing.NextAttemptAt
+		}
+	}
+	if failing.Status != WebhookStatusDead || failing.LastStatus != http.StatusServiceUnavailable || failing.LastError == "" {
+		t.Fatalf("expected dead-lettered delivery, got %+v", failing)
+	}
+	if WebhookBackoff(1) != 30*time.Second || WebhookBackoff(3) != 2*time.Minute || WebhookBackoff(30) != 6*time.Hour {
+		t.Fatalf("unexpected backoff schedule")
+	}
+
+	// redelivery re-queues the same payload
+	rcv.status = http.StatusNoContent
+	if _, err := svc.Redeliver("T2", all.Webhook.ID, failing.ID); err == nil {
+		t.Fatalf("another tenant must not redeliver")
+	}
+	re, err := svc.Redeliver("T1", all.Webhook.ID, failing.ID)
+	if err != nil || re.Status != WebhookStatusPending || re.Attempts != 0 {
+		t.Fatalf("Redeliver: %+v %v", re, err)
+	}
+	if n, _ := svc.ProcessDue(); n != 1 {
+		t.Fatalf("redelivery not attempted")
+	}
+	if d, _ := store.GetWebhookDelivery(failing.ID); d.Status != WebhookStatusDelivered || string(rcv.received[len(rcv.received)-1].body) != string(failing.Payload) {
+		t.Fatalf("redelivery failed: %+v", d)
+	}
+
+	ping, err := svc.Test("T1", all.Webhook.ID)
+	if err != nil || ping.Event != WebhookEventPing || ping.Status != WebhookStatusDelivered {
+		t.Fatalf("Test: %+v %v", ping, err)
+	}
+}
+
+func TestWebhookClientRefusesInternalReceivers(t *testing.T) {
+	rcv := &webhookReceiver{status: http.StatusOK}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	// the default client refuses loopback receivers after resolving the name
+	store := newStubWebhookStore()
+	svc, _ := newTestWebhookService(store)
+	svc.client = NewWebhookClient(false)
+	hook, _ := svc.Create("T1", WebhookInput{URL: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1), Events: []string{"*"}})
+	d, err := svc.Test("T1", hook.Webhook.ID)
+	if err != nil || d.Status != WebhookStatusPending || d.LastError != errPrivateDestination.Error() || len(rcv.received) != 0 {
+		t.Fatalf("loopback delivery: %+v %v (received %d)", d, err, len(rcv.received))
+	}
+	for _, ip := range []string{"10.0.0.1", "172.16.5.4", "192.168.1.1", "169.254.169.254", "127.0.0.1", "::1", "fe80::1", "fc00::1", "100.64.0.1", "0.0.0.0"} {
+		if err := publicDialControl("tcp", net.JoinHostPort(ip, "80"), nil); err == nil {
+			t.Fatalf("%s allowed", ip)
+		}
+	}
+	if err := publicDialControl("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Fatalf("public address refused: %v", err)
+	}
+
+	// redirects are not followed and response bodies are not recorded
+	target := &webhookReceiver{status: http.StatusOK}
+	targetSrv := httptest.NewServer(target)
+	defer targetSrv.Close()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, targetSrv.URL, http.StatusFound)
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("db password is hunter2"))
+	})
+	local := httptest.NewServer(mux)
+	defer local.Close()
+	svc.client = NewWebhookClient(true)
+	redirect, _ := svc.Create("T1", WebhookInput{URL: local.URL + "/redirect", Events: []string{"*"}})
+	if d, err := svc.Test("T1", redirect.Webhook.ID); err != nil || d.LastStatus != http.StatusFound || d.Status == WebhookStatusDelivered || len(target.received) != 0 {
+		t.Fatalf("redirect: %+v %v", d, err)
+	}
+	failing, _ := svc.Create("T1", WebhookInput{URL: local.URL + "/fail", Events: []string{"*"}})
+	if d, err := svc.Test("T1", failing.Webhook.ID); err != nil || d.LastError != "HTTP 500" {
+		t.Fatalf("failure: %+v %v", d, err)
+	}
+}
+
+func TestWebhookSendsWithoutHoldingTheQueueLock(t *testing.T) {
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+	store := newStubWebhookStore()
+	svc, _ := newTestWebhookService(store)
+	hook, _ := svc.Create("T1", WebhookInput{URL: srv.URL, Events: []string{"*"}})
+
+	done := make(chan *WebhookDelivery)
+	go func() {
+		d, err := svc.Test("T1", hook.Webhook.ID)
+		if err != nil {
+			t.Errorf("Test: %v", err)
+		}
+		done <- d
+	}()
+	<-arrived
+
+	// while the ping is in flight the queue stays usable and the ping is not sent twice
+	if n, err := svc.ProcessDue(); err != nil || n != 0 {
+		t.Fatalf("ProcessDue during a send = %d, %v", n, err)
+	}
+	svc.mu.Lock()
+	var pending string
+	for id := range svc.inflight {
+		pending = id
+	}
+	svc.mu.Unlock()
+	if _, err := svc.Redeliver("T1", hook.Webhook.ID, pending); err == nil {
+		t.Fatal("redelivered a delivery in flight")
+	}
+	close(release)
+	if d := <-done; d == nil || d.Status != WebhookStatusDelivered {
+		t.Fatalf("ping: %+v", d)
+	}
+	if len(svc.inflight) != 0 {
+		t.Fatalf("claims left behind: %v", svc.inflight)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -21,6 +24,46 @@ func baseURL() string {
 	return "http://127.0.0.1:18080"
 }
 
+// waitForMail waits for a mail to email that the server writes into SYNAP_TEST_MAIL_DIR (run the
+// server with SYNAP_MAILER=file and SYNAP_MAIL_DIR pointing there) whose body matches re, and
+// returns the body and the submatches.
+func waitForMail(t *testing.T, email string, re *regexp.Regexp) (string, []string) {
+	t.Helper()
+	dir := strings.TrimSpace(os.Getenv("SYNAP_TEST_MAIL_DIR"))
+	if dir == "" {
+		t.Fatalf("SYNAP_TEST_MAIL_DIR must name the server's SYNAP_MAIL_DIR to read its mail")
+	}
+	suffix := strings.NewReplacer("@", "_at_", "/", "_").Replace(email) + ".eml"
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if !strings.HasSuffix(e.Name(), suffix) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				t.Fatalf("read mail: %v", err)
+			}
+			if m := re.FindStringSubmatch(string(data)); m != nil {
+				return string(data), m
+			}
+		}
+	}
+	t.Fatalf("no mail matching %s for %s in %s", re, email, dir)
+	return "", nil
+}
+
+// verificationToken returns the token of the verification mail sent to email.
+func verificationToken(t *testing.T, email string) string {
+	t.Helper()
+	_, m := waitForMail(t, email, regexp.MustCompile(`/auth/verify\?token=([A-Za-z0-9_%-]+)`))
+	token, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatalf("token in mail: %v", err)
+	}
+	return token
+}
+
 func TestUserJourneyIntegration(t *testing.T) {
 	client := &http.Client{Timeout: 5 * time.Second}
 	base := baseURL()
@@ -55,6 +98,15 @@ func TestUserJourneyIntegration(t *testing.T) {
 		t.Fatalf("login did not return token")
 	}
 
+	// exports need a confirmed address
+	var verifyResp struct {
+		OK bool `json:"ok"`
+	}
+	doPost(t, client, base+"/api/auth/verify-email", "", map[string]string{"token": verificationToken(t, userEmail)}, &verifyResp)
+	if !verifyResp.OK {
+		t.Fatalf("email verification failed")
+	}
+
 	var createScaleResp struct {
 		ID string `json:"id"`
 	}
@@ -106,6 +158,7 @@ func TestUserJourneyIntegration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("new request: %v", err)
 	}
+	req.Header.Set("Authorization", "Bearer "+token)
 	resp, err := client.Do(req)
 	if err != nil {
 		t.Fatalf("export request failed: %v", err)
@@ -125,6 +178,98 @@ func TestUserJourneyIntegration(t *testing.T) {
 	}
 }
 
+// verifiedAccount registers an account with its own tenant, confirms its address and returns its token.
+func verifiedAccount(t *testing.T, client *http.Client, base, prefix string) string {
+	t.Helper()
+	email := fmt.Sprintf("%s_%d@example.com", prefix, time.Now().UnixNano())
+	var reg struct {
+		Token string `json:"token"`
+	}
+	doPost(t, client, base+"/api/auth/register", "", map[string]any{"email": email, "password": "Secret123!", "tenantName": prefix}, &reg)
+	doPost(t, client, base+"/api/auth/verify-email", "", map[string]string{"token": verificationToken(t, email)}, nil)
+	return reg.Token
+}
+
+func getStatus(t *testing.T, client *http.Client, url, token string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("get %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestScaleRoutesRefuseOtherTenants(t *testing.T) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	base := baseURL()
+	owner := verifiedAccount(t, client, base, "owner")
+	stranger := verifiedAccount(t, client, base, "stranger")
+
+	var scale struct {
+		ID string `json:"id"`
+	}
+	doPost(t, client, base+"/api/scales", owner, map[string]any{"name_i18n": map[string]string{"en": "Private"}}, &scale)
+	var item struct {
+		ID string `json:"id"`
+	}
+	doPost(t, client, base+"/api/items", owner, map[string]any{"scale_id": scale.ID, "stem_i18n": map[string]string{"en": "Q"}, "type": "likert"}, &item)
+	doPost(t, client, base+"/api/responses/bulk", "", map[string]any{"scale_id": scale.ID, "answers": []map[string]any{{"item_id": item.ID, "raw": 3}}}, nil)
+
+	for _, path := range []string{
+		"/api/export?format=long&scale_id=",
+		"/api/export?format=items&scale_id=",
+		"/api/metrics/alpha?bootstrap=100&scale_id=",
+		"/api/admin/stats?scale_id=",
+		"/api/admin/analytics/summary?scale_id=",
+		"/api/admin/analytics/items?scale_id=",
+	} {
+		if code := getStatus(t, client, base+path+scale.ID, stranger); code != http.StatusForbidden {
+			t.Fatalf("%s of another tenant's scale: status %d", path, code)
+		}
+		if code := getStatus(t, client, base+path+scale.ID, owner); code != http.StatusOK {
+			t.Fatalf("%s of the owner's scale: status %d", path, code)
+		}
+	}
+}
+
+func TestMailedLinksIgnoreTheHostHeader(t *testing.T) {
+	public := strings.TrimRight(strings.TrimSpace(os.Getenv("SYNAP_TEST_PUBLIC_URL")), "/")
+	if public == "" {
+		t.Fatalf("SYNAP_TEST_PUBLIC_URL must name the server's SYNAP_PUBLIC_URL")
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	base := baseURL()
+	email := fmt.Sprintf("host_%d@example.com", time.Now().UnixNano())
+	doPost(t, client, base+"/api/auth/register", "", map[string]any{"email": email, "password": "Secret123!", "tenantName": "Host check"}, nil)
+
+	payload, _ := json.Marshal(map[string]string{"email": email})
+	req, err := http.NewRequest(http.MethodPost, base+"/api/auth/password/forgot", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Host = "evil.example"
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-Host", "evil.example")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("forgot request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("forgot status %d", resp.StatusCode)
+	}
+	body, m := waitForMail(t, email, regexp.MustCompile(`(\S*)/auth/reset\?token=`))
+	if m[1] != public || strings.Contains(body, "evil.example") {
+		t.Fatalf("reset link must point at %s, got mail:\n%s", public, body)
+	}
+}
+
 func doPost(t *testing.T, client *http.Client, url, token string, body any, out any) {
 	t.Helper()
 	payload, err := json.Marshal(body)